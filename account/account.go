@@ -354,6 +354,9 @@ func (acc *DB) GetBalance(api client.QueueProtocolAPI, in *types.ReqBalance) ([]
 				return nil, err
 			}
 			account, err = acc.LoadExecAccountHistoryQueue(api, addr, execaddress, hash)
+			if err == types.ErrStatePruned {
+				return nil, err
+			}
 			if err != nil {
 				log.Error("GetBalance", "err", err.Error())
 				continue
@@ -390,6 +393,9 @@ func (acc *DB) GetExecBalance(api client.QueueProtocolAPI, in *types.ReqGetExecB
 	//log.Info("DB.GetExecBalance", "hash", common.ToHex(req.StateHash), "Prefix", string(req.Start), "End", string(req.End), "Addr", string(req.Suffix))
 
 	res, err := api.StoreList(&req)
+	if err == types.ErrStatePruned {
+		return nil, err
+	}
 	if err != nil {
 		err = types.ErrTypeAsset
 		return nil, err
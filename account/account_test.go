@@ -587,6 +587,27 @@ func TestGetBalance(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestGetBalancePruned(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	accCoin := NewCoinsAccount(cfg)
+	addr := "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
+
+	//历史状态已经被裁剪时返回 ErrStatePruned
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	api.On("StoreGet", mock.Anything).Return(nil, types.ErrStatePruned).Twice()
+	api.On("StoreList", mock.Anything).Return(nil, types.ErrStatePruned).Once()
+	in := &types.ReqBalance{Addresses: []string{addr}, StateHash: "3131"}
+	_, err := accCoin.GetBalance(api, in)
+	assert.Equal(t, types.ErrStatePruned, err)
+	in.Execer = "coins"
+	_, err = accCoin.GetBalance(api, in)
+	assert.Equal(t, types.ErrStatePruned, err)
+	_, err = accCoin.GetExecBalance(api, &types.ReqGetExecBalance{Symbol: "bty", Execer: "coins", StateHash: []byte("11"), Addr: []byte(addr)})
+	assert.Equal(t, types.ErrStatePruned, err)
+	api.AssertExpectations(t)
+}
+
 func TestDB_Mint(t *testing.T) {
 	_, tokenCoin := GenerAccDb()
 	tokenCoin.GenerAccData()
@@ -0,0 +1,578 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+const (
+	defaultArchiveSegmentSize = 256 * 1024 * 1024
+	archiveRecordHeaderSize   = 8
+	archiveLocSize            = 16
+	archiveSegmentPrefix      = "seg-"
+	archiveSegmentSuffix      = ".dat"
+)
+
+var (
+	archiveIndexPrefix = []byte("CHAIN-archive-")
+	archiveHeightKey   = []byte("FLAG:ArchiveHeight")
+
+	errArchiveNotFound = errors.New("ErrArchiveNotFound")
+	errArchiveCorrupt  = errors.New("ErrArchiveCorrupt")
+)
+
+//archiveLoc 归档记录在段文件中的位置
+type archiveLoc struct {
+	seg    uint32
+	offset int64
+	size   uint32
+}
+
+func (loc *archiveLoc) encode() []byte {
+	buf := make([]byte, archiveLocSize)
+	binary.BigEndian.PutUint32(buf[0:4], loc.seg)
+	binary.BigEndian.PutUint64(buf[4:12], uint64(loc.offset))
+	binary.BigEndian.PutUint32(buf[12:16], loc.size)
+	return buf
+}
+
+func decodeArchiveLoc(data []byte) (*archiveLoc, error) {
+	if len(data) != archiveLocSize {
+		return nil, errArchiveCorrupt
+	}
+	return &archiveLoc{
+		seg:    binary.BigEndian.Uint32(data[0:4]),
+		offset: int64(binary.BigEndian.Uint64(data[4:12])),
+		size:   binary.BigEndian.Uint32(data[12:16]),
+	}, nil
+}
+
+func calcArchiveKey(height int64) []byte {
+	return append(archiveIndexPrefix, []byte(fmt.Sprintf("%012d", height))...)
+}
+
+func archiveSegmentName(id uint32) string {
+	return fmt.Sprintf("%s%06d%s", archiveSegmentPrefix, id, archiveSegmentSuffix)
+}
+
+//archiveStore 区块归档存储
+//主链区块的body和完整receipt压缩后追加写入段文件, 记录格式为[长度][crc32][zlib数据],
+//高度到段内位置的索引保存在blockchain db中
+type archiveStore struct {
+	//mtx 保护当前写入的段文件
+	mtx     sync.Mutex
+	db      dbm.DB
+	dir     string
+	segSize int64
+	//下一个待归档的高度
+	height  int64
+	cur     *os.File
+	curID   uint32
+	curSize int64
+	//fileMtx 保护已打开的段文件
+	fileMtx sync.Mutex
+	files   map[uint32]*os.File
+}
+
+//archiveDir 归档段文件目录
+func archiveDir(cfg *types.BlockChain) string {
+	if cfg.ArchiveDir != "" {
+		return cfg.ArchiveDir
+	}
+	return filepath.Join(cfg.DbPath, "archive")
+}
+
+func newArchiveStore(db dbm.DB, dir string, segSize int64) (*archiveStore, error) {
+	if segSize <= 0 {
+		segSize = defaultArchiveSegmentSize
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	s := &archiveStore{db: db, dir: dir, segSize: segSize, files: make(map[uint32]*os.File)}
+	ids, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		s.curID = ids[len(ids)-1]
+	}
+	err = s.openCurrent()
+	if err != nil {
+		return nil, err
+	}
+	flag := &types.Int64{}
+	data, err := db.Get(archiveHeightKey)
+	if err == nil {
+		err = types.Decode(data, flag)
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+	} else if err != dbm.ErrNotFoundInDb && err != types.ErrNotFound {
+		s.close()
+		return nil, err
+	}
+	s.height = flag.GetData()
+	return s, nil
+}
+
+//segments 按编号升序列出目录下的段文件
+func (s *archiveStore) segments() ([]uint32, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint32
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, archiveSegmentPrefix) || !strings.HasSuffix(name, archiveSegmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, archiveSegmentPrefix), archiveSegmentSuffix), 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint32(id))
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (s *archiveStore) openCurrent() error {
+	file, err := os.OpenFile(filepath.Join(s.dir, archiveSegmentName(s.curID)), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.cur = file
+	s.curSize = info.Size()
+	s.fileMtx.Lock()
+	s.files[s.curID] = file
+	s.fileMtx.Unlock()
+	return nil
+}
+
+//rotate 当前段文件写满后切换到下一个段文件
+func (s *archiveStore) rotate() error {
+	err := s.cur.Sync()
+	if err != nil {
+		return err
+	}
+	s.curID++
+	return s.openCurrent()
+}
+
+//getHeight 获取下一个待归档的高度, 低于该高度的主链区块均已归档
+func (s *archiveStore) getHeight() int64 {
+	return atomic.LoadInt64(&s.height)
+}
+
+func (s *archiveStore) setHeight(height int64) {
+	atomic.StoreInt64(&s.height, height)
+}
+
+//appendRecord 追加写入一条已编码的记录, 需持有写锁
+func (s *archiveStore) appendRecord(record []byte) (*archiveLoc, error) {
+	if s.curSize > 0 && s.curSize+int64(len(record)) > s.segSize {
+		err := s.rotate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err := s.cur.Write(record)
+	if err != nil {
+		return nil, err
+	}
+	loc := &archiveLoc{seg: s.curID, offset: s.curSize, size: uint32(len(record))}
+	s.curSize += int64(len(record))
+	return loc, nil
+}
+
+//append 压缩并追加写入区块body, 返回其在段文件中的位置
+func (s *archiveStore) append(body *types.BlockBody) (*archiveLoc, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write(types.Encode(body))
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, archiveRecordHeaderSize+buf.Len())
+	binary.BigEndian.PutUint32(record[0:4], uint32(buf.Len()))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(buf.Bytes()))
+	copy(record[archiveRecordHeaderSize:], buf.Bytes())
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.appendRecord(record)
+}
+
+//sync 将当前段文件刷盘, 需在写入索引之前调用
+func (s *archiveStore) sync() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cur.Sync()
+}
+
+func (s *archiveStore) file(id uint32) (*os.File, error) {
+	s.fileMtx.Lock()
+	defer s.fileMtx.Unlock()
+	if file, ok := s.files[id]; ok {
+		return file, nil
+	}
+	file, err := os.Open(filepath.Join(s.dir, archiveSegmentName(id)))
+	if err != nil {
+		return nil, err
+	}
+	s.files[id] = file
+	return file, nil
+}
+
+//readRecord 读取并校验一条完整记录
+func (s *archiveStore) readRecord(loc *archiveLoc) ([]byte, error) {
+	if loc.size < archiveRecordHeaderSize {
+		return nil, errArchiveCorrupt
+	}
+	file, err := s.file(loc.seg)
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, loc.size)
+	_, err = file.ReadAt(record, loc.offset)
+	if err != nil {
+		return nil, err
+	}
+	data := record[archiveRecordHeaderSize:]
+	if binary.BigEndian.Uint32(record[0:4]) != uint32(len(data)) || binary.BigEndian.Uint32(record[4:8]) != crc32.ChecksumIEEE(data) {
+		return nil, errArchiveCorrupt
+	}
+	return record, nil
+}
+
+//get 通过高度获取归档的区块body
+func (s *archiveStore) get(height int64) (*types.BlockBody, error) {
+	value, err := s.db.Get(calcArchiveKey(height))
+	if err != nil || value == nil {
+		return nil, errArchiveNotFound
+	}
+	loc, err := decodeArchiveLoc(value)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.readRecord(loc)
+	if err != nil {
+		return nil, err
+	}
+	r, err := zlib.NewReader(bytes.NewReader(record[archiveRecordHeaderSize:]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var body types.BlockBody
+	err = types.Decode(data, &body)
+	if err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+//compact 重建段文件, 只保留索引引用的记录
+//新段文件从当前最大编号之后开始写入, 索引全部更新后再删除旧段文件, 中途退出不会丢失数据
+func (s *archiveStore) compact() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	const batchDataSize = 1024 * 1024
+	lastID := s.curID
+	err := s.rotate()
+	if err != nil {
+		return err
+	}
+	it := s.db.Iterator(archiveIndexPrefix, nil, false)
+	defer it.Close()
+	batch := s.db.NewBatch(true)
+	count := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		loc, err := decodeArchiveLoc(it.Value())
+		if err != nil {
+			return err
+		}
+		if loc.seg > lastID {
+			continue
+		}
+		record, err := s.readRecord(loc)
+		if err != nil {
+			chainlog.Error("compactArchive readRecord", "key", string(it.Key()), "err", err)
+			return err
+		}
+		newLoc, err := s.appendRecord(record)
+		if err != nil {
+			return err
+		}
+		batch.Set(it.Key(), newLoc.encode())
+		count++
+		if batch.ValueSize() > batchDataSize {
+			err = s.cur.Sync()
+			if err != nil {
+				return err
+			}
+			dbm.MustWrite(batch)
+			batch.Reset()
+		}
+	}
+	err = it.Error()
+	if err != nil {
+		return err
+	}
+	err = s.cur.Sync()
+	if err != nil {
+		return err
+	}
+	dbm.MustWrite(batch)
+
+	ids, err := s.segments()
+	if err != nil {
+		return err
+	}
+	removed := 0
+	s.fileMtx.Lock()
+	defer s.fileMtx.Unlock()
+	for _, id := range ids {
+		if id > lastID {
+			continue
+		}
+		if file, ok := s.files[id]; ok {
+			file.Close()
+			delete(s.files, id)
+		}
+		err = os.Remove(filepath.Join(s.dir, archiveSegmentName(id)))
+		if err != nil {
+			return err
+		}
+		removed++
+	}
+	chainlog.Info("compactArchive", "records", count, "removed segments", removed)
+	return nil
+}
+
+//archiveSegment 备份时记录的段文件编号以及大小
+type archiveSegment struct {
+	id   uint32
+	size int64
+}
+
+//snapshot 记录全部段文件当前的大小, 需在获取blockchain数据库快照之后调用,
+//段文件先写入数据再写入索引, 快照中的索引指向的记录都在记录的大小之内
+func (s *archiveStore) snapshot() ([]archiveSegment, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ids, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	segs := make([]archiveSegment, 0, len(ids))
+	for _, id := range ids {
+		if id == s.curID {
+			segs = append(segs, archiveSegment{id: id, size: s.curSize})
+			continue
+		}
+		info, err := os.Stat(filepath.Join(s.dir, archiveSegmentName(id)))
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, archiveSegment{id: id, size: info.Size()})
+	}
+	return segs, nil
+}
+
+//backup 把段文件复制到dir, 每个段文件只复制到snapshot时的大小, 返回复制的总字节数
+func (s *archiveStore) backup(dir string, segs []archiveSegment) (int64, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, seg := range segs {
+		name := archiveSegmentName(seg.id)
+		err = copyFile(filepath.Join(s.dir, name), filepath.Join(dir, name), seg.size)
+		if err != nil {
+			return 0, err
+		}
+		total += seg.size
+	}
+	return total, nil
+}
+
+func (s *archiveStore) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.fileMtx.Lock()
+	defer s.fileMtx.Unlock()
+	for id, file := range s.files {
+		file.Close()
+		delete(s.files, id)
+	}
+}
+
+//CompactArchive 离线重建归档段文件, 清理中断归档遗留的无效记录, 需在节点停止时执行
+func CompactArchive(cfg *types.BlockChain) error {
+	db := dbm.NewDB("blockchain", cfg.Driver, cfg.DbPath, cfg.DbCache)
+	defer db.Close()
+	s, err := newArchiveStore(db, archiveDir(cfg), cfg.ArchiveSegmentSize)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	return s.compact()
+}
+
+//initArchive 打开归档存储
+func (chain *BlockChain) initArchive() {
+	if !chain.cfg.EnableArchive {
+		flagHeight, _ := chain.blockStore.loadFlag(archiveHeightKey) //一旦开启归档，后续不能关闭
+		if flagHeight != 0 {
+			panic("toml config disable archive, but database has archived blocks")
+		}
+		return
+	}
+	s, err := newArchiveStore(chain.blockStore.db, archiveDir(chain.cfg), chain.cfg.ArchiveSegmentSize)
+	if err != nil {
+		panic(err)
+	}
+	chain.blockStore.archive = s
+}
+
+//ArchiveChain 启动区块归档
+func (chain *BlockChain) ArchiveChain() {
+	if chain.blockStore.archive == nil {
+		return
+	}
+	chain.reducewg.Add(1)
+	go chain.ArchiveRoutine()
+}
+
+//ArchiveRoutine 定时将超过保留高度的区块归档
+func (chain *BlockChain) ArchiveRoutine() {
+	defer chain.reducewg.Done()
+
+	flagHeight := chain.blockStore.archive.getHeight()
+	// 10s检测一次是否可以进行归档
+	checkTicker := time.NewTicker(10 * time.Second)
+	defer checkTicker.Stop()
+	for {
+		select {
+		case <-chain.quit:
+			return
+		case <-checkTicker.C:
+			flagHeight = chain.TryArchive(flagHeight, 100)
+		}
+	}
+}
+
+//TryArchive 每隔rangeHeight个区块归档一次, 返回下一个待归档的高度
+func (chain *BlockChain) TryArchive(flagHeight int64, rangeHeight int64) (newHeight int64) {
+	archive := chain.blockStore.archive
+	if archive == nil {
+		return flagHeight
+	}
+	if rangeHeight <= 0 {
+		rangeHeight = 100
+	}
+	reserve := chain.cfg.ArchiveReserveHeight
+	if reserve < ReduceHeight {
+		reserve = ReduceHeight
+	}
+	safetyHeight := chain.GetBlockHeight() - reserve
+	if safetyHeight/rangeHeight > flagHeight/rangeHeight {
+		sync := true
+		if atomic.LoadInt32(&chain.isbatchsync) == 0 {
+			sync = false
+		}
+		chain.walkOver(flagHeight, safetyHeight, sync, chain.archiveBody,
+			func(batch dbm.Batch, height int64) {
+				// 段文件先于索引刷盘, 中断后重新归档只会在段文件中留下无效记录
+				err := archive.sync()
+				if err != nil {
+					panic(err)
+				}
+				height++
+				batch.Set(archiveHeightKey, types.Encode(&types.Int64{Data: height}))
+			})
+		flagHeight = safetyHeight + 1
+		archive.setHeight(flagHeight)
+		chainlog.Debug("archive ticker", "current height", flagHeight)
+	}
+	return flagHeight
+}
+
+//archiveBody 将主链区块的body和receipt写入段文件, 删除数据库中对应的数据并精简TxResult
+func (chain *BlockChain) archiveBody(batch dbm.Batch, height int64) {
+	blockDetail, err := chain.blockStore.LoadBlockByHeight(height)
+	if err != nil {
+		chainlog.Error("archiveBody LoadBlockByHeight", "height", height, "error", err)
+		return
+	}
+	cfg := chain.client.GetConfig()
+	block := blockDetail.GetBlock()
+	hash := block.Hash(cfg)
+	loc, err := chain.blockStore.archive.append(chain.blockStore.BlockdetailToBlockBody(blockDetail))
+	if err != nil {
+		panic(err)
+	}
+	batch.Set(calcArchiveKey(height), loc.encode())
+
+	bodykvs, err := delBlockBodyTable(chain.blockStore.db, height, hash)
+	if err != nil {
+		panic(err)
+	}
+	receiptkvs, err := delBlockReceiptTable(chain.blockStore.db, height, hash)
+	if err != nil {
+		panic(err)
+	}
+	for _, kv := range append(bodykvs, receiptkvs...) {
+		if kv.GetValue() == nil {
+			batch.Delete(kv.GetKey())
+		}
+	}
+	for index, tx := range block.Txs {
+		txresult := &types.TxResult{
+			Height:     height,
+			Index:      int32(index),
+			Blocktime:  block.BlockTime,
+			ActionName: tx.ActionName(),
+		}
+		batch.Set(cfg.CalcTxKey(tx.Hash()), types.Encode(txresult))
+	}
+}
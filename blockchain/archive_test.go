@@ -0,0 +1,80 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveStoreCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // clean up
+
+	db := dbm.NewDB("blockchain", "leveldb", dir, 100)
+	defer db.Close()
+	s, err := newArchiveStore(db, dir+"/archive", 256)
+	require.Nil(t, err)
+
+	newBody := func(height int64) *types.BlockBody {
+		return &types.BlockBody{
+			Txs:      []*types.Transaction{{Execer: []byte("coins"), Nonce: height}},
+			Receipts: []*types.ReceiptData{{Ty: types.ExecOk}},
+			Hash:     []byte{byte(height)},
+			Height:   height,
+		}
+	}
+	for i := int64(0); i < 10; i++ {
+		//模拟中断后重复归档, 第一次写入的记录成为无效记录
+		_, err = s.append(newBody(i))
+		require.Nil(t, err)
+		loc, err := s.append(newBody(i))
+		require.Nil(t, err)
+		require.Nil(t, db.Set(calcArchiveKey(i), loc.encode()))
+	}
+	require.Nil(t, s.sync())
+	ids, err := s.segments()
+	require.Nil(t, err)
+	require.True(t, len(ids) > 1)
+
+	_, err = s.get(10)
+	require.Equal(t, errArchiveNotFound, err)
+	body, err := s.get(3)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), body.Txs[0].Nonce)
+
+	require.Nil(t, s.compact())
+	newIds, err := s.segments()
+	require.Nil(t, err)
+	require.True(t, newIds[0] > ids[len(ids)-1])
+	require.True(t, len(newIds) < len(ids))
+	for i := int64(0); i < 10; i++ {
+		body, err = s.get(i)
+		require.Nil(t, err)
+		require.Equal(t, i, body.Height)
+		require.Equal(t, int32(types.ExecOk), body.Receipts[0].Ty)
+	}
+	s.close()
+
+	//重新打开后继续追加
+	s, err = newArchiveStore(db, dir+"/archive", 256)
+	require.Nil(t, err)
+	defer s.close()
+	loc, err := s.append(newBody(10))
+	require.Nil(t, err)
+	require.Nil(t, db.Set(calcArchiveKey(10), loc.encode()))
+	body, err = s.get(10)
+	require.Nil(t, err)
+	require.Equal(t, int64(10), body.Height)
+	body, err = s.get(0)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), body.Height)
+}
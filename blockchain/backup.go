@@ -0,0 +1,472 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/types"
+)
+
+const (
+	backupVersion      = 1
+	backupManifestName = "MANIFEST"
+	backupArchiveDir   = "archive"
+	//获取快照时各模块的应答超时, 超时之后放弃本次备份并恢复区块写入
+	backupBeginTimeout = 30 * time.Second
+)
+
+//backupModules 除blockchain之外需要备份数据库的模块, 备份目录下按模块名保存
+var backupModules = []string{"store", "wallet", "p2p"}
+
+func (chain *BlockChain) backupDB(msg *queue.Message) {
+	req := (msg.Data).(*types.ReqBackupDB)
+	manifest, err := chain.BackupDB(req)
+	if err != nil {
+		msg.Reply(chain.client.NewMessage("rpc", types.EventBackupDB, err))
+		return
+	}
+	msg.Reply(chain.client.NewMessage("rpc", types.EventBackupDB, manifest))
+}
+
+//BackupDB 在区块边界给blockchain, store, wallet, p2p的数据库做一致性备份
+//获取快照期间持有chainLock暂停区块的执行和写入, 全部模块获取快照之后恢复写入, 再把快照写入备份目录;
+//不支持快照的数据库在持有chainLock期间直接复制, 暂停时间随数据库大小增长.
+//wallet和p2p的数据不依赖于区块, 只保证和其他模块同时获取快照
+func (chain *BlockChain) BackupDB(req *types.ReqBackupDB) (*types.BackupManifest, error) {
+	if !atomic.CompareAndSwapInt32(&chain.backingUp, 0, 1) {
+		return nil, types.ErrBackupInProgress
+	}
+	defer atomic.StoreInt32(&chain.backingUp, 0)
+
+	dir, err := prepareBackupDir(req.GetDir())
+	if err != nil {
+		chainlog.Error("BackupDB", "dir", req.GetDir(), "err", err)
+		return nil, err
+	}
+	manifest, err := chain.backupTo(dir)
+	if err != nil {
+		chainlog.Error("BackupDB", "dir", dir, "err", err)
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	chainlog.Info("BackupDB", "dir", dir, "height", manifest.Height, "pause(ms)", manifest.PauseTime)
+	return manifest, nil
+}
+
+func (chain *BlockChain) backupTo(dir string) (*types.BackupManifest, error) {
+	manifest := &types.BackupManifest{
+		Version:    backupVersion,
+		Title:      chain.client.GetConfig().GetTitle(),
+		CreateTime: types.Now().Unix(),
+	}
+	var begun []string
+	var segs []archiveSegment
+	//没有获取快照, 需要在暂停写入期间直接复制的模块
+	direct := make(map[string]bool)
+
+	chain.chainLock.Lock()
+	beg := types.Now()
+	header := chain.blockStore.LastHeader()
+	manifest.Height = header.Height
+	manifest.BlockHash = header.Hash
+	manifest.StateHash = header.StateHash
+	err := chain.backup.Begin(filepath.Join(dir, "blockchain"))
+	if err == nil {
+		begun = append(begun, "blockchain")
+		direct["blockchain"] = !chain.backup.Snapshot()
+		for _, module := range backupModules {
+			var info *types.BackupDBInfo
+			//先加入begun再等待应答, 应答超时或者出错时也要发送abort,
+			//否则模块处理完迟到的begin之后一直处于备份中, 之后的备份都会失败
+			begun = append(begun, module)
+			req := &types.ReqSnapshotDB{Op: dbm.BackupBegin, Dir: filepath.Join(dir, module)}
+			info, err = chain.sendSnapshotDB(module, req, backupBeginTimeout)
+			if err != nil {
+				chainlog.Error("backupDB begin", "module", module, "err", err)
+				break
+			}
+			if info == nil {
+				begun = begun[:len(begun)-1]
+			} else {
+				direct[module] = !info.Snapshot
+			}
+		}
+	}
+	if err == nil && chain.blockStore.archive != nil {
+		segs, err = chain.blockStore.archive.snapshot()
+	}
+	infos := make(map[string]*types.BackupDBInfo)
+	for _, module := range begun {
+		if err != nil {
+			break
+		}
+		if direct[module] {
+			infos[module], err = chain.writeBackup(dir, module)
+		}
+	}
+	chain.chainLock.Unlock()
+	manifest.PauseTime = int64(types.Since(beg) / time.Millisecond)
+	if err != nil {
+		chain.abortBackup(begun)
+		return nil, err
+	}
+
+	for _, module := range begun {
+		info, ok := infos[module]
+		if !ok {
+			info, err = chain.writeBackup(dir, module)
+			if err != nil {
+				//已经写入的模块abort时不做任何操作
+				chain.abortBackup(begun)
+				return nil, err
+			}
+		}
+		manifest.Dbs = append(manifest.Dbs, info)
+	}
+	if chain.blockStore.archive != nil {
+		manifest.ArchiveSegments = int32(len(segs))
+		manifest.ArchiveSize, err = chain.blockStore.archive.backup(filepath.Join(dir, "blockchain", backupArchiveDir), segs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = writeBackupManifest(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+//writeBackup 把模块的快照写入备份目录, 没有快照的模块直接复制数据库
+func (chain *BlockChain) writeBackup(dir, module string) (*types.BackupDBInfo, error) {
+	var info *types.BackupDBInfo
+	var err error
+	if module == "blockchain" {
+		info, err = chain.backup.Write()
+	} else {
+		info, err = chain.sendSnapshotDB(module, &types.ReqSnapshotDB{Op: dbm.BackupWrite}, 0)
+	}
+	if err == nil && info == nil {
+		err = types.ErrTypeAsset
+	}
+	if err != nil {
+		chainlog.Error("backupDB write", "module", module, "err", err)
+		return nil, err
+	}
+	info.Module = module
+	info.Path, err = filepath.Rel(dir, info.Path)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (chain *BlockChain) abortBackup(modules []string) {
+	for _, module := range modules {
+		if module == "blockchain" {
+			chain.backup.Abort()
+			continue
+		}
+		_, err := chain.sendSnapshotDB(module, &types.ReqSnapshotDB{Op: dbm.BackupAbort}, backupBeginTimeout)
+		if err != nil {
+			chainlog.Error("abortBackup", "module", module, "err", err)
+		}
+	}
+}
+
+//sendSnapshotDB 发送快照请求, 模块没有启用时(例如关闭p2p时的mock模块)返回nil
+func (chain *BlockChain) sendSnapshotDB(module string, req *types.ReqSnapshotDB, timeout time.Duration) (*types.BackupDBInfo, error) {
+	msg := chain.client.NewMessage(module, types.EventSnapshotDB, req)
+	err := chain.client.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := chain.client.WaitTimeout(msg, timeout)
+	if err != nil {
+		return nil, err
+	}
+	switch reply := resp.GetData().(type) {
+	case *types.BackupDBInfo:
+		return reply, nil
+	case *types.Reply:
+		if !reply.GetIsOk() {
+			return nil, nil
+		}
+	}
+	return nil, types.ErrTypeAsset
+}
+
+//prepareBackupDir 备份目录必须不存在或者为空
+func prepareBackupDir(dir string) (string, error) {
+	if dir == "" {
+		return "", types.ErrInvalidParam
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err == nil && len(infos) > 0 {
+		return "", types.ErrFileExists
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+func writeBackupManifest(dir string, manifest *types.BackupManifest) error {
+	data, err := types.PBToJSON(manifest)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, backupManifestName+".tmp")
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, backupManifestName))
+}
+
+//LoadBackupManifest 读取备份目录中的清单
+func LoadBackupManifest(dir string) (*types.BackupManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &types.BackupManifest{}
+	err = types.JSONToPB(data, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+//backupTarget 备份中的数据库恢复到本节点配置的目录, 返回目标目录和配置的数据库驱动
+func backupTarget(mcfg *types.Config, info *types.BackupDBInfo) (string, string, error) {
+	var dbPath, driver string
+	switch info.GetModule() {
+	case "blockchain":
+		dbPath, driver = mcfg.BlockChain.DbPath, mcfg.BlockChain.Driver
+	case "store":
+		dbPath, driver = mcfg.Store.DbPath, mcfg.Store.Driver
+	case "wallet":
+		dbPath, driver = mcfg.Wallet.DbPath, mcfg.Wallet.Driver
+	case "p2p":
+		dbPath, driver = mcfg.P2P.DbPath, mcfg.P2P.Driver
+	default:
+		return "", "", types.ErrBackupCorrupt
+	}
+	//清单中的路径为模块名加上模块DbPath下的子目录
+	rel := filepath.Clean(info.GetPath())
+	if rel != info.GetModule() && !strings.HasPrefix(rel, info.GetModule()+string(filepath.Separator)) {
+		return "", "", types.ErrBackupCorrupt
+	}
+	sub, err := filepath.Rel(info.GetModule(), rel)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dbPath, sub), driver, nil
+}
+
+//verifyBackupDB 校验备份数据库的key数目, 大小以及校验和
+func verifyBackupDB(dir string, info *types.BackupDBInfo) error {
+	path := filepath.Join(dir, info.GetPath())
+	db, err := dbm.NewReadOnlyDB(info.GetName(), info.GetDriver(), path, 64)
+	if err == types.ErrNotSupport {
+		db = dbm.NewDB(info.GetName(), info.GetDriver(), path, 64)
+	} else if err != nil {
+		return err
+	}
+	defer db.Close()
+	sum, err := dbm.ChecksumDB(db)
+	if err != nil {
+		return err
+	}
+	if sum.Count != info.GetCount() || sum.Size != info.GetSize() || !bytes.Equal(sum.Checksum, info.GetChecksum()) {
+		chainlog.Error("verifyBackupDB", "module", info.GetModule(), "name", info.GetName(),
+			"count", sum.Count, "expect", info.GetCount(), "size", sum.Size, "expect", info.GetSize())
+		return types.ErrBackupCorrupt
+	}
+	return nil
+}
+
+//verifyBackupTip 备份的blockchain数据库中最新区块需要和清单一致
+func verifyBackupTip(dir string, manifest *types.BackupManifest) error {
+	for _, info := range manifest.GetDbs() {
+		if info.GetModule() != "blockchain" {
+			continue
+		}
+		path := filepath.Join(dir, info.GetPath())
+		db, err := dbm.NewReadOnlyDB(info.GetName(), info.GetDriver(), path, 64)
+		if err == types.ErrNotSupport {
+			db = dbm.NewDB(info.GetName(), info.GetDriver(), path, 64)
+		} else if err != nil {
+			return err
+		}
+		defer db.Close()
+		header, err := LoadLastHeader(db)
+		if err != nil {
+			return err
+		}
+		if header.Height != manifest.GetHeight() || !bytes.Equal(header.Hash, manifest.GetBlockHash()) ||
+			!bytes.Equal(header.StateHash, manifest.GetStateHash()) {
+			chainlog.Error("verifyBackupTip", "height", header.Height, "expect", manifest.GetHeight(),
+				"hash", common.ToHex(header.Hash), "expect", common.ToHex(manifest.GetBlockHash()))
+			return types.ErrBackupCorrupt
+		}
+		return nil
+	}
+	chainlog.Error("verifyBackupTip", "err", "blockchain db not in manifest")
+	return types.ErrBackupCorrupt
+}
+
+//RestoreBackup 从备份目录恢复全部模块的数据库, 需在节点启动之前执行
+//恢复之前校验清单中的每个数据库以及备份的最新区块, 本节点已经存在的数据库不会被覆盖
+func RestoreBackup(cfg *types.Chain33Config, dir string) (*types.BackupManifest, error) {
+	manifest, err := LoadBackupManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.GetVersion() != backupVersion {
+		chainlog.Error("RestoreBackup", "version", manifest.GetVersion())
+		return nil, types.ErrBackupCorrupt
+	}
+	if manifest.GetTitle() != cfg.GetTitle() {
+		chainlog.Error("RestoreBackup", "title", manifest.GetTitle(), "expect", cfg.GetTitle())
+		return nil, types.ErrInvalidParam
+	}
+	mcfg := cfg.GetModuleConfig()
+	targets := make([]string, len(manifest.GetDbs()))
+	for i, info := range manifest.GetDbs() {
+		target, driver, err := backupTarget(mcfg, info)
+		if err != nil {
+			return nil, err
+		}
+		if driver != info.GetDriver() {
+			chainlog.Error("RestoreBackup", "module", info.GetModule(), "driver", info.GetDriver(), "expect", driver)
+			return nil, types.ErrInvalidParam
+		}
+		targets[i], err = dbm.LocalPath(info.GetName(), driver, target)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(targets[i]); err == nil {
+			chainlog.Error("RestoreBackup", "module", info.GetModule(), "exist", targets[i])
+			return nil, types.ErrFileExists
+		}
+		err = verifyBackupDB(dir, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = verifyBackupTip(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	archiveSrc := filepath.Join(dir, "blockchain", backupArchiveDir)
+	if manifest.GetArchiveSegments() > 0 {
+		err = verifyBackupArchive(archiveSrc, archiveDir(mcfg.BlockChain), manifest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, info := range manifest.GetDbs() {
+		src, err := dbm.LocalPath(info.GetName(), info.GetDriver(), filepath.Join(dir, info.GetPath()))
+		if err != nil {
+			return nil, err
+		}
+		err = copyDir(src, targets[i])
+		if err != nil {
+			return nil, err
+		}
+		chainlog.Info("RestoreBackup", "module", info.GetModule(), "name", info.GetName(), "to", targets[i])
+	}
+	if manifest.GetArchiveSegments() > 0 {
+		err = copyDir(archiveSrc, archiveDir(mcfg.BlockChain))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+//verifyBackupArchive 校验归档段文件的数目和大小, 目标目录中不能已经有段文件
+func verifyBackupArchive(src, dst string, manifest *types.BackupManifest) error {
+	backup := &archiveStore{dir: src}
+	ids, err := backup.segments()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, id := range ids {
+		info, err := os.Stat(filepath.Join(src, archiveSegmentName(id)))
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+	}
+	if len(ids) != int(manifest.GetArchiveSegments()) || size != manifest.GetArchiveSize() {
+		chainlog.Error("verifyBackupArchive", "segments", len(ids), "size", size)
+		return types.ErrBackupCorrupt
+	}
+	ids, err = (&archiveStore{dir: dst}).segments()
+	if err == nil && len(ids) > 0 {
+		chainlog.Error("verifyBackupArchive", "exist", dst)
+		return types.ErrFileExists
+	}
+	return nil
+}
+
+//copyDir 复制目录下的全部文件
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target, info.Size())
+	})
+}
+
+//copyFile 复制文件的前size个字节并刷盘
+func copyFile(src, dst string, size int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(out, in, size)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/33cn/chain33/blockchain"
+	dbm "github.com/33cn/chain33/common/db"
+	_ "github.com/33cn/chain33/system"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	"github.com/33cn/chain33/util/testnode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestoreDB(t *testing.T) {
+	mock33 := testnode.New("", nil)
+	defer mock33.Close()
+	mock33.Listen()
+	require.Nil(t, mock33.SendHot())
+	require.Nil(t, mock33.WaitHeight(1))
+
+	dir, err := ioutil.TempDir("", "backup")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	backupDir := filepath.Join(dir, "backup")
+
+	api := mock33.GetAPI()
+	_, err = api.BackupDB(&types.ReqBackupDB{})
+	assert.Equal(t, types.ErrInvalidParam, err)
+	manifest, err := api.BackupDB(&types.ReqBackupDB{Dir: backupDir})
+	require.Nil(t, err)
+	last := mock33.GetLastBlock()
+	cfg := mock33.GetClient().GetConfig()
+	assert.Equal(t, last.Height, manifest.Height)
+	assert.Equal(t, last.Hash(cfg), manifest.BlockHash)
+	assert.Equal(t, last.StateHash, manifest.StateHash)
+	//mock p2p没有数据库
+	require.Equal(t, 3, len(manifest.Dbs))
+	for i, module := range []string{"blockchain", "store", "wallet"} {
+		assert.Equal(t, module, manifest.Dbs[i].Module)
+		assert.Equal(t, module, manifest.Dbs[i].Path)
+		assert.True(t, manifest.Dbs[i].Snapshot)
+		assert.True(t, manifest.Dbs[i].Count > 0)
+	}
+	//备份目录不为空
+	_, err = api.BackupDB(&types.ReqBackupDB{Dir: backupDir})
+	assert.Equal(t, types.ErrFileExists, err)
+
+	//备份之后继续出块
+	require.Nil(t, mock33.SendHot())
+	require.Nil(t, mock33.WaitHeight(last.Height+1))
+
+	loaded, err := blockchain.LoadBackupManifest(backupDir)
+	require.Nil(t, err)
+	assert.Equal(t, manifest.String(), loaded.String())
+
+	restoreCfg := testnode.GetDefaultConfig()
+	util.ResetDatadir(restoreCfg.GetModuleConfig(), filepath.Join(dir, "restore"))
+	restored, err := blockchain.RestoreBackup(restoreCfg, backupDir)
+	require.Nil(t, err)
+	assert.Equal(t, manifest.Height, restored.Height)
+
+	mcfg := restoreCfg.GetModuleConfig()
+	db := dbm.NewDB("blockchain", mcfg.BlockChain.Driver, mcfg.BlockChain.DbPath, 64)
+	header, err := blockchain.LoadLastHeader(db)
+	db.Close()
+	require.Nil(t, err)
+	assert.Equal(t, manifest.BlockHash, header.Hash)
+
+	//已经存在的数据库不会被覆盖
+	_, err = blockchain.RestoreBackup(restoreCfg, backupDir)
+	assert.Equal(t, types.ErrFileExists, err)
+
+	//清单和备份数据不一致
+	manifest.Dbs[1].Count++
+	data, err := types.PBToJSON(manifest)
+	require.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(backupDir, "MANIFEST"), data, 0644))
+	restoreCfg = testnode.GetDefaultConfig()
+	util.ResetDatadir(restoreCfg.GetModuleConfig(), filepath.Join(dir, "restore2"))
+	_, err = blockchain.RestoreBackup(restoreCfg, backupDir)
+	assert.Equal(t, types.ErrBackupCorrupt, err)
+}
+
+func TestBackupDBAbortFailedModule(t *testing.T) {
+	mock33 := testnode.New("", nil)
+	defer mock33.Close()
+	require.Nil(t, mock33.WaitHeight(0))
+
+	dir, err := ioutil.TempDir("", "backup")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	//wallet已经处于备份中, 获取快照失败
+	client := mock33.GetClient()
+	msg := client.NewMessage("wallet", types.EventSnapshotDB, &types.ReqSnapshotDB{Op: dbm.BackupBegin, Dir: filepath.Join(dir, "other")})
+	require.Nil(t, client.Send(msg, true))
+	_, err = client.Wait(msg)
+	require.Nil(t, err)
+
+	api := mock33.GetAPI()
+	_, err = api.BackupDB(&types.ReqBackupDB{Dir: filepath.Join(dir, "backup1")})
+	assert.Equal(t, types.ErrBackupInProgress, err)
+	//失败的模块同样被abort, 之后的备份可以正常进行
+	manifest, err := api.BackupDB(&types.ReqBackupDB{Dir: filepath.Join(dir, "backup2")})
+	require.Nil(t, err)
+	assert.Equal(t, 3, len(manifest.Dbs))
+}
@@ -17,6 +17,7 @@ import (
 	dbm "github.com/33cn/chain33/common/db"
 	"github.com/33cn/chain33/common/difficulty"
 	"github.com/33cn/chain33/common/version"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/types"
 	"github.com/golang/protobuf/proto"
@@ -135,6 +136,8 @@ type BlockStore struct {
 	lastheaderlock sync.Mutex
 	saveSequence   bool
 	isParaChain    bool
+	//区块归档存储, 未使能归档时为nil
+	archive *archiveStore
 }
 
 //NewBlockStore new
@@ -380,12 +383,14 @@ func (bs *BlockStore) UpdateHeight() {
 		return
 	}
 	atomic.StoreInt64(&bs.height, height)
+	metrics.BlockHeight.Update(height)
 	storeLog.Debug("UpdateHeight", "curblockheight", height)
 }
 
 //UpdateHeight2 更新指定的block高度到BlockStore.Height
 func (bs *BlockStore) UpdateHeight2(height int64) {
 	atomic.StoreInt64(&bs.height, height)
+	metrics.BlockHeight.Update(height)
 	storeLog.Debug("UpdateHeight2", "curblockheight", height)
 }
 
@@ -616,10 +621,10 @@ func (bs *BlockStore) GetTx(hash []byte) (*types.TxResult, error) {
 
 func (bs *BlockStore) getRealTxResult(txr *types.TxResult) *types.TxResult {
 	cfg := bs.client.GetConfig()
-	if !cfg.IsEnable("reduceLocaldb") {
+	if !cfg.IsEnable("reduceLocaldb") && (bs.archive == nil || txr.GetTx() != nil) {
 		return txr
 	}
-	// 如果是精简版的localdb 则需要从block中获取tx交易内容以及receipt
+	// 如果是精简版的localdb或者区块已归档 则需要从block中获取tx交易内容以及receipt
 	blockinfo, err := bs.LoadBlockByHeight(txr.Height)
 	if err != nil {
 		chainlog.Error("getRealTxResult LoadBlockByHeight", "height", txr.Height, "error", err)
@@ -808,6 +813,21 @@ func LoadBlockStoreHeight(db dbm.DB) (int64, error) {
 	return decodeHeight(bytes)
 }
 
+//LoadLastHeader 加载数据库中最新的区块头, 用于节点停止时的离线工具
+func LoadLastHeader(db dbm.DB) (*types.Header, error) {
+	height, err := LoadBlockStoreHeight(db)
+	if err != nil {
+		return nil, err
+	}
+	return LoadHeaderByHeight(db, height)
+}
+
+//LoadHeaderByHeight 从数据库中加载指定高度的主链区块头, 用于节点停止时的离线工具
+func LoadHeaderByHeight(db dbm.DB, height int64) (*types.Header, error) {
+	bs := &BlockStore{db: db}
+	return bs.GetBlockHeaderByHeight(height)
+}
+
 // 将收到的block都暂时存储到db中，加入主链之后会重新覆盖。主要是用于chain重组时获取侧链的block使用
 func (bs *BlockStore) dbMaybeStoreBlock(blockdetail *types.BlockDetail, sync bool) error {
 	if blockdetail == nil {
@@ -1336,6 +1356,14 @@ func (bs *BlockStore) loadBlockByIndex(indexName string, prefix []byte, primaryK
 
 	//获取body
 	blockbody, err := getBodyByIndex(bs.db, indexName, prefix, primaryKey)
+	archived := false
+	if (blockbody == nil || err != nil) && bs.archive != nil {
+		// 数据库中没有body时从归档段文件中获取, 归档的body包含完整的receipt
+		body, archiveErr := bs.archive.get(blockheader.Height)
+		if archiveErr == nil && bytes.Equal(body.Hash, blockheader.Hash) {
+			blockbody, err, archived = body, nil, true
+		}
+	}
 	if blockbody == nil || err != nil {
 		if err != dbm.ErrNotFoundInDb {
 			storeLog.Error("loadBlockByIndex:getBodyByIndex", "indexName", indexName, "prefix", prefix, "primaryKey", primaryKey, "err", err)
@@ -1345,7 +1373,7 @@ func (bs *BlockStore) loadBlockByIndex(indexName string, prefix []byte, primaryK
 
 	blockreceipt := blockbody.Receipts
 	// 非精简节点查询时候需要在ReceiptTable中获取详细的receipt信息, 精简情况下可以获取未精简部分receipt
-	if !cfg.IsEnable("reduceLocaldb") || bs.Height() < blockheader.Height+ReduceHeight {
+	if !archived && (!cfg.IsEnable("reduceLocaldb") || bs.Height() < blockheader.Height+ReduceHeight) {
 		receipt, err := getReceiptByIndex(bs.db, indexName, prefix, primaryKey)
 		if receipt != nil {
 			blockreceipt = receipt.Receipts
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/types"
 )
 
@@ -152,7 +153,10 @@ func (chain *BlockChain) SynRoutine() {
 	checkBestChainTicker := time.NewTicker(120 * time.Second)
 
 	//节点启动后首先尝试开启快速下载模式,目前默认开启
-	if chain.GetDownloadSyncStatus() {
+	//使能区块头优先同步时不使用快速下载模式
+	if chain.headerSync != nil {
+		chain.UpdateDownloadSyncStatus(false)
+	} else if chain.GetDownloadSyncStatus() {
 		go chain.FastDownLoadBlocks()
 	}
 	for {
@@ -162,7 +166,8 @@ func (chain *BlockChain) SynRoutine() {
 			return
 		case <-blockSynTicker.C:
 			//synlog.Info("blockSynTicker")
-			if !chain.GetDownloadSyncStatus() {
+			//落后较多时使用区块头优先同步
+			if !chain.GetDownloadSyncStatus() && !chain.headerSync.tick() {
 				go chain.SynBlocksFromPeers()
 			}
 
@@ -315,6 +320,14 @@ func (chain *BlockChain) fetchPeerList() error {
 	}
 	curheigt := chain.GetBlockHeight()
 
+	var peerCount int64
+	for _, peer := range peerlist.Peers {
+		if !peer.Self {
+			peerCount++
+		}
+	}
+	metrics.PeerCount.Update(peerCount)
+
 	var peerInfoList PeerInfoList
 	for _, peer := range peerlist.Peers {
 		//chainlog.Info("fetchPeerList", "peername:", peer.Name, "peerHeight:", peer.Header.Height)
@@ -522,6 +535,7 @@ func (chain *BlockChain) RecoveryFaultPeer() {
 			if bytes.Equal(faultpeer.FaultHash, blockhash) {
 				synlog.Debug("RecoveryFaultPeer ", "Height", faultpeer.FaultHeight, "FaultHash", common.ToHex(faultpeer.FaultHash), "pid", pid)
 				delete(chain.faultPeerList, pid)
+				metrics.FaultPeerCount.Update(int64(len(chain.faultPeerList)))
 				continue
 			}
 		}
@@ -545,6 +559,7 @@ func (chain *BlockChain) AddFaultPeer(faultpeer *FaultPeerInfo) {
 		synlog.Debug("AddFaultPeer old", "pid", faultnode.Peer.Name, "FaultHeight", faultnode.FaultHeight, "FaultHash", common.ToHex(faultnode.FaultHash), "Err", faultnode.ErrInfo)
 	}
 	chain.faultPeerList[faultpeer.Peer.Name] = faultpeer
+	metrics.FaultPeerCount.Update(int64(len(chain.faultPeerList)))
 	synlog.Debug("AddFaultPeer new", "pid", faultpeer.Peer.Name, "FaultHeight", faultpeer.FaultHeight, "FaultHash", common.ToHex(faultpeer.FaultHash), "Err", faultpeer.ErrInfo)
 }
 
@@ -555,6 +570,7 @@ func (chain *BlockChain) RemoveFaultPeer(pid string) {
 	synlog.Debug("RemoveFaultPeer", "pid", pid)
 
 	delete(chain.faultPeerList, pid)
+	metrics.FaultPeerCount.Update(int64(len(chain.faultPeerList)))
 }
 
 //UpdateFaultPeer 更新此故障peer的请求标志位
@@ -585,6 +601,22 @@ func (chain *BlockChain) RecordFaultPeer(pid string, height int64, hash []byte,
 	faultnode.ErrInfo = err
 	faultnode.ReqFlag = false
 	chain.AddFaultPeer(&faultnode)
+	chain.reportFaultPeer(pid, types.PeerFaultInvalidBlock, err)
+}
+
+//reportFaultPeer 通知p2p模块对提供出错block或者区块头的peer扣分，评分过低的peer会被断开连接并封禁, ty参考types.PeerFault*
+func (chain *BlockChain) reportFaultPeer(pid string, ty int32, err error) {
+	if chain.client == nil {
+		return
+	}
+	report := &types.PeerFaultReport{Pid: pid, Ty: ty}
+	if err != nil {
+		report.ErrInfo = err.Error()
+	}
+	msg := chain.client.NewMessage("p2p", types.EventReportFaultPeer, report)
+	if err := chain.client.Send(msg, false); err != nil {
+		synlog.Error("reportFaultPeer", "pid", pid, "err", err)
+	}
 }
 
 //SynBlocksFromPeers blockSynSeconds时间检测一次本节点的height是否有增长，没有增长就需要通过对端peerlist获取最新高度，发起同步
@@ -601,6 +633,11 @@ func (chain *BlockChain) SynBlocksFromPeers() {
 		atomic.CompareAndSwapInt32(&chain.isbatchsync, 0, 1)
 	}
 
+	//区块头优先同步进行中
+	if chain.headerSync.isRunning() {
+		synlog.Info("chain headerSync InProgress")
+		return
+	}
 	//如果任务正常，那么不重复启动任务
 	if chain.syncTask.InProgress() {
 		synlog.Info("chain syncTask InProgress")
@@ -794,6 +831,11 @@ func (chain *BlockChain) ProcBlockHeaders(headers *types.Headers, pid string) er
 		return types.ErrContinueBack
 	}
 	synlog.Info("ProcBlockHeaders find fork point", "height", ForkHeight, "hash", common.ToHex(forkhash))
+	//分叉点低于已经越过的检查点，不再回滚
+	if err = chain.checkpoints.checkFork(ForkHeight, tipheight); err != nil {
+		chain.reportFaultPeer(pid, types.PeerFaultInvalidHeader, err)
+		return err
+	}
 
 	//获取此pid对应的peer信息，
 	peerinfo := chain.GetPeerInfo(pid)
@@ -834,6 +876,10 @@ func (chain *BlockChain) ProcAddBlockHeadersMsg(headers *types.Headers, pid stri
 	}
 	count := len(headers.Items)
 	synlog.Debug("ProcAddBlockHeadersMsg", "count", count, "pid", pid)
+	//区块头优先同步请求的headers
+	if chain.headerSync.onHeaders(headers, pid) {
+		return nil
+	}
 	if count == 1 {
 		return chain.ProcBlockHeader(headers, pid)
 	}
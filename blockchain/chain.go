@@ -15,6 +15,7 @@ import (
 	"github.com/33cn/chain33/common"
 	dbm "github.com/33cn/chain33/common/db"
 	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/common/trace"
 	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/types"
 	lru "github.com/hashicorp/golang-lru"
@@ -74,8 +75,13 @@ type BlockChain struct {
 	bestChain *chainView
 
 	chainLock sync.RWMutex
+	//当前正在处理的区块的span, 只在持有chainLock时访问
+	procSpan *trace.Span
 	//blockchain的启动时间
 	startTime time.Time
+	//在线备份blockchain数据库, backingUp标记是否有正在进行的备份
+	backup    *dbm.Backuper
+	backingUp int32
 
 	//标记本节点是否已经追赶上主链
 	isCaughtUp bool
@@ -120,6 +126,11 @@ type BlockChain struct {
 
 	blockOnChain   *BlockOnChain
 	onChainTimeout int64
+
+	//检查点, 拒绝检查点以下的分叉
+	checkpoints *checkpoints
+	//先同步区块头再并行下载区块体, 未使能时为nil
+	headerSync *headerSync
 }
 
 //New new
@@ -189,11 +200,18 @@ func (chain *BlockChain) initConfig(cfg *types.Chain33Config) {
 	chain.isParaChain = mcfg.IsParaChain
 	cfg.S("quickIndex", mcfg.EnableTxQuickIndex)
 	cfg.S("reduceLocaldb", mcfg.EnableReduceLocaldb)
+	if mcfg.EnableArchive && mcfg.EnableReduceLocaldb {
+		panic("enableArchive and enableReduceLocaldb can not be enabled at the same time")
+	}
 
 	if mcfg.OnChainTimeout > 0 {
 		chain.onChainTimeout = mcfg.OnChainTimeout
 	}
 	chain.initOnChainTimeout()
+	chain.checkpoints = newCheckpoints(cfg.GetTitle(), mcfg.Checkpoints)
+	if mcfg.HeaderFirstSync {
+		chain.headerSync = newHeaderSync(chain)
+	}
 }
 
 //Close 关闭区块链
@@ -220,6 +238,9 @@ func (chain *BlockChain) Close() {
 	//wait for reducewg quit:
 	chainlog.Info("blockchain wait for reducewg quit")
 	chain.reducewg.Wait()
+	if chain.blockStore.archive != nil {
+		chain.blockStore.archive.close()
+	}
 
 	//关闭数据库
 	chain.blockStore.db.Close()
@@ -231,9 +252,13 @@ func (chain *BlockChain) SetQueueClient(client queue.Client) {
 	chain.client = client
 	chain.client.Sub("blockchain")
 
-	blockStoreDB := dbm.NewDB("blockchain", chain.cfg.Driver, chain.cfg.DbPath, chain.cfg.DbCache)
+	db := dbm.NewDB("blockchain", chain.cfg.Driver, chain.cfg.DbPath, chain.cfg.DbCache)
+	//备份需要数据库本身的快照接口, 使用没有缓存的db
+	chain.backup = dbm.NewBackuper(db, "blockchain", chain.cfg.Driver)
+	blockStoreDB := dbm.WrapCachedDB(db, "blockchain", chain.cfg.ReadCacheSize)
 	blockStore := NewBlockStore(chain, blockStoreDB, client)
 	chain.blockStore = blockStore
+	chain.initArchive()
 	stateHash := chain.getStateHash()
 	chain.query = NewQuery(blockStoreDB, chain.client, stateHash)
 
@@ -319,7 +344,12 @@ func (chain *BlockChain) getStateHash() []byte {
 
 //SendAddBlockEvent blockchain 模块add block到db之后通知mempool 和consense模块做相应的更新
 func (chain *BlockChain) SendAddBlockEvent(block *types.BlockDetail) (err error) {
-	if chain.client == nil {
+	return chain.sendAddBlockEvent(chain.client, block)
+}
+
+//sendAddBlockEvent 通过client发送, 用于携带区块处理的span上下文
+func (chain *BlockChain) sendAddBlockEvent(client queue.Client, block *types.BlockDetail) (err error) {
+	if client == nil {
 		chainlog.Error("SendAddBlockEvent: chain client not bind message queue.")
 		return types.ErrClientNotBindQueue
 	}
@@ -330,21 +360,21 @@ func (chain *BlockChain) SendAddBlockEvent(block *types.BlockDetail) (err error)
 	chainlog.Debug("SendAddBlockEvent", "Height", block.Block.Height)
 
 	chainlog.Debug("SendAddBlockEvent -->>mempool")
-	msg := chain.client.NewMessage("mempool", types.EventAddBlock, block)
-	Err := chain.client.Send(msg, false)
+	msg := client.NewMessage("mempool", types.EventAddBlock, block)
+	Err := client.Send(msg, false)
 	if Err != nil {
 		chainlog.Error("SendAddBlockEvent -->>mempool", "err", Err)
 	}
 	chainlog.Debug("SendAddBlockEvent -->>consensus")
 
-	msg = chain.client.NewMessage("consensus", types.EventAddBlock, block)
-	Err = chain.client.Send(msg, false)
+	msg = client.NewMessage("consensus", types.EventAddBlock, block)
+	Err = client.Send(msg, false)
 	if Err != nil {
 		chainlog.Error("SendAddBlockEvent -->>consensus", "err", Err)
 	}
 	chainlog.Debug("SendAddBlockEvent -->>wallet", "height", block.GetBlock().GetHeight())
-	msg = chain.client.NewMessage("wallet", types.EventAddBlock, block)
-	Err = chain.client.Send(msg, false)
+	msg = client.NewMessage("wallet", types.EventAddBlock, block)
+	Err = client.Send(msg, false)
 	if Err != nil {
 		chainlog.Error("SendAddBlockEvent -->>wallet", "err", Err)
 	}
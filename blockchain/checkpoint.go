@@ -0,0 +1,97 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+)
+
+//各条链内置的检查点, key为链的title
+var (
+	checkpointsLock    sync.Mutex
+	builtinCheckpoints = make(map[string]map[int64]string)
+)
+
+//RegisterCheckpoints 注册指定title链的内置检查点, 一般在init中调用, 配置文件中相同高度的检查点优先
+func RegisterCheckpoints(title string, points map[int64]string) {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+	if builtinCheckpoints[title] == nil {
+		builtinCheckpoints[title] = make(map[int64]string)
+	}
+	for height, hash := range points {
+		builtinCheckpoints[title][height] = hash
+	}
+}
+
+//checkpoints 检查点, 检查点高度的区块哈希必须一致, 已经越过的检查点以下不允许分叉
+type checkpoints struct {
+	points  map[int64][]byte
+	heights []int64 //升序排列
+}
+
+//newCheckpoints 合并内置检查点和配置的检查点, 配置格式错误时panic
+func newCheckpoints(title string, cfg map[string]string) *checkpoints {
+	cp := &checkpoints{points: make(map[int64][]byte)}
+	checkpointsLock.Lock()
+	for height, hash := range builtinCheckpoints[title] {
+		cp.add(height, hash)
+	}
+	checkpointsLock.Unlock()
+	for key, hash := range cfg {
+		height, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("checkpoint height %s err %s", key, err))
+		}
+		cp.add(height, hash)
+	}
+	sort.Slice(cp.heights, func(i, j int) bool { return cp.heights[i] < cp.heights[j] })
+	return cp
+}
+
+func (cp *checkpoints) add(height int64, hash string) {
+	h, err := common.FromHex(hash)
+	if err != nil || len(h) != 32 || height < 0 {
+		panic(fmt.Sprintf("checkpoint height %d hash %s invalid", height, hash))
+	}
+	if _, ok := cp.points[height]; !ok {
+		cp.heights = append(cp.heights, height)
+	}
+	cp.points[height] = h
+}
+
+//checkHash 检查点高度的区块哈希不一致时返回错误
+func (cp *checkpoints) checkHash(height int64, hash []byte) error {
+	if point, ok := cp.points[height]; ok && !bytes.Equal(point, hash) {
+		chainlog.Error("checkHash", "height", height, "hash", common.ToHex(hash), "checkpoint", common.ToHex(point))
+		return types.ErrCheckpointMismatch
+	}
+	return nil
+}
+
+//lastCheckpoint 获取不高于height的最高检查点, 没有时返回-1
+func (cp *checkpoints) lastCheckpoint(height int64) int64 {
+	i := sort.Search(len(cp.heights), func(i int) bool { return cp.heights[i] > height })
+	if i == 0 {
+		return -1
+	}
+	return cp.heights[i-1]
+}
+
+//checkFork 主链高度为tipHeight时, 分叉点低于已经越过的检查点则拒绝
+func (cp *checkpoints) checkFork(forkHeight, tipHeight int64) error {
+	if last := cp.lastCheckpoint(tipHeight); last > forkHeight {
+		chainlog.Error("checkFork", "forkHeight", forkHeight, "tipHeight", tipHeight, "checkpoint", last)
+		return types.ErrForkBelowCheckpoint
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoints(t *testing.T) {
+	hash1 := "0x" + strings.Repeat("11", 32)
+	hash2 := "0x" + strings.Repeat("22", 32)
+	RegisterCheckpoints("user.test.checkpoint", map[int64]string{100: hash1, 300: hash1})
+	cp := newCheckpoints("user.test.checkpoint", map[string]string{"200": hash2, "300": hash2})
+	assert.Equal(t, []int64{100, 200, 300}, cp.heights)
+
+	h1, _ := common.FromHex(hash1)
+	h2, _ := common.FromHex(hash2)
+	assert.Nil(t, cp.checkHash(100, h1))
+	assert.Equal(t, types.ErrCheckpointMismatch, cp.checkHash(100, h2))
+	//配置的检查点优先
+	assert.Nil(t, cp.checkHash(300, h2))
+	assert.Nil(t, cp.checkHash(150, h2))
+
+	assert.Equal(t, int64(-1), cp.lastCheckpoint(99))
+	assert.Equal(t, int64(100), cp.lastCheckpoint(100))
+	assert.Equal(t, int64(200), cp.lastCheckpoint(299))
+	assert.Equal(t, int64(300), cp.lastCheckpoint(1000))
+
+	assert.Nil(t, cp.checkFork(50, 99))
+	assert.Nil(t, cp.checkFork(100, 150))
+	assert.Equal(t, types.ErrForkBelowCheckpoint, cp.checkFork(99, 150))
+	assert.Equal(t, types.ErrForkBelowCheckpoint, cp.checkFork(250, 300))
+
+	empty := newCheckpoints("user.test.none", nil)
+	assert.Nil(t, empty.checkFork(0, 1000))
+
+	assert.Panics(t, func() { newCheckpoints("", map[string]string{"abc": hash1}) })
+	assert.Panics(t, func() { newCheckpoints("", map[string]string{"10": "0x1234"}) })
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"os/user"
 	"path/filepath"
 	"sync/atomic"
@@ -40,7 +41,12 @@ func calcblockHeightKey(height int64) []byte {
 func (chain *BlockChain) ExportBlockProc(title string, dir string, startHeight int64) {
 	//获取文件路径
 	dataDir := getDataDir(dir)
-	err := chain.ExportBlock(title, dataDir, startHeight)
+	var err error
+	if title != chain.client.GetConfig().GetTitle() {
+		err = types.ErrInvalidParam
+	} else {
+		err = chain.ExportBlockFile(filepath.Join(dataDir, title), startHeight, -1, DefaultChunkBlocks)
+	}
 	exportlog.Info("ExportBlockProc:complete", "title", title, "dir", dir, "err", err)
 	syscall.Exit(0)
 }
@@ -55,12 +61,20 @@ func (chain *BlockChain) ImportBlockProc(filename string, dir string) {
 	if !chain.cfgBatchSync {
 		atomic.CompareAndSwapInt32(&chain.isbatchsync, 1, 0)
 	}
-	err := chain.ImportBlock(filename, dataDir)
+	var err error
+	if _, statErr := os.Stat(filepath.Join(dataDir, filename+".db")); statErr == nil {
+		//兼容旧版本导出的leveldb格式文件
+		err = chain.ImportBlock(filename, dataDir)
+	} else if filename == "-" {
+		err = chain.ImportBlockFile(filename)
+	} else {
+		err = chain.ImportBlockFile(filepath.Join(dataDir, filename))
+	}
 	exportlog.Info("importBlock:complete", "filename", filename, "dir", dir, "err", err)
 	syscall.Exit(0)
 }
 
-//ExportBlock 通过指定title和起始高度将block信息导出到一个指定文件中。
+//ExportBlock 通过指定title和起始高度将block信息导出到一个指定文件中, 旧版本的leveldb导出格式。
 // title:chain33/bityuan
 // startHeight:需要导入/导出的起始高度
 // dbPath:存储到指定路径,默认当前目录下
@@ -5,10 +5,15 @@
 package blockchain_test
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/33cn/chain33/blockchain"
+	"github.com/33cn/chain33/common"
 	dbm "github.com/33cn/chain33/common/db"
 	_ "github.com/33cn/chain33/system"
 	"github.com/33cn/chain33/types"
@@ -105,3 +110,67 @@ func testImportBlockProc(t *testing.T) {
 
 	chainlog.Info("TestImportBlockProc end --------------------")
 }
+
+func TestExportImportBlockFile(t *testing.T) {
+	mock33 := testnode.New("", nil)
+	chain := mock33.GetBlockChain()
+	cfg := mock33.GetClient().GetConfig()
+	for chain.GetBlockHeight() < 15 {
+		_, err := addMainTx(cfg, mock33.GetGenesisKey(), mock33.GetAPI())
+		require.NoError(t, err)
+		time.Sleep(sendTxWait)
+	}
+	dir, err := ioutil.TempDir("", "exportblock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	//分两次导出, 第二次从最后一个完整分片之后继续
+	err = chain.ExportBlockFile(dir, 0, 7, 5)
+	require.NoError(t, err)
+	manifest, err := blockchain.LoadManifest(dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(manifest.Chunks))
+	err = chain.ExportBlockFile(dir, 0, 15, 5)
+	require.NoError(t, err)
+	manifest, err = blockchain.LoadManifest(dir)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(manifest.Chunks))
+	require.Equal(t, int64(8), manifest.Chunks[2].StartHeight)
+	require.Equal(t, int64(15), manifest.EndHeight)
+	hash, err := chain.GetStore().GetBlockHashByHeight(15)
+	require.NoError(t, err)
+	require.Equal(t, common.ToHex(hash), manifest.Chunks[3].Hash)
+	err = chain.ExportBlockFile(dir, 1, 15, 5)
+	require.Equal(t, types.ErrInValidFileHeader, err)
+	mock33.Close()
+
+	mock33 = testnode.New("", nil)
+	defer mock33.Close()
+	chain = mock33.GetBlockChain()
+	//导入单个分片文件
+	err = chain.ImportBlockFile(filepath.Join(dir, manifest.Chunks[0].File))
+	require.NoError(t, err)
+	require.Equal(t, int64(4), chain.GetBlockHeight())
+
+	//分片摘要不匹配
+	sum := manifest.Chunks[1].Sha256
+	manifest.Chunks[1].Sha256 = manifest.Chunks[2].Sha256
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, blockchain.ManifestFileName), data, 0644))
+	err = chain.ImportBlockDir(dir)
+	require.Equal(t, blockchain.ErrChunkDigest, err)
+	require.Equal(t, int64(4), chain.GetBlockHeight())
+
+	//从中断处继续导入
+	manifest.Chunks[1].Sha256 = sum
+	data, err = json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, blockchain.ManifestFileName), data, 0644))
+	err = chain.ImportBlockFile(dir)
+	require.NoError(t, err)
+	header, err := chain.ProcGetLastHeaderMsg()
+	require.NoError(t, err)
+	require.Equal(t, int64(15), header.Height)
+	require.Equal(t, hash, header.Hash)
+}
@@ -0,0 +1,482 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+/*
+区块导出文件格式
+
+导出目录包含一个manifest.json和若干按编号命名的分片文件 blocks-000000.gz, blocks-000001.gz, ...
+
+分片文件是gzip压缩的区块流, 区块流由连续的记录组成, 每条记录为
+	uvarint(len(data)) | data
+data为protobuf编码的types.Block, 记录按区块高度严格连续递增.
+gzip允许多个压缩流直接拼接, 因此按编号拼接所有分片仍然是一个合法的区块流,
+例如 cat blocks-*.gz | chain33-cli block import --file - 可以从标准输入流式导入.
+
+manifest.json 记录导出链的title, 起止高度, 每个分片的高度范围, sha256摘要,
+以及分片最后一个区块的哈希作为检查点, 导入时校验分片摘要以及检查点的区块哈希.
+导出时每写完一个分片才更新manifest, 中断后再次导出到同一目录会从最后一个完整分片之后继续.
+导入时跳过本地已存在的区块, 中断后重新导入即可继续.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+)
+
+const (
+	//ManifestFileName 导出目录中manifest文件名
+	ManifestFileName = "manifest.json"
+	//DefaultChunkBlocks 默认每个分片包含的区块数
+	DefaultChunkBlocks int64 = 10000
+
+	exportFileVersion = 1
+	importWindow      = 256
+)
+
+var (
+	//ErrChunkDigest 分片文件摘要校验失败
+	ErrChunkDigest = errors.New("ErrChunkDigest")
+	//ErrCheckpointHash 检查点区块哈希不匹配
+	ErrCheckpointHash = errors.New("ErrCheckpointHash")
+)
+
+//ExportChunk 导出分片信息
+type ExportChunk struct {
+	File        string `json:"file"`
+	StartHeight int64  `json:"startHeight"`
+	EndHeight   int64  `json:"endHeight"`
+	//分片文件的sha256摘要
+	Sha256 string `json:"sha256"`
+	//分片最后一个区块的哈希, 作为导入时的检查点
+	Hash string `json:"hash"`
+}
+
+//ExportManifest 导出目录的描述信息
+type ExportManifest struct {
+	Version     int            `json:"version"`
+	Title       string         `json:"title"`
+	TestNet     bool           `json:"testNet"`
+	StartHeight int64          `json:"startHeight"`
+	EndHeight   int64          `json:"endHeight"`
+	ChunkBlocks int64          `json:"chunkBlocks"`
+	Chunks      []*ExportChunk `json:"chunks"`
+}
+
+func chunkFileName(index int) string {
+	return fmt.Sprintf("blocks-%06d.gz", index)
+}
+
+//LoadManifest 读取导出目录中的manifest
+func LoadManifest(dir string) (*ExportManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest ExportManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+//saveManifest 先写临时文件再重命名, 保证manifest总是完整的
+func saveManifest(dir string, manifest *ExportManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ManifestFileName+".tmp")
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ManifestFileName))
+}
+
+//checkpoints manifest中各分片的检查点
+func (manifest *ExportManifest) checkpoints() (map[int64][]byte, error) {
+	points := make(map[int64][]byte, len(manifest.Chunks))
+	for _, chunk := range manifest.Chunks {
+		hash, err := common.FromHex(chunk.Hash)
+		if err != nil {
+			return nil, err
+		}
+		points[chunk.EndHeight] = hash
+	}
+	return points, nil
+}
+
+//writeBlockRecord 写入一条区块记录
+func writeBlockRecord(w io.Writer, block *types.Block) error {
+	data := types.Encode(block)
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	_, err := w.Write(buf[:n])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+//readBlockRecord 读取一条区块记录, 流结束时返回io.EOF
+func readBlockRecord(r *bufio.Reader) (*types.Block, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(types.MaxBlockSize)*2 {
+		return nil, types.ErrBlockSize
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	var block types.Block
+	err = types.Decode(data, &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+//exportEndHeight 计算导出的结束高度, end小于0时只导出当前高度减去blockCount之前的区块, 避免导出侧链的数据
+func (chain *BlockChain) exportEndHeight(end int64) (int64, error) {
+	curheight, err := LoadBlockStoreHeight(chain.blockStore.db)
+	if err != nil {
+		return 0, err
+	}
+	if end < 0 {
+		end = curheight - blockCount
+	}
+	if end > curheight {
+		return 0, types.ErrBlockHeight
+	}
+	return end, nil
+}
+
+//ExportBlockStream 将[start, end]的主链区块以gzip区块流的形式写入w
+func (chain *BlockChain) ExportBlockStream(w io.Writer, start, end int64) error {
+	end, err := chain.exportEndHeight(end)
+	if err != nil {
+		return err
+	}
+	if start < 0 || start > end {
+		return types.ErrInvalidParam
+	}
+	_, err = chain.writeBlockChunk(w, start, end)
+	return err
+}
+
+//writeBlockChunk 写入一个gzip压缩的区块流, 返回最后一个区块的哈希
+func (chain *BlockChain) writeBlockChunk(w io.Writer, start, end int64) ([]byte, error) {
+	cfg := chain.client.GetConfig()
+	zw := gzip.NewWriter(w)
+	var hash []byte
+	for height := start; height <= end; height++ {
+		block, err := chain.blockStore.LoadBlockByHeight(height)
+		if err != nil {
+			exportlog.Error("writeBlockChunk:LoadBlockByHeight", "height", height, "error", err)
+			return nil, err
+		}
+		err = writeBlockRecord(zw, block.Block)
+		if err != nil {
+			return nil, err
+		}
+		hash = block.Block.Hash(cfg)
+	}
+	return hash, zw.Close()
+}
+
+//ExportBlockFile 将[start, end]的主链区块导出到dir目录, 每个分片包含chunkBlocks个区块
+//目录中已有导出数据时校验后从最后一个完整分片之后继续导出
+func (chain *BlockChain) ExportBlockFile(dir string, start, end, chunkBlocks int64) error {
+	cfg := chain.client.GetConfig()
+	if chunkBlocks <= 0 {
+		chunkBlocks = DefaultChunkBlocks
+	}
+	end, err := chain.exportEndHeight(end)
+	if err != nil {
+		return err
+	}
+	if start < 0 || start > end {
+		return types.ErrInvalidParam
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := LoadManifest(dir)
+	if os.IsNotExist(err) {
+		manifest = &ExportManifest{
+			Version:     exportFileVersion,
+			Title:       cfg.GetTitle(),
+			TestNet:     cfg.IsTestNet(),
+			StartHeight: start,
+			EndHeight:   start - 1,
+			ChunkBlocks: chunkBlocks,
+		}
+	} else if err != nil {
+		return err
+	} else if manifest.Title != cfg.GetTitle() || manifest.TestNet != cfg.IsTestNet() || manifest.StartHeight != start {
+		exportlog.Error("ExportBlockFile:manifest", "title", manifest.Title, "startHeight", manifest.StartHeight, "start", start)
+		return types.ErrInValidFileHeader
+	}
+
+	next := manifest.EndHeight + 1
+	if len(manifest.Chunks) > 0 && next <= end {
+		//继续导出需要校验block是否连续
+		last := manifest.Chunks[len(manifest.Chunks)-1]
+		block, err := chain.blockStore.LoadBlockByHeight(next)
+		if err != nil {
+			return err
+		}
+		if common.ToHex(block.Block.ParentHash) != last.Hash {
+			exportlog.Error("ExportBlockFile:block discontinuous", "endHeight", last.EndHeight, "hash", last.Hash, "parentHash", common.ToHex(block.Block.ParentHash))
+			return types.ErrBlockHashNoMatch
+		}
+	}
+	for next <= end {
+		chunkEnd := next + manifest.ChunkBlocks - 1
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+		chunk, err := chain.exportChunk(dir, len(manifest.Chunks), next, chunkEnd)
+		if err != nil {
+			return err
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk)
+		manifest.EndHeight = chunkEnd
+		err = saveManifest(dir, manifest)
+		if err != nil {
+			return err
+		}
+		exportlog.Info("ExportBlockFile", "file", chunk.File, "start", next, "end", chunkEnd)
+		next = chunkEnd + 1
+	}
+	return nil
+}
+
+//exportChunk 导出一个分片文件, 写入临时文件刷盘后再重命名
+func (chain *BlockChain) exportChunk(dir string, index int, start, end int64) (*ExportChunk, error) {
+	name := chunkFileName(index)
+	tmp := filepath.Join(dir, name+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+	digest := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(file, digest))
+	hash, err := chain.writeBlockChunk(w, start, end)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = os.Rename(tmp, filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return &ExportChunk{
+		File:        name,
+		StartHeight: start,
+		EndHeight:   end,
+		Sha256:      hex.EncodeToString(digest.Sum(nil)),
+		Hash:        common.ToHex(hash),
+	}, nil
+}
+
+//ImportBlockFile 导入区块, path可以是导出目录, 单个gzip区块流文件, 或者"-"表示从标准输入读取区块流
+func (chain *BlockChain) ImportBlockFile(path string) error {
+	if path == "-" {
+		return chain.ImportBlockStream(os.Stdin, nil)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return chain.ImportBlockDir(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return chain.ImportBlockStream(file, nil)
+}
+
+//ImportBlockDir 从导出目录导入区块, 校验分片摘要以及检查点
+func (chain *BlockChain) ImportBlockDir(dir string) error {
+	cfg := chain.client.GetConfig()
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+	if manifest.Title != cfg.GetTitle() || manifest.TestNet != cfg.IsTestNet() {
+		exportlog.Error("ImportBlockDir:manifest", "title", manifest.Title, "testNet", manifest.TestNet)
+		return types.ErrInValidFileHeader
+	}
+	checkpoints, err := manifest.checkpoints()
+	if err != nil {
+		return err
+	}
+	for _, chunk := range manifest.Chunks {
+		//跳过已经导入的分片
+		if chunk.EndHeight <= chain.GetBlockHeight() {
+			continue
+		}
+		err = chain.importChunk(dir, chunk, checkpoints)
+		if err != nil {
+			exportlog.Error("ImportBlockDir", "file", chunk.File, "err", err)
+			return err
+		}
+		exportlog.Info("ImportBlockDir", "file", chunk.File, "height", chain.GetBlockHeight())
+	}
+	return nil
+}
+
+func (chain *BlockChain) importChunk(dir string, chunk *ExportChunk, checkpoints map[int64][]byte) error {
+	file, err := os.Open(filepath.Join(dir, chunk.File))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	digest := sha256.New()
+	_, err = io.Copy(digest, file)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(digest.Sum(nil)) != chunk.Sha256 {
+		return ErrChunkDigest
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return chain.ImportBlockStream(file, checkpoints)
+}
+
+//importTask 导入流水线中的一个区块, 签名校验完成后关闭done
+type importTask struct {
+	block *types.Block
+	ok    bool
+	done  chan struct{}
+}
+
+//ImportBlockStream 从gzip区块流导入区块, 本地已存在的区块校验哈希后跳过
+//区块签名由多个goroutine提前并行校验, 区块按顺序执行, checkpoints不为空时校验对应高度的区块哈希
+func (chain *BlockChain) ImportBlockStream(r io.Reader, checkpoints map[int64][]byte) error {
+	cfg := chain.client.GetConfig()
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	quit := make(chan struct{})
+	defer close(quit)
+	tasks := make(chan *importTask, importWindow)
+	ordered := make(chan *importTask, importWindow)
+	var readErr error
+	go func() {
+		defer close(ordered)
+		defer close(tasks)
+		br := bufio.NewReader(zr)
+		for {
+			block, err := readBlockRecord(br)
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			task := &importTask{block: block, done: make(chan struct{})}
+			select {
+			case tasks <- task:
+			case <-quit:
+				return
+			}
+			select {
+			case ordered <- task:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	for i := 0; i < runtime.NumCPU(); i++ {
+		go func() {
+			for task := range tasks {
+				//本地已存在的区块不需要校验签名
+				task.ok = task.block.Height <= chain.GetBlockHeight() || task.block.Height == 0 || task.block.CheckSign(cfg)
+				close(task.done)
+			}
+		}()
+	}
+
+	for task := range ordered {
+		<-task.done
+		block := task.block
+		if !task.ok {
+			exportlog.Error("ImportBlockStream:CheckSign", "height", block.Height)
+			return types.ErrSign
+		}
+		hash := block.Hash(cfg)
+		if point, ok := checkpoints[block.Height]; ok && !bytes.Equal(point, hash) {
+			exportlog.Error("ImportBlockStream:checkpoint", "height", block.Height, "hash", common.ToHex(hash), "checkpoint", common.ToHex(point))
+			return ErrCheckpointHash
+		}
+		curheight := chain.GetBlockHeight()
+		if block.Height <= curheight {
+			localHash, err := chain.blockStore.GetBlockHashByHeight(block.Height)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(localHash, hash) {
+				exportlog.Error("ImportBlockStream:hash", "height", block.Height, "hash", common.ToHex(hash), "localHash", common.ToHex(localHash))
+				return types.ErrBlockHashNoMatch
+			}
+			continue
+		}
+		if block.Height != curheight+1 {
+			exportlog.Error("ImportBlockStream", "curheight", curheight, "height", block.Height)
+			return ErrBlockHeightDiscontinuous
+		}
+		err = chain.mainChainImport(block)
+		if err != nil {
+			exportlog.Error("ImportBlockStream:mainChainImport", "height", block.Height, "err", err)
+			return err
+		}
+	}
+	return readErr
+}
@@ -0,0 +1,632 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/types"
+)
+
+//const
+const (
+	//一次请求的区块头数量
+	headerSyncBatch int64 = 1000
+	//已校验的区块头最多领先已执行区块的数量
+	headerSyncAhead int64 = 20000
+	//已下载未执行的区块体数量上限, 限制临时存储的占用
+	bodyDownloadAhead int64 = 2048
+	//节点下载区块体的窗口大小, 根据节点吞吐量在上下限之间调整
+	bodyWindowInit int64 = 16
+	bodyWindowMin  int64 = 4
+	bodyWindowMax  int64 = 128
+	//窗口期望的下载时长, 快于该值时窗口翻倍, 慢于两倍时窗口减半
+	bodyWindowExpect = time.Second * 2
+	//区块头请求或者区块体窗口的超时时间
+	headerSyncTimeout = time.Second * 30
+)
+
+//HeaderChecker 共识相关的区块头校验(如工作量证明或者签名), 在下载区块体之前执行, parent为已校验的前一个区块头
+type HeaderChecker func(cfg *types.Chain33Config, parent, header *types.Header) error
+
+var headerCheckers = make(map[string]HeaderChecker)
+
+//RegisterHeaderChecker 共识模块注册区块头校验函数, name为共识名称
+func RegisterHeaderChecker(name string, checker HeaderChecker) {
+	if _, ok := headerCheckers[name]; ok {
+		panic("RegisterHeaderChecker dup name " + name)
+	}
+	headerCheckers[name] = checker
+}
+
+//checkHeader 校验区块头的链接关系, 哈希, 时间, 交易数以及检查点, 最后调用共识注册的校验函数
+//每个区块头在加入已校验的区块头链之前都要通过该校验, 避免为伪造的区块头链下载区块体
+func (chain *BlockChain) checkHeader(parent, header *types.Header) error {
+	cfg := chain.client.GetConfig()
+	if parent.Height+1 != header.Height {
+		return types.ErrBlockHeight
+	}
+	if !bytes.Equal(header.ParentHash, parent.Hash) {
+		return types.ErrParentHash
+	}
+	if !bytes.Equal(header.CalcHash(cfg), header.Hash) {
+		return types.ErrBlockHashNoMatch
+	}
+	if cfg.IsFork(header.Height, "ForkCheckBlockTime") && parent.BlockTime > header.BlockTime {
+		return types.ErrBlockTime
+	}
+	if cfg.IsFork(header.Height, "ForkBlockCheck") && header.TxCount > cfg.GetP(header.Height).MaxTxNumber {
+		return types.ErrManyTx
+	}
+	if err := chain.checkpoints.checkHash(header.Height, header.Hash); err != nil {
+		return err
+	}
+	if checker, ok := headerCheckers[cfg.GetModuleConfig().Consensus.Name]; ok {
+		return checker(cfg, parent, header)
+	}
+	return nil
+}
+
+//syncRequest 向某个节点发起的区块头或者区块体请求
+type syncRequest struct {
+	pid   string
+	start int64
+	end   int64
+	//区块体窗口中尚未收到的高度
+	missing map[int64]bool
+	time    time.Time
+}
+
+//syncPeer 参与下载区块体的节点
+type syncPeer struct {
+	pid    string
+	height int64
+	window int64
+	//每秒下载的区块数
+	rate float64
+	req  *syncRequest
+}
+
+//finish 窗口下载完成, 根据耗时调整窗口大小
+func (p *syncPeer) finish(now time.Time) {
+	elapsed := now.Sub(p.req.time)
+	rate := float64(p.req.end-p.req.start+1) / (elapsed.Seconds() + 0.001)
+	if p.rate == 0 {
+		p.rate = rate
+	} else {
+		p.rate = p.rate*0.7 + rate*0.3
+	}
+	if elapsed < bodyWindowExpect && p.window < bodyWindowMax {
+		p.window *= 2
+		if p.window > bodyWindowMax {
+			p.window = bodyWindowMax
+		}
+	} else if elapsed > bodyWindowExpect*2 && p.window > bodyWindowMin {
+		p.window /= 2
+		if p.window < bodyWindowMin {
+			p.window = bodyWindowMin
+		}
+	}
+	p.req = nil
+}
+
+//headerSync 先同步区块头再并行下载区块体:
+//1. 从最优链节点批量获取区块头, 校验通过后领先于区块体下载
+//2. 按窗口将区块体分配给多个节点并行下载, 窗口大小随节点吞吐量调整, 超时的高度重新分配
+//3. 下载的区块体与区块头比对后临时存储, 由单独的goroutine按高度顺序执行
+type headerSync struct {
+	chain *BlockChain
+	mtx   sync.Mutex
+
+	running bool
+	target  int64
+	//已校验的最高区块头
+	headerTip *types.Header
+	//已校验尚未执行的区块头
+	headers   map[int64]*types.Header
+	headerReq *syncRequest
+	//轮流选择请求区块头的节点
+	headerPeer int
+	//下一个待分配下载的区块体高度
+	nextBody int64
+	//超时需要重新分配的区块体高度, 升序
+	retry []int64
+	//已下载尚未执行的区块体, 对应提供区块的节点
+	bodies map[int64]string
+	peers  map[string]*syncPeer
+
+	//有新的区块体下载完成, 通知执行
+	ready chan struct{}
+	done  chan struct{}
+}
+
+func newHeaderSync(chain *BlockChain) *headerSync {
+	return &headerSync{
+		chain: chain,
+		ready: make(chan struct{}, 1),
+	}
+}
+
+//isRunning 是否正在进行区块头优先同步, s为nil时返回false
+func (s *headerSync) isRunning() bool {
+	if s == nil {
+		return false
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.running
+}
+
+//tick 定时检查同步状态, 落后较多时启动同步, 处理超时并分配新的请求. 返回false时由普通同步处理
+func (s *headerSync) tick() bool {
+	if s == nil {
+		return false
+	}
+	chain := s.chain
+	curHeight := chain.GetBlockHeight()
+	target := chain.GetPeerMaxBlkHeight()
+	peers := make(map[string]int64)
+	for _, pid := range chain.GetBestChainPids() {
+		if info := chain.GetPeerInfo(pid); info != nil {
+			peers[pid] = info.Height
+		}
+	}
+
+	s.mtx.Lock()
+	if !s.running {
+		s.mtx.Unlock()
+		//落后较少时由普通同步处理
+		if target-curHeight <= BackBlockNum || len(peers) == 0 || chain.syncTask.InProgress() || chain.downLoadTask.InProgress() {
+			return false
+		}
+		tip, err := chain.blockStore.GetBlockHeaderByHeight(curHeight)
+		if err != nil {
+			synlog.Error("headerSync tick", "height", curHeight, "err", err)
+			return false
+		}
+		s.mtx.Lock()
+		s.start(tip, target)
+	} else if curHeight >= s.target && target-curHeight <= BackBlockNum {
+		synlog.Info("headerSync complete", "height", curHeight, "target", s.target)
+		bodies := s.stop()
+		s.mtx.Unlock()
+		s.clean(bodies)
+		return false
+	}
+	if target > s.target {
+		s.target = target
+	}
+	s.updatePeers(peers)
+	s.checkTimeout(types.Now())
+	s.mtx.Unlock()
+
+	go s.schedule()
+	return true
+}
+
+//start 从tip开始同步到target高度, 需要持有锁
+func (s *headerSync) start(tip *types.Header, target int64) {
+	synlog.Info("headerSync start", "height", tip.Height, "target", target)
+	s.running = true
+	s.target = target
+	s.headerTip = tip
+	s.headers = make(map[int64]*types.Header)
+	s.headerReq = nil
+	s.nextBody = tip.Height + 1
+	s.retry = nil
+	s.bodies = make(map[int64]string)
+	s.peers = make(map[string]*syncPeer)
+	s.done = make(chan struct{})
+	go s.execRoutine(s.done)
+}
+
+//stop 结束同步, 返回尚未执行的区块体高度, 需要持有锁
+func (s *headerSync) stop() []int64 {
+	if !s.running {
+		return nil
+	}
+	s.running = false
+	close(s.done)
+	var bodies []int64
+	for height := range s.bodies {
+		bodies = append(bodies, height)
+	}
+	s.headers = nil
+	s.bodies = nil
+	s.peers = nil
+	s.retry = nil
+	s.headerReq = nil
+	return bodies
+}
+
+//clean 删除未执行的临时区块
+func (s *headerSync) clean(heights []int64) {
+	for _, height := range heights {
+		if err := s.chain.blockStore.db.Delete(calcHeightToTempBlockKey(height)); err != nil {
+			synlog.Error("headerSync clean", "height", height, "err", err)
+		}
+	}
+}
+
+//updatePeers 更新参与下载的节点, 不在最优链上的节点未完成的窗口重新分配, 需要持有锁
+func (s *headerSync) updatePeers(peers map[string]int64) {
+	for pid, p := range s.peers {
+		if _, ok := peers[pid]; !ok {
+			s.removePeer(p)
+		}
+	}
+	for pid, height := range peers {
+		p, ok := s.peers[pid]
+		if !ok {
+			p = &syncPeer{pid: pid, window: bodyWindowInit}
+			s.peers[pid] = p
+		}
+		p.height = height
+	}
+}
+
+func (s *headerSync) removePeer(p *syncPeer) {
+	if p.req != nil {
+		s.release(p.req)
+	}
+	delete(s.peers, p.pid)
+}
+
+//release 未收到的区块体高度重新分配
+func (s *headerSync) release(req *syncRequest) {
+	for height := range req.missing {
+		s.retry = append(s.retry, height)
+	}
+	sort.Slice(s.retry, func(i, j int) bool { return s.retry[i] < s.retry[j] })
+}
+
+//checkTimeout 超时的区块头请求重新发起, 超时的窗口重新分配并且窗口减半, 需要持有锁
+func (s *headerSync) checkTimeout(now time.Time) {
+	if s.headerReq != nil && now.Sub(s.headerReq.time) > headerSyncTimeout {
+		synlog.Info("headerSync header timeout", "pid", s.headerReq.pid, "start", s.headerReq.start)
+		s.headerReq = nil
+	}
+	for _, p := range s.peers {
+		if p.req == nil || now.Sub(p.req.time) <= headerSyncTimeout {
+			continue
+		}
+		synlog.Info("headerSync body timeout", "pid", p.pid, "start", p.req.start, "end", p.req.end, "missing", len(p.req.missing))
+		s.release(p.req)
+		p.req = nil
+		p.window /= 2
+		if p.window < bodyWindowMin {
+			p.window = bodyWindowMin
+		}
+	}
+}
+
+//schedule 分配区块头和区块体请求并发送给p2p模块
+func (s *headerSync) schedule() {
+	execHeight := s.chain.GetBlockHeight()
+	now := types.Now()
+	s.mtx.Lock()
+	if !s.running {
+		s.mtx.Unlock()
+		return
+	}
+	var headerReq *syncRequest
+	if s.headerReq == nil && s.headerTip.Height < s.target && s.headerTip.Height-execHeight < headerSyncAhead {
+		if p := s.pickHeaderPeer(); p != nil {
+			start := s.headerTip.Height + 1
+			end := start + headerSyncBatch - 1
+			if end > p.height {
+				end = p.height
+			}
+			headerReq = &syncRequest{pid: p.pid, start: start, end: end, time: now}
+			s.headerReq = headerReq
+		}
+	}
+	//吞吐量高的节点优先分配
+	peers := make([]*syncPeer, 0, len(s.peers))
+	for _, p := range s.peers {
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].rate > peers[j].rate })
+	var bodyReqs []*syncRequest
+	for _, p := range peers {
+		if p.req != nil {
+			continue
+		}
+		if req := s.nextWindow(p, execHeight, now); req != nil {
+			p.req = req
+			bodyReqs = append(bodyReqs, req)
+		}
+	}
+	s.mtx.Unlock()
+
+	if headerReq != nil {
+		if err := s.chain.FetchBlockHeaders(headerReq.start, headerReq.end, headerReq.pid); err != nil {
+			synlog.Error("headerSync FetchBlockHeaders", "pid", headerReq.pid, "err", err)
+			s.mtx.Lock()
+			if s.headerReq == headerReq {
+				s.headerReq = nil
+			}
+			s.mtx.Unlock()
+		}
+	}
+	for _, req := range bodyReqs {
+		if err := s.fetchBodies(req); err != nil {
+			synlog.Error("headerSync fetchBodies", "pid", req.pid, "err", err)
+			s.mtx.Lock()
+			if p, ok := s.peers[req.pid]; ok && p.req == req {
+				s.release(req)
+				p.req = nil
+			}
+			s.mtx.Unlock()
+		}
+	}
+}
+
+//pickHeaderPeer 轮流选择高度足够的节点请求区块头, 需要持有锁
+func (s *headerSync) pickHeaderPeer() *syncPeer {
+	var pids []string
+	for pid, p := range s.peers {
+		if p.height > s.headerTip.Height {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) == 0 {
+		return nil
+	}
+	sort.Strings(pids)
+	s.headerPeer++
+	return s.peers[pids[s.headerPeer%len(pids)]]
+}
+
+//nextWindow 为节点分配下一个下载窗口, 优先分配超时的高度, 需要持有锁
+func (s *headerSync) nextWindow(p *syncPeer, execHeight int64, now time.Time) *syncRequest {
+	limit := s.headerTip.Height
+	if p.height < limit {
+		limit = p.height
+	}
+	if execHeight+bodyDownloadAhead < limit {
+		limit = execHeight + bodyDownloadAhead
+	}
+	var heights []int64
+	for len(s.retry) > 0 && int64(len(heights)) < p.window && s.retry[0] <= limit {
+		height := s.retry[0]
+		if _, ok := s.bodies[height]; ok || height <= execHeight {
+			s.retry = s.retry[1:]
+			continue
+		}
+		//只分配连续的高度
+		if len(heights) > 0 && height != heights[len(heights)-1]+1 {
+			break
+		}
+		heights = append(heights, height)
+		s.retry = s.retry[1:]
+	}
+	if len(heights) == 0 {
+		for s.nextBody <= limit && int64(len(heights)) < p.window {
+			heights = append(heights, s.nextBody)
+			s.nextBody++
+		}
+	}
+	if len(heights) == 0 {
+		return nil
+	}
+	req := &syncRequest{
+		pid:     p.pid,
+		start:   heights[0],
+		end:     heights[len(heights)-1],
+		missing: make(map[int64]bool),
+		time:    now,
+	}
+	for _, height := range heights {
+		req.missing[height] = true
+	}
+	return req
+}
+
+//fetchBodies 从指定节点下载窗口内的区块体
+func (s *headerSync) fetchBodies(req *syncRequest) error {
+	client := s.chain.client
+	synlog.Debug("headerSync fetchBodies", "pid", req.pid, "start", req.start, "end", req.end)
+	reqBlocks := &types.ReqBlocks{Start: req.start, End: req.end, IsDetail: false, Pid: []string{req.pid}}
+	msg := client.NewMessage("p2p", types.EventFetchBlocks, reqBlocks)
+	if err := client.Send(msg, true); err != nil {
+		return err
+	}
+	resp, err := client.Wait(msg)
+	if err != nil {
+		return err
+	}
+	return resp.Err()
+}
+
+//onHeaders 处理区块头请求的回复, 不是本同步请求的区块头返回false
+func (s *headerSync) onHeaders(headers *types.Headers, pid string) bool {
+	if s == nil || len(headers.GetItems()) == 0 {
+		return false
+	}
+	s.mtx.Lock()
+	if !s.running || s.headerReq == nil || s.headerReq.pid != pid || headers.Items[0].Height != s.headerReq.start {
+		s.mtx.Unlock()
+		return false
+	}
+	s.headerReq = nil
+	parent := s.headerTip
+	for _, header := range headers.Items {
+		if err := s.chain.checkHeader(parent, header); err != nil {
+			synlog.Error("headerSync checkHeader", "pid", pid, "height", header.Height, "hash", common.ToHex(header.Hash), "err", err)
+			if p, ok := s.peers[pid]; ok {
+				s.removePeer(p)
+			}
+			s.mtx.Unlock()
+			s.chain.reportFaultPeer(pid, types.PeerFaultInvalidHeader, err)
+			return true
+		}
+		s.headers[header.Height] = header
+		parent = header
+	}
+	s.headerTip = parent
+	s.mtx.Unlock()
+	synlog.Debug("headerSync onHeaders", "pid", pid, "headerHeight", parent.Height)
+	go s.schedule()
+	return true
+}
+
+//onBlock 处理下载的区块体, 与已校验的区块头比对后临时存储, 不在同步范围内的区块返回false
+func (s *headerSync) onBlock(blockpid *types.BlockPid) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	block := blockpid.Block
+	s.mtx.Lock()
+	if !s.running {
+		s.mtx.Unlock()
+		return false, nil
+	}
+	header, ok := s.headers[block.Height]
+	if !ok {
+		s.mtx.Unlock()
+		return false, nil
+	}
+	if _, ok := s.bodies[block.Height]; ok {
+		s.mtx.Unlock()
+		return true, nil
+	}
+	s.mtx.Unlock()
+
+	cfg := s.chain.client.GetConfig()
+	var err error
+	if !bytes.Equal(block.Hash(cfg), header.Hash) {
+		err = types.ErrBlockHashNoMatch
+	} else if !bytes.Equal(block.TxHash, merkle.CalcMerkleRoot(cfg, block.Height, block.Txs)) {
+		err = types.ErrCheckTxHash
+	}
+	if err != nil {
+		synlog.Error("headerSync onBlock", "pid", blockpid.Pid, "height", block.Height, "err", err)
+		s.chain.reportFaultPeer(blockpid.Pid, types.PeerFaultInvalidBlock, err)
+		return true, err
+	}
+	if err = s.chain.WriteBlockToDbTemp(block, false); err != nil {
+		return true, err
+	}
+
+	now := types.Now()
+	s.mtx.Lock()
+	if !s.running {
+		s.mtx.Unlock()
+		s.clean([]int64{block.Height})
+		return true, nil
+	}
+	s.bodies[block.Height] = blockpid.Pid
+	for _, p := range s.peers {
+		if p.req == nil || !p.req.missing[block.Height] {
+			continue
+		}
+		delete(p.req.missing, block.Height)
+		if len(p.req.missing) == 0 {
+			p.finish(now)
+		}
+	}
+	s.mtx.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	go s.schedule()
+	return true, nil
+}
+
+//execRoutine 按高度顺序执行已下载的区块, 执行失败时结束同步
+func (s *headerSync) execRoutine(done chan struct{}) {
+	chain := s.chain
+	for {
+		height := chain.GetBlockHeight() + 1
+		s.mtx.Lock()
+		pid, ok := s.bodies[height]
+		s.mtx.Unlock()
+		if !ok {
+			select {
+			case <-s.ready:
+			case <-done:
+				return
+			case <-chain.quit:
+				return
+			}
+			continue
+		}
+		block, err := chain.ReadBlockByHeight(height)
+		if err == nil {
+			_, _, _, err = chain.ProcessBlock(false, &types.BlockDetail{Block: block}, pid, true, -1)
+		}
+		//区块未能添加到主链, 可能本节点已经切换到其他分支
+		if err == nil || err == types.ErrBlockExist {
+			err = nil
+			if chain.GetBlockHeight() < height {
+				err = types.ErrBlockHashNoMatch
+			}
+		}
+		s.mtx.Lock()
+		select {
+		case <-done:
+			s.mtx.Unlock()
+			return
+		default:
+		}
+		if err != nil {
+			synlog.Error("headerSync exec", "height", height, "pid", pid, "err", err)
+			bodies := s.stop()
+			s.mtx.Unlock()
+			s.clean(bodies)
+			return
+		}
+		delete(s.bodies, height)
+		delete(s.headers, height)
+		s.mtx.Unlock()
+	}
+}
+
+//progress 获取同步进度
+func (s *headerSync) progress(execHeight, peerMaxHeight int64) *types.SyncProgress {
+	progress := &types.SyncProgress{
+		TargetHeight: peerMaxHeight,
+		HeaderHeight: execHeight,
+		BodyHeight:   execHeight,
+		ExecHeight:   execHeight,
+	}
+	if s == nil {
+		return progress
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !s.running {
+		return progress
+	}
+	progress.InProgress = true
+	progress.TargetHeight = s.target
+	if s.headerTip.Height > execHeight {
+		progress.HeaderHeight = s.headerTip.Height
+	}
+	for {
+		if _, ok := s.bodies[progress.BodyHeight+1]; !ok {
+			break
+		}
+		progress.BodyHeight++
+	}
+	for _, p := range s.peers {
+		progress.Peers = append(progress.Peers, &types.PeerSyncRate{Pid: p.pid, Window: p.window, Rate: p.rate})
+	}
+	sort.Slice(progress.Peers, func(i, j int) bool { return progress.Peers[i].Pid < progress.Peers[j].Pid })
+	return progress
+}
+
+//GetSyncProgress 获取区块同步进度, 包括已校验的区块头, 已下载的区块体以及已执行的区块高度
+func (chain *BlockChain) GetSyncProgress() *types.SyncProgress {
+	return chain.headerSync.progress(chain.GetBlockHeight(), chain.GetPeerMaxBlkHeight())
+}
@@ -0,0 +1,260 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//genSyncBlocks 在parent之后生成count个没有交易的区块
+func genSyncBlocks(cfg *types.Chain33Config, parent *types.Header, count int) []*types.Block {
+	var blocks []*types.Block
+	for i := 0; i < count; i++ {
+		block := &types.Block{
+			ParentHash: parent.Hash,
+			Height:     parent.Height + 1,
+			BlockTime:  parent.BlockTime + 1,
+			Difficulty: cfg.GetP(0).PowLimitBits,
+		}
+		block.TxHash = merkle.CalcMerkleRoot(cfg, block.Height, block.Txs)
+		blocks = append(blocks, block)
+		parent = block.GetHeader(cfg)
+	}
+	return blocks
+}
+
+func syncHeaders(cfg *types.Chain33Config, blocks []*types.Block) *types.Headers {
+	headers := &types.Headers{}
+	for _, block := range blocks {
+		headers.Items = append(headers.Items, block.GetHeader(cfg))
+	}
+	return headers
+}
+
+func TestCheckHeader(t *testing.T) {
+	chain := InitEnv()
+	cfg := chain.client.GetConfig()
+	tip := &types.Header{Height: 10, Hash: []byte("tip"), BlockTime: 100, Difficulty: cfg.GetP(0).PowLimitBits}
+	header := genSyncBlocks(cfg, tip, 1)[0].GetHeader(cfg)
+	assert.Nil(t, chain.checkHeader(tip, header))
+
+	bad := types.Clone(header).(*types.Header)
+	bad.Height = 12
+	assert.Equal(t, types.ErrBlockHeight, chain.checkHeader(tip, bad))
+	bad = types.Clone(header).(*types.Header)
+	bad.ParentHash = []byte("other")
+	assert.Equal(t, types.ErrParentHash, chain.checkHeader(tip, bad))
+	bad = types.Clone(header).(*types.Header)
+	bad.StateHash = []byte("state")
+	assert.Equal(t, types.ErrBlockHashNoMatch, chain.checkHeader(tip, bad))
+	bad.Hash = bad.CalcHash(cfg)
+	assert.Nil(t, chain.checkHeader(tip, bad))
+	bad.BlockTime = 99
+	bad.Hash = bad.CalcHash(cfg)
+	assert.Equal(t, types.ErrBlockTime, chain.checkHeader(tip, bad))
+
+	name := cfg.GetModuleConfig().Consensus.Name
+	old, ok := headerCheckers[name]
+	headerCheckers[name] = func(cfg *types.Chain33Config, parent, header *types.Header) error {
+		if header.Difficulty != parent.Difficulty {
+			return types.ErrBlockHeaderDifficulty
+		}
+		return nil
+	}
+	defer func() {
+		if ok {
+			headerCheckers[name] = old
+		} else {
+			delete(headerCheckers, name)
+		}
+	}()
+	assert.Nil(t, chain.checkHeader(tip, header))
+	bad = types.Clone(header).(*types.Header)
+	bad.Difficulty = 1
+	bad.Hash = bad.CalcHash(cfg)
+	assert.Equal(t, types.ErrBlockHeaderDifficulty, chain.checkHeader(tip, bad))
+
+	chain.checkpoints = &checkpoints{points: map[int64][]byte{11: []byte("checkpoint")}, heights: []int64{11}}
+	assert.Equal(t, types.ErrCheckpointMismatch, chain.checkHeader(tip, header))
+}
+
+func TestSyncPeerWindow(t *testing.T) {
+	s := &headerSync{
+		headerTip: &types.Header{Height: 100},
+		nextBody:  1,
+		bodies:    make(map[int64]string),
+		peers:     make(map[string]*syncPeer),
+	}
+	now := time.Now()
+	s.updatePeers(map[string]int64{"p1": 100, "p2": 20})
+	p1, p2 := s.peers["p1"], s.peers["p2"]
+	assert.Equal(t, bodyWindowInit, p1.window)
+
+	p1.req = s.nextWindow(p1, 0, now)
+	assert.Equal(t, int64(1), p1.req.start)
+	assert.Equal(t, bodyWindowInit, p1.req.end)
+	//不超过节点高度
+	p2.window = 64
+	p2.req = s.nextWindow(p2, 0, now)
+	assert.Equal(t, bodyWindowInit+1, p2.req.start)
+	assert.Equal(t, int64(20), p2.req.end)
+	assert.Nil(t, s.nextWindow(p2, 0, now))
+
+	//快速完成窗口翻倍
+	p1.finish(now.Add(time.Second))
+	assert.Equal(t, bodyWindowInit*2, p1.window)
+	assert.InDelta(t, 16, p1.rate, 0.1)
+	assert.Nil(t, p1.req)
+
+	//超时的窗口重新分配, 窗口减半
+	delete(p2.req.missing, 18)
+	s.bodies[18] = "p2"
+	s.checkTimeout(now.Add(headerSyncTimeout + time.Second))
+	assert.Nil(t, p2.req)
+	assert.Equal(t, int64(32), p2.window)
+	assert.Equal(t, []int64{17, 19, 20}, s.retry)
+	req := s.nextWindow(p1, 0, now)
+	assert.Equal(t, int64(17), req.start)
+	assert.Equal(t, int64(17), req.end)
+	req = s.nextWindow(p1, 0, now)
+	assert.Equal(t, int64(19), req.start)
+	assert.Equal(t, int64(20), req.end)
+	assert.Equal(t, 0, len(s.retry))
+
+	//已下载未执行的区块体数量受限
+	req = s.nextWindow(p1, 20-bodyDownloadAhead, now)
+	assert.Nil(t, req)
+
+	//节点不在最优链上时未完成的窗口重新分配
+	p1.req = s.nextWindow(p1, 0, now)
+	s.updatePeers(map[string]int64{"p2": 100})
+	assert.Nil(t, s.peers["p1"])
+	assert.Equal(t, 32, len(s.retry))
+}
+
+func TestHeaderSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "headersync")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	q := queue.New("channel")
+	q.SetConfig(cfg)
+	chain := New(cfg)
+	chain.client = q.Client()
+	blockStoreDB := dbm.NewDB("blockchain", "leveldb", dir, 100)
+	defer blockStoreDB.Close()
+	chain.blockStore = NewBlockStore(chain, blockStoreDB, nil)
+
+	//模拟p2p模块, 记录收到的请求
+	p2p := q.Client()
+	p2p.Sub("p2p")
+	reqs := make(chan *queue.Message, 100)
+	go func() {
+		for msg := range p2p.Recv() {
+			if msg.Ty == types.EventFetchBlocks || msg.Ty == types.EventFetchBlockHeaders {
+				msg.Reply(p2p.NewMessage("blockchain", types.EventReply, &types.Reply{IsOk: true}))
+			}
+			reqs <- msg
+		}
+	}()
+	waitReq := func(ty int64) *queue.Message {
+		for {
+			select {
+			case msg := <-reqs:
+				if msg.Ty == ty {
+					return msg
+				}
+			case <-time.After(time.Second * 5):
+				t.Fatal("wait request timeout", ty)
+			}
+		}
+	}
+
+	tip := &types.Header{Height: 0, Hash: []byte("genesis"), BlockTime: 1}
+	blocks := genSyncBlocks(cfg, tip, 20)
+	s := newHeaderSync(chain)
+	assert.False(t, s.isRunning())
+	s.mtx.Lock()
+	s.start(tip, 20)
+	s.updatePeers(map[string]int64{"p1": 20})
+	s.mtx.Unlock()
+	assert.True(t, s.isRunning())
+
+	s.schedule()
+	req := waitReq(types.EventFetchBlockHeaders).GetData().(*types.ReqBlocks)
+	assert.Equal(t, int64(1), req.Start)
+	assert.Equal(t, int64(20), req.End)
+	assert.Equal(t, []string{"p1"}, req.Pid)
+
+	//非本同步请求的headers
+	headers := syncHeaders(cfg, blocks[:5])
+	assert.False(t, s.onHeaders(headers, "p2"))
+	assert.False(t, s.onHeaders(syncHeaders(cfg, blocks[1:5]), "p1"))
+	assert.True(t, s.onHeaders(headers, "p1"))
+	req = waitReq(types.EventFetchBlocks).GetData().(*types.ReqBlocks)
+	assert.Equal(t, int64(1), req.Start)
+	assert.Equal(t, int64(5), req.End)
+
+	//不在区块头范围内的区块由普通流程处理
+	handled, err := s.onBlock(&types.BlockPid{Pid: "p1", Block: blocks[6]})
+	assert.False(t, handled)
+	assert.Nil(t, err)
+	//区块体与区块头不一致
+	bad := types.Clone(blocks[0]).(*types.Block)
+	bad.Txs = []*types.Transaction{{Execer: []byte("none")}}
+	handled, err = s.onBlock(&types.BlockPid{Pid: "p1", Block: bad})
+	assert.True(t, handled)
+	assert.NotNil(t, err)
+	for _, block := range blocks[:5] {
+		handled, err = s.onBlock(&types.BlockPid{Pid: "p1", Block: block})
+		assert.True(t, handled)
+		assert.Nil(t, err)
+	}
+	saved, err := chain.ReadBlockByHeight(3)
+	require.Nil(t, err)
+	assert.Equal(t, blocks[2].Hash(cfg), saved.Hash(cfg))
+
+	progress := s.progress(0, 30)
+	assert.True(t, progress.InProgress)
+	assert.Equal(t, int64(20), progress.TargetHeight)
+	assert.Equal(t, int64(5), progress.HeaderHeight)
+	assert.Equal(t, int64(5), progress.BodyHeight)
+	assert.Equal(t, int64(0), progress.ExecHeight)
+	require.Equal(t, 1, len(progress.Peers))
+	assert.Equal(t, bodyWindowInit*2, progress.Peers[0].Window)
+
+	//区块头校验失败的节点被移除并上报
+	s.mtx.Lock()
+	s.headerReq = &syncRequest{pid: "p1", start: 6}
+	s.mtx.Unlock()
+	headers = syncHeaders(cfg, blocks[5:8])
+	headers.Items[1].ParentHash = nil
+	assert.True(t, s.onHeaders(headers, "p1"))
+	report := waitReq(types.EventReportFaultPeer).GetData().(*types.PeerFaultReport)
+	assert.Equal(t, "p1", report.Pid)
+	assert.Equal(t, int64(5), s.progress(0, 30).HeaderHeight)
+	assert.Equal(t, 0, len(s.progress(0, 30).Peers))
+
+	s.mtx.Lock()
+	bodies := s.stop()
+	s.mtx.Unlock()
+	s.clean(bodies)
+	assert.False(t, s.isRunning())
+	_, err = chain.ReadBlockByHeight(4)
+	assert.Equal(t, types.ErrHeightNotExist, err)
+	assert.Equal(t, int64(-1), chain.GetBlockHeight())
+	assert.False(t, chain.GetSyncProgress().InProgress)
+}
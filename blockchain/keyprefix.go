@@ -0,0 +1,62 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/db/table"
+	"github.com/33cn/chain33/types"
+)
+
+//登记blockchain数据库中的key前缀以及表结构, 执行器的localdb也保存在blockchain数据库中
+func init() {
+	int64Value := func() types.Message { return &types.Int64{} }
+	prefixes := []struct {
+		prefix   string
+		desc     string
+		newValue func() types.Message
+	}{
+		{string(blockLastHeight), "最新区块高度", int64Value},
+		{string(bodyPrefix), "hash -> 区块body(旧版本)", func() types.Message { return &types.BlockBody{} }},
+		{string(headerPrefix), "hash -> 区块头(旧版本)", func() types.Message { return &types.Header{} }},
+		{string(heightToHeaderPrefix), "高度 -> 区块头(旧版本)", func() types.Message { return &types.Header{} }},
+		{string(hashPrefix), "hash -> 高度", int64Value},
+		{string(tdPrefix), "hash -> 难度", nil},
+		{string(heightToHashKeyPrefix), "高度 -> hash", nil},
+		{string(LastSequence), "最新的sequence", int64Value},
+		{string(seqToHashKey), "sequence -> hash", func() types.Message { return &types.BlockSequence{} }},
+		{string(HashToSeqPrefix), "hash -> sequence", int64Value},
+		{string(seqCBPrefix), "sequence推送注册", func() types.Message { return &types.BlockSeqCB{} }},
+		{string(seqCBLastNumPrefix), "sequence推送进度", int64Value},
+		{string(LastParaSequence), "平行链最新的sequence", int64Value},
+		{string(paraSeqToHashKey), "平行链sequence -> hash", func() types.Message { return &types.BlockSequence{} }},
+		{string(HashToParaSeqPrefix), "hash -> 平行链sequence", int64Value},
+		{string(tempBlockKey), "同步中的临时区块", func() types.Message { return &types.Block{} }},
+		{string(lastTempBlockKey), "最新的临时区块高度", int64Value},
+		{string(archiveIndexPrefix), "高度 -> 归档文件位置", nil},
+		{string(archiveHeightKey), "归档高度", int64Value},
+		{string(chainBodyPrefix) + "-", "区块body表", nil},
+		{string(chainHeaderPrefix) + "-", "区块头表", nil},
+		{string(chainReceiptPrefix) + "-", "区块回执表", nil},
+		{string(chainParaTxPrefix) + "-", "平行链交易表", nil},
+		{string(types.TxHashPerfix), "交易hash -> 交易结果", func() types.Message { return &types.TxResult{} }},
+		{string(types.TxShortHashPerfix), "交易短hash", nil},
+		{string(types.TxAddrHash), "地址 -> 交易", func() types.Message { return &types.ReplyTxInfo{} }},
+		{string(types.TxAddrDirHash), "地址 -> 转入转出交易", func() types.Message { return &types.ReplyTxInfo{} }},
+		{string(types.AddrTxsCount), "地址交易数目", int64Value},
+		{"TotalFeeKey:", "区块hash -> 累计手续费", func() types.Message { return &types.TotalFee{} }},
+		{string(types.LocalPrefix) + "-", "执行器localdb", nil},
+		{"FLAG:", "数据库标记", int64Value},
+		{string(dbm.LocalMVCCPrefix) + "d.", "执行器localdb多版本数据", func() types.Message { return &types.KeyVersion{} }},
+		{string(dbm.LocalMVCCPrefix) + "m.", "执行器localdb多版本元数据", nil},
+	}
+	for _, p := range prefixes {
+		dbm.RegisterKeyPrefix("blockchain", p.prefix, p.desc, p.newValue)
+	}
+	table.RegisterTable(bodyOpt, func() table.RowMeta { return NewBodyRow() })
+	table.RegisterTable(headerOpt, func() table.RowMeta { return NewHeaderRow() })
+	table.RegisterTable(receiptOpt, func() table.RowMeta { return NewReceiptRow() })
+	table.RegisterTable(paratxOpt, func() table.RowMeta { return NewParaTxRow() })
+}
@@ -0,0 +1,46 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/db/table"
+	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	db := dbm.NewDB("blockchain", "memdb", "", 16)
+	defer db.Close()
+	body := &types.BlockBody{Hash: []byte("hash0123456789"), Height: 10, MainHash: []byte("main"), MainHeight: 10}
+	kvs, err := saveBlockBodyTable(db, body)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(kvs))
+	for _, kv := range kvs {
+		decoded, err := table.DecodeTableKV(kv.Key, kv.Value)
+		require.NoError(t, err)
+		assert.Equal(t, "CHAIN-body-body", decoded.Table)
+		assert.Equal(t, calcHeightHashKey(10, body.Hash), decoded.Primary)
+		if decoded.Index == "" {
+			assert.True(t, proto.Equal(body, decoded.Data))
+		} else {
+			assert.Equal(t, "hash", decoded.Index)
+			assert.Equal(t, body.Hash, decoded.IndexValue)
+		}
+		assert.Equal(t, "CHAIN-body-", dbm.MatchKeyPrefix("blockchain", kv.Key).Prefix)
+	}
+
+	p := dbm.MatchKeyPrefix("blockchain", blockLastHeight)
+	require.NotNil(t, p)
+	value := p.NewValue()
+	require.NoError(t, types.Decode(types.Encode(&types.Int64{Data: 10}), value))
+	assert.Equal(t, int64(10), value.(*types.Int64).Data)
+	assert.Equal(t, string(heightToHeaderPrefix), dbm.MatchKeyPrefix("blockchain", calcHeightToBlockHeaderKey(1)).Prefix)
+	assert.Equal(t, string(archiveIndexPrefix), dbm.MatchKeyPrefix("blockchain", calcArchiveKey(1)).Prefix)
+}
@@ -79,9 +79,21 @@ func (chain *BlockChain) localSet(msg *queue.Message) {
 	msg.Reply(chain.client.NewMessage("", types.EventLocalSet, nil))
 }
 
-//创建 localdb transaction
+//创建 localdb transaction, 参数为高度时读取开启localmvcc之后保存的该高度的数据
 func (chain *BlockChain) localNew(msg *queue.Message) {
-	tx := db.NewLocalDB(chain.blockStore.db)
+	maindb := chain.blockStore.db
+	if height, ok := (msg.Data).(*types.Int64); ok {
+		trashHeight, err := chain.blockStore.loadFlag(types.LocalMVCCTrashHeight)
+		if err == nil && height.Data < trashHeight {
+			err = types.ErrLocalDBPruned
+		}
+		if err != nil {
+			msg.Reply(chain.client.NewMessage("", types.EventLocalNew, err))
+			return
+		}
+		maindb = db.NewLocalMVCCDB(maindb, height.Data)
+	}
+	tx := db.NewLocalDB(maindb)
 	id := common.StorePointer(tx)
 	msg.Reply(chain.client.NewMessage("", types.EventLocalNew, &types.Int64{Data: id}))
 }
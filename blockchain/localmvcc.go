@@ -0,0 +1,74 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"time"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+//LocalMVCCChain 执行器开启localmvcc并且设置了保留高度时, 启动过期版本的回收
+func (chain *BlockChain) LocalMVCCChain() {
+	mcfg := chain.client.GetConfig().GetModuleConfig().Exec
+	if !mcfg.EnableLocalMVCC || mcfg.LocalMVCCRetainHeight <= 0 {
+		return
+	}
+	chain.reducewg.Add(1)
+	go chain.LocalMVCCTrashRoutine()
+}
+
+//LocalMVCCTrashRoutine 定时回收超过保留高度的localdb多版本数据
+func (chain *BlockChain) LocalMVCCTrashRoutine() {
+	defer chain.reducewg.Done()
+
+	flagHeight, err := chain.blockStore.loadFlag(types.LocalMVCCTrashHeight)
+	if err != nil {
+		panic(err)
+	}
+	// 10s检测一次是否可以进行回收
+	checkTicker := time.NewTicker(10 * time.Second)
+	defer checkTicker.Stop()
+	for {
+		select {
+		case <-chain.quit:
+			return
+		case <-checkTicker.C:
+			flagHeight = chain.TryTrashLocalMVCC(flagHeight, 100)
+		}
+	}
+}
+
+//TryTrashLocalMVCC 每隔rangeHeight个区块回收一次, 返回已经回收的高度
+//回收需要遍历全部的多版本数据, 比较慢, 所以rangeHeight不宜过小
+func (chain *BlockChain) TryTrashLocalMVCC(flagHeight int64, rangeHeight int64) (newHeight int64) {
+	if rangeHeight <= 0 {
+		rangeHeight = 100
+	}
+	retain := chain.client.GetConfig().GetModuleConfig().Exec.LocalMVCCRetainHeight
+	if retain < ReduceHeight {
+		retain = ReduceHeight
+	}
+	trashHeight := chain.GetBlockHeight() - retain
+	if trashHeight/rangeHeight <= flagHeight/rangeHeight {
+		return flagHeight
+	}
+	//先记录回收高度, 回收过程中在更早的高度上查询会返回ErrLocalDBPruned
+	kv := types.FlagKV(types.LocalMVCCTrashHeight, trashHeight)
+	err := chain.blockStore.db.Set(kv.Key, kv.Value)
+	if err != nil {
+		chainlog.Error("TryTrashLocalMVCC save flag", "height", trashHeight, "err", err)
+		return flagHeight
+	}
+	start := time.Now()
+	err = dbm.TrashLocalMVCC(chain.blockStore.db, trashHeight)
+	if err != nil {
+		chainlog.Error("TryTrashLocalMVCC", "height", trashHeight, "err", err)
+		return flagHeight
+	}
+	chainlog.Info("TryTrashLocalMVCC", "height", trashHeight, "cost", time.Since(start))
+	return trashHeight
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/types"
 )
 
@@ -33,6 +34,7 @@ func (chain *BlockChain) checkClockDrift() {
 	}
 	now := types.Now()
 	drift := now.Sub(realnow)
+	metrics.ClockDrift.Update(int64(drift / time.Millisecond))
 	if drift < -driftThreshold || drift > driftThreshold {
 		warning := fmt.Sprintf("System clock seems off by %v, which can prevent network connectivity", drift)
 		howtofix := fmt.Sprintf("Please enable network time synchronisation in system settings")
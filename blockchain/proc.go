@@ -104,6 +104,14 @@ func (chain *BlockChain) ProcRecvMsg() {
 		case types.EventGetParaTxByTitleAndHeight:
 			go chain.processMsg(msg, reqnum, chain.getParaTxByTitleAndHeight)
 
+			//获取区块同步进度
+		case types.EventGetSyncProgress:
+			go chain.processMsg(msg, reqnum, chain.getSyncProgress)
+
+			//在线备份全部模块的数据库
+		case types.EventBackupDB:
+			go chain.processMsg(msg, reqnum, chain.backupDB)
+
 		default:
 			go chain.processMsg(msg, reqnum, chain.unknowMsg)
 		}
@@ -176,7 +184,13 @@ func (chain *BlockChain) addBlock(msg *queue.Message) {
 	reply.IsOk = true
 	blockpid := msg.Data.(*types.BlockPid)
 	//chainlog.Error("addBlock", "height", blockpid.Block.Height, "pid", blockpid.Pid)
-	if chain.GetDownloadSyncStatus() {
+	if handled, err := chain.headerSync.onBlock(blockpid); handled {
+		if err != nil {
+			chainlog.Error("headerSync onBlock", "height", blockpid.Block.Height, "err", err.Error())
+			reply.IsOk = false
+			reply.Msg = []byte(err.Error())
+		}
+	} else if chain.GetDownloadSyncStatus() {
 		err := chain.WriteBlockToDbTemp(blockpid.Block, true)
 		if err != nil {
 			chainlog.Error("WriteBlockToDbTemp", "height", blockpid.Block.Height, "err", err.Error())
@@ -645,3 +659,8 @@ func (chain *BlockChain) getParaTxByTitleAndHeight(msg *queue.Message) {
 	}
 	msg.Reply(chain.client.NewMessage("", types.EventReplyParaTxByTitle, reply))
 }
+
+//getSyncProgress 获取区块同步进度
+func (chain *BlockChain) getSyncProgress(msg *queue.Message) {
+	msg.Reply(chain.client.NewMessage("", types.EventGetSyncProgress, chain.GetSyncProgress()))
+}
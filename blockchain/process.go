@@ -9,10 +9,14 @@ import (
 	"container/list"
 	"math/big"
 	"sync/atomic"
+	"time"
 
 	"github.com/33cn/chain33/client/api"
 	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/difficulty"
+	"github.com/33cn/chain33/common/trace"
+	"github.com/33cn/chain33/metrics"
+	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/types"
 	"github.com/33cn/chain33/util"
 )
@@ -23,6 +27,10 @@ import (
 // 返回参数说明：是否主链，是否孤儿节点，具体err
 func (b *BlockChain) ProcessBlock(broadcast bool, block *types.BlockDetail, pid string, addBlock bool, sequence int64) (*types.BlockDetail, bool, bool, error) {
 	chainlog.Debug("ProcessBlock:Processing", "height", block.Block.Height, "blockHash", common.ToHex(block.Block.Hash(b.client.GetConfig())))
+	span := trace.StartSpan(trace.SpanContext{}, "blockchain.ProcessBlock")
+	defer span.End()
+	span.SetAttribute("height", block.Block.Height)
+	span.SetAttribute("pid", pid)
 
 	//blockchain close 时不再处理block
 	if atomic.LoadInt32(&b.isclosed) == 1 {
@@ -65,6 +73,16 @@ func (b *BlockChain) ProcessBlock(broadcast bool, block *types.BlockDetail, pid
 		return nil, false, false, types.ErrBlockExist
 	}
 
+	//检查点高度的区块哈希必须一致, 已经越过的检查点以下不接受分叉区块
+	if err := b.checkpoints.checkHash(block.Block.Height, blockHash); err != nil {
+		b.reportFaultPeer(pid, types.PeerFaultInvalidHeader, err)
+		return nil, false, false, err
+	}
+	if err := b.checkpoints.checkFork(block.Block.Height-1, b.bestChain.Height()); err != nil {
+		b.reportFaultPeer(pid, types.PeerFaultInvalidHeader, err)
+		return nil, false, false, err
+	}
+
 	// 判断本区块是否已经存在孤儿链中
 	exists = b.orphanPool.IsKnownOrphan(blockHash)
 	if exists {
@@ -98,14 +116,17 @@ func (b *BlockChain) ProcessBlock(broadcast bool, block *types.BlockDetail, pid
 	}
 
 	// 基本检测通过之后尝试添加block到主链上
-	return b.maybeAddBestChain(broadcast, block, pid, sequence)
+	return b.maybeAddBestChain(broadcast, block, pid, sequence, span)
 }
 
-//基本检测通过之后尝试将此block添加到主链上
-func (b *BlockChain) maybeAddBestChain(broadcast bool, block *types.BlockDetail, pid string, sequence int64) (*types.BlockDetail, bool, bool, error) {
+//基本检测通过之后尝试将此block添加到主链上, span 作为本次处理过程中执行区块的parent
+func (b *BlockChain) maybeAddBestChain(broadcast bool, block *types.BlockDetail, pid string, sequence int64, span *trace.Span) (*types.BlockDetail, bool, bool, error) {
 	b.chainLock.Lock()
 	defer b.chainLock.Unlock()
+	b.procSpan = span
+	defer func() { b.procSpan = nil }()
 
+	beg := time.Now()
 	blockHash := block.Block.Hash(b.client.GetConfig())
 	exists := b.blockExists(blockHash)
 	if exists {
@@ -115,6 +136,7 @@ func (b *BlockChain) maybeAddBestChain(broadcast bool, block *types.BlockDetail,
 	blockdetail, isMainChain, err := b.maybeAcceptBlock(broadcast, block, pid, sequence)
 
 	if err != nil {
+		span.SetError(err)
 		return nil, false, false, err
 	}
 	// 尝试处理blockHash对应的孤儿子节点
@@ -122,6 +144,7 @@ func (b *BlockChain) maybeAddBestChain(broadcast bool, block *types.BlockDetail,
 	if err != nil {
 		return nil, false, false, err
 	}
+	metrics.BlockProcessTime.UpdateSince(beg)
 	return blockdetail, isMainChain, false, nil
 }
 
@@ -269,6 +292,10 @@ func (b *BlockChain) connectBlock(node *blockNode, blockdetail *types.BlockDetai
 	}
 
 	// Make sure it's extending the end of the best chain.
+	span := trace.StartSpan(b.procSpan.Context(), "blockchain.connectBlock")
+	defer span.End()
+	span.SetAttribute("height", blockdetail.Block.Height)
+
 	parentHash := blockdetail.Block.GetParentHash()
 	if !bytes.Equal(parentHash, b.bestChain.Tip().hash) {
 		chainlog.Error("connectBlock hash err", "height", blockdetail.Block.Height, "Tip.height", b.bestChain.Tip().height)
@@ -286,8 +313,12 @@ func (b *BlockChain) connectBlock(node *blockNode, blockdetail *types.BlockDetai
 	block := blockdetail.Block
 	prevStateHash := b.bestChain.Tip().statehash
 	errReturn := (node.pid != "self")
-	blockdetail, _, err = execBlock(b.client, prevStateHash, block, errReturn, sync)
+	execSpan := trace.StartSpan(span.Context(), "blockchain.execBlock")
+	blockdetail, _, err = execBlock(queue.WithSpan(b.client, execSpan.Context()), prevStateHash, block, errReturn, sync)
+	execSpan.SetError(err)
+	execSpan.End()
 	if err != nil {
+		span.SetError(err)
 		//记录执行出错的block信息,需要过滤掉一些特殊的错误，不计入故障中，尝试再次执行
 		if IsRecordFaultErr(err) {
 			b.RecordFaultPeer(node.pid, block.Height, node.hash, err)
@@ -311,6 +342,7 @@ func (b *BlockChain) connectBlock(node *blockNode, blockdetail *types.BlockDetai
 	}
 
 	beg := types.Now()
+	writeSpan := trace.StartSpan(span.Context(), "blockchain.writeBlock")
 	// 写入磁盘 批量将block信息写入磁盘
 	newbatch := b.blockStore.NewBatch(sync)
 
@@ -354,6 +386,7 @@ func (b *BlockChain) connectBlock(node *blockNode, blockdetail *types.BlockDetai
 		chainlog.Error("connectBlock newbatch.Write", "err", err)
 		panic(err)
 	}
+	writeSpan.End()
 	chainlog.Debug("connectBlock write db", "height", block.Height, "batchsync", sync, "cost", types.Since(beg), "hash", common.ToHex(blockdetail.Block.Hash(cfg)))
 
 	// 更新最新的高度和header
@@ -365,7 +398,7 @@ func (b *BlockChain) connectBlock(node *blockNode, blockdetail *types.BlockDetai
 
 	b.query.updateStateHash(blockdetail.GetBlock().GetStateHash())
 
-	err = b.SendAddBlockEvent(blockdetail)
+	err = b.sendAddBlockEvent(queue.WithSpan(b.client, span.Context()), blockdetail)
 	if err != nil {
 		chainlog.Debug("connectBlock SendAddBlockEvent", "err", err)
 	}
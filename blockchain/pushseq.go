@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/types"
 )
 
@@ -122,6 +123,7 @@ func (p *pushseq) runTask(input pushNotify) {
 			select {
 			case cb = <-in.cb:
 				if cb.URL == "" {
+					metrics.PushSeqLag.Delete(cb.Name)
 					return
 				}
 				p.trigeRun(run, 0)
@@ -135,6 +137,7 @@ func (p *pushseq) runTask(input pushNotify) {
 				if lastseq == -1 {
 					lastseq = p.pushseqStore.GetLastPushSeq(cb.Name)
 				}
+				metrics.PushSeqLag.Update(cb.Name, maxseq-lastseq)
 				if lastseq >= maxseq {
 					p.trigeRun(run, 100*time.Millisecond)
 					continue
@@ -158,6 +161,7 @@ func (p *pushseq) runTask(input pushNotify) {
 				}
 				//update seqid
 				lastseq = updateSeq
+				metrics.PushSeqLag.Update(cb.Name, maxseq-lastseq)
 				p.trigeRun(run, 0)
 			}
 		}
@@ -43,3 +43,57 @@ func TestTryReduceLocalDB(t *testing.T) {
 		assert.Equal(t, flagHeight, int64((i+1)*count+1))
 	}
 }
+
+func TestTryArchive(t *testing.T) {
+	cfg := testnode.GetDefaultConfig()
+	mcfg := cfg.GetModuleConfig().BlockChain
+	mcfg.EnableArchive = true
+	mcfg.ArchiveReserveHeight = 2
+	mcfg.ArchiveSegmentSize = 1024
+	mock33 := testnode.NewWithConfig(cfg, nil)
+	defer mock33.Close()
+	chain := mock33.GetBlockChain()
+
+	blockchain.ReduceHeight = 0
+	defer func() {
+		blockchain.ReduceHeight = 10000
+	}()
+
+	count := 10
+	txs := util.GenCoinsTxs(cfg, mock33.GetGenesisKey(), int64(count))
+	for j := 0; j < len(txs); j++ {
+		reply, err := mock33.GetAPI().SendTx(txs[j])
+		assert.Nil(t, err)
+		assert.Equal(t, reply.IsOk, true)
+		mock33.WaitHeight(int64(j + 1))
+	}
+	flagHeight := chain.TryArchive(0, 5)
+	assert.Equal(t, int64(count-2+1), flagHeight)
+	//未到下一个归档间隔不做处理
+	assert.Equal(t, flagHeight, chain.TryArchive(flagHeight, 5))
+
+	_, err := chain.GetDB().Get([]byte("CHAIN-archive-000000000003"))
+	assert.Nil(t, err)
+
+	//已归档的区块和交易透明读取
+	for j := 0; j < len(txs); j++ {
+		detail, err := chain.GetBlock(int64(j + 1))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(detail.Block.Txs))
+		assert.Equal(t, 1, len(detail.Receipts))
+		assert.Equal(t, txs[j].Hash(), detail.Block.Txs[0].Hash())
+
+		txresult, err := chain.GetTxResultFromDb(txs[j].Hash())
+		assert.Nil(t, err)
+		assert.Equal(t, int64(j+1), txresult.Height)
+		assert.Equal(t, txs[j].Hash(), txresult.Tx.Hash())
+		assert.NotNil(t, txresult.Receiptdate)
+		assert.True(t, len(txresult.Receiptdate.Logs) > 0)
+		assert.NotNil(t, txresult.Receiptdate.Logs[0].Log)
+	}
+	hash, err := chain.GetStore().GetBlockHashByHeight(3)
+	assert.Nil(t, err)
+	detail, err := chain.GetStore().LoadBlockByHash(hash)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), detail.Block.Height)
+}
@@ -24,6 +24,10 @@ func (chain *BlockChain) Upgrade() {
 	chain.UpgradePlugin()
 	chainlog.Info("chain reduce start")
 	chain.ReduceChain()
+	chainlog.Info("chain archive start")
+	chain.ArchiveChain()
+	chainlog.Info("chain localmvcc trash start")
+	chain.LocalMVCCChain()
 }
 
 // UpgradePlugin 升级插件
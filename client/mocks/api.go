@@ -37,6 +37,29 @@ func (_m *QueueProtocolAPI) AddSeqCallBack(param *types.BlockSeqCB) (*types.Repl
 	return r0, r1
 }
 
+// BanPeer provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) BanPeer(param *types.ReqBanPeer) (*types.Reply, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.Reply
+	if rf, ok := ret.Get(0).(func(*types.ReqBanPeer) *types.Reply); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Reply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqBanPeer) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Close provides a mock function with given fields:
 func (_m *QueueProtocolAPI) Close() {
 	_m.Called()
@@ -134,6 +157,29 @@ func (_m *QueueProtocolAPI) GetAddrOverview(param *types.ReqAddr) (*types.AddrOv
 	return r0, r1
 }
 
+// GetBannedPeers provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) GetBannedPeers(param *types.ReqBannedPeers) (*types.BannedPeers, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.BannedPeers
+	if rf, ok := ret.Get(0).(func(*types.ReqBannedPeers) *types.BannedPeers); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.BannedPeers)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqBannedPeers) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlockByHashes provides a mock function with given fields: param
 func (_m *QueueProtocolAPI) GetBlockByHashes(param *types.ReqHashes) (*types.BlockDetails, error) {
 	ret := _m.Called(param)
@@ -702,6 +748,29 @@ func (_m *QueueProtocolAPI) IsSync() (*types.Reply, error) {
 	return r0, r1
 }
 
+// GetSyncProgress provides a mock function with given fields:
+func (_m *QueueProtocolAPI) GetSyncProgress() (*types.SyncProgress, error) {
+	ret := _m.Called()
+
+	var r0 *types.SyncProgress
+	if rf, ok := ret.Get(0).(func() *types.SyncProgress); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.SyncProgress)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListSeqCallBack provides a mock function with given fields:
 func (_m *QueueProtocolAPI) ListSeqCallBack() (*types.BlockSeqCBs, error) {
 	ret := _m.Called()
@@ -1202,6 +1271,75 @@ func (_m *QueueProtocolAPI) StoreList(param *types.StoreList) (*types.StoreListR
 	return r0, r1
 }
 
+// GetStorePruneStatus provides a mock function with given fields:
+func (_m *QueueProtocolAPI) GetStorePruneStatus() (*types.StorePruneStatus, error) {
+	ret := _m.Called()
+
+	var r0 *types.StorePruneStatus
+	if rf, ok := ret.Get(0).(func() *types.StorePruneStatus); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.StorePruneStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetStorePrunePause provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) SetStorePrunePause(param *types.ReqStorePrune) (*types.StorePruneStatus, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.StorePruneStatus
+	if rf, ok := ret.Get(0).(func(*types.ReqStorePrune) *types.StorePruneStatus); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.StorePruneStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqStorePrune) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BackupDB provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) BackupDB(param *types.ReqBackupDB) (*types.BackupManifest, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.BackupManifest
+	if rf, ok := ret.Get(0).(func(*types.ReqBackupDB) *types.BackupManifest); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.BackupManifest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqBackupDB) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // StoreMemSet provides a mock function with given fields: param
 func (_m *QueueProtocolAPI) StoreMemSet(param *types.StoreSetWithSync) (*types.ReplyHash, error) {
 	ret := _m.Called(param)
@@ -1271,6 +1409,29 @@ func (_m *QueueProtocolAPI) StoreSet(param *types.StoreSetWithSync) (*types.Repl
 	return r0, r1
 }
 
+// UnbanPeer provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) UnbanPeer(param *types.ReqBanPeer) (*types.Reply, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.Reply
+	if rf, ok := ret.Get(0).(func(*types.ReqBanPeer) *types.Reply); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Reply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqBanPeer) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Version provides a mock function with given fields:
 func (_m *QueueProtocolAPI) Version() (*types.VersionInfo, error) {
 	ret := _m.Called()
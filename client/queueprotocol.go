@@ -6,6 +6,7 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -431,6 +432,41 @@ func (q *QueueProtocol) IsSync() (*types.Reply, error) {
 	return nil, err
 }
 
+// GetSyncProgress query the block sync progress
+func (q *QueueProtocol) GetSyncProgress() (*types.SyncProgress, error) {
+	msg, err := q.send(blockchainKey, types.EventGetSyncProgress, &types.ReqNil{})
+	if err != nil {
+		log.Error("GetSyncProgress", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.SyncProgress); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("GetSyncProgress", "Error", err.Error())
+	return nil, err
+}
+
+// BackupDB backup the databases of all modules at block boundary
+func (q *QueueProtocol) BackupDB(param *types.ReqBackupDB) (*types.BackupManifest, error) {
+	if param == nil || param.GetDir() == "" {
+		err := types.ErrInvalidParam
+		log.Error("BackupDB", "Error", err)
+		return nil, err
+	}
+	msg, err := q.send(blockchainKey, types.EventBackupDB, param)
+	if err != nil {
+		log.Error("BackupDB", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.BackupManifest); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("BackupDB", "Error", err.Error())
+	return nil, err
+}
+
 // IsNtpClockSync query the ntp clock sync state
 func (q *QueueProtocol) IsNtpClockSync() (*types.Reply, error) {
 	msg, err := q.send(blockchainKey, types.EventIsNtpClockSync, &types.ReqNil{})
@@ -603,6 +639,54 @@ func (q *QueueProtocol) GetNetInfo(req *types.P2PGetNetInfoReq) (*types.NodeNetI
 	return nil, err
 }
 
+// BanPeer ban the peer
+func (q *QueueProtocol) BanPeer(req *types.ReqBanPeer) (*types.Reply, error) {
+	return q.sendP2PReply("BanPeer", types.EventBanPeer, req)
+}
+
+// UnbanPeer unban the peer
+func (q *QueueProtocol) UnbanPeer(req *types.ReqBanPeer) (*types.Reply, error) {
+	return q.sendP2PReply("UnbanPeer", types.EventUnbanPeer, req)
+}
+
+func (q *QueueProtocol) sendP2PReply(funcName string, ty int64, req *types.ReqBanPeer) (*types.Reply, error) {
+	if req == nil || req.GetPid() == "" {
+		err := types.ErrInvalidParam
+		log.Error(funcName, "Error", err)
+		return nil, err
+	}
+	msg, err := q.send(p2pKey, ty, req)
+	if err != nil {
+		log.Error(funcName, "Error", err.Error())
+		return nil, err
+	}
+	reply, ok := msg.GetData().(*types.Reply)
+	if !ok {
+		err = types.ErrTypeAsset
+		log.Error(funcName, "Error", err.Error())
+		return nil, err
+	}
+	if !reply.GetIsOk() {
+		return nil, errors.New(string(reply.GetMsg()))
+	}
+	return reply, nil
+}
+
+// GetBannedPeers get the banned peers
+func (q *QueueProtocol) GetBannedPeers(req *types.ReqBannedPeers) (*types.BannedPeers, error) {
+	msg, err := q.send(p2pKey, types.EventGetBannedPeers, req)
+	if err != nil {
+		log.Error("GetBannedPeers", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.BannedPeers); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("GetBannedPeers", "Error", err.Error())
+	return nil, err
+}
+
 // StoreSet set value by statehash and key to statedb
 func (q *QueueProtocol) StoreSet(param *types.StoreSetWithSync) (*types.ReplyHash, error) {
 	if param == nil {
@@ -768,6 +852,41 @@ func (q *QueueProtocol) StoreGetTotalCoins(param *types.IterateRangeByStateHash)
 	return nil, err
 }
 
+// GetStorePruneStatus query the state pruning progress of store
+func (q *QueueProtocol) GetStorePruneStatus() (*types.StorePruneStatus, error) {
+	msg, err := q.send(storeKey, types.EventStorePruneStatus, &types.ReqNil{})
+	if err != nil {
+		log.Error("GetStorePruneStatus", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.StorePruneStatus); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("GetStorePruneStatus", "Error", err.Error())
+	return nil, err
+}
+
+// SetStorePrunePause pause or resume the state pruning of store
+func (q *QueueProtocol) SetStorePrunePause(param *types.ReqStorePrune) (*types.StorePruneStatus, error) {
+	if param == nil {
+		err := types.ErrInvalidParam
+		log.Error("SetStorePrunePause", "Error", err)
+		return nil, err
+	}
+	msg, err := q.send(storeKey, types.EventStorePrunePause, param)
+	if err != nil {
+		log.Error("SetStorePrunePause", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.StorePruneStatus); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("SetStorePrunePause", "Error", err.Error())
+	return nil, err
+}
+
 // CloseQueue close client queue
 func (q *QueueProtocol) CloseQueue() (*types.Reply, error) {
 	return q.client.CloseQueue()
@@ -41,6 +41,12 @@ type QueueProtocolAPI interface {
 	PeerInfo(param *types.P2PGetPeerReq) (*types.PeerList, error)
 	// types.EventGetNetInfo
 	GetNetInfo(param *types.P2PGetNetInfoReq) (*types.NodeNetInfo, error)
+	// types.EventBanPeer
+	BanPeer(param *types.ReqBanPeer) (*types.Reply, error)
+	// types.EventUnbanPeer
+	UnbanPeer(param *types.ReqBanPeer) (*types.Reply, error)
+	// types.EventGetBannedPeers
+	GetBannedPeers(param *types.ReqBannedPeers) (*types.BannedPeers, error)
 	// --------------- p2p interfaces end
 	// +++++++++++++++ wallet interfaces begin
 	// types.EventLocalGet
@@ -79,6 +85,10 @@ type QueueProtocolAPI interface {
 	IsSync() (*types.Reply, error)
 	// types.EventIsNtpClockSync
 	IsNtpClockSync() (*types.Reply, error)
+	// types.EventGetSyncProgress
+	GetSyncProgress() (*types.SyncProgress, error)
+	// types.EventBackupDB
+	BackupDB(param *types.ReqBackupDB) (*types.BackupManifest, error)
 	// types.EventGetLastHeader
 	GetLastHeader() (*types.Header, error)
 
@@ -110,6 +120,10 @@ type QueueProtocolAPI interface {
 	StoreDel(param *types.StoreDel) (*types.ReplyHash, error)
 	StoreGetTotalCoins(*types.IterateRangeByStateHash) (*types.ReplyGetTotalCoins, error)
 	StoreList(param *types.StoreList) (*types.StoreListReply, error)
+	// types.EventStorePruneStatus
+	GetStorePruneStatus() (*types.StorePruneStatus, error)
+	// types.EventStorePrunePause
+	SetStorePrunePause(param *types.ReqStorePrune) (*types.StorePruneStatus, error)
 	// --------------- store interfaces end
 
 	// +++++++++++++++ other interfaces begin
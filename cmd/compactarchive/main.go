@@ -0,0 +1,38 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package main 重建区块归档段文件, 清理中断归档遗留的无效记录, 需在节点停止时执行
+package main
+
+import (
+	"flag"
+
+	"github.com/33cn/chain33/blockchain"
+	clog "github.com/33cn/chain33/common/log"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+)
+
+var datadir = flag.String("datadir", "", "data dir of chain33, include logs and datas")
+var configPath = flag.String("f", "chain33.toml", "configfile")
+
+func main() {
+	clog.SetLogLevel("info")
+	flag.Parse()
+	cfg := types.NewChain33Config(types.ReadFile(*configPath))
+	mcfg := cfg.GetModuleConfig()
+	if *datadir != "" {
+		util.ResetDatadir(mcfg, *datadir)
+	}
+	if !mcfg.BlockChain.EnableArchive {
+		log.Info("archive is not enabled")
+		return
+	}
+	err := blockchain.CompactArchive(mcfg.BlockChain)
+	if err != nil {
+		panic(err)
+	}
+	log.Info("compact archive done")
+}
@@ -0,0 +1,263 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package main 只读方式查看节点停止之后的模块数据库, 统计key前缀, 按照登记的类型解析数据以及比较两个数据库
+// 默认按照前缀统计key的数目和大小, -list 列出数据, -get 查询单个key, -diff 和另一个目录下的同名数据库比较
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/33cn/chain33/blockchain"
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/db/table"
+	clog "github.com/33cn/chain33/common/log"
+	_ "github.com/33cn/chain33/system/p2p/dht"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	_ "github.com/33cn/chain33/system/store/flat"
+	_ "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	_ "github.com/33cn/chain33/wallet/common"
+)
+
+//modules 支持查看的数据库
+var modules = []string{"blockchain", "store", "wallet", "p2p"}
+
+var (
+	configPath = flag.String("f", "chain33.toml", "configfile")
+	datadir    = flag.String("datadir", "", "data dir of chain33, include logs and datas")
+	module     = flag.String("db", "blockchain", "database to inspect: "+strings.Join(modules, ", "))
+	dbPath     = flag.String("path", "", "database dir, default from the config file")
+	driver     = flag.String("driver", "", "database driver, default from the config file")
+	prefix     = flag.String("prefix", "", "key prefix, 0x for hex")
+	list       = flag.Bool("list", false, "list keys and decoded values under the prefix")
+	limit      = flag.Int("limit", 20, "max keys to list")
+	getKey     = flag.String("get", "", "decode the value of a key, 0x for hex")
+	diffPath   = flag.String("diff", "", "compare with the database of the same name under this dir")
+	samples    = flag.Int("samples", 5, "max different keys to print for each prefix when diff")
+)
+
+func main() {
+	clog.SetLogLevel("error")
+	flag.Parse()
+	name, drv, dir, cache, err := dbConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	db, err := dbm.NewReadOnlyDB(name, drv, dir, cache)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open db", name, dir, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	keyPrefix, err := parseKey(*prefix)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "prefix", err)
+		os.Exit(1)
+	}
+	switch {
+	case *getKey != "":
+		err = get(db, *getKey)
+	case *diffPath != "":
+		err = diff(db, name, drv, cache, keyPrefix)
+	case *list:
+		err = listKeys(db, keyPrefix)
+	default:
+		err = stat(db, keyPrefix)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func dbConfig() (name, drv, dir string, cache int32, err error) {
+	cfg := types.NewChain33Config(types.ReadFile(*configPath))
+	mcfg := cfg.GetModuleConfig()
+	if *datadir != "" {
+		util.ResetDatadir(mcfg, *datadir)
+	}
+	name = *module
+	switch name {
+	case "blockchain":
+		drv, dir, cache = mcfg.BlockChain.Driver, mcfg.BlockChain.DbPath, mcfg.BlockChain.DbCache
+	case "store":
+		drv, dir, cache = mcfg.Store.Driver, mcfg.Store.DbPath, mcfg.Store.DbCache
+	case "wallet":
+		drv, dir, cache = mcfg.Wallet.Driver, mcfg.Wallet.DbPath, mcfg.Wallet.DbCache
+	case "p2p":
+		//dht的地址簿数据库
+		name = "addrbook"
+		drv, dir, cache = mcfg.P2P.Driver, filepath.Join(mcfg.P2P.DbPath, p2pty.DHTTypeName), mcfg.P2P.DbCache
+	default:
+		return "", "", "", 0, fmt.Errorf("unknown db %s, supported: %s", name, strings.Join(modules, ", "))
+	}
+	if *dbPath != "" {
+		dir = *dbPath
+	}
+	if *driver != "" {
+		drv = *driver
+	}
+	return name, drv, dir, cache, nil
+}
+
+func parseKey(key string) ([]byte, error) {
+	if strings.HasPrefix(key, "0x") {
+		return common.FromHex(key)
+	}
+	return []byte(key), nil
+}
+
+//formatKey 可打印的key直接输出, 否则可打印的部分只保留到最后一个分隔符以及之后的数字(例如高度),
+//避免把hash开头的可打印字节当作前缀, 剩余部分输出hex
+func formatKey(key []byte) string {
+	n := 0
+	for n < len(key) && key[n] >= 0x20 && key[n] <= 0x7e {
+		n++
+	}
+	if n == len(key) {
+		return string(key)
+	}
+	cut := 0
+	for i := 0; i < n; i++ {
+		if key[i] == ':' || key[i] == '-' {
+			cut = i + 1
+		}
+	}
+	for cut < n && key[cut] >= '0' && key[cut] <= '9' {
+		cut++
+	}
+	if cut == 0 {
+		return common.ToHex(key)
+	}
+	return string(key[:cut]) + "+" + common.ToHex(key[cut:])
+}
+
+//decodeValue 依次按照登记的表结构, 前缀对应的类型解析value
+func decodeValue(key, value []byte) string {
+	if kv, err := table.DecodeTableKV(key, value); err == nil {
+		s := fmt.Sprintf("table=%s", kv.Table)
+		if kv.Index != "" {
+			s += fmt.Sprintf(" index=%s", kv.Index)
+		}
+		if kv.IndexValue != nil {
+			s += fmt.Sprintf(" value=%s", formatKey(kv.IndexValue))
+		}
+		if kv.Primary != nil {
+			s += fmt.Sprintf(" primary=%s", formatKey(kv.Primary))
+		}
+		if kv.Data != nil {
+			s += " " + toJSON(kv.Data)
+		}
+		return s
+	}
+	if p := dbm.MatchKeyPrefix(*module, key); p != nil && p.NewValue != nil {
+		msg := p.NewValue()
+		if err := types.Decode(value, msg); err == nil {
+			return toJSON(msg)
+		}
+	}
+	return formatKey(value)
+}
+
+func toJSON(msg types.Message) string {
+	data, err := types.PBToJSON(msg)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+func stat(db dbm.DB, keyPrefix []byte) error {
+	stats, err := dbm.StatPrefixes(db, *module, keyPrefix)
+	if err != nil {
+		return err
+	}
+	var count, size int64
+	fmt.Printf("%-32s %12s %14s %14s  %s\n", "prefix", "count", "keysize", "valuesize", "desc")
+	for _, s := range stats {
+		fmt.Printf("%-32s %12d %14d %14d  %s\n", formatKey([]byte(s.Prefix)), s.Count, s.KeySize, s.ValueSize, s.Desc)
+		count += s.Count
+		size += s.KeySize + s.ValueSize
+	}
+	fmt.Printf("total %d keys, %d bytes\n", count, size)
+	return nil
+}
+
+func listKeys(db dbm.DB, keyPrefix []byte) error {
+	end := []byte(nil)
+	if len(keyPrefix) == 0 {
+		end = types.EmptyValue
+	}
+	it := db.Iterator(keyPrefix, end, false)
+	defer it.Close()
+	n := 0
+	for it.Rewind(); it.Valid() && n < *limit; it.Next() {
+		fmt.Printf("%s => %s\n", formatKey(it.Key()), decodeValue(it.Key(), it.Value()))
+		n++
+	}
+	return it.Error()
+}
+
+func get(db dbm.DB, k string) error {
+	key, err := parseKey(k)
+	if err != nil {
+		return err
+	}
+	value, err := db.Get(key)
+	if err != nil {
+		return err
+	}
+	if p := dbm.MatchKeyPrefix(*module, key); p != nil {
+		fmt.Println("prefix:", p.Prefix, p.Desc)
+	}
+	fmt.Println(decodeValue(key, value))
+	return nil
+}
+
+func diff(db dbm.DB, name, drv string, cache int32, keyPrefix []byte) error {
+	other, err := dbm.NewReadOnlyDB(name, drv, *diffPath, cache)
+	if err != nil {
+		return err
+	}
+	defer other.Close()
+	diffs, err := dbm.DiffDB(db, other, *module, keyPrefix, *samples)
+	if err != nil {
+		return err
+	}
+	differ := 0
+	fmt.Printf("%-32s %12s %12s %12s %12s\n", "prefix", "same", "changed", "onlyleft", "onlyright")
+	for _, d := range diffs {
+		fmt.Printf("%-32s %12d %12d %12d %12d\n", formatKey([]byte(d.Prefix)), d.Same, d.Changed, d.OnlyLeft, d.OnlyRight)
+		if !d.Differ() {
+			continue
+		}
+		differ++
+		for _, key := range d.Samples {
+			l, lerr := db.Get(key)
+			r, rerr := other.Get(key)
+			fmt.Printf("    %s\n", formatKey(key))
+			fmt.Printf("      left:  %s\n", formatDiffValue(key, l, lerr))
+			fmt.Printf("      right: %s\n", formatDiffValue(key, r, rerr))
+		}
+	}
+	if differ > 0 {
+		return fmt.Errorf("%d prefixes differ", differ)
+	}
+	return nil
+}
+
+func formatDiffValue(key, value []byte, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return decodeValue(key, value)
+}
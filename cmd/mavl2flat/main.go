@@ -0,0 +1,74 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package main 把mavl存储中最新区块的状态导入flat存储, 需在节点停止时执行
+// 导入之后的区块状态hash由flat存储计算, 和mavl不同, 网络中的全部节点需要同时切换
+package main
+
+import (
+	"flag"
+
+	"github.com/33cn/chain33/blockchain"
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	clog "github.com/33cn/chain33/common/log"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/system/store/flat"
+	mavl "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+)
+
+var datadir = flag.String("datadir", "", "data dir of chain33, include logs and datas")
+var configPath = flag.String("f", "chain33.toml", "configfile")
+var output = flag.String("o", "", "db path of flat store, default is store dbPath + \"-flat\"")
+
+type mavlConfig struct {
+	EnableMavlPrefix bool `json:"enableMavlPrefix"`
+	EnableMVCC       bool `json:"enableMVCC"`
+	EnableMavlPrune  bool `json:"enableMavlPrune"`
+}
+
+func main() {
+	clog.SetLogLevel("info")
+	flag.Parse()
+	cfg := types.NewChain33Config(types.ReadFile(*configPath))
+	mcfg := cfg.GetModuleConfig()
+	if *datadir != "" {
+		util.ResetDatadir(mcfg, *datadir)
+	}
+	if *output == "" {
+		*output = mcfg.Store.DbPath + "-flat"
+	}
+	var sub mavlConfig
+	if data, ok := cfg.GetSubConfig().Store["mavl"]; ok {
+		types.MustDecode(data, &sub)
+	}
+	treeCfg := &mavl.TreeConfig{
+		EnableMavlPrefix: sub.EnableMavlPrefix || sub.EnableMavlPrune,
+		EnableMVCC:       sub.EnableMVCC,
+	}
+
+	chainDB := dbm.NewDB("blockchain", mcfg.BlockChain.Driver, mcfg.BlockChain.DbPath, mcfg.BlockChain.DbCache)
+	defer chainDB.Close()
+	header, err := blockchain.LoadLastHeader(chainDB)
+	if err != nil {
+		panic(err)
+	}
+	storeDB := dbm.NewDB("store", mcfg.Store.Driver, mcfg.Store.DbPath, mcfg.Store.DbCache)
+	defer storeDB.Close()
+	tree := mavl.NewTree(storeDB, true, treeCfg)
+	err = tree.Load(header.StateHash)
+	if err != nil {
+		panic(err)
+	}
+	flatDB := dbm.NewDB("store", mcfg.Store.Driver, *output, mcfg.Store.DbCache)
+	defer flatDB.Close()
+	log.Info("migrate mavl to flat", "height", header.Height, "stateHash", common.ToHex(header.StateHash), "output", *output)
+	hash, err := flat.Import(flatDB, header.Height, header.StateHash, tree.Iterate)
+	if err != nil {
+		panic(err)
+	}
+	log.Info("migrate done, set [store] name=\"flat\" and dbPath to output", "height", header.Height, "flatHash", common.ToHex(hash))
+}
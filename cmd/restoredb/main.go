@@ -0,0 +1,43 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package main 从在线备份的目录恢复节点全部模块的数据库, 需在节点启动之前执行
+// 恢复之前校验备份清单, 每个数据库的校验和以及最新区块, 本节点已经存在的数据库不会被覆盖
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/33cn/chain33/blockchain"
+	"github.com/33cn/chain33/common"
+	clog "github.com/33cn/chain33/common/log"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+)
+
+var datadir = flag.String("datadir", "", "data dir of chain33, include logs and datas")
+var configPath = flag.String("f", "chain33.toml", "configfile")
+var backupDir = flag.String("backup", "", "backup dir created by the db backup rpc")
+
+func main() {
+	clog.SetLogLevel("info")
+	flag.Parse()
+	if *backupDir == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg := types.NewChain33Config(types.ReadFile(*configPath))
+	mcfg := cfg.GetModuleConfig()
+	if *datadir != "" {
+		util.ResetDatadir(mcfg, *datadir)
+	}
+	manifest, err := blockchain.RestoreBackup(cfg, *backupDir)
+	if err != nil {
+		log.Error("restore failed", "err", err)
+		os.Exit(1)
+	}
+	log.Info("restore done", "height", manifest.Height, "hash", common.ToHex(manifest.BlockHash), "dbs", len(manifest.Dbs))
+}
@@ -0,0 +1,230 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"path"
+	"sync"
+
+	"github.com/33cn/chain33/types"
+)
+
+//备份的步骤, 对应ReqSnapshotDB中的op
+const (
+	BackupBegin int32 = iota + 1 //获取快照, 调用方需要保证此时数据库没有写入
+	BackupWrite                  //把快照写入备份目录, 不支持快照时直接复制数据库, 调用方需要保证复制期间没有写入
+	BackupAbort                  //放弃备份, 释放快照
+)
+
+const (
+	backupBatchSize = 1024 * 1024
+	backupCache     = 64
+)
+
+//Snapshot 数据库某一时刻的只读视图
+type Snapshot interface {
+	IteratorDB
+	Release()
+}
+
+//Snapshotter 支持快照的数据库
+type Snapshotter interface {
+	NewSnapshot() (Snapshot, error)
+}
+
+//LocalPath 数据库在本地磁盘上的目录, memdb以及ssdb, pegasus等远程数据库不支持备份
+func LocalPath(name string, backend string, dir string) (string, error) {
+	switch backend {
+	case levelDBBackendStr, goLevelDBBackendStr, pebbleDBBackendStr:
+		return path.Join(dir, name+".db"), nil
+	case goBadgerDBBackendStr:
+		return dir, nil
+	}
+	return "", types.ErrNotSupport
+}
+
+//ChecksumDB 按顺序计算数据库中全部key和value的校验和, 用于恢复之前校验备份
+func ChecksumDB(src IteratorDB) (*types.BackupDBInfo, error) {
+	return scanDB(src, nil)
+}
+
+//CopyDB 把src中的全部数据写入dst, 返回写入的数据统计和校验和
+func CopyDB(src IteratorDB, dst DB) (*types.BackupDBInfo, error) {
+	batch := dst.NewBatch(true)
+	info, err := scanDB(src, func(key, value []byte) error {
+		//badger等数据库的batch不会复制数据, 迭代器的key和value在下一次迭代之后失效
+		batch.Set(cloneByte(key), cloneByte(value))
+		if batch.ValueSize() < backupBatchSize {
+			return nil
+		}
+		err := batch.Write()
+		if err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func scanDB(src IteratorDB, fn func(key, value []byte) error) (*types.BackupDBInfo, error) {
+	it := src.Iterator(nil, types.EmptyValue, false)
+	defer it.Close()
+	info := &types.BackupDBInfo{}
+	h := sha256.New()
+	var buf [binary.MaxVarintLen64]byte
+	for it.Rewind(); it.Valid(); it.Next() {
+		key, value := it.Key(), it.Value()
+		for _, data := range [][]byte{key, value} {
+			n := binary.PutUvarint(buf[:], uint64(len(data)))
+			h.Write(buf[:n])
+			h.Write(data)
+		}
+		info.Count++
+		info.Size += int64(len(key) + len(value))
+		if fn == nil {
+			continue
+		}
+		err := fn(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := it.Error()
+	if err != nil {
+		return nil, err
+	}
+	info.Checksum = h.Sum(nil)
+	return info, nil
+}
+
+//Backuper 模块数据库的在线备份, 分为获取快照和写入两步
+//获取快照时调用方需要暂停写入, 写入快照可以和数据库正常的读写同时进行;
+//不支持快照的数据库在写入时直接复制, 调用方需要在复制完成之前一直暂停写入
+type Backuper struct {
+	mtx    sync.Mutex
+	db     DB
+	name   string
+	driver string
+	dir    string
+	snap   Snapshot
+}
+
+//NewBackuper new, name和driver需要和打开数据库时一致
+func NewBackuper(db DB, name string, driver string) *Backuper {
+	return &Backuper{db: db, name: name, driver: driver}
+}
+
+//Begin 获取快照, dir为本数据库的备份目录
+func (b *Backuper) Begin(dir string) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.dir != "" {
+		return types.ErrBackupInProgress
+	}
+	_, err := LocalPath(b.name, b.driver, dir)
+	if err != nil {
+		return err
+	}
+	//不支持快照的数据库在Write时直接复制
+	if s, ok := b.db.(Snapshotter); ok {
+		snap, err := s.NewSnapshot()
+		if err != nil {
+			return err
+		}
+		b.snap = snap
+	}
+	b.dir = dir
+	return nil
+}
+
+//Snapshot 本次备份是否获取了快照, 没有快照时Write期间调用方需要暂停写入
+func (b *Backuper) Snapshot() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.snap != nil
+}
+
+//Write 把快照写入备份目录并释放快照, 没有快照时直接复制数据库
+func (b *Backuper) Write() (*types.BackupDBInfo, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.dir == "" {
+		return nil, types.ErrInvalidParam
+	}
+	defer b.reset()
+	var src IteratorDB = b.db
+	if b.snap != nil {
+		src = b.snap
+	}
+	info, err := b.copy(src, b.dir)
+	if err != nil {
+		return nil, err
+	}
+	info.Snapshot = b.snap != nil
+	return info, nil
+}
+
+//Abort 放弃备份, 已经写入的数据由调用方清理
+func (b *Backuper) Abort() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.reset()
+}
+
+//Handle 处理blockchain发来的快照请求
+func (b *Backuper) Handle(req *types.ReqSnapshotDB) (*types.BackupDBInfo, error) {
+	switch req.GetOp() {
+	case BackupBegin:
+		err := b.Begin(req.GetDir())
+		if err != nil {
+			return nil, err
+		}
+		return &types.BackupDBInfo{Name: b.name, Driver: b.driver, Path: req.GetDir(), Snapshot: b.Snapshot()}, nil
+	case BackupWrite:
+		return b.Write()
+	case BackupAbort:
+		b.Abort()
+		return &types.BackupDBInfo{Name: b.name, Driver: b.driver}, nil
+	}
+	return nil, types.ErrInvalidParam
+}
+
+func (b *Backuper) reset() {
+	if b.snap != nil {
+		b.snap.Release()
+	}
+	b.snap = nil
+	b.dir = ""
+}
+
+func (b *Backuper) copy(src IteratorDB, dir string) (*types.BackupDBInfo, error) {
+	dbCreator, ok := backends[b.driver]
+	if !ok {
+		return nil, types.ErrNotSupport
+	}
+	dst, err := dbCreator(b.name, dir, backupCache)
+	if err != nil {
+		return nil, err
+	}
+	defer dst.Close()
+	info, err := CopyDB(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	info.Name = b.name
+	info.Driver = b.driver
+	info.Path = dir
+	return info, nil
+}
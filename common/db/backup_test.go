@@ -0,0 +1,126 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBackuper(t *testing.T, driver string) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := NewDB("test", driver, filepath.Join(dir, "data"), 16)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i))))
+	}
+	expect, err := ChecksumDB(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), expect.Count)
+
+	b := NewBackuper(db, "test", driver)
+	_, err = b.Write()
+	assert.Equal(t, types.ErrInvalidParam, err)
+	backupDir := filepath.Join(dir, "backup")
+	_, err = b.Handle(&types.ReqSnapshotDB{Op: BackupBegin, Dir: backupDir})
+	require.NoError(t, err)
+	assert.Equal(t, types.ErrBackupInProgress, b.Begin(backupDir))
+
+	//获取快照之后的写入不在备份中
+	require.NoError(t, db.Set([]byte("key-100"), []byte("value-100")))
+	require.NoError(t, db.Delete([]byte("key-000")))
+	info, err := b.Handle(&types.ReqSnapshotDB{Op: BackupWrite})
+	require.NoError(t, err)
+	assert.True(t, info.Snapshot)
+	assert.Equal(t, "test", info.Name)
+	assert.Equal(t, driver, info.Driver)
+	assert.Equal(t, backupDir, info.Path)
+	assert.Equal(t, expect.Count, info.Count)
+	assert.Equal(t, expect.Size, info.Size)
+	assert.Equal(t, expect.Checksum, info.Checksum)
+
+	bdb := NewDB("test", driver, backupDir, 16)
+	defer bdb.Close()
+	restored, err := ChecksumDB(bdb)
+	require.NoError(t, err)
+	assert.Equal(t, expect, restored)
+	value, err := bdb.Get([]byte("key-000"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value-000"), value)
+
+	//放弃备份之后可以重新开始
+	require.NoError(t, b.Begin(filepath.Join(dir, "backup2")))
+	b.Abort()
+	require.NoError(t, b.Begin(filepath.Join(dir, "backup2")))
+	b.Abort()
+}
+
+func TestBackuper(t *testing.T) {
+	testBackuper(t, "leveldb")
+	testBackuper(t, "pebble")
+	testBackuper(t, "gobadgerdb")
+}
+
+//noSnapshotDB 不支持快照的数据库
+type noSnapshotDB struct {
+	DB
+}
+
+func TestBackuperNoSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := NewDB("test", "leveldb", filepath.Join(dir, "data"), 16)
+	defer db.Close()
+	require.NoError(t, db.Set([]byte("key-000"), []byte("value-000")))
+
+	b := NewBackuper(&noSnapshotDB{db}, "test", "leveldb")
+	backupDir := filepath.Join(dir, "backup")
+	info, err := b.Handle(&types.ReqSnapshotDB{Op: BackupBegin, Dir: backupDir})
+	require.NoError(t, err)
+	assert.False(t, info.Snapshot)
+	assert.False(t, b.Snapshot())
+
+	//没有快照时直接复制, 包含Write之前的全部写入
+	require.NoError(t, db.Set([]byte("key-001"), []byte("value-001")))
+	expect, err := ChecksumDB(db)
+	require.NoError(t, err)
+	info, err = b.Handle(&types.ReqSnapshotDB{Op: BackupWrite})
+	require.NoError(t, err)
+	assert.False(t, info.Snapshot)
+	assert.Equal(t, int64(2), info.Count)
+	assert.Equal(t, expect.Checksum, info.Checksum)
+
+	bdb := NewDB("test", "leveldb", backupDir, 16)
+	defer bdb.Close()
+	restored, err := ChecksumDB(bdb)
+	require.NoError(t, err)
+	assert.Equal(t, expect, restored)
+}
+
+func TestBackuperNotSupport(t *testing.T) {
+	db := NewDB("test", "memdb", "", 16)
+	defer db.Close()
+	b := NewBackuper(db, "test", "memdb")
+	assert.Equal(t, types.ErrNotSupport, b.Begin("backup"))
+	_, err := b.Handle(&types.ReqSnapshotDB{Op: 0})
+	assert.Equal(t, types.ErrInvalidParam, err)
+
+	p, err := LocalPath("test", "goleveldb", "data")
+	assert.Nil(t, err)
+	assert.Equal(t, "data/test.db", p)
+	p, err = LocalPath("test", "gobadgerdb", "data")
+	assert.Nil(t, err)
+	assert.Equal(t, "data", p)
+}
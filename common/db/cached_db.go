@@ -0,0 +1,454 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	log "github.com/33cn/chain33/common/log/log15"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+//cachedEntryOverhead 每个缓存项除key和value之外估算的内存开销
+const cachedEntryOverhead = 64
+
+var clog = log.New("module", "db.cached")
+
+//CachedDBOptions CachedDB配置
+type CachedDBOptions struct {
+	//用于metrics命名, 区分不同的数据库
+	Name string
+	//读缓存中key和value的总字节数上限, 超过之后淘汰最近最少使用的项
+	MaxBytes int64
+	//开启之后Set和Delete只写入内存, 调用Commit时批量写入数据库
+	WriteBack bool
+	//写回缓冲区的字节数上限, 超过之后立即写入数据库, 0表示只在Commit时写入
+	MaxDirtyBytes int64
+}
+
+type cachedEntry struct {
+	key   string
+	value []byte
+	//negative 表示数据库中没有这个key
+	negative bool
+}
+
+func (e *cachedEntry) size() int64 {
+	return int64(len(e.key) + len(e.value) + cachedEntryOverhead)
+}
+
+type dirtyEntry struct {
+	value   []byte
+	deleted bool
+}
+
+//CachedDB 可以包装任意DB的读缓存, 按照key和value的大小淘汰, 同时缓存不存在的key;
+//可选写回模式, 多次Set合并之后在Commit时批量写入.
+//写回模式下Iterator, NewBatch写入, BeginTx以及同步写入之前会先把缓冲区写入数据库
+type CachedDB struct {
+	DB
+	opts CachedDBOptions
+
+	mtx  sync.Mutex
+	lru  *list.List
+	keys map[string]*list.Element
+	size int64
+	//gen 每次写入数据库之后递增, 读取数据库期间有写入时不把读到的旧值放入缓存
+	gen uint64
+	//写回缓冲区, flushing为正在写入数据库的缓冲区, 写入完成之前仍然可以读到
+	dirty     map[string]*dirtyEntry
+	dirtySize int64
+	flushing  map[string]*dirtyEntry
+	flushMtx  sync.Mutex
+
+	hitCounter    metrics.Counter
+	missCounter   metrics.Counter
+	negHitCounter metrics.Counter
+	evictCounter  metrics.Counter
+	flushCounter  metrics.Counter
+}
+
+//NewCachedDB 包装db, 关闭CachedDB时会关闭db
+func NewCachedDB(db DB, opts *CachedDBOptions) *CachedDB {
+	c := &CachedDB{
+		DB:    db,
+		lru:   list.New(),
+		keys:  make(map[string]*list.Element),
+		dirty: make(map[string]*dirtyEntry),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	namespace := "db/cache/" + c.opts.Name + "/"
+	c.hitCounter = metrics.NewRegisteredCounter(namespace+"hit", nil)
+	c.missCounter = metrics.NewRegisteredCounter(namespace+"miss", nil)
+	c.negHitCounter = metrics.NewRegisteredCounter(namespace+"neghit", nil)
+	c.evictCounter = metrics.NewRegisteredCounter(namespace+"evict", nil)
+	c.flushCounter = metrics.NewRegisteredCounter(namespace+"flush", nil)
+	return c
+}
+
+//WrapCachedDB cacheSize(MB)大于0时给db加上读缓存, 否则直接返回db
+//写回模式需要调用者在合适的时机Commit, 这里只开启读缓存
+func WrapCachedDB(db DB, name string, cacheSize int32) DB {
+	if cacheSize <= 0 {
+		return db
+	}
+	return NewCachedDB(db, &CachedDBOptions{Name: name, MaxBytes: int64(cacheSize) << 20})
+}
+
+//Get 依次从写回缓冲区, 读缓存以及数据库中读取
+func (c *CachedDB) Get(key []byte) ([]byte, error) {
+	c.mtx.Lock()
+	value, err, ok := c.getCached(key)
+	gen := c.gen
+	c.mtx.Unlock()
+	if ok {
+		return value, err
+	}
+	c.missCounter.Inc(1)
+	value, err = c.DB.Get(key)
+	if err != nil && err != ErrNotFoundInDb {
+		return nil, err
+	}
+	c.mtx.Lock()
+	if c.gen == gen {
+		c.put(key, value, err == ErrNotFoundInDb)
+	}
+	c.mtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return cloneByte(value), nil
+}
+
+func (c *CachedDB) getCached(key []byte) ([]byte, error, bool) {
+	for _, buf := range []map[string]*dirtyEntry{c.dirty, c.flushing} {
+		if e, ok := buf[string(key)]; ok {
+			c.hitCounter.Inc(1)
+			if e.deleted {
+				return nil, ErrNotFoundInDb, true
+			}
+			return cloneByte(e.value), nil, true
+		}
+	}
+	elem, ok := c.keys[string(key)]
+	if !ok {
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(elem)
+	e := elem.Value.(*cachedEntry)
+	if e.negative {
+		c.negHitCounter.Inc(1)
+		return nil, ErrNotFoundInDb, true
+	}
+	c.hitCounter.Inc(1)
+	return cloneByte(e.value), nil, true
+}
+
+//put 更新读缓存, 需持有锁
+func (c *CachedDB) put(key, value []byte, negative bool) {
+	c.remove(string(key))
+	e := &cachedEntry{key: string(key), value: cloneByte(value), negative: negative}
+	if negative {
+		e.value = nil
+	}
+	if c.opts.MaxBytes <= 0 || e.size() > c.opts.MaxBytes {
+		return
+	}
+	c.keys[e.key] = c.lru.PushFront(e)
+	c.size += e.size()
+	for c.size > c.opts.MaxBytes {
+		elem := c.lru.Back()
+		c.remove(elem.Value.(*cachedEntry).key)
+		c.evictCounter.Inc(1)
+	}
+}
+
+func (c *CachedDB) remove(key string) {
+	elem, ok := c.keys[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(elem)
+	delete(c.keys, key)
+	c.size -= elem.Value.(*cachedEntry).size()
+}
+
+//Set 写回模式下写入缓冲区, 否则写入数据库并更新缓存
+func (c *CachedDB) Set(key []byte, value []byte) error {
+	if c.opts.WriteBack {
+		return c.setDirty(key, &dirtyEntry{value: cloneByte(value)})
+	}
+	err := c.DB.Set(key, value)
+	if err != nil {
+		return err
+	}
+	c.update(key, value, false)
+	return nil
+}
+
+//Delete 写回模式下写入缓冲区, 否则从数据库中删除并缓存为不存在
+func (c *CachedDB) Delete(key []byte) error {
+	if c.opts.WriteBack {
+		return c.setDirty(key, &dirtyEntry{deleted: true})
+	}
+	err := c.DB.Delete(key)
+	if err != nil {
+		return err
+	}
+	c.update(key, nil, true)
+	return nil
+}
+
+//SetSync 先写入缓冲区中的数据, 再同步写入
+func (c *CachedDB) SetSync(key []byte, value []byte) error {
+	err := c.Flush()
+	if err != nil {
+		return err
+	}
+	err = c.DB.SetSync(key, value)
+	if err != nil {
+		return err
+	}
+	c.update(key, value, false)
+	return nil
+}
+
+//DeleteSync 先写入缓冲区中的数据, 再同步删除
+func (c *CachedDB) DeleteSync(key []byte) error {
+	err := c.Flush()
+	if err != nil {
+		return err
+	}
+	err = c.DB.DeleteSync(key)
+	if err != nil {
+		return err
+	}
+	c.update(key, nil, true)
+	return nil
+}
+
+func (c *CachedDB) update(key, value []byte, negative bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.gen++
+	c.put(key, value, negative)
+}
+
+func (c *CachedDB) setDirty(key []byte, e *dirtyEntry) error {
+	c.mtx.Lock()
+	if old, ok := c.dirty[string(key)]; ok {
+		c.dirtySize -= int64(len(key) + len(old.value))
+	}
+	c.dirty[string(key)] = e
+	c.dirtySize += int64(len(key) + len(e.value))
+	full := c.opts.MaxDirtyBytes > 0 && c.dirtySize >= c.opts.MaxDirtyBytes
+	c.mtx.Unlock()
+	if full {
+		return c.Flush()
+	}
+	return nil
+}
+
+//Flush 把写回缓冲区中的数据批量写入数据库, 写入失败时数据保留在缓冲区中
+func (c *CachedDB) Flush() error {
+	c.flushMtx.Lock()
+	defer c.flushMtx.Unlock()
+	c.mtx.Lock()
+	if len(c.dirty) == 0 {
+		c.mtx.Unlock()
+		return nil
+	}
+	c.flushing = c.dirty
+	c.dirty = make(map[string]*dirtyEntry)
+	c.dirtySize = 0
+	c.mtx.Unlock()
+
+	batch := c.DB.NewBatch(false)
+	for key, e := range c.flushing {
+		if e.deleted {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Set([]byte(key), e.value)
+		}
+	}
+	err := batch.Write()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err != nil {
+		//缓冲区中更新的写入优先
+		for key, e := range c.flushing {
+			if _, ok := c.dirty[key]; !ok {
+				c.dirty[key] = e
+				c.dirtySize += int64(len(key) + len(e.value))
+			}
+		}
+		c.flushing = nil
+		clog.Error("Flush", "name", c.opts.Name, "err", err)
+		return err
+	}
+	c.gen++
+	for key, e := range c.flushing {
+		c.put([]byte(key), e.value, e.deleted)
+	}
+	c.flushing = nil
+	c.flushCounter.Inc(1)
+	return nil
+}
+
+//Begin 写回模式下开始新的一批写入, 不需要额外处理
+func (c *CachedDB) Begin() {
+}
+
+//Commit 把写回缓冲区中的数据写入数据库
+func (c *CachedDB) Commit() error {
+	return c.Flush()
+}
+
+//Rollback 丢弃写回缓冲区中还没有写入数据库的数据, 已经因为超过上限而写入的数据不能回滚
+func (c *CachedDB) Rollback() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.dirty = make(map[string]*dirtyEntry)
+	c.dirtySize = 0
+}
+
+//Iterator 迭代器直接读取数据库, 写回模式下先写入缓冲区中的数据
+func (c *CachedDB) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	err := c.Flush()
+	if err != nil {
+		clog.Error("Iterator flush", "name", c.opts.Name, "err", err)
+	}
+	return c.DB.Iterator(start, end, reverse)
+}
+
+//NewBatch batch写入之后更新缓存
+func (c *CachedDB) NewBatch(sync bool) Batch {
+	return &cachedBatch{Batch: c.DB.NewBatch(sync), db: c}
+}
+
+//BeginTx 事务提交之后删除事务中写入的key的缓存
+func (c *CachedDB) BeginTx() (TxKV, error) {
+	err := c.Flush()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := c.DB.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	return &cachedTx{TxKV: tx, db: c}, nil
+}
+
+//Close 写入缓冲区中的数据之后关闭数据库
+func (c *CachedDB) Close() {
+	err := c.Flush()
+	if err != nil {
+		clog.Error("Close flush", "name", c.opts.Name, "err", err)
+	}
+	c.DB.Close()
+}
+
+//Stats 在数据库的统计信息中增加缓存的统计
+func (c *CachedDB) Stats() map[string]string {
+	stats := c.DB.Stats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	stats["cache.hit"] = fmt.Sprint(c.hitCounter.Count())
+	stats["cache.miss"] = fmt.Sprint(c.missCounter.Count())
+	stats["cache.neghit"] = fmt.Sprint(c.negHitCounter.Count())
+	stats["cache.evict"] = fmt.Sprint(c.evictCounter.Count())
+	stats["cache.flush"] = fmt.Sprint(c.flushCounter.Count())
+	stats["cache.entries"] = fmt.Sprint(c.lru.Len())
+	stats["cache.size"] = fmt.Sprint(c.size)
+	stats["cache.dirty"] = fmt.Sprint(len(c.dirty))
+	stats["cache.dirtysize"] = fmt.Sprint(c.dirtySize)
+	return stats
+}
+
+type cachedOp struct {
+	key     []byte
+	value   []byte
+	deleted bool
+}
+
+type cachedBatch struct {
+	Batch
+	db  *CachedDB
+	ops []cachedOp
+}
+
+func (b *cachedBatch) Set(key, value []byte) {
+	b.Batch.Set(key, value)
+	b.ops = append(b.ops, cachedOp{key: cloneByte(key), value: cloneByte(value)})
+}
+
+func (b *cachedBatch) Delete(key []byte) {
+	b.Batch.Delete(key)
+	b.ops = append(b.ops, cachedOp{key: cloneByte(key), deleted: true})
+}
+
+//Write 先写入CachedDB缓冲区中的数据, 保证batch中的写入在之后生效
+func (b *cachedBatch) Write() error {
+	err := b.db.Flush()
+	if err != nil {
+		return err
+	}
+	err = b.Batch.Write()
+	if err != nil {
+		return err
+	}
+	b.db.mtx.Lock()
+	b.db.gen++
+	for _, op := range b.ops {
+		b.db.put(op.key, op.value, op.deleted)
+	}
+	b.db.mtx.Unlock()
+	//和底层batch一致, 提交之后的写入只包含新增的操作
+	b.ops = nil
+	return nil
+}
+
+func (b *cachedBatch) Reset() {
+	b.Batch.Reset()
+	b.ops = nil
+}
+
+type cachedTx struct {
+	TxKV
+	db   *CachedDB
+	keys [][]byte
+}
+
+func (tx *cachedTx) Set(key []byte, value []byte) error {
+	err := tx.TxKV.Set(key, value)
+	if err != nil {
+		return err
+	}
+	tx.keys = append(tx.keys, cloneByte(key))
+	return nil
+}
+
+func (tx *cachedTx) Commit() error {
+	err := tx.TxKV.Commit()
+	if err != nil {
+		return err
+	}
+	tx.db.mtx.Lock()
+	defer tx.db.mtx.Unlock()
+	tx.db.gen++
+	for _, key := range tx.keys {
+		tx.db.remove(string(key))
+	}
+	return nil
+}
+
@@ -0,0 +1,255 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCachedDB(t *testing.T, writeBack bool) (*CachedDB, func()) {
+	pebbledb, closer := newTestPebbleDB(t)
+	return NewCachedDB(pebbledb, &CachedDBOptions{Name: t.Name(), MaxBytes: 1024 * 1024, WriteBack: writeBack}), closer
+}
+
+func TestCachedDBSuite(t *testing.T) {
+	for _, writeBack := range []bool{false, true} {
+		for _, fn := range []func(*testing.T, DB){testDBIterator, testDBIteratorAllKey, testDBIteratorReserverExample,
+			testDBIteratorDel, testDBBoundary, testDBIteratorResult} {
+			db, closer := newTestCachedDB(t, writeBack)
+			fn(t, db)
+			closer()
+		}
+	}
+}
+
+func TestWrapCachedDB(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	assert.Equal(t, pebbledb, WrapCachedDB(pebbledb, t.Name(), 0))
+	db, ok := WrapCachedDB(pebbledb, t.Name(), 1).(*CachedDB)
+	require.True(t, ok)
+	assert.Equal(t, int64(1024*1024), db.opts.MaxBytes)
+	assert.False(t, db.opts.WriteBack)
+}
+
+func TestCachedDBReadThrough(t *testing.T) {
+	db, closer := newTestCachedDB(t, false)
+	defer closer()
+	require.NoError(t, db.DB.Set([]byte("key1"), []byte("value1")))
+
+	value, err := db.Get([]byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+	//返回值是复制的, 修改不影响缓存
+	value[0] = 'x'
+	value, err = db.Get([]byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	//不存在的key也被缓存
+	_, err = db.Get([]byte("key2"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	_, err = db.Get([]byte("key2"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	stats := db.Stats()
+	assert.Equal(t, "2", stats["cache.miss"])
+	assert.Equal(t, "1", stats["cache.hit"])
+	assert.Equal(t, "1", stats["cache.neghit"])
+	assert.Equal(t, "2", stats["cache.entries"])
+
+	//写入之后缓存更新
+	require.NoError(t, db.Set([]byte("key2"), []byte("value2")))
+	value, err = db.Get([]byte("key2"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value2"), value)
+	require.NoError(t, db.Delete([]byte("key1")))
+	_, err = db.Get([]byte("key1"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	_, err = db.DB.Get([]byte("key1"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+
+	//batch和事务的写入对缓存可见
+	batch := db.NewBatch(true)
+	batch.Set([]byte("key3"), []byte("value3"))
+	batch.Delete([]byte("key2"))
+	require.NoError(t, batch.Write())
+	value, err = db.Get([]byte("key3"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value3"), value)
+	_, err = db.Get([]byte("key2"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+
+	tx, err := db.BeginTx()
+	require.NoError(t, err)
+	require.NoError(t, tx.Set([]byte("key3"), []byte("value4")))
+	require.NoError(t, tx.Commit())
+	value, err = db.Get([]byte("key3"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value4"), value)
+}
+
+func TestCachedDBEvict(t *testing.T) {
+	mdb := newGoMemDB(t)
+	db := NewCachedDB(mdb, &CachedDBOptions{Name: t.Name(), MaxBytes: 10 * (cachedEntryOverhead + 8 + 100)})
+	for i := 0; i < 20; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key-%04d", i)), make([]byte, 100)))
+	}
+	stats := db.Stats()
+	assert.Equal(t, "10", stats["cache.entries"])
+	assert.Equal(t, "10", stats["cache.evict"])
+	//大的value淘汰更多的项
+	require.NoError(t, db.Set([]byte("big-0000"), make([]byte, 350)))
+	stats = db.Stats()
+	assert.Equal(t, "8", stats["cache.entries"])
+	//最早写入的已经被淘汰, 仍然可以从数据库读取
+	value, err := db.Get([]byte("key-0000"))
+	require.NoError(t, err)
+	assert.Equal(t, 100, len(value))
+	assert.Equal(t, "1", db.Stats()["cache.miss"])
+	//超过上限的value不缓存
+	require.NoError(t, db.Set([]byte("huge"), make([]byte, 4096)))
+	_, err = db.Get([]byte("huge"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", db.Stats()["cache.miss"])
+}
+
+func TestCachedDBWriteBack(t *testing.T) {
+	db, closer := newTestCachedDB(t, true)
+	defer closer()
+	require.NoError(t, db.DB.Set([]byte("key0"), []byte("value0")))
+
+	db.Begin()
+	require.NoError(t, db.Set([]byte("key1"), []byte("value1")))
+	require.NoError(t, db.Delete([]byte("key0")))
+	value, err := db.Get([]byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+	_, err = db.Get([]byte("key0"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	//Commit之前没有写入数据库
+	_, err = db.DB.Get([]byte("key1"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	_, err = db.DB.Get([]byte("key0"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", db.Stats()["cache.dirty"])
+
+	require.NoError(t, db.Commit())
+	value, err = db.DB.Get([]byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+	_, err = db.DB.Get([]byte("key0"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	stats := db.Stats()
+	assert.Equal(t, "0", stats["cache.dirty"])
+	assert.Equal(t, "1", stats["cache.flush"])
+
+	//Rollback丢弃没有写入的数据
+	db.Begin()
+	require.NoError(t, db.Set([]byte("key2"), []byte("value2")))
+	db.Rollback()
+	_, err = db.Get([]byte("key2"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+
+	//batch写入在缓冲区之后生效
+	require.NoError(t, db.Set([]byte("key3"), []byte("value3")))
+	batch := db.NewBatch(true)
+	batch.Set([]byte("key3"), []byte("batch3"))
+	require.NoError(t, batch.Write())
+	value, err = db.DB.Get([]byte("key3"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("batch3"), value)
+
+	//迭代器可以读到缓冲区中的数据
+	require.NoError(t, db.Set([]byte("key4"), []byte("value4")))
+	list := NewListHelper(db)
+	values := list.List([]byte("key"), nil, 0, ListASC)
+	assert.Equal(t, 3, len(values))
+
+	//超过上限自动写入
+	db.opts.MaxDirtyBytes = 32
+	require.NoError(t, db.Set([]byte("key5"), make([]byte, 16)))
+	_, err = db.DB.Get([]byte("key5"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	require.NoError(t, db.Set([]byte("key6"), make([]byte, 16)))
+	_, err = db.DB.Get([]byte("key5"))
+	require.NoError(t, err)
+}
+
+func TestCachedDBLocalDB(t *testing.T) {
+	db, closer := newTestCachedDB(t, false)
+	defer closer()
+	require.NoError(t, db.Set([]byte("key1"), []byte("value1")))
+	ldb := NewLocalDB(db)
+	value, err := ldb.Get([]byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+	assert.Equal(t, "1", db.Stats()["cache.hit"])
+	//localdb的写入只在内存中, 不会写入CachedDB
+	ldb.Begin()
+	require.NoError(t, ldb.Set([]byte("key2"), []byte("value2")))
+	require.NoError(t, ldb.Commit())
+	_, err = db.Get([]byte("key2"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+	values, err := ldb.List([]byte("key"), nil, 0, ListASC)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("value1"), []byte("value2")}, values)
+}
+
+//go test -race 检查并发读写, 结束之后缓存和数据库一致
+func TestCachedDBConcurrent(t *testing.T) {
+	for _, writeBack := range []bool{false, true} {
+		mdb, closer := newTestPebbleDB(t)
+		db := NewCachedDB(mdb, &CachedDBOptions{Name: t.Name(), MaxBytes: 4096, WriteBack: writeBack, MaxDirtyBytes: 512})
+		const keys, rounds = 32, 200
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					key := []byte(fmt.Sprintf("key-%02d-%d", i%keys, w))
+					if i%7 == 0 {
+						assert.NoError(t, db.Delete(key))
+						continue
+					}
+					assert.NoError(t, db.Set(key, []byte(fmt.Sprintf("%d", i))))
+					if i%50 == 0 {
+						assert.NoError(t, db.Commit())
+					}
+				}
+			}(w)
+		}
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					_, err := db.Get([]byte(fmt.Sprintf("key-%02d-%d", i%keys, i%4)))
+					if err != nil {
+						assert.Equal(t, ErrNotFoundInDb, err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		require.NoError(t, db.Commit())
+		//缓存和数据库一致
+		for w := 0; w < 4; w++ {
+			for k := 0; k < keys; k++ {
+				key := []byte(fmt.Sprintf("key-%02d-%d", k, w))
+				v1, err1 := db.Get(key)
+				v2, err2 := mdb.Get(key)
+				assert.Equal(t, err2, err1)
+				assert.Equal(t, v2, v1)
+			}
+		}
+		closer()
+	}
+}
@@ -3,7 +3,7 @@
 // license that can be found in the LICENSE file.
 
 // Package db 数据库操作底层接口定义以及实现包括：leveldb、
-// memdb、mvcc、badgerdb、pegasus、ssdb
+// memdb、mvcc、badgerdb、pegasus、ssdb、pebble
 package db
 
 import (
@@ -181,6 +181,7 @@ const (
 	goBadgerDBBackendStr  = "gobadgerdb"
 	ssDBBackendStr        = "ssdb"
 	goPegasusDbBackendStr = "pegasus"
+	pebbleDBBackendStr    = "pebble"
 )
 
 type dbCreator func(name string, dir string, cache int) (DB, error)
@@ -210,6 +211,25 @@ func NewDB(name string, backend string, dir string, cache int32) DB {
 	return db
 }
 
+//NewReadOnlyDB 只读方式打开数据库, 用于节点停止之后的离线检查工具, 目前支持leveldb和pebble
+func NewReadOnlyDB(name string, backend string, dir string, cache int32) (DB, error) {
+	switch backend {
+	case levelDBBackendStr, goLevelDBBackendStr:
+		db, err := NewGoLevelDBReadOnly(name, dir, int(cache))
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case pebbleDBBackendStr:
+		db, err := NewPebbleDBReadOnly(name, dir, int(cache))
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+	return nil, types.ErrNotSupport
+}
+
 //BaseDB 交易缓存
 type BaseDB struct {
 	cache *lru.ARCCache
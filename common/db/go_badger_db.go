@@ -188,6 +188,31 @@ func (db *GoBadgerDB) Stats() map[string]string {
 //Iterator 迭代器
 func (db *GoBadgerDB) Iterator(start, end []byte, reverse bool) Iterator {
 	txn := db.db.NewTransaction(false)
+	it := newGoBadgerDBIt(txn, start, end, reverse)
+	it.discard = true
+	return it
+}
+
+//NewSnapshot 获取数据库当前时刻的快照, 用只读事务实现, 之后的写入对快照不可见
+func (db *GoBadgerDB) NewSnapshot() (Snapshot, error) {
+	return &goBadgerSnapshot{txn: db.db.NewTransaction(false)}, nil
+}
+
+type goBadgerSnapshot struct {
+	txn *badger.Txn
+}
+
+//Iterator 快照上的迭代器, 关闭迭代器不会释放快照
+func (s *goBadgerSnapshot) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	return newGoBadgerDBIt(s.txn, start, end, reverse)
+}
+
+//Release 释放快照
+func (s *goBadgerSnapshot) Release() {
+	s.txn.Discard()
+}
+
+func newGoBadgerDBIt(txn *badger.Txn, start, end []byte, reverse bool) *goBadgerDBIt {
 	opts := badger.DefaultIteratorOptions
 	opts.Reverse = reverse
 	it := txn.NewIterator(opts)
@@ -202,14 +227,16 @@ func (db *GoBadgerDB) Iterator(start, end []byte, reverse bool) Iterator {
 	} else {
 		it.Seek(start)
 	}
-	return &goBadgerDBIt{it, itBase{start, end, reverse}, txn, nil}
+	return &goBadgerDBIt{Iterator: it, itBase: itBase{start, end, reverse}, txn: txn}
 }
 
 type goBadgerDBIt struct {
 	*badger.Iterator
 	itBase
 	txn *badger.Txn
-	err error
+	//迭代器独占事务时关闭迭代器同时释放事务
+	discard bool
+	err     error
 }
 
 //Next next
@@ -237,7 +264,9 @@ func (it *goBadgerDBIt) Seek(key []byte) bool {
 //Close 关闭
 func (it *goBadgerDBIt) Close() {
 	it.Iterator.Close()
-	it.txn.Discard()
+	if it.discard {
+		it.txn.Discard()
+	}
 }
 
 //Valid 是否合法
@@ -66,6 +66,15 @@ type GoLevelDB struct {
 
 //NewGoLevelDB new
 func NewGoLevelDB(name string, dir string, cache int) (*GoLevelDB, error) {
+	return openGoLevelDB(name, dir, cache, false)
+}
+
+//NewGoLevelDBReadOnly 只读方式打开, 数据库不存在或者损坏时直接返回错误, 不做修复
+func NewGoLevelDBReadOnly(name string, dir string, cache int) (*GoLevelDB, error) {
+	return openGoLevelDB(name, dir, cache, true)
+}
+
+func openGoLevelDB(name string, dir string, cache int, readOnly bool) (*GoLevelDB, error) {
 	dbPath := path.Join(dir, name+".db")
 	if cache == 0 {
 		cache = 64
@@ -83,8 +92,10 @@ func NewGoLevelDB(name string, dir string, cache int) (*GoLevelDB, error) {
 		BlockCacheCapacity:     cache / 2 * opt.MiB,
 		WriteBuffer:            cache / 4 * opt.MiB, // Two of these are used internally
 		Filter:                 filter.NewBloomFilter(10),
+		ReadOnly:               readOnly,
+		ErrorIfMissing:         readOnly,
 	})
-	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+	if _, corrupted := err.(*errors.ErrCorrupted); corrupted && !readOnly {
 		db, err = leveldb.RecoverFile(dbPath, nil)
 	}
 	if err != nil {
@@ -244,6 +255,38 @@ func (db *GoLevelDB) Iterator(start []byte, end []byte, reverse bool) Iterator {
 	return &goLevelDBIt{it, itBase{start, end, reverse}}
 }
 
+//NewSnapshot 获取数据库当前时刻的快照, 之后的写入对快照不可见
+func (db *GoLevelDB) NewSnapshot() (Snapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		llog.Error("NewSnapshot", "error", err)
+		return nil, err
+	}
+	return &goLevelDBSnapshot{snap: snap}, nil
+}
+
+type goLevelDBSnapshot struct {
+	snap *leveldb.Snapshot
+}
+
+//Iterator 快照上的迭代器
+func (s *goLevelDBSnapshot) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	if end == nil {
+		end = bytesPrefix(start)
+	}
+	if bytes.Equal(end, types.EmptyValue) {
+		end = nil
+	}
+	r := &util.Range{Start: start, Limit: end}
+	it := s.snap.NewIterator(r, nil)
+	return &goLevelDBIt{it, itBase{start, end, reverse}}
+}
+
+//Release 释放快照
+func (s *goLevelDBSnapshot) Release() {
+	s.snap.Release()
+}
+
 //BeginTx call panic when BeginTx not rewrite
 func (db *GoLevelDB) BeginTx() (TxKV, error) {
 	tx, err := db.db.OpenTransaction()
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -290,3 +291,27 @@ func TestGoLevelDBResult(t *testing.T) {
 
 	testDBIteratorResult(t, leveldb)
 }
+
+func TestGoLevelDBReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goleveldb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewReadOnlyDB("goleveldb", "leveldb", dir, 128)
+	assert.NotNil(t, err)
+	_, err = NewReadOnlyDB("goleveldb", "memdb", dir, 128)
+	assert.Equal(t, types.ErrNotSupport, err)
+
+	leveldb, err := NewGoLevelDB("goleveldb", dir, 128)
+	require.NoError(t, err)
+	require.NoError(t, leveldb.Set([]byte("key"), []byte("value")))
+	leveldb.Close()
+
+	rdb, err := NewReadOnlyDB("goleveldb", "leveldb", dir, 128)
+	require.NoError(t, err)
+	defer rdb.Close()
+	value, err := rdb.Get([]byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value)
+	assert.NotNil(t, rdb.Set([]byte("key"), []byte("value2")))
+}
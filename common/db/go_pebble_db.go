@@ -0,0 +1,453 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/types"
+	"github.com/cockroachdb/pebble"
+)
+
+var plog = log.New("module", "db.pebble")
+
+const (
+	pebbleMiB = 1024 * 1024
+	//pebble默认的数据块大小, SetCacheSize按照每个缓存条目一个数据块计算块缓存的大小
+	pebbleBlockSize = 4096
+)
+
+func init() {
+	dbCreator := func(name string, dir string, cache int) (DB, error) {
+		return NewPebbleDB(name, dir, cache)
+	}
+	registerDBCreator(pebbleDBBackendStr, dbCreator, false)
+}
+
+//PebbleDB db
+//打开时按照cache参数(dbCache配置, 单位MB)通过pebble.NewCache创建块缓存,
+//SetCacheSize 时如果需要更大的块缓存, 重新打开数据库替换块缓存
+type PebbleDB struct {
+	BaseDB
+	db     *pebble.DB
+	cache  *pebble.Cache
+	dbPath string
+	opts   *pebble.Options
+}
+
+//pebble 的日志输出到chain33的日志
+type pebbleLogger struct{}
+
+func (pebbleLogger) Infof(format string, args ...interface{}) {
+	plog.Debug(fmt.Sprintf(format, args...))
+}
+
+func (pebbleLogger) Fatalf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	plog.Crit(msg)
+	panic(msg)
+}
+
+//NewPebbleDB new, cache 为块缓存的大小(MB), memtable另外使用cache/4
+func NewPebbleDB(name string, dir string, cache int) (*PebbleDB, error) {
+	return openPebbleDB(name, dir, cache, false)
+}
+
+//NewPebbleDBReadOnly 只读方式打开, 数据库不存在时返回错误
+func NewPebbleDBReadOnly(name string, dir string, cache int) (*PebbleDB, error) {
+	return openPebbleDB(name, dir, cache, true)
+}
+
+func openPebbleDB(name string, dir string, cache int, readOnly bool) (*PebbleDB, error) {
+	dbPath := path.Join(dir, name+".db")
+	if cache == 0 {
+		cache = 64
+	}
+	handles := cache
+	if handles < 16 {
+		handles = 16
+	}
+	if cache < 8 {
+		cache = 8
+	}
+	blockCache := pebble.NewCache(int64(cache) * pebbleMiB)
+	opts := &pebble.Options{
+		Cache:            blockCache,
+		MaxOpenFiles:     handles,
+		MemTableSize:     cache / 4 * pebbleMiB,
+		Logger:           pebbleLogger{},
+		ReadOnly:         readOnly,
+		ErrorIfNotExists: readOnly,
+	}
+	db, err := pebble.Open(dbPath, opts)
+	if err != nil {
+		blockCache.Unref()
+		plog.Error("NewPebbleDB", "error", err)
+		return nil, err
+	}
+	return &PebbleDB{db: db, cache: blockCache, dbPath: dbPath, opts: opts}, nil
+}
+
+//SetCacheSize 设置mavl节点使用的ARC缓存(条目数), 同时按照每个条目一个数据块重建块缓存,
+//块缓存不小于打开时dbCache的大小. 和BaseDB一样只有第一次调用生效, 需要在打开之后读写之前调用
+func (db *PebbleDB) SetCacheSize(size int) {
+	if db.GetCache() != nil {
+		return
+	}
+	db.BaseDB.SetCacheSize(size)
+	cacheSize := int64(size) * pebbleBlockSize
+	if cacheSize <= db.cache.MaxSize() {
+		return
+	}
+	err := db.resetCache(cacheSize)
+	if err != nil {
+		plog.Error("SetCacheSize", "size", size, "error", err)
+	}
+}
+
+//resetCache pebble的块缓存不能调整大小, 只能关闭之后使用新的块缓存重新打开
+func (db *PebbleDB) resetCache(cacheSize int64) error {
+	err := db.db.Close()
+	if err != nil {
+		return err
+	}
+	blockCache := pebble.NewCache(cacheSize)
+	db.opts.Cache = blockCache
+	pdb, err := pebble.Open(db.dbPath, db.opts)
+	if err != nil {
+		//恢复使用原来的块缓存
+		blockCache.Unref()
+		db.opts.Cache = db.cache
+		old, rerr := pebble.Open(db.dbPath, db.opts)
+		if rerr != nil {
+			panic(rerr)
+		}
+		db.db = old
+		return err
+	}
+	db.cache.Unref()
+	db.db, db.cache = pdb, blockCache
+	return nil
+}
+
+//Get get
+func (db *PebbleDB) Get(key []byte) ([]byte, error) {
+	value, closer, err := db.db.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFoundInDb
+		}
+		plog.Error("Get", "error", err)
+		return nil, err
+	}
+	//返回的value在closer关闭之后失效, 需要复制
+	res := cloneByte(value)
+	closer.Close()
+	return res, nil
+}
+
+//Set set
+func (db *PebbleDB) Set(key []byte, value []byte) error {
+	err := db.db.Set(key, value, pebble.NoSync)
+	if err != nil {
+		plog.Error("Set", "error", err)
+		return err
+	}
+	return nil
+}
+
+//SetSync 同步
+func (db *PebbleDB) SetSync(key []byte, value []byte) error {
+	err := db.db.Set(key, value, pebble.Sync)
+	if err != nil {
+		plog.Error("SetSync", "error", err)
+		return err
+	}
+	return nil
+}
+
+//Delete 删除
+func (db *PebbleDB) Delete(key []byte) error {
+	err := db.db.Delete(key, pebble.NoSync)
+	if err != nil {
+		plog.Error("Delete", "error", err)
+		return err
+	}
+	return nil
+}
+
+//DeleteSync 删除同步
+func (db *PebbleDB) DeleteSync(key []byte) error {
+	err := db.db.Delete(key, pebble.Sync)
+	if err != nil {
+		plog.Error("DeleteSync", "error", err)
+		return err
+	}
+	return nil
+}
+
+//DB db
+func (db *PebbleDB) DB() *pebble.DB {
+	return db.db
+}
+
+//Close 关闭
+func (db *PebbleDB) Close() {
+	err := db.db.Close()
+	if err != nil {
+		plog.Error("Close", "error", err)
+	}
+	db.cache.Unref()
+}
+
+//Print 打印
+func (db *PebbleDB) Print() {
+	plog.Info("Print", "stats", db.db.Metrics().String())
+	it := db.db.NewIter(nil)
+	defer it.Close()
+	for it.First(); it.Valid(); it.Next() {
+		plog.Info("Print", "key", string(it.Key()), "value", string(it.Value()))
+	}
+}
+
+//Stats pebble的统计信息, 包括各层文件, 压缩, 缓存等
+func (db *PebbleDB) Stats() map[string]string {
+	m := db.db.Metrics()
+	total := m.Total()
+	return map[string]string{
+		"pebble.stats":         m.String(),
+		"pebble.readamp":       fmt.Sprint(m.ReadAmp()),
+		"pebble.sstables":      fmt.Sprint(total.NumFiles),
+		"pebble.size":          fmt.Sprint(total.Size),
+		"pebble.memtablesize":  fmt.Sprint(m.MemTable.Size),
+		"pebble.compactdebt":   fmt.Sprint(m.Compact.EstimatedDebt),
+		"pebble.blockcachehit": fmt.Sprint(m.BlockCache.Hits),
+	}
+}
+
+//Iterator 迭代器
+func (db *PebbleDB) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	if end == nil {
+		end = bytesPrefix(start)
+	}
+	if bytes.Equal(end, types.EmptyValue) {
+		end = nil
+	}
+	it := db.db.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return &pebbleIt{it, itBase{start, end, reverse}}
+}
+
+//BeginTx 通过indexed batch实现事务, 事务中的读取可以看到未提交的写入
+func (db *PebbleDB) BeginTx() (TxKV, error) {
+	return &pebbleTx{batch: db.db.NewIndexedBatch()}, nil
+}
+
+//NewSnapshot 获取数据库当前时刻的快照, 之后的写入对快照不可见
+func (db *PebbleDB) NewSnapshot() (Snapshot, error) {
+	return &pebbleSnapshot{snap: db.db.NewSnapshot()}, nil
+}
+
+type pebbleSnapshot struct {
+	snap *pebble.Snapshot
+}
+
+//Iterator 快照上的迭代器
+func (s *pebbleSnapshot) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	if end == nil {
+		end = bytesPrefix(start)
+	}
+	if bytes.Equal(end, types.EmptyValue) {
+		end = nil
+	}
+	it := s.snap.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return &pebbleIt{it, itBase{start, end, reverse}}
+}
+
+//Release 释放快照
+func (s *pebbleSnapshot) Release() {
+	err := s.snap.Close()
+	if err != nil {
+		plog.Error("Snapshot Release", "error", err)
+	}
+}
+
+//CompactRange 压缩[start, limit]之间的数据, start或者limit为nil时压缩到数据库的头或者尾
+func (db *PebbleDB) CompactRange(start, limit []byte) error {
+	if start == nil || limit == nil {
+		it := db.db.NewIter(nil)
+		if start == nil && it.First() {
+			start = cloneByte(it.Key())
+		}
+		if limit == nil && it.Last() {
+			limit = cloneByte(it.Key())
+		}
+		err := it.Close()
+		if err != nil {
+			return err
+		}
+		if start == nil || limit == nil {
+			//空数据库
+			return nil
+		}
+	}
+	return db.db.Compact(start, limit)
+}
+
+type pebbleIt struct {
+	*pebble.Iterator
+	itBase
+}
+
+//Close 关闭
+func (dbit *pebbleIt) Close() {
+	err := dbit.Iterator.Close()
+	if err != nil {
+		plog.Error("pebbleIt Close", "error", err)
+	}
+}
+
+//Next next
+func (dbit *pebbleIt) Next() bool {
+	if dbit.reverse {
+		return dbit.Iterator.Prev() && dbit.Valid()
+	}
+	return dbit.Iterator.Next() && dbit.Valid()
+}
+
+//Rewind ...
+func (dbit *pebbleIt) Rewind() bool {
+	if dbit.reverse {
+		return dbit.Iterator.Last() && dbit.Valid()
+	}
+	return dbit.Iterator.First() && dbit.Valid()
+}
+
+//Seek 和leveldb一致, 定位到第一个不小于key的位置
+func (dbit *pebbleIt) Seek(key []byte) bool {
+	return dbit.Iterator.SeekGE(key)
+}
+
+func (dbit *pebbleIt) ValueCopy() []byte {
+	return cloneByte(dbit.Iterator.Value())
+}
+
+func (dbit *pebbleIt) Valid() bool {
+	return dbit.Iterator.Valid() && dbit.checkKey(dbit.Key())
+}
+
+type pebbleBatch struct {
+	db    *PebbleDB
+	batch *pebble.Batch
+	wop   *pebble.WriteOptions
+	size  int
+	len   int
+}
+
+//NewBatch new
+func (db *PebbleDB) NewBatch(sync bool) Batch {
+	wop := pebble.NoSync
+	if sync {
+		wop = pebble.Sync
+	}
+	return &pebbleBatch{db: db, batch: db.db.NewBatch(), wop: wop}
+}
+
+func (mBatch *pebbleBatch) Set(key, value []byte) {
+	mBatch.batch.Set(key, value, nil)
+	mBatch.size += len(key)
+	mBatch.size += len(value)
+	mBatch.len += len(value)
+}
+
+func (mBatch *pebbleBatch) Delete(key []byte) {
+	mBatch.batch.Delete(key, nil)
+	mBatch.size += len(key)
+	mBatch.len++
+}
+
+//Write pebble的batch提交之后不能再次提交, 提交之后换成新的batch, 之后的写入只包含新增的操作
+func (mBatch *pebbleBatch) Write() error {
+	err := mBatch.batch.Commit(mBatch.wop)
+	if err != nil {
+		plog.Error("Write", "error", err)
+		return err
+	}
+	mBatch.batch.Close()
+	mBatch.batch = mBatch.db.db.NewBatch()
+	return nil
+}
+
+func (mBatch *pebbleBatch) ValueSize() int {
+	return mBatch.size
+}
+
+//ValueLen  batch数量
+func (mBatch *pebbleBatch) ValueLen() int {
+	return mBatch.len
+}
+
+func (mBatch *pebbleBatch) Reset() {
+	mBatch.batch.Reset()
+	mBatch.len = 0
+	mBatch.size = 0
+}
+
+type pebbleTx struct {
+	batch *pebble.Batch
+}
+
+func (db *pebbleTx) Commit() error {
+	defer db.batch.Close()
+	return db.batch.Commit(pebble.Sync)
+}
+
+func (db *pebbleTx) Rollback() {
+	db.batch.Close()
+}
+
+//Get get in transaction
+func (db *pebbleTx) Get(key []byte) ([]byte, error) {
+	value, closer, err := db.batch.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFoundInDb
+		}
+		plog.Error("tx Get", "error", err)
+		return nil, err
+	}
+	res := cloneByte(value)
+	closer.Close()
+	return res, nil
+}
+
+//Set set in transaction
+func (db *pebbleTx) Set(key []byte, value []byte) error {
+	err := db.batch.Set(key, value, nil)
+	if err != nil {
+		plog.Error("tx Set", "error", err)
+		return err
+	}
+	return nil
+}
+
+//Iterator 迭代器 in transaction
+func (db *pebbleTx) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	if end == nil {
+		end = bytesPrefix(start)
+	}
+	if bytes.Equal(end, types.EmptyValue) {
+		end = nil
+	}
+	it := db.batch.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return &pebbleIt{it, itBase{start, end, reverse}}
+}
+
+//Begin call panic when Begin not rewrite
+func (db *pebbleTx) Begin() {
+	panic("Begin not impl")
+}
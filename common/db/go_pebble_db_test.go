@@ -0,0 +1,184 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPebbleDB(t *testing.T) (*PebbleDB, func()) {
+	dir, err := ioutil.TempDir("", "pebble")
+	require.NoError(t, err)
+	pebbledb, err := NewPebbleDB("pebble", dir, 128)
+	require.NoError(t, err)
+	return pebbledb, func() {
+		pebbledb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// pebble迭代器测试
+func TestPebbleDBIterator(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testDBIterator(t, pebbledb)
+}
+
+func TestPebbleDBCache(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	//dbCache为块缓存的大小
+	assert.Equal(t, int64(128*pebbleMiB), pebbledb.cache.MaxSize())
+	//块缓存不小于dbCache
+	pebbledb.SetCacheSize(100)
+	assert.NotNil(t, pebbledb.GetCache())
+	assert.Equal(t, int64(128*pebbleMiB), pebbledb.cache.MaxSize())
+}
+
+func TestPebbleDBSetCacheSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pebble")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pebbledb, err := NewPebbleDB("pebble", dir, 8)
+	require.NoError(t, err)
+	defer pebbledb.Close()
+	require.NoError(t, pebbledb.Set([]byte("key"), []byte("value")))
+
+	//重新打开数据库替换块缓存, 数据不受影响
+	pebbledb.SetCacheSize(4096)
+	assert.Equal(t, int64(4096*pebbleBlockSize), pebbledb.cache.MaxSize())
+	value, err := pebbledb.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	require.NoError(t, pebbledb.Set([]byte("key2"), []byte("value2")))
+	//只有第一次调用生效
+	pebbledb.SetCacheSize(8192)
+	assert.Equal(t, int64(4096*pebbleBlockSize), pebbledb.cache.MaxSize())
+}
+
+func TestPebbleDBIteratorAll(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testDBIteratorAllKey(t, pebbledb)
+}
+
+func TestPebbleDBIteratorReserverExample(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testDBIteratorReserverExample(t, pebbledb)
+}
+
+func TestPebbleDBIteratorDel(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testDBIteratorDel(t, pebbledb)
+}
+
+func TestPebbleDBBatch(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testBatch(t, pebbledb)
+
+	//提交之后继续使用同一个batch
+	batch := pebbledb.NewBatch(true)
+	batch.Set([]byte("k1"), []byte("v1"))
+	require.NoError(t, batch.Write())
+	batch.Set([]byte("k2"), []byte("v2"))
+	require.NoError(t, batch.Write())
+	batch.Reset()
+	assert.Equal(t, 0, batch.ValueSize())
+	batch.Delete([]byte("k1"))
+	require.NoError(t, batch.Write())
+	_, err := pebbledb.Get([]byte("k1"))
+	assert.Equal(t, types.ErrNotFound, err)
+	v, err := pebbledb.Get([]byte("k2"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v2"), v)
+}
+
+func TestPebbleDBTransaction(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testTransaction(t, pebbledb)
+
+	//事务中的迭代器可以看到未提交的写入
+	require.NoError(t, pebbledb.Set([]byte("tx/1"), []byte("1")))
+	tx, err := pebbledb.BeginTx()
+	require.NoError(t, err)
+	tx.Set([]byte("tx/2"), []byte("2"))
+	it := tx.Iterator([]byte("tx/"), nil, false)
+	var keys []string
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	it.Close()
+	assert.Equal(t, []string{"tx/1", "tx/2"}, keys)
+	tx.Rollback()
+}
+
+// pebble边界测试
+func TestPebbleDBBoundary(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testDBBoundary(t, pebbledb)
+}
+
+func TestPebbleDBResult(t *testing.T) {
+	pebbledb, closer := newTestPebbleDB(t)
+	defer closer()
+	testDBIteratorResult(t, pebbledb)
+}
+
+func TestPebbleDBCompactAndStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pebble")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := NewDB("pebble", "pebble", dir, 16)
+	defer db.Close()
+
+	assert.Nil(t, db.CompactRange(nil, nil))
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key/%04d", i)), []byte(fmt.Sprintf("value/%04d", i))))
+	}
+	assert.Nil(t, db.CompactRange([]byte("key/0100"), []byte("key/0200")))
+	assert.Nil(t, db.CompactRange(nil, nil))
+	stats := db.Stats()
+	assert.NotEqual(t, "0", stats["pebble.sstables"])
+	assert.NotEmpty(t, stats["pebble.stats"])
+	v, err := db.Get([]byte("key/0500"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value/0500"), v)
+
+	db.SetCacheSize(100)
+	assert.NotNil(t, db.GetCache())
+}
+
+func TestPebbleDBReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pebble")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewReadOnlyDB("pebble", "pebble", dir, 16)
+	assert.NotNil(t, err)
+	pebbledb, err := NewPebbleDB("pebble", dir, 16)
+	require.NoError(t, err)
+	require.NoError(t, pebbledb.Set([]byte("key"), []byte("value")))
+	pebbledb.Close()
+
+	rdb, err := NewReadOnlyDB("pebble", "pebble", dir, 16)
+	require.NoError(t, err)
+	defer rdb.Close()
+	value, err := rdb.Get([]byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value)
+	assert.NotNil(t, rdb.Set([]byte("key"), []byte("value2")))
+}
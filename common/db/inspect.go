@@ -0,0 +1,229 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/33cn/chain33/types"
+)
+
+//maxGroupLen 没有登记的key按照第一个分隔符分组, 分组名的最大长度
+const maxGroupLen = 32
+
+//BinaryGroup 分隔符之前出现不可打印字符的key的分组名, 例如没有前缀的mavl节点hash
+const BinaryGroup = "<binary>"
+
+//KeyPrefix 模块数据库中已知的key前缀以及value的类型, 用于dbinspect等离线工具解析数据
+type KeyPrefix struct {
+	Prefix string
+	Desc   string
+	//NewValue 返回value对应的protobuf结构, 为nil时value不是protobuf编码
+	NewValue func() types.Message
+}
+
+var (
+	keyPrefixMtx sync.RWMutex
+	keyPrefixes  = make(map[string][]*KeyPrefix)
+)
+
+//RegisterKeyPrefix 登记模块数据库中的key前缀, module为数据库名称, 一般在init中调用
+func RegisterKeyPrefix(module string, prefix string, desc string, newValue func() types.Message) {
+	keyPrefixMtx.Lock()
+	defer keyPrefixMtx.Unlock()
+	list := keyPrefixes[module]
+	for i, p := range list {
+		if p.Prefix == prefix {
+			list[i] = &KeyPrefix{Prefix: prefix, Desc: desc, NewValue: newValue}
+			return
+		}
+	}
+	list = append(list, &KeyPrefix{Prefix: prefix, Desc: desc, NewValue: newValue})
+	sort.Slice(list, func(i, j int) bool { return list[i].Prefix < list[j].Prefix })
+	keyPrefixes[module] = list
+}
+
+//GetKeyPrefixes 模块登记的全部key前缀, 按照前缀排序
+func GetKeyPrefixes(module string) []*KeyPrefix {
+	keyPrefixMtx.RLock()
+	defer keyPrefixMtx.RUnlock()
+	return append([]*KeyPrefix{}, keyPrefixes[module]...)
+}
+
+//MatchKeyPrefix key匹配的最长的登记前缀, 没有匹配时返回nil
+func MatchKeyPrefix(module string, key []byte) *KeyPrefix {
+	keyPrefixMtx.RLock()
+	defer keyPrefixMtx.RUnlock()
+	var match *KeyPrefix
+	for _, p := range keyPrefixes[module] {
+		if bytes.HasPrefix(key, []byte(p.Prefix)) && (match == nil || len(p.Prefix) > len(match.Prefix)) {
+			match = p
+		}
+	}
+	return match
+}
+
+//GroupKey key所属的分组, 优先使用比prefix更长的登记前缀,
+//否则为prefix加上剩余部分第一个':'或者'-'分隔符之前的部分
+func GroupKey(module string, prefix []byte, key []byte) (string, *KeyPrefix) {
+	p := MatchKeyPrefix(module, key)
+	if p != nil && len(p.Prefix) > len(prefix) {
+		return p.Prefix, p
+	}
+	rest := key[len(prefix):]
+	for i, b := range rest {
+		if i >= maxGroupLen {
+			return string(prefix) + string(rest[:i]), p
+		}
+		if b == ':' || b == '-' {
+			return string(prefix) + string(rest[:i+1]), p
+		}
+		if b < 0x20 || b > 0x7e {
+			return string(prefix) + BinaryGroup, p
+		}
+	}
+	return string(key), p
+}
+
+//PrefixStat 一个分组下的key数目和大小
+type PrefixStat struct {
+	Prefix    string
+	Desc      string
+	Count     int64
+	KeySize   int64
+	ValueSize int64
+}
+
+//StatPrefixes 统计数据库中prefix下每个分组的key数目和大小, 按照分组排序
+func StatPrefixes(db IteratorDB, module string, prefix []byte) ([]*PrefixStat, error) {
+	stats := make(map[string]*PrefixStat)
+	it := db.Iterator(prefix, inspectEnd(prefix), false)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		key := it.Key()
+		group, p := GroupKey(module, prefix, key)
+		stat, ok := stats[group]
+		if !ok {
+			stat = &PrefixStat{Prefix: group}
+			if p != nil && p.Prefix == group {
+				stat.Desc = p.Desc
+			}
+			stats[group] = stat
+		}
+		stat.Count++
+		stat.KeySize += int64(len(key))
+		stat.ValueSize += int64(len(it.Value()))
+	}
+	err := it.Error()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*PrefixStat, 0, len(stats))
+	for _, stat := range stats {
+		list = append(list, stat)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Prefix < list[j].Prefix })
+	return list, nil
+}
+
+//PrefixDiff 两个数据库中一个分组下的差异
+type PrefixDiff struct {
+	Prefix    string
+	Same      int64
+	Changed   int64
+	OnlyLeft  int64
+	OnlyRight int64
+	//Samples 有差异的key, 最多保留调用时指定的数目
+	Samples [][]byte
+}
+
+//Differ 是否存在差异
+func (d *PrefixDiff) Differ() bool {
+	return d.Changed > 0 || d.OnlyLeft > 0 || d.OnlyRight > 0
+}
+
+//DiffDB 按照分组比较两个数据库中prefix下的数据, 每个分组最多记录samples个有差异的key
+func DiffDB(left, right IteratorDB, module string, prefix []byte, samples int) ([]*PrefixDiff, error) {
+	diffs := make(map[string]*PrefixDiff)
+	record := func(key []byte) *PrefixDiff {
+		group, _ := GroupKey(module, prefix, key)
+		diff, ok := diffs[group]
+		if !ok {
+			diff = &PrefixDiff{Prefix: group}
+			diffs[group] = diff
+		}
+		return diff
+	}
+	sample := func(diff *PrefixDiff, key []byte) {
+		if len(diff.Samples) < samples {
+			diff.Samples = append(diff.Samples, cloneByte(key))
+		}
+	}
+	lit := left.Iterator(prefix, inspectEnd(prefix), false)
+	defer lit.Close()
+	rit := right.Iterator(prefix, inspectEnd(prefix), false)
+	defer rit.Close()
+	lit.Rewind()
+	rit.Rewind()
+	lok, rok := lit.Valid(), rit.Valid()
+	for lok || rok {
+		cmp := 0
+		switch {
+		case !rok:
+			cmp = -1
+		case !lok:
+			cmp = 1
+		default:
+			cmp = bytes.Compare(lit.Key(), rit.Key())
+		}
+		switch {
+		case cmp < 0:
+			diff := record(lit.Key())
+			diff.OnlyLeft++
+			sample(diff, lit.Key())
+			lit.Next()
+			lok = lit.Valid()
+		case cmp > 0:
+			diff := record(rit.Key())
+			diff.OnlyRight++
+			sample(diff, rit.Key())
+			rit.Next()
+			rok = rit.Valid()
+		default:
+			diff := record(lit.Key())
+			if bytes.Equal(lit.Value(), rit.Value()) {
+				diff.Same++
+			} else {
+				diff.Changed++
+				sample(diff, lit.Key())
+			}
+			lit.Next()
+			rit.Next()
+			lok, rok = lit.Valid(), rit.Valid()
+		}
+	}
+	for _, it := range []Iterator{lit, rit} {
+		err := it.Error()
+		if err != nil {
+			return nil, err
+		}
+	}
+	list := make([]*PrefixDiff, 0, len(diffs))
+	for _, diff := range diffs {
+		list = append(list, diff)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Prefix < list[j].Prefix })
+	return list, nil
+}
+
+//inspectEnd prefix为空时遍历整个数据库
+func inspectEnd(prefix []byte) []byte {
+	if len(prefix) == 0 {
+		return types.EmptyValue
+	}
+	return nil
+}
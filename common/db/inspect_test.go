@@ -0,0 +1,81 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	RegisterKeyPrefix("inspect", "Hash:", "hash -> height", func() types.Message { return &types.Int64{} })
+	RegisterKeyPrefix("inspect", "Height:", "height -> hash", nil)
+	RegisterKeyPrefix("inspect", "Hash:x", "longer", nil)
+	RegisterKeyPrefix("inspect", "Hash:", "block hash -> height", func() types.Message { return &types.Int64{} })
+	prefixes := GetKeyPrefixes("inspect")
+	require.Equal(t, 3, len(prefixes))
+	assert.Equal(t, "Hash:", prefixes[0].Prefix)
+	assert.Equal(t, "block hash -> height", prefixes[0].Desc)
+	assert.Equal(t, "Height:", prefixes[2].Prefix)
+
+	assert.Equal(t, "Hash:x", MatchKeyPrefix("inspect", []byte("Hash:x1")).Prefix)
+	assert.Equal(t, "Hash:", MatchKeyPrefix("inspect", []byte("Hash:y1")).Prefix)
+	assert.Nil(t, MatchKeyPrefix("inspect", []byte("Other:1")))
+	assert.Nil(t, MatchKeyPrefix("other", []byte("Hash:1")))
+
+	group, p := GroupKey("inspect", nil, []byte("Hash:y1"))
+	assert.Equal(t, "Hash:", group)
+	assert.Equal(t, prefixes[0], p)
+	group, _ = GroupKey("inspect", nil, []byte("mavl-coins-bty-"))
+	assert.Equal(t, "mavl-", group)
+	group, _ = GroupKey("inspect", []byte("mavl-"), []byte("mavl-coins-bty-"))
+	assert.Equal(t, "mavl-coins-", group)
+	group, _ = GroupKey("inspect", nil, []byte{0x01, 0x02})
+	assert.Equal(t, BinaryGroup, group)
+	group, _ = GroupKey("inspect", nil, []byte("blockLastHeight"))
+	assert.Equal(t, "blockLastHeight", group)
+}
+
+func TestStatAndDiffDB(t *testing.T) {
+	left := newGoMemDB(t)
+	right := newGoMemDB(t)
+	RegisterKeyPrefix("inspect", "Hash:", "block hash -> height", func() types.Message { return &types.Int64{} })
+	for _, db := range []DB{left, right} {
+		require.NoError(t, db.Set([]byte("Hash:1"), []byte("1")))
+		require.NoError(t, db.Set([]byte("Hash:2"), []byte("2")))
+		require.NoError(t, db.Set([]byte("mavl-coins-1"), []byte("10")))
+		require.NoError(t, db.Set([]byte{0x01, 0x02}, []byte("node")))
+	}
+	require.NoError(t, left.Set([]byte("Hash:3"), []byte("3")))
+	require.NoError(t, right.Set([]byte("mavl-coins-1"), []byte("11")))
+	require.NoError(t, right.Set([]byte("mavl-token-1"), []byte("11")))
+
+	stats, err := StatPrefixes(left, "inspect", nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(stats))
+	assert.Equal(t, &PrefixStat{Prefix: BinaryGroup, Count: 1, KeySize: 2, ValueSize: 4}, stats[0])
+	assert.Equal(t, &PrefixStat{Prefix: "Hash:", Desc: "block hash -> height", Count: 3, KeySize: 18, ValueSize: 3}, stats[1])
+	assert.Equal(t, "mavl-", stats[2].Prefix)
+	stats, err = StatPrefixes(right, "inspect", []byte("mavl-"))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(stats))
+	assert.Equal(t, "mavl-coins-", stats[0].Prefix)
+	assert.Equal(t, "mavl-token-", stats[1].Prefix)
+
+	diffs, err := DiffDB(left, right, "inspect", nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(diffs))
+	assert.False(t, diffs[0].Differ())
+	assert.Equal(t, &PrefixDiff{Prefix: "Hash:", Same: 2, OnlyLeft: 1, Samples: [][]byte{[]byte("Hash:3")}}, diffs[1])
+	assert.Equal(t, &PrefixDiff{Prefix: "mavl-", Changed: 1, OnlyRight: 1, Samples: [][]byte{[]byte("mavl-coins-1")}}, diffs[2])
+	diffs, err = DiffDB(left, right, "inspect", []byte("mavl-"), 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(diffs))
+	assert.Equal(t, int64(1), diffs[0].Changed)
+	assert.Equal(t, int64(1), diffs[1].OnlyRight)
+}
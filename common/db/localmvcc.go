@@ -0,0 +1,216 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"bytes"
+
+	"github.com/33cn/chain33/types"
+)
+
+//LocalMVCCPrefix 执行器localdb多版本数据的key前缀, 和状态的mvcc数据分开保存
+var LocalMVCCPrefix = []byte(".-localmvcc-.")
+
+//LocalMVCC 按照区块高度保存执行器localdb的多版本数据, 版本号就是区块高度
+//每个版本的value是编码之后的KeyVersion, 删除key的版本也会保留下来
+type LocalMVCC struct {
+	*SimpleMVCC
+}
+
+//NewLocalMVCC new
+func NewLocalMVCC(db KVDB) *LocalMVCC {
+	return &LocalMVCC{SimpleMVCC: NewSimpleMVCCWithPrefix(db, LocalMVCCPrefix)}
+}
+
+//AddLocalMVCC 把区块中交易产生的localdb数据转换成height版本的kv, 同一个key只保留最后一次设置的值
+func (m *LocalMVCC) AddLocalMVCC(kvs []*types.KeyValue, hash []byte, prevHash []byte, height int64) ([]*types.KeyValue, error) {
+	index := make(map[string]int)
+	var versions []*types.KeyValue
+	for _, kv := range kvs {
+		item := &types.KeyVersion{Height: height, Value: kv.Value, Deleted: kv.Value == nil}
+		value := types.Encode(item)
+		if i, ok := index[string(kv.Key)]; ok {
+			versions[i].Value = value
+			continue
+		}
+		index[string(kv.Key)] = len(versions)
+		versions = append(versions, &types.KeyValue{Key: kv.Key, Value: value})
+	}
+	return m.AddMVCC(versions, hash, prevHash, height)
+}
+
+//DelLocalMVCC 回滚区块时删除height版本的全部数据, 只能从最高的版本开始删除
+func (m *LocalMVCC) DelLocalMVCC(hash []byte, height int64) ([]*types.KeyValue, error) {
+	return m.DelMVCC(hash, height, true)
+}
+
+//GetAt 查询key在height高度时的值
+func (m *LocalMVCC) GetAt(key []byte, height int64) ([]byte, error) {
+	data, err := m.GetV(key, height)
+	if err == types.ErrVersion {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item types.KeyVersion
+	err = types.Decode(data, &item)
+	if err != nil {
+		return nil, err
+	}
+	if item.Deleted {
+		return nil, types.ErrNotFound
+	}
+	return item.Value, nil
+}
+
+//GetKeyHistory 查询key在[fromHeight, toHeight]之间的版本, 按照高度从小到大排列, 最多返回count个
+//toHeight小于0表示查询到最新高度
+func (m *LocalMVCC) GetKeyHistory(key []byte, fromHeight, toHeight int64, count int32) ([]*types.KeyVersion, error) {
+	prefix := m.GetKeyPerfix(key)
+	//从fromHeight之前的最后一个版本之后开始遍历, 没有这个版本时从第一个版本开始
+	var start []byte
+	if fromHeight > 0 {
+		search, err := m.GetKey(key, fromHeight-1)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := m.kvdb.List(prefix, search, 1, ListSeek)
+		if err != nil && err != types.ErrNotFound {
+			return nil, err
+		}
+		if len(vals) == 2 {
+			v, err := getVersion(vals[0])
+			if err != nil {
+				return nil, err
+			}
+			if v < fromHeight {
+				start = vals[0]
+			}
+		}
+	}
+	values, err := m.kvdb.List(prefix, start, count, ListASC)
+	if err != nil && err != types.ErrNotFound {
+		return nil, err
+	}
+	var list []*types.KeyVersion
+	for _, value := range values {
+		var item types.KeyVersion
+		err = types.Decode(value, &item)
+		if err != nil {
+			return nil, err
+		}
+		if toHeight >= 0 && item.Height > toHeight {
+			break
+		}
+		list = append(list, &item)
+	}
+	return list, nil
+}
+
+//TrashLocalMVCC 回收height之前的版本, 每个key保留不超过height的最新版本, 同时删除不再需要回滚的版本列表
+//回收之后只能查询height及之后的历史数据
+func TrashLocalMVCC(db DB, height int64) error {
+	m := NewMVCCWithPrefix(db, LocalMVCCPrefix)
+	err := m.TrashKeepLatest(height)
+	if err != nil {
+		return err
+	}
+	prefix := m.withPrefix(metaVersionListPart)
+	it := db.Iterator(prefix, m.getVersionKeyListKey(height+1), false)
+	defer it.Close()
+	batch := db.NewBatch(false)
+	for it.Rewind(); it.Valid(); it.Next() {
+		batch.Delete(it.Key())
+	}
+	err = it.Error()
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+//localMVCCDB height高度时的localdb只读视图, 数据来自LocalMVCC保存的多版本数据
+type localMVCCDB struct {
+	DB
+	mvcc   *LocalMVCC
+	height int64
+}
+
+//NewLocalMVCCDB 创建height高度时的localdb只读视图, 用于在历史高度上查询执行器的localdb
+//遍历时会先把范围内的数据加载到内存中, 只适合查询范围有限的历史数据
+func NewLocalMVCCDB(db DB, height int64) DB {
+	return &localMVCCDB{DB: db, mvcc: NewLocalMVCC(NewKVDB(db)), height: height}
+}
+
+func (db *localMVCCDB) Get(key []byte) ([]byte, error) {
+	return db.mvcc.GetAt(key, db.height)
+}
+
+func (db *localMVCCDB) Set(key []byte, value []byte) error {
+	return types.ErrDisableWrite
+}
+
+func (db *localMVCCDB) SetSync(key []byte, value []byte) error {
+	return types.ErrDisableWrite
+}
+
+func (db *localMVCCDB) Delete(key []byte) error {
+	return types.ErrDisableWrite
+}
+
+func (db *localMVCCDB) DeleteSync(key []byte) error {
+	return types.ErrDisableWrite
+}
+
+//Iterator 按照key分组遍历多版本数据, 取每个key不超过height的最新版本
+func (db *localMVCCDB) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	mem := newMemDB()
+	dataPrefix := db.mvcc.dataPrefix()
+	it := db.DB.Iterator(append(dataPrefix, start...), bytesPrefix(dataPrefix), false)
+	defer it.Close()
+	var key, value []byte
+	flush := func() {
+		if key != nil && value != nil {
+			err := mem.Set(key, value)
+			if err != nil {
+				panic(err)
+			}
+		}
+		key, value = nil, nil
+	}
+	for it.Rewind(); it.Valid(); it.Next() {
+		cut := cutVersion(it.Key())
+		if len(cut) <= len(dataPrefix) {
+			continue
+		}
+		k := cut[len(dataPrefix):]
+		if end == nil && !bytes.HasPrefix(k, start) {
+			break
+		}
+		if end != nil && !bytes.Equal(end, types.EmptyValue) && bytes.Compare(k, end) >= 0 {
+			break
+		}
+		if !bytes.Equal(k, key) {
+			flush()
+			key = k
+		}
+		v, err := getVersion(it.Key())
+		if err != nil || v > db.height {
+			continue
+		}
+		var item types.KeyVersion
+		err = types.Decode(it.Value(), &item)
+		if err != nil {
+			continue
+		}
+		value = nil
+		if !item.Deleted && len(item.Value) > 0 {
+			value = item.Value
+		}
+	}
+	flush()
+	return mem.Iterator(start, end, reverse)
+}
@@ -0,0 +1,182 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveKVs(t *testing.T, db DB, kvs []*types.KeyValue) {
+	batch := db.NewBatch(true)
+	for _, kv := range kvs {
+		if kv.Value == nil {
+			batch.Delete(kv.Key)
+		} else {
+			batch.Set(kv.Key, kv.Value)
+		}
+	}
+	require.NoError(t, batch.Write())
+}
+
+//height 0: a=a0 b=b0
+//height 1: a=a1 c=c1
+//height 2: b删除
+//height 3: a=a3
+func addLocalMVCCBlocks(t *testing.T, db DB) *LocalMVCC {
+	m := NewLocalMVCC(NewKVDB(db))
+	blocks := [][]*types.KeyValue{
+		{{Key: []byte("a"), Value: []byte("a0")}, {Key: []byte("b"), Value: []byte("b0")}},
+		{{Key: []byte("a"), Value: []byte("x")}, {Key: []byte("c"), Value: []byte("c1")}, {Key: []byte("a"), Value: []byte("a1")}},
+		{{Key: []byte("b")}},
+		{{Key: []byte("a"), Value: []byte("a3")}},
+	}
+	for i, kvs := range blocks {
+		kvlist, err := m.AddLocalMVCC(kvs, hashN(i), hashN(i-1), int64(i))
+		require.NoError(t, err)
+		saveKVs(t, db, kvlist)
+	}
+	return m
+}
+
+func TestLocalMVCCGetAt(t *testing.T) {
+	m := getMVCC()
+	defer closeMVCC(m)
+	lm := addLocalMVCCBlocks(t, m.db)
+
+	_, err := lm.AddLocalMVCC(nil, hashN(5), hashN(4), 5)
+	assert.Equal(t, types.ErrNotFound, err)
+
+	cases := []struct {
+		key    string
+		height int64
+		value  string
+	}{
+		{"a", 0, "a0"}, {"a", 1, "a1"}, {"a", 2, "a1"}, {"a", 3, "a3"}, {"a", 10, "a3"},
+		{"b", 1, "b0"}, {"b", 2, ""}, {"c", 0, ""}, {"c", 3, "c1"},
+	}
+	for _, c := range cases {
+		v, err := lm.GetAt([]byte(c.key), c.height)
+		if c.value == "" {
+			assert.Equal(t, types.ErrNotFound, err, c.key)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, c.value, string(v), c.key)
+	}
+
+	versions, err := lm.GetKeyHistory([]byte("a"), 0, -1, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 3, len(versions))
+	assert.Equal(t, int64(1), versions[1].Height)
+	assert.Equal(t, []byte("a1"), versions[1].Value)
+	versions, err = lm.GetKeyHistory([]byte("a"), 1, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(versions))
+	//分页查询, 下一页从上一页最后的高度加1开始
+	versions, err = lm.GetKeyHistory([]byte("a"), 0, -1, 2)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(versions))
+	assert.Equal(t, int64(1), versions[1].Height)
+	for _, from := range []int64{2, 3} {
+		versions, err = lm.GetKeyHistory([]byte("a"), from, -1, 2)
+		assert.NoError(t, err)
+		require.Equal(t, 1, len(versions))
+		assert.Equal(t, int64(3), versions[0].Height)
+	}
+	versions, err = lm.GetKeyHistory([]byte("a"), 1, -1, 1)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(versions))
+	assert.Equal(t, int64(1), versions[0].Height)
+	versions, err = lm.GetKeyHistory([]byte("a"), 4, -1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(versions))
+	versions, err = lm.GetKeyHistory([]byte("b"), 0, -1, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(versions))
+	assert.True(t, versions[1].Deleted)
+	versions, err = lm.GetKeyHistory([]byte("d"), 0, -1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(versions))
+
+	//回滚最高的区块之后, 最新的版本回到高度2
+	_, err = lm.DelLocalMVCC(hashN(2), 2)
+	assert.Equal(t, types.ErrCanOnlyDelTopVersion, err)
+	kvlist, err := lm.DelLocalMVCC(hashN(3), 3)
+	assert.NoError(t, err)
+	saveKVs(t, m.db, kvlist)
+	v, err := lm.GetAt([]byte("a"), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a1"), v)
+	maxv, err := lm.GetMaxVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), maxv)
+}
+
+func TestLocalMVCCDB(t *testing.T) {
+	m := getMVCC()
+	defer closeMVCC(m)
+	addLocalMVCCBlocks(t, m.db)
+
+	local := NewLocalDB(NewLocalMVCCDB(m.db, 1))
+	v, err := local.Get([]byte("b"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("b0"), v)
+	values, err := local.List(nil, nil, 0, ListASC)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a1"), []byte("b0"), []byte("c1")}, values)
+	values, err = local.List([]byte("a"), nil, 0, ListDESC)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a1")}, values)
+	assert.Equal(t, int64(3), local.PrefixCount(nil))
+
+	local = NewLocalDB(NewLocalMVCCDB(m.db, 2))
+	_, err = local.Get([]byte("b"))
+	assert.Equal(t, types.ErrNotFound, err)
+	values, err = local.List(nil, nil, 0, ListDESC)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("c1"), []byte("a1")}, values)
+
+	assert.Equal(t, types.ErrDisableWrite, NewLocalMVCCDB(m.db, 2).Set([]byte("a"), []byte("a")))
+}
+
+func TestTrashLocalMVCC(t *testing.T) {
+	m := getMVCC()
+	defer closeMVCC(m)
+	lm := addLocalMVCCBlocks(t, m.db)
+
+	require.NoError(t, TrashLocalMVCC(m.db, 2))
+	//高度2及之后的数据不变
+	v, err := lm.GetAt([]byte("a"), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a1"), v)
+	v, err = lm.GetAt([]byte("c"), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("c1"), v)
+	_, err = lm.GetAt([]byte("b"), 2)
+	assert.Equal(t, types.ErrNotFound, err)
+	versions, err := lm.GetKeyHistory([]byte("a"), 0, -1, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(versions))
+	assert.Equal(t, int64(1), versions[0].Height)
+
+	//更早的版本列表已经删除, 之后的版本仍然可以回滚
+	_, err = lm.GetDelKVList(2)
+	assert.Equal(t, types.ErrNotFound, err)
+	_, err = lm.GetDelKVList(3)
+	assert.NoError(t, err)
+
+	//不影响默认前缀下的mvcc数据
+	kvlist, err := m.AddMVCC([]*types.KeyValue{{Key: []byte("a"), Value: []byte("s0")}}, hashN(0), nil, 0)
+	assert.NoError(t, err)
+	saveKVs(t, m.db, kvlist)
+	require.NoError(t, TrashLocalMVCC(m.db, 3))
+	v, err = m.GetV([]byte("a"), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("s0"), v)
+}
@@ -16,9 +16,6 @@ import (
 var mvccPrefix = []byte(".-mvcc-.")
 var mvccMeta = append(mvccPrefix, []byte("m.")...)
 var mvccData = append(mvccPrefix, []byte("d.")...)
-var mvccLast = append(mvccPrefix, []byte("l.")...)
-var mvccMetaVersion = append(mvccMeta, []byte("version.")...)
-var mvccMetaVersionKeyList = append(mvccMeta, []byte("versionkl.")...)
 
 //MVCC mvcc interface
 type MVCC interface {
@@ -56,19 +53,29 @@ type MVCCHelper struct {
 //SimpleMVCC kvdb
 type SimpleMVCC struct {
 	kvdb KVDB
+	//prefix 全部mvcc数据的key前缀, 不同前缀下的版本互不影响
+	prefix []byte
 }
 
 var mvcclog = log.New("module", "db.mvcc")
 
+//trashBatchSize TrashKeepLatest 每批删除的key的大小
+const trashBatchSize = 1024 * 1024
+
 //NewMVCC create MVCC object use db DB
 func NewMVCC(db DB) *MVCCHelper {
-	return &MVCCHelper{SimpleMVCC: NewSimpleMVCC(NewKVDB(db)), db: db}
+	return NewMVCCWithPrefix(db, mvccPrefix)
+}
+
+//NewMVCCWithPrefix 使用指定的key前缀创建MVCC
+func NewMVCCWithPrefix(db DB, prefix []byte) *MVCCHelper {
+	return &MVCCHelper{SimpleMVCC: NewSimpleMVCCWithPrefix(NewKVDB(db), prefix), db: db}
 }
 
 //PrintAll 打印全部
 func (m *MVCCHelper) PrintAll() {
 	println("--meta--")
-	it := m.db.Iterator(mvccMeta, nil, true)
+	it := m.db.Iterator(m.metaPrefix(), nil, true)
 	defer it.Close()
 	for it.Rewind(); it.Valid(); it.Next() {
 		if it.Error() != nil {
@@ -79,7 +86,7 @@ func (m *MVCCHelper) PrintAll() {
 	}
 
 	println("--data--")
-	it = m.db.Iterator(mvccData, nil, true)
+	it = m.db.Iterator(m.dataPrefix(), nil, true)
 	defer it.Close()
 	for it.Rewind(); it.Valid(); it.Next() {
 		if it.Error() != nil {
@@ -89,7 +96,7 @@ func (m *MVCCHelper) PrintAll() {
 		println(string(it.Key()), string(it.Value()))
 	}
 	println("--last--")
-	it = m.db.Iterator(mvccLast, nil, true)
+	it = m.db.Iterator(m.lastPrefix(), nil, true)
 	defer it.Close()
 	for it.Rewind(); it.Valid(); it.Next() {
 		if it.Error() != nil {
@@ -102,7 +109,7 @@ func (m *MVCCHelper) PrintAll() {
 
 //Trash del some old kv
 func (m *MVCCHelper) Trash(version int64) error {
-	it := m.db.Iterator(mvccData, nil, true)
+	it := m.db.Iterator(m.dataPrefix(), nil, true)
 	defer it.Close()
 	perfixkey := []byte("--.xxx.--")
 	for it.Rewind(); it.Valid(); it.Next() {
@@ -134,6 +141,49 @@ func (m *MVCCHelper) Trash(version int64) error {
 	return nil
 }
 
+//TrashKeepLatest 删除version之前的旧版本, 和Trash不同的是每个key保留不超过version的最新版本,
+//保证version及之后的版本都可以查询
+func (m *MVCCHelper) TrashKeepLatest(version int64) error {
+	it := m.db.Iterator(m.dataPrefix(), nil, true)
+	defer it.Close()
+	batch := m.db.NewBatch(false)
+	var perfixkey []byte
+	kept := false
+	for it.Rewind(); it.Valid(); it.Next() {
+		//如果进入一个新的key, 从最新的版本开始检查
+		//需要比较包含分隔符的完整key, 否则 a-x 这样的key会被当作 a 的旧版本
+		if k := versionKeyPrefix(it.Key()); !bytes.Equal(k, perfixkey) {
+			perfixkey = k
+			kept = false
+		}
+		v, err := getVersion(it.Key())
+		if err != nil {
+			mvcclog.Error("TrashKeepLatest get verson", "err", err)
+			continue
+		}
+		if v > version {
+			continue
+		}
+		if !kept {
+			kept = true
+			continue
+		}
+		batch.Delete(cloneByte(it.Key()))
+		if batch.ValueSize() < trashBatchSize {
+			continue
+		}
+		if err = batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+	}
+	if err := it.Error(); err != nil {
+		mvcclog.Error("TrashKeepLatest", "error", err)
+		return err
+	}
+	return batch.Write()
+}
+
 //DelVersion del stateHash version map
 func (m *MVCCHelper) DelVersion(hash []byte) error {
 	version, err := m.GetVersion(hash)
@@ -175,12 +225,17 @@ func (m *MVCCHelper) DelV(key []byte, version int64) error {
 
 //NewSimpleMVCC new
 func NewSimpleMVCC(db KVDB) *SimpleMVCC {
-	return &SimpleMVCC{db}
+	return NewSimpleMVCCWithPrefix(db, mvccPrefix)
+}
+
+//NewSimpleMVCCWithPrefix 使用指定的key前缀, 例如执行器localdb的多版本数据和状态的多版本数据分开保存
+func NewSimpleMVCCWithPrefix(db KVDB, prefix []byte) *SimpleMVCC {
+	return &SimpleMVCC{kvdb: db, prefix: prefix}
 }
 
 //GetVersion get stateHash and version map
 func (m *SimpleMVCC) GetVersion(hash []byte) (int64, error) {
-	key := getVersionHashKey(hash)
+	key := m.getVersionHashKey(hash)
 	value, err := m.kvdb.Get(key)
 	if err != nil {
 		if err == ErrNotFoundInDb {
@@ -201,7 +256,7 @@ func (m *SimpleMVCC) GetVersion(hash []byte) (int64, error) {
 
 //GetVersionHash 获取版本hash
 func (m *SimpleMVCC) GetVersionHash(version int64) ([]byte, error) {
-	key := getVersionKey(version)
+	key := m.getVersionKey(version)
 	value, err := m.kvdb.Get(key)
 	if err != nil {
 		if err == ErrNotFoundInDb {
@@ -214,7 +269,7 @@ func (m *SimpleMVCC) GetVersionHash(version int64) ([]byte, error) {
 
 //GetMaxVersion 获取最高版本
 func (m *SimpleMVCC) GetMaxVersion() (int64, error) {
-	vals, err := m.kvdb.List(mvccMetaVersion, nil, 1, ListDESC)
+	vals, err := m.kvdb.List(m.withPrefix(metaVersionPart), nil, 1, ListDESC)
 	if err != nil {
 		return 0, err
 	}
@@ -224,7 +279,7 @@ func (m *SimpleMVCC) GetMaxVersion() (int64, error) {
 
 //GetSaveKV only export set key and value with version
 func (m *SimpleMVCC) GetSaveKV(key []byte, value []byte, version int64) (*types.KeyValue, error) {
-	k, err := GetKey(key, version)
+	k, err := m.GetKey(key, version)
 	if err != nil {
 		return nil, err
 	}
@@ -233,7 +288,7 @@ func (m *SimpleMVCC) GetSaveKV(key []byte, value []byte, version int64) (*types.
 
 //GetDelKV only export del key and value with version
 func (m *SimpleMVCC) GetDelKV(key []byte, version int64) (*types.KeyValue, error) {
-	k, err := GetKey(key, version)
+	k, err := m.GetKey(key, version)
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +297,7 @@ func (m *SimpleMVCC) GetDelKV(key []byte, version int64) (*types.KeyValue, error
 
 //GetDelKVList 获取列表
 func (m *SimpleMVCC) GetDelKVList(version int64) ([]*types.KeyValue, error) {
-	k := getVersionKeyListKey(version)
+	k := m.getVersionKeyListKey(version)
 	data, err := m.kvdb.Get(k)
 	if err != nil {
 		return nil, err
@@ -260,11 +315,11 @@ func (m *SimpleMVCC) SetVersionKV(hash []byte, version int64) ([]*types.KeyValue
 	if version < 0 {
 		return nil, types.ErrVersion
 	}
-	key := append(mvccMeta, hash...)
+	key := m.getVersionHashKey(hash)
 	data := &types.Int64{Data: version}
 	v1 := &types.KeyValue{Key: key, Value: types.Encode(data)}
 
-	k2 := append(mvccMetaVersion, pad(version)...)
+	k2 := m.getVersionKey(version)
 	v2 := &types.KeyValue{Key: k2, Value: hash}
 	return []*types.KeyValue{v1, v2}, nil
 }
@@ -292,8 +347,8 @@ func (m *SimpleMVCC) SetV(key []byte, value []byte, version int64) error {
 
 //GetV get key with version
 func (m *SimpleMVCC) GetV(key []byte, version int64) ([]byte, error) {
-	prefix := GetKeyPerfix(key)
-	search, err := GetKey(key, version)
+	prefix := m.GetKeyPerfix(key)
+	search, err := m.GetKey(key, version)
 	if err != nil {
 		return nil, err
 	}
@@ -346,7 +401,7 @@ func (m *SimpleMVCC) AddMVCC(kvs []*types.KeyValue, hash []byte, prevHash []byte
 		}
 		kvlist = append(kvlist, kv)
 	}
-	kvlist = append(kvlist, &types.KeyValue{Key: getVersionKeyListKey(version), Value: types.Encode(delkeys)})
+	kvlist = append(kvlist, &types.KeyValue{Key: m.getVersionKeyListKey(version), Value: types.Encode(delkeys)})
 	return kvlist, nil
 }
 
@@ -416,6 +471,15 @@ func cutVersion(key []byte) []byte {
 	return nil
 }
 
+//versionKeyPrefix 去掉版本号之后的key, 保留最后的分隔符
+func versionKeyPrefix(key []byte) []byte {
+	i := bytes.LastIndexByte(key, '.')
+	if i < 0 {
+		return nil
+	}
+	return cloneByte(key[:i+1])
+}
+
 func getVersion(key []byte) (int64, error) {
 	s, err := getVersionString(key)
 	if err != nil {
@@ -431,37 +495,64 @@ func pad(version int64) []byte {
 
 //GetKeyPerfix 获取key前缀
 func GetKeyPerfix(key []byte) []byte {
-	b := append([]byte{}, mvccData...)
-	newkey := append(b, key...)
-	newkey = append(newkey, []byte(".")...)
-	return newkey
+	return NewSimpleMVCC(nil).GetKeyPerfix(key)
 }
 
 //GetKey 获取键
 func GetKey(key []byte, version int64) ([]byte, error) {
-	newkey := append(GetKeyPerfix(key), pad(version)...)
+	return NewSimpleMVCC(nil).GetKey(key, version)
+}
+
+//GetKeyPerfix 获取key在当前前缀下的数据前缀
+func (m *SimpleMVCC) GetKeyPerfix(key []byte) []byte {
+	newkey := append(m.dataPrefix(), key...)
+	newkey = append(newkey, []byte(".")...)
+	return newkey
+}
+
+//GetKey 获取key在当前前缀下某个版本的键
+func (m *SimpleMVCC) GetKey(key []byte, version int64) ([]byte, error) {
+	newkey := append(m.GetKeyPerfix(key), pad(version)...)
 	return newkey, nil
 }
 
-func getLastKey(key []byte) []byte {
-	b := append([]byte{}, mvccLast...)
-	return append(b, key...)
+const (
+	metaPart            = "m."
+	dataPart            = "d."
+	lastPart            = "l."
+	metaVersionPart     = "m.version."
+	metaVersionListPart = "m.versionkl."
+)
+
+func (m *SimpleMVCC) withPrefix(part string) []byte {
+	b := append([]byte{}, m.prefix...)
+	return append(b, part...)
+}
+
+func (m *SimpleMVCC) metaPrefix() []byte {
+	return m.withPrefix(metaPart)
+}
+
+func (m *SimpleMVCC) dataPrefix() []byte {
+	return m.withPrefix(dataPart)
+}
+
+func (m *SimpleMVCC) lastPrefix() []byte {
+	return m.withPrefix(lastPart)
+}
+
+func (m *SimpleMVCC) getLastKey(key []byte) []byte {
+	return append(m.lastPrefix(), key...)
 }
 
-func getVersionHashKey(hash []byte) []byte {
-	b := append([]byte{}, mvccMeta...)
-	key := append(b, hash...)
-	return key
+func (m *SimpleMVCC) getVersionHashKey(hash []byte) []byte {
+	return append(m.metaPrefix(), hash...)
 }
 
-func getVersionKey(version int64) []byte {
-	b := append([]byte{}, mvccMetaVersion...)
-	key := append(b, pad(version)...)
-	return key
+func (m *SimpleMVCC) getVersionKey(version int64) []byte {
+	return append(m.withPrefix(metaVersionPart), pad(version)...)
 }
 
-func getVersionKeyListKey(version int64) []byte {
-	b := append([]byte{}, mvccMetaVersionKeyList...)
-	key := append(b, pad(version)...)
-	return key
+func (m *SimpleMVCC) getVersionKeyListKey(version int64) []byte {
+	return append(m.withPrefix(metaVersionListPart), pad(version)...)
 }
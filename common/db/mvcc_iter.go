@@ -22,6 +22,11 @@ func NewMVCCIter(db DB) *MVCCIter {
 	return &MVCCIter{MVCCHelper: NewMVCC(db)}
 }
 
+//NewMVCCIterWithPrefix 使用指定的key前缀创建MVCCIter, last数据也保存在这个前缀下
+func NewMVCCIterWithPrefix(db DB, prefix []byte) *MVCCIter {
+	return &MVCCIter{MVCCHelper: NewMVCCWithPrefix(db, prefix)}
+}
+
 //AddMVCC add
 func (m *MVCCIter) AddMVCC(kvs []*types.KeyValue, hash []byte, prevHash []byte, version int64) ([]*types.KeyValue, error) {
 	kvlist, err := m.MVCCHelper.AddMVCC(kvs, hash, prevHash, version)
@@ -30,7 +35,7 @@ func (m *MVCCIter) AddMVCC(kvs []*types.KeyValue, hash []byte, prevHash []byte,
 	}
 	//添加last
 	for _, v := range kvs {
-		last := getLastKey(v.Key)
+		last := m.getLastKey(v.Key)
 		kv := &types.KeyValue{Key: last, Value: v.Value}
 		kvlist = append(kvlist, kv)
 	}
@@ -52,13 +57,13 @@ func (m *MVCCIter) DelMVCC(hash []byte, version int64, strict bool) ([]*types.Ke
 		if version > 0 {
 			lastv, err := m.GetV(v.Key, version-1)
 			if err == types.ErrNotFound {
-				kvlist = append(kvlist, &types.KeyValue{Key: getLastKey(v.Key)})
+				kvlist = append(kvlist, &types.KeyValue{Key: m.getLastKey(v.Key)})
 				continue
 			}
 			if err != nil {
 				return nil, err
 			}
-			kvlist = append(kvlist, &types.KeyValue{Key: getLastKey(v.Key), Value: lastv})
+			kvlist = append(kvlist, &types.KeyValue{Key: m.getLastKey(v.Key), Value: lastv})
 		}
 	}
 	return kvlist, nil
@@ -67,25 +72,26 @@ func (m *MVCCIter) DelMVCC(hash []byte, version int64, strict bool) ([]*types.Ke
 //Iterator 迭代
 func (m *MVCCIter) Iterator(start, end []byte, reserver bool) Iterator {
 	if start == nil {
-		start = mvccLast
+		start = m.lastPrefix()
 	} else {
-		start = getLastKey(start)
+		start = m.getLastKey(start)
 	}
 	if end != nil {
-		end = getLastKey(end)
+		end = m.getLastKey(end)
 	} else {
 		end = bytesPrefix(start)
 	}
-	return &mvccIt{m.db.Iterator(start, end, reserver)}
+	return &mvccIt{Iterator: m.db.Iterator(start, end, reserver), prefix: m.lastPrefix()}
 }
 
 type mvccIt struct {
 	Iterator
+	prefix []byte
 }
 
 //Prefix 前缀
 func (dbit *mvccIt) Prefix() []byte {
-	return mvccLast
+	return dbit.prefix
 }
 
 //Key key
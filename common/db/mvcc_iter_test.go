@@ -82,6 +82,26 @@ func TestAddDelMVCCIter(t *testing.T) {
 	//m.PrintAll()
 }
 
+func TestMVCCIterWithPrefix(t *testing.T) {
+	m := getMVCCIter()
+	defer m.db.Close()
+	other := NewMVCCIterWithPrefix(m.db, []byte(".-other-."))
+	kvlist, err := m.AddMVCC(KeyValueList([2]string{"a", "1"}), hashN(0), nil, 0)
+	assert.Nil(t, err)
+	saveKVList(m.db, kvlist)
+	kvlist, err = other.AddMVCC(KeyValueList([2]string{"b", "2"}), hashN(0), nil, 0)
+	assert.Nil(t, err)
+	saveKVList(m.db, kvlist)
+	//last数据保存在各自的前缀下, 迭代时互不影响
+	values := NewListHelper(m).List(nil, nil, 100, 1)
+	assert.Equal(t, [][]byte{[]byte("1")}, values)
+	values = NewListHelper(other).List(nil, nil, 100, 1)
+	assert.Equal(t, [][]byte{[]byte("2")}, values)
+	value, err := m.db.Get([]byte(".-other-.l.b"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("2"), value)
+}
+
 func TestGetAllCoinsMVCCIter(t *testing.T) {
 	dir, err := ioutil.TempDir("", "goleveldb")
 	assert.Nil(t, err)
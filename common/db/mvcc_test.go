@@ -157,6 +157,57 @@ func TestVersionSetAndGet(t *testing.T) {
 	assert.Equal(t, v, []byte("v3"))
 }
 
+func TestTrashKeepLatest(t *testing.T) {
+	setV := func(m *MVCCHelper) {
+		m.SetV([]byte("k0"), []byte("v0"), 0)
+		m.SetV([]byte("k0"), []byte("v01"), 1)
+		m.SetV([]byte("k0"), []byte("v03"), 3)
+		m.SetV([]byte("k1"), []byte("v1"), 1)
+	}
+	//Trash 删除除了最新版本之外的不超过version的全部版本
+	m := getMVCC()
+	setV(m)
+	assert.Nil(t, m.Trash(2))
+	_, err := m.GetV([]byte("k0"), 2)
+	assert.Equal(t, types.ErrNotFound, err)
+	v, err := m.GetV([]byte("k0"), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v03"), v)
+	closeMVCC(m)
+
+	//TrashKeepLatest 保留不超过version的最新版本
+	m = getMVCC()
+	defer closeMVCC(m)
+	setV(m)
+	assert.Nil(t, m.TrashKeepLatest(2))
+	_, err = m.GetV([]byte("k0"), 0)
+	assert.Equal(t, types.ErrNotFound, err)
+	v, err = m.GetV([]byte("k0"), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v01"), v)
+	v, err = m.GetV([]byte("k0"), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v03"), v)
+	v, err = m.GetV([]byte("k1"), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), v)
+
+	//前缀相同的其他key不是旧版本
+	m.SetV([]byte("a-x"), []byte("ax1"), 1)
+	m.SetV([]byte("a"), []byte("a1"), 1)
+	m.SetV([]byte("a"), []byte("a2"), 2)
+	m.SetV([]byte("a"), []byte("a3"), 3)
+	assert.Nil(t, m.TrashKeepLatest(5))
+	v, err = m.GetV([]byte("a-x"), 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ax1"), v)
+	v, err = m.GetV([]byte("a"), 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("a3"), v)
+	_, err = m.GetV([]byte("a"), 2)
+	assert.Equal(t, types.ErrNotFound, err)
+}
+
 func hashN(n int) []byte {
 	s := fmt.Sprint(n)
 	return common.Sha256([]byte(s))
@@ -0,0 +1,117 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/33cn/chain33/types"
+)
+
+//登记的表结构, 用于dbinspect等离线工具按照表结构解析数据库中的数据
+var (
+	registryMtx sync.RWMutex
+	registry    = make(map[string]*registeredTable)
+)
+
+type registeredTable struct {
+	opt     *Option
+	newMeta func() RowMeta
+}
+
+//TableKV 按照表结构解析之后的一条数据
+type TableKV struct {
+	//Table 表名, 为 prefix-name
+	Table string
+	//Index 索引名, 数据行为空, 计数器为autoinc
+	Index   string
+	Primary []byte
+	//IndexValue 索引的值
+	IndexValue []byte
+	//Data 数据行的内容, 只有数据行和计数器有
+	Data types.Message
+}
+
+//RegisterTable 登记表结构, 一般在init中调用, 同一个表重复登记时覆盖
+func RegisterTable(opt *Option, newMeta func() RowMeta) {
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+	registry[opt.Prefix+sep+opt.Name] = &registeredTable{opt: opt, newMeta: newMeta}
+}
+
+//RegisteredTables 全部登记的表结构, 按照表名排序
+func RegisteredTables() []*Option {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	opts := make([]*Option, 0, len(registry))
+	for _, t := range registry {
+		opts = append(opts, t.opt)
+	}
+	sort.Slice(opts, func(i, j int) bool {
+		return opts[i].Prefix+sep+opts[i].Name < opts[j].Prefix+sep+opts[j].Name
+	})
+	return opts
+}
+
+//DecodeTableKV 按照登记的表结构解析key value, 不属于任何登记的表时返回ErrNotFound
+func DecodeTableKV(key, value []byte) (*TableKV, error) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	for name, t := range registry {
+		if !bytes.HasPrefix(key, []byte(name+sep)) {
+			continue
+		}
+		return t.decode(name, key, value)
+	}
+	return nil, types.ErrNotFound
+}
+
+func (t *registeredTable) decode(name string, key, value []byte) (*TableKV, error) {
+	kv := &TableKV{Table: name}
+	rest := key[len(name+sep):]
+	switch {
+	case bytes.HasPrefix(rest, []byte(data[1:])):
+		primary, body, err := DecodeRow(value)
+		if err != nil {
+			return nil, err
+		}
+		row := t.newMeta().CreateRow()
+		err = types.Decode(body, row.Data)
+		if err != nil {
+			return nil, err
+		}
+		kv.Primary = primary
+		kv.Data = row.Data
+		return kv, nil
+	case bytes.HasPrefix(rest, []byte(meta[1:])):
+		rest = rest[len(meta)-1:]
+		for _, index := range t.opt.Index {
+			if !bytes.HasPrefix(rest, []byte(index+sep)) {
+				continue
+			}
+			kv.Index = index
+			//索引值中可能包含分隔符, 通过value中的主键确定索引值的范围
+			kv.Primary = value
+			indexValue := rest[len(index+sep):]
+			if bytes.HasSuffix(indexValue, append([]byte(sep), value...)) {
+				indexValue = indexValue[:len(indexValue)-len(value)-len(sep)]
+			}
+			kv.IndexValue = indexValue
+			return kv, nil
+		}
+	case bytes.Equal(rest, []byte("autoinc"+sep)):
+		var num types.Int64
+		err := types.Decode(value, &num)
+		if err != nil {
+			return nil, err
+		}
+		kv.Index = "autoinc"
+		kv.Data = &num
+		return kv, nil
+	}
+	return nil, types.ErrNotFound
+}
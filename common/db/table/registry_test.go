@@ -0,0 +1,67 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeTableKV(t *testing.T) {
+	dir, ldb, kvdb := util.CreateTestDB()
+	defer util.CloseTestDB(dir, ldb)
+	opt := &Option{
+		Prefix:  "LODB-registry",
+		Name:    "tx",
+		Primary: "",
+		Index:   []string{"From", "To"},
+	}
+	RegisterTable(opt, func() RowMeta { return NewTransactionRow() })
+	assert.Contains(t, RegisteredTables(), opt)
+
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	table, err := NewTable(NewTransactionRow(), kvdb, opt)
+	require.NoError(t, err)
+	addr, priv := util.Genaddress()
+	tx := util.CreateNoneTx(cfg, priv)
+	require.NoError(t, table.Add(tx))
+	kvs, err := table.Save()
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(kvs))
+
+	var rows, indexes, counts int
+	for _, kv := range kvs {
+		decoded, err := DecodeTableKV(kv.Key, kv.Value)
+		require.NoError(t, err)
+		assert.Equal(t, "LODB-registry-tx", decoded.Table)
+		switch decoded.Index {
+		case "":
+			rows++
+			assert.True(t, proto.Equal(tx, decoded.Data))
+			assert.Equal(t, []byte("00000000000000000001"), decoded.Primary)
+		case "From":
+			indexes++
+			assert.Equal(t, []byte(addr), decoded.IndexValue)
+			assert.Equal(t, []byte("00000000000000000001"), decoded.Primary)
+		case "To":
+			indexes++
+			assert.Equal(t, []byte(tx.To), decoded.IndexValue)
+		case "autoinc":
+			counts++
+			assert.Equal(t, int64(1), decoded.Data.(*types.Int64).Data)
+		}
+	}
+	assert.Equal(t, 1, rows)
+	assert.Equal(t, 2, indexes)
+	assert.Equal(t, 1, counts)
+
+	_, err = DecodeTableKV([]byte("LODB-other-tx-d-1"), nil)
+	assert.Equal(t, types.ErrNotFound, err)
+}
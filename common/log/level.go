@@ -0,0 +1,162 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/33cn/chain33/common/log/log15"
+)
+
+// moduleKey 日志上下文中模块名的key, 即log.New("module", "blockchain")
+const moduleKey = "module"
+
+// ErrEmptyModule 设置模块日志级别时模块名为空
+var ErrEmptyModule = errors.New("ErrEmptyModule")
+
+// moduleLevels 按模块设置的日志级别, 写时复制, 读日志时无锁
+type moduleLevels struct {
+	lvls     map[string]log15.Lvl
+	maxLevel log15.Lvl
+}
+
+var (
+	levelLock sync.Mutex
+	levels    atomic.Value
+)
+
+func init() {
+	levels.Store(&moduleLevels{lvls: make(map[string]log15.Lvl)})
+}
+
+func loadLevels() *moduleLevels {
+	return levels.Load().(*moduleLevels)
+}
+
+// lookup 查找模块的日志级别, 没有单独设置时逐级向上查找, 如p2p.dht -> p2p
+func (m *moduleLevels) lookup(module string, def log15.Lvl) log15.Lvl {
+	if len(m.lvls) == 0 || module == "" {
+		return def
+	}
+	for {
+		if lvl, ok := m.lvls[module]; ok {
+			return lvl
+		}
+		i := strings.LastIndexByte(module, '.')
+		if i < 0 {
+			return def
+		}
+		module = module[:i]
+	}
+}
+
+func recordModule(r *log15.Record) string {
+	for i := 0; i+1 < len(r.Ctx); i += 2 {
+		if k, ok := r.Ctx[i].(string); ok && k == moduleKey {
+			module, _ := r.Ctx[i+1].(string)
+			return module
+		}
+	}
+	return ""
+}
+
+// moduleLvlHandler 按照模块的日志级别过滤日志, 模块没有单独设置时使用默认级别lvl
+type moduleLvlHandler struct {
+	lvl int32
+	h   log15.Handler
+}
+
+func newModuleLvlHandler(lvl log15.Lvl, h log15.Handler) log15.Handler {
+	return &moduleLvlHandler{lvl: int32(lvl), h: h}
+}
+
+func (h *moduleLvlHandler) level() log15.Lvl {
+	return log15.Lvl(atomic.LoadInt32(&h.lvl))
+}
+
+func (h *moduleLvlHandler) Log(r *log15.Record) error {
+	if r.Lvl > loadLevels().lookup(recordModule(r), h.level()) {
+		return nil
+	}
+	return h.h.Log(r)
+}
+
+// MaxLevel 默认级别和所有模块级别中最详细的一个, logger据此提前过滤日志
+func (h *moduleLvlHandler) MaxLevel() int {
+	lvl := h.level()
+	if max := loadLevels().maxLevel; max > lvl {
+		lvl = max
+	}
+	return int(lvl)
+}
+
+// SetMaxLevel 设置默认日志级别
+func (h *moduleLvlHandler) SetMaxLevel(maxLevel int) {
+	atomic.StoreInt32(&h.lvl, int32(maxLevel))
+}
+
+// SetModuleLevel 运行时设置模块的日志级别, 同时作用于控制台和文件日志, 子模块继承上级模块的设置
+// level为空时删除该模块的单独设置, 恢复使用默认级别
+func SetModuleLevel(module, level string) error {
+	if module == "" {
+		return ErrEmptyModule
+	}
+	var lvl log15.Lvl
+	if level != "" {
+		var err error
+		lvl, err = log15.LvlFromString(level)
+		if err != nil {
+			return err
+		}
+	}
+
+	levelLock.Lock()
+	old := loadLevels()
+	m := &moduleLevels{lvls: make(map[string]log15.Lvl, len(old.lvls)+1)}
+	for k, v := range old.lvls {
+		m.lvls[k] = v
+	}
+	if level == "" {
+		delete(m.lvls, module)
+	} else {
+		m.lvls[module] = lvl
+	}
+	for _, v := range m.lvls {
+		if v > m.maxLevel {
+			m.maxLevel = v
+		}
+	}
+	levels.Store(m)
+	levelLock.Unlock()
+
+	refreshRoot()
+	return nil
+}
+
+// ModuleLevels 返回所有单独设置了日志级别的模块
+func ModuleLevels() map[string]string {
+	m := loadLevels()
+	lvls := make(map[string]string, len(m.lvls))
+	for k, v := range m.lvls {
+		lvls[k] = v.String()
+	}
+	return lvls
+}
+
+// DefaultLevels 返回文件和控制台日志的默认级别, 没有开启文件日志时file为空
+func DefaultLevels() (file string, console string) {
+	handlerLock.Lock()
+	defer handlerLock.Unlock()
+	if fileHandler != nil && isActive(*fileHandler) {
+		file = (*fileHandler).(*moduleLvlHandler).level().String()
+	}
+	if consoleHandler != nil && isActive(*consoleHandler) {
+		console = (*consoleHandler).(*moduleLvlHandler).level().String()
+	}
+	return file, console
+}
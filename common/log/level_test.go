@@ -0,0 +1,93 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/33cn/chain33/common/log/log15"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleLevel(t *testing.T) {
+	var records []*log15.Record
+	h := newModuleLvlHandler(log15.LvlError, log15.FuncHandler(int(log15.LvlDebug), func(r *log15.Record) error {
+		records = append(records, r)
+		return nil
+	}))
+	setRootHandlers(h)
+	defer setRootHandlers()
+
+	p2plog := New("module", "p2p")
+	dhtlog := p2plog.New("submodule", "dht")
+	peerlog := New("module", "p2p.peer")
+	chainlog := New("module", "blockchain")
+
+	p2plog.Info("p2p")
+	chainlog.Info("blockchain")
+	assert.Equal(t, 0, len(records))
+
+	require.Nil(t, SetModuleLevel("p2p", "debug"))
+	assert.Equal(t, int(log15.LvlDebug), h.MaxLevel())
+	p2plog.Debug("p2p")
+	dhtlog.Debug("dht")
+	peerlog.Debug("peer")
+	chainlog.Info("blockchain")
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, map[string]string{"p2p": "dbug"}, ModuleLevels())
+
+	require.Nil(t, SetModuleLevel("p2p.peer", "crit"))
+	peerlog.Error("peer")
+	assert.Equal(t, 3, len(records))
+
+	require.Nil(t, SetModuleLevel("p2p", ""))
+	require.Nil(t, SetModuleLevel("p2p.peer", ""))
+	assert.Equal(t, int(log15.LvlError), h.MaxLevel())
+	p2plog.Debug("p2p")
+	peerlog.Error("peer")
+	assert.Equal(t, 4, len(records))
+	assert.Equal(t, 0, len(ModuleLevels()))
+
+	assert.Equal(t, ErrEmptyModule, SetModuleLevel("", "debug"))
+	assert.NotNil(t, SetModuleLevel("p2p", "verbose"))
+}
+
+func TestModuleLevelConcurrent(t *testing.T) {
+	h := newModuleLvlHandler(log15.LvlError, log15.DiscardHandler())
+	setRootHandlers(h)
+	defer setRootHandlers()
+
+	//运行时修改日志级别的同时, 其他协程在写日志和创建子logger
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				New("module", "p2p").New("peer", j).Debug("p2p")
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		require.Nil(t, SetModuleLevel("p2p", "debug"))
+		require.Nil(t, SetModuleLevel("p2p", ""))
+	}
+	wg.Wait()
+}
+
+func TestRecordModule(t *testing.T) {
+	r := &log15.Record{Ctx: []interface{}{"module", "p2p.dht", "submodule", "peer"}}
+	assert.Equal(t, "p2p.dht", recordModule(r))
+	r.Ctx = []interface{}{"height", 1}
+	assert.Equal(t, "", recordModule(r))
+
+	m := &moduleLevels{lvls: map[string]log15.Lvl{"p2p": log15.LvlWarn, "p2p.dht": log15.LvlDebug}}
+	assert.Equal(t, log15.LvlDebug, m.lookup("p2p.dht.peer", log15.LvlError))
+	assert.Equal(t, log15.LvlWarn, m.lookup("p2p.peer", log15.LvlError))
+	assert.Equal(t, log15.LvlError, m.lookup("p2pnext", log15.LvlError))
+	assert.Equal(t, log15.LvlError, m.lookup("", log15.LvlError))
+}
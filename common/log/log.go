@@ -7,16 +7,31 @@ package log
 
 import (
 	"os"
+	"sync"
+	"time"
 
 	"github.com/33cn/chain33/common/log/log15"
 	"github.com/33cn/chain33/types"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
+// 日志输出格式
+const (
+	// FormatJSON json格式, 每行一条日志, 便于日志收集系统解析
+	FormatJSON = "json"
+	// FormatLogfmt key=value格式
+	FormatLogfmt = "logfmt"
+	// FormatTerminal 终端格式, 控制台默认使用
+	FormatTerminal = "terminal"
+)
+
 var (
 	// 保存日志处理器的引用，方便后续调整日志信息，而不重新初始化
 	fileHandler    *log15.Handler
 	consoleHandler *log15.Handler
+	// 当前挂在root logger上的处理器, 模块日志级别变化后需要重新设置, 使各个logger的级别生效
+	activeHandlers []log15.Handler
+	handlerLock    sync.Mutex
 )
 
 func init() {
@@ -25,9 +40,9 @@ func init() {
 
 //SetLogLevel 设置控制台日志输出级别
 func SetLogLevel(logLevel string) {
-	handler := getConsoleLogHandler(logLevel)
+	handler := getConsoleLogHandler(logLevel, "")
 	(*handler).SetMaxLevel(int(getLevel(logLevel)))
-	log15.Root().SetHandler(*handler)
+	setRootHandlers(*handler)
 }
 
 //SetFileLog 设置文件日志和控制台日志信息
@@ -36,7 +51,10 @@ func SetFileLog(log *types.Log) {
 		log = &types.Log{LogFile: "logs/chain33.log"}
 	}
 	if log.LogFile == "" {
-		SetLogLevel(log.LogConsoleLevel)
+		setModuleLevels(log.ModuleLevels)
+		handler := getConsoleLogHandler(log.LogConsoleLevel, log.Format)
+		(*handler).SetMaxLevel(int(getLevel(log.LogConsoleLevel)))
+		setRootHandlers(*handler)
 	} else {
 		resetLog(log)
 	}
@@ -45,7 +63,46 @@ func SetFileLog(log *types.Log) {
 // 清空原来所有的日志Handler，根据配置文件信息重置文件和控制台日志
 func resetLog(log *types.Log) {
 	fillDefaultValue(log)
-	log15.Root().SetHandler(log15.MultiHandler(*getConsoleLogHandler(log.LogConsoleLevel), *getFileLogHandler(log)))
+	setModuleLevels(log.ModuleLevels)
+	setRootHandlers(*getConsoleLogHandler(log.LogConsoleLevel, log.Format), *getFileLogHandler(log))
+}
+
+func setModuleLevels(lvls map[string]string) {
+	for module, level := range lvls {
+		if err := SetModuleLevel(module, level); err != nil {
+			log15.Root().Error("SetModuleLevel", "module", module, "level", level, "err", err)
+		}
+	}
+}
+
+func setRootHandlers(hs ...log15.Handler) {
+	handlerLock.Lock()
+	activeHandlers = hs
+	handlerLock.Unlock()
+	refreshRoot()
+}
+
+// refreshRoot 重新设置root logger的处理器, 按照最新的日志级别更新所有logger的最大级别
+func refreshRoot() {
+	handlerLock.Lock()
+	defer handlerLock.Unlock()
+	switch len(activeHandlers) {
+	case 0:
+		return
+	case 1:
+		log15.Root().SetHandler(activeHandlers[0])
+	default:
+		log15.Root().SetHandler(log15.MultiHandler(activeHandlers...))
+	}
+}
+
+func isActive(h log15.Handler) bool {
+	for _, active := range activeHandlers {
+		if active == h {
+			return true
+		}
+	}
+	return false
 }
 
 // 保证默认性况下为error级别，防止打印太多日志
@@ -62,17 +119,29 @@ func isWindows() bool {
 	return os.PathSeparator == '\\' && os.PathListSeparator == ';'
 }
 
-func getConsoleLogHandler(logLevel string) *log15.Handler {
+func getFormat(format string, def log15.Format) log15.Format {
+	switch format {
+	case FormatJSON:
+		return log15.JSONFormat()
+	case FormatLogfmt:
+		return log15.LogfmtFormat()
+	case FormatTerminal:
+		return log15.TerminalFormat()
+	}
+	return def
+}
+
+func getConsoleLogHandler(logLevel string, format string) *log15.Handler {
 	if consoleHandler != nil {
 		return consoleHandler
 	}
-	format := log15.TerminalFormat()
+	def := log15.TerminalFormat()
 	if isWindows() {
-		format = log15.LogfmtFormat()
+		def = log15.LogfmtFormat()
 	}
-	stdouth := log15.LvlFilterHandler(
+	stdouth := newModuleLvlHandler(
 		getLevel(logLevel),
-		log15.StreamHandler(os.Stdout, format),
+		log15.StreamHandler(os.Stdout, getFormat(format, def)),
 	)
 
 	consoleHandler = &stdouth
@@ -94,10 +163,12 @@ func getFileLogHandler(log *types.Log) *log15.Handler {
 		Compress:   log.Compress,
 	}
 
-	fileh := log15.LvlFilterHandler(
-		getLevel(log.Loglevel),
-		log15.StreamHandler(rotateLogger, log15.LogfmtFormat()),
-	)
+	// 按时间间隔切分日志文件, 与按大小切分同时生效
+	if log.RotateInterval > 0 {
+		go rotateRoutine(rotateLogger, time.Duration(log.RotateInterval)*time.Hour)
+	}
+
+	fileh := log15.StreamHandler(rotateLogger, getFormat(log.Format, log15.LogfmtFormat()))
 
 	// 增加打印调用源文件、方法和代码行的判断
 	if log.CallerFile {
@@ -106,12 +177,23 @@ func getFileLogHandler(log *types.Log) *log15.Handler {
 	if log.CallerFunction {
 		fileh = log15.CallerFuncHandler(fileh)
 	}
+	fileh = newModuleLvlHandler(getLevel(log.Loglevel), fileh)
 
 	fileHandler = &fileh
 
 	return &fileh
 }
 
+func rotateRoutine(logger *lumberjack.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := logger.Rotate(); err != nil {
+			log15.Root().Error("rotate log file", "file", logger.Filename, "err", err)
+		}
+	}
+}
+
 func getLevel(lvlString string) log15.Lvl {
 	lvl, err := log15.LvlFromString(lvlString)
 	if err != nil {
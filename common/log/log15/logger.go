@@ -6,6 +6,8 @@ package log15
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-stack/stack"
@@ -103,14 +105,21 @@ type Logger interface {
 }
 
 type logger struct {
-	ctx      []interface{}
-	h        *swapHandler
+	ctx []interface{}
+	h   *swapHandler
+	// maxLevel and children can be changed at runtime (e.g. by the log level rpc)
+	// while other goroutines are logging or creating child loggers
+	maxLevel int32
+	mu       sync.Mutex
 	children []Logger
-	maxLevel int
+}
+
+func newLogger(ctx []interface{}, maxLevel int) *logger {
+	return &logger{ctx: ctx, h: new(swapHandler), maxLevel: int32(maxLevel)}
 }
 
 func (l *logger) write(msg string, lvl Lvl, ctx []interface{}) {
-	if l.maxLevel < int(lvl) {
+	if atomic.LoadInt32(&l.maxLevel) < int32(lvl) {
 		return
 	}
 	l.h.Log(&Record{
@@ -128,14 +137,25 @@ func (l *logger) write(msg string, lvl Lvl, ctx []interface{}) {
 }
 
 func (l *logger) New(ctx ...interface{}) Logger {
-	child := &logger{newContext(l.ctx, ctx), new(swapHandler), nil, l.maxLevel}
+	child := newLogger(newContext(l.ctx, ctx), int(atomic.LoadInt32(&l.maxLevel)))
 	child.SetHandler(l.h)
+	l.mu.Lock()
 	l.children = append(l.children, child)
+	l.mu.Unlock()
 	return child
 }
 
 func (l *logger) SetMaxLevel(maxLevel int) {
-	l.maxLevel = maxLevel
+	atomic.StoreInt32(&l.maxLevel, int32(maxLevel))
+	for _, logger := range l.getChildren() {
+		logger.SetMaxLevel(maxLevel)
+	}
+}
+
+func (l *logger) getChildren() []Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Logger(nil), l.children...)
 }
 
 func newContext(prefix []interface{}, suffix []interface{}) []interface{} {
@@ -172,9 +192,10 @@ func (l *logger) GetHandler() Handler {
 
 func (l *logger) SetHandler(h Handler) {
 	l.h.Swap(h)
-	l.maxLevel = h.MaxLevel()
-	for _, logger := range l.children {
-		logger.SetMaxLevel(l.maxLevel)
+	maxLevel := h.MaxLevel()
+	atomic.StoreInt32(&l.maxLevel, int32(maxLevel))
+	for _, logger := range l.getChildren() {
+		logger.SetMaxLevel(maxLevel)
 	}
 }
 
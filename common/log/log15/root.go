@@ -27,7 +27,7 @@ func init() {
 		StderrHandler = StreamHandler(colorable.NewColorableStderr(), TerminalFormat())
 	}
 
-	root = &logger{[]interface{}{}, new(swapHandler), nil, int(LvlDebug)}
+	root = newLogger([]interface{}{}, int(LvlDebug))
 	root.SetHandler(StdoutHandler)
 }
 
@@ -0,0 +1,30 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package siphash
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	//SipHash论文中的测试向量, 密钥为00..0f
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	k0 := binary.LittleEndian.Uint64(key)
+	k1 := binary.LittleEndian.Uint64(key[8:])
+	data := make([]byte, 64)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	assert.Equal(t, uint64(0x726fdb47dd0e0e31), Hash(k0, k1, nil))
+	assert.Equal(t, uint64(0xa129ca6149be45e5), Hash(k0, k1, data[:15]))
+	assert.Equal(t, uint64(0x93f5f5799a932462), Hash(k0, k1, data[:8]))
+	assert.NotEqual(t, Hash(k0, k1, data[:32]), Hash(k0+1, k1, data[:32]))
+}
@@ -0,0 +1,176 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"encoding/binary"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+)
+
+//节点类型
+const (
+	kindEmpty    = byte(0)
+	kindLeaf     = byte(1)
+	kindInternal = byte(2)
+)
+
+const (
+	hashLen = 32
+	maxBits = hashLen * 8
+	refLen  = 1 + hashLen + 8
+)
+
+var (
+	leafPrefix     = []byte{0}
+	internalPrefix = []byte{1}
+	zeroHash       = make([]byte, hashLen)
+)
+
+//child 子节点的引用, 节点在数据库中的key由创建版本以及所在的位置决定
+type child struct {
+	kind    byte
+	hash    []byte
+	version int64
+}
+
+func (c child) nodeHash() []byte {
+	if c.kind == kindEmpty {
+		return zeroHash
+	}
+	return c.hash
+}
+
+func (c child) encode(buf []byte) []byte {
+	buf = append(buf, c.kind)
+	if c.kind == kindEmpty {
+		return append(buf, make([]byte, refLen-1)...)
+	}
+	buf = append(buf, c.hash...)
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], uint64(c.version))
+	return append(buf, v[:]...)
+}
+
+func decodeChild(data []byte) (child, error) {
+	if len(data) < refLen {
+		return child{}, types.ErrDecode
+	}
+	c := child{kind: data[0]}
+	if c.kind == kindEmpty {
+		return c, nil
+	}
+	if c.kind != kindLeaf && c.kind != kindInternal {
+		return child{}, types.ErrDecode
+	}
+	c.hash = append([]byte{}, data[1:1+hashLen]...)
+	c.version = int64(binary.BigEndian.Uint64(data[1+hashLen : refLen]))
+	return c, nil
+}
+
+//leafNode 叶子节点保存 sha256(key) 和原始的 value
+type leafNode struct {
+	keyHash []byte
+	value   []byte
+}
+
+func (n *leafNode) hash() []byte {
+	return leafHash(n.keyHash, common.Sha256(n.value))
+}
+
+func (n *leafNode) encode() []byte {
+	buf := make([]byte, 0, 1+hashLen+len(n.value))
+	buf = append(buf, kindLeaf)
+	buf = append(buf, n.keyHash...)
+	return append(buf, n.value...)
+}
+
+//internalNode 内部节点保存左右子节点的引用
+type internalNode struct {
+	children [2]child
+}
+
+func (n *internalNode) hash() []byte {
+	return internalHash(n.children[0].nodeHash(), n.children[1].nodeHash())
+}
+
+func (n *internalNode) encode() []byte {
+	buf := make([]byte, 0, 1+2*refLen)
+	buf = append(buf, kindInternal)
+	buf = n.children[0].encode(buf)
+	return n.children[1].encode(buf)
+}
+
+func decodeNode(data []byte) (*leafNode, *internalNode, error) {
+	if len(data) == 0 {
+		return nil, nil, types.ErrDecode
+	}
+	switch data[0] {
+	case kindLeaf:
+		if len(data) < 1+hashLen {
+			return nil, nil, types.ErrDecode
+		}
+		return &leafNode{keyHash: data[1 : 1+hashLen], value: data[1+hashLen:]}, nil, nil
+	case kindInternal:
+		if len(data) != 1+2*refLen {
+			return nil, nil, types.ErrDecode
+		}
+		left, err := decodeChild(data[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		right, err := decodeChild(data[1+refLen:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &internalNode{children: [2]child{left, right}}, nil
+	}
+	return nil, nil, types.ErrDecode
+}
+
+//leafHash 叶子hash = sha256(0x00 + sha256(key) + sha256(value))
+func leafHash(keyHash, valueHash []byte) []byte {
+	data := make([]byte, 0, 1+2*hashLen)
+	data = append(data, leafPrefix...)
+	data = append(data, keyHash...)
+	data = append(data, valueHash...)
+	return common.Sha256(data)
+}
+
+//internalHash 内部节点hash = sha256(0x01 + left + right), 空子树的hash为32字节的0
+func internalHash(left, right []byte) []byte {
+	data := make([]byte, 0, 1+2*hashLen)
+	data = append(data, internalPrefix...)
+	data = append(data, left...)
+	data = append(data, right...)
+	return common.Sha256(data)
+}
+
+//bit 获取路径上第i位, 第0位是根节点下的第一次分叉
+func bit(keyHash []byte, i int) int {
+	return int(keyHash[i/8]>>(7-uint(i%8))) & 1
+}
+
+//maskPath 只保留路径的前depth位
+func maskPath(keyHash []byte, depth int) []byte {
+	path := make([]byte, hashLen)
+	copy(path, keyHash[:(depth+7)/8])
+	if depth%8 != 0 {
+		path[depth/8] &= byte(0xff << (8 - uint(depth%8)))
+	}
+	return path
+}
+
+//setBit 返回把第i位设置为b之后的路径
+func setBit(path []byte, i int, b int) []byte {
+	p := append([]byte{}, path...)
+	if b == 1 {
+		p[i/8] |= 1 << (7 - uint(i%8))
+	} else {
+		p[i/8] &^= 1 << (7 - uint(i%8))
+	}
+	return p
+}
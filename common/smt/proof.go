@@ -0,0 +1,166 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"bytes"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+)
+
+//Proof key的包含或者不包含证明
+//Siblings 为路径上从根向下的非空兄弟节点, 空的兄弟节点只在 Bitmap 中标记
+//路径的终点是其他key的叶子时(不包含证明), LeafKeyHash 和 LeafValueHash 为该叶子的内容
+type Proof struct {
+	Depth         int
+	Bitmap        []byte
+	Siblings      [][]byte
+	LeafKeyHash   []byte
+	LeafValueHash []byte
+}
+
+//Prove 生成版本中key的证明, key 存在时为包含证明, 否则为不包含证明
+func (t *Tree) Prove(key []byte, version int64) (*Proof, error) {
+	c, err := t.rootRef(version)
+	if err != nil {
+		return nil, err
+	}
+	keyHash := common.Sha256(key)
+	proof := &Proof{}
+	var bitmap [hashLen]byte
+	for depth := 0; c.kind == kindInternal; depth++ {
+		_, node, err := t.loadNode(c, depth, keyHash)
+		if err != nil {
+			return nil, err
+		}
+		b := bit(keyHash, depth)
+		sibling := node.children[1-b]
+		if sibling.kind != kindEmpty {
+			bitmap[depth/8] |= 1 << (7 - uint(depth%8))
+			proof.Siblings = append(proof.Siblings, sibling.hash)
+		}
+		c = node.children[b]
+		proof.Depth = depth + 1
+	}
+	proof.Bitmap = append([]byte{}, bitmap[:(proof.Depth+7)/8]...)
+	if c.kind == kindLeaf {
+		leaf, _, err := t.loadNode(c, proof.Depth, keyHash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(leaf.keyHash, keyHash) {
+			proof.LeafKeyHash = append([]byte{}, leaf.keyHash...)
+			proof.LeafValueHash = common.Sha256(leaf.value)
+		}
+	}
+	return proof, nil
+}
+
+//VerifyProof 验证证明, value 为 nil 时验证key不存在, 否则验证key的值为value
+func VerifyProof(root, key, value []byte, proof *Proof) bool {
+	if proof == nil || proof.Depth < 0 || proof.Depth > maxBits || len(proof.Bitmap) != (proof.Depth+7)/8 {
+		return false
+	}
+	count := 0
+	for i := 0; i < proof.Depth; i++ {
+		count += bit(proof.Bitmap, i)
+	}
+	if count != len(proof.Siblings) {
+		return false
+	}
+	keyHash := common.Sha256(key)
+	var h []byte
+	switch {
+	case value != nil:
+		if proof.LeafKeyHash != nil {
+			return false
+		}
+		h = leafHash(keyHash, common.Sha256(value))
+	case proof.LeafKeyHash != nil:
+		if len(proof.LeafKeyHash) != hashLen || len(proof.LeafValueHash) != hashLen || bytes.Equal(proof.LeafKeyHash, keyHash) {
+			return false
+		}
+		//路径上的其他叶子必须和key有相同的前缀
+		for i := 0; i < proof.Depth; i++ {
+			if bit(proof.LeafKeyHash, i) != bit(keyHash, i) {
+				return false
+			}
+		}
+		h = leafHash(proof.LeafKeyHash, proof.LeafValueHash)
+	default:
+		h = zeroHash
+	}
+	next := len(proof.Siblings) - 1
+	for depth := proof.Depth - 1; depth >= 0; depth-- {
+		sibling := zeroHash
+		if bit(proof.Bitmap, depth) == 1 {
+			sibling = proof.Siblings[next]
+			next--
+			if len(sibling) != hashLen {
+				return false
+			}
+		}
+		if bit(keyHash, depth) == 0 {
+			h = internalHash(h, sibling)
+		} else {
+			h = internalHash(sibling, h)
+		}
+	}
+	return bytes.Equal(h, root)
+}
+
+//Encode 编码证明: depth(2字节) + bitmap + siblings + 终点叶子标记(1字节) [+ leafKeyHash + leafValueHash]
+func (p *Proof) Encode() []byte {
+	buf := make([]byte, 0, 2+len(p.Bitmap)+hashLen*len(p.Siblings)+1+2*hashLen)
+	buf = append(buf, byte(p.Depth>>8), byte(p.Depth))
+	buf = append(buf, p.Bitmap...)
+	for _, s := range p.Siblings {
+		buf = append(buf, s...)
+	}
+	if p.LeafKeyHash == nil {
+		return append(buf, 0)
+	}
+	buf = append(buf, 1)
+	buf = append(buf, p.LeafKeyHash...)
+	return append(buf, p.LeafValueHash...)
+}
+
+//DecodeProof 解码证明
+func DecodeProof(data []byte) (*Proof, error) {
+	if len(data) < 3 {
+		return nil, types.ErrDecode
+	}
+	p := &Proof{Depth: int(data[0])<<8 | int(data[1])}
+	if p.Depth > maxBits {
+		return nil, types.ErrDecode
+	}
+	data = data[2:]
+	n := (p.Depth + 7) / 8
+	if len(data) < n+1 {
+		return nil, types.ErrDecode
+	}
+	p.Bitmap = append([]byte{}, data[:n]...)
+	data = data[n:]
+	for i := 0; i < p.Depth; i++ {
+		if bit(p.Bitmap, i) == 0 {
+			continue
+		}
+		if len(data) < hashLen+1 {
+			return nil, types.ErrDecode
+		}
+		p.Siblings = append(p.Siblings, append([]byte{}, data[:hashLen]...))
+		data = data[hashLen:]
+	}
+	switch {
+	case len(data) == 1 && data[0] == 0:
+		return p, nil
+	case len(data) == 1+2*hashLen && data[0] == 1:
+		p.LeafKeyHash = append([]byte{}, data[1:1+hashLen]...)
+		p.LeafValueHash = append([]byte{}, data[1+hashLen:]...)
+		return p, nil
+	}
+	return nil, types.ErrDecode
+}
@@ -0,0 +1,461 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package smt 实现保存在 common/db.DB 上的稀疏默克尔树, 支持批量更新, 包含与不包含证明, 多版本的根以及裁剪
+package smt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/types"
+)
+
+/*
+树的结构:
+
+1. key 的路径为 sha256(key) 的256位, 从高位开始, 0 走左子树, 1 走右子树
+2. 只包含一个叶子的子树直接用该叶子表示, 叶子位于能和其他叶子区分开的最浅位置, 因此根hash只和kv集合有关
+3. 空子树的hash为32字节的0, 空树的根也是32字节的0
+
+数据库中的key (prefix 由使用者指定, 用于区分不同的树):
+
+prefix + "n" + version + depth + path  -> 节点, 节点创建之后不再修改
+prefix + "s" + staleVersion + nodeKey  -> 节点从 staleVersion 开始不再被引用, 用于裁剪
+prefix + "r" + version                 -> 根节点的引用
+
+节点以创建时的版本以及位置作为key, 不同版本共享没有修改的节点;
+裁剪到版本v时, 删除所有在v(含)之前已经不再被引用的节点, v之前的根都不能再使用
+*/
+
+var slog = log.New("module", "common.smt")
+
+var (
+	//ErrVersionNotFound 版本不存在或者已经被裁剪
+	ErrVersionNotFound = errors.New("ErrVersionNotFound")
+	//ErrVersionExists 版本已经存在
+	ErrVersionExists = errors.New("ErrVersionExists")
+	//ErrNotLatestVersion 只能删除最新的版本
+	ErrNotLatestVersion = errors.New("ErrNotLatestVersion")
+	//ErrNodeNotFound 树的节点缺失
+	ErrNodeNotFound = errors.New("ErrNodeNotFound")
+)
+
+//EmptyRoot 空树的根
+var EmptyRoot = zeroHash
+
+//Tree 稀疏默克尔树, 本身不保存状态, 可以并发读取
+type Tree struct {
+	db         dbm.DB
+	nodePrefix []byte
+	stalePref  []byte
+	rootPrefix []byte
+}
+
+//NewTree 创建树, prefix 用于在同一个数据库中区分不同的树
+func NewTree(db dbm.DB, prefix []byte) *Tree {
+	join := func(s string) []byte {
+		return append(append([]byte{}, prefix...), s...)
+	}
+	return &Tree{db: db, nodePrefix: join("n"), stalePref: join("s"), rootPrefix: join("r")}
+}
+
+func encodeVersion(version int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(version))
+	return b[:]
+}
+
+func (t *Tree) nodeKey(version int64, depth int, path []byte) []byte {
+	key := make([]byte, 0, len(t.nodePrefix)+8+2+hashLen)
+	key = append(key, t.nodePrefix...)
+	key = append(key, encodeVersion(version)...)
+	key = append(key, byte(depth>>8), byte(depth))
+	return append(key, path...)
+}
+
+func (t *Tree) staleKey(version int64, nodeKey []byte) []byte {
+	key := make([]byte, 0, len(t.stalePref)+8+len(nodeKey))
+	key = append(key, t.stalePref...)
+	key = append(key, encodeVersion(version)...)
+	return append(key, nodeKey...)
+}
+
+func (t *Tree) rootKey(version int64) []byte {
+	return append(append([]byte{}, t.rootPrefix...), encodeVersion(version)...)
+}
+
+func (t *Tree) rootRef(version int64) (child, error) {
+	if version < 0 {
+		return child{kind: kindEmpty}, nil
+	}
+	data, err := t.db.Get(t.rootKey(version))
+	if err != nil || len(data) == 0 {
+		return child{}, ErrVersionNotFound
+	}
+	return decodeChild(data)
+}
+
+func (t *Tree) loadNode(c child, depth int, path []byte) (*leafNode, *internalNode, error) {
+	data, err := t.db.Get(t.nodeKey(c.version, depth, maskPath(path, depth)))
+	if err != nil || len(data) == 0 {
+		return nil, nil, ErrNodeNotFound
+	}
+	return decodeNode(data)
+}
+
+//Root 获取版本的根hash, version 为 -1 时返回空树的根
+func (t *Tree) Root(version int64) ([]byte, error) {
+	c, err := t.rootRef(version)
+	if err != nil {
+		return nil, err
+	}
+	return c.nodeHash(), nil
+}
+
+//LatestVersion 获取最新的版本, 没有任何版本时返回 -1
+func (t *Tree) LatestVersion() (int64, error) {
+	it := t.db.Iterator(t.rootPrefix, nil, true)
+	defer it.Close()
+	if !it.Rewind() {
+		return -1, nil
+	}
+	if it.Error() != nil {
+		return -1, it.Error()
+	}
+	return int64(binary.BigEndian.Uint64(it.Key()[len(t.rootPrefix):])), nil
+}
+
+//Get 获取版本中key对应的值, key 不存在时返回 nil
+func (t *Tree) Get(key []byte, version int64) ([]byte, error) {
+	c, err := t.rootRef(version)
+	if err != nil {
+		return nil, err
+	}
+	keyHash := common.Sha256(key)
+	for depth := 0; ; depth++ {
+		switch c.kind {
+		case kindEmpty:
+			return nil, nil
+		case kindLeaf:
+			leaf, _, err := t.loadNode(c, depth, keyHash)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(leaf.keyHash, keyHash) {
+				return nil, nil
+			}
+			return leaf.value, nil
+		}
+		_, node, err := t.loadNode(c, depth, keyHash)
+		if err != nil {
+			return nil, err
+		}
+		c = node.children[bit(keyHash, depth)]
+	}
+}
+
+//ChangeSet 一次批量更新的结果, KV 中 Value 为 nil 表示删除
+type ChangeSet struct {
+	Version int64
+	Root    []byte
+	KV      []*types.KeyValue
+}
+
+type entry struct {
+	keyHash []byte
+	value   []byte
+}
+
+//subtree 更新过程中的子树, 最后统一写入, 避免叶子在上下移动时产生无用的节点
+type subtree struct {
+	kind byte
+	//未修改的已有节点以及它的位置
+	ref      *child
+	refDepth int
+	refPath  []byte
+	//新的叶子
+	leaf *entry
+	//新的内部节点
+	left, right *subtree
+}
+
+var emptySubtree = &subtree{kind: kindEmpty}
+
+type updater struct {
+	tree    *Tree
+	version int64
+	cs      *ChangeSet
+}
+
+//Update 在 baseVersion 的树上批量写入kvs, 生成版本 version 的修改集合, value 为 nil 表示删除key
+//同一个key出现多次时以最后一次为准; 修改集合需要通过 Commit 或者使用者自己的batch写入数据库
+//version 必须大于 baseVersion, 且 baseVersion 之后的版本需要先通过 DeleteVersion 删除
+func (t *Tree) Update(baseVersion, version int64, kvs []*types.KeyValue) (*ChangeSet, error) {
+	if version <= baseVersion {
+		return nil, types.ErrVersion
+	}
+	if _, err := t.rootRef(version); err == nil {
+		return nil, ErrVersionExists
+	}
+	root, err := t.rootRef(baseVersion)
+	if err != nil {
+		return nil, err
+	}
+	last := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		last[string(common.Sha256(kv.Key))] = kv.Value
+	}
+	entries := make([]*entry, 0, len(last))
+	for keyHash, value := range last {
+		entries = append(entries, &entry{keyHash: []byte(keyHash), value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].keyHash, entries[j].keyHash) < 0
+	})
+	u := &updater{tree: t, version: version, cs: &ChangeSet{Version: version}}
+	s, err := u.update(root, 0, zeroHash, entries)
+	if err != nil {
+		return nil, err
+	}
+	newRoot, err := u.materialize(s, 0, zeroHash)
+	if err != nil {
+		return nil, err
+	}
+	u.cs.Root = newRoot.nodeHash()
+	u.cs.KV = append(u.cs.KV, &types.KeyValue{Key: t.rootKey(version), Value: newRoot.encode(nil)})
+	return u.cs, nil
+}
+
+//Commit 把修改集合写入数据库
+func (t *Tree) Commit(cs *ChangeSet, sync bool) error {
+	batch := t.db.NewBatch(sync)
+	for _, kv := range cs.KV {
+		if kv.Value == nil {
+			batch.Delete(kv.Key)
+		} else {
+			batch.Set(kv.Key, kv.Value)
+		}
+	}
+	return batch.Write()
+}
+
+func (u *updater) stale(c child, depth int, path []byte) {
+	nodeKey := u.tree.nodeKey(c.version, depth, maskPath(path, depth))
+	u.cs.KV = append(u.cs.KV, &types.KeyValue{Key: u.tree.staleKey(u.version, nodeKey), Value: []byte{1}})
+}
+
+func refSubtree(c child, depth int, path []byte) *subtree {
+	if c.kind == kindEmpty {
+		return emptySubtree
+	}
+	ref := c
+	return &subtree{kind: c.kind, ref: &ref, refDepth: depth, refPath: path}
+}
+
+func (u *updater) update(c child, depth int, path []byte, entries []*entry) (*subtree, error) {
+	if len(entries) == 0 {
+		return refSubtree(c, depth, path), nil
+	}
+	switch c.kind {
+	case kindEmpty:
+		return u.build(depth, path, entries), nil
+	case kindLeaf:
+		leaf, _, err := u.tree.loadNode(c, depth, path)
+		if err != nil {
+			return nil, err
+		}
+		merged := make([]*entry, 0, len(entries)+1)
+		overridden := false
+		changed := false
+		for _, e := range entries {
+			if bytes.Equal(e.keyHash, leaf.keyHash) {
+				overridden = true
+			}
+			if e.value != nil {
+				changed = true
+			}
+		}
+		//只删除不存在的key, 叶子不变
+		if !overridden && !changed {
+			return refSubtree(c, depth, path), nil
+		}
+		u.stale(c, depth, path)
+		if !overridden {
+			merged = append(merged, &entry{keyHash: leaf.keyHash, value: leaf.value})
+		}
+		merged = append(merged, entries...)
+		sort.Slice(merged, func(i, j int) bool {
+			return bytes.Compare(merged[i].keyHash, merged[j].keyHash) < 0
+		})
+		return u.build(depth, path, merged), nil
+	}
+	_, node, err := u.tree.loadNode(c, depth, path)
+	if err != nil {
+		return nil, err
+	}
+	split := sort.Search(len(entries), func(i int) bool {
+		return bit(entries[i].keyHash, depth) == 1
+	})
+	leftPath, rightPath := setBit(path, depth, 0), setBit(path, depth, 1)
+	left, err := u.update(node.children[0], depth+1, leftPath, entries[:split])
+	if err != nil {
+		return nil, err
+	}
+	right, err := u.update(node.children[1], depth+1, rightPath, entries[split:])
+	if err != nil {
+		return nil, err
+	}
+	if unchanged(left, node.children[0], depth+1) && unchanged(right, node.children[1], depth+1) {
+		return refSubtree(c, depth, path), nil
+	}
+	u.stale(c, depth, path)
+	return combine(left, right), nil
+}
+
+func unchanged(s *subtree, c child, depth int) bool {
+	if s.kind == kindEmpty {
+		return c.kind == kindEmpty
+	}
+	return s.ref != nil && s.refDepth == depth && bytes.Equal(s.ref.hash, c.hash) && s.ref.version == c.version
+}
+
+//build 由kv集合构造子树, entries 按 keyHash 有序
+func (u *updater) build(depth int, path []byte, entries []*entry) *subtree {
+	live := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		if e.value != nil {
+			live = append(live, e)
+		}
+	}
+	entries = live
+	if len(entries) == 0 {
+		return emptySubtree
+	}
+	if len(entries) == 1 {
+		return &subtree{kind: kindLeaf, leaf: entries[0]}
+	}
+	split := sort.Search(len(entries), func(i int) bool {
+		return bit(entries[i].keyHash, depth) == 1
+	})
+	left := u.build(depth+1, setBit(path, depth, 0), entries[:split])
+	right := u.build(depth+1, setBit(path, depth, 1), entries[split:])
+	return combine(left, right)
+}
+
+//combine 合并左右子树, 只剩一个叶子时叶子上移
+func combine(left, right *subtree) *subtree {
+	if left.kind == kindEmpty && right.kind == kindEmpty {
+		return emptySubtree
+	}
+	if left.kind == kindEmpty && right.kind == kindLeaf {
+		return right
+	}
+	if right.kind == kindEmpty && left.kind == kindLeaf {
+		return left
+	}
+	return &subtree{kind: kindInternal, left: left, right: right}
+}
+
+//materialize 把子树写入修改集合, 返回子树根的引用
+func (u *updater) materialize(s *subtree, depth int, path []byte) (child, error) {
+	switch {
+	case s.kind == kindEmpty:
+		return child{kind: kindEmpty}, nil
+	case s.ref != nil && (s.kind == kindInternal || s.refDepth == depth):
+		return *s.ref, nil
+	case s.ref != nil:
+		//已有的叶子移动了位置
+		leaf, _, err := u.tree.loadNode(*s.ref, s.refDepth, s.refPath)
+		if err != nil {
+			return child{}, err
+		}
+		u.stale(*s.ref, s.refDepth, s.refPath)
+		return u.writeLeaf(&entry{keyHash: leaf.keyHash, value: leaf.value}, depth), nil
+	case s.leaf != nil:
+		return u.writeLeaf(s.leaf, depth), nil
+	}
+	left, err := u.materialize(s.left, depth+1, setBit(path, depth, 0))
+	if err != nil {
+		return child{}, err
+	}
+	right, err := u.materialize(s.right, depth+1, setBit(path, depth, 1))
+	if err != nil {
+		return child{}, err
+	}
+	node := &internalNode{children: [2]child{left, right}}
+	c := child{kind: kindInternal, hash: node.hash(), version: u.version}
+	u.cs.KV = append(u.cs.KV, &types.KeyValue{Key: u.tree.nodeKey(u.version, depth, maskPath(path, depth)), Value: node.encode()})
+	return c, nil
+}
+
+func (u *updater) writeLeaf(e *entry, depth int) child {
+	node := &leafNode{keyHash: e.keyHash, value: e.value}
+	u.cs.KV = append(u.cs.KV, &types.KeyValue{Key: u.tree.nodeKey(u.version, depth, maskPath(e.keyHash, depth)), Value: node.encode()})
+	return child{kind: kindLeaf, hash: node.hash(), version: u.version}
+}
+
+//DeleteVersion 删除最新的版本, 恢复到前一个版本的状态, 用于回滚
+func (t *Tree) DeleteVersion(version int64, sync bool) error {
+	latest, err := t.LatestVersion()
+	if err != nil {
+		return err
+	}
+	if latest != version {
+		return ErrNotLatestVersion
+	}
+	batch := t.db.NewBatch(sync)
+	for _, prefix := range [][]byte{t.nodePrefix, t.stalePref} {
+		start := append(append([]byte{}, prefix...), encodeVersion(version)...)
+		it := t.db.Iterator(start, nil, false)
+		for it.Rewind(); it.Valid(); it.Next() {
+			batch.Delete(append([]byte{}, it.Key()...))
+		}
+		err = it.Error()
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+	batch.Delete(t.rootKey(version))
+	return batch.Write()
+}
+
+//Prune 删除 version 之前的全部版本, 只保留 version 以及之后的版本可以访问, 返回删除的节点数目
+func (t *Tree) Prune(version int64, sync bool) (int, error) {
+	batch := t.db.NewBatch(sync)
+	count := 0
+	it := t.db.Iterator(t.stalePref, append(append([]byte{}, t.stalePref...), encodeVersion(version+1)...), false)
+	for it.Rewind(); it.Valid(); it.Next() {
+		key := it.Key()
+		batch.Delete(append([]byte{}, key[len(t.stalePref)+8:]...))
+		batch.Delete(append([]byte{}, key...))
+		count++
+	}
+	err := it.Error()
+	it.Close()
+	if err != nil {
+		return 0, err
+	}
+	rit := t.db.Iterator(t.rootPrefix, t.rootKey(version), false)
+	for rit.Rewind(); rit.Valid(); rit.Next() {
+		batch.Delete(append([]byte{}, rit.Key()...))
+	}
+	err = rit.Error()
+	rit.Close()
+	if err != nil {
+		return 0, err
+	}
+	err = batch.Write()
+	if err != nil {
+		return 0, err
+	}
+	slog.Debug("smt prune", "version", version, "nodes", count)
+	return count, nil
+}
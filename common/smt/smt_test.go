@@ -0,0 +1,247 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) (dbm.DB, func()) {
+	dir, err := ioutil.TempDir("", "smt")
+	require.Nil(t, err)
+	db := dbm.NewDB("smt", "leveldb", dir, 100)
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func genKVs(r *rand.Rand, n, keyRange int) []*types.KeyValue {
+	kvs := make([]*types.KeyValue, n)
+	for i := range kvs {
+		kvs[i] = &types.KeyValue{Key: []byte(fmt.Sprintf("key-%d", r.Intn(keyRange)))}
+		if r.Intn(4) != 0 {
+			kvs[i].Value = []byte(fmt.Sprintf("value-%d", r.Int()))
+		}
+	}
+	return kvs
+}
+
+func commit(t *testing.T, tree *Tree, base, version int64, kvs []*types.KeyValue) []byte {
+	cs, err := tree.Update(base, version, kvs)
+	require.Nil(t, err)
+	require.Nil(t, tree.Commit(cs, true))
+	return cs.Root
+}
+
+//countNodes 统计从根可以到达的节点数目
+func countNodes(t *testing.T, tree *Tree, c child, depth int, path []byte) int {
+	switch c.kind {
+	case kindEmpty:
+		return 0
+	case kindLeaf:
+		leaf, _, err := tree.loadNode(c, depth, path)
+		require.Nil(t, err)
+		require.Equal(t, c.hash, leaf.hash())
+		return 1
+	}
+	_, node, err := tree.loadNode(c, depth, path)
+	require.Nil(t, err)
+	require.Equal(t, c.hash, node.hash())
+	return 1 + countNodes(t, tree, node.children[0], depth+1, setBit(path, depth, 0)) +
+		countNodes(t, tree, node.children[1], depth+1, setBit(path, depth, 1))
+}
+
+func TestUpdateGet(t *testing.T) {
+	db, closer := newTestDB(t)
+	defer closer()
+	tree := NewTree(db, []byte("smt-"))
+	root, err := tree.Root(-1)
+	assert.Nil(t, err)
+	assert.Equal(t, EmptyRoot, root)
+
+	r := rand.New(rand.NewSource(1))
+	expect := make(map[string][]byte)
+	var history []map[string][]byte
+	for v := int64(0); v < 20; v++ {
+		kvs := genKVs(r, 50, 200)
+		commit(t, tree, v-1, v, kvs)
+		for _, kv := range kvs {
+			if kv.Value == nil {
+				delete(expect, string(kv.Key))
+			} else {
+				expect[string(kv.Key)] = kv.Value
+			}
+		}
+		snapshot := make(map[string][]byte)
+		for k, val := range expect {
+			snapshot[k] = val
+		}
+		history = append(history, snapshot)
+	}
+	latest, err := tree.LatestVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(19), latest)
+	for v, snapshot := range history {
+		for i := 0; i < 200; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			value, err := tree.Get([]byte(key), int64(v))
+			assert.Nil(t, err)
+			assert.Equal(t, snapshot[key], value)
+		}
+	}
+
+	//根hash只和最终的kv集合有关
+	db2, closer2 := newTestDB(t)
+	defer closer2()
+	tree2 := NewTree(db2, nil)
+	var kvs []*types.KeyValue
+	for k, v := range expect {
+		kvs = append(kvs, &types.KeyValue{Key: []byte(k), Value: v})
+	}
+	root2 := commit(t, tree2, -1, 0, kvs)
+	root, err = tree.Root(19)
+	assert.Nil(t, err)
+	assert.Equal(t, root, root2)
+
+	//全部删除之后回到空树
+	for i := range kvs {
+		kvs[i].Value = nil
+	}
+	assert.Equal(t, EmptyRoot, commit(t, tree2, 0, 1, kvs))
+	_, err = tree2.Update(0, 1, kvs)
+	assert.Equal(t, ErrVersionExists, err)
+}
+
+func TestProof(t *testing.T) {
+	db, closer := newTestDB(t)
+	defer closer()
+	tree := NewTree(db, nil)
+
+	proof, err := tree.Prove([]byte("a"), -1)
+	assert.Nil(t, err)
+	assert.True(t, VerifyProof(EmptyRoot, []byte("a"), nil, proof))
+
+	var kvs []*types.KeyValue
+	for i := 0; i < 100; i++ {
+		kvs = append(kvs, &types.KeyValue{Key: []byte(fmt.Sprintf("key-%d", i)), Value: []byte(fmt.Sprintf("value-%d", i))})
+	}
+	root := commit(t, tree, -1, 0, kvs)
+	for i := 0; i < 200; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		proof, err := tree.Prove(key, 0)
+		assert.Nil(t, err)
+		proof, err = DecodeProof(proof.Encode())
+		assert.Nil(t, err)
+		if i < 100 {
+			value := []byte(fmt.Sprintf("value-%d", i))
+			assert.True(t, VerifyProof(root, key, value, proof))
+			assert.False(t, VerifyProof(root, key, []byte("other"), proof))
+			assert.False(t, VerifyProof(root, key, nil, proof))
+		} else {
+			assert.True(t, VerifyProof(root, key, nil, proof))
+			assert.False(t, VerifyProof(root, key, []byte("value"), proof))
+		}
+		if len(proof.Siblings) > 0 {
+			proof.Siblings[0] = EmptyRoot
+			assert.False(t, VerifyProof(root, key, nil, proof))
+		}
+	}
+	_, err = DecodeProof([]byte{0, 1})
+	assert.Equal(t, types.ErrDecode, err)
+}
+
+func TestPruneAndDeleteVersion(t *testing.T) {
+	db, closer := newTestDB(t)
+	defer closer()
+	tree := NewTree(db, []byte("smt-"))
+	r := rand.New(rand.NewSource(2))
+	var roots [][]byte
+	for v := int64(0); v < 30; v++ {
+		roots = append(roots, commit(t, tree, v-1, v, genKVs(r, 30, 100)))
+	}
+
+	//删除最新的版本后可以重新生成
+	assert.Equal(t, ErrNotLatestVersion, tree.DeleteVersion(28, true))
+	assert.Nil(t, tree.DeleteVersion(29, true))
+	_, err := tree.Root(29)
+	assert.Equal(t, ErrVersionNotFound, err)
+	kvs := genKVs(r, 30, 100)
+	root29 := commit(t, tree, 28, 29, kvs)
+
+	n, err := tree.Prune(20, true)
+	assert.Nil(t, err)
+	assert.True(t, n > 0)
+	_, err = tree.Root(19)
+	assert.Equal(t, ErrVersionNotFound, err)
+	_, err = tree.Get([]byte("key-1"), 10)
+	assert.Equal(t, ErrVersionNotFound, err)
+
+	//剩余的节点正好是保留版本可以到达的节点
+	reachable := make(map[string]bool)
+	for v := int64(20); v < 30; v++ {
+		c, err := tree.rootRef(v)
+		require.Nil(t, err)
+		collect(t, tree, c, 0, zeroHash, reachable)
+	}
+	stored := 0
+	it := db.Iterator(tree.nodePrefix, nil, false)
+	for it.Rewind(); it.Valid(); it.Next() {
+		assert.True(t, reachable[string(it.Key())])
+		stored++
+	}
+	it.Close()
+	assert.Equal(t, len(reachable), stored)
+	for v := int64(20); v < 29; v++ {
+		root, err := tree.Root(v)
+		assert.Nil(t, err)
+		assert.Equal(t, roots[v], root)
+	}
+	root, err := tree.Root(29)
+	assert.Nil(t, err)
+	assert.Equal(t, root29, root)
+	c, err := tree.rootRef(29)
+	assert.Nil(t, err)
+	assert.True(t, countNodes(t, tree, c, 0, zeroHash) > 0)
+}
+
+func collect(t *testing.T, tree *Tree, c child, depth int, path []byte, keys map[string]bool) {
+	if c.kind == kindEmpty {
+		return
+	}
+	keys[string(tree.nodeKey(c.version, depth, maskPath(path, depth)))] = true
+	if c.kind == kindLeaf {
+		return
+	}
+	_, node, err := tree.loadNode(c, depth, path)
+	require.Nil(t, err)
+	collect(t, tree, node.children[0], depth+1, setBit(path, depth, 0), keys)
+	collect(t, tree, node.children[1], depth+1, setBit(path, depth, 1), keys)
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	dir, err := ioutil.TempDir("", "smt")
+	require.Nil(b, err)
+	defer os.RemoveAll(dir)
+	db := dbm.NewDB("smt", "leveldb", dir, 100)
+	defer db.Close()
+	tree := NewTree(db, nil)
+	r := rand.New(rand.NewSource(3))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cs, err := tree.Update(int64(i)-1, int64(i), genKVs(r, 1000, 1000000))
+		require.Nil(b, err)
+		require.Nil(b, tree.Commit(cs, false))
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/33cn/chain33/types"
+)
+
+const (
+	defaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+	defaultTraceFile    = "logs/trace.json"
+	maxQueueSize        = 2048
+	maxExportBatchSize  = 512
+	exportInterval      = 2 * time.Second
+	otlpTimeout         = 10 * time.Second
+)
+
+// Exporter 导出结束的span
+type Exporter interface {
+	ExportSpans(spans []*SpanData) error
+	Shutdown() error
+}
+
+// NewExporter 根据配置新建exporter, 支持 otlp 和 file
+func NewExporter(cfg *types.Trace) (Exporter, error) {
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	switch cfg.Exporter {
+	case "", "otlp":
+		return NewOTLPExporter(cfg.Endpoint, serviceName), nil
+	case "file":
+		return NewFileExporter(cfg.FilePath)
+	default:
+		return nil, fmt.Errorf("trace: unknown exporter %s", cfg.Exporter)
+	}
+}
+
+//batchProcessor 在后台批量导出span, 队列满时丢弃, 不阻塞区块处理
+type batchProcessor struct {
+	exporter Exporter
+	queue    chan *SpanData
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func newBatchProcessor(exporter Exporter) *batchProcessor {
+	p := &batchProcessor{
+		exporter: exporter,
+		queue:    make(chan *SpanData, maxQueueSize),
+		done:     make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+func (p *batchProcessor) add(span *SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		tlog.Debug("trace queue full, drop span", "name", span.Name)
+	}
+}
+
+func (p *batchProcessor) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	batch := make([]*SpanData, 0, maxExportBatchSize)
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= maxExportBatchSize {
+				batch = p.export(batch)
+			}
+		case <-ticker.C:
+			batch = p.export(batch)
+		case <-p.done:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+				default:
+					p.export(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *batchProcessor) export(batch []*SpanData) []*SpanData {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := p.exporter.ExportSpans(batch); err != nil {
+		tlog.Error("export spans", "count", len(batch), "err", err)
+	}
+	return batch[:0]
+}
+
+func (p *batchProcessor) shutdown() {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		if err := p.exporter.Shutdown(); err != nil {
+			tlog.Error("shutdown exporter", "err", err)
+		}
+	})
+}
+
+//OTLP/HTTP json 编码, 参考 opentelemetry-proto trace/v1/trace.proto
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusError      = 2
+)
+
+func otlpValue(v interface{}) map[string]interface{} {
+	switch x := v.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": x}
+	case bool:
+		return map[string]interface{}{"boolValue": x}
+	case int:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(x), 10)}
+	case int32:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(x), 10)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(x, 10)}
+	case uint64:
+		return map[string]interface{}{"intValue": strconv.FormatUint(x, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": x}
+	case time.Duration:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(x), 10)}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprint(x)}
+	}
+}
+
+// OTLPExporter 以OTLP/HTTP json格式把span发送到collector
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter endpoint 为collector的完整地址, 默认 http://localhost:4318/v1/traces
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	if endpoint == "" {
+		endpoint = defaultOTLPEndpoint
+	}
+	return &OTLPExporter{endpoint: endpoint, serviceName: serviceName, client: &http.Client{Timeout: otlpTimeout}}
+}
+
+func (e *OTLPExporter) encode(spans []*SpanData) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.Parent != (SpanID{}) {
+			span.ParentSpanID = s.Parent.String()
+		}
+		for _, attr := range s.Attributes {
+			span.Attributes = append(span.Attributes, otlpKeyValue{Key: attr.Key, Value: otlpValue(attr.Value)})
+		}
+		if s.Err != "" {
+			span.Status = &otlpStatus{Code: otlpStatusError, Message: s.Err}
+		}
+		out = append(out, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpValue(e.serviceName)}}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: defaultServiceName}, Spans: out}},
+	}}}
+}
+
+// ExportSpans 发送一批span
+func (e *OTLPExporter) ExportSpans(spans []*SpanData) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("trace: otlp export status %s", resp.Status)
+	}
+	return nil
+}
+
+// Shutdown 关闭
+func (e *OTLPExporter) Shutdown() error {
+	return nil
+}
+
+//fileSpan 文件中每行一个span
+type fileSpan struct {
+	TraceID    string                 `json:"traceId"`
+	SpanID     string                 `json:"spanId"`
+	ParentID   string                 `json:"parentSpanId,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	DurationMs float64                `json:"durationMs"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Err        string                 `json:"error,omitempty"`
+}
+
+// FileExporter 把span以json lines格式写入文件, 用于离线分析
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+}
+
+// NewFileExporter 以追加方式打开文件, path 为空时使用 logs/trace.json
+func NewFileExporter(path string) (*FileExporter, error) {
+	if path == "" {
+		path = defaultTraceFile
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// ExportSpans 写入一批span
+func (e *FileExporter) ExportSpans(spans []*SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		fs := fileSpan{
+			TraceID:    s.Context.TraceID.String(),
+			SpanID:     s.Context.SpanID.String(),
+			Name:       s.Name,
+			Start:      s.Start,
+			End:        s.End,
+			DurationMs: float64(s.End.Sub(s.Start)) / float64(time.Millisecond),
+			Err:        s.Err,
+		}
+		if s.Parent != (SpanID{}) {
+			fs.ParentID = s.Parent.String()
+		}
+		if len(s.Attributes) > 0 {
+			fs.Attributes = make(map[string]interface{}, len(s.Attributes))
+			for _, attr := range s.Attributes {
+				fs.Attributes[attr.Key] = attr.Value
+			}
+		}
+		if err := enc.Encode(&fs); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// Shutdown 关闭文件
+func (e *FileExporter) Shutdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+	return e.file.Close()
+}
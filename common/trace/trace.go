@@ -0,0 +1,269 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trace 区块处理路径上的span追踪, 数据模型和OpenTelemetry一致,
+// span 上下文通过 queue.Message 在模块之间传递. 追踪未开启时 StartSpan 返回nil,
+// nil span 上的所有操作都是空操作, 不产生额外的内存分配.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/types"
+)
+
+var tlog = log.New("module", "trace")
+
+const (
+	defaultServiceName = "chain33"
+	traceParentVersion = "00"
+)
+
+// ErrInvalidTraceParent traceparent 格式错误
+var ErrInvalidTraceParent = errors.New("ErrInvalidTraceParent")
+
+// TraceID 16字节的trace id
+type TraceID [16]byte
+
+// SpanID 8字节的span id
+type SpanID [8]byte
+
+// String hex编码
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String hex编码
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext 在模块之间传递的span上下文
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid trace id 和 span id 都不为空
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent 按W3C traceparent格式编码, 用于跨进程传递
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return traceParentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent 解析W3C traceparent格式的span上下文
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || parts[0] != traceParentVersion || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// Attribute span的属性
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData 结束的span, 交给Exporter导出
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Err        string
+}
+
+// Span 一次操作的耗时记录, 为nil时所有方法都是空操作
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Context 返回span上下文, nil span 返回空的上下文
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttribute 设置属性
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: value})
+	s.mu.Unlock()
+}
+
+// SetError 记录错误, err为nil时忽略
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Err = err.Error()
+	s.mu.Unlock()
+}
+
+// End 结束span并提交导出, 多次调用只生效一次
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if t := getTracer(); t != nil {
+		t.processor.add(&data)
+	}
+}
+
+//tracer 全局追踪器, 为nil时追踪关闭
+type tracer struct {
+	sampleRate float64
+	processor  *batchProcessor
+}
+
+var globalTracer atomic.Value
+
+func getTracer() *tracer {
+	t, _ := globalTracer.Load().(*tracer)
+	return t
+}
+
+// Enabled 是否开启了追踪
+func Enabled() bool {
+	return getTracer() != nil
+}
+
+// Init 根据配置开启追踪, cfg 为nil或者没有开启时关闭追踪
+func Init(cfg *types.Trace) error {
+	if cfg == nil || !cfg.Enable {
+		Close()
+		return nil
+	}
+	exporter, err := NewExporter(cfg)
+	if err != nil {
+		return err
+	}
+	SetExporter(exporter, cfg.SampleRate)
+	tlog.Info("trace enabled", "exporter", cfg.Exporter, "sampleRate", cfg.SampleRate)
+	return nil
+}
+
+// SetExporter 使用exporter开启追踪, sampleRate 为新建trace的采样比例, 取值 (0, 1]
+func SetExporter(exporter Exporter, sampleRate float64) {
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	old := getTracer()
+	globalTracer.Store(&tracer{sampleRate: sampleRate, processor: newBatchProcessor(exporter)})
+	if old != nil {
+		old.processor.shutdown()
+	}
+}
+
+// Close 关闭追踪, 导出所有未导出的span
+func Close() {
+	old := getTracer()
+	if old == nil {
+		return
+	}
+	globalTracer.Store((*tracer)(nil))
+	old.processor.shutdown()
+}
+
+// StartSpan 新建span, parent 无效时新建trace并按比例采样, 否则继承parent的采样结果.
+// 追踪关闭或者没有被采样时返回nil
+func StartSpan(parent SpanContext, name string) *Span {
+	t := getTracer()
+	if t == nil {
+		return nil
+	}
+	if parent.IsValid() && !parent.Sampled {
+		return nil
+	}
+	s := &Span{}
+	s.data.Name = name
+	s.data.Start = time.Now()
+	if parent.IsValid() {
+		s.data.Context.TraceID = parent.TraceID
+		s.data.Parent = parent.SpanID
+	} else {
+		s.data.Context.TraceID = newTraceID()
+		if !t.sample(s.data.Context.TraceID) {
+			return nil
+		}
+	}
+	s.data.Context.SpanID = newSpanID()
+	s.data.Context.Sampled = true
+	return s
+}
+
+//sample 根据trace id 的低8字节采样, 同一个trace在不同进程中采样结果一致
+func (t *tracer) sample(id TraceID) bool {
+	if t.sampleRate >= 1 {
+		return true
+	}
+	x := binary.BigEndian.Uint64(id[8:]) >> 11
+	return float64(x) < t.sampleRate*(1<<53)
+}
+
+func newTraceID() (id TraceID) {
+	for id == (TraceID{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for id == (SpanID{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,238 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memExporter struct {
+	mu       sync.Mutex
+	spans    []*SpanData
+	shutdown bool
+}
+
+func (e *memExporter) ExportSpans(spans []*SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memExporter) Shutdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func (e *memExporter) find(name string) *SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestDisabled(t *testing.T) {
+	Close()
+	assert.False(t, Enabled())
+	span := StartSpan(SpanContext{}, "test")
+	assert.Nil(t, span)
+	//nil span 上的操作都是空操作
+	span.SetAttribute("height", 1)
+	span.SetError(errors.New("err"))
+	span.End()
+	assert.False(t, span.Context().IsValid())
+
+	allocs := testing.AllocsPerRun(100, func() {
+		s := StartSpan(SpanContext{}, "test")
+		s.SetAttribute("height", int64(1))
+		s.End()
+	})
+	assert.Equal(t, float64(0), allocs)
+	assert.Nil(t, Init(nil))
+	assert.Nil(t, Init(&types.Trace{}))
+	assert.False(t, Enabled())
+}
+
+func TestSpan(t *testing.T) {
+	exporter := &memExporter{}
+	SetExporter(exporter, 1)
+	assert.True(t, Enabled())
+
+	root := StartSpan(SpanContext{}, "root")
+	require.NotNil(t, root)
+	root.SetAttribute("height", int64(10))
+	child := StartSpan(root.Context(), "child")
+	child.SetError(errors.New("exec err"))
+	child.End()
+	child.End()
+	root.End()
+
+	//没有被采样的parent, 子span也不采样
+	notSampled := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}}
+	assert.Nil(t, StartSpan(notSampled, "ignore"))
+	Close()
+	assert.False(t, Enabled())
+	assert.True(t, exporter.shutdown)
+
+	assert.Equal(t, 2, len(exporter.spans))
+	r, c := exporter.find("root"), exporter.find("child")
+	require.NotNil(t, r)
+	require.NotNil(t, c)
+	assert.Equal(t, r.Context.TraceID, c.Context.TraceID)
+	assert.Equal(t, r.Context.SpanID, c.Parent)
+	assert.Equal(t, SpanID{}, r.Parent)
+	assert.Equal(t, "exec err", c.Err)
+	assert.Equal(t, []Attribute{{Key: "height", Value: int64(10)}}, r.Attributes)
+	assert.False(t, r.End.Before(r.Start))
+}
+
+func TestSample(t *testing.T) {
+	tr := &tracer{sampleRate: 0.5}
+	var id TraceID
+	assert.True(t, tr.sample(id))
+	for i := 8; i < 16; i++ {
+		id[i] = 0xff
+	}
+	assert.False(t, tr.sample(id))
+	id[8] = 0x7f
+	assert.True(t, tr.sample(id))
+	assert.True(t, (&tracer{sampleRate: 1}).sample(id))
+
+	exporter := &memExporter{}
+	SetExporter(exporter, 0.000001)
+	defer Close()
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if StartSpan(SpanContext{}, "test") != nil {
+			sampled++
+		}
+	}
+	assert.True(t, sampled < 10)
+}
+
+func TestTraceParent(t *testing.T) {
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	s := sc.TraceParent()
+	assert.Equal(t, 55, len(s))
+	parsed, err := ParseTraceParent(s)
+	assert.Nil(t, err)
+	assert.Equal(t, sc, parsed)
+
+	sc.Sampled = false
+	parsed, err = ParseTraceParent(sc.TraceParent())
+	assert.Nil(t, err)
+	assert.Equal(t, sc, parsed)
+
+	assert.Equal(t, "", SpanContext{}.TraceParent())
+	for _, bad := range []string{"", "00-xx-yy-01", "01-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-01",
+		"00-00000000000000000000000000000000-" + sc.SpanID.String() + "-01"} {
+		_, err = ParseTraceParent(bad)
+		assert.Equal(t, ErrInvalidTraceParent, err, bad)
+	}
+}
+
+func testSpans() []*SpanData {
+	root := StartSpan(SpanContext{}, "root")
+	root.SetAttribute("height", int64(10))
+	root.SetAttribute("pid", "self")
+	child := StartSpan(root.Context(), "child")
+	child.SetError(errors.New("exec err"))
+	child.End()
+	root.End()
+	return []*SpanData{&root.data, &child.data}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	exporter := &memExporter{}
+	SetExporter(exporter, 1)
+	spans := testSpans()
+	Close()
+
+	var req otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(body, &req))
+	}))
+	defer server.Close()
+
+	e, err := NewExporter(&types.Trace{Exporter: "otlp", Endpoint: server.URL + "/v1/traces"})
+	require.Nil(t, err)
+	require.Nil(t, e.ExportSpans(spans))
+	require.Nil(t, e.Shutdown())
+	require.Equal(t, 1, len(req.ResourceSpans))
+	rs := req.ResourceSpans[0]
+	assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, "chain33", rs.Resource.Attributes[0].Value["stringValue"])
+	out := rs.ScopeSpans[0].Spans
+	require.Equal(t, 2, len(out))
+	assert.Equal(t, "root", out[0].Name)
+	assert.Equal(t, spans[0].Context.TraceID.String(), out[0].TraceID)
+	assert.Equal(t, "", out[0].ParentSpanID)
+	assert.Equal(t, "10", out[0].Attributes[0].Value["intValue"])
+	assert.Equal(t, "self", out[0].Attributes[1].Value["stringValue"])
+	assert.Equal(t, out[0].SpanID, out[1].ParentSpanID)
+	assert.Equal(t, otlpStatusError, out[1].Status.Code)
+
+	failed := NewOTLPExporter(server.URL+"/notfound", "chain33")
+	assert.NotNil(t, failed.ExportSpans(spans))
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs", "trace.json")
+
+	e, err := NewExporter(&types.Trace{Exporter: "file", FilePath: path})
+	require.Nil(t, err)
+	SetExporter(e, 1)
+	testSpans()
+	Close()
+
+	f, err := os.Open(path)
+	require.Nil(t, err)
+	defer f.Close()
+	var lines []fileSpan
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var fs fileSpan
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &fs))
+		lines = append(lines, fs)
+	}
+	require.Equal(t, 2, len(lines))
+	//子span先结束, 先写入
+	assert.Equal(t, "child", lines[0].Name)
+	assert.Equal(t, lines[1].SpanID, lines[0].ParentID)
+	assert.Equal(t, "exec err", lines[0].Err)
+	assert.Equal(t, float64(10), lines[1].Attributes["height"])
+
+	_, err = NewExporter(&types.Trace{Exporter: "jaeger"})
+	assert.NotNil(t, err)
+}
@@ -64,6 +64,7 @@ func New(cfg *typ.Chain33Config) *Executor {
 	exec.pluginEnable = make(map[string]bool)
 	exec.pluginEnable["stat"] = mcfg.EnableStat
 	exec.pluginEnable["mvcc"] = mcfg.EnableMVCC
+	exec.pluginEnable["localmvcc"] = mcfg.EnableLocalMVCC
 	exec.pluginEnable["addrindex"] = !mcfg.DisableAddrIndex
 	exec.pluginEnable["txindex"] = true
 	exec.pluginEnable["fee"] = true
@@ -204,7 +205,11 @@ func (exec *Executor) procExecQuery(msg *queue.Message) {
 	}
 	var localdb dbm.KVDB
 	if !exec.disableLocal {
-		localdb = NewLocalDB(exec.client)
+		localdb, err = exec.queryLocalDB(data)
+		if err != nil {
+			msg.Reply(exec.client.NewMessage("", types.EventBlockChainQuery, err))
+			return
+		}
 		defer localdb.(*LocalDB).Close()
 		driver.SetLocalDB(localdb)
 	}
@@ -226,6 +231,21 @@ func (exec *Executor) procExecQuery(msg *queue.Message) {
 	msg.Reply(exec.client.NewMessage("", types.EventBlockChainQuery, ret))
 }
 
+//queryLocalDB 开启localmvcc并且Extra中指定了历史高度时, localdb读取该高度的数据
+func (exec *Executor) queryLocalDB(data *types.ChainExecutor) (dbm.KVDB, error) {
+	if exec.pluginEnable["localmvcc"] && len(data.Extra) > 0 {
+		var height types.LocalDBHeight
+		err := types.Decode(data.Extra, &height)
+		if err != nil {
+			return nil, err
+		}
+		if height.Enable {
+			return NewLocalDBAt(exec.client, height.Height)
+		}
+	}
+	return NewLocalDB(exec.client), nil
+}
+
 func (exec *Executor) procExecCheckTx(msg *queue.Message) {
 	//panic 处理
 	defer func() {
@@ -236,6 +256,10 @@ func (exec *Executor) procExecCheckTx(msg *queue.Message) {
 		}
 	}()
 	datas := msg.GetData().(*types.ExecTxList)
+	span := msg.StartSpan("executor.procExecCheckTx")
+	defer span.End()
+	span.SetAttribute("height", datas.Height)
+	span.SetAttribute("txs", len(datas.Txs))
 	ctx := &executorCtx{
 		stateHash:  datas.StateHash,
 		height:     datas.Height,
@@ -280,6 +304,10 @@ func (exec *Executor) procExecTxList(msg *queue.Message) {
 		}
 	}()
 	datas := msg.GetData().(*types.ExecTxList)
+	span := msg.StartSpan("executor.procExecTxList")
+	defer span.End()
+	span.SetAttribute("height", datas.Height)
+	span.SetAttribute("txs", len(datas.Txs))
 	ctx := &executorCtx{
 		stateHash:  datas.StateHash,
 		height:     datas.Height,
@@ -365,6 +393,10 @@ func (exec *Executor) procExecAddBlock(msg *queue.Message) {
 	}()
 	datas := msg.GetData().(*types.BlockDetail)
 	b := datas.Block
+	span := msg.StartSpan("executor.procExecAddBlock")
+	defer span.End()
+	span.SetAttribute("height", b.Height)
+	span.SetAttribute("txs", len(b.Txs))
 	ctx := &executorCtx{
 		stateHash:  b.StateHash,
 		height:     b.Height,
@@ -389,6 +421,7 @@ func (exec *Executor) procExecAddBlock(msg *queue.Message) {
 			panic(err)
 		}
 	}
+	var txkvPlugins []txKVPlugin
 	for name, plugin := range globalPlugins {
 		kvs, ok, err := plugin.CheckEnable(execute, exec.pluginEnable[name])
 		if err != nil {
@@ -397,6 +430,9 @@ func (exec *Executor) procExecAddBlock(msg *queue.Message) {
 		if !ok {
 			continue
 		}
+		if p, ok := plugin.(txKVPlugin); ok {
+			txkvPlugins = append(txkvPlugins, p)
+		}
 		if len(kvs) > 0 {
 			kvset.KV = append(kvset.KV, kvs...)
 		}
@@ -415,6 +451,7 @@ func (exec *Executor) procExecAddBlock(msg *queue.Message) {
 			}
 		}
 	}
+	var txkvs []*types.KeyValue
 	for i := 0; i < len(b.Txs); i++ {
 		tx := b.Txs[i]
 		execute.localDB.(*LocalDB).StartTx()
@@ -425,7 +462,16 @@ func (exec *Executor) procExecAddBlock(msg *queue.Message) {
 		}
 		if kv != nil && kv.KV != nil {
 			kvset.KV = append(kvset.KV, kv.KV...)
+			txkvs = append(txkvs, kv.KV...)
+		}
+	}
+	for _, plugin := range txkvPlugins {
+		kvs, err := plugin.ExecLocalTxKV(execute, datas, txkvs)
+		if err != nil {
+			msg.Reply(exec.client.NewMessage("", types.EventAddBlock, err))
+			return
 		}
+		kvset.KV = append(kvset.KV, kvs...)
 	}
 	msg.Reply(exec.client.NewMessage("", types.EventAddBlock, &kvset))
 }
@@ -43,6 +43,33 @@ func NewLocalDB(cli queue.Client) db.KVDB {
 	}
 }
 
+//NewLocalDBAt 创建读取height高度时数据的LocalDB, 需要开启localmvcc插件
+func NewLocalDBAt(cli queue.Client, height int64) (db.KVDB, error) {
+	api, err := client.New(cli, nil)
+	if err != nil {
+		return nil, err
+	}
+	msg := cli.NewMessage("blockchain", types.EventLocalNew, &types.Int64{Data: height})
+	err = cli.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cli.Wait(msg)
+	if err != nil {
+		return nil, err
+	}
+	txid, ok := resp.GetData().(*types.Int64)
+	if !ok {
+		return nil, types.ErrTypeAsset
+	}
+	return &LocalDB{
+		cache:  make(map[string][]byte),
+		txid:   txid,
+		client: cli,
+		api:    api,
+	}, nil
+}
+
 //DisableRead 禁止读取LocalDB数据库
 func (l *LocalDB) DisableRead() {
 	l.disableread = true
@@ -0,0 +1,92 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package executor_test
+
+import (
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	"github.com/33cn/chain33/util/testnode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalMVCCQuery(t *testing.T) {
+	cfg := testnode.GetDefaultConfig()
+	cfg.GetModuleConfig().Exec.EnableLocalMVCC = true
+	mock33 := testnode.NewWithConfig(cfg, nil)
+	defer mock33.Close()
+	api := mock33.GetAPI()
+	addr, _ := util.Genaddress()
+	var heights []int64
+	for i := int64(1); i <= 2; i++ {
+		tx := util.CreateCoinsTx(cfg, mock33.GetGenesisKey(), addr, i*types.Coin)
+		hash := mock33.SendTx(tx)
+		mock33.WaitTx(hash)
+		detail, err := api.QueryTx(&types.ReqHash{Hash: hash})
+		require.NoError(t, err)
+		heights = append(heights, detail.Height)
+	}
+
+	//coins按照地址统计的收款金额
+	key := []byte("LODB-coins-Addr:" + addr)
+	reply, err := api.QueryChain(&types.ChainExecutor{
+		Driver:   "coins",
+		FuncName: "GetKeyHistory",
+		Param:    types.Encode(&types.ReqKeyHistory{Key: key, ToHeight: -1}),
+	})
+	require.NoError(t, err)
+	versions := reply.(*types.ReplyKeyHistory).Versions
+	require.Equal(t, 2, len(versions))
+	for i, amount := range []int64{1, 3} {
+		assert.Equal(t, heights[i], versions[i].Height)
+		var reciver types.Int64
+		assert.NoError(t, types.Decode(versions[i].Value, &reciver))
+		assert.Equal(t, amount*types.Coin, reciver.Data)
+	}
+
+	//按照count分页
+	reply, err = api.QueryChain(&types.ChainExecutor{
+		Driver:   "coins",
+		FuncName: "GetKeyHistory",
+		Param:    types.Encode(&types.ReqKeyHistory{Key: key, FromHeight: heights[0] + 1, ToHeight: -1, Count: 1}),
+	})
+	require.NoError(t, err)
+	versions = reply.(*types.ReplyKeyHistory).Versions
+	require.Equal(t, 1, len(versions))
+	assert.Equal(t, heights[1], versions[0].Height)
+	_, err = api.QueryChain(&types.ChainExecutor{
+		Driver:   "coins",
+		FuncName: "GetKeyHistory",
+		Param:    types.Encode(&types.ReqKeyHistory{Key: key, ToHeight: -1, Count: int32(types.MaxBlockCountPerTime) + 1}),
+	})
+	assert.Equal(t, types.ErrMaxCountPerTime, err)
+	//插件写入的数据没有历史版本
+	_, err = api.QueryChain(&types.ChainExecutor{
+		Driver:   "coins",
+		FuncName: "GetKeyHistory",
+		Param:    types.Encode(&types.ReqKeyHistory{Key: types.CalcTxAddrHashKey(addr, ""), ToHeight: -1}),
+	})
+	assert.Equal(t, types.ErrLocalPrefix, err)
+
+	//在历史高度上查询localdb
+	query := func(height int64) (types.Message, error) {
+		return api.QueryChain(&types.ChainExecutor{
+			Driver:   "coins",
+			FuncName: "GetAddrReciver",
+			Param:    types.Encode(&types.ReqAddr{Addr: addr}),
+			Extra:    types.Encode(&types.LocalDBHeight{Enable: true, Height: height}),
+		})
+	}
+	_, err = query(heights[0] - 1)
+	assert.Equal(t, types.ErrEmpty, err)
+	reply, err = query(heights[0])
+	assert.NoError(t, err)
+	assert.Equal(t, types.Coin, reply.(*types.Int64).Data)
+	reply, err = query(heights[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 3*types.Coin, reply.(*types.Int64).Data)
+}
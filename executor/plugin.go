@@ -20,6 +20,11 @@ type plugin interface {
 	ExecDelLocal(executor *executor, data *types.BlockDetail) ([]*types.KeyValue, error)
 }
 
+//txKVPlugin 需要处理区块中交易产生的localdb数据的插件, 在所有交易执行ExecLocal之后调用
+type txKVPlugin interface {
+	ExecLocalTxKV(executor *executor, data *types.BlockDetail, txkvs []*types.KeyValue) ([]*types.KeyValue, error)
+}
+
 var globalPlugins = make(map[string]plugin)
 
 // RegisterPlugin register plugin
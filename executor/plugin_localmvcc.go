@@ -0,0 +1,63 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package executor
+
+import (
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+func init() {
+	RegisterPlugin("localmvcc", &localMVCCPlugin{})
+}
+
+//localMVCCPlugin 按照区块高度保存执行器localdb的多版本数据, 回滚区块时删除该高度的版本
+//只保存交易ExecLocal产生的数据(LODB-前缀的key), 其他插件写入localdb的数据没有历史版本
+type localMVCCPlugin struct {
+	pluginBase
+}
+
+func (p *localMVCCPlugin) CheckEnable(executor *executor, enable bool) (kvs []*types.KeyValue, ok bool, err error) {
+	kvs, ok, err = p.checkFlag(executor, types.FlagKeyLocalMVCC, enable)
+	if err == types.ErrDBFlag {
+		panic("localmvcc config is enable, it must be synchronized from 0 height ")
+	}
+	return kvs, ok, err
+}
+
+func (p *localMVCCPlugin) ExecLocal(executor *executor, data *types.BlockDetail) ([]*types.KeyValue, error) {
+	return nil, nil
+}
+
+func (p *localMVCCPlugin) ExecLocalTxKV(executor *executor, data *types.BlockDetail, txkvs []*types.KeyValue) ([]*types.KeyValue, error) {
+	return AddLocalMVCC(executor.localDB, executor.api.GetConfig(), data, txkvs), nil
+}
+
+func (p *localMVCCPlugin) ExecDelLocal(executor *executor, data *types.BlockDetail) ([]*types.KeyValue, error) {
+	return DelLocalMVCC(executor.localDB, executor.api.GetConfig(), data), nil
+}
+
+// AddLocalMVCC convert local key value of txs to mvcc kv data with block height as version
+func AddLocalMVCC(db dbm.KVDB, cfg *types.Chain33Config, detail *types.BlockDetail, kvs []*types.KeyValue) (kvlist []*types.KeyValue) {
+	b := detail.Block
+	mvcc := dbm.NewLocalMVCC(db)
+	//版本号和区块hash对应, 检查区块高度是否是连续的
+	kvlist, err := mvcc.AddLocalMVCC(kvs, b.Hash(cfg), b.ParentHash, b.Height)
+	if err != nil {
+		panic(err)
+	}
+	return kvlist
+}
+
+// DelLocalMVCC del local mvcc kv data of the block height
+func DelLocalMVCC(db dbm.KVDB, cfg *types.Chain33Config, detail *types.BlockDetail) (kvlist []*types.KeyValue) {
+	b := detail.Block
+	mvcc := dbm.NewLocalMVCC(db)
+	kvlist, err := mvcc.DelLocalMVCC(b.Hash(cfg), b.Height)
+	if err != nil {
+		panic(err)
+	}
+	return kvlist
+}
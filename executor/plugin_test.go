@@ -39,6 +39,11 @@ func TestPlugin(t *testing.T) {
 		assert.NoError(t, err)
 		kvs, err := plugin.ExecLocal(executor, detail)
 		assert.NoError(t, err)
+		if p, ok := plugin.(txKVPlugin); ok {
+			txkvs, err := p.ExecLocalTxKV(executor, detail, nil)
+			assert.NoError(t, err)
+			kvs = append(kvs, txkvs...)
+		}
 		for _, kv := range kvs {
 			err = kvdb.Set(kv.Key, kv.Value)
 			assert.NoError(t, err)
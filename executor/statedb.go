@@ -137,6 +137,10 @@ func (s *StateDB) get(key []byte) ([]byte, error) {
 		return nil, err
 	}
 	resp, err := s.client.Wait(msg)
+	if err == types.ErrStatePruned {
+		//查询的历史状态已经被裁剪
+		return nil, err
+	}
 	if err != nil {
 		panic(err) //no happen for ever
 	}
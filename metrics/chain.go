@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+
+	go_metrics "github.com/rcrowley/go-metrics"
+)
+
+//链相关的指标, 由各个模块在状态变化时更新, 导出时只读取指标的值, 不访问模块内部的锁.
+//指标名称保持稳定, prometheus 导出时加上 chain33_ 前缀, 并把 / 替换为 _ :
+//  chain33_blockchain_height                  gauge   主链最新高度
+//  chain33_blockchain_peer_count              gauge   blockchain 同步使用的peer个数
+//  chain33_blockchain_fault_peer_count        gauge   执行区块出错的故障peer个数
+//  chain33_blockchain_block_process_seconds   summary 区块从接收到写入主链的耗时
+//  chain33_blockchain_pushseq_lag{callback}   gauge   每个推送回调落后最新sequence的个数
+//  chain33_mempool_size                       gauge   mempool中的交易个数
+//  chain33_mempool_bytes                      gauge   mempool中交易占用的字节数
+//  chain33_store_commit_seconds               summary store commit的耗时
+//  chain33_blockchain_clock_drift_ms          gauge   最近一次ntp检测到的本地时钟偏差(毫秒)
+//  chain33_db_write_errors                    counter 写数据库失败的次数
+//  chain33_p2p_compactblock_recv              counter 收到的紧凑区块个数
+//  chain33_p2p_compactblock_reconstructed     counter 只使用本地mempool直接还原的紧凑区块个数
+//  chain33_p2p_compactblock_txreq             counter 需要批量请求缺失交易的紧凑区块个数
+//  chain33_p2p_compactblock_fullreq           counter 还原失败回退到请求完整区块的次数
+//  chain33_p2p_compactblock_tx_total          counter 紧凑区块中以短id发送的交易个数
+//  chain33_p2p_compactblock_tx_hit            counter 短id在本地mempool中命中的交易个数, 命中率为 tx_hit / tx_total
+const (
+	BlockHeightName       = "blockchain/height"
+	PeerCountName         = "blockchain/peer/count"
+	FaultPeerCountName    = "blockchain/fault/peer/count"
+	BlockProcessTimeName  = "blockchain/block/process"
+	PushSeqLagName        = "blockchain/pushseq/lag"
+	PushSeqLagLabel       = "callback"
+	MempoolSizeName       = "mempool/size"
+	MempoolBytesName      = "mempool/bytes"
+	StoreCommitTimeName   = "store/commit"
+	ClockDriftName        = "blockchain/clock/drift/ms"
+	DBWriteErrorsName     = "db/write/errors"
+	CompactBlockRecvName  = "p2p/compactblock/recv"
+	CompactBlockHitName   = "p2p/compactblock/reconstructed"
+	CompactTxReqName      = "p2p/compactblock/txreq"
+	CompactBlockFullName  = "p2p/compactblock/fullreq"
+	CompactTxTotalName    = "p2p/compactblock/tx/total"
+	CompactTxHitName      = "p2p/compactblock/tx/hit"
+	defaultGaugeVecLength = 8
+)
+
+//链相关的指标
+var (
+	BlockHeight      = go_metrics.NewRegisteredGauge(BlockHeightName, nil)
+	PeerCount        = go_metrics.NewRegisteredGauge(PeerCountName, nil)
+	FaultPeerCount   = go_metrics.NewRegisteredGauge(FaultPeerCountName, nil)
+	BlockProcessTime = go_metrics.NewRegisteredTimer(BlockProcessTimeName, nil)
+	MempoolSize      = go_metrics.NewRegisteredGauge(MempoolSizeName, nil)
+	MempoolBytes     = go_metrics.NewRegisteredGauge(MempoolBytesName, nil)
+	StoreCommitTime  = go_metrics.NewRegisteredTimer(StoreCommitTimeName, nil)
+	ClockDrift       = go_metrics.NewRegisteredGauge(ClockDriftName, nil)
+	DBWriteErrors    = go_metrics.NewRegisteredCounter(DBWriteErrorsName, nil)
+	PushSeqLag       = NewGaugeVec(PushSeqLagName, PushSeqLagLabel)
+	CompactBlockRecv = go_metrics.NewRegisteredCounter(CompactBlockRecvName, nil)
+	CompactBlockHit  = go_metrics.NewRegisteredCounter(CompactBlockHitName, nil)
+	CompactTxReq     = go_metrics.NewRegisteredCounter(CompactTxReqName, nil)
+	CompactBlockFull = go_metrics.NewRegisteredCounter(CompactBlockFullName, nil)
+	CompactTxTotal   = go_metrics.NewRegisteredCounter(CompactTxTotalName, nil)
+	CompactTxHit     = go_metrics.NewRegisteredCounter(CompactTxHitName, nil)
+)
+
+var (
+	gaugeVecsMu sync.RWMutex
+	gaugeVecs   []*GaugeVec
+)
+
+// GaugeVec 带一个标签的一组gauge, 例如每个推送回调各自的延迟
+type GaugeVec struct {
+	Name   string
+	Label  string
+	mu     sync.RWMutex
+	gauges map[string]go_metrics.Gauge
+}
+
+// NewGaugeVec 新建并注册GaugeVec
+func NewGaugeVec(name, label string) *GaugeVec {
+	v := &GaugeVec{Name: name, Label: label, gauges: make(map[string]go_metrics.Gauge, defaultGaugeVecLength)}
+	gaugeVecsMu.Lock()
+	gaugeVecs = append(gaugeVecs, v)
+	gaugeVecsMu.Unlock()
+	return v
+}
+
+// Update 更新标签对应的值
+func (v *GaugeVec) Update(labelValue string, value int64) {
+	v.mu.RLock()
+	g, ok := v.gauges[labelValue]
+	v.mu.RUnlock()
+	if !ok {
+		v.mu.Lock()
+		g, ok = v.gauges[labelValue]
+		if !ok {
+			g = go_metrics.NewGauge()
+			v.gauges[labelValue] = g
+		}
+		v.mu.Unlock()
+	}
+	g.Update(value)
+}
+
+// Delete 删除标签
+func (v *GaugeVec) Delete(labelValue string) {
+	v.mu.Lock()
+	delete(v.gauges, labelValue)
+	v.mu.Unlock()
+}
+
+// Each 按标签排序遍历
+func (v *GaugeVec) Each(f func(labelValue string, value int64)) {
+	v.mu.RLock()
+	labels := make([]string, 0, len(v.gauges))
+	values := make(map[string]int64, len(v.gauges))
+	for label, g := range v.gauges {
+		labels = append(labels, label)
+		values[label] = g.Value()
+	}
+	v.mu.RUnlock()
+	sort.Strings(labels)
+	for _, label := range labels {
+		f(label, values[label])
+	}
+}
+
+// EachGaugeVec 遍历所有注册的GaugeVec
+func EachGaugeVec(f func(v *GaugeVec)) {
+	gaugeVecsMu.RLock()
+	vecs := append([]*GaugeVec(nil), gaugeVecs...)
+	gaugeVecsMu.RUnlock()
+	for _, v := range vecs {
+		f(v)
+	}
+}
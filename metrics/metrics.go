@@ -53,6 +53,12 @@ func StartMetrics(cfg *types.Chain33Config) {
 			influxdbcfg.Username,
 			influxdbcfg.Password,
 			"")
+	case "prometheus":
+		var promcfg prometheusPara
+		if subcfg, ok := cfg.GetSubConfig().Metrics[metrics.DataEmitMode]; ok {
+			types.MustDecode(subcfg, &promcfg)
+		}
+		go startPrometheus(go_metrics.DefaultRegistry, promcfg.ListenAddr, promcfg.Path)
 	default:
 		log.Error("startMetrics", "The dataEmitMode set is not supported now ", metrics.DataEmitMode)
 		return
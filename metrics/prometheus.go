@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	go_metrics "github.com/rcrowley/go-metrics"
+)
+
+const (
+	prometheusPrefix      = "chain33_"
+	defaultPrometheusAddr = "localhost:9101"
+	defaultPrometheusPath = "/metrics"
+)
+
+var prometheusQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+type prometheusPara struct {
+	ListenAddr string `json:"listenAddr,omitempty"`
+	Path       string `json:"path,omitempty"`
+}
+
+//startPrometheus 启动http服务, 以prometheus文本格式导出metrics
+func startPrometheus(r go_metrics.Registry, addr, path string) {
+	if addr == "" {
+		addr = defaultPrometheusAddr
+	}
+	if path == "" {
+		path = defaultPrometheusPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, PrometheusHandler(r))
+	log.Info("StartMetrics with prometheus", "addr", addr, "path", path)
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		log.Error("startPrometheus", "addr", addr, "err", err)
+	}
+}
+
+// PrometheusHandler 返回导出registry和链相关指标的http handler
+func PrometheusHandler(r go_metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		WritePrometheus(bw, r)
+		err := bw.Flush()
+		if err != nil {
+			log.Error("prometheus write", "err", err)
+		}
+	})
+}
+
+// WritePrometheus 把registry中的指标以prometheus文本格式写入w, 按名称排序输出
+func WritePrometheus(w io.Writer, r go_metrics.Registry) {
+	metrics := make(map[string]interface{})
+	r.Each(func(name string, i interface{}) {
+		metrics[prometheusName(name)] = i
+	})
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch m := metrics[name].(type) {
+		case go_metrics.Counter:
+			writeMetric(w, name, "counter", float64(m.Count()))
+		case go_metrics.Gauge:
+			writeMetric(w, name, "gauge", float64(m.Value()))
+		case go_metrics.GaugeFloat64:
+			writeMetric(w, name, "gauge", m.Value())
+		case go_metrics.Meter:
+			ms := m.Snapshot()
+			writeMetric(w, name+"_total", "counter", float64(ms.Count()))
+			writeMetric(w, name+"_rate1m", "gauge", ms.Rate1())
+		case go_metrics.Histogram:
+			hs := m.Snapshot()
+			writeSummary(w, name, hs.Percentiles(prometheusQuantiles), float64(hs.Sum()), hs.Count(), 1)
+		case go_metrics.Timer:
+			ts := m.Snapshot()
+			//timer 以纳秒记录, 导出时转换为秒
+			scale := float64(time.Second)
+			writeSummary(w, name+"_seconds", ts.Percentiles(prometheusQuantiles), float64(ts.Sum()), ts.Count(), scale)
+		}
+	}
+	EachGaugeVec(func(v *GaugeVec) {
+		name := prometheusName(v.Name)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+		v.Each(func(labelValue string, value int64) {
+			fmt.Fprintf(w, "%s{%s=%s} %d\n", name, v.Label, strconv.Quote(labelValue), value)
+		})
+	})
+}
+
+func writeMetric(w io.Writer, name, typ string, value float64) {
+	fmt.Fprintf(w, "# TYPE %s %s\n%s %s\n", name, typ, name, formatFloat(value))
+}
+
+func writeSummary(w io.Writer, name string, ps []float64, sum float64, count int64, scale float64) {
+	fmt.Fprintf(w, "# TYPE %s summary\n", name)
+	for i, q := range prometheusQuantiles {
+		fmt.Fprintf(w, "%s{quantile=\"%s\"} %s\n", name, formatFloat(q), formatFloat(ps[i]/scale))
+	}
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", name, formatFloat(sum/scale), name, count)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+//prometheusName 把go-metrics的名称转换成合法的prometheus名称, 如 blockchain/height -> chain33_blockchain_height
+func prometheusName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+	if !strings.HasPrefix(name, prometheusPrefix) {
+		name = prometheusPrefix + name
+	}
+	return name
+}
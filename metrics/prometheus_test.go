@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	go_metrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusName(t *testing.T) {
+	assert.Equal(t, "chain33_blockchain_height", prometheusName(BlockHeightName))
+	assert.Equal(t, "chain33_leveldb_compact_time", prometheusName("leveldb/compact/time"))
+	assert.Equal(t, "chain33_a_b_c", prometheusName("a.b-c"))
+	assert.Equal(t, "chain33_mempool_size", prometheusName("chain33_mempool_size"))
+}
+
+func TestWritePrometheus(t *testing.T) {
+	r := go_metrics.NewRegistry()
+	go_metrics.NewRegisteredGauge("blockchain/height", r).Update(100)
+	go_metrics.NewRegisteredCounter("p2p/msg", r).Inc(3)
+	go_metrics.NewRegisteredGaugeFloat64("ratio", r).Update(0.5)
+	go_metrics.NewRegisteredMeter("disk/write", r).Mark(10)
+	timer := go_metrics.NewRegisteredTimer("store/commit", r)
+	timer.Update(time.Second)
+	timer.Update(3 * time.Second)
+	lag := NewGaugeVec("test/pushseq/lag", "callback")
+	lag.Update("cb2", 5)
+	lag.Update("cb1", 1)
+	lag.Update("cb3", 1)
+	lag.Delete("cb3")
+
+	var buf bytes.Buffer
+	WritePrometheus(&buf, r)
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE chain33_blockchain_height gauge\nchain33_blockchain_height 100\n")
+	assert.Contains(t, out, "# TYPE chain33_p2p_msg counter\nchain33_p2p_msg 3\n")
+	assert.Contains(t, out, "chain33_ratio 0.5\n")
+	assert.Contains(t, out, "chain33_disk_write_total 10\n")
+	assert.Contains(t, out, "# TYPE chain33_store_commit_seconds summary\n")
+	assert.Contains(t, out, "chain33_store_commit_seconds_sum 4\n")
+	assert.Contains(t, out, "chain33_store_commit_seconds_count 2\n")
+	assert.Contains(t, out, "chain33_test_pushseq_lag{callback=\"cb1\"} 1\nchain33_test_pushseq_lag{callback=\"cb2\"} 5\n")
+	assert.NotContains(t, out, "cb3")
+	//按名称排序输出
+	assert.True(t, strings.Index(out, "chain33_blockchain_height") < strings.Index(out, "chain33_p2p_msg"))
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	BlockHeight.Update(10)
+	MempoolSize.Update(2)
+	PushSeqLag.Update("test", 3)
+	defer PushSeqLag.Delete("test")
+
+	rec := httptest.NewRecorder()
+	PrometheusHandler(go_metrics.DefaultRegistry).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, string(body), "chain33_blockchain_height 10\n")
+	assert.Contains(t, string(body), "chain33_mempool_size 2\n")
+	assert.Contains(t, string(body), "chain33_blockchain_pushseq_lag{callback=\"test\"} 3\n")
+	assert.Contains(t, string(body), "chain33_blockchain_block_process_seconds_count")
+}
@@ -25,7 +25,7 @@ func (mgr *Manager) handleSysEvent() {
 
 		switch msg.Ty {
 
-		case types.EventTxBroadcast, types.EventBlockBroadcast: //广播
+		case types.EventTxBroadcast, types.EventBlockBroadcast, types.EventReportFaultPeer: //广播
 			mgr.pub2All(msg)
 		case types.EventFetchBlocks, types.EventGetMempool, types.EventFetchBlockHeaders:
 			mgr.pub2P2P(msg, mgr.p2pCfg.Types[0])
@@ -51,6 +51,18 @@ func (mgr *Manager) handleSysEvent() {
 			}
 			mgr.pub2P2P(msg, p2pTy)
 
+		case types.EventBanPeer, types.EventUnbanPeer:
+			req, _ := msg.Data.(*types.ReqBanPeer)
+			mgr.pub2P2P(msg, mgr.selectP2PType(req.GetP2PType()))
+
+		case types.EventGetBannedPeers:
+			req, _ := msg.Data.(*types.ReqBannedPeers)
+			mgr.pub2P2P(msg, mgr.selectP2PType(req.GetP2PType()))
+
+		case types.EventSnapshotDB:
+			//地址簿数据库由默认的p2p类型备份
+			mgr.pub2P2P(msg, mgr.p2pCfg.Types[0])
+
 		default:
 			log.Warn("unknown msgtype", "msg", msg)
 			msg.Reply(mgr.Client.NewMessage("", msg.Ty, types.Reply{Msg: []byte("unknown msgtype")}))
@@ -92,6 +104,16 @@ func (mgr *Manager) PubBroadCast(hash string, data interface{}, eventTy int) err
 	return err
 }
 
+// selectP2PType 请求指定的p2p类型不存在时采用默认配置
+func (mgr *Manager) selectP2PType(p2pTy string) string {
+	for _, ty := range mgr.p2pCfg.Types {
+		if ty == p2pTy {
+			return ty
+		}
+	}
+	return mgr.p2pCfg.Types[0]
+}
+
 //
 func (mgr *Manager) pub2All(msg *queue.Message) {
 
@@ -96,6 +96,7 @@ func (client *client) SendTimeout(msg *Message, waitReply bool, timeout time.Dur
 	if client.isClose() {
 		return ErrIsQueueClosed
 	}
+	msg.markSend()
 	if !waitReply {
 		msg.chReply = nil
 		return client.q.sendLowTimeout(msg, timeout)
@@ -194,6 +195,11 @@ func (client *client) Close() {
 	}
 }
 
+// TopicStatus 返回所有被订阅的消息通道的状态, 用于健康检查
+func (client *client) TopicStatus() []*TopicStatus {
+	return client.q.TopicStatus()
+}
+
 // CloseQueue 关闭消息队列
 func (client *client) CloseQueue() (*types.Reply, error) {
 	//	client.q.Close()
@@ -228,8 +234,11 @@ func (client *client) Sub(topic string) {
 	client.wg.Add(1)
 	client.setTopic(topic)
 	sub := client.q.chanSub(topic)
+	sub.markRecv()
+	atomic.AddInt32(&sub.subscribed, 1)
 	go func() {
 		defer func() {
+			atomic.AddInt32(&sub.subscribed, -1)
 			client.wg.Done()
 		}()
 		for {
@@ -240,6 +249,7 @@ func (client *client) Sub(topic string) {
 					return
 				}
 				client.Recv() <- data
+				sub.markRecv()
 			default:
 				select {
 				case data, ok := <-sub.high:
@@ -248,12 +258,14 @@ func (client *client) Sub(topic string) {
 						return
 					}
 					client.Recv() <- data
+					sub.markRecv()
 				case data, ok := <-sub.low:
 					if client.isEnd(data, ok) {
 						qlog.Info("unsub3", "topic", topic)
 						return
 					}
 					client.Recv() <- data
+					sub.markRecv()
 				case <-client.done:
 					qlog.Error("unsub4", "topic", topic)
 					return
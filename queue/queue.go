@@ -10,11 +10,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/33cn/chain33/common/trace"
 	"github.com/33cn/chain33/types"
 
 	log "github.com/33cn/chain33/common/log/log15"
@@ -50,6 +52,42 @@ type chanSub struct {
 	high    chan *Message
 	low     chan *Message
 	isClose int32
+	//订阅的client个数和最近一次把消息交给模块的时间(纳秒), 用于检测模块是否存活
+	subscribed int32
+	lastRecv   int64
+}
+
+func (sub *chanSub) markRecv() {
+	atomic.StoreInt64(&sub.lastRecv, time.Now().UnixNano())
+}
+
+// TopicStatus 被订阅的消息通道的状态
+type TopicStatus struct {
+	Topic string
+	//等待模块处理的消息个数
+	Pending int
+	//最近一次模块取走消息的时间, 没有消息时为订阅的时间
+	LastRecv time.Time
+}
+
+// TopicStatus 返回所有被订阅的消息通道的状态, 按订阅的topic排序.
+// 模块阻塞时消息在通道中堆积, 并且LastRecv不再更新
+func (q *queue) TopicStatus() []*TopicStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var status []*TopicStatus
+	for topic, sub := range q.chanSubs {
+		if sub.isClose == 1 || atomic.LoadInt32(&sub.subscribed) == 0 {
+			continue
+		}
+		status = append(status, &TopicStatus{
+			Topic:    topic,
+			Pending:  len(sub.high) + len(sub.low),
+			LastRecv: time.Unix(0, atomic.LoadInt64(&sub.lastRecv)),
+		})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Topic < status[j].Topic })
+	return status
 }
 
 // Queue only one obj in project
@@ -288,6 +326,9 @@ type Message struct {
 	Data     interface{}
 	chReply  chan *Message
 	callback func(msg *Message)
+	//追踪的span上下文和发送时间, 用于统计消息在队列中的等待时间
+	spanContext trace.SpanContext
+	sendTime    time.Time
 }
 
 // NewMessage new message
@@ -346,6 +387,35 @@ func (msg *Message) String() string {
 		types.GetEventName(int(msg.Ty)), msg.ID, msg.Err(), msg.chReply != nil)
 }
 
+// SpanContext 返回消息携带的span上下文
+func (msg *Message) SpanContext() trace.SpanContext {
+	return msg.spanContext
+}
+
+// SetSpanContext 设置消息携带的span上下文, 接收模块以它为parent新建span
+func (msg *Message) SetSpanContext(sc trace.SpanContext) {
+	msg.spanContext = sc
+}
+
+// StartSpan 以消息携带的span上下文为parent新建span, 并记录消息在队列中的等待时间.
+// 消息没有被追踪时返回nil
+func (msg *Message) StartSpan(name string) *trace.Span {
+	if !msg.spanContext.Sampled {
+		return nil
+	}
+	span := trace.StartSpan(msg.spanContext, name)
+	if !msg.sendTime.IsZero() {
+		span.SetAttribute("queue.wait", time.Since(msg.sendTime))
+	}
+	return span
+}
+
+func (msg *Message) markSend() {
+	if msg.spanContext.Sampled {
+		msg.sendTime = time.Now()
+	}
+}
+
 // ReplyErr reply error
 func (msg *Message) ReplyErr(title string, err error) {
 	var reply types.Reply
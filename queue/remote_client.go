@@ -0,0 +1,385 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const defaultReconnectInterval = 1000
+
+//remoteClient 通过grpc双向流连接核心节点消息队列的Client
+type remoteClient struct {
+	cfg      *types.Chain33Config
+	secret   string
+	interval time.Duration
+	conn     *grpc.ClientConn
+	recv     chan *Message
+	done     chan struct{}
+	wg       sync.WaitGroup
+	isClosed int32
+
+	mu        sync.Mutex
+	stream    types.QueueTransport_StreamClient
+	cancel    context.CancelFunc
+	connected chan struct{}
+	topic     string
+	pending   map[int64]*Message
+	sendMu    sync.Mutex
+}
+
+// NewRemoteClient 新建一个连接到核心节点远程队列服务的Client, 断线后自动重连
+// 没有开启TLS时拒绝明文发送共享密钥, 除非配置了allowInsecureLocal并且连接的是本机回环地址
+func NewRemoteClient(cfg *types.Chain33Config, rcfg *types.RemoteQueue) (Client, error) {
+	if rcfg == nil || rcfg.ServerAddr == "" {
+		return nil, types.ErrInvalidParam
+	}
+	if err := checkInsecure(rcfg, rcfg.ServerAddr); err != nil {
+		return nil, err
+	}
+	transport := grpc.WithInsecure()
+	if rcfg.EnableTLS {
+		creds, err := clientCreds(rcfg)
+		if err != nil {
+			return nil, err
+		}
+		transport = grpc.WithTransportCredentials(creds)
+	}
+	conn, err := grpc.Dial(rcfg.ServerAddr, transport)
+	if err != nil {
+		return nil, err
+	}
+	interval := rcfg.ReconnectInterval
+	if interval <= 0 {
+		interval = defaultReconnectInterval
+	}
+	client := &remoteClient{
+		cfg:       cfg,
+		secret:    rcfg.Secret,
+		interval:  time.Duration(interval) * time.Millisecond,
+		conn:      conn,
+		recv:      make(chan *Message, 5),
+		done:      make(chan struct{}),
+		connected: make(chan struct{}),
+		pending:   make(map[int64]*Message),
+	}
+	client.wg.Add(1)
+	go client.run()
+	return client, nil
+}
+
+// GetConfig return the queue Chain33Config
+func (client *remoteClient) GetConfig() *types.Chain33Config {
+	if client.cfg == nil {
+		panic("Chain33Config is nil")
+	}
+	return client.cfg
+}
+
+func (client *remoteClient) run() {
+	defer client.wg.Done()
+	for {
+		stream, err := client.connect()
+		if err == nil {
+			client.serve(stream)
+		} else {
+			qlog.Error("remote queue connect", "err", err)
+		}
+		client.disconnect()
+		select {
+		case <-client.done:
+			return
+		case <-time.After(client.interval):
+		}
+	}
+}
+
+func (client *remoteClient) connect() (types.QueueTransport_StreamClient, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if client.secret != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, remoteSecretKey, client.secret)
+	}
+	stream, err := types.NewQueueTransportClient(client.conn).Stream(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.isClose() {
+		cancel()
+		return nil, ErrIsQueueClosed
+	}
+	if client.topic != "" {
+		err = stream.Send(&types.QueueFrame{Kind: frameSub, Topic: client.topic})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	client.stream = stream
+	client.cancel = cancel
+	close(client.connected)
+	qlog.Info("remote queue connected", "topic", client.topic)
+	return stream, nil
+}
+
+//disconnect 连接断开, 所有等待回复的消息回复错误
+func (client *remoteClient) disconnect() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.stream == nil {
+		return
+	}
+	client.cancel()
+	client.stream = nil
+	client.connected = make(chan struct{})
+	for id, msg := range client.pending {
+		msg.Reply(NewMessage(0, msg.Topic, msg.Ty, ErrRemoteDisconnected))
+		delete(client.pending, id)
+	}
+}
+
+func (client *remoteClient) serve(stream types.QueueTransport_StreamClient) {
+	for {
+		frame, err := stream.Recv()
+		if err != nil {
+			qlog.Error("remote queue recv", "err", err)
+			return
+		}
+		data, err := decodeData(frame)
+		if err != nil {
+			data = err
+		}
+		switch frame.Kind {
+		case frameDeliver:
+			var msg *Message
+			if frame.WaitReply {
+				msg = NewMessage(frame.Id, frame.Topic, frame.Ty, data)
+				client.wg.Add(1)
+				go client.waitReply(msg)
+			} else {
+				msg = NewMessageCallback(frame.Id, frame.Topic, frame.Ty, data, nil)
+			}
+			setFrameSpan(msg, frame)
+			select {
+			case client.recv <- msg:
+			case <-client.done:
+				return
+			}
+		case frameReply:
+			client.mu.Lock()
+			msg, ok := client.pending[frame.Id]
+			delete(client.pending, frame.Id)
+			client.mu.Unlock()
+			if ok {
+				msg.Reply(NewMessage(frame.Id, msg.Topic, frame.Ty, data))
+			}
+		default:
+			qlog.Error("remote queue unknown frame", "kind", frame.Kind)
+		}
+	}
+}
+
+//waitReply 等待本进程模块对投递消息的回复, 再发送回核心节点
+func (client *remoteClient) waitReply(msg *Message) {
+	defer client.wg.Done()
+	select {
+	case reply := <-msg.chReply:
+		err := client.sendFrame(newFrame(frameReply, msg.ID, reply), 0)
+		if err != nil {
+			qlog.Error("remote queue reply", "msg", msg, "err", err)
+		}
+	case <-client.done:
+	}
+}
+
+//sendFrame 发送一帧数据, 没有连接时最多等待timeout, timeout为-1时一直等待
+func (client *remoteClient) sendFrame(frame *types.QueueFrame, timeout time.Duration) error {
+	stream, err := client.waitStream(timeout)
+	if err != nil {
+		return err
+	}
+	client.sendMu.Lock()
+	defer client.sendMu.Unlock()
+	err = stream.Send(frame)
+	if err != nil {
+		return ErrRemoteDisconnected
+	}
+	return nil
+}
+
+func (client *remoteClient) waitStream(timeout time.Duration) (types.QueueTransport_StreamClient, error) {
+	client.mu.Lock()
+	stream, connected := client.stream, client.connected
+	client.mu.Unlock()
+	if stream != nil {
+		return stream, nil
+	}
+	if timeout == 0 {
+		return nil, ErrRemoteDisconnected
+	}
+	var t <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		t = timer.C
+	}
+	select {
+	case <-connected:
+		return client.waitStream(0)
+	case <-client.done:
+		return nil, ErrIsQueueClosed
+	case <-t:
+		return nil, ErrQueueTimeout
+	}
+}
+
+// Send 发送消息,msg 消息 ,waitReply 是否等待回应
+func (client *remoteClient) Send(msg *Message, waitReply bool) (err error) {
+	timeout := time.Duration(-1)
+	err = client.SendTimeout(msg, waitReply, timeout)
+	if err == ErrQueueTimeout {
+		panic(err)
+	}
+	return err
+}
+
+// SendTimeout 超时发送， msg 消息 ,waitReply 是否等待回应， timeout 超时时间
+func (client *remoteClient) SendTimeout(msg *Message, waitReply bool, timeout time.Duration) (err error) {
+	if client.isClose() {
+		return ErrIsQueueClosed
+	}
+	frame := &types.QueueFrame{Kind: frameSend, Id: msg.ID, Topic: msg.Topic, Ty: msg.Ty, WaitReply: waitReply, Timeout: int64(timeout),
+		TraceParent: msg.spanContext.TraceParent()}
+	if err := encodeData(frame, msg.Data); err != nil {
+		return err
+	}
+	if !waitReply {
+		msg.chReply = nil
+		return client.sendFrame(frame, timeout)
+	}
+	client.mu.Lock()
+	client.pending[msg.ID] = msg
+	client.mu.Unlock()
+	err = client.sendFrame(frame, timeout)
+	if err != nil {
+		client.mu.Lock()
+		delete(client.pending, msg.ID)
+		client.mu.Unlock()
+	}
+	return err
+}
+
+// NewMessage 新建消息 topic模块名称 ty消息类型 data 数据
+func (client *remoteClient) NewMessage(topic string, ty int64, data interface{}) (msg *Message) {
+	id := atomic.AddInt64(&gid, 1)
+	return NewMessage(id, topic, ty, data)
+}
+
+// Reply 回复消息, 远程队列不支持回调方式的消息
+func (client *remoteClient) Reply(msg *Message) {
+	if msg.chReply != nil {
+		msg.Reply(msg)
+	}
+}
+
+// WaitTimeout 等待时间 msg 消息 timeout 超时时间
+func (client *remoteClient) WaitTimeout(msg *Message, timeout time.Duration) (*Message, error) {
+	if msg.chReply == nil {
+		return &Message{}, errors.New("empty wait channel")
+	}
+	var t <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		t = timer.C
+	}
+	select {
+	case msg = <-msg.chReply:
+		return msg, msg.Err()
+	case <-client.done:
+		return &Message{}, ErrIsQueueClosed
+	case <-t:
+		return &Message{}, ErrQueueTimeout
+	}
+}
+
+// Wait 等待时间
+func (client *remoteClient) Wait(msg *Message) (*Message, error) {
+	timeout := time.Duration(-1)
+	msg, err := client.WaitTimeout(msg, timeout)
+	if err == ErrQueueTimeout {
+		panic(err)
+	}
+	return msg, err
+}
+
+// Recv 获取接受消息通道
+func (client *remoteClient) Recv() chan *Message {
+	return client.recv
+}
+
+// Sub 订阅消息类型, 重连后自动重新订阅
+func (client *remoteClient) Sub(topic string) {
+	if client.isClose() {
+		return
+	}
+	client.mu.Lock()
+	client.topic = topic
+	stream := client.stream
+	client.mu.Unlock()
+	if stream == nil {
+		return
+	}
+	client.sendMu.Lock()
+	defer client.sendMu.Unlock()
+	if err := stream.Send(&types.QueueFrame{Kind: frameSub, Topic: topic}); err != nil {
+		qlog.Error("remote queue sub", "topic", topic, "err", err)
+	}
+}
+
+func (client *remoteClient) isClose() bool {
+	return atomic.LoadInt32(&client.isClosed) == 1
+}
+
+// Close 关闭client, 断开和核心节点的连接
+func (client *remoteClient) Close() {
+	if !atomic.CompareAndSwapInt32(&client.isClosed, 0, 1) {
+		return
+	}
+	close(client.done)
+	client.mu.Lock()
+	if client.cancel != nil {
+		client.cancel()
+	}
+	client.mu.Unlock()
+	client.wg.Wait()
+	err := client.conn.Close()
+	if err != nil {
+		qlog.Error("remote queue close", "err", err)
+	}
+	close(client.recv)
+	for msg := range client.recv {
+		msg.Reply(client.NewMessage(msg.Topic, msg.Ty, types.ErrChannelClosed))
+	}
+}
+
+// CloseQueue 关闭核心节点的消息队列
+func (client *remoteClient) CloseQueue() (*types.Reply, error) {
+	err := client.sendFrame(&types.QueueFrame{Kind: frameCloseQueue}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Reply{IsOk: true}, nil
+}
@@ -0,0 +1,138 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/33cn/chain33/common/trace"
+	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
+)
+
+//远程队列传输帧的类型
+const (
+	frameSub        = 1 //模块进程订阅topic
+	frameSend       = 2 //模块进程向核心节点发送消息
+	frameDeliver    = 3 //核心节点向模块进程投递订阅的消息
+	frameReply      = 4 //请求消息的回复
+	frameCloseQueue = 5 //关闭核心节点的消息队列
+)
+
+//远程队列的错误
+var (
+	ErrRemoteDataType     = errors.New("ErrRemoteDataType")
+	ErrRemoteDisconnected = errors.New("ErrRemoteDisconnected")
+	ErrRemoteAuth         = errors.New("ErrRemoteAuth")
+	ErrRemoteInsecure     = errors.New("ErrRemoteInsecure")
+)
+
+//跨进程传输时error只能以字符串的形式传递, 模块代码中经常通过 == 比较错误,
+//所以需要把字符串还原为原来的error对象
+var (
+	remoteErrMu sync.RWMutex
+	remoteErrs  = make(map[string]error)
+)
+
+func init() {
+	RegisterRemoteError(ErrIsQueueClosed, ErrQueueTimeout, ErrQueueChannelFull,
+		ErrRemoteDataType, ErrRemoteDisconnected, ErrRemoteAuth, ErrRemoteInsecure,
+		types.ErrNotFound, types.ErrChannelClosed, types.ErrSaveSeedFirst, types.ErrDataBaseDamage,
+		types.ErrDBFlag, types.ErrActionNotSupport, types.ErrUnknowDriver, types.ErrPrivkeyExist,
+		types.ErrNotSupport, types.ErrConsensusHashErr, types.ErrBlockExist, types.ErrTxExist,
+		types.ErrMemFull, types.ErrIsClosed, types.ErrInvalidParam,
+		types.ErrWalletIsLocked, types.ErrAccountNotExist, types.ErrBlockNotFound, types.ErrHashNotExist)
+}
+
+// RegisterRemoteError 注册通过远程队列传输后需要还原的error
+func RegisterRemoteError(errs ...error) {
+	remoteErrMu.Lock()
+	defer remoteErrMu.Unlock()
+	for _, err := range errs {
+		remoteErrs[err.Error()] = err
+	}
+}
+
+func lookupRemoteError(msg string) error {
+	remoteErrMu.RLock()
+	defer remoteErrMu.RUnlock()
+	if err, ok := remoteErrs[msg]; ok {
+		return err
+	}
+	return errors.New(msg)
+}
+
+//encodeData 把消息数据编码到传输帧中, 只支持protobuf消息, error 和 nil
+func encodeData(frame *types.QueueFrame, data interface{}) error {
+	if data == nil {
+		return nil
+	}
+	if err, ok := data.(error); ok {
+		frame.Err = err.Error()
+		return nil
+	}
+	msg, ok := data.(proto.Message)
+	if !ok {
+		return ErrRemoteDataType
+	}
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	name := proto.MessageName(msg)
+	if name == "" {
+		return ErrRemoteDataType
+	}
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	frame.DataType = name
+	frame.Data = raw
+	return nil
+}
+
+//decodeData 从传输帧中还原消息数据
+func decodeData(frame *types.QueueFrame) (interface{}, error) {
+	if frame.Err != "" {
+		return lookupRemoteError(frame.Err), nil
+	}
+	if frame.DataType == "" {
+		return nil, nil
+	}
+	ty := proto.MessageType(frame.DataType)
+	if ty == nil || ty.Kind() != reflect.Ptr {
+		return nil, ErrRemoteDataType
+	}
+	msg := reflect.New(ty.Elem()).Interface().(proto.Message)
+	if err := proto.Unmarshal(frame.Data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//newFrame 根据消息构造传输帧, 数据无法编码时以错误的形式传递给对端
+func newFrame(kind int32, id int64, msg *Message) *types.QueueFrame {
+	frame := &types.QueueFrame{Kind: kind, Id: id, Topic: msg.Topic, Ty: msg.Ty, TraceParent: msg.spanContext.TraceParent()}
+	if err := encodeData(frame, msg.Data); err != nil {
+		qlog.Error("remote encode data", "msg", msg, "err", err)
+		frame.Err = err.Error()
+	}
+	return frame
+}
+
+//setFrameSpan 恢复传输帧携带的span上下文
+func setFrameSpan(msg *Message, frame *types.QueueFrame) {
+	if frame.TraceParent == "" {
+		return
+	}
+	sc, err := trace.ParseTraceParent(frame.TraceParent)
+	if err != nil {
+		qlog.Debug("remote parse traceparent", "traceParent", frame.TraceParent, "err", err)
+		return
+	}
+	msg.spanContext = sc
+}
@@ -0,0 +1,347 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+//远程队列：
+//核心节点通过 RemoteServer 把本地队列暴露给其他进程,
+//模块进程通过 NewRemoteClient 得到一个 Client, 使用方式和本地队列完全一样.
+//1. 模块进程订阅的topic由核心节点代为订阅, 收到的消息通过grpc双向流投递给模块进程
+//2. 模块进程发送的消息由核心节点转发到本地队列, 回复再通过双向流返回
+//3. 模块进程断线期间, 订阅的消息缓存在本地队列中, 重连后继续投递
+
+//remoteSecretKey grpc metadata 中携带共享密钥的key
+const remoteSecretKey = "chain33-queue-secret"
+
+// RemoteServer 远程消息队列服务
+type RemoteServer struct {
+	q      Queue
+	client Client
+	cfg    *types.RemoteQueue
+	server *grpc.Server
+	mu     sync.Mutex
+	topics map[string]*remoteTopic
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+//remoteTopic 核心节点代替模块进程订阅的topic
+type remoteTopic struct {
+	topic   string
+	client  Client
+	mu      sync.Mutex
+	stream  *remoteStream
+	wake    chan struct{}
+	pending map[int64]*Message
+	seq     int64
+}
+
+//remoteStream 一个模块进程的连接
+type remoteStream struct {
+	stream types.QueueTransport_StreamServer
+	mu     sync.Mutex
+}
+
+func (s *remoteStream) send(frame *types.QueueFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream.Send(frame)
+}
+
+// NewRemoteServer 新建远程消息队列服务, secret 为空时不做认证, enableTLS 时使用certFile和keyFile
+func NewRemoteServer(q Queue, rcfg *types.RemoteQueue) (*RemoteServer, error) {
+	if rcfg == nil {
+		return nil, types.ErrInvalidParam
+	}
+	opts := []grpc.ServerOption{}
+	if rcfg.EnableTLS {
+		creds, err := serverCreds(rcfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s := &RemoteServer{
+		q:      q,
+		client: q.Client(),
+		cfg:    rcfg,
+		topics: make(map[string]*remoteTopic),
+		done:   make(chan struct{}),
+	}
+	if rcfg.Secret == "" {
+		qlog.Warn("remote queue server start without secret")
+	}
+	opts = append(opts, grpc.StreamInterceptor(s.auth))
+	s.server = grpc.NewServer(opts...)
+	types.RegisterQueueTransportServer(s.server, s)
+	return s, nil
+}
+
+// Listen 监听地址并在后台提供服务, 没有开启TLS时不允许在非本机地址上明文接收共享密钥
+func (s *RemoteServer) Listen(addr string) (net.Addr, error) {
+	if err := checkInsecure(s.cfg, addr); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	qlog.Info("remote queue server listen", "addr", l.Addr())
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			qlog.Error("remote queue server", "err", err)
+		}
+	}()
+	return l.Addr(), nil
+}
+
+// Close 关闭服务, 同时取消所有代理的订阅
+func (s *RemoteServer) Close() {
+	close(s.done)
+	s.server.Stop()
+	s.mu.Lock()
+	for _, t := range s.topics {
+		t.client.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	qlog.Info("remote queue server closed")
+}
+
+func (s *RemoteServer) auth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if s.cfg.Secret != "" && !checkSecret(ss.Context(), s.cfg.Secret) {
+		qlog.Error("remote queue auth failed")
+		return ErrRemoteAuth
+	}
+	return handler(srv, ss)
+}
+
+func checkSecret(ctx context.Context, secret string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	values := md.Get(remoteSecretKey)
+	if len(values) != 1 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(values[0]), []byte(secret)) == 1
+}
+
+// Stream 处理一个模块进程的连接
+func (s *RemoteServer) Stream(stream types.QueueTransport_StreamServer) error {
+	rs := &remoteStream{stream: stream}
+	//转发的消息按顺序发送到本地队列, 等待回复则放到独立的goroutine中
+	sends := make(chan *types.QueueFrame, defaultChanBuffer)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for frame := range sends {
+			s.forward(rs, frame)
+		}
+	}()
+	defer func() {
+		close(sends)
+		s.detach(rs)
+	}()
+	for {
+		frame, err := stream.Recv()
+		if err != nil {
+			qlog.Info("remote queue stream closed", "err", err)
+			return err
+		}
+		switch frame.Kind {
+		case frameSub:
+			s.subscribe(frame.Topic, rs)
+		case frameSend:
+			select {
+			case sends <- frame:
+			case <-s.done:
+				return ErrIsQueueClosed
+			}
+		case frameReply:
+			s.reply(frame)
+		case frameCloseQueue:
+			_, err = s.client.CloseQueue()
+			if err != nil {
+				qlog.Error("remote queue close queue", "err", err)
+			}
+		default:
+			qlog.Error("remote queue unknown frame", "kind", frame.Kind)
+		}
+	}
+}
+
+//forward 把模块进程发送的消息转发到本地队列
+func (s *RemoteServer) forward(rs *remoteStream, frame *types.QueueFrame) {
+	data, err := decodeData(frame)
+	if err != nil {
+		s.replyErr(rs, frame, err)
+		return
+	}
+	msg := s.client.NewMessage(frame.Topic, frame.Ty, data)
+	setFrameSpan(msg, frame)
+	err = s.client.SendTimeout(msg, frame.WaitReply, time.Duration(frame.Timeout))
+	if err != nil {
+		s.replyErr(rs, frame, err)
+		return
+	}
+	if !frame.WaitReply {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		resp, err := s.client.WaitTimeout(msg, 0)
+		if err != nil && resp.Data == nil {
+			s.replyErr(rs, frame, err)
+			return
+		}
+		err = rs.send(newFrame(frameReply, frame.Id, resp))
+		if err != nil {
+			qlog.Error("remote queue send reply", "msg", msg, "err", err)
+		}
+	}()
+}
+
+func (s *RemoteServer) replyErr(rs *remoteStream, frame *types.QueueFrame, err error) {
+	qlog.Error("remote queue forward", "topic", frame.Topic, "ty", types.GetEventName(int(frame.Ty)), "err", err)
+	if !frame.WaitReply {
+		return
+	}
+	reply := &types.QueueFrame{Kind: frameReply, Id: frame.Id, Topic: frame.Topic, Ty: frame.Ty, Err: err.Error()}
+	if err := rs.send(reply); err != nil {
+		qlog.Error("remote queue send reply", "topic", frame.Topic, "err", err)
+	}
+}
+
+//reply 模块进程对投递消息的回复
+func (s *RemoteServer) reply(frame *types.QueueFrame) {
+	s.mu.Lock()
+	t, ok := s.topics[frame.Topic]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	msg, ok := t.pending[frame.Id]
+	delete(t.pending, frame.Id)
+	t.mu.Unlock()
+	if !ok {
+		qlog.Debug("remote queue reply not found", "topic", frame.Topic, "id", frame.Id)
+		return
+	}
+	data, err := decodeData(frame)
+	if err != nil {
+		data = err
+	}
+	msg.Reply(s.client.NewMessage(msg.Topic, frame.Ty, data))
+}
+
+//subscribe 代替模块进程订阅topic, 已经订阅过的topic直接绑定到新的连接
+func (s *RemoteServer) subscribe(topic string, rs *remoteStream) {
+	s.mu.Lock()
+	t, ok := s.topics[topic]
+	if !ok {
+		t = &remoteTopic{
+			topic:   topic,
+			client:  s.q.Client(),
+			wake:    make(chan struct{}, 1),
+			pending: make(map[int64]*Message),
+		}
+		s.topics[topic] = t
+		t.client.Sub(topic)
+		s.wg.Add(1)
+		go s.deliver(t)
+	}
+	s.mu.Unlock()
+	qlog.Info("remote queue sub", "topic", topic)
+	t.mu.Lock()
+	t.failPending()
+	t.stream = rs
+	t.mu.Unlock()
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+//detach 连接断开, 已经投递但还没有回复的消息全部回复错误
+func (s *RemoteServer) detach(rs *remoteStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.topics {
+		t.mu.Lock()
+		if t.stream == rs {
+			t.failPending()
+			t.stream = nil
+		}
+		t.mu.Unlock()
+	}
+}
+
+//deliver 把本地队列中订阅的消息投递到模块进程, 没有连接时等待模块进程重连
+func (s *RemoteServer) deliver(t *remoteTopic) {
+	defer s.wg.Done()
+	for msg := range t.client.Recv() {
+		for !s.deliverMsg(t, msg) {
+			select {
+			case <-t.wake:
+				continue
+			case <-s.done:
+				//服务已关闭, 继续读取直到订阅关闭, 避免阻塞本地队列
+				msg.Reply(s.client.NewMessage(msg.Topic, msg.Ty, ErrIsQueueClosed))
+			}
+			break
+		}
+	}
+}
+
+func (s *RemoteServer) deliverMsg(t *remoteTopic, msg *Message) bool {
+	t.mu.Lock()
+	rs := t.stream
+	if rs == nil {
+		t.mu.Unlock()
+		return false
+	}
+	t.seq++
+	id := t.seq
+	waitReply := msg.chReply != nil
+	if waitReply {
+		t.pending[id] = msg
+	}
+	t.mu.Unlock()
+	frame := newFrame(frameDeliver, id, msg)
+	frame.WaitReply = waitReply
+	if err := rs.send(frame); err != nil {
+		qlog.Error("remote queue deliver", "msg", msg, "err", err)
+		t.mu.Lock()
+		delete(t.pending, id)
+		if t.stream == rs {
+			t.stream = nil
+		}
+		t.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+func (t *remoteTopic) failPending() {
+	for id, msg := range t.pending {
+		msg.Reply(NewMessage(0, msg.Topic, msg.Ty, ErrRemoteDisconnected))
+		delete(t.pending, id)
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteTestSecret = "123456"
+
+func TestRemoteCodec(t *testing.T) {
+	frame := &types.QueueFrame{}
+	assert.Nil(t, encodeData(frame, &types.ReqNil{}))
+	assert.Equal(t, "types.ReqNil", frame.DataType)
+	data, err := decodeData(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.ReqNil{}, data)
+
+	frame = &types.QueueFrame{}
+	assert.Nil(t, encodeData(frame, &types.ReqString{Data: "hello"}))
+	data, err = decodeData(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", data.(*types.ReqString).Data)
+
+	frame = &types.QueueFrame{}
+	assert.Nil(t, encodeData(frame, types.ErrNotFound))
+	data, err = decodeData(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, types.ErrNotFound, data)
+
+	frame = &types.QueueFrame{}
+	assert.Nil(t, encodeData(frame, errors.New("unknown error")))
+	data, err = decodeData(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, "unknown error", data.(error).Error())
+
+	frame = &types.QueueFrame{}
+	var header *types.Header
+	assert.Nil(t, encodeData(frame, header))
+	assert.Nil(t, encodeData(frame, nil))
+	data, err = decodeData(frame)
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+
+	assert.Equal(t, ErrRemoteDataType, encodeData(frame, "hello"))
+	_, err = decodeData(&types.QueueFrame{DataType: "types.NotExist"})
+	assert.Equal(t, ErrRemoteDataType, err)
+}
+
+func newRemoteTestConfig(addr string) *types.RemoteQueue {
+	return &types.RemoteQueue{ServerAddr: addr, Secret: remoteTestSecret, ReconnectInterval: 100, AllowInsecureLocal: true}
+}
+
+func newRemoteTestServer(t *testing.T, q Queue, rcfg *types.RemoteQueue) (*RemoteServer, net.Addr) {
+	server, err := NewRemoteServer(q, rcfg)
+	require.Nil(t, err)
+	addr, err := server.Listen("127.0.0.1:0")
+	require.Nil(t, err)
+	return server, addr
+}
+
+//remoteEcho 模拟运行在独立进程中的模块, 把交易的payload原样回复
+func remoteEcho(client Client, topic string) {
+	client.Sub(topic)
+	for msg := range client.Recv() {
+		if msg.Ty == types.EventTx {
+			tx := msg.GetData().(*types.Transaction)
+			msg.Reply(client.NewMessage(topic, types.EventReply, &types.Reply{IsOk: true, Msg: tx.Payload}))
+			continue
+		}
+		msg.Reply(client.NewMessage(topic, types.EventReply, types.ErrActionNotSupport))
+	}
+}
+
+func sendEcho(t *testing.T, client Client, topic string, payload string) {
+	msg := client.NewMessage(topic, types.EventTx, &types.Transaction{Payload: []byte(payload)})
+	require.Nil(t, client.SendTimeout(msg, true, time.Second))
+	resp, err := client.WaitTimeout(msg, 5*time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, payload, string(resp.GetData().(*types.Reply).Msg))
+}
+
+func waitRemoteSub(t *testing.T, server *RemoteServer, topic string) {
+	for i := 0; i < 100; i++ {
+		server.mu.Lock()
+		rt, ok := server.topics[topic]
+		server.mu.Unlock()
+		if ok {
+			rt.mu.Lock()
+			attached := rt.stream != nil
+			rt.mu.Unlock()
+			if attached {
+				return
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("remote sub timeout", topic)
+}
+
+func TestRemoteQueue(t *testing.T) {
+	q := New("channel")
+	defer q.Close()
+	server, addr := newRemoteTestServer(t, q, newRemoteTestConfig(""))
+	defer server.Close()
+
+	//核心节点中的blockchain模块
+	go func() {
+		client := q.Client()
+		client.Sub("blockchain")
+		for msg := range client.Recv() {
+			msg.Reply(client.NewMessage("blockchain", types.EventHeader, &types.Header{Height: 100}))
+		}
+	}()
+
+	remote, err := NewRemoteClient(nil, newRemoteTestConfig(addr.String()))
+	require.Nil(t, err)
+	defer remote.Close()
+	go remoteEcho(remote, "wallet")
+	waitRemoteSub(t, server, "wallet")
+
+	//核心节点发送到远程模块
+	local := q.Client()
+	sendEcho(t, local, "wallet", "hello")
+	msg := local.NewMessage("wallet", types.EventGetLastHeader, &types.ReqNil{})
+	require.Nil(t, local.Send(msg, true))
+	_, err = local.Wait(msg)
+	assert.Equal(t, types.ErrActionNotSupport, err)
+
+	//远程模块发送到核心节点
+	msg = remote.NewMessage("blockchain", types.EventGetLastHeader, &types.ReqNil{})
+	require.Nil(t, remote.Send(msg, true))
+	resp, err := remote.Wait(msg)
+	require.Nil(t, err)
+	assert.Equal(t, int64(100), resp.GetData().(*types.Header).Height)
+
+	//不支持的数据类型
+	msg = remote.NewMessage("blockchain", types.EventGetLastHeader, "hello")
+	assert.Equal(t, ErrRemoteDataType, remote.Send(msg, true))
+
+	//不等待回复
+	msg = remote.NewMessage("blockchain", types.EventGetLastHeader, &types.ReqNil{})
+	assert.Nil(t, remote.Send(msg, false))
+}
+
+func TestRemoteQueueAuth(t *testing.T) {
+	q := New("channel")
+	defer q.Close()
+	server, addr := newRemoteTestServer(t, q, newRemoteTestConfig(""))
+	defer server.Close()
+
+	cfg := newRemoteTestConfig(addr.String())
+	cfg.Secret = "654321"
+	remote, err := NewRemoteClient(nil, cfg)
+	require.Nil(t, err)
+	defer remote.Close()
+	msg := remote.NewMessage("blockchain", types.EventGetLastHeader, &types.ReqNil{})
+	assert.Nil(t, remote.SendTimeout(msg, true, time.Second))
+	_, err = remote.WaitTimeout(msg, time.Second)
+	assert.NotNil(t, err)
+	server.mu.Lock()
+	assert.Equal(t, 0, len(server.topics))
+	server.mu.Unlock()
+
+	_, err = NewRemoteClient(nil, &types.RemoteQueue{})
+	assert.Equal(t, types.ErrInvalidParam, err)
+}
+
+//writeTestCert 生成localhost的自签名证书, 同时作为CA使用
+func writeTestCert(t *testing.T, dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestRemoteQueueTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remotequeue")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	serverCert, serverKey := writeTestCert(t, dir, "server")
+	clientCert, clientKey := writeTestCert(t, dir, "client")
+
+	//没有开启TLS时拒绝明文传输密钥
+	_, err = NewRemoteClient(nil, &types.RemoteQueue{ServerAddr: "127.0.0.1:8806", Secret: remoteTestSecret})
+	assert.Equal(t, ErrRemoteInsecure, err)
+	_, err = NewRemoteClient(nil, &types.RemoteQueue{ServerAddr: "10.0.0.1:8806", Secret: remoteTestSecret, AllowInsecureLocal: true})
+	assert.Equal(t, ErrRemoteInsecure, err)
+	q := New("channel")
+	defer q.Close()
+	insecure, err := NewRemoteServer(q, &types.RemoteQueue{Secret: remoteTestSecret, AllowInsecureLocal: true})
+	require.Nil(t, err)
+	_, err = insecure.Listen(":0")
+	assert.Equal(t, ErrRemoteInsecure, err)
+	_, err = NewRemoteServer(q, &types.RemoteQueue{Secret: remoteTestSecret, EnableTLS: true})
+	assert.Equal(t, types.ErrInvalidParam, err)
+
+	//双向认证
+	server, addr := newRemoteTestServer(t, q, &types.RemoteQueue{Secret: remoteTestSecret, EnableTLS: true,
+		CertFile: serverCert, KeyFile: serverKey, CAFile: clientCert})
+	defer server.Close()
+	go func() {
+		client := q.Client()
+		client.Sub("blockchain")
+		for msg := range client.Recv() {
+			msg.Reply(client.NewMessage("blockchain", types.EventHeader, &types.Header{Height: 100}))
+		}
+	}()
+	cfg := &types.RemoteQueue{ServerAddr: addr.String(), Secret: remoteTestSecret, ReconnectInterval: 100, EnableTLS: true,
+		CertFile: clientCert, KeyFile: clientKey, CAFile: serverCert}
+	remote, err := NewRemoteClient(nil, cfg)
+	require.Nil(t, err)
+	defer remote.Close()
+	msg := remote.NewMessage("blockchain", types.EventGetLastHeader, &types.ReqNil{})
+	require.Nil(t, remote.SendTimeout(msg, true, 5*time.Second))
+	resp, err := remote.WaitTimeout(msg, 5*time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, int64(100), resp.GetData().(*types.Header).Height)
+
+	//没有客户端证书不能连接
+	cfg.CertFile, cfg.KeyFile = "", ""
+	noCert, err := NewRemoteClient(nil, cfg)
+	require.Nil(t, err)
+	defer noCert.Close()
+	msg = noCert.NewMessage("blockchain", types.EventGetLastHeader, &types.ReqNil{})
+	assert.NotNil(t, noCert.SendTimeout(msg, true, time.Second))
+}
+
+//remoteProxy 转发tcp连接, 用于模拟网络断开
+type remoteProxy struct {
+	l       net.Listener
+	backend string
+	mu      sync.Mutex
+	conns   []net.Conn
+}
+
+func newRemoteProxy(t *testing.T, backend string) *remoteProxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	p := &remoteProxy{l: l, backend: backend}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", backend)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, conn, upstream)
+			p.mu.Unlock()
+			go io.Copy(conn, upstream)
+			go io.Copy(upstream, conn)
+		}
+	}()
+	return p
+}
+
+func (p *remoteProxy) breakConns() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
+
+func TestRemoteQueueReconnect(t *testing.T) {
+	q := New("channel")
+	defer q.Close()
+	server, addr := newRemoteTestServer(t, q, newRemoteTestConfig(""))
+	defer server.Close()
+	proxy := newRemoteProxy(t, addr.String())
+	defer proxy.l.Close()
+
+	remote, err := NewRemoteClient(nil, newRemoteTestConfig(proxy.l.Addr().String()))
+	require.Nil(t, err)
+	defer remote.Close()
+	go remoteEcho(remote, "wallet")
+	waitRemoteSub(t, server, "wallet")
+	local := q.Client()
+	sendEcho(t, local, "wallet", "before")
+
+	proxy.breakConns()
+	//断线期间发送的消息缓存在核心节点, 重连后投递
+	msg := local.NewMessage("wallet", types.EventTx, &types.Transaction{Payload: []byte("after")})
+	require.Nil(t, local.SendTimeout(msg, true, time.Second))
+	resp, err := local.WaitTimeout(msg, 10*time.Second)
+	for err == ErrRemoteDisconnected {
+		//消息投递到了已经断开的连接
+		msg = local.NewMessage("wallet", types.EventTx, &types.Transaction{Payload: []byte("after")})
+		require.Nil(t, local.SendTimeout(msg, true, time.Second))
+		resp, err = local.WaitTimeout(msg, 10*time.Second)
+	}
+	require.Nil(t, err)
+	assert.Equal(t, "after", string(resp.GetData().(*types.Reply).Msg))
+}
+
+//TestRemoteHelperProcess 作为独立的模块进程运行, 只在 TestRemoteQueueProcess 中被调用
+func TestRemoteHelperProcess(t *testing.T) {
+	addr := os.Getenv("CHAIN33_REMOTE_QUEUE_ADDR")
+	if addr == "" {
+		return
+	}
+	remote, err := NewRemoteClient(nil, newRemoteTestConfig(addr))
+	if err != nil {
+		os.Exit(1)
+	}
+	remoteEcho(remote, "wallet")
+	os.Exit(0)
+}
+
+func TestRemoteQueueProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip two process test in short mode")
+	}
+	q := New("channel")
+	defer q.Close()
+	server, addr := newRemoteTestServer(t, q, newRemoteTestConfig(""))
+	defer server.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestRemoteHelperProcess")
+	cmd.Env = append(os.Environ(), "CHAIN33_REMOTE_QUEUE_ADDR="+addr.String())
+	require.Nil(t, cmd.Start())
+	defer func() {
+		assert.Nil(t, cmd.Process.Kill())
+		_ = cmd.Wait()
+	}()
+	waitRemoteSub(t, server, "wallet")
+
+	local := q.Client()
+	for i := 0; i < 10; i++ {
+		sendEcho(t, local, "wallet", string(rune('a'+i)))
+	}
+}
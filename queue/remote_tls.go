@@ -0,0 +1,92 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+
+	"github.com/33cn/chain33/types"
+	"google.golang.org/grpc/credentials"
+)
+
+//serverCreds 核心节点的TLS配置, 配置了CA时要求模块进程提供客户端证书
+func serverCreds(rcfg *types.RemoteQueue) (credentials.TransportCredentials, error) {
+	if rcfg.CertFile == "" || rcfg.KeyFile == "" {
+		return nil, types.ErrInvalidParam
+	}
+	cert, err := tls.LoadX509KeyPair(rcfg.CertFile, rcfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	conf := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if rcfg.CAFile != "" {
+		pool, err := loadCertPool(rcfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(conf), nil
+}
+
+//clientCreds 模块进程的TLS配置, 没有配置CA时使用系统CA校验核心节点的证书
+func clientCreds(rcfg *types.RemoteQueue) (credentials.TransportCredentials, error) {
+	conf := &tls.Config{}
+	if rcfg.CAFile != "" {
+		pool, err := loadCertPool(rcfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	if rcfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(rcfg.CertFile, rcfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(conf), nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no valid certificate in " + file)
+	}
+	return pool, nil
+}
+
+//checkInsecure 没有开启TLS时, 共享密钥只允许在配置了allowInsecureLocal的本机回环地址上明文传输
+func checkInsecure(rcfg *types.RemoteQueue, addr string) error {
+	if rcfg.EnableTLS || rcfg.Secret == "" {
+		return nil
+	}
+	if rcfg.AllowInsecureLocal && isLoopback(addr) {
+		return nil
+	}
+	return ErrRemoteInsecure
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
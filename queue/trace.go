@@ -0,0 +1,32 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import "github.com/33cn/chain33/common/trace"
+
+//spanClient 通过它新建的消息都携带同一个span上下文
+type spanClient struct {
+	Client
+	sc trace.SpanContext
+}
+
+// WithSpan 返回一个Client, 通过它新建的消息都携带span上下文, 用于把trace传递给接收模块.
+// span没有被采样时直接返回client, 不增加开销
+func WithSpan(client Client, sc trace.SpanContext) Client {
+	if !sc.Sampled {
+		return client
+	}
+	if c, ok := client.(*spanClient); ok {
+		client = c.Client
+	}
+	return &spanClient{Client: client, sc: sc}
+}
+
+// NewMessage 新建携带span上下文的消息
+func (c *spanClient) NewMessage(topic string, ty int64, data interface{}) *Message {
+	msg := c.Client.NewMessage(topic, ty, data)
+	msg.spanContext = c.sc
+	return msg
+}
@@ -0,0 +1,93 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/common/trace"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceExporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (e *traceExporter) ExportSpans(spans []*trace.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *traceExporter) Shutdown() error {
+	return nil
+}
+
+func TestMessageSpan(t *testing.T) {
+	q := New("channel")
+	defer q.Close()
+	client := q.Client()
+	//没有开启追踪时直接返回原来的client
+	assert.Equal(t, client, WithSpan(client, trace.SpanContext{}))
+
+	exporter := &traceExporter{}
+	trace.SetExporter(exporter, 1)
+	defer trace.Close()
+	root := trace.StartSpan(trace.SpanContext{}, "root")
+	require.NotNil(t, root)
+
+	go func() {
+		store := q.Client()
+		store.Sub("store")
+		for msg := range store.Recv() {
+			span := msg.StartSpan("store.Commit")
+			span.End()
+			msg.Reply(store.NewMessage("", types.EventStoreCommit, &types.ReplyHash{}))
+		}
+	}()
+	spanClient := WithSpan(WithSpan(client, root.Context()), root.Context())
+	msg := spanClient.NewMessage("store", types.EventStoreCommit, &types.ReqHash{})
+	assert.Equal(t, root.Context(), msg.SpanContext())
+	require.Nil(t, spanClient.Send(msg, true))
+	_, err := spanClient.Wait(msg)
+	require.Nil(t, err)
+	assert.False(t, msg.sendTime.IsZero())
+	root.End()
+	trace.Close()
+
+	require.Equal(t, 2, len(exporter.spans))
+	child := exporter.spans[0]
+	assert.Equal(t, "store.Commit", child.Name)
+	assert.Equal(t, root.Context().TraceID, child.Context.TraceID)
+	assert.Equal(t, root.Context().SpanID, child.Parent)
+	assert.Equal(t, "queue.wait", child.Attributes[0].Key)
+
+	//没有被追踪的消息
+	msg = client.NewMessage("store", types.EventStoreCommit, nil)
+	assert.Nil(t, msg.StartSpan("store.Commit"))
+}
+
+func TestRemoteFrameSpan(t *testing.T) {
+	sc := trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}, Sampled: true}
+	msg := NewMessage(1, "store", types.EventStoreCommit, &types.ReqHash{})
+	msg.SetSpanContext(sc)
+	frame := newFrame(frameDeliver, msg.ID, msg)
+	assert.Equal(t, sc.TraceParent(), frame.TraceParent)
+
+	recv := NewMessage(frame.Id, frame.Topic, frame.Ty, nil)
+	setFrameSpan(recv, frame)
+	assert.Equal(t, sc, recv.SpanContext())
+
+	recv = NewMessage(frame.Id, frame.Topic, frame.Ty, nil)
+	setFrameSpan(recv, &types.QueueFrame{TraceParent: "invalid"})
+	assert.False(t, recv.SpanContext().IsValid())
+	assert.Equal(t, time.Time{}, recv.sendTime)
+}
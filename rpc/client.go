@@ -354,6 +354,9 @@ func (c *channelClient) GetAllExecBalance(in *types.ReqAllExecBalance) (*types.A
 			AssetSymbol: in.AssetSymbol,
 		}
 		res, err := c.GetBalance(params)
+		if err == types.ErrStatePruned {
+			return nil, err
+		}
 		if err != nil {
 			continue
 		}
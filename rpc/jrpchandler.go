@@ -14,6 +14,7 @@ import (
 
 	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/address"
+	clog "github.com/33cn/chain33/common/log"
 	"github.com/33cn/chain33/types"
 	wcom "github.com/33cn/chain33/wallet/common"
 
@@ -871,6 +872,59 @@ func (c *Chain33) Query(in rpctypes.Query4Jrpc, result *interface{}) error {
 	return nil
 }
 
+// QueryHistory 在指定高度或者状态哈希对应的历史状态上查询, 状态已经被裁剪时返回 ErrStatePruned
+func (c *Chain33) QueryHistory(in rpctypes.QueryHistory4Jrpc, result *interface{}) error {
+	cfg := c.cli.GetConfig()
+	execty := types.LoadExecutorType(in.Execer)
+	if execty == nil {
+		log.Error("QueryHistory", "funcname", in.FuncName, "err", types.ErrNotSupport)
+		return types.ErrNotSupport
+	}
+	decodePayload, err := execty.CreateQuery(in.FuncName, in.Payload)
+	if err != nil {
+		log.Error("QueryHistory", "err", err.Error())
+		return err
+	}
+	var stateHash, extra []byte
+	if in.StateHash != "" {
+		stateHash, err = common.FromHex(in.StateHash)
+		if err != nil {
+			return err
+		}
+	} else if in.Height != nil && *in.Height >= 0 {
+		height := *in.Height
+		headers, err := c.cli.GetHeaders(&types.ReqBlocks{Start: height, End: height})
+		if err != nil {
+			return err
+		}
+		if len(headers.GetItems()) == 0 {
+			return types.ErrHeightNotExist
+		}
+		stateHash = headers.Items[0].StateHash
+		//开启localmvcc时localdb也读取该高度的数据
+		extra = types.Encode(&types.LocalDBHeight{Enable: true, Height: height})
+	}
+	resp, err := c.cli.QueryChain(&types.ChainExecutor{
+		Driver:    cfg.ExecName(in.Execer),
+		FuncName:  in.FuncName,
+		StateHash: stateHash,
+		Param:     types.Encode(decodePayload),
+		Extra:     extra,
+	})
+	if err != nil {
+		log.Error("QueryHistory", "err", err.Error())
+		return err
+	}
+	var jsonmsg json.RawMessage
+	jsonmsg, err = execty.QueryToJSON(in.FuncName, resp)
+	if err != nil {
+		log.Error("QueryHistory", "err", err.Error())
+		return err
+	}
+	*result = jsonmsg
+	return nil
+}
+
 // DumpPrivkey dump privkey
 func (c *Chain33) DumpPrivkey(in types.ReqString, result *interface{}) error {
 	reply, err := c.cli.ExecWalletFunc("wallet", "DumpPrivkey", &in)
@@ -955,6 +1009,73 @@ func (c *Chain33) IsNtpClockSync(in *types.ReqNil, result *interface{}) error {
 	return nil
 }
 
+// GetSyncProgress 获取区块同步进度, 包括已校验的区块头, 已下载的区块体以及已执行的区块高度
+func (c *Chain33) GetSyncProgress(in *types.ReqNil, result *interface{}) error {
+	reply, err := c.cli.GetSyncProgress()
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
+// GetStorePruneStatus 获取mavl状态裁剪进度, 包括已裁剪的高度, 删除的节点数目以及待裁剪的区块数目
+func (c *Chain33) GetStorePruneStatus(in *types.ReqNil, result *interface{}) error {
+	reply, err := c.cli.GetStorePruneStatus()
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
+// SetStorePrunePause 暂停或者恢复mavl状态裁剪, 节点重启之后恢复裁剪
+func (c *Chain33) SetStorePrunePause(in *types.ReqStorePrune, result *interface{}) error {
+	if in == nil {
+		return types.ErrInvalidParam
+	}
+	reply, err := c.cli.SetStorePrunePause(in)
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
+// BackupDB 在区块边界在线备份blockchain, store, wallet, p2p的数据库, 返回备份清单
+func (c *Chain33) BackupDB(in *types.ReqBackupDB, result *interface{}) error {
+	if in == nil || in.Dir == "" {
+		return types.ErrInvalidParam
+	}
+	reply, err := c.cli.BackupDB(in)
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
+// SetLogLevel 运行时设置模块日志级别, 作用于rpc所在的进程
+func (c *Chain33) SetLogLevel(in *rpctypes.ReqLogLevel, result *interface{}) error {
+	if in == nil {
+		return types.ErrInvalidParam
+	}
+	if err := clog.SetModuleLevel(in.Module, in.Level); err != nil {
+		return err
+	}
+	*result = &types.Reply{IsOk: true}
+	return nil
+}
+
+// GetLogLevels 获取当前生效的日志级别
+func (c *Chain33) GetLogLevels(in *types.ReqNil, result *interface{}) error {
+	var levels rpctypes.LogLevels
+	levels.Loglevel, levels.LogConsoleLevel = clog.DefaultLevels()
+	levels.ModuleLevels = clog.ModuleLevels()
+	*result = &levels
+	return nil
+}
+
 // QueryTotalFee query total fee
 func (c *Chain33) QueryTotalFee(in *types.LocalDBGet, result *interface{}) error {
 	if in == nil || len(in.Keys) != 1 {
@@ -1003,7 +1124,38 @@ func (c *Chain33) GetNetInfo(in types.P2PGetNetInfoReq, result *interface{}) err
 		Service:      resp.GetService(),
 		Outbounds:    resp.GetOutbounds(),
 		Inbounds:     resp.GetInbounds(),
+		Reachability: resp.GetReachability(),
+	}
+	return nil
+}
+
+// BanPeer 封禁节点, 时长为0时使用默认时长
+func (c *Chain33) BanPeer(in *types.ReqBanPeer, result *interface{}) error {
+	reply, err := c.cli.BanPeer(in)
+	if err != nil {
+		return err
 	}
+	*result = &rpctypes.Reply{IsOk: reply.GetIsOk()}
+	return nil
+}
+
+// UnbanPeer 解除节点封禁
+func (c *Chain33) UnbanPeer(in *types.ReqBanPeer, result *interface{}) error {
+	reply, err := c.cli.UnbanPeer(in)
+	if err != nil {
+		return err
+	}
+	*result = &rpctypes.Reply{IsOk: reply.GetIsOk()}
+	return nil
+}
+
+// GetBannedPeers 获取处于封禁期的节点
+func (c *Chain33) GetBannedPeers(in types.ReqBannedPeers, result *interface{}) error {
+	reply, err := c.cli.GetBannedPeers(&in)
+	if err != nil {
+		return err
+	}
+	*result = reply
 	return nil
 }
 
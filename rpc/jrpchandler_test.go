@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"encoding/hex"
+	"encoding/json"
 
 	"github.com/33cn/chain33/account"
 	"github.com/33cn/chain33/client"
@@ -926,6 +927,79 @@ func TestChain33_GetPeerInfoOk(t *testing.T) {
 	assert.Equal(t, testResult.(*rpctypes.PeerList).Peers[0].Addr, peerlist.Peers[0].Addr)
 }
 
+func TestChain33_BanPeer(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	testChain33 := newTestChain33(api)
+
+	req := &types.ReqBanPeer{Pid: "16Uiu2HAmTest", Duration: 60}
+	api.On("BanPeer", req).Return(&types.Reply{IsOk: true}, nil)
+	api.On("UnbanPeer", req).Return(nil, errors.New("ErrPeerNotBanned"))
+	bans := &types.BannedPeers{Items: []*types.BannedPeer{{Pid: req.Pid, Expire: 100, Count: 1}}}
+	api.On("GetBannedPeers", mock.Anything).Return(bans, nil)
+
+	var testResult interface{}
+	assert.Nil(t, testChain33.BanPeer(req, &testResult))
+	assert.True(t, testResult.(*rpctypes.Reply).IsOk)
+	assert.NotNil(t, testChain33.UnbanPeer(req, &testResult))
+	assert.Nil(t, testChain33.GetBannedPeers(types.ReqBannedPeers{}, &testResult))
+	assert.Equal(t, req.Pid, testResult.(*types.BannedPeers).Items[0].Pid)
+	mock.AssertExpectationsForObjects(t, api)
+}
+
+func TestChain33_GetSyncProgress(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	testChain33 := newTestChain33(api)
+
+	progress := &types.SyncProgress{InProgress: true, TargetHeight: 100, HeaderHeight: 80, BodyHeight: 50, ExecHeight: 40}
+	api.On("GetSyncProgress").Return(progress, nil).Once()
+	api.On("GetSyncProgress").Return(nil, types.ErrTypeAsset)
+
+	var testResult interface{}
+	assert.Nil(t, testChain33.GetSyncProgress(&types.ReqNil{}, &testResult))
+	assert.Equal(t, progress, testResult)
+	assert.Equal(t, types.ErrTypeAsset, testChain33.GetSyncProgress(&types.ReqNil{}, &testResult))
+}
+
+func TestChain33_StorePrune(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	testChain33 := newTestChain33(api)
+
+	status := &types.StorePruneStatus{Enable: true, RetainHeight: 100, CurHeight: 1000, PrunedHeight: 800, Backlog: 100}
+	api.On("GetStorePruneStatus").Return(status, nil)
+	var testResult interface{}
+	assert.Nil(t, testChain33.GetStorePruneStatus(&types.ReqNil{}, &testResult))
+	assert.Equal(t, status, testResult)
+
+	paused := &types.StorePruneStatus{Enable: true, Paused: true}
+	api.On("SetStorePrunePause", &types.ReqStorePrune{Pause: true}).Return(paused, nil)
+	assert.Equal(t, types.ErrInvalidParam, testChain33.SetStorePrunePause(nil, &testResult))
+	assert.Nil(t, testChain33.SetStorePrunePause(&types.ReqStorePrune{Pause: true}, &testResult))
+	assert.Equal(t, paused, testResult)
+}
+
+func TestChain33_BackupDB(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	testChain33 := newTestChain33(api)
+
+	manifest := &types.BackupManifest{Version: 1, Height: 100, Dbs: []*types.BackupDBInfo{{Module: "blockchain", Name: "blockchain"}}}
+	api.On("BackupDB", &types.ReqBackupDB{Dir: "backup"}).Return(manifest, nil)
+	api.On("BackupDB", &types.ReqBackupDB{Dir: "busy"}).Return(nil, types.ErrBackupInProgress)
+	var testResult interface{}
+	assert.Equal(t, types.ErrInvalidParam, testChain33.BackupDB(nil, &testResult))
+	assert.Equal(t, types.ErrInvalidParam, testChain33.BackupDB(&types.ReqBackupDB{}, &testResult))
+	assert.Equal(t, types.ErrBackupInProgress, testChain33.BackupDB(&types.ReqBackupDB{Dir: "busy"}, &testResult))
+	assert.Nil(t, testChain33.BackupDB(&types.ReqBackupDB{Dir: "backup"}, &testResult))
+	assert.Equal(t, manifest, testResult)
+}
+
 func TestChain33_GetHeaders(t *testing.T) {
 	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
 	api := new(mocks.QueueProtocolAPI)
@@ -1460,6 +1534,52 @@ func TestChain33_Query(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestChain33_QueryHistory(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	client := newTestChain33(api)
+	var testResult interface{}
+	payload := []byte(`{"addr":"1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"}`)
+	param := types.Encode(&types.ReqAddr{Addr: "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"})
+
+	in := rpctypes.QueryHistory4Jrpc{Execer: "unknown", FuncName: "GetAddrReciver", Payload: payload}
+	assert.Equal(t, types.ErrNotSupport, client.QueryHistory(in, &testResult))
+
+	//指定状态哈希
+	stateHash := []byte("0123456789abcdef0123456789abcdef")
+	in = rpctypes.QueryHistory4Jrpc{Execer: "coins", FuncName: "GetAddrReciver", Payload: payload, StateHash: common.ToHex(stateHash)}
+	api.On("QueryChain", &types.ChainExecutor{Driver: "coins", FuncName: "GetAddrReciver", StateHash: stateHash, Param: param}).Return(&types.Int64{Data: 10}, nil).Once()
+	assert.Nil(t, client.QueryHistory(in, &testResult))
+	assert.Equal(t, `{"data":"10"}`, string(testResult.(json.RawMessage)))
+
+	//通过高度获取状态哈希, 状态已经被裁剪
+	hisHash := []byte("fedcba9876543210fedcba9876543210")
+	api.On("GetHeaders", &types.ReqBlocks{Start: 5, End: 5}).Return(&types.Headers{Items: []*types.Header{{Height: 5, StateHash: hisHash}}}, nil).Once()
+	extra := types.Encode(&types.LocalDBHeight{Enable: true, Height: 5})
+	api.On("QueryChain", &types.ChainExecutor{Driver: "coins", FuncName: "GetAddrReciver", StateHash: hisHash, Param: param, Extra: extra}).Return(nil, types.ErrStatePruned).Once()
+	height := int64(5)
+	in = rpctypes.QueryHistory4Jrpc{Execer: "coins", FuncName: "GetAddrReciver", Payload: payload, Height: &height}
+	assert.Equal(t, types.ErrStatePruned, client.QueryHistory(in, &testResult))
+
+	api.On("GetHeaders", &types.ReqBlocks{Start: 6, End: 6}).Return(&types.Headers{}, nil).Once()
+	height = 6
+	assert.Equal(t, types.ErrHeightNotExist, client.QueryHistory(in, &testResult))
+
+	//高度小于0查询最新状态
+	api.On("QueryChain", &types.ChainExecutor{Driver: "coins", FuncName: "GetAddrReciver", Param: param}).Return(&types.Int64{Data: 20}, nil).Twice()
+	height = -1
+	assert.Nil(t, client.QueryHistory(in, &testResult))
+	assert.Equal(t, `{"data":"20"}`, string(testResult.(json.RawMessage)))
+
+	//没有指定高度查询最新状态
+	in = rpctypes.QueryHistory4Jrpc{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"execer":"coins","funcName":"GetAddrReciver","payload":{"addr":"1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"}}`), &in))
+	assert.Nil(t, in.Height)
+	assert.Nil(t, client.QueryHistory(in, &testResult))
+	assert.Equal(t, `{"data":"20"}`, string(testResult.(json.RawMessage)))
+}
+
 func TestChain33_DumpPrivkey(t *testing.T) {
 	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
 	api := new(mocks.QueueProtocolAPI)
@@ -1539,6 +1659,30 @@ func TestChain33_CloseQueue(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestChain33_SetLogLevel(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	api := new(mocks.QueueProtocolAPI)
+	api.On("GetConfig", mock.Anything).Return(cfg)
+	client := newTestChain33(api)
+	var testResult interface{}
+	err := client.SetLogLevel(nil, &testResult)
+	assert.Equal(t, types.ErrInvalidParam, err)
+
+	err = client.SetLogLevel(&rpctypes.ReqLogLevel{Module: "jrpc_test", Level: "debug"}, &testResult)
+	assert.NoError(t, err)
+	assert.True(t, testResult.(*types.Reply).IsOk)
+
+	err = client.GetLogLevels(nil, &testResult)
+	assert.NoError(t, err)
+	assert.Equal(t, "dbug", testResult.(*rpctypes.LogLevels).ModuleLevels["jrpc_test"])
+
+	err = client.SetLogLevel(&rpctypes.ReqLogLevel{Module: "jrpc_test"}, &testResult)
+	assert.NoError(t, err)
+	err = client.GetLogLevels(nil, &testResult)
+	assert.NoError(t, err)
+	assert.Equal(t, "", testResult.(*rpctypes.LogLevels).ModuleLevels["jrpc_test"])
+}
+
 func TestChain33_AddSeqCallBack(t *testing.T) {
 	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
 	api := new(mocks.QueueProtocolAPI)
@@ -297,6 +297,16 @@ type Query4Jrpc struct {
 	Payload  json.RawMessage `json:"payload"`
 }
 
+// QueryHistory4Jrpc 在历史状态上查询, stateHash 优先, 否则查询 height 高度的状态, 没有指定 height 或者 height 小于0查询最新状态
+// 按照 height 查询并且执行器开启了 localmvcc 时, localdb 也读取 height 高度的数据
+type QueryHistory4Jrpc struct {
+	Execer    string          `json:"execer"`
+	FuncName  string          `json:"funcName"`
+	Payload   json.RawMessage `json:"payload"`
+	Height    *int64          `json:"height,omitempty"`
+	StateHash string          `json:"stateHash"`
+}
+
 // ChainExecutor chain executor
 type ChainExecutor struct {
 	Driver    string          `json:"execer"`
@@ -320,6 +330,7 @@ type NodeNetinfo struct {
 	Service      bool   `json:"service"`
 	Outbounds    int32  `json:"outbounds"`
 	Inbounds     int32  `json:"inbounds"`
+	Reachability string `json:"reachability,omitempty"`
 }
 
 // ReplyCacheTxList reply cache tx list
@@ -404,3 +415,16 @@ type ReWriteRawTx struct {
 	Expire string `json:"expire"`
 	Index  int32  `json:"index"`
 }
+
+// ReqLogLevel 设置模块日志级别, level为空时恢复使用默认级别
+type ReqLogLevel struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+// LogLevels 当前生效的日志级别
+type LogLevels struct {
+	Loglevel        string            `json:"loglevel"`
+	LogConsoleLevel string            `json:"logConsoleLevel"`
+	ModuleLevels    map[string]string `json:"moduleLevels"`
+}
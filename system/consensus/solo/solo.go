@@ -8,6 +8,7 @@ package solo
 import (
 	"time"
 
+	"github.com/33cn/chain33/blockchain"
 	log "github.com/33cn/chain33/common/log/log15"
 	"github.com/33cn/chain33/common/merkle"
 	"github.com/33cn/chain33/queue"
@@ -28,6 +29,7 @@ type Client struct {
 func init() {
 	drivers.Reg("solo", New)
 	drivers.QueryData.Register("solo", &Client{})
+	blockchain.RegisterHeaderChecker("solo", checkHeader)
 }
 
 type subConfig struct {
@@ -86,8 +88,18 @@ func (client *Client) ProcEvent(msg *queue.Message) bool {
 	return false
 }
 
-//CheckBlock solo不检查任何的交易
+//CheckBlock solo不检查任何的交易, 只检查区块头的难度
 func (client *Client) CheckBlock(parent *types.Block, current *types.BlockDetail) error {
+	cfg := client.GetAPI().GetConfig()
+	return checkHeader(cfg, parent.GetHeader(cfg), current.Block.GetHeader(cfg))
+}
+
+//checkHeader solo 挖矿固定难度, 难度不一致的区块头会在分叉选择时得到错误的工作量
+//同步区块头时也使用该函数校验, 不需要等到下载区块体
+func checkHeader(cfg *types.Chain33Config, parent, header *types.Header) error {
+	if header.Height > 0 && header.Difficulty != cfg.GetP(0).PowLimitBits {
+		return types.ErrBlockHeaderDifficulty
+	}
 	return nil
 }
 
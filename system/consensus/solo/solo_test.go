@@ -36,6 +36,18 @@ func TestSolo(t *testing.T) {
 	mock33.WaitHeight(2)
 }
 
+func TestCheckHeader(t *testing.T) {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	parent := &types.Header{Height: 1, Difficulty: cfg.GetP(0).PowLimitBits}
+	header := &types.Header{Height: 2, Difficulty: cfg.GetP(0).PowLimitBits}
+	assert.Nil(t, checkHeader(cfg, parent, header))
+	header.Difficulty++
+	assert.Equal(t, types.ErrBlockHeaderDifficulty, checkHeader(cfg, parent, header))
+	header.Difficulty = 0
+	header.Height = 0
+	assert.Nil(t, checkHeader(cfg, parent, header))
+}
+
 func BenchmarkSolo(b *testing.B) {
 	cfg := testnode.GetDefaultConfig()
 	subcfg := cfg.GetSubConfig()
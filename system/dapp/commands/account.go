@@ -119,6 +119,28 @@ func addBalanceFlags(cmd *cobra.Command) {
 	cmd.Flags().IntP("height", "", -1, "block height")
 }
 
+// getStateHashByHeight 获取区块高度对应的状态哈希, 高度小于0返回空, 即查询最新状态
+func getStateHashByHeight(rpcLaddr string, height int64) (string, error) {
+	if height < 0 {
+		return "", nil
+	}
+	params := types.ReqBlocks{
+		Start:    height,
+		End:      height,
+		IsDetail: false,
+	}
+	var res rpctypes.Headers
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetHeaders", params, &res)
+	_, err := ctx.RunResult()
+	if err != nil {
+		return "", err
+	}
+	if len(res.Items) == 0 {
+		return "", types.ErrHeightNotExist
+	}
+	return res.Items[0].StateHash, nil
+}
+
 func getExecuterNameString() string {
 	str := "executer name (only "
 	allowExeName := types.AllowUserExec
@@ -154,22 +176,10 @@ func balance(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	stateHash := ""
-	if height >= 0 {
-		params := types.ReqBlocks{
-			Start:    int64(height),
-			End:      int64(height),
-			IsDetail: false,
-		}
-		var res rpctypes.Headers
-		ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetHeaders", params, &res)
-		_, err := ctx.RunResult()
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return
-		}
-		h := res.Items[0]
-		stateHash = h.StateHash
+	stateHash, err := getStateHashByHeight(rpcLaddr, int64(height))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
 	}
 
 	if execer == "" {
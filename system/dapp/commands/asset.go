@@ -74,22 +74,10 @@ func assetBalance(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	stateHash := ""
-	if height >= 0 {
-		params := types.ReqBlocks{
-			Start:    int64(height),
-			End:      int64(height),
-			IsDetail: false,
-		}
-		var res rpcTypes.Headers
-		ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetHeaders", params, &res)
-		_, err := ctx.RunResult()
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			return
-		}
-		h := res.Items[0]
-		stateHash = h.StateHash
+	stateHash, err := getStateHashByHeight(rpcLaddr, int64(height))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
 	}
 
 	var addrs []string
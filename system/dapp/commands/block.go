@@ -10,10 +10,16 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/33cn/chain33/blockchain"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/executor"
+	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/rpc/jsonclient"
 	rpctypes "github.com/33cn/chain33/rpc/types"
+	"github.com/33cn/chain33/store"
 	commandtypes "github.com/33cn/chain33/system/dapp/commands/types"
 	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
 	"github.com/spf13/cobra"
 )
 
@@ -38,6 +44,8 @@ func BlockCmd() *cobra.Command {
 		AddBlockSeqCallBackCmd(),
 		ListBlockSeqCallBackCmd(),
 		GetSeqCallBackLastNumCmd(),
+		ExportBlockFileCmd(),
+		ImportBlockFileCmd(),
 	)
 
 	return cmd
@@ -412,3 +420,109 @@ func getSeqCallBackLastNumCmd(cmd *cobra.Command, args []string) {
 	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetSeqCallBackLastNum", params, &res)
 	ctx.Run()
 }
+
+// ExportBlockFileCmd export blocks of a stopped node to block file
+func ExportBlockFileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export main chain blocks of a stopped node to chunk files",
+		Run:   exportBlockFile,
+	}
+	addOfflineChainFlags(cmd)
+	cmd.Flags().StringP("dir", "o", "", "export dir, - for gzip block stream to stdout")
+	cmd.MarkFlagRequired("dir")
+	cmd.Flags().Int64P("start", "s", 0, "block start height")
+	cmd.Flags().Int64P("end", "e", -1, "block end height, default current height - 1024")
+	cmd.Flags().Int64P("chunk", "c", blockchain.DefaultChunkBlocks, "blocks per chunk file")
+	return cmd
+}
+
+// ImportBlockFileCmd import blocks from block file into a stopped node
+func ImportBlockFileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import blocks from export dir, chunk file or stdin into a stopped node",
+		Run:   importBlockFile,
+	}
+	addOfflineChainFlags(cmd)
+	cmd.Flags().StringP("file", "i", "", "export dir with manifest.json, chunk file or concatenated gzip block stream, - to read the stream from stdin")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func addOfflineChainFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("config", "f", "chain33.toml", "config file of the stopped node")
+	cmd.Flags().StringP("datadir", "d", "", "data dir of the stopped node")
+}
+
+//openOfflineChain 在进程内打开已停止节点的数据目录, 导入区块时同时启动执行器和存储模块
+func openOfflineChain(cmd *cobra.Command, withExec bool) (*blockchain.BlockChain, func()) {
+	configPath, _ := cmd.Flags().GetString("config")
+	datadir, _ := cmd.Flags().GetString("datadir")
+	cfg := types.NewChain33Config(types.ReadFile(configPath))
+	mcfg := cfg.GetModuleConfig()
+	if datadir != "" {
+		util.ResetDatadir(mcfg, datadir)
+	}
+	mcfg.Consensus.Minerstart = false
+
+	q := queue.New("channel")
+	q.SetConfig(cfg)
+	chain := blockchain.New(cfg)
+	chain.SetQueueClient(q.Client())
+	modules := []queue.Module{chain}
+	if withExec {
+		exec := executor.New(cfg)
+		exec.SetQueueClient(q.Client())
+		s := store.New(cfg)
+		s.SetQueueClient(q.Client())
+		modules = append(modules, exec, s)
+		for _, key := range []string{"mempool", "consensus", "wallet", "p2p"} {
+			m := &util.MockModule{Key: key}
+			m.SetQueueClient(q.Client())
+			modules = append(modules, m)
+		}
+	}
+	return chain, func() {
+		for i := len(modules) - 1; i >= 0; i-- {
+			modules[i].Close()
+		}
+		q.Close()
+	}
+}
+
+func exportBlockFile(cmd *cobra.Command, args []string) {
+	dir, _ := cmd.Flags().GetString("dir")
+	start, _ := cmd.Flags().GetInt64("start")
+	end, _ := cmd.Flags().GetInt64("end")
+	chunk, _ := cmd.Flags().GetInt64("chunk")
+	if dir == "-" {
+		//区块流写到标准输出, 日志改为输出到标准错误
+		log.Root().SetHandler(log.LvlFilterHandler(log.LvlError, log.StderrHandler))
+	}
+
+	chain, closer := openOfflineChain(cmd, false)
+	defer closer()
+	var err error
+	if dir == "-" {
+		err = chain.ExportBlockStream(os.Stdout, start, end)
+	} else {
+		err = chain.ExportBlockFile(dir, start, end, chunk)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func importBlockFile(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+
+	chain, closer := openOfflineChain(cmd, true)
+	defer closer()
+	err := chain.ImportBlockFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println("import done, height:", chain.GetBlockHeight())
+}
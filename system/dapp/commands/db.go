@@ -0,0 +1,51 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/33cn/chain33/rpc/jsonclient"
+	"github.com/33cn/chain33/types"
+	"github.com/spf13/cobra"
+)
+
+// DBCmd node database command
+func DBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Node database operation",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	cmd.AddCommand(
+		BackupDBCmd(),
+	)
+
+	return cmd
+}
+
+// BackupDBCmd backup the databases of all modules online
+func BackupDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Backup blockchain, store, wallet and p2p databases at block boundary, restore with the restoredb tool",
+		Run:   backupDB,
+	}
+	addBackupDBFlags(cmd)
+	return cmd
+}
+
+func addBackupDBFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("dir", "d", "", "backup dir on the node, must not exist or be empty")
+	cmd.MarkFlagRequired("dir")
+}
+
+func backupDB(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	dir, _ := cmd.Flags().GetString("dir")
+	params := types.ReqBackupDB{Dir: dir}
+	var res types.BackupManifest
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.BackupDB", params, &res)
+	ctx.Run()
+}
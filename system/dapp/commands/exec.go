@@ -6,6 +6,7 @@ package commands
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
@@ -13,6 +14,8 @@ import (
 	"time"
 
 	"github.com/33cn/chain33/common/address"
+	"github.com/33cn/chain33/rpc/jsonclient"
+	rpctypes "github.com/33cn/chain33/rpc/types"
 	"github.com/33cn/chain33/types"
 	"github.com/spf13/cobra"
 )
@@ -28,6 +31,7 @@ func ExecCmd() *cobra.Command {
 	cmd.AddCommand(
 		GetExecAddrCmd(),
 		UserDataCmd(),
+		QueryExecCmd(),
 	)
 
 	return cmd
@@ -60,6 +64,53 @@ func getAddrByExec(cmd *cobra.Command, args []string) {
 	fmt.Println(result)
 }
 
+// QueryExecCmd  query executor at latest or history state
+func QueryExecCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "Query executor, at history state if height or state hash set",
+		Run:   queryExec,
+	}
+	addQueryExecFlags(cmd)
+	return cmd
+}
+
+func addQueryExecFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("exec", "e", "", "executor name")
+	cmd.MarkFlagRequired("exec")
+	cmd.Flags().StringP("funcName", "n", "", "query function name, without Query_ prefix")
+	cmd.MarkFlagRequired("funcName")
+	cmd.Flags().StringP("payload", "p", "{}", "query payload in json")
+	cmd.Flags().Int64P("height", "", -1, "block height of the state, default latest")
+	cmd.Flags().StringP("state_hash", "s", "", "state hash to query, prior to height")
+}
+
+func queryExec(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	execer, _ := cmd.Flags().GetString("exec")
+	funcName, _ := cmd.Flags().GetString("funcName")
+	payload, _ := cmd.Flags().GetString("payload")
+	height, _ := cmd.Flags().GetInt64("height")
+	stateHash, _ := cmd.Flags().GetString("state_hash")
+
+	if !json.Valid([]byte(payload)) {
+		fmt.Fprintln(os.Stderr, "payload is not valid json")
+		return
+	}
+	params := rpctypes.QueryHistory4Jrpc{
+		Execer:    execer,
+		FuncName:  funcName,
+		Payload:   json.RawMessage(payload),
+		StateHash: stateHash,
+	}
+	if height >= 0 {
+		params.Height = &height
+	}
+	var res json.RawMessage
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.QueryHistory", params, &res)
+	ctx.Run()
+}
+
 // UserDataCmd  create user data
 func UserDataCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -0,0 +1,75 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/33cn/chain33/rpc/jsonclient"
+	rpctypes "github.com/33cn/chain33/rpc/types"
+	"github.com/spf13/cobra"
+)
+
+// LogCmd log command
+func LogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Node log operation",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	cmd.AddCommand(
+		SetLogLevelCmd(),
+		GetLogLevelsCmd(),
+	)
+
+	return cmd
+}
+
+// SetLogLevelCmd set module log level at runtime
+func SetLogLevelCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set_level",
+		Short: "Set log level of module, sub modules inherit it",
+		Run:   setLogLevel,
+	}
+	addSetLogLevelFlags(cmd)
+	return cmd
+}
+
+func addSetLogLevelFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("module", "m", "", "module name, e.g. blockchain, p2p, p2p.dht")
+	cmd.MarkFlagRequired("module")
+
+	cmd.Flags().StringP("level", "l", "", "log level, debug/info/warn/error/crit, empty to reset to default level")
+}
+
+func setLogLevel(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	module, _ := cmd.Flags().GetString("module")
+	level, _ := cmd.Flags().GetString("level")
+	params := rpctypes.ReqLogLevel{
+		Module: module,
+		Level:  level,
+	}
+	var res rpctypes.Reply
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.SetLogLevel", params, &res)
+	ctx.Run()
+}
+
+// GetLogLevelsCmd get log levels in effect
+func GetLogLevelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "levels",
+		Short: "Get default and module log levels",
+		Run:   getLogLevels,
+	}
+	return cmd
+}
+
+func getLogLevels(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	var res rpctypes.LogLevels
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetLogLevels", nil, &res)
+	ctx.Run()
+}
@@ -24,9 +24,13 @@ func NetCmd() *cobra.Command {
 		GetPeerInfoCmd(),
 		IsClockSyncCmd(),
 		IsSyncCmd(),
+		GetSyncProgressCmd(),
 		GetNetInfoCmd(),
 		GetFatalFailureCmd(),
 		GetTimeStausCmd(),
+		BanPeerCmd(),
+		UnbanPeerCmd(),
+		GetBannedPeersCmd(),
 	)
 
 	return cmd
@@ -86,6 +90,23 @@ func isSync(cmd *cobra.Command, args []string) {
 	ctx.Run()
 }
 
+// GetSyncProgressCmd get block sync progress
+func GetSyncProgressCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync_progress",
+		Short: "Get block sync progress of headers, bodies and executed blocks",
+		Run:   syncProgress,
+	}
+	return cmd
+}
+
+func syncProgress(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	var res types.SyncProgress
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetSyncProgress", nil, &res)
+	ctx.Run()
+}
+
 // GetNetInfoCmd get net info
 func GetNetInfoCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -139,3 +160,73 @@ func timestatus(cmd *cobra.Command, args []string) {
 	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetTimeStatus", nil, &res)
 	ctx.Run()
 }
+
+// BanPeerCmd ban peer
+func BanPeerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ban",
+		Short: "Ban peer and disconnect it",
+		Run:   banPeer,
+	}
+	cmd.Flags().StringP("pid", "p", "", "peer id")
+	cmd.MarkFlagRequired("pid")
+	cmd.Flags().Int64P("duration", "d", 0, "ban duration in seconds, 0 means default duration which grows with ban count")
+	cmd.Flags().StringP("reason", "r", "", "ban reason")
+	cmd.Flags().StringP("type", "t", "", "p2p type, gossip or dht")
+	return cmd
+}
+
+func banPeer(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	pid, _ := cmd.Flags().GetString("pid")
+	duration, _ := cmd.Flags().GetInt64("duration")
+	reason, _ := cmd.Flags().GetString("reason")
+	p2pty, _ := cmd.Flags().GetString("type")
+	req := types.ReqBanPeer{Pid: pid, Duration: duration, Reason: reason, P2PType: p2pty}
+	var res rpctypes.Reply
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.BanPeer", req, &res)
+	ctx.Run()
+}
+
+// UnbanPeerCmd unban peer
+func UnbanPeerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unban",
+		Short: "Unban peer",
+		Run:   unbanPeer,
+	}
+	cmd.Flags().StringP("pid", "p", "", "peer id")
+	cmd.MarkFlagRequired("pid")
+	cmd.Flags().StringP("type", "t", "", "p2p type, gossip or dht")
+	return cmd
+}
+
+func unbanPeer(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	pid, _ := cmd.Flags().GetString("pid")
+	p2pty, _ := cmd.Flags().GetString("type")
+	req := types.ReqBanPeer{Pid: pid, P2PType: p2pty}
+	var res rpctypes.Reply
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.UnbanPeer", req, &res)
+	ctx.Run()
+}
+
+// GetBannedPeersCmd get banned peers
+func GetBannedPeersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list_banned",
+		Short: "Get banned peers",
+		Run:   bannedPeers,
+	}
+	cmd.Flags().StringP("type", "t", "", "p2p type, gossip or dht")
+	return cmd
+}
+
+func bannedPeers(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	p2pty, _ := cmd.Flags().GetString("type")
+	req := types.ReqBannedPeers{P2PType: p2pty}
+	var res types.BannedPeers
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetBannedPeers", req, &res)
+	ctx.Run()
+}
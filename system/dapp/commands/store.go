@@ -0,0 +1,81 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/33cn/chain33/rpc/jsonclient"
+	"github.com/33cn/chain33/types"
+	"github.com/spf13/cobra"
+)
+
+// StoreCmd store command
+func StoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "store",
+		Short: "State store operation",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	cmd.AddCommand(
+		GetPruneStatusCmd(),
+		PausePruneCmd(),
+		ResumePruneCmd(),
+	)
+
+	return cmd
+}
+
+// GetPruneStatusCmd get mavl state pruning progress
+func GetPruneStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune_status",
+		Short: "Get state pruning progress, pruned height, reclaimed nodes and backlog",
+		Run:   getPruneStatus,
+	}
+	return cmd
+}
+
+func getPruneStatus(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	var res types.StorePruneStatus
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.GetStorePruneStatus", nil, &res)
+	ctx.Run()
+}
+
+// PausePruneCmd pause mavl state pruning until resumed or node restarted
+func PausePruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune_pause",
+		Short: "Pause state pruning until resumed or node restarted",
+		Run:   pausePrune,
+	}
+	return cmd
+}
+
+func pausePrune(cmd *cobra.Command, args []string) {
+	setPrunePause(cmd, true)
+}
+
+// ResumePruneCmd resume mavl state pruning
+func ResumePruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune_resume",
+		Short: "Resume state pruning",
+		Run:   resumePrune,
+	}
+	return cmd
+}
+
+func resumePrune(cmd *cobra.Command, args []string) {
+	setPrunePause(cmd, false)
+}
+
+func setPrunePause(cmd *cobra.Command, pause bool) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	params := types.ReqStorePrune{Pause: pause}
+	var res types.StorePruneStatus
+	ctx := jsonclient.NewRPCCtx(rpcLaddr, "Chain33.SetStorePrunePause", params, &res)
+	ctx.Run()
+}
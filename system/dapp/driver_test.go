@@ -254,4 +254,11 @@ func TestDriverBase_Query(t *testing.T) {
 
 	_, err = demo.Query("", nil)
 	assert.Equal(t, types.ErrActionNotSupport, err)
+
+	//DriverBase中定义的查询接口, 所有执行器都支持
+	assert.Contains(t, types.ListMethod(demo), "Query_GetKeyHistory")
+	_, err = demo.Query_GetKeyHistory(&types.ReqKeyHistory{Count: int32(types.MaxBlockCountPerTime) + 1})
+	assert.Equal(t, types.ErrMaxCountPerTime, err)
+	_, err = demo.Query_GetKeyHistory(&types.ReqKeyHistory{Key: []byte("LODB-demo-a")})
+	assert.Equal(t, types.ErrNotSupport, err)
 }
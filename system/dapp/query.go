@@ -5,13 +5,18 @@
 package dapp
 
 import (
+	"bytes"
 	"errors"
 	"reflect"
 
+	dbm "github.com/33cn/chain33/common/db"
 	"github.com/33cn/chain33/types"
 	"github.com/golang/protobuf/proto"
 )
 
+//localMVCCKeyPrefix localmvcc 保存历史版本的localdb key前缀
+var localMVCCKeyPrefix = append(append([]byte{}, types.LocalPrefix...), '-')
+
 // GetTxsByAddr find all transactions in this address by the addr prefix
 // query transaction are placed by default ：coins in the query
 func (d *DriverBase) GetTxsByAddr(addr *types.ReqAddr) (types.Message, error) {
@@ -95,6 +100,36 @@ func (d *DriverBase) GetAddrTxsCount(reqkey *types.ReqKey) (types.Message, error
 	return &counts, nil
 }
 
+// Query_GetKeyHistory query the versions of the localdb key between the heights, localmvcc must be enabled.
+// it is defined in DriverBase, so every executor supports it.
+// localmvcc only keeps the versions of the kvs set by the ExecLocal of executors (keys with the LODB- prefix),
+// the kvs of other plugins (txindex, addrindex, fee, stat...) have no history, so the query of these keys is rejected
+func (d *DriverBase) Query_GetKeyHistory(req *types.ReqKeyHistory) (types.Message, error) {
+	if req.Count < 0 || int64(req.Count) > types.MaxBlockCountPerTime {
+		return nil, types.ErrMaxCountPerTime
+	}
+	if !bytes.HasPrefix(req.Key, localMVCCKeyPrefix) {
+		return nil, types.ErrLocalPrefix
+	}
+	count := req.Count
+	if count == 0 {
+		count = int32(types.MaxBlockCountPerTime)
+	}
+	db := d.GetLocalDB()
+	_, err := db.Get(types.FlagKeyLocalMVCC)
+	if err == types.ErrNotFound {
+		return nil, types.ErrNotSupport
+	}
+	if err != nil {
+		return nil, err
+	}
+	versions, err := dbm.NewLocalMVCC(db).GetKeyHistory(req.Key, req.FromHeight, req.ToHeight, count)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ReplyKeyHistory{Versions: versions}, nil
+}
+
 // Query defines query function
 func (d *DriverBase) Query(funcname string, params []byte) (msg types.Message, err error) {
 	funcmap := d.child.GetFuncMap()
@@ -5,6 +5,7 @@
 package mempool
 
 import (
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/types"
 )
 
@@ -65,6 +66,7 @@ func (cache *txCache) Remove(hash string) {
 	cache.LastTxCache.Remove(tx)
 	cache.totalFee -= tx.Fee
 	cache.SHashTxCache.Remove(tx)
+	cache.updateMetrics()
 }
 
 //Exist 是否存在
@@ -120,9 +122,16 @@ func (cache *txCache) Push(tx *types.Transaction) error {
 	cache.LastTxCache.Push(tx)
 	cache.totalFee += tx.Fee
 	cache.SHashTxCache.Push(tx)
+	cache.updateMetrics()
 	return nil
 }
 
+//updateMetrics 更新mempool的交易个数和占用空间
+func (cache *txCache) updateMetrics() {
+	metrics.MempoolSize.Update(int64(cache.qcache.Size()))
+	metrics.MempoolBytes.Update(cache.qcache.GetCacheBytes())
+}
+
 func (cache *txCache) removeExpiredTx(cfg *types.Chain33Config, height, blocktime int64) {
 	var txs []string
 	cache.qcache.Walk(0, func(tx *Item) bool {
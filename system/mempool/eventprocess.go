@@ -151,6 +151,10 @@ func (mem *Mempool) eventTxList(msg *queue.Message) {
 // EventAddBlock 将添加到区块内的交易从mempool中删除
 func (mem *Mempool) eventAddBlock(msg *queue.Message) {
 	block := msg.GetData().(*types.BlockDetail).Block
+	span := msg.StartSpan("mempool.AddBlock")
+	defer span.End()
+	span.SetAttribute("height", block.Height)
+	span.SetAttribute("txs", len(block.Txs))
 	if block.Height > mem.Height() || (block.Height == 0 && mem.Height() == 0) {
 		header := &types.Header{}
 		header.BlockTime = block.BlockTime
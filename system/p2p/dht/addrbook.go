@@ -8,6 +8,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/33cn/chain33/system/p2p/dht/manage"
 	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
 
 	"github.com/33cn/chain33/common/db"
@@ -17,10 +18,20 @@ import (
 )
 
 const (
-	addrkeyTag = "mutiaddrs"
-	privKeyTag = "privkey"
+	addrkeyTag   = "mutiaddrs"
+	privKeyTag   = "privkey"
+	banKeyPrefix = "banpeer-"
+	allowListKey = "allowlist"
 )
 
+//登记地址簿数据库中的key前缀, 用于dbinspect解析数据
+func init() {
+	db.RegisterKeyPrefix("p2p", addrkeyTag, "节点地址列表(json)", nil)
+	db.RegisterKeyPrefix("p2p", privKeyTag, "节点私钥(hex)", nil)
+	db.RegisterKeyPrefix("p2p", banKeyPrefix, "禁止连接的节点", func() types.Message { return &types.BannedPeer{} })
+	db.RegisterKeyPrefix("p2p", allowListKey, "最近一次加载的链上白名单", func() types.Message { return &types.ReplyConfig{} })
+}
+
 // AddrBook peer address manager
 type AddrBook struct {
 	mtx     sync.Mutex
@@ -28,6 +39,7 @@ type AddrBook struct {
 	privkey string
 	pubkey  string
 	bookDb  db.DB
+	backup  *db.Backuper
 }
 
 func NewAddrBook(cfg *types.P2P) *AddrBook {
@@ -36,6 +48,7 @@ func NewAddrBook(cfg *types.P2P) *AddrBook {
 	}
 	dbPath := cfg.DbPath + "/" + p2pty.DHTTypeName
 	a.bookDb = db.NewDB("addrbook", a.cfg.Driver, dbPath, a.cfg.DbCache)
+	a.backup = db.NewBackuper(a.bookDb, "addrbook", a.cfg.Driver)
 
 	if !a.loadDb() {
 		a.initKey()
@@ -153,6 +166,53 @@ func (a *AddrBook) SaveAddr(addrinfos []peer.AddrInfo) error {
 
 }
 
+// SaveBan save banned peer
+func (a *AddrBook) SaveBan(ban *types.BannedPeer) error {
+	return a.bookDb.Set([]byte(banKeyPrefix+ban.Pid), types.Encode(ban))
+}
+
+// DeleteBan delete banned peer
+func (a *AddrBook) DeleteBan(pid string) error {
+	return a.bookDb.Delete([]byte(banKeyPrefix + pid))
+}
+
+// LoadBans load all banned peers
+func (a *AddrBook) LoadBans() []*types.BannedPeer {
+	var bans []*types.BannedPeer
+	values := db.NewListHelper(a.bookDb).PrefixScan([]byte(banKeyPrefix))
+	for _, value := range values {
+		var ban types.BannedPeer
+		if err := types.Decode(value, &ban); err != nil {
+			log.Error("LoadBans", "decode err", err)
+			continue
+		}
+		bans = append(bans, &ban)
+	}
+	return bans
+}
+
+// SaveAllowList 保存最近一次从manage合约加载的白名单, key为manage合约的配置项, value为配置的值
+func (a *AddrBook) SaveAllowList(key, value string) error {
+	return a.bookDb.Set([]byte(allowListKey), types.Encode(&types.ReplyConfig{Key: key, Value: value}))
+}
+
+// LoadAllowList 加载保存的白名单, 没有保存或者配置项key不一致时返回false
+func (a *AddrBook) LoadAllowList(key string) ([]string, bool) {
+	value, err := a.bookDb.Get([]byte(allowListKey))
+	if err != nil || len(value) == 0 {
+		return nil, false
+	}
+	var config types.ReplyConfig
+	if err = types.Decode(value, &config); err != nil {
+		log.Error("LoadAllowList", "decode err", err)
+		return nil, false
+	}
+	if config.Key != key {
+		return nil, false
+	}
+	return manage.ParseAllowList(config.Value), true
+}
+
 // StoreHostID store host id into file
 func (a *AddrBook) StoreHostID(id peer.ID, path string) {
 	dbPath := path + "/" + p2pty.DHTTypeName
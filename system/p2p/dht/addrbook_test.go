@@ -0,0 +1,53 @@
+package dht
+
+import (
+	"os"
+	"testing"
+
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddrBookBans(t *testing.T) {
+	cfg := types.NewChain33Config(types.ReadFile("../../../cmd/chain33/chain33.test.toml"))
+	datadir := util.ResetDatadir(cfg.GetModuleConfig(), "$TEMP/")
+	defer os.RemoveAll(datadir)
+
+	book := NewAddrBook(cfg.GetModuleConfig().P2P)
+	ban := &types.BannedPeer{Pid: "16Uiu2HAmTest", Expire: 100, Count: 2, Reason: "test"}
+	assert.Nil(t, book.SaveBan(ban))
+	assert.Nil(t, book.SaveBan(&types.BannedPeer{Pid: "16Uiu2HAmTest2"}))
+	bans := book.LoadBans()
+	assert.Equal(t, 2, len(bans))
+	assert.Equal(t, ban.String(), bans[0].String())
+
+	assert.Nil(t, book.DeleteBan(ban.Pid))
+	bans = book.LoadBans()
+	assert.Equal(t, 1, len(bans))
+	assert.Equal(t, "16Uiu2HAmTest2", bans[0].Pid)
+	//私钥等数据不受影响
+	assert.NotNil(t, book.GetPrivkey())
+}
+
+func TestAddrBookAllowList(t *testing.T) {
+	cfg := types.NewChain33Config(types.ReadFile("../../../cmd/chain33/chain33.test.toml"))
+	datadir := util.ResetDatadir(cfg.GetModuleConfig(), "$TEMP/")
+	defer os.RemoveAll(datadir)
+
+	book := NewAddrBook(cfg.GetModuleConfig().P2P)
+	_, ok := book.LoadAllowList("p2p-allow-peers")
+	assert.False(t, ok)
+	assert.Nil(t, book.SaveAllowList("p2p-allow-peers", "[peer1 peer2]"))
+	items, ok := book.LoadAllowList("p2p-allow-peers")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"peer1", "peer2"}, items)
+	//配置项改变之后不使用保存的白名单
+	_, ok = book.LoadAllowList("other")
+	assert.False(t, ok)
+	//空列表同样有效
+	assert.Nil(t, book.SaveAllowList("p2p-allow-peers", "[]"))
+	items, ok = book.LoadAllowList("p2p-allow-peers")
+	assert.True(t, ok)
+	assert.Equal(t, 0, len(items))
+}
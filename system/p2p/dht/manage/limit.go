@@ -0,0 +1,242 @@
+package manage
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// 限速参数
+const (
+	//单次读写的最大字节数, 大块数据分段等待令牌, 避免一次等待过长
+	limitChunkSize = 32 * 1024
+)
+
+// ErrPeerBusy 请求超过对端的频率限制, 请求未被处理
+var ErrPeerBusy = errors.New("ErrPeerBusy")
+
+// tokenBucket 令牌桶, 为nil时表示不限制
+type tokenBucket struct {
+	mtx    sync.Mutex
+	rate   float64 //每秒产生的令牌数
+	burst  float64 //最多积累的令牌数
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// allow 令牌足够时消耗n个令牌, 否则不消耗并返回false
+func (b *tokenBucket) allow(n float64) bool {
+	if b == nil {
+		return true
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.refill(time.Now())
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// reserve 消耗n个令牌, 令牌不足时透支, 返回需要等待的时长
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.refill(time.Now())
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+type peerLimit struct {
+	upload   *tokenBucket
+	download *tokenBucket
+	requests map[string]*tokenBucket
+}
+
+// LimitManager 带宽和请求频率限制, 带宽分别按全局和单个节点限制上传下载速率,
+// 请求频率按节点和协议限制, 超过频率限制的请求由stream handler直接回复限流响应, 请求方收到ErrPeerBusy
+type LimitManager struct {
+	mtx          sync.Mutex
+	host         core.Host
+	upload       *tokenBucket
+	download     *tokenBucket
+	peerUpload   float64
+	peerDownload float64
+	reqRates     map[string]float64
+	peers        map[peer.ID]*peerLimit
+	notifiee     network.Notifiee
+}
+
+// NewLimitManager new limit manager, 带宽单位为KB/s, 0表示不限制
+func NewLimitManager(host core.Host, cfg *p2pty.P2PSubConfig) *LimitManager {
+	l := &LimitManager{
+		host:         host,
+		upload:       newBandwidthBucket(float64(cfg.MaxUploadRate) * 1024),
+		download:     newBandwidthBucket(float64(cfg.MaxDownloadRate) * 1024),
+		peerUpload:   float64(cfg.PeerUploadRate) * 1024,
+		peerDownload: float64(cfg.PeerDownloadRate) * 1024,
+		reqRates:     make(map[string]float64),
+		peers:        make(map[peer.ID]*peerLimit),
+	}
+	//节点断开后清理限速记录
+	l.notifiee = &network.NotifyBundle{DisconnectedF: func(n network.Network, conn network.Conn) {
+		if n.Connectedness(conn.RemotePeer()) == network.Connected {
+			return
+		}
+		l.mtx.Lock()
+		delete(l.peers, conn.RemotePeer())
+		l.mtx.Unlock()
+	}}
+	host.Network().Notify(l.notifiee)
+	return l
+}
+
+// 带宽令牌桶最多积累1秒的流量, 至少可以通过一个分段
+func newBandwidthBucket(rate float64) *tokenBucket {
+	return newTokenBucket(rate, math.Max(rate, limitChunkSize))
+}
+
+// Close close limit manager
+func (l *LimitManager) Close() {
+	if l == nil {
+		return
+	}
+	l.host.Network().StopNotify(l.notifiee)
+}
+
+// SetRequestLimit 设置协议的请求频率限制, rate为单个节点每秒允许的请求次数, 0表示不限制
+func (l *LimitManager) SetRequestLimit(protocol string, rate int32) {
+	if l == nil {
+		return
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if rate <= 0 {
+		delete(l.reqRates, protocol)
+	} else {
+		l.reqRates[protocol] = float64(rate)
+	}
+	for _, limit := range l.peers {
+		delete(limit.requests, protocol)
+	}
+}
+
+func (l *LimitManager) getPeer(pid peer.ID) *peerLimit {
+	limit, ok := l.peers[pid]
+	if !ok {
+		limit = &peerLimit{
+			upload:   newBandwidthBucket(l.peerUpload),
+			download: newBandwidthBucket(l.peerDownload),
+			requests: make(map[string]*tokenBucket),
+		}
+		l.peers[pid] = limit
+	}
+	return limit
+}
+
+// AllowRequest 节点的请求是否在频率限制内, 未设置限制或l为nil时返回true
+func (l *LimitManager) AllowRequest(pid peer.ID, protocol string) bool {
+	if l == nil {
+		return true
+	}
+	l.mtx.Lock()
+	rate, ok := l.reqRates[protocol]
+	if !ok {
+		l.mtx.Unlock()
+		return true
+	}
+	limit := l.getPeer(pid)
+	bucket, ok := limit.requests[protocol]
+	if !ok {
+		bucket = newTokenBucket(rate, math.Max(rate, 1))
+		limit.requests[protocol] = bucket
+	}
+	l.mtx.Unlock()
+	return bucket.allow(1)
+}
+
+// LimitStream 对stream的读写进行带宽限制, 未设置带宽限制时返回原stream
+func (l *LimitManager) LimitStream(s network.Stream) network.Stream {
+	if l == nil || s == nil || (l.upload == nil && l.download == nil && l.peerUpload <= 0 && l.peerDownload <= 0) {
+		return s
+	}
+	return &limitedStream{Stream: s, limiter: l, pid: s.Conn().RemotePeer()}
+}
+
+// wait 等待全局以及节点的带宽令牌
+func (l *LimitManager) wait(pid peer.ID, n int, upload bool) {
+	l.mtx.Lock()
+	limit := l.getPeer(pid)
+	l.mtx.Unlock()
+	global, local := l.download, limit.download
+	if upload {
+		global, local = l.upload, limit.upload
+	}
+	delay := global.reserve(float64(n))
+	if d := local.reserve(float64(n)); d > delay {
+		delay = d
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+type limitedStream struct {
+	network.Stream
+	limiter *LimitManager
+	pid     peer.ID
+}
+
+func (s *limitedStream) Read(p []byte) (int, error) {
+	if len(p) > limitChunkSize {
+		p = p[:limitChunkSize]
+	}
+	n, err := s.Stream.Read(p)
+	if n > 0 {
+		s.limiter.wait(s.pid, n, false)
+	}
+	return n, err
+}
+
+func (s *limitedStream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > limitChunkSize {
+			chunk = chunk[:limitChunkSize]
+		}
+		s.limiter.wait(s.pid, len(chunk), true)
+		n, err := s.Stream.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
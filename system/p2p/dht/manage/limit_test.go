@@ -0,0 +1,106 @@
+package manage
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	"github.com/libp2p/go-libp2p-core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	var nilBucket *tokenBucket
+	assert.True(t, nilBucket.allow(100))
+	assert.Equal(t, time.Duration(0), nilBucket.reserve(100))
+	assert.Nil(t, newTokenBucket(0, 10))
+
+	b := newTokenBucket(10, 2)
+	assert.True(t, b.allow(1))
+	assert.True(t, b.allow(1))
+	assert.False(t, b.allow(1))
+	//透支后需要等待令牌补充
+	assert.True(t, b.reserve(5) > time.Millisecond*400)
+	time.Sleep(time.Millisecond * 200)
+	assert.False(t, b.allow(1))
+}
+
+func TestLimitManagerRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn, err := mocknet.FullMeshConnected(ctx, 3)
+	require.Nil(t, err)
+	host, remote, other := mn.Hosts()[0], mn.Hosts()[1], mn.Hosts()[2]
+	l := NewLimitManager(host, &p2pty.P2PSubConfig{})
+	defer l.Close()
+
+	l.SetRequestLimit("/test/req", 2)
+	l.SetRequestLimit("/test/none", 0)
+	assert.True(t, l.AllowRequest(remote.ID(), "/test/req"))
+	assert.True(t, l.AllowRequest(remote.ID(), "/test/req"))
+	assert.False(t, l.AllowRequest(remote.ID(), "/test/req"))
+	//不同节点和未限制的协议互不影响
+	assert.True(t, l.AllowRequest(other.ID(), "/test/req"))
+	assert.True(t, l.AllowRequest(remote.ID(), "/test/none"))
+	time.Sleep(time.Millisecond * 600)
+	assert.True(t, l.AllowRequest(remote.ID(), "/test/req"))
+
+	//断开连接后清理节点记录
+	require.Nil(t, mn.DisconnectPeers(host.ID(), remote.ID()))
+	for i := 0; i < 50; i++ {
+		l.mtx.Lock()
+		_, ok := l.peers[remote.ID()]
+		l.mtx.Unlock()
+		if !ok {
+			break
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+	l.mtx.Lock()
+	assert.Equal(t, 1, len(l.peers))
+	l.mtx.Unlock()
+
+	var nilManager *LimitManager
+	assert.True(t, nilManager.AllowRequest(remote.ID(), "/test/req"))
+	nilManager.SetRequestLimit("/test/req", 1)
+	nilManager.Close()
+}
+
+func TestLimitManagerBandwidth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn, err := mocknet.FullMeshConnected(ctx, 2)
+	require.Nil(t, err)
+	host, remote := mn.Hosts()[0], mn.Hosts()[1]
+	//单节点上传64KB/s, 首个分段不需要等待
+	l := NewLimitManager(host, &p2pty.P2PSubConfig{PeerUploadRate: 64})
+	defer l.Close()
+	unlimited := NewLimitManager(remote, &p2pty.P2PSubConfig{})
+	defer unlimited.Close()
+
+	received := make(chan int, 1)
+	remote.SetStreamHandler("/test/data", func(s network.Stream) {
+		s = unlimited.LimitStream(s)
+		data, _ := ioutil.ReadAll(s)
+		_ = s.Close()
+		received <- len(data)
+	})
+	s, err := host.NewStream(ctx, remote.ID(), "/test/data")
+	require.Nil(t, err)
+	s = l.LimitStream(s)
+	_, ok := s.(*limitedStream)
+	assert.True(t, ok)
+
+	start := time.Now()
+	n, err := s.Write(make([]byte, 128*1024))
+	assert.Nil(t, err)
+	assert.Equal(t, 128*1024, n)
+	require.Nil(t, s.Close())
+	assert.Equal(t, 128*1024, <-received)
+	//64KB初始令牌, 剩余64KB需要等待约1秒
+	assert.True(t, time.Since(start) > time.Millisecond*800)
+}
@@ -0,0 +1,399 @@
+package manage
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	"github.com/libp2p/go-libp2p"
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/sec"
+	secio "github.com/libp2p/go-libp2p-secio"
+)
+
+// PermissionSecurityID 许可网络的安全传输协议, secio握手之后交换节点证书并校验白名单,
+// 和普通节点的secio协议不兼容, 不在白名单内的节点无法完成握手
+const PermissionSecurityID = "/chain33/secio-permission/1.0.0"
+
+// 许可网络参数
+const (
+	permissionRefresh = time.Second * 30      //重新加载链上白名单的周期
+	certVerifyTimeout = time.Second * 10      //握手时交换证书的超时时间
+	certVerifyTTL     = permissionRefresh * 2 //证书校验结果的有效期, 每次重新加载白名单时重新校验已连接节点的证书并延长
+	certMaxSize       = 1 << 16
+)
+
+var (
+	// ErrPeerNotAllowed 节点不在白名单内且没有有效证书
+	ErrPeerNotAllowed = errors.New("ErrPeerNotAllowed")
+	// ErrInvalidCert 节点证书无效
+	ErrInvalidCert = errors.New("ErrInvalidCert")
+)
+
+// AllowListLoader 加载链上白名单, 元素为节点id或公钥, 无法确定当前白名单时返回错误
+type AllowListLoader func() ([]string, error)
+
+// verifiedCert 握手时校验通过的节点证书
+type verifiedCert struct {
+	cert   *x509.Certificate
+	expire time.Time
+}
+
+// PermissionManager 许可网络, 只接受白名单内的节点或持有ca签发证书的节点连接,
+// 白名单包括配置文件中的节点和manage合约中配置的节点, 链上白名单定期重新加载,
+// 不再允许的节点会被断开连接. 证书的CommonName必须为节点id, 证书过期之后同样断开连接
+type PermissionManager struct {
+	mtx      sync.RWMutex
+	host     core.Host
+	local    peer.ID
+	enabled  bool
+	static   map[peer.ID]bool
+	chain    map[peer.ID]bool
+	verified map[peer.ID]*verifiedCert //证书校验通过的节点
+	loader   AllowListLoader
+	roots    *x509.CertPool
+	cert     []byte //本节点证书, der格式
+	notifiee network.Notifiee
+	done     chan struct{}
+}
+
+// NewPermissionManager new permission manager, local为本节点id, 配置错误时panic, 未开启许可模式时不做任何限制
+func NewPermissionManager(local peer.ID, cfg *p2pty.P2PSubConfig, loader AllowListLoader) *PermissionManager {
+	p := &PermissionManager{
+		local:    local,
+		enabled:  cfg.EnablePermission,
+		static:   make(map[peer.ID]bool),
+		chain:    make(map[peer.ID]bool),
+		verified: make(map[peer.ID]*verifiedCert),
+		done:     make(chan struct{}),
+	}
+	if !p.enabled {
+		return p
+	}
+	for _, item := range cfg.AllowPeers {
+		pid, err := ParseAllowPeer(item)
+		if err != nil {
+			panic(fmt.Sprintf("allow peer %s err %s", item, err))
+		}
+		p.static[pid] = true
+	}
+	if cfg.CACert != "" {
+		p.roots = x509.NewCertPool()
+		for _, cert := range mustLoadCerts(cfg.CACert) {
+			p.roots.AddCert(cert)
+		}
+	}
+	if cfg.NodeCert != "" {
+		certs := mustLoadCerts(cfg.NodeCert)
+		if certs[0].Subject.CommonName != local.Pretty() {
+			panic(fmt.Sprintf("node cert common name %s not match peer id %s", certs[0].Subject.CommonName, local.Pretty()))
+		}
+		p.cert = certs[0].Raw
+	}
+	if cfg.PermissionManageKey != "" {
+		p.loader = loader
+	}
+	return p
+}
+
+// Options 创建host时使用的安全传输选项, 在握手阶段拒绝不允许的节点, 未开启许可模式时为空
+func (p *PermissionManager) Options(priv crypto.PrivKey) []libp2p.Option {
+	if !p.enabled {
+		return nil
+	}
+	tpt, err := secio.New(priv)
+	if err != nil {
+		panic(err)
+	}
+	return []libp2p.Option{libp2p.Security(PermissionSecurityID, &permissionTransport{Transport: tpt, p: p})}
+}
+
+// Start 开始定期加载链上白名单, 外部创建的host没有使用Options时, 连接建立后立即断开不允许的节点
+func (p *PermissionManager) Start(host core.Host) {
+	if !p.enabled {
+		return
+	}
+	p.host = host
+	p.notifiee = &network.NotifyBundle{ConnectedF: func(n network.Network, conn network.Conn) {
+		if pid := conn.RemotePeer(); !p.IsAllowed(pid) {
+			go p.reject(pid, ErrPeerNotAllowed)
+		}
+	}}
+	host.Network().Notify(p.notifiee)
+	go p.monitor()
+}
+
+// ParseAllowPeer 解析白名单元素, 支持节点id或者十六进制的secp256k1公钥
+func ParseAllowPeer(item string) (peer.ID, error) {
+	item = strings.TrimSpace(item)
+	if pid, err := peer.IDB58Decode(item); err == nil {
+		return pid, nil
+	}
+	data, err := common.FromHex(item)
+	if err != nil {
+		return "", err
+	}
+	pub, err := crypto.UnmarshalSecp256k1PublicKey(data)
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPublicKey(pub)
+}
+
+// ParseAllowList 解析manage合约中配置的白名单, 格式为[item1 item2]
+func ParseAllowList(value string) []string {
+	return strings.Fields(strings.Trim(value, "[]"))
+}
+
+func mustLoadCerts(file string) []*x509.Certificate {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(fmt.Sprintf("load cert %s err %s", file, err))
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			panic(fmt.Sprintf("parse cert %s err %s", file, err))
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		panic(fmt.Sprintf("no cert found in %s", file))
+	}
+	return certs
+}
+
+// Close close permission manager
+func (p *PermissionManager) Close() {
+	if !p.enabled || p.host == nil {
+		return
+	}
+	defer func() {
+		if recover() != nil {
+			log.Error("channel reclosed")
+		}
+	}()
+	p.host.Network().StopNotify(p.notifiee)
+	close(p.done)
+}
+
+func (p *PermissionManager) monitor() {
+	ticker := time.NewTicker(permissionRefresh)
+	defer ticker.Stop()
+	for {
+		p.Refresh()
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh 重新加载链上白名单, 重新校验已连接节点的证书, 并断开不再允许的节点
+// 加载失败(比如节点还未同步完成)时保留之前的链上白名单, 加载到空列表时清空链上白名单
+func (p *PermissionManager) Refresh() {
+	if !p.enabled {
+		return
+	}
+	if p.loader != nil {
+		items, err := p.loader()
+		if err != nil {
+			log.Error("PermissionManager", "load allow list err", err)
+		} else {
+			chain := make(map[peer.ID]bool)
+			for _, item := range items {
+				pid, err := ParseAllowPeer(item)
+				if err != nil {
+					log.Error("PermissionManager", "allow peer", item, "err", err)
+					continue
+				}
+				chain[pid] = true
+			}
+			p.mtx.Lock()
+			p.chain = chain
+			p.mtx.Unlock()
+		}
+	}
+	p.reverifyCerts()
+	for _, pid := range p.host.Network().Peers() {
+		if !p.IsAllowed(pid) {
+			p.reject(pid, ErrPeerNotAllowed)
+		}
+	}
+}
+
+// IsAllowed 节点是否允许连接, 未开启许可模式或p为nil时返回true
+func (p *PermissionManager) IsAllowed(pid peer.ID) bool {
+	if p == nil || !p.enabled || pid == p.local {
+		return true
+	}
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	if p.static[pid] || p.chain[pid] {
+		return true
+	}
+	v, ok := p.verified[pid]
+	return ok && time.Now().Before(v.expire)
+}
+
+// reverifyCerts 重新校验证书并延长已连接节点的有效期, 证书失效或者记录过期时删除
+// 已经断开的节点不再延长, 重新连接时在握手阶段校验
+func (p *PermissionManager) reverifyCerts() {
+	now := time.Now()
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for pid, v := range p.verified {
+		if now.After(v.expire) || p.checkCert(v.cert, now) != nil {
+			delete(p.verified, pid)
+			continue
+		}
+		if p.host.Network().Connectedness(pid) == network.Connected {
+			v.expire = now.Add(certVerifyTTL)
+		}
+	}
+}
+
+func (p *PermissionManager) reject(pid peer.ID, err error) {
+	log.Info("PermissionManager", "disconnect peer", pid, "err", err)
+	p.mtx.Lock()
+	delete(p.verified, pid)
+	p.mtx.Unlock()
+	//清除地址避免主动重连
+	p.host.Peerstore().ClearAddrs(pid)
+	_ = p.host.Network().ClosePeer(pid)
+}
+
+// handshake secio握手之后双方互相发送节点证书, 对方不在白名单内时校验证书, 未配置ca或校验失败则拒绝连接
+func (p *PermissionManager) handshake(ctx context.Context, conn sec.SecureConn) error {
+	deadline := time.Now().Add(certVerifyTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+
+	writeErr := make(chan error, 1)
+	go func() {
+		writeErr <- writeCert(conn, p.cert)
+	}()
+	data, err := readCert(conn)
+	if err != nil {
+		return err
+	}
+	if err = <-writeErr; err != nil {
+		return err
+	}
+
+	pid := conn.RemotePeer()
+	if p.IsAllowed(pid) {
+		return nil
+	}
+	if p.roots == nil {
+		return ErrPeerNotAllowed
+	}
+	cert, err := p.verifyCert(pid, data)
+	if err != nil {
+		return err
+	}
+	p.mtx.Lock()
+	p.verified[pid] = &verifiedCert{cert: cert, expire: time.Now().Add(certVerifyTTL)}
+	p.mtx.Unlock()
+	log.Debug("PermissionManager", "cert verified", pid)
+	return nil
+}
+
+func (p *PermissionManager) verifyCert(pid peer.ID, data []byte) (*x509.Certificate, error) {
+	if len(data) == 0 {
+		return nil, ErrPeerNotAllowed
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, ErrInvalidCert
+	}
+	if cert.Subject.CommonName != pid.Pretty() {
+		return nil, ErrInvalidCert
+	}
+	if err = p.checkCert(cert, time.Now()); err != nil {
+		log.Debug("PermissionManager", "verify cert", pid, "err", err)
+		return nil, ErrInvalidCert
+	}
+	return cert, nil
+}
+
+// checkCert 校验证书在now时刻是否有效
+func (p *PermissionManager) checkCert(cert *x509.Certificate, now time.Time) error {
+	_, err := cert.Verify(x509.VerifyOptions{Roots: p.roots, CurrentTime: now, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	return err
+}
+
+// writeCert 证书格式为4字节长度加上der格式的证书, 没有证书时长度为0
+func writeCert(w io.Writer, cert []byte) error {
+	buf := make([]byte, 4+len(cert))
+	binary.BigEndian.PutUint32(buf, uint32(len(cert)))
+	copy(buf[4:], cert)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readCert(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > certMaxSize {
+		return nil, ErrInvalidCert
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// permissionTransport 在secio握手之后校验对方节点是否允许连接
+type permissionTransport struct {
+	*secio.Transport
+	p *PermissionManager
+}
+
+func (t *permissionTransport) SecureInbound(ctx context.Context, insecure net.Conn) (sec.SecureConn, error) {
+	conn, err := t.Transport.SecureInbound(ctx, insecure)
+	if err != nil {
+		return nil, err
+	}
+	return t.check(ctx, conn)
+}
+
+func (t *permissionTransport) SecureOutbound(ctx context.Context, insecure net.Conn, pid peer.ID) (sec.SecureConn, error) {
+	conn, err := t.Transport.SecureOutbound(ctx, insecure, pid)
+	if err != nil {
+		return nil, err
+	}
+	return t.check(ctx, conn)
+}
+
+func (t *permissionTransport) check(ctx context.Context, conn sec.SecureConn) (sec.SecureConn, error) {
+	if err := t.p.handshake(ctx, conn); err != nil {
+		log.Info("PermissionManager", "reject peer", conn.RemotePeer(), "err", err)
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
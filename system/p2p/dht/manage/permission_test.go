@@ -0,0 +1,248 @@
+package manage
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	"github.com/33cn/chain33/types"
+	"github.com/libp2p/go-libp2p"
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//genCert 生成证书, parent为nil时生成自签名的ca证书
+func genCert(t *testing.T, dir, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (string, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	file := filepath.Join(dir, cn+".pem")
+	require.Nil(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	return file, cert, key
+}
+
+func waitDisconnected(mn mocknet.Mocknet, a, b peer.ID) bool {
+	for i := 0; i < 50; i++ {
+		if len(mn.Net(a).ConnsToPeer(b)) == 0 {
+			return true
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+	return false
+}
+
+func TestPermissionManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn, err := mocknet.FullMeshLinked(ctx, 4)
+	require.Nil(t, err)
+	hosts := mn.Hosts()
+	host, static, onChain, other := hosts[0], hosts[1], hosts[2], hosts[3]
+
+	var mtx sync.Mutex
+	chainList := []string{onChain.ID().Pretty()}
+	var loadErr error
+	loader := func() ([]string, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return chainList, loadErr
+	}
+	cfg := &p2pty.P2PSubConfig{
+		EnablePermission:    true,
+		AllowPeers:          []string{static.ID().Pretty()},
+		PermissionManageKey: "p2p-allow-peers",
+	}
+	p := NewPermissionManager(host.ID(), cfg, loader)
+	p.Start(host)
+	defer p.Close()
+	p.Refresh()
+	assert.True(t, p.IsAllowed(static.ID()))
+	assert.True(t, p.IsAllowed(onChain.ID()))
+	assert.False(t, p.IsAllowed(other.ID()))
+
+	//内存网络没有安全握手, 连接建立后断开不允许的节点
+	for _, h := range hosts[1:] {
+		_, err = mn.ConnectPeers(h.ID(), host.ID())
+		require.Nil(t, err)
+	}
+	assert.True(t, waitDisconnected(mn, host.ID(), other.ID()))
+	assert.Equal(t, 1, len(host.Network().ConnsToPeer(static.ID())))
+	assert.Equal(t, 1, len(host.Network().ConnsToPeer(onChain.ID())))
+
+	//加载失败时保留之前的列表
+	mtx.Lock()
+	loadErr = types.ErrNotSync
+	mtx.Unlock()
+	p.Refresh()
+	assert.True(t, p.IsAllowed(onChain.ID()))
+	mtx.Lock()
+	loadErr = nil
+	mtx.Unlock()
+
+	//链上白名单移除后断开连接
+	mtx.Lock()
+	chainList = []string{other.ID().Pretty()}
+	mtx.Unlock()
+	p.Refresh()
+	assert.False(t, p.IsAllowed(onChain.ID()))
+	assert.True(t, p.IsAllowed(other.ID()))
+	assert.True(t, waitDisconnected(mn, host.ID(), onChain.ID()))
+	assert.Equal(t, 1, len(host.Network().ConnsToPeer(static.ID())))
+
+	//链上白名单清空后断开只在链上允许的节点
+	_, err = mn.ConnectPeers(other.ID(), host.ID())
+	require.Nil(t, err)
+	mtx.Lock()
+	chainList = nil
+	mtx.Unlock()
+	p.Refresh()
+	assert.False(t, p.IsAllowed(other.ID()))
+	assert.True(t, waitDisconnected(mn, host.ID(), other.ID()))
+	assert.True(t, p.IsAllowed(static.ID()))
+	assert.Equal(t, 1, len(host.Network().ConnsToPeer(static.ID())))
+
+	var nilManager *PermissionManager
+	assert.True(t, nilManager.IsAllowed(other.ID()))
+	disabled := NewPermissionManager(other.ID(), &p2pty.P2PSubConfig{}, nil)
+	disabled.Start(other)
+	assert.True(t, disabled.IsAllowed(host.ID()))
+	assert.Nil(t, disabled.Options(nil))
+	disabled.Close()
+}
+
+func newPermissionHost(t *testing.T, cfg *p2pty.P2PSubConfig, certFile func(pid peer.ID) string) (core.Host, *PermissionManager) {
+	priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	require.Nil(t, err)
+	pid, err := peer.IDFromPrivateKey(priv)
+	require.Nil(t, err)
+	if certFile != nil {
+		cfg.NodeCert = certFile(pid)
+	}
+	p := NewPermissionManager(pid, cfg, nil)
+	opts := append(p.Options(priv), libp2p.Identity(priv), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	host, err := libp2p.New(context.Background(), opts...)
+	require.Nil(t, err)
+	p.Start(host)
+	return host, p
+}
+
+func TestPermissionHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "permission")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile, ca, caKey := genCert(t, dir, "ca", nil, nil)
+	//证书与节点id不一致
+	badFile, _, _ := genCert(t, dir, "bad", ca, caKey)
+	assert.Panics(t, func() {
+		NewPermissionManager(peer.ID("other"), &p2pty.P2PSubConfig{EnablePermission: true, NodeCert: badFile}, nil)
+	})
+
+	host, p := newPermissionHost(t, &p2pty.P2PSubConfig{EnablePermission: true, CACert: caFile}, nil)
+	defer host.Close()
+	defer p.Close()
+	connect := func(h core.Host) error {
+		return h.Connect(context.Background(), peer.AddrInfo{ID: host.ID(), Addrs: host.Addrs()})
+	}
+
+	//持有ca签发证书的节点在握手时通过校验
+	certPeer, certManager := newPermissionHost(t, &p2pty.P2PSubConfig{EnablePermission: true, AllowPeers: []string{host.ID().Pretty()}}, func(pid peer.ID) string {
+		file, _, _ := genCert(t, dir, pid.Pretty(), ca, caKey)
+		return file
+	})
+	defer certPeer.Close()
+	defer certManager.Close()
+	assert.Nil(t, connect(certPeer))
+	assert.True(t, p.IsAllowed(certPeer.ID()))
+	assert.Equal(t, 1, len(host.Network().ConnsToPeer(certPeer.ID())))
+
+	//没有证书的节点握手失败, 不会建立连接
+	other, otherManager := newPermissionHost(t, &p2pty.P2PSubConfig{EnablePermission: true, AllowPeers: []string{host.ID().Pretty()}}, nil)
+	defer other.Close()
+	defer otherManager.Close()
+	assert.NotNil(t, connect(other))
+	assert.False(t, p.IsAllowed(other.ID()))
+	assert.Equal(t, 0, len(host.Network().ConnsToPeer(other.ID())))
+
+	//没有开启许可模式的节点无法协商安全传输协议
+	normal, err := libp2p.New(context.Background(), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.Nil(t, err)
+	defer normal.Close()
+	assert.NotNil(t, connect(normal))
+	assert.Equal(t, 0, len(host.Network().ConnsToPeer(normal.ID())))
+
+	//重新加载白名单时延长已连接节点的证书校验记录
+	p.mtx.Lock()
+	p.verified[certPeer.ID()].expire = time.Now().Add(time.Second)
+	p.mtx.Unlock()
+	p.Refresh()
+	assert.True(t, p.IsAllowed(certPeer.ID()))
+	p.mtx.RLock()
+	assert.True(t, p.verified[certPeer.ID()].expire.After(time.Now().Add(certVerifyTTL/2)))
+	p.mtx.RUnlock()
+	//校验记录过期之后断开连接
+	p.mtx.Lock()
+	p.verified[certPeer.ID()].expire = time.Now().Add(-time.Second)
+	p.mtx.Unlock()
+	assert.False(t, p.IsAllowed(certPeer.ID()))
+	p.Refresh()
+	p.mtx.RLock()
+	assert.Equal(t, 0, len(p.verified))
+	p.mtx.RUnlock()
+	assert.Equal(t, 0, len(host.Network().ConnsToPeer(certPeer.ID())))
+}
+
+func TestParseAllowPeer(t *testing.T) {
+	priv, pub, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	require.Nil(t, err)
+	id, err := peer.IDFromPrivateKey(priv)
+	require.Nil(t, err)
+	data, err := pub.Bytes()
+	require.Nil(t, err)
+	raw, err := pub.Raw()
+	require.Nil(t, err)
+
+	pid, err := ParseAllowPeer(id.Pretty())
+	assert.Nil(t, err)
+	assert.Equal(t, id, pid)
+	pid, err = ParseAllowPeer(common.ToHex(raw))
+	assert.Nil(t, err)
+	assert.Equal(t, id, pid)
+	_, err = ParseAllowPeer(common.ToHex(data[:10]))
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, ParseAllowList("[a b]"))
+	assert.Equal(t, 0, len(ParseAllowList("[]")))
+}
@@ -0,0 +1,277 @@
+package manage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// 节点评分参数
+const (
+	scoreMax           = 100              //评分上限, 避免长期正常的节点积累过高评分后作恶
+	scoreBanThreshold  = -100             //评分低于该值时封禁节点
+	scoreDecay         = 0.9              //每个衰减周期评分向0衰减的比例
+	scoreDecayToZero   = 0.1              //评分绝对值低于该值时重置为0
+	scoreDecayInterval = time.Minute      //评分衰减周期, 同时检查节点时延
+	scoreHighLatency   = time.Second * 2  //时延超过该值时扣分
+	banBaseDuration    = time.Minute * 10 //首次封禁时长, 之后每次封禁时长翻倍
+	banMaxDuration     = time.Hour * 24   //最长封禁时长
+	banCountDecay      = time.Hour * 24   //解封后每隔该时长, 累计封禁次数减一
+)
+
+// scoreWeights 节点各类行为对应的分值
+var scoreWeights = map[int32]float64{
+	types.PeerGoodMsg:            1,
+	types.PeerFaultInvalidBlock:  -50,
+	types.PeerFaultInvalidTx:     -10,
+	types.PeerFaultTimeout:       -5,
+	types.PeerFaultProtocol:      -20,
+	types.PeerFaultLatency:       -2,
+	types.PeerFaultRateLimit:     -2,
+	types.PeerFaultInvalidHeader: -30,
+}
+
+var (
+	// ErrBanSelf 不能封禁本节点
+	ErrBanSelf = errors.New("ErrBanSelf")
+	// ErrPeerNotBanned 节点未被封禁
+	ErrPeerNotBanned = errors.New("ErrPeerNotBanned")
+)
+
+// BanStore 封禁记录持久化, 节点重启后封禁依然有效
+type BanStore interface {
+	SaveBan(ban *types.BannedPeer) error
+	DeleteBan(pid string) error
+	LoadBans() []*types.BannedPeer
+}
+
+// ScoreManager 节点评分服务, 综合区块/交易校验, 下载超时, 协议违规以及时延等信息对节点评分,
+// 评分过低的节点会被断开连接并封禁一段时间, 封禁时长随累计封禁次数增长, 次数随时间衰减
+type ScoreManager struct {
+	mtx    sync.Mutex
+	host   core.Host
+	store  BanStore
+	scores map[peer.ID]float64
+	//包含已过期但累计次数尚未衰减完的记录
+	bans     map[peer.ID]*types.BannedPeer
+	notifiee network.Notifiee
+	done     chan struct{}
+}
+
+// NewScoreManager new score manager, 从store中加载封禁记录
+func NewScoreManager(host core.Host, store BanStore) *ScoreManager {
+	s := &ScoreManager{
+		host:   host,
+		store:  store,
+		scores: make(map[peer.ID]float64),
+		bans:   make(map[peer.ID]*types.BannedPeer),
+		done:   make(chan struct{}),
+	}
+	for _, ban := range store.LoadBans() {
+		pid, err := peer.IDB58Decode(ban.Pid)
+		if err != nil {
+			log.Error("NewScoreManager", "pid", ban.Pid, "decode err", err)
+			continue
+		}
+		s.bans[pid] = ban
+	}
+	//拒绝被封禁节点的连接
+	s.notifiee = &network.NotifyBundle{ConnectedF: func(n network.Network, conn network.Conn) {
+		if s.IsBanned(conn.RemotePeer()) {
+			log.Debug("ScoreManager", "close banned peer", conn.RemotePeer())
+			go conn.Close()
+		}
+	}}
+	host.Network().Notify(s.notifiee)
+	go s.monitor()
+	return s
+}
+
+// Close close score manager
+func (s *ScoreManager) Close() {
+	defer func() {
+		if recover() != nil {
+			log.Error("channel reclosed")
+		}
+	}()
+	s.host.Network().StopNotify(s.notifiee)
+	close(s.done)
+}
+
+// Report 上报节点行为, ty参考types.PeerFault*, 评分低于阈值时封禁节点, s为nil时忽略
+func (s *ScoreManager) Report(pid peer.ID, ty int32) {
+	weight, ok := scoreWeights[ty]
+	if s == nil || !ok || pid == "" || pid == s.host.ID() {
+		return
+	}
+	s.mtx.Lock()
+	score := math.Min(s.scores[pid]+weight, scoreMax)
+	s.scores[pid] = score
+	s.mtx.Unlock()
+	if ty != types.PeerGoodMsg {
+		log.Debug("ScoreManager", "report pid", pid, "ty", ty, "score", score)
+	}
+
+	if score < scoreBanThreshold {
+		err := s.Ban(pid, 0, fmt.Sprintf("low score %.1f", score))
+		if err != nil {
+			log.Error("ScoreManager", "ban pid", pid, "err", err)
+		}
+	}
+}
+
+// Score 获取节点评分
+func (s *ScoreManager) Score(pid peer.ID) float64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.scores[pid]
+}
+
+// Ban 封禁节点并断开连接, duration为0时根据累计封禁次数计算封禁时长
+func (s *ScoreManager) Ban(pid peer.ID, duration time.Duration, reason string) error {
+	if pid == s.host.ID() {
+		return ErrBanSelf
+	}
+	now := time.Now()
+	s.mtx.Lock()
+	ban := &types.BannedPeer{Pid: pid.Pretty(), Reason: reason, Count: 1}
+	if old, ok := s.bans[pid]; ok {
+		ban.Count += decayBanCount(old, now)
+	}
+	if duration <= 0 {
+		duration = banDuration(ban.Count)
+	}
+	ban.Expire = now.Add(duration).Unix()
+	s.bans[pid] = ban
+	delete(s.scores, pid)
+	s.mtx.Unlock()
+
+	log.Info("ScoreManager", "ban pid", pid, "duration", duration, "count", ban.Count, "reason", reason)
+	//清除地址避免主动重连, 被动连接由notifiee拒绝
+	s.host.Peerstore().ClearAddrs(pid)
+	_ = s.host.Network().ClosePeer(pid)
+	return s.store.SaveBan(ban)
+}
+
+// Unban 解除节点封禁, 同时清除累计封禁次数
+func (s *ScoreManager) Unban(pid peer.ID) error {
+	s.mtx.Lock()
+	ban, ok := s.bans[pid]
+	if !ok || ban.Expire <= time.Now().Unix() {
+		s.mtx.Unlock()
+		return ErrPeerNotBanned
+	}
+	delete(s.bans, pid)
+	s.mtx.Unlock()
+	log.Info("ScoreManager", "unban pid", pid)
+	return s.store.DeleteBan(pid.Pretty())
+}
+
+// IsBanned 节点是否处于封禁期, s为nil时返回false
+func (s *ScoreManager) IsBanned(pid peer.ID) bool {
+	if s == nil {
+		return false
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ban, ok := s.bans[pid]
+	return ok && ban.Expire > time.Now().Unix()
+}
+
+// BannedPeers 获取处于封禁期的节点, 按解封时间排序
+func (s *ScoreManager) BannedPeers() []*types.BannedPeer {
+	now := time.Now().Unix()
+	var bans []*types.BannedPeer
+	s.mtx.Lock()
+	for _, ban := range s.bans {
+		if ban.Expire > now {
+			bans = append(bans, types.Clone(ban).(*types.BannedPeer))
+		}
+	}
+	s.mtx.Unlock()
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Expire < bans[j].Expire })
+	return bans
+}
+
+func (s *ScoreManager) monitor() {
+	ticker := time.NewTicker(scoreDecayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkLatency()
+			s.decay(time.Now())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// checkLatency 对时延过高的已连接节点扣分
+func (s *ScoreManager) checkLatency() {
+	for _, pid := range s.host.Network().Peers() {
+		if s.host.Peerstore().LatencyEWMA(pid) > scoreHighLatency {
+			s.Report(pid, types.PeerFaultLatency)
+		}
+	}
+}
+
+// decay 评分向0衰减, 删除累计封禁次数已经衰减完的封禁记录
+func (s *ScoreManager) decay(now time.Time) {
+	var expired []string
+	s.mtx.Lock()
+	for pid, score := range s.scores {
+		score *= scoreDecay
+		if math.Abs(score) < scoreDecayToZero {
+			delete(s.scores, pid)
+			continue
+		}
+		s.scores[pid] = score
+	}
+	for pid, ban := range s.bans {
+		if ban.Expire <= now.Unix() && decayBanCount(ban, now) == 0 {
+			delete(s.bans, pid)
+			expired = append(expired, ban.Pid)
+		}
+	}
+	s.mtx.Unlock()
+
+	for _, pid := range expired {
+		if err := s.store.DeleteBan(pid); err != nil {
+			log.Error("ScoreManager", "delete ban", pid, "err", err)
+		}
+	}
+}
+
+// decayBanCount 计算解封后经过衰减的累计封禁次数
+func decayBanCount(ban *types.BannedPeer, now time.Time) int32 {
+	elapsed := now.Unix() - ban.Expire
+	if elapsed <= 0 {
+		return ban.Count
+	}
+	count := int64(ban.Count) - elapsed/int64(banCountDecay/time.Second)
+	if count < 0 {
+		return 0
+	}
+	return int32(count)
+}
+
+// banDuration 封禁时长随封禁次数翻倍
+func banDuration(count int32) time.Duration {
+	duration := banBaseDuration
+	for i := int32(1); i < count && duration < banMaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > banMaxDuration {
+		duration = banMaxDuration
+	}
+	return duration
+}
@@ -0,0 +1,133 @@
+package manage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memBanStore struct {
+	sync.Mutex
+	bans map[string]*types.BannedPeer
+}
+
+func (m *memBanStore) SaveBan(ban *types.BannedPeer) error {
+	m.Lock()
+	defer m.Unlock()
+	m.bans[ban.Pid] = ban
+	return nil
+}
+
+func (m *memBanStore) DeleteBan(pid string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.bans, pid)
+	return nil
+}
+
+func (m *memBanStore) LoadBans() []*types.BannedPeer {
+	m.Lock()
+	defer m.Unlock()
+	var bans []*types.BannedPeer
+	for _, ban := range m.bans {
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
+func TestScoreManagerBan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn, err := mocknet.FullMeshConnected(ctx, 2)
+	require.Nil(t, err)
+	host, remote := mn.Hosts()[0], mn.Hosts()[1]
+	store := &memBanStore{bans: make(map[string]*types.BannedPeer)}
+	s := NewScoreManager(host, store)
+	defer s.Close()
+
+	s.Report(remote.ID(), types.PeerGoodMsg)
+	assert.Equal(t, float64(1), s.Score(remote.ID()))
+	s.Report(remote.ID(), -1)
+	assert.Equal(t, float64(1), s.Score(remote.ID()))
+	s.Report(host.ID(), types.PeerFaultInvalidBlock)
+	assert.Equal(t, float64(0), s.Score(host.ID()))
+
+	//两次无效区块后评分低于阈值被封禁
+	s.Report(remote.ID(), types.PeerFaultInvalidBlock)
+	assert.False(t, s.IsBanned(remote.ID()))
+	s.Report(remote.ID(), types.PeerFaultInvalidBlock)
+	s.Report(remote.ID(), types.PeerFaultInvalidBlock)
+	assert.True(t, s.IsBanned(remote.ID()))
+	assert.Equal(t, 0, len(host.Network().ConnsToPeer(remote.ID())))
+	bans := s.BannedPeers()
+	require.Equal(t, 1, len(bans))
+	assert.Equal(t, remote.ID().Pretty(), bans[0].Pid)
+	assert.Equal(t, int32(1), bans[0].Count)
+	assert.Equal(t, 1, len(store.LoadBans()))
+
+	//被封禁节点主动连接会被断开
+	_, err = mn.ConnectPeers(remote.ID(), host.ID())
+	require.Nil(t, err)
+	for i := 0; i < 20 && len(host.Network().ConnsToPeer(remote.ID())) > 0; i++ {
+		time.Sleep(time.Millisecond * 50)
+	}
+	assert.Equal(t, 0, len(host.Network().ConnsToPeer(remote.ID())))
+
+	//重启后从store加载封禁记录
+	s2 := NewScoreManager(host, store)
+	assert.True(t, s2.IsBanned(remote.ID()))
+	s2.Close()
+
+	assert.Equal(t, ErrBanSelf, s.Ban(host.ID(), 0, ""))
+	assert.Nil(t, s.Unban(remote.ID()))
+	assert.Equal(t, ErrPeerNotBanned, s.Unban(remote.ID()))
+	assert.False(t, s.IsBanned(remote.ID()))
+	assert.Equal(t, 0, len(store.LoadBans()))
+
+	var nilManager *ScoreManager
+	nilManager.Report(remote.ID(), types.PeerFaultTimeout)
+	assert.False(t, nilManager.IsBanned(remote.ID()))
+}
+
+func TestScoreManagerDecay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn, err := mocknet.FullMeshConnected(ctx, 1)
+	require.Nil(t, err)
+	store := &memBanStore{bans: make(map[string]*types.BannedPeer)}
+	s := NewScoreManager(mn.Hosts()[0], store)
+	defer s.Close()
+
+	pid := peer.ID("test")
+	s.Report(pid, types.PeerFaultInvalidTx)
+	for i := 0; i < 100; i++ {
+		s.decay(time.Now())
+	}
+	assert.Equal(t, float64(0), s.Score(pid))
+
+	//再次封禁时长翻倍
+	require.Nil(t, s.Ban(pid, 0, "test"))
+	require.Nil(t, s.Ban(pid, 0, "test"))
+	ban := s.BannedPeers()[0]
+	assert.Equal(t, int32(2), ban.Count)
+	assert.InDelta(t, time.Now().Add(banBaseDuration*2).Unix(), ban.Expire, 1)
+
+	//解封后累计次数随时间衰减, 衰减完后删除记录
+	expire := time.Unix(ban.Expire, 0)
+	assert.Equal(t, int32(1), decayBanCount(ban, expire.Add(banCountDecay)))
+	s.decay(expire.Add(banCountDecay))
+	assert.Equal(t, 1, len(store.LoadBans()))
+	s.decay(expire.Add(banCountDecay * 2))
+	assert.Equal(t, 0, len(store.LoadBans()))
+
+	assert.Equal(t, banBaseDuration, banDuration(1))
+	assert.Equal(t, banBaseDuration*4, banDuration(3))
+	assert.Equal(t, banMaxDuration, banDuration(100))
+}
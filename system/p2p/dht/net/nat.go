@@ -0,0 +1,325 @@
+package net
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	ggio "github.com/gogo/protobuf/io"
+	libp2p "github.com/libp2p/go-libp2p"
+	autonat "github.com/libp2p/go-libp2p-autonat"
+	pb "github.com/libp2p/go-libp2p-autonat/pb"
+	circuit "github.com/libp2p/go-libp2p-circuit"
+	"github.com/libp2p/go-libp2p-core/helpers"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+	multiaddr "github.com/multiformats/go-multiaddr"
+)
+
+//节点的nat可达性
+const (
+	ReachabilityUnknown = "unknown"
+	ReachabilityPublic  = "public"
+	ReachabilityPrivate = "private"
+)
+
+var (
+	//检查中继连接的周期
+	relayRefreshInterval = time.Minute
+	//autonat回拨超时时间
+	dialBackTimeout = time.Second * 15
+)
+
+//NatManager nat穿透相关服务: 端口映射, autonat可达性检测及回拨服务, 中继地址, 连接反转
+type NatManager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    *p2pty.P2PSubConfig
+	host   host.Host
+	//autonat回拨使用独立的host, 避免复用已有连接
+	dialer  host.Host
+	autoNAT autonat.AutoNAT
+	relays  []*peer.AddrInfo
+
+	mtx        sync.RWMutex
+	relayAddrs []multiaddr.Multiaddr
+	reversing  map[peer.ID]time.Time
+	dialMtx    sync.Mutex
+}
+
+//NewNatManager 在创建host之前调用, 通过Options获取host的nat相关配置
+func NewNatManager(cfg *p2pty.P2PSubConfig) *NatManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &NatManager{
+		ctx:      ctx,
+		cancel:   cancel,
+		cfg:      cfg,
+		reversing: make(map[peer.ID]time.Time),
+	}
+	//保持中继顺序固定, 便于比较地址是否变化
+	for _, relay := range ConvertPeers(cfg.RelayNodes) {
+		n.relays = append(n.relays, relay)
+	}
+	sort.Slice(n.relays, func(i, j int) bool { return n.relays[i].ID < n.relays[j].ID })
+	return n
+}
+
+//Options 创建host的nat相关参数
+func (n *NatManager) Options() []libp2p.Option {
+	var opts []libp2p.Option
+	if !n.cfg.DisableNATPortMap {
+		opts = append(opts, libp2p.NATPortMap())
+	}
+	if n.cfg.EnableRelayHop {
+		opts = append(opts, libp2p.EnableRelay(circuit.OptHop))
+	}
+	opts = append(opts, libp2p.AddrsFactory(n.addrsFactory))
+	return opts
+}
+
+//Start 启动autonat, 中继和连接反转服务
+func (n *NatManager) Start(h host.Host) {
+	n.host = h
+	if n.cfg.EnableAutoNAT {
+		dialer, err := libp2p.New(n.ctx, libp2p.NoListenAddrs)
+		if err != nil {
+			log.Error("NatManager", "new dialer err", err)
+		} else {
+			n.dialer = dialer
+			h.SetStreamHandler(autonat.AutoNATProto, n.handleDialBack)
+		}
+		n.autoNAT = autonat.NewAutoNAT(n.ctx, h, n.directAddrs)
+	}
+	if n.cfg.EnableConnReversal {
+		h.SetStreamHandler(ConnReversalProto, n.handleConnReversal)
+		h.Network().Notify(&network.NotifyBundle{ConnectedF: n.onConnected})
+	}
+	if len(n.relays) > 0 {
+		go n.manageRelays()
+	}
+}
+
+//Close 关闭服务
+func (n *NatManager) Close() {
+	n.cancel()
+	if n.host != nil {
+		n.host.RemoveStreamHandler(autonat.AutoNATProto)
+		n.host.RemoveStreamHandler(ConnReversalProto)
+	}
+	if n.dialer != nil {
+		_ = n.dialer.Close()
+	}
+}
+
+//Reachability 获取本节点的可达性, 未开启autonat时为unknown
+func (n *NatManager) Reachability() string {
+	if n == nil || n.autoNAT == nil {
+		return ReachabilityUnknown
+	}
+	switch n.autoNAT.Status() {
+	case autonat.NATStatusPublic:
+		return ReachabilityPublic
+	case autonat.NATStatusPrivate:
+		return ReachabilityPrivate
+	default:
+		return ReachabilityUnknown
+	}
+}
+
+//RelayAddrs 当前对外提供的中继地址
+func (n *NatManager) RelayAddrs() []multiaddr.Multiaddr {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+	return append([]multiaddr.Multiaddr(nil), n.relayAddrs...)
+}
+
+func (n *NatManager) addrsFactory(addrs []multiaddr.Multiaddr) []multiaddr.Multiaddr {
+	return append(addrs, n.RelayAddrs()...)
+}
+
+//directAddrs 本节点可以直接连接的地址, 不包含中继地址
+func (n *NatManager) directAddrs() []multiaddr.Multiaddr {
+	var addrs []multiaddr.Multiaddr
+	for _, addr := range n.host.Addrs() {
+		if !isRelayAddr(addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+//needRelay 未开启autonat时始终使用中继, 否则只在检测到处于nat之后时使用
+func (n *NatManager) needRelay() bool {
+	return n.autoNAT == nil || n.autoNAT.Status() == autonat.NATStatusPrivate
+}
+
+func (n *NatManager) manageRelays() {
+	ticker := time.NewTicker(relayRefreshInterval)
+	defer ticker.Stop()
+	for {
+		n.refreshRelays()
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//refreshRelays 连接中继节点, 并更新对外提供的中继地址
+func (n *NatManager) refreshRelays() {
+	var addrs []multiaddr.Multiaddr
+	if n.needRelay() {
+		for _, relay := range n.relays {
+			if relay.ID == n.host.ID() {
+				continue
+			}
+			n.host.Peerstore().AddAddrs(relay.ID, relay.Addrs, peerstore.PermanentAddrTTL)
+			if n.host.Network().Connectedness(relay.ID) != network.Connected {
+				ctx, cancel := context.WithTimeout(n.ctx, dialBackTimeout)
+				err := n.host.Connect(ctx, *relay)
+				cancel()
+				if err != nil {
+					log.Error("refreshRelays", "relay", relay.ID, "err", err)
+					continue
+				}
+			}
+			n.host.ConnManager().Protect(relay.ID, "relay")
+			circuitAddr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/p2p/%s/p2p-circuit", relay.ID.Pretty()))
+			if err != nil {
+				continue
+			}
+			for _, addr := range relay.Addrs {
+				addrs = append(addrs, addr.Encapsulate(circuitAddr))
+			}
+		}
+	}
+
+	n.mtx.Lock()
+	changed := !equalAddrs(n.relayAddrs, addrs)
+	n.relayAddrs = addrs
+	n.mtx.Unlock()
+	if !changed {
+		return
+	}
+	log.Info("refreshRelays", "relayAddrs", addrs)
+	//通知已连接节点地址变化
+	if pusher, ok := n.host.(interface{ PushIdentify() }); ok {
+		pusher.PushIdentify()
+	}
+}
+
+//handleDialBack autonat服务端, 回拨请求节点与连接来源ip相同的地址
+func (n *NatManager) handleDialBack(s network.Stream) {
+	defer helpers.FullClose(s)
+	r := ggio.NewDelimitedReader(s, network.MessageSizeMax)
+	w := ggio.NewDelimitedWriter(s)
+	var req pb.Message
+	if err := r.ReadMsg(&req); err != nil || req.GetType() != pb.Message_DIAL {
+		_ = s.Reset()
+		return
+	}
+	resp := n.dialBack(s.Conn(), req.GetDial().GetPeer())
+	if err := w.WriteMsg(resp); err != nil {
+		_ = s.Reset()
+	}
+}
+
+func (n *NatManager) dialBack(conn network.Conn, info *pb.Message_PeerInfo) *pb.Message {
+	pid, err := peer.IDFromBytes(info.GetId())
+	if err != nil || pid != conn.RemotePeer() {
+		return newDialResponse(pb.Message_E_BAD_REQUEST, "peer id mismatch", nil)
+	}
+	if isRelayAddr(conn.RemoteMultiaddr()) {
+		return newDialResponse(pb.Message_E_DIAL_REFUSED, "relayed connection", nil)
+	}
+	observed := addrIP(conn.RemoteMultiaddr())
+	if observed == nil {
+		return newDialResponse(pb.Message_E_INTERNAL_ERROR, "unknown remote ip", nil)
+	}
+	//只回拨与来源ip一致的地址, 避免被利用去连接第三方
+	var addrs []multiaddr.Multiaddr
+	for _, data := range info.GetAddrs() {
+		addr, err := multiaddr.NewMultiaddrBytes(data)
+		if err != nil || isRelayAddr(addr) {
+			continue
+		}
+		if observed.Equal(addrIP(addr)) {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return newDialResponse(pb.Message_E_DIAL_REFUSED, "no dialable addresses", nil)
+	}
+
+	n.dialMtx.Lock()
+	defer n.dialMtx.Unlock()
+	ctx, cancel := context.WithTimeout(n.ctx, dialBackTimeout)
+	defer cancel()
+	n.dialer.Peerstore().ClearAddrs(pid)
+	clearBackoff(n.dialer, pid)
+	err = n.dialer.Connect(ctx, peer.AddrInfo{ID: pid, Addrs: addrs})
+	if err != nil {
+		log.Debug("dialBack", "pid", pid, "err", err)
+		return newDialResponse(pb.Message_E_DIAL_ERROR, "dial failed", nil)
+	}
+	var remote multiaddr.Multiaddr
+	if conns := n.dialer.Network().ConnsToPeer(pid); len(conns) > 0 {
+		remote = conns[0].RemoteMultiaddr()
+	}
+	_ = n.dialer.Network().ClosePeer(pid)
+	if remote == nil {
+		return newDialResponse(pb.Message_E_DIAL_ERROR, "connection closed", nil)
+	}
+	return newDialResponse(pb.Message_OK, "OK", remote)
+}
+
+func newDialResponse(status pb.Message_ResponseStatus, text string, addr multiaddr.Multiaddr) *pb.Message {
+	resp := &pb.Message_DialResponse{Status: status.Enum(), StatusText: &text}
+	if addr != nil {
+		resp.Addr = addr.Bytes()
+	}
+	return &pb.Message{Type: pb.Message_DIAL_RESPONSE.Enum(), DialResponse: resp}
+}
+
+//clearBackoff 清除拨号失败的退避记录, 使下次拨号立即生效
+func clearBackoff(h host.Host, pid peer.ID) {
+	if sw, ok := h.Network().(*swarm.Swarm); ok {
+		sw.Backoff().Clear(pid)
+	}
+}
+
+//addrIP 获取地址中的ip, 不包含ip时返回nil
+func addrIP(addr multiaddr.Multiaddr) net.IP {
+	if ip, err := addr.ValueForProtocol(multiaddr.P_IP4); err == nil {
+		return net.ParseIP(ip)
+	}
+	if ip, err := addr.ValueForProtocol(multiaddr.P_IP6); err == nil {
+		return net.ParseIP(ip)
+	}
+	return nil
+}
+
+func isRelayAddr(addr multiaddr.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT)
+	return err == nil
+}
+
+func equalAddrs(a, b []multiaddr.Multiaddr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package net
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	libp2p "github.com/libp2p/go-libp2p"
+	autonat "github.com/libp2p/go-libp2p-autonat"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	multiaddr "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newNatHost(t *testing.T, cfg *p2pty.P2PSubConfig) (host.Host, *NatManager) {
+	cfg.DisableNATPortMap = true
+	n := NewNatManager(cfg)
+	opts := append(n.Options(), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	h, err := libp2p.New(context.Background(), opts...)
+	require.Nil(t, err)
+	n.Start(h)
+	return h, n
+}
+
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return false
+}
+
+func TestNatManagerRelayConnReversal(t *testing.T) {
+	relay, relayNat := newNatHost(t, &p2pty.P2PSubConfig{EnableRelayHop: true})
+	defer relay.Close()
+	defer relayNat.Close()
+	relayAddr := fmt.Sprintf("%s/p2p/%s", relay.Addrs()[0], relay.ID().Pretty())
+
+	//通过中继对外提供地址
+	hc, natC := newNatHost(t, &p2pty.P2PSubConfig{RelayNodes: []string{relayAddr}, EnableConnReversal: true})
+	defer hc.Close()
+	defer natC.Close()
+	require.True(t, waitFor(func() bool { return len(natC.RelayAddrs()) > 0 }))
+	circuitAddr := natC.RelayAddrs()[0]
+	assert.True(t, isRelayAddr(circuitAddr))
+	assert.Contains(t, hc.Addrs(), circuitAddr)
+	assert.NotContains(t, natC.directAddrs(), circuitAddr)
+	assert.Equal(t, ReachabilityUnknown, natC.Reachability())
+
+	//通过中继连接后由对方反向拨号建立直连
+	hb, natB := newNatHost(t, &p2pty.P2PSubConfig{EnableConnReversal: true})
+	defer hb.Close()
+	defer natB.Close()
+	hb.Peerstore().AddAddrs(hc.ID(), []multiaddr.Multiaddr{circuitAddr}, peerstore.TempAddrTTL)
+	require.Nil(t, hb.Connect(context.Background(), peer.AddrInfo{ID: hc.ID()}))
+	direct := func() bool {
+		for _, conn := range hb.Network().ConnsToPeer(hc.ID()) {
+			if !isRelayAddr(conn.RemoteMultiaddr()) {
+				return true
+			}
+		}
+		return false
+	}
+	assert.True(t, waitFor(direct))
+	//直连成功后清除记录, 失败时同一节点间隔一段时间才会再次尝试
+	assert.True(t, waitFor(func() bool {
+		natB.mtx.RLock()
+		defer natB.mtx.RUnlock()
+		_, ok := natB.reversing[hc.ID()]
+		return !ok
+	}))
+	assert.True(t, natB.startReversal(hc.ID()))
+	assert.False(t, natB.startReversal(hc.ID()))
+}
+
+func TestNatManagerDialBack(t *testing.T) {
+	server, natS := newNatHost(t, &p2pty.P2PSubConfig{EnableAutoNAT: true})
+	defer server.Close()
+	defer natS.Close()
+	client, natC := newNatHost(t, &p2pty.P2PSubConfig{})
+	defer client.Close()
+	defer natC.Close()
+	require.Nil(t, client.Connect(context.Background(), peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	addr, err := autonat.NewAutoNATClient(client, nil).DialBack(ctx, server.ID())
+	require.Nil(t, err)
+	assert.Contains(t, client.Addrs(), addr)
+
+	//只回拨与来源ip一致的地址
+	other, _ := multiaddr.NewMultiaddr("/ip4/1.2.3.4/tcp/13803")
+	_, err = autonat.NewAutoNATClient(client, func() []multiaddr.Multiaddr {
+		return []multiaddr.Multiaddr{other}
+	}).DialBack(ctx, server.ID())
+	assert.True(t, autonat.IsDialRefused(err))
+
+	assert.Equal(t, ReachabilityUnknown, natS.Reachability())
+	var nilManager *NatManager
+	assert.Equal(t, ReachabilityUnknown, nilManager.Reachability())
+}
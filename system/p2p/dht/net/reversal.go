@@ -0,0 +1,205 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	ggio "github.com/gogo/protobuf/io"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	multiaddr "github.com/multiformats/go-multiaddr"
+)
+
+//ConnReversalProto 连接反转协议, 在中继连接上交换双方直连地址后由响应方(中继地址的持有方)主动拨号发起方.
+//响应方的出站连接可以穿过自身的nat, 但发起方必须可以被直接访问(公网地址或者端口映射),
+//双方都处于nat之后时无法建立直连, 会恢复使用中继连接.
+//当前版本的libp2p不支持tcp同时打开, 因此没有实现双方同时拨号的打洞
+const ConnReversalProto = "/chain33/connreversal/1.0.0"
+
+const (
+	reversalConnect = 1
+	reversalSync    = 2
+)
+
+var (
+	reversalTimeout = time.Minute
+	//发起方等待直连建立的时间
+	reversalWait = time.Second * 15
+	//同一节点连接反转失败后的重试间隔
+	reversalRetry = time.Minute * 10
+
+	errNoDirectAddrs  = errors.New("no direct addrs")
+	errReversalMsg    = errors.New("unexpected conn reversal message")
+	errReversalFailed = errors.New("conn reversal failed")
+)
+
+//onConnected 通过中继主动连接到其他节点时请求对方反向直连
+func (n *NatManager) onConnected(_ network.Network, conn network.Conn) {
+	if !isRelayAddr(conn.RemoteMultiaddr()) || conn.Stat().Direction != network.DirOutbound {
+		return
+	}
+	if !n.startReversal(conn.RemotePeer()) {
+		return
+	}
+	go func(pid peer.ID) {
+		err := n.connReversal(pid)
+		log.Info("connReversal", "pid", pid, "err", err)
+	}(conn.RemotePeer())
+}
+
+//startReversal 同一节点同时只进行一次连接反转, 失败后间隔一段时间才重试
+func (n *NatManager) startReversal(pid peer.ID) bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if last, ok := n.reversing[pid]; ok && time.Since(last) < reversalRetry {
+		return false
+	}
+	n.reversing[pid] = time.Now()
+	return true
+}
+
+//connReversal 发起方: 交换地址后发送sync, 等待响应方拨号建立直连, 失败时恢复中继连接
+func (n *NatManager) connReversal(pid peer.ID) error {
+	ctx, cancel := context.WithTimeout(n.ctx, reversalTimeout)
+	defer cancel()
+	s, err := n.host.NewStream(ctx, pid, ConnReversalProto)
+	if err != nil {
+		return err
+	}
+	r := ggio.NewDelimitedReader(s, network.MessageSizeMax)
+	w := ggio.NewDelimitedWriter(s)
+	_ = s.SetDeadline(time.Now().Add(reversalTimeout))
+
+	if err = w.WriteMsg(&types.ConnReversal{Ty: reversalConnect, Addrs: addrsToBytes(n.directAddrs())}); err != nil {
+		_ = s.Reset()
+		return err
+	}
+	var resp types.ConnReversal
+	if err = r.ReadMsg(&resp); err != nil {
+		_ = s.Reset()
+		return err
+	}
+	if resp.Ty != reversalConnect {
+		_ = s.Reset()
+		return errReversalMsg
+	}
+	if len(bytesToAddrs(resp.Addrs)) == 0 {
+		_ = s.Reset()
+		return errNoDirectAddrs
+	}
+	if err = w.WriteMsg(&types.ConnReversal{Ty: reversalSync}); err != nil {
+		_ = s.Reset()
+		return err
+	}
+	_ = s.Close()
+
+	if !n.waitDirect(ctx, pid) {
+		//对方无法直连本节点, 恢复中继连接
+		clearBackoff(n.host, pid)
+		if rerr := n.host.Connect(ctx, peer.AddrInfo{ID: pid}); rerr != nil {
+			log.Error("connReversal", "pid", pid, "reconnect relay err", rerr)
+		}
+		return errReversalFailed
+	}
+	//直连建立后不再需要中继连接
+	for _, conn := range n.host.Network().ConnsToPeer(pid) {
+		if isRelayAddr(conn.RemoteMultiaddr()) {
+			_ = conn.Close()
+		}
+	}
+	n.mtx.Lock()
+	delete(n.reversing, pid)
+	n.mtx.Unlock()
+	return nil
+}
+
+//handleConnReversal 响应方: 回复本节点直连地址, 收到sync后断开中继连接并拨号发起方
+func (n *NatManager) handleConnReversal(s network.Stream) {
+	pid := s.Conn().RemotePeer()
+	r := ggio.NewDelimitedReader(s, network.MessageSizeMax)
+	w := ggio.NewDelimitedWriter(s)
+	_ = s.SetDeadline(time.Now().Add(reversalTimeout))
+
+	var req types.ConnReversal
+	if err := r.ReadMsg(&req); err != nil || req.Ty != reversalConnect {
+		_ = s.Reset()
+		return
+	}
+	if err := w.WriteMsg(&types.ConnReversal{Ty: reversalConnect, Addrs: addrsToBytes(n.directAddrs())}); err != nil {
+		_ = s.Reset()
+		return
+	}
+	var sync types.ConnReversal
+	if err := r.ReadMsg(&sync); err != nil || sync.Ty != reversalSync {
+		_ = s.Reset()
+		return
+	}
+	_ = s.Close()
+
+	ctx, cancel := context.WithTimeout(n.ctx, reversalTimeout)
+	defer cancel()
+	//失败时由发起方恢复中继连接
+	if err := n.directConnect(ctx, pid, bytesToAddrs(req.Addrs)); err != nil {
+		log.Debug("handleConnReversal", "pid", pid, "err", err)
+	}
+}
+
+//waitDirect 等待与节点建立直连
+func (n *NatManager) waitDirect(ctx context.Context, pid peer.ID) bool {
+	ctx, cancel := context.WithTimeout(ctx, reversalWait)
+	defer cancel()
+	ticker := time.NewTicker(time.Millisecond * 100)
+	defer ticker.Stop()
+	for {
+		for _, conn := range n.host.Network().ConnsToPeer(pid) {
+			if !isRelayAddr(conn.RemoteMultiaddr()) {
+				return true
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+//directConnect 关闭中继连接, 只使用对方的直连地址拨号
+func (n *NatManager) directConnect(ctx context.Context, pid peer.ID, addrs []multiaddr.Multiaddr) error {
+	if len(addrs) == 0 {
+		return errNoDirectAddrs
+	}
+	ps := n.host.Peerstore()
+	ps.ClearAddrs(pid)
+	ps.AddAddrs(pid, addrs, peerstore.RecentlyConnectedAddrTTL)
+	for _, conn := range n.host.Network().ConnsToPeer(pid) {
+		if isRelayAddr(conn.RemoteMultiaddr()) {
+			_ = conn.Close()
+		}
+	}
+	clearBackoff(n.host, pid)
+	return n.host.Connect(ctx, peer.AddrInfo{ID: pid})
+}
+
+func addrsToBytes(addrs []multiaddr.Multiaddr) [][]byte {
+	data := make([][]byte, 0, len(addrs))
+	for _, addr := range addrs {
+		data = append(data, addr.Bytes())
+	}
+	return data
+}
+
+func bytesToAddrs(data [][]byte) []multiaddr.Multiaddr {
+	var addrs []multiaddr.Multiaddr
+	for _, b := range data {
+		addr, err := multiaddr.NewMultiaddrBytes(b)
+		if err != nil || isRelayAddr(addr) {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
@@ -8,6 +8,7 @@ package dht
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 
@@ -30,6 +31,7 @@ import (
 	connmgr "github.com/libp2p/go-libp2p-connmgr"
 	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/peer"
 	multiaddr "github.com/multiformats/go-multiaddr"
 )
 
@@ -45,10 +47,15 @@ type P2P struct {
 	host          core.Host
 	discovery     *net.Discovery
 	connManag     *manage.ConnManager
+	scoreManag    *manage.ScoreManager
+	limitManag    *manage.LimitManager
+	natManag      *net.NatManager
+	permManag     *manage.PermissionManager
 	peerInfoManag *manage.PeerInfoManager
 	api           client.QueueProtocolAPI
 	client        queue.Client
 	addrbook      *AddrBook
+	env           *prototypes.P2PEnv
 	taskGroup     *sync.WaitGroup
 
 	closed  int32
@@ -60,6 +67,16 @@ type P2P struct {
 
 // New new dht p2p network
 func New(mgr *p2p.Manager, subCfg []byte) p2p.IP2P {
+	return newP2P(mgr, p2pty.DHTTypeName, subCfg, nil)
+}
+
+// NewWithHost 使用外部创建的libp2p host, 用于内存网络模拟等测试场景, 此时nat相关配置不再生效,
+// p2pType为注册的p2p类型名称, 用于订阅p2p事件
+func NewWithHost(mgr *p2p.Manager, p2pType string, subCfg []byte, host core.Host) p2p.IP2P {
+	return newP2P(mgr, p2pType, subCfg, host)
+}
+
+func newP2P(mgr *p2p.Manager, p2pType string, subCfg []byte, host core.Host) *P2P {
 
 	chainCfg := mgr.ChainCfg
 	p2pCfg := chainCfg.GetModuleConfig().P2P
@@ -73,9 +90,9 @@ func New(mgr *p2p.Manager, subCfg []byte) p2p.IP2P {
 	priv := addrbook.GetPrivkey()
 
 	bandwidthTracker := metrics.NewBandwidthCounter()
-	host := newHost(mcfg.Port, priv, bandwidthTracker, int(mcfg.MaxConnectNum))
+	natManag := net.NewNatManager(mcfg)
 	p2p := &P2P{
-		host:          host,
+		natManag:      natManag,
 		peerInfoManag: manage.NewPeerInfoManager(mgr.Client),
 		chainCfg:      chainCfg,
 		subCfg:        mcfg,
@@ -86,16 +103,60 @@ func New(mgr *p2p.Manager, subCfg []byte) p2p.IP2P {
 		mgr:           mgr,
 		taskGroup:     &sync.WaitGroup{},
 	}
-	p2p.subChan = p2p.mgr.PubSub.Sub(p2pty.DHTTypeName)
+	p2p.subChan = p2p.mgr.PubSub.Sub(p2pType)
+	//许可网络模式下在安全握手时检查白名单和节点证书, 需要在创建host之前初始化
+	if host == nil {
+		local, err := peer.IDFromPrivateKey(priv)
+		if err != nil {
+			panic(err)
+		}
+		p2p.permManag = manage.NewPermissionManager(local, p2p.subCfg, p2p.loadAllowList)
+		opts := append(natManag.Options(), p2p.permManag.Options(priv)...)
+		host = newHost(mcfg.Port, priv, bandwidthTracker, int(mcfg.MaxConnectNum), opts...)
+	} else {
+		p2p.permManag = manage.NewPermissionManager(host.ID(), p2p.subCfg, p2p.loadAllowList)
+	}
+	p2p.host = host
+	p2p.permManag.Start(p2p.host)
 	p2p.discovery = net.InitDhtDiscovery(p2p.host, p2p.addrbook.AddrsInfo(), p2p.chainCfg, p2p.subCfg)
 	p2p.connManag = manage.NewConnManager(p2p.host, p2p.discovery, bandwidthTracker, p2p.subCfg)
+	p2p.scoreManag = manage.NewScoreManager(p2p.host, p2p.addrbook)
+	p2p.limitManag = manage.NewLimitManager(p2p.host, p2p.subCfg)
 	p2p.addrbook.StoreHostID(p2p.host.ID(), p2pCfg.DbPath)
 	log.Info("NewP2p", "peerId", p2p.host.ID(), "addrs", p2p.host.Addrs())
 
 	return p2p
 }
 
-func newHost(port int32, priv p2pcrypto.PrivKey, bandwidthTracker metrics.Reporter, maxconnect int) core.Host {
+//loadAllowList 从manage合约加载链上白名单, 加载成功后保存到地址簿
+func (p *P2P) loadAllowList() ([]string, error) {
+	//未同步完成时链上白名单可能还未生效, 使用上次保存的白名单, 避免重启之后拒绝只在链上允许的节点
+	//没有保存的白名单时返回错误保留之前的列表
+	sync, err := p.api.IsSync()
+	if err != nil {
+		return nil, err
+	}
+	if !sync.GetIsOk() {
+		if items, ok := p.addrbook.LoadAllowList(p.subCfg.PermissionManageKey); ok {
+			return items, nil
+		}
+		return nil, types.ErrNotSync
+	}
+	reply, err := p.api.Query("manage", "GetConfigItem", &types.ReqString{Data: p.subCfg.PermissionManageKey})
+	if err != nil {
+		return nil, err
+	}
+	config, ok := reply.(*types.ReplyConfig)
+	if !ok {
+		return nil, types.ErrTypeAsset
+	}
+	if err = p.addrbook.SaveAllowList(p.subCfg.PermissionManageKey, config.GetValue()); err != nil {
+		log.Error("loadAllowList", "save err", err)
+	}
+	return manage.ParseAllowList(config.GetValue()), nil
+}
+
+func newHost(port int32, priv p2pcrypto.PrivKey, bandwidthTracker metrics.Reporter, maxconnect int, opts ...libp2p.Option) core.Host {
 	m, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port))
 	if err != nil {
 		return nil
@@ -107,15 +168,16 @@ func newHost(port int32, priv p2pcrypto.PrivKey, bandwidthTracker metrics.Report
 	if maxconnect <= 0 {
 		maxconnect = 100
 	}
-	host, err := libp2p.New(context.Background(),
+	opts = append(opts,
 		libp2p.ListenAddrs(m),
 		libp2p.Identity(priv),
 		libp2p.BandwidthReporter(bandwidthTracker),
-		libp2p.NATPortMap(),
 
 		//connmgr 默认连接100个，最少3/4*maxconnect个
 		libp2p.ConnectionManager(connmgr.NewConnManager(maxconnect*3/4, maxconnect, 0)),
 	)
+	//端口映射, 中继等nat相关参数由opts传入
+	host, err := libp2p.New(context.Background(), opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -146,23 +208,45 @@ func (p *P2P) managePeers() {
 func (p *P2P) StartP2P() {
 
 	//提供给其他插件使用的共享接口
-	env := &prototypes.P2PEnv{
+	p.env = &prototypes.P2PEnv{
 		ChainCfg:        p.chainCfg,
 		QueueClient:     p.client,
 		Host:            p.host,
 		ConnManager:     p.connManag,
+		ScoreManager:    p.scoreManag,
+		LimitManager:    p.limitManag,
+		NatManager:      p.natManag,
 		Discovery:       p.discovery,
 		PeerInfoManager: p.peerInfoManag,
+		PermManager:     p.permManag,
 		P2PManager:      p.mgr,
 		SubConfig:       p.subCfg,
 	}
-	protocol.Init(env)
+	p.natManag.Start(p.host)
+	protocol.Init(p.env)
+	p.env.RegisterEventHandler(types.EventSnapshotDB, p.handleSnapshotEvent)
 	go p.managePeers()
 	go p.handleP2PEvent()
 	go p.findLANPeers()
 
 }
 
+//handleSnapshotEvent 在线备份地址簿数据库, 备份到p2p备份目录下的dht子目录, 和DbPath下的目录结构一致
+func (p *P2P) handleSnapshotEvent(msg *queue.Message) {
+	req := msg.GetData().(*types.ReqSnapshotDB)
+	dir := req.GetDir()
+	if dir != "" {
+		dir = filepath.Join(dir, p2pty.DHTTypeName)
+	}
+	info, err := p.addrbook.backup.Handle(&types.ReqSnapshotDB{Op: req.GetOp(), Dir: dir})
+	if err != nil {
+		log.Error("handleSnapshotEvent", "op", req.GetOp(), "err", err)
+		msg.Reply(p.client.NewMessage("", types.EventSnapshotDB, err))
+		return
+	}
+	msg.Reply(p.client.NewMessage("", types.EventSnapshotDB, info))
+}
+
 //查询本局域网内是否有节点
 func (p *P2P) findLANPeers() {
 	peerChan, err := p.discovery.FindLANPeers(p.host, fmt.Sprintf("/%s-mdns/%d", p.chainCfg.GetTitle(), p.subCfg.Channel))
@@ -200,7 +284,7 @@ func (p *P2P) handleP2PEvent() {
 		go func(qmsg *queue.Message) {
 			defer p.taskGroup.Done()
 			log.Debug("handleP2PEvent", "recv msg ty", qmsg.Ty)
-			protocol.HandleEvent(qmsg)
+			protocol.HandleEvent(p.env, qmsg)
 
 		}(msg)
 
@@ -213,9 +297,12 @@ func (p *P2P) CloseP2P() {
 	atomic.StoreInt32(&p.closed, 1)
 	p.waitTaskDone()
 	p.connManag.Close()
+	p.scoreManag.Close()
+	p.limitManag.Close()
+	p.natManag.Close()
+	p.permManag.Close()
 	p.peerInfoManag.Close()
 	p.host.Close()
-	prototypes.ClearEventHandler()
 }
 
 func (p *P2P) isClose() bool {
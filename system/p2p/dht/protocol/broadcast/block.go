@@ -20,8 +20,11 @@ func (protocol *broadCastProtocol) sendBlock(block *types.P2PBlock, p2pData *typ
 	}
 	blockSize := types.Size(block.Block)
 	//log.Debug("P2PSendBlock", "blockHash", blockHash, "peerAddr", peerAddr, "blockSize(KB)", float32(blockSize)/1024)
-	//区块内交易采用哈希广播
-	if blockSize >= int(protocol.p2pCfg.MinLtBlockSize*1024) {
+	//区块内交易采用短id广播
+	if blockSize >= int(protocol.p2pCfg.MinLtBlockSize*1024) && protocol.p2pCfg.EnableCompactBlock {
+		cb := protocol.newCompactBlock(block.Block, byteHash, blockSize)
+		p2pData.Value = &types.BroadCastData_CompactBlock{CompactBlock: cb}
+	} else if blockSize >= int(protocol.p2pCfg.MinLtBlockSize*1024) {
 		ltBlock := &types.LightBlock{}
 		ltBlock.Size = int64(blockSize)
 		ltBlock.Header = block.Block.GetHeader(protocol.GetChainCfg())
@@ -73,15 +76,7 @@ func (protocol *broadCastProtocol) recvLtBlock(ltBlock *types.LightBlock, pid, p
 	}
 
 	//组装block
-	block := &types.Block{}
-	block.TxHash = ltBlock.Header.TxHash
-	block.Signature = ltBlock.Header.Signature
-	block.ParentHash = ltBlock.Header.ParentHash
-	block.Height = ltBlock.Header.Height
-	block.BlockTime = ltBlock.Header.BlockTime
-	block.Difficulty = ltBlock.Header.Difficulty
-	block.Version = ltBlock.Header.Version
-	block.StateHash = ltBlock.Header.StateHash
+	block := newBlockFromHeader(ltBlock.Header)
 	//add miner tx
 	block.Txs = append(block.Txs, ltBlock.MinerTx)
 
@@ -26,6 +26,21 @@ func Test_sendBlock(t *testing.T) {
 	assert.True(t, ok)
 	assert.True(t, ltBlock.MinerTx == minerTx)
 	assert.Equal(t, 3, len(ltBlock.STxHashes))
+
+	//紧凑区块, 没有收到过广播的交易预填充
+	proto.p2pCfg.EnableCompactBlock = true
+	proto.txFilter.Add(hex.EncodeToString(tx.Hash()), true)
+	proto.txFilter.Add(hex.EncodeToString(tx1.Hash()), true)
+	data, ok = proto.handleSend(&types.P2PBlock{Block: testBlock}, "compactpid", testAddr)
+	assert.True(t, ok)
+	cb := data.Value.(*types.BroadCastData_CompactBlock).CompactBlock
+	assert.Equal(t, 2, len(cb.ShortIDs))
+	assert.Equal(t, 2, len(cb.PrefilledTxs))
+	assert.True(t, cb.PrefilledTxs[0].Tx == minerTx)
+	assert.Equal(t, int32(3), cb.PrefilledTxs[1].Index)
+	//同一区块只构建一次
+	data, _ = proto.handleSend(&types.P2PBlock{Block: testBlock}, "compactpid2", testAddr)
+	assert.True(t, cb == data.Value.(*types.BroadCastData_CompactBlock).CompactBlock)
 }
 
 func Test_recvBlock(t *testing.T) {
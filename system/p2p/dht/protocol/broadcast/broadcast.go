@@ -26,6 +26,8 @@ var log = log15.New("module", "p2p.broadcast")
 const (
 	protoTypeID = "BroadcastProtocolType"
 	ID          = "/chain33/p2p/broadcast/1.0.0"
+	//广播中交易和区块查询请求的频率限制
+	queryLimitID = ID + "/query"
 )
 
 func init() {
@@ -42,6 +44,8 @@ type broadCastProtocol struct {
 	txSendFilter    *utils.Filterdata
 	blockSendFilter *utils.Filterdata
 	ltBlockCache    *utils.SpaceLimitCache
+	compactCache    compactCache
+	compactTxs      *compactTxCache
 	p2pCfg          *p2pty.P2PSubConfig
 }
 
@@ -57,12 +61,17 @@ func (protocol *broadCastProtocol) InitProtocol(env *prototypes.P2PEnv) {
 	//发送交易和区块时过滤缓存, 解决冗余广播发送
 	protocol.txSendFilter = utils.NewFilter(txSendFilterCacheNum)
 	protocol.blockSendFilter = utils.NewFilter(blockSendFilterCacheNum)
+	protocol.compactTxs = newCompactTxCache(txRecvFilterCacheNum)
 
 	// 单独复制一份， 避免data race
 	subCfg := *(env.SubConfig)
-	//注册事件处理函数
-	prototypes.RegisterEventHandler(types.EventTxBroadcast, protocol.handleEvent)
-	prototypes.RegisterEventHandler(types.EventBlockBroadcast, protocol.handleEvent)
+	//注册事件处理函数, 配置为gossipsub时由gossipsub协议负责对外广播, 这里只保留接收以及超过pubsub消息上限的区块广播
+	if subCfg.BroadcastProtocol != p2pty.BroadcastGossipSub {
+		env.RegisterEventHandler(types.EventTxBroadcast, protocol.handleEvent)
+		env.RegisterEventHandler(types.EventBlockBroadcast, protocol.handleEvent)
+	} else {
+		env.RegisterEventHandler(prototypes.EventLargeBlockBroadcast, protocol.handleEvent)
+	}
 
 	//ttl至少设为2
 	if subCfg.LightTxTTL <= 1 {
@@ -82,6 +91,7 @@ func (protocol *broadCastProtocol) InitProtocol(env *prototypes.P2PEnv) {
 	//内部组装成功失败或成功都会进行清理，实际运行并不会长期占用内存，只要限制极端情况最大值
 	protocol.ltBlockCache = utils.NewSpaceLimitCache(ltBlockCacheNum, int(subCfg.LtBlockCacheSize*1024*1024))
 	protocol.p2pCfg = &subCfg
+	env.LimitManager.SetRequestLimit(queryLimitID, subCfg.QueryReqRate)
 }
 
 type broadCastHandler struct {
@@ -121,6 +131,10 @@ func (protocol *broadCastProtocol) handleEvent(msg *queue.Message) {
 	var sendData interface{}
 	if tx, ok := msg.GetData().(*types.Transaction); ok {
 		txHash := hex.EncodeToString(tx.Hash())
+		//mempool接收的交易都会在这里广播, 缓存下来用于还原紧凑区块
+		if protocol.p2pCfg.EnableCompactBlock {
+			protocol.compactTxs.add(tx)
+		}
 		//此处使用新分配结构，避免重复修改已保存的ttl
 		route := &types.P2PRoute{TTL: 1}
 		//是否已存在记录，不存在表示本节点发起的交易
@@ -185,6 +199,7 @@ func (protocol *broadCastProtocol) sendPeer(pid string, data interface{}, delayS
 		log.Error("sendPeer", "id", pid, "NewStreamErr", err)
 		return nil, err
 	}
+	stream = protocol.GetLimitManager().LimitStream(stream)
 
 	err = prototypes.WriteStream(broadData, stream)
 	if err != nil {
@@ -230,6 +245,10 @@ func (protocol *broadCastProtocol) handleReceive(data *types.BroadCastData, pid
 	defer func() {
 		if r := recover(); r != nil {
 			log.Error("handleReceive_Panic", "recvData", data, "pid", pid, "addr", peerAddr, "recoverErr", r)
+			//异常数据视为违反协议
+			if rawID, err := peer.IDB58Decode(pid); err == nil {
+				protocol.GetScoreManager().Report(rawID, types.PeerFaultProtocol)
+			}
 		}
 	}()
 	if tx := data.GetTx(); tx != nil {
@@ -238,6 +257,8 @@ func (protocol *broadCastProtocol) handleReceive(data *types.BroadCastData, pid
 		err = protocol.recvLtTx(ltTx, pid, peerAddr)
 	} else if ltBlc := data.GetLtBlock(); ltBlc != nil {
 		err = protocol.recvLtBlock(ltBlc, pid, peerAddr)
+	} else if cb := data.GetCompactBlock(); cb != nil {
+		err = protocol.recvCompactBlock(cb, pid, peerAddr)
 	} else if blc := data.GetBlock(); blc != nil {
 		err = protocol.recvBlock(blc, pid, peerAddr)
 	} else if query := data.GetQuery(); query != nil {
@@ -247,6 +268,13 @@ func (protocol *broadCastProtocol) handleReceive(data *types.BroadCastData, pid
 	}
 	if err != nil {
 		log.Error("handleReceive", "pid", pid, "addr", peerAddr, "recvData", data.Value, "err", err)
+		return
+	}
+	//正常提供交易或区块数据的节点加分, 查询请求不计分
+	if data.GetQuery() == nil {
+		if rawID, err := peer.IDB58Decode(pid); err == nil {
+			protocol.GetScoreManager().Report(rawID, types.PeerGoodMsg)
+		}
 	}
 	return
 }
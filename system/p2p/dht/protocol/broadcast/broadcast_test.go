@@ -5,6 +5,7 @@
 package broadcast
 
 import (
+	"context"
 	"encoding/hex"
 	"testing"
 
@@ -13,9 +14,11 @@ import (
 	"github.com/33cn/chain33/client"
 	commlog "github.com/33cn/chain33/common/log"
 	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/system/p2p/dht/manage"
 	prototypes "github.com/33cn/chain33/system/p2p/dht/protocol/types"
 	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
 	"github.com/33cn/chain33/types"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -67,7 +70,6 @@ func newTestEnv(q queue.Queue) *prototypes.P2PEnv {
 func newTestProtocolWithQueue(q queue.Queue) *broadCastProtocol {
 	env := newTestEnv(q)
 	protocol := &broadCastProtocol{}
-	prototypes.ClearEventHandler()
 	protocol.InitProtocol(env)
 	return protocol
 }
@@ -83,6 +85,18 @@ func TestBroadCastProtocol_InitProtocol(t *testing.T) {
 	protocol := newTestProtocol()
 	assert.Equal(t, defaultMinLtBlockSize, int(protocol.p2pCfg.MinLtBlockSize))
 	assert.Equal(t, defaultLtTxBroadCastTTL, int(protocol.p2pCfg.LightTxTTL))
+	_, ok := protocol.GetEventHandler(prototypes.EventLargeBlockBroadcast)
+	assert.False(t, ok)
+
+	//配置为gossipsub时只负责广播超过pubsub消息上限的区块
+	env := newTestEnv(queue.New("test"))
+	env.SubConfig.BroadcastProtocol = p2pty.BroadcastGossipSub
+	protocol = &broadCastProtocol{}
+	protocol.InitProtocol(env)
+	_, ok = protocol.GetEventHandler(prototypes.EventLargeBlockBroadcast)
+	assert.True(t, ok)
+	_, ok = protocol.GetEventHandler(types.EventBlockBroadcast)
+	assert.False(t, ok)
 }
 
 func testHandleEvent(protocol *broadCastProtocol, msg *queue.Message) {
@@ -128,3 +142,35 @@ func Test_util(t *testing.T) {
 	exist = addIgnoreSendPeerAtomic(proto.txSendFilter, "hash", "pid1")
 	assert.True(t, exist)
 }
+
+type memBanStore struct{}
+
+func (memBanStore) SaveBan(*types.BannedPeer) error { return nil }
+func (memBanStore) DeleteBan(string) error          { return nil }
+func (memBanStore) LoadBans() []*types.BannedPeer   { return nil }
+
+func TestReportGoodMsg(t *testing.T) {
+	q := queue.New("test")
+	defer q.Close()
+	proto := newTestProtocolWithQueue(q)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn, err := mocknet.FullMeshLinked(ctx, 2)
+	assert.Nil(t, err)
+	scorer := manage.NewScoreManager(mn.Hosts()[0], memBanStore{})
+	defer scorer.Close()
+	proto.ScoreManager = scorer
+	remote := mn.Hosts()[1].ID()
+
+	q.Client().Sub("mempool")
+	data := &types.BroadCastData{Value: &types.BroadCastData_Tx{Tx: &types.P2PTx{Tx: tx}}}
+	assert.Nil(t, proto.handleReceive(data, remote.Pretty(), testAddr))
+	assert.Equal(t, float64(1), scorer.Score(remote))
+
+	//无效数据和查询请求不加分
+	data = &types.BroadCastData{Value: &types.BroadCastData_Tx{Tx: &types.P2PTx{}}}
+	assert.Equal(t, types.ErrInvalidParam, proto.handleReceive(data, remote.Pretty(), testAddr))
+	data = &types.BroadCastData{Value: &types.BroadCastData_Query{Query: &types.P2PQueryData{}}}
+	_ = proto.handleReceive(data, remote.Pretty(), testAddr)
+	assert.Equal(t, float64(1), scorer.Score(remote))
+}
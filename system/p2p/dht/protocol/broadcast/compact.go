@@ -0,0 +1,259 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package broadcast
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/common/siphash"
+	"github.com/33cn/chain33/metrics"
+	"github.com/33cn/chain33/types"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+//短id取siphash的低48位
+const shortIDMask = 1<<48 - 1
+
+//compactCache 缓存最近一次构建的紧凑区块, 同一区块向多个节点广播时只构建一次
+type compactCache struct {
+	mtx       sync.Mutex
+	blockHash string
+	block     *types.CompactBlock
+}
+
+//shortIDKey 由区块哈希和nonce生成siphash密钥, 不同区块及不同发送方的短id互不相同
+func shortIDKey(blockHash []byte, nonce uint64) (uint64, uint64) {
+	buf := make([]byte, len(blockHash)+8)
+	copy(buf, blockHash)
+	binary.LittleEndian.PutUint64(buf[len(blockHash):], nonce)
+	h := sha256.Sum256(buf)
+	return binary.LittleEndian.Uint64(h[:8]), binary.LittleEndian.Uint64(h[8:16])
+}
+
+func calcShortID(k0, k1 uint64, txHash []byte) uint64 {
+	return siphash.Hash(k0, k1, txHash) & shortIDMask
+}
+
+//txBroadcastHash 交易广播时使用的哈希, 交易组内的交易以整个交易组广播, header为交易组哈希
+func txBroadcastHash(tx *types.Transaction) []byte {
+	if tx.GetGroupCount() > 0 && len(tx.Header) > 0 {
+		return tx.Header
+	}
+	return tx.Hash()
+}
+
+//newCompactBlock 构建紧凑区块, 挖矿交易以及本节点没有收到过广播的交易预测对端也不存在, 直接预填充
+func (protocol *broadCastProtocol) newCompactBlock(block *types.Block, blockHash []byte, blockSize int) *types.CompactBlock {
+	hexHash := hex.EncodeToString(blockHash)
+	protocol.compactCache.mtx.Lock()
+	defer protocol.compactCache.mtx.Unlock()
+	if protocol.compactCache.blockHash == hexHash {
+		return protocol.compactCache.block
+	}
+
+	var nonceBytes [8]byte
+	_, _ = rand.Read(nonceBytes[:])
+	cb := &types.CompactBlock{
+		Size:   int64(blockSize),
+		Header: block.GetHeader(protocol.GetChainCfg()),
+		Nonce:  binary.LittleEndian.Uint64(nonceBytes[:]),
+	}
+	cb.Header.Hash = blockHash
+	cb.Header.Signature = block.Signature
+	k0, k1 := shortIDKey(blockHash, cb.Nonce)
+	for i, tx := range block.Txs {
+		if i == 0 || !protocol.txFilter.Contains(hex.EncodeToString(txBroadcastHash(tx))) {
+			cb.PrefilledTxs = append(cb.PrefilledTxs, &types.PrefilledTx{Index: int32(i), Tx: tx})
+			continue
+		}
+		cb.ShortIDs = append(cb.ShortIDs, calcShortID(k0, k1, tx.Hash()))
+	}
+	protocol.compactCache.blockHash = hexHash
+	protocol.compactCache.block = cb
+	return cb
+}
+
+//compactTxCache 缓存mempool接收之后广播的交易及其哈希, 还原紧凑区块时不需要查询整个mempool并重新计算交易哈希
+//短id的密钥随区块变化, 每个区块需要重新计算一次, 同一区块重复还原时复用上次的结果
+type compactTxCache struct {
+	mtx      sync.Mutex
+	txs      *lru.Cache
+	key      [2]uint64
+	shortIDs map[uint64]*types.Transaction
+}
+
+type cachedTx struct {
+	hash []byte
+	tx   *types.Transaction
+}
+
+func newCompactTxCache(num int) *compactTxCache {
+	txs, err := lru.New(num)
+	if err != nil {
+		panic(err)
+	}
+	return &compactTxCache{txs: txs}
+}
+
+//add 交易组展开为组内交易
+func (c *compactTxCache) add(tx *types.Transaction) {
+	txs := []*types.Transaction{tx}
+	if tx.GetGroupCount() > 0 {
+		group, err := tx.GetTxGroup()
+		if err == nil && group != nil {
+			txs = group.Txs
+		}
+	}
+	for _, tx := range txs {
+		hash := tx.Hash()
+		c.txs.Add(string(hash), &cachedTx{hash: hash, tx: tx})
+	}
+}
+
+//getShortIDs 计算缓存内所有交易的短id, 短id冲突的交易无法确定, 记为nil
+func (c *compactTxCache) getShortIDs(k0, k1 uint64) map[uint64]*types.Transaction {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.shortIDs != nil && c.key == [2]uint64{k0, k1} {
+		return c.shortIDs
+	}
+	keys := c.txs.Keys()
+	pool := make(map[uint64]*types.Transaction, len(keys))
+	for _, key := range keys {
+		val, ok := c.txs.Peek(key)
+		if !ok {
+			continue
+		}
+		ctx := val.(*cachedTx)
+		id := calcShortID(k0, k1, ctx.hash)
+		if _, exist := pool[id]; exist {
+			pool[id] = nil
+			continue
+		}
+		pool[id] = ctx.tx
+	}
+	c.key, c.shortIDs = [2]uint64{k0, k1}, pool
+	return pool
+}
+
+//buildCompactBlock 使用预填充交易和缓存的mempool交易还原区块, 返回缺失交易在区块内的索引, 缺失的位置以空交易占位
+func (protocol *broadCastProtocol) buildCompactBlock(cb *types.CompactBlock) (*types.Block, []int32, error) {
+	block := newBlockFromHeader(cb.Header)
+	txCount := len(cb.ShortIDs) + len(cb.PrefilledTxs)
+	block.Txs = make([]*types.Transaction, txCount)
+	for _, ptx := range cb.PrefilledTxs {
+		if ptx.Index < 0 || int(ptx.Index) >= txCount || ptx.Tx == nil || block.Txs[ptx.Index] != nil {
+			return nil, nil, types.ErrInvalidParam
+		}
+		block.Txs[ptx.Index] = ptx.Tx
+	}
+	if len(cb.ShortIDs) == 0 {
+		return block, nil, nil
+	}
+
+	pool := protocol.compactTxs.getShortIDs(shortIDKey(cb.Header.Hash, cb.Nonce))
+	var missing []int32
+	next := 0
+	for i := range block.Txs {
+		if block.Txs[i] != nil {
+			continue
+		}
+		if tx := pool[cb.ShortIDs[next]]; tx != nil {
+			block.Txs[i] = tx
+		} else {
+			missing = append(missing, int32(i))
+			block.Txs[i] = &types.Transaction{}
+		}
+		next++
+	}
+	metrics.CompactTxTotal.Inc(int64(len(cb.ShortIDs)))
+	metrics.CompactTxHit.Inc(int64(len(cb.ShortIDs) - len(missing)))
+	return block, missing, nil
+}
+
+func (protocol *broadCastProtocol) recvCompactBlock(cb *types.CompactBlock, pid, peerAddr string) error {
+
+	if cb.GetHeader() == nil {
+		return types.ErrInvalidParam
+	}
+	blockHash := hex.EncodeToString(cb.Header.Hash)
+	//将节点id添加到发送过滤, 避免冗余发送
+	addIgnoreSendPeerAtomic(protocol.blockSendFilter, blockHash, pid)
+	//检测是否已经收到此block
+	if protocol.blockFilter.AddWithCheckAtomic(blockHash, true) {
+		return nil
+	}
+	metrics.CompactBlockRecv.Inc(1)
+
+	block, missing, err := protocol.buildCompactBlock(cb)
+	if err != nil {
+		protocol.blockFilter.Remove(blockHash)
+		return err
+	}
+	if len(missing) == 0 {
+		if bytes.Equal(block.TxHash, merkle.CalcMerkleRoot(protocol.BaseProtocol.ChainCfg, block.GetHeight(), block.Txs)) {
+			log.Debug("recvCompactBlock", "height", block.GetHeight(), "txCount", len(block.Txs), "size(KB)", float32(cb.Size)/1024)
+			metrics.CompactBlockHit.Inc(1)
+			if err := protocol.postBlockChain(blockHash, pid, block); err != nil {
+				log.Error("recvCompactBlock", "send block to blockchain Error", err.Error())
+				return errSendBlockChain
+			}
+			return nil
+		}
+		//短id碰撞导致交易根哈希不一致, 请求完整区块
+		log.Debug("recvCompactBlock", "height", block.GetHeight(), "hash", blockHash, "err", "txHashMismatch")
+	}
+
+	log.Debug("recvCompactBlock", "height", block.GetHeight(), "hash", blockHash,
+		"txCount", len(block.Txs), "missTxCount", len(missing), "blockSize(KB)", float32(cb.Size)/1024)
+	//缺失的交易个数大于总数1/3, 直接请求完整区块
+	if len(missing) > 0 && float32(len(missing)) > float32(len(block.Txs))/3 {
+		missing = nil
+	}
+	if len(missing) == 0 {
+		metrics.CompactBlockFull.Inc(1)
+	} else {
+		metrics.CompactTxReq.Inc(1)
+	}
+
+	//一次请求所有缺失的交易, 空的TxIndices表示请求区块内所有交易
+	query := &types.P2PQueryData{
+		Value: &types.P2PQueryData_BlockTxReq{
+			BlockTxReq: &types.P2PBlockTxReq{
+				BlockHash: blockHash,
+				TxIndices: missing,
+			},
+		},
+	}
+	//缓存不完整的区块, 收到回复后由recvQueryReply补全, 校验失败时再回退到请求完整区块
+	protocol.ltBlockCache.Add(blockHash, block, block.Size())
+	_, err = protocol.sendPeer(pid, query, false)
+	if err != nil {
+		log.Error("recvCompactBlock", "pid", pid, "addr", peerAddr, "err", err)
+		protocol.blockFilter.Remove(blockHash)
+		protocol.ltBlockCache.Remove(blockHash)
+		return errSendStream
+	}
+	return nil
+}
+
+//newBlockFromHeader 由区块头组装不包含交易的区块
+func newBlockFromHeader(header *types.Header) *types.Block {
+	block := &types.Block{}
+	block.TxHash = header.TxHash
+	block.Signature = header.Signature
+	block.ParentHash = header.ParentHash
+	block.Height = header.Height
+	block.BlockTime = header.BlockTime
+	block.Difficulty = header.Difficulty
+	block.Version = header.Version
+	block.StateHash = header.StateHash
+	return block
+}
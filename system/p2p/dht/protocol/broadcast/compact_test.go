@@ -0,0 +1,126 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package broadcast
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/metrics"
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCompactTestTxs(count int) []*types.Transaction {
+	txs := make([]*types.Transaction, 0, count)
+	for i := 0; i < count; i++ {
+		txs = append(txs, &types.Transaction{Execer: []byte("coins"), Payload: payload, Fee: 100000, Nonce: int64(i + 1)})
+	}
+	return txs
+}
+
+func Test_recvCompactBlock(t *testing.T) {
+
+	q := queue.New("test")
+	proto := newTestProtocolWithQueue(q)
+	proto.p2pCfg.MinLtBlockSize = 0
+	proto.p2pCfg.EnableCompactBlock = true
+	defer q.Close()
+	//模拟mempool接收交易之后的广播事件, 广播时缓存交易
+	setMempool := func(txs []*types.Transaction) {
+		proto.compactTxs = newCompactTxCache(txRecvFilterCacheNum)
+		for _, tx := range txs {
+			testHandleEvent(proto, queue.NewMessage(0, "p2p", types.EventTxBroadcast, tx))
+		}
+	}
+	newCli := q.Client()
+	newCli.Sub("blockchain")
+
+	txs := newCompactTestTxs(12)
+	txGroup, err := types.CreateTxGroup(txs[10:12], proto.ChainCfg.GetMinTxFeeRate())
+	require.Nil(t, err)
+	blockTxs := append([]*types.Transaction{minerTx}, txs[:10]...)
+	blockTxs = append(blockTxs, txGroup.Txs...)
+	//除txs[9]外其他交易都收到过广播
+	for _, tx := range append(append([]*types.Transaction{}, txs[:9]...), txGroup.Tx()) {
+		proto.txFilter.Add(hex.EncodeToString(tx.Hash()), true)
+	}
+	newBlock := func(height int64) *types.Block {
+		block := &types.Block{Height: height, Txs: blockTxs}
+		block.TxHash = merkle.CalcMerkleRoot(proto.ChainCfg, height, block.Txs)
+		return block
+	}
+	sendCount := 0
+	newCompact := func(block *types.Block) *types.BroadCastData {
+		sendCount++
+		data, ok := proto.handleSend(&types.P2PBlock{Block: block}, fmt.Sprintf("sendpid%d", sendCount), testAddr)
+		require.True(t, ok)
+		return data
+	}
+	cb := newCompact(newBlock(10)).GetCompactBlock()
+	require.NotNil(t, cb)
+	assert.Equal(t, 2, len(cb.PrefilledTxs))
+	assert.Equal(t, int32(10), cb.PrefilledTxs[1].Index)
+	assert.Equal(t, 11, len(cb.ShortIDs))
+
+	//mempool和区块交易一致, 直接还原
+	memTxList := append(append([]*types.Transaction{}, txs[:9]...), txGroup.Tx())
+	setMempool(append(memTxList, newCompactTestTxs(20)[15:]...))
+	hit, txTotal, txHit := metrics.CompactBlockHit.Count(), metrics.CompactTxTotal.Count(), metrics.CompactTxHit.Count()
+	err = proto.handleReceive(newCompact(newBlock(10)), testPid, testAddr)
+	assert.Nil(t, err)
+	msg := <-newCli.Recv()
+	assert.Equal(t, types.EventBroadcastAddBlock, int(msg.Ty))
+	assert.Equal(t, newBlock(10).Hash(proto.ChainCfg), msg.Data.(*types.BlockPid).Block.Hash(proto.ChainCfg))
+	assert.Equal(t, hit+1, metrics.CompactBlockHit.Count())
+	assert.Equal(t, txTotal+11, metrics.CompactTxTotal.Count())
+	assert.Equal(t, txHit+11, metrics.CompactTxHit.Count())
+	//重复接收
+	assert.Nil(t, proto.handleReceive(newCompact(newBlock(10)), testPid, testAddr))
+
+	//mempool缺失部分交易, 一次请求所有缺失交易
+	setMempool(append(append([]*types.Transaction{}, txs[:2]...), txs[3], txs[5], txs[6], txs[7], txs[8], txGroup.Tx()))
+	block := newBlock(11)
+	data := newCompact(block)
+	rebuild, missing, err := proto.buildCompactBlock(data.GetCompactBlock())
+	require.Nil(t, err)
+	assert.Equal(t, []int32{3, 5}, missing)
+	txReq := metrics.CompactTxReq.Count()
+	err = proto.handleReceive(data, testPid, testAddr)
+	assert.Equal(t, errSendStream, err)
+	assert.Equal(t, txReq+1, metrics.CompactTxReq.Count())
+	//收到缺失的交易后完成组装
+	blockHash := hex.EncodeToString(block.Hash(proto.ChainCfg))
+	proto.ltBlockCache.Add(blockHash, rebuild, rebuild.Size())
+	rep := &types.P2PBlockTxReply{BlockHash: blockHash, TxIndices: missing, Txs: []*types.Transaction{txs[2], txs[4]}}
+	assert.Nil(t, proto.recvQueryReply(rep, testPid, testAddr))
+	msg = <-newCli.Recv()
+	assert.Equal(t, block.Hash(proto.ChainCfg), msg.Data.(*types.BlockPid).Block.Hash(proto.ChainCfg))
+
+	//缺失超过1/3, 请求完整区块
+	setMempool(txs[:2])
+	full := metrics.CompactBlockFull.Count()
+	err = proto.handleReceive(newCompact(newBlock(12)), testPid, testAddr)
+	assert.Equal(t, errSendStream, err)
+	assert.Equal(t, full+1, metrics.CompactBlockFull.Count())
+
+	//交易根哈希不一致, 请求完整区块
+	setMempool(memTxList)
+	block = newBlock(13)
+	block.TxHash = []byte("test")
+	err = proto.handleReceive(newCompact(block), testPid, testAddr)
+	assert.Equal(t, errSendStream, err)
+	assert.Equal(t, full+2, metrics.CompactBlockFull.Count())
+
+	//预填充交易索引越界
+	cb = newCompact(newBlock(14)).GetCompactBlock()
+	cb.PrefilledTxs[1].Index = 100
+	err = proto.handleReceive(&types.BroadCastData{Value: &types.BroadCastData_CompactBlock{CompactBlock: cb}}, testPid, testAddr)
+	assert.Equal(t, types.ErrInvalidParam, err)
+	assert.False(t, proto.blockFilter.Contains(hex.EncodeToString(cb.Header.Hash)))
+}
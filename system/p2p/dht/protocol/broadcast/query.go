@@ -10,7 +10,9 @@ import (
 	"github.com/33cn/chain33/common"
 
 	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/types"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 func (protocol *broadCastProtocol) sendQueryData(query *types.P2PQueryData, p2pData *types.BroadCastData, peerAddr string) bool {
@@ -27,6 +29,11 @@ func (protocol *broadCastProtocol) sendQueryReply(rep *types.P2PBlockTxReply, p2
 
 func (protocol *broadCastProtocol) recvQueryData(query *types.P2PQueryData, pid, peerAddr string) error {
 
+	//广播流没有响应, 超过频率限制的查询直接丢弃
+	if rawID, err := peer.IDB58Decode(pid); err == nil && !protocol.GetLimitManager().AllowRequest(rawID, queryLimitID) {
+		protocol.GetScoreManager().Report(rawID, types.PeerFaultRateLimit)
+		return errPeerBusy
+	}
 	var reply interface{}
 	if txReq := query.GetTxReq(); txReq != nil {
 
@@ -126,6 +133,7 @@ func (protocol *broadCastProtocol) recvQueryReply(rep *types.P2PBlockTxReply, pi
 	}
 
 	log.Debug("recvQueryReply", "getBlockRetry", block.GetHeight(), "hash", rep.BlockHash)
+	metrics.CompactBlockFull.Inc(1)
 
 	query := &types.P2PQueryData{
 		Value: &types.P2PQueryData_BlockTxReq{
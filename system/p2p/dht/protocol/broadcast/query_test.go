@@ -4,13 +4,16 @@
 package broadcast
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"testing"
 
 	"github.com/33cn/chain33/common/merkle"
 	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/system/p2p/dht/manage"
 	"github.com/33cn/chain33/types"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -119,3 +122,22 @@ func Test_recvQueryReply(t *testing.T) {
 	assert.Equal(t, block.Hash(proto.ChainCfg), blc.Block.Hash(proto.ChainCfg))
 
 }
+
+func Test_recvQueryDataRateLimit(t *testing.T) {
+
+	q := queue.New("test")
+	defer q.Close()
+	env := newTestEnv(q)
+	env.SubConfig.QueryReqRate = 1
+	mn, err := mocknet.FullMeshConnected(context.Background(), 2)
+	assert.Nil(t, err)
+	env.LimitManager = manage.NewLimitManager(mn.Hosts()[0], env.SubConfig)
+	defer env.LimitManager.Close()
+	proto := &broadCastProtocol{}
+	proto.InitProtocol(env)
+
+	pid := mn.Hosts()[1].ID().Pretty()
+	query := &types.BroadCastData{Value: &types.BroadCastData_Query{Query: &types.P2PQueryData{}}}
+	assert.Nil(t, proto.handleReceive(query, pid, testAddr))
+	assert.Equal(t, errPeerBusy, proto.handleReceive(query, pid, testAddr))
+}
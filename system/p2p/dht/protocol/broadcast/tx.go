@@ -43,7 +43,7 @@ func (protocol *broadCastProtocol) sendTx(tx *types.P2PTx, p2pData *types.BroadC
 
 func (protocol *broadCastProtocol) recvTx(tx *types.P2PTx, pid, peerAddr string) (err error) {
 	if tx.GetTx() == nil {
-		return
+		return types.ErrInvalidParam
 	}
 	txHash := hex.EncodeToString(tx.GetTx().Hash())
 	//将节点id添加到发送过滤, 避免冗余发送
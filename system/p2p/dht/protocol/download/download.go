@@ -13,6 +13,7 @@ import (
 
 	core "github.com/libp2p/go-libp2p-core"
 
+	"github.com/33cn/chain33/system/p2p/dht/manage"
 	prototypes "github.com/33cn/chain33/system/p2p/dht/protocol/types"
 	uuid "github.com/google/uuid"
 
@@ -21,7 +22,9 @@ import (
 )
 
 var (
-	log = log15.New("module", "p2p.download")
+	log             = log15.New("module", "p2p.download")
+	errInvalidParam = errors.New("param error")
+	errInvalidBlock = errors.New("invalid block")
 )
 
 func init() {
@@ -42,7 +45,8 @@ type downloadProtol struct {
 func (d *downloadProtol) InitProtocol(env *prototypes.P2PEnv) {
 	d.P2PEnv = env
 	//注册事件处理函数
-	prototypes.RegisterEventHandler(types.EventFetchBlocks, d.handleEvent)
+	env.RegisterEventHandler(types.EventFetchBlocks, d.handleEvent)
+	env.LimitManager.SetRequestLimit(DownloadBlockReq, env.SubConfig.DownloadReqRate)
 
 }
 
@@ -72,7 +76,7 @@ func (d *downloadProtol) processReq(id string, message *types.P2PGetBlocks) (*ty
 
 	//允许下载的最大高度区间为256
 	if message.GetEndHeight()-message.GetStartHeight() > 256 || message.GetEndHeight() < message.GetStartHeight() {
-		return nil, errors.New("param error")
+		return nil, errInvalidParam
 
 	}
 	//开始下载指定高度
@@ -108,6 +112,9 @@ func (d *downloadProtol) onReq(id string, message *types.P2PGetBlocks, s core.St
 	log.Debug("OnReq", "start", message.GetStartHeight(), "end", message.GetStartHeight(), "remoteId", s.Conn().RemotePeer().String(), "id", id)
 
 	blockdata, err := d.processReq(id, message)
+	if err == errInvalidParam {
+		d.GetScoreManager().Report(s.Conn().RemotePeer(), types.PeerFaultProtocol)
+	}
 	if err != nil {
 		log.Error("processReq", "err", err, "pid", s.Conn().RemotePeer().String())
 		return
@@ -233,18 +240,32 @@ ReDownload:
 	err := d.SendRecvPeer(req, &resp)
 	if err != nil {
 		log.Error("handleEvent", "SendRecvPeer", err, "pid", task.Pid)
+		//对端限流不视为节点故障
+		if err != manage.ErrPeerBusy {
+			d.GetScoreManager().Report(task.Pid, types.PeerFaultTimeout)
+		}
 		d.releaseJob(task)
 		tasks = tasks.Remove(task)
 		goto ReDownload
 	}
 
-	block := resp.GetMessage().GetItems()[0].GetBlock()
+	//对端返回的区块为空或者高度不符时视为违反协议
+	items := resp.GetMessage().GetItems()
+	if len(items) == 0 || items[0].GetBlock() == nil || items[0].GetBlock().GetHeight() != blockheight {
+		log.Error("handleEvent", "pid", task.Pid, "height", blockheight, "err", errInvalidBlock)
+		d.GetScoreManager().Report(task.Pid, types.PeerFaultProtocol)
+		d.releaseJob(task)
+		tasks = tasks.Remove(task)
+		goto ReDownload
+	}
+	block := items[0].GetBlock()
 	remotePid := task.Pid.Pretty()
 	costTime := (time.Now().UnixNano() - downloadStart) / 1e6
 
 	log.Debug("download+++++", "from", remotePid, "blockheight", block.GetHeight(),
 		"blockSize (bytes)", block.Size(), "costTime ms", costTime)
 
+	d.GetScoreManager().Report(task.Pid, types.PeerGoodMsg)
 	client := d.GetQueueClient()
 	newmsg := client.NewMessage("blockchain", types.EventSyncBlock, &types.BlockPid{Pid: remotePid, Block: block}) //加入到输出通道)
 	client.SendTimeout(newmsg, false, 10*time.Second)
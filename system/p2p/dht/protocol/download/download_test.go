@@ -79,7 +79,6 @@ func newTestProtocolWithQueue(q queue.Queue) *downloadProtol {
 	env := newTestEnv(q)
 	protocol := &downloadProtol{}
 	protocol.BaseProtocol = new(prototypes.BaseProtocol)
-	prototypes.ClearEventHandler()
 	protocol.InitProtocol(env)
 
 	return protocol
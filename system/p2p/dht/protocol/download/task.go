@@ -72,7 +72,7 @@ func (d *downloadProtol) initJob(pids []string, jobID string) Tasks {
 	}
 	latency := d.GetConnsManager().GetLatencyByPeer(pIDs)
 	for _, pID := range pIDs {
-		if pID.Pretty() == d.GetHost().ID().Pretty() {
+		if pID.Pretty() == d.GetHost().ID().Pretty() || d.GetScoreManager().IsBanned(pID) {
 			continue
 		}
 		var job TaskInfo
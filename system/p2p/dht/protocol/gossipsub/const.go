@@ -0,0 +1,31 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gossipsub
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// 已发布的交易和区块哈希过滤缓存, 避免重复发布
+	sendFilterCacheNum = 10240
+	// 已接收的交易和区块哈希过滤缓存, 避免重复校验
+	recvFilterCacheNum = 20480
+	// 每个主题同时进行的消息校验数
+	maxConcurrentValidate = 64
+	// 消息校验超时时间
+	validateTimeout = time.Second * 10
+	// pubsub单条消息的大小上限, 需要预留消息头的空间
+	pubsubMaxMsgSize = 1<<20 - 1024
+)
+
+// 内部自定义错误
+var (
+	errMsgTooBig     = errors.New("errMsgTooBig")
+	errMerkleRoot    = errors.New("errMerkleRoot")
+	errBlockSign     = errors.New("errBlockSign")
+	errUnknownParent = errors.New("errUnknownParent")
+)
@@ -0,0 +1,143 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gossipsub 基于libp2p pubsub的gossipsub路由广播交易和区块
+//
+// 交易和区块分别在独立的主题内传播, 主题名称包含p2p网络频道. mesh维护以及ihave/iwant消息摘要交换由go-libp2p-pubsub完成,
+// 本包只负责发布本地的交易和区块, 并为每个主题注册校验函数: 收到的消息需要先通过mempool或blockchain相关校验才会继续转发,
+// 发送无效数据的节点上报到节点评分服务, 评分过低被封禁的节点会被断开连接, 其消息直接丢弃.
+// 区块在转发前需要校验签名, 并且父区块必须是本地主链上的区块.
+//
+// 当前依赖的pubsub版本单条消息上限为1MB, 超过上限的区块不通过gossipsub广播, 转由broadcast协议以紧凑区块或普通方式广播.
+package gossipsub
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/p2p/utils"
+	"github.com/33cn/chain33/queue"
+	prototypes "github.com/33cn/chain33/system/p2p/dht/protocol/types"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	"github.com/33cn/chain33/types"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+var log = log15.New("module", "p2p.gossipsub")
+
+const (
+	protoTypeID = "GossipSubProtocolType"
+)
+
+func init() {
+	prototypes.RegisterProtocol(protoTypeID, &gossipSubProtocol{})
+}
+
+type gossipSubProtocol struct {
+	*prototypes.BaseProtocol
+
+	txTopic    string
+	blockTopic string
+	ps         *pubsub.PubSub
+	//已发布和已接收的交易及区块哈希, pubsub按发布者和序号去重, 同一交易可能由多个节点发布
+	sendFilter *utils.Filterdata
+	recvFilter *utils.Filterdata
+}
+
+// InitProtocol init protocol, 只有配置broadcastProtocol为gossipsub时才会启用
+func (protocol *gossipSubProtocol) InitProtocol(env *prototypes.P2PEnv) {
+	protocol.BaseProtocol = new(prototypes.BaseProtocol)
+	protocol.P2PEnv = env
+	if env.SubConfig.BroadcastProtocol != p2pty.BroadcastGossipSub {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-env.Host.Network().Process().Closing()
+		cancel()
+	}()
+	ps, err := pubsub.NewGossipSub(ctx, env.Host)
+	if err != nil {
+		log.Error("InitProtocol", "new gossipsub err", err)
+		cancel()
+		return
+	}
+	protocol.ps = ps
+	protocol.sendFilter = utils.NewFilter(sendFilterCacheNum)
+	protocol.recvFilter = utils.NewFilter(recvFilterCacheNum)
+	protocol.txTopic = fmt.Sprintf("/%s/tx/%d", env.ChainCfg.GetTitle(), env.SubConfig.Channel)
+	protocol.blockTopic = fmt.Sprintf("/%s/block/%d", env.ChainCfg.GetTitle(), env.SubConfig.Channel)
+	validators := map[string]pubsub.Validator{
+		protocol.txTopic:    protocol.newValidator(protocol.validateTx, types.PeerFaultInvalidTx),
+		protocol.blockTopic: protocol.newValidator(protocol.validateBlock, types.PeerFaultInvalidBlock),
+	}
+	for topic, validate := range validators {
+		err = ps.RegisterTopicValidator(topic, validate, pubsub.WithValidatorTimeout(validateTimeout),
+			pubsub.WithValidatorConcurrency(maxConcurrentValidate))
+		if err != nil {
+			log.Error("InitProtocol", "topic", topic, "register validator err", err)
+			return
+		}
+		sub, err := ps.Subscribe(topic)
+		if err != nil {
+			log.Error("InitProtocol", "topic", topic, "subscribe err", err)
+			return
+		}
+		go drainSubscription(ctx, sub)
+	}
+
+	env.RegisterEventHandler(types.EventTxBroadcast, protocol.handleEvent)
+	env.RegisterEventHandler(types.EventBlockBroadcast, protocol.handleEvent)
+}
+
+// drainSubscription 订阅主题以加入mesh, 消息在校验时已经提交到mempool或blockchain, 这里直接丢弃
+func drainSubscription(ctx context.Context, sub *pubsub.Subscription) {
+	defer sub.Cancel()
+	for {
+		if _, err := sub.Next(ctx); err != nil {
+			return
+		}
+	}
+}
+
+func (protocol *gossipSubProtocol) handleEvent(msg *queue.Message) {
+	var topic string
+	var hash []byte
+	switch data := msg.GetData().(type) {
+	case *types.Transaction:
+		topic, hash = protocol.txTopic, data.Hash()
+	case *types.Block:
+		topic, hash = protocol.blockTopic, data.Hash(protocol.GetChainCfg())
+	default:
+		return
+	}
+	key := hex.EncodeToString(hash)
+	//已发布或者从其他节点收到的数据不再发布
+	if protocol.recvFilter.Contains(key) || protocol.sendFilter.AddWithCheckAtomic(key, true) {
+		return
+	}
+	data := types.Encode(msg.GetData().(types.Message))
+	if len(data) > pubsubMaxMsgSize {
+		protocol.broadcastLargeBlock(msg, key, len(data))
+		return
+	}
+	err := protocol.ps.Publish(topic, data)
+	if err != nil {
+		log.Error("handleEvent", "topic", topic, "hash", key, "publish err", err)
+	}
+}
+
+// broadcastLargeBlock 超过pubsub消息上限的区块交给broadcast协议广播, 交易大小受mempool限制不会超过上限
+func (protocol *gossipSubProtocol) broadcastLargeBlock(msg *queue.Message, key string, size int) {
+	handler, ok := protocol.GetEventHandler(prototypes.EventLargeBlockBroadcast)
+	if _, isBlock := msg.GetData().(*types.Block); !isBlock || !ok {
+		log.Warn("broadcastLargeBlock", "hash", key, "size", size, "err", errMsgTooBig)
+		return
+	}
+	log.Debug("broadcastLargeBlock", "hash", key, "size", size)
+	handler(msg)
+}
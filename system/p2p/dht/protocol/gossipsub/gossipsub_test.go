@@ -0,0 +1,351 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gossipsub
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	commlog "github.com/33cn/chain33/common/log"
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/p2p"
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/system/p2p/dht/manage"
+	prototypes "github.com/33cn/chain33/system/p2p/dht/protocol/types"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	commlog.SetLogLevel("error")
+}
+
+type testNode struct {
+	protocol *gossipSubProtocol
+	host     core.Host
+	scorer   *manage.ScoreManager
+	txs      chan *types.Transaction
+	blocks   chan *types.BlockPid
+}
+
+type memBanStore struct {
+	mtx  sync.Mutex
+	bans map[string]*types.BannedPeer
+}
+
+func (m *memBanStore) SaveBan(ban *types.BannedPeer) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.bans[ban.Pid] = ban
+	return nil
+}
+
+func (m *memBanStore) DeleteBan(pid string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.bans, pid)
+	return nil
+}
+
+func (m *memBanStore) LoadBans() []*types.BannedPeer {
+	return nil
+}
+
+// 模拟的blockchain中主链上区块的哈希
+var testParentHash = []byte("test parent hash")
+
+// newTestNodes 在内存网络中创建n个启用gossipsub的节点, 模拟的mempool接收所有交易, 签名为空的交易返回ErrSign
+func newTestNodes(t *testing.T, n int) ([]*testNode, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	//pubsub校验消息签名, 不能使用mocknet默认生成的测试密钥
+	mn := mocknet.New(ctx)
+	for i := 0; i < n; i++ {
+		priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
+		require.Nil(t, err)
+		_, err = mn.AddPeer(priv, multiaddr.StringCast(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", 13800+i)))
+		require.Nil(t, err)
+	}
+	require.Nil(t, mn.LinkAll())
+
+	cfg := types.NewChain33Config(types.ReadFile("../../../../../cmd/chain33/chain33.test.toml"))
+	var nodes []*testNode
+	var queues []queue.Queue
+	for _, host := range mn.Hosts() {
+		q := queue.New("channel")
+		q.SetConfig(cfg)
+		go q.Start()
+		queues = append(queues, q)
+
+		node := &testNode{
+			host:   host,
+			txs:    make(chan *types.Transaction, 16),
+			blocks: make(chan *types.BlockPid, 16),
+		}
+		mempool := q.Client()
+		mempool.Sub("mempool")
+		go func() {
+			for msg := range mempool.Recv() {
+				tx := msg.GetData().(*types.Transaction)
+				if tx.Signature == nil {
+					msg.Reply(mempool.NewMessage("", types.EventReply, &types.Reply{Msg: []byte(types.ErrSign.Error())}))
+					continue
+				}
+				node.txs <- tx
+				msg.Reply(mempool.NewMessage("", types.EventReply, &types.Reply{IsOk: true}))
+			}
+		}()
+		blockchain := q.Client()
+		blockchain.Sub("blockchain")
+		go func() {
+			for msg := range blockchain.Recv() {
+				if msg.Ty == types.EventGetHeaders {
+					req := msg.GetData().(*types.ReqBlocks)
+					header := &types.Header{Height: req.Start, Hash: testParentHash}
+					msg.Reply(blockchain.NewMessage("", types.EventHeaders, &types.Headers{Items: []*types.Header{header}}))
+					continue
+				}
+				node.blocks <- msg.GetData().(*types.BlockPid)
+			}
+		}()
+
+		mgr := p2p.NewP2PMgr(cfg)
+		mgr.Client = q.Client()
+		node.scorer = manage.NewScoreManager(host, &memBanStore{bans: make(map[string]*types.BannedPeer)})
+		env := &prototypes.P2PEnv{
+			ChainCfg:     cfg,
+			QueueClient:  q.Client(),
+			Host:         host,
+			P2PManager:   mgr,
+			ScoreManager: node.scorer,
+			SubConfig:    &p2pty.P2PSubConfig{Channel: 1, BroadcastProtocol: p2pty.BroadcastGossipSub},
+		}
+		node.protocol = &gossipSubProtocol{}
+		node.protocol.InitProtocol(env)
+		require.NotNil(t, node.protocol.ps)
+		nodes = append(nodes, node)
+	}
+	require.Nil(t, mn.ConnectAllButSelf())
+
+	return nodes, func() {
+		for _, node := range nodes {
+			node.scorer.Close()
+		}
+		for _, host := range mn.Hosts() {
+			_ = host.Close()
+		}
+		cancel()
+		for _, q := range queues {
+			q.Close()
+		}
+	}
+}
+
+func newTestTx(note string) *types.Transaction {
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte(note), Fee: 100000}
+	tx.Signature = &types.Signature{Ty: 1, Signature: []byte(note)}
+	return tx
+}
+
+func waitMeshReady(t *testing.T, nodes []*testNode) {
+	for i := 0; i < 50; i++ {
+		ready := true
+		for _, node := range nodes {
+			for _, topic := range []string{node.protocol.txTopic, node.protocol.blockTopic} {
+				if len(node.protocol.ps.ListPeers(topic)) < len(nodes)-1 {
+					ready = false
+				}
+			}
+		}
+		if ready {
+			//订阅通告到达之后, 下一次心跳时才会加入mesh
+			time.Sleep(pubsub.GossipSubHeartbeatInterval * 2)
+			return
+		}
+		time.Sleep(time.Millisecond * 200)
+	}
+	t.Fatal("gossipsub mesh not ready")
+}
+
+// publishRaw 绕过本地的事件处理和发送过滤, 直接在主题内发布数据, 模拟恶意节点
+func publishRaw(t *testing.T, node *testNode, topic string, msg types.Message) {
+	require.Nil(t, node.protocol.ps.Publish(topic, types.Encode(msg)))
+}
+
+func TestGossipSubTx(t *testing.T) {
+	nodes, closer := newTestNodes(t, 4)
+	defer closer()
+	waitMeshReady(t, nodes)
+
+	tx := newTestTx("gossip tx")
+	nodes[0].protocol.handleEvent(queue.NewMessage(0, "p2p", types.EventTxBroadcast, tx))
+	for _, node := range nodes[1:] {
+		select {
+		case recv := <-node.txs:
+			assert.Equal(t, tx.Hash(), recv.Hash())
+		case <-time.After(time.Second * 5):
+			t.Fatal("wait tx timeout")
+		}
+	}
+
+	//重复发布不会再次转发
+	nodes[0].protocol.handleEvent(queue.NewMessage(0, "p2p", types.EventTxBroadcast, tx))
+	for _, node := range nodes[1:] {
+		select {
+		case <-node.txs:
+			t.Fatal("duplicate tx")
+		case <-time.After(time.Millisecond * 500):
+		}
+	}
+}
+
+func TestGossipSubBlock(t *testing.T) {
+	nodes, closer := newTestNodes(t, 3)
+	defer closer()
+	waitMeshReady(t, nodes)
+
+	cfg := nodes[0].protocol.GetChainCfg()
+	block := newTestBlock(cfg, 10)
+	nodes[0].protocol.handleEvent(queue.NewMessage(0, "p2p", types.EventBlockBroadcast, block))
+	for _, node := range nodes[1:] {
+		select {
+		case recv := <-node.blocks:
+			assert.Equal(t, block.Hash(cfg), recv.Block.Hash(cfg))
+		case <-time.After(time.Second * 5):
+			t.Fatal("wait block timeout")
+		}
+	}
+}
+
+func newTestBlock(cfg *types.Chain33Config, height int64) *types.Block {
+	_, priv := util.Genaddress()
+	block := &types.Block{Height: height, ParentHash: testParentHash, Txs: []*types.Transaction{util.CreateNoneTx(cfg, priv)}}
+	block.TxHash = merkle.CalcMerkleRoot(cfg, block.Height, block.Txs)
+	return block
+}
+
+func TestGossipSubInvalidBlock(t *testing.T) {
+	nodes, closer := newTestNodes(t, 2)
+	defer closer()
+	waitMeshReady(t, nodes)
+	node, attacker := nodes[0], nodes[1]
+	cfg := node.protocol.GetChainCfg()
+	topic := node.protocol.blockTopic
+
+	//父区块不在本地主链上, 只提交到blockchain, 不转发也不惩罚
+	block := newTestBlock(cfg, 11)
+	block.ParentHash = []byte("unknown parent")
+	publishRaw(t, attacker, topic, block)
+	select {
+	case recv := <-node.blocks:
+		assert.Equal(t, block.Height, recv.Block.Height)
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait block timeout")
+	}
+	assert.Equal(t, float64(0), node.scorer.Score(attacker.host.ID()))
+
+	//交易签名错误
+	block = newTestBlock(cfg, 12)
+	block.Txs[0].Signature.Signature = []byte("invalid sign")
+	block.TxHash = merkle.CalcMerkleRoot(cfg, block.Height, block.Txs)
+	publishRaw(t, attacker, topic, block)
+	var score float64
+	for i := 0; i < 20 && score >= 0; i++ {
+		time.Sleep(time.Millisecond * 100)
+		score = node.scorer.Score(attacker.host.ID())
+	}
+	assert.True(t, score < 0)
+	select {
+	case <-node.blocks:
+		t.Fatal("invalid block accepted")
+	default:
+	}
+}
+
+func TestGossipSubInvalidTx(t *testing.T) {
+	nodes, closer := newTestNodes(t, 2)
+	defer closer()
+	waitMeshReady(t, nodes)
+	node, attacker := nodes[0], nodes[1]
+	topic := node.protocol.txTopic
+
+	//无法解析的数据
+	require.Nil(t, attacker.protocol.ps.Publish(topic, []byte("invalid tx")))
+	//mempool校验签名失败
+	tx := newTestTx("invalid sign")
+	tx.Signature = nil
+	publishRaw(t, attacker, topic, tx)
+
+	var score float64
+	for i := 0; i < 20 && score > -20; i++ {
+		time.Sleep(time.Millisecond * 100)
+		score = node.scorer.Score(attacker.host.ID())
+	}
+	assert.Equal(t, float64(-20), score)
+	select {
+	case <-node.txs:
+		t.Fatal("invalid tx accepted")
+	default:
+	}
+
+	//被封禁节点的消息直接丢弃
+	require.Nil(t, node.scorer.Ban(attacker.host.ID(), time.Minute, "test"))
+	publishRaw(t, attacker, topic, newTestTx("banned"))
+	select {
+	case <-node.txs:
+		t.Fatal("tx from banned peer accepted")
+	case <-time.After(time.Millisecond * 500):
+	}
+}
+
+func TestValidateTxRecvFilter(t *testing.T) {
+	nodes, closer := newTestNodes(t, 1)
+	defer closer()
+	protocol := nodes[0].protocol
+	pid := nodes[0].host.ID()
+
+	//mempool没有接收的交易不记录, 之后还可以再次提交
+	tx := newTestTx("recv filter")
+	tx.Signature = nil
+	assert.Equal(t, validationReject, protocol.validateTx(pid, types.Encode(tx)))
+	assert.False(t, protocol.recvFilter.Contains(hex.EncodeToString(tx.Hash())))
+
+	tx = newTestTx("recv filter")
+	assert.Equal(t, validationAccept, protocol.validateTx(pid, types.Encode(tx)))
+	assert.True(t, protocol.recvFilter.Contains(hex.EncodeToString(tx.Hash())))
+	assert.Equal(t, validationIgnore, protocol.validateTx(pid, types.Encode(tx)))
+}
+
+func TestGossipSubLargeBlock(t *testing.T) {
+	nodes, closer := newTestNodes(t, 1)
+	defer closer()
+	protocol := nodes[0].protocol
+	cfg := protocol.GetChainCfg()
+
+	large := make(chan *types.Block, 1)
+	protocol.RegisterEventHandler(prototypes.EventLargeBlockBroadcast, func(msg *queue.Message) {
+		large <- msg.GetData().(*types.Block)
+	})
+	block := newTestBlock(cfg, 10)
+	block.Txs[0].Payload = make([]byte, pubsubMaxMsgSize)
+	protocol.handleEvent(queue.NewMessage(0, "p2p", types.EventBlockBroadcast, block))
+	select {
+	case recv := <-large:
+		assert.Equal(t, block.Hash(cfg), recv.Hash(cfg))
+	default:
+		t.Fatal("large block not broadcast")
+	}
+}
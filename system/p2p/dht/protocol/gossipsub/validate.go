@@ -0,0 +1,157 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gossipsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/types"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// validationResult 消息校验结果
+type validationResult int
+
+const (
+	validationAccept validationResult = iota
+	// 消息不需要转发, 但不惩罚发送节点, 比如交易已经存在或本地mempool已满
+	validationIgnore
+	// 无效消息, 不转发并惩罚发送节点
+	validationReject
+)
+
+// validator 主题消息校验函数, data为序列化后的交易或区块
+type validator func(pid peer.ID, data []byte) validationResult
+
+// mempool返回这些错误说明交易无效, 需要惩罚发送节点, 其他错误如交易已存在, mempool已满或未同步完成只是不再转发
+var invalidTxErrs = []error{
+	types.ErrSign,
+	types.ErrEmptyTx,
+	types.ErrTxMsgSizeTooBig,
+	types.ErrInvalidAddress,
+}
+
+func txCheckResult(errMsg string) validationResult {
+	for _, err := range invalidTxErrs {
+		if errMsg == err.Error() {
+			return validationReject
+		}
+	}
+	return validationIgnore
+}
+
+// newValidator 转换为pubsub主题校验函数, 本节点发布的消息不需要校验, 校验不通过的消息上报到节点评分服务
+// 被封禁的节点会被断开连接, 断开之前收到的或者由其他节点转发的被封禁节点的消息直接丢弃
+// 没有使用pubsub的黑名单选项, 当前版本在黑名单节点重建流时会重复关闭发送通道
+func (protocol *gossipSubProtocol) newValidator(validate validator, faultTy int32) pubsub.Validator {
+	return func(ctx context.Context, pid peer.ID, msg *pubsub.Message) bool {
+		if pid == protocol.Host.ID() {
+			return true
+		}
+		scorer := protocol.GetScoreManager()
+		if scorer.IsBanned(pid) || scorer.IsBanned(msg.GetFrom()) {
+			return false
+		}
+		result := validate(pid, msg.GetData())
+		if result == validationReject {
+			scorer.Report(pid, faultTy)
+		}
+		return result == validationAccept
+	}
+}
+
+// received 记录从其他节点收到的数据, 已经收到过的返回true
+func (protocol *gossipSubProtocol) received(hash []byte) bool {
+	return protocol.recvFilter.AddWithCheckAtomic(hex.EncodeToString(hash), true)
+}
+
+// validateTx 交易提交到mempool校验, mempool接收后才继续转发
+
+// validateTx 交易提交到mempool校验, mempool接收后才继续转发
+func (protocol *gossipSubProtocol) validateTx(pid peer.ID, data []byte) validationResult {
+	tx := &types.Transaction{}
+	if err := types.Decode(data, tx); err != nil {
+		log.Error("validateTx", "pid", pid.Pretty(), "decode err", err)
+		return validationReject
+	}
+	hash := hex.EncodeToString(tx.Hash())
+	if protocol.recvFilter.Contains(hash) {
+		return validationIgnore
+	}
+	resp, err := protocol.QueryMempool(types.EventTx, tx)
+	if err != nil {
+		log.Error("validateTx", "pid", pid.Pretty(), "query mempool err", err)
+		return validationIgnore
+	}
+	reply, ok := resp.(*types.Reply)
+	if !ok {
+		return validationIgnore
+	}
+	if reply.IsOk {
+		if protocol.recvFilter.AddWithCheckAtomic(hash, true) {
+			return validationIgnore
+		}
+		return validationAccept
+	}
+	return txCheckResult(string(reply.Msg))
+}
+
+// validateBlock 校验区块哈希, 交易默克尔根和签名, 并且父区块是本地主链上的区块才继续转发
+// 本地还不能确认父区块(落后于对方或者在其他分叉上)时只提交到blockchain, 不转发也不惩罚发送节点
+func (protocol *gossipSubProtocol) validateBlock(pid peer.ID, data []byte) validationResult {
+	block := &types.Block{}
+	if err := types.Decode(data, block); err != nil {
+		log.Error("validateBlock", "pid", pid.Pretty(), "decode err", err)
+		return validationReject
+	}
+	cfg := protocol.GetChainCfg()
+	hash := block.Hash(cfg)
+	if protocol.received(hash) {
+		return validationIgnore
+	}
+	if err := checkBlock(cfg, block); err != nil {
+		log.Error("validateBlock", "pid", pid.Pretty(), "height", block.Height, "err", err)
+		return validationReject
+	}
+	if !block.CheckSign(cfg) {
+		log.Error("validateBlock", "pid", pid.Pretty(), "height", block.Height, "err", errBlockSign)
+		return validationReject
+	}
+	result := protocol.checkParent(block)
+	err := protocol.P2PManager.PubBroadCast(hex.EncodeToString(hash), &types.BlockPid{Pid: pid.Pretty(), Block: block}, types.EventBroadcastAddBlock)
+	if err != nil {
+		log.Error("validateBlock", "pid", pid.Pretty(), "height", block.Height, "post blockchain err", err)
+	}
+	return result
+}
+
+// checkParent 父区块必须是本地主链上height-1高度的区块
+func (protocol *gossipSubProtocol) checkParent(block *types.Block) validationResult {
+	if block.Height <= 0 {
+		return validationIgnore
+	}
+	resp, err := protocol.QueryBlockChain(types.EventGetHeaders, &types.ReqBlocks{Start: block.Height - 1, End: block.Height - 1})
+	if err != nil {
+		log.Debug("checkParent", "height", block.Height, "get parent header err", err)
+		return validationIgnore
+	}
+	headers, ok := resp.(*types.Headers)
+	if !ok || len(headers.GetItems()) == 0 || !bytes.Equal(headers.Items[0].Hash, block.ParentHash) {
+		log.Debug("checkParent", "height", block.Height, "err", errUnknownParent)
+		return validationIgnore
+	}
+	return validationAccept
+}
+
+func checkBlock(cfg *types.Chain33Config, block *types.Block) error {
+	if !bytes.Equal(block.TxHash, merkle.CalcMerkleRoot(cfg, block.Height, block.Txs)) {
+		return errMerkleRoot
+	}
+	return nil
+}
@@ -12,9 +12,9 @@ var (
 )
 
 // HandleEvent handle p2p event
-func HandleEvent(msg *queue.Message) {
+func HandleEvent(env *types.P2PEnv, msg *queue.Message) {
 
-	if eventHander, ok := types.GetEventHandler(msg.Ty); ok {
+	if eventHander, ok := env.GetEventHandler(msg.Ty); ok {
 		log.Debug("HandleEvent", "msgTy", msg.Ty)
 		eventHander(msg)
 	} else {
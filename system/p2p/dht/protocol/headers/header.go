@@ -34,7 +34,8 @@ type headerInfoProtol struct {
 
 func (h *headerInfoProtol) InitProtocol(env *prototypes.P2PEnv) {
 	h.P2PEnv = env
-	prototypes.RegisterEventHandler(types.EventFetchBlockHeaders, h.handleEvent)
+	env.RegisterEventHandler(types.EventFetchBlockHeaders, h.handleEvent)
+	env.LimitManager.SetRequestLimit(HeaderInfoReq, env.SubConfig.HeadersReqRate)
 }
 
 func (h *headerInfoProtol) processReq(id string, getheaders *types.P2PGetHeaders) (*types.MessageHeaderResp, error) {
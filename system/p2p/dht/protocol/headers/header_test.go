@@ -74,7 +74,6 @@ func newTestProtocolWithQueue(q queue.Queue) *headerInfoProtol {
 	env := newTestEnv(q)
 	protocol := &headerInfoProtol{}
 	protocol.BaseProtocol = new(prototypes.BaseProtocol)
-	prototypes.ClearEventHandler()
 	protocol.InitProtocol(env)
 
 	return protocol
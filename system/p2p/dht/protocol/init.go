@@ -3,6 +3,7 @@ package protocol
 import (
 	_ "github.com/33cn/chain33/system/p2p/dht/protocol/broadcast" //广播协议
 	_ "github.com/33cn/chain33/system/p2p/dht/protocol/download"  //区块下载协议
+	_ "github.com/33cn/chain33/system/p2p/dht/protocol/gossipsub" //gossipsub广播协议
 	_ "github.com/33cn/chain33/system/p2p/dht/protocol/headers"   //区块头拉取
 	_ "github.com/33cn/chain33/system/p2p/dht/protocol/peer"      //邻居节点维护
 	prototypes "github.com/33cn/chain33/system/p2p/dht/protocol/types"
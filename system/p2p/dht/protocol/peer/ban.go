@@ -0,0 +1,44 @@
+package peer
+
+import (
+	"time"
+
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/types"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// reportHandleEvent 其他模块上报的节点异常行为, 如blockchain执行区块失败
+func (p *peerInfoProtol) reportHandleEvent(msg *queue.Message) {
+	report := msg.GetData().(*types.PeerFaultReport)
+	pid, err := peer.IDB58Decode(report.GetPid())
+	if err != nil {
+		log.Debug("reportHandleEvent", "pid", report.GetPid(), "decode err", err)
+		return
+	}
+	log.Debug("reportHandleEvent", "pid", report.GetPid(), "ty", report.GetTy(), "errInfo", report.GetErrInfo())
+	p.GetScoreManager().Report(pid, report.GetTy())
+}
+
+func (p *peerInfoProtol) banHandleEvent(msg *queue.Message) {
+	req := msg.GetData().(*types.ReqBanPeer)
+	pid, err := peer.IDB58Decode(req.GetPid())
+	if err == nil {
+		err = p.GetScoreManager().Ban(pid, time.Duration(req.GetDuration())*time.Second, req.GetReason())
+	}
+	msg.ReplyErr("banHandleEvent", err)
+}
+
+func (p *peerInfoProtol) unbanHandleEvent(msg *queue.Message) {
+	req := msg.GetData().(*types.ReqBanPeer)
+	pid, err := peer.IDB58Decode(req.GetPid())
+	if err == nil {
+		err = p.GetScoreManager().Unban(pid)
+	}
+	msg.ReplyErr("unbanHandleEvent", err)
+}
+
+func (p *peerInfoProtol) bannedHandleEvent(msg *queue.Message) {
+	bans := &types.BannedPeers{Items: p.GetScoreManager().BannedPeers()}
+	msg.Reply(p.GetQueueClient().NewMessage("rpc", types.EventGetBannedPeers, bans))
+}
@@ -16,6 +16,7 @@ func (p *peerInfoProtol) netinfoHandleEvent(msg *queue.Message) {
 	netinfo.Localaddr = strings.Split(p.GetHost().Addrs()[0].String(), "/")[2]
 	netinfo.Outbounds = int32(outsize)
 	netinfo.Inbounds = int32(insize)
+	netinfo.Reachability = p.NatManager.Reachability()
 	netinfo.Service = false
 	if netinfo.Inbounds != 0 {
 		netinfo.Service = true
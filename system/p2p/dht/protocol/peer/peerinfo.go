@@ -42,8 +42,14 @@ type peerInfoProtol struct {
 func (p *peerInfoProtol) InitProtocol(env *prototypes.P2PEnv) {
 	p.P2PEnv = env
 	p.p2pCfg = env.SubConfig
-	prototypes.RegisterEventHandler(types.EventPeerInfo, p.handleEvent)
-	prototypes.RegisterEventHandler(types.EventGetNetInfo, p.netinfoHandleEvent)
+	env.RegisterEventHandler(types.EventPeerInfo, p.handleEvent)
+	env.RegisterEventHandler(types.EventGetNetInfo, p.netinfoHandleEvent)
+	env.RegisterEventHandler(types.EventReportFaultPeer, p.reportHandleEvent)
+	env.RegisterEventHandler(types.EventBanPeer, p.banHandleEvent)
+	env.RegisterEventHandler(types.EventUnbanPeer, p.unbanHandleEvent)
+	env.RegisterEventHandler(types.EventGetBannedPeers, p.bannedHandleEvent)
+	env.LimitManager.SetRequestLimit(PeerInfoReq, env.SubConfig.PeerInfoReqRate)
+	env.LimitManager.SetRequestLimit(PeerVersionReq, env.SubConfig.PeerInfoReqRate)
 	go p.detectNodeAddr()
 	go p.fetchPeersInfo()
 
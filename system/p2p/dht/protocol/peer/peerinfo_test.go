@@ -82,7 +82,6 @@ func newTestProtocolWithQueue(q queue.Queue) *peerInfoProtol {
 	env := newTestEnv(q)
 	protocol := &peerInfoProtol{}
 	protocol.BaseProtocol = new(prototypes.BaseProtocol)
-	prototypes.ClearEventHandler()
 	protocol.InitProtocol(env)
 
 	return protocol
@@ -12,30 +12,64 @@ import (
 // EventHandler handle chain33 event
 type EventHandler func(*queue.Message)
 
+// EventLargeBlockBroadcast p2p内部事件, 配置为gossipsub时超过pubsub消息上限的区块转由broadcast协议广播
+// 使用负数避免与chain33全局事件类型冲突
+const EventLargeBlockBroadcast int64 = -1
+
 var (
-	eventHandlerMap = make(map[int64]EventHandler)
+	//通过包级别接口注册的事件处理函数, 对进程内所有的p2p实例生效
+	globalEventHandlerMap = make(map[int64]EventHandler)
 )
 
-// RegisterEventHandler 注册消息处理函数
+// RegisterEventHandler 注册全局的消息处理函数, 所有P2PEnv都可以获取到
+//
+// Deprecated: 同一进程内有多个p2p实例时事件会被重复处理, 使用P2PEnv.RegisterEventHandler
 func RegisterEventHandler(eventID int64, handler EventHandler) {
 
 	if handler == nil {
 		panic(fmt.Sprintf("addEventHandler, handler is nil, id=%d", eventID))
 	}
-	if _, dup := eventHandlerMap[eventID]; dup {
+	if _, dup := globalEventHandlerMap[eventID]; dup {
 		panic(fmt.Sprintf("addEventHandler, duplicate handler, id=%d", eventID))
 	}
-	eventHandlerMap[eventID] = handler
+	globalEventHandlerMap[eventID] = handler
 }
 
-// GetEventHandler get event handler
+// GetEventHandler get global event handler
+//
+// Deprecated: 使用P2PEnv.GetEventHandler
 func GetEventHandler(eventID int64) (EventHandler, bool) {
-	handler, ok := eventHandlerMap[eventID]
+	handler, ok := globalEventHandlerMap[eventID]
 
 	return handler, ok
 }
 
-// ClearEventHandler clear event handler map
+// ClearEventHandler clear global event handler map
+//
+// Deprecated: 事件处理函数保存在P2PEnv中, 不再需要清理
 func ClearEventHandler() {
-	eventHandlerMap = make(map[int64]EventHandler)
+	globalEventHandlerMap = make(map[int64]EventHandler)
+}
+
+// RegisterEventHandler 注册消息处理函数, 事件处理函数保存在各自的P2PEnv中, 同一进程内的多个p2p实例互不影响
+func (env *P2PEnv) RegisterEventHandler(eventID int64, handler EventHandler) {
+
+	if handler == nil {
+		panic(fmt.Sprintf("addEventHandler, handler is nil, id=%d", eventID))
+	}
+	if env.eventHandlerMap == nil {
+		env.eventHandlerMap = make(map[int64]EventHandler)
+	}
+	if _, dup := env.eventHandlerMap[eventID]; dup {
+		panic(fmt.Sprintf("addEventHandler, duplicate handler, id=%d", eventID))
+	}
+	env.eventHandlerMap[eventID] = handler
+}
+
+// GetEventHandler get event handler, 没有注册时使用全局注册的事件处理函数
+func (env *P2PEnv) GetEventHandler(eventID int64) (EventHandler, bool) {
+	if handler, ok := env.eventHandlerMap[eventID]; ok {
+		return handler, ok
+	}
+	return GetEventHandler(eventID)
 }
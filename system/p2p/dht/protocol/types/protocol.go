@@ -61,10 +61,16 @@ type P2PEnv struct {
 	QueueClient     queue.Client
 	Host            core.Host
 	ConnManager     *manage.ConnManager
+	ScoreManager    *manage.ScoreManager
+	LimitManager    *manage.LimitManager
+	NatManager      *net.NatManager
 	PeerInfoManager *manage.PeerInfoManager
+	PermManager     *manage.PermissionManager
 	Discovery       *net.Discovery
 	P2PManager      *p2p.Manager
 	SubConfig       *p2pty.P2PSubConfig
+
+	eventHandlerMap map[int64]EventHandler
 }
 
 // BaseProtocol store public data
@@ -161,6 +167,16 @@ func (base *BaseProtocol) GetConnsManager() *manage.ConnManager {
 
 }
 
+// GetScoreManager get peer score manager
+func (base *BaseProtocol) GetScoreManager() *manage.ScoreManager {
+	return base.ScoreManager
+}
+
+// GetLimitManager get bandwidth and request rate limit manager
+func (base *BaseProtocol) GetLimitManager() *manage.LimitManager {
+	return base.LimitManager
+}
+
 // GetPeerInfoManager get peer info manager
 func (base *BaseProtocol) GetPeerInfoManager() *manage.PeerInfoManager {
 	return base.PeerInfoManager
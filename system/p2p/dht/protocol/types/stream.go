@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/system/p2p/dht/manage"
 	"github.com/33cn/chain33/types"
 	core "github.com/libp2p/go-libp2p-core"
 )
@@ -92,6 +93,28 @@ func (s *BaseStreamHandler) GetProtocol() IProtocol {
 func (s *BaseStreamHandler) HandleStream(stream core.Stream) {
 	//log.Debug("BaseStreamHandler", "HandlerStream", stream.Conn().RemoteMultiaddr().String(), "proto", stream.Protocol())
 	//TODO verify校验放在这里
+	var limiter *manage.LimitManager
+	var scorer *manage.ScoreManager
+	var perm *manage.PermissionManager
+	if env := s.Protocol.GetP2PEnv(); env != nil {
+		limiter, scorer, perm = env.LimitManager, env.ScoreManager, env.PermManager
+	}
+	pid := stream.Conn().RemotePeer()
+	if !perm.IsAllowed(pid) {
+		//许可网络模式下不处理白名单之外节点的请求
+		log.Debug("HandleStream", "pid", pid, "protocol", stream.Protocol(), "err", manage.ErrPeerNotAllowed)
+		_ = stream.Reset()
+		return
+	}
+	if !limiter.AllowRequest(pid, string(stream.Protocol())) {
+		//请求过于频繁, 不处理请求直接回复限流响应, 请求方收到ErrPeerBusy, 并扣减节点评分
+		log.Debug("HandleStream", "pid", pid, "protocol", stream.Protocol(), "err", manage.ErrPeerBusy)
+		scorer.Report(pid, types.PeerFaultRateLimit)
+		_ = WriteBusyReply(stream)
+		CloseStream(stream)
+		return
+	}
+	stream = limiter.LimitStream(stream)
 	s.child.Handle(stream)
 	CloseStream(stream)
 }
@@ -17,7 +17,6 @@ func initProtocolMap() {
 
 	protocolTypeMap = make(map[string]reflect.Type)
 	streamHandlerTypeMap = make(map[string]reflect.Type)
-	eventHandlerMap = make(map[int64]EventHandler)
 }
 
 type testProtocol struct {
@@ -99,7 +98,7 @@ func testEventHandler(*queue.Message) {
 
 }
 
-func testRegisterEventPanic(eventID int64, handler EventHandler) (isPanic bool) {
+func testRegisterEventPanic(env *P2PEnv, eventID int64, handler EventHandler) (isPanic bool) {
 
 	defer func() {
 		if e := recover(); e != nil {
@@ -107,20 +106,37 @@ func testRegisterEventPanic(eventID int64, handler EventHandler) (isPanic bool)
 		}
 	}()
 
-	RegisterEventHandler(eventID, handler)
+	env.RegisterEventHandler(eventID, handler)
 	return false
 }
 
 func TestRegisterEventHandler(t *testing.T) {
 
-	initProtocolMap()
-	RegisterEventHandler(1, testEventHandler)
-	RegisterEventHandler(2, testEventHandler)
-	assert.True(t, testRegisterEventPanic(1, testEventHandler))
-	assert.True(t, testRegisterEventPanic(3, nil))
-	assert.Equal(t, int(2), len(eventHandlerMap))
-	_, exist := GetEventHandler(1)
+	env := &P2PEnv{}
+	env.RegisterEventHandler(1, testEventHandler)
+	env.RegisterEventHandler(2, testEventHandler)
+	assert.True(t, testRegisterEventPanic(env, 1, testEventHandler))
+	assert.True(t, testRegisterEventPanic(env, 3, nil))
+	assert.Equal(t, int(2), len(env.eventHandlerMap))
+	_, exist := env.GetEventHandler(1)
+	assert.True(t, exist)
+	//不同实例的事件处理函数互不影响
+	other := &P2PEnv{}
+	_, exist = other.GetEventHandler(1)
+	assert.False(t, exist)
+	other.RegisterEventHandler(1, testEventHandler)
+
+	//兼容全局注册的事件处理函数
+	RegisterEventHandler(4, testEventHandler)
+	defer ClearEventHandler()
+	_, exist = env.GetEventHandler(4)
+	assert.True(t, exist)
+	_, exist = other.GetEventHandler(4)
 	assert.True(t, exist)
+	assert.Panics(t, func() { RegisterEventHandler(4, testEventHandler) })
+	ClearEventHandler()
+	_, exist = GetEventHandler(4)
+	assert.False(t, exist)
 }
 
 func TestProtocolManager_Init(t *testing.T) {
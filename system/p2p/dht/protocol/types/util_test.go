@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/33cn/chain33/system/p2p/dht/manage"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
 	"github.com/33cn/chain33/types"
 	"github.com/libp2p/go-libp2p"
 	core "github.com/libp2p/go-libp2p-core"
@@ -128,3 +130,96 @@ func BenchmarkNewStream(b *testing.B) {
 		NewStream(h1, h2.ID(), msgID)
 	}
 }
+
+type peerInfoTestHandler struct {
+	*BaseStreamHandler
+}
+
+func (h *peerInfoTestHandler) Handle(s core.Stream) {
+	var req types.MessagePeerInfoReq
+	if ReadStream(&req, s) == nil {
+		_ = WriteStream(&types.MessagePeerInfoResp{MessageData: &types.MessageComm{Id: req.GetMessageData().GetId()},
+			Message: &types.P2PPeerInfo{Name: "test"}}, s)
+	}
+}
+
+func TestStreamRateLimit(t *testing.T) {
+	h1, h2 := newHostPair(13806, 13807)
+	defer h1.Close()
+	defer h2.Close()
+	err := h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	assert.Nil(t, err)
+
+	msgID := "/peerInfoTest"
+	limiter := manage.NewLimitManager(h2, &p2pty.P2PSubConfig{PeerUploadRate: 1024})
+	defer limiter.Close()
+	limiter.SetRequestLimit(msgID, 1)
+	remote := &testProtocol{}
+	remote.P2PEnv = &P2PEnv{Host: h2, LimitManager: limiter}
+	handler := &BaseStreamHandler{Protocol: remote, child: &peerInfoTestHandler{BaseStreamHandler: &BaseStreamHandler{}}}
+	h2.SetStreamHandler(protocol.ID(msgID), handler.HandleStream)
+
+	proto := &testProtocol{}
+	proto.P2PEnv = &P2PEnv{Host: h1}
+	req := &StreamRequest{
+		PeerID: h2.ID(),
+		MsgID:  msgID,
+		Data:   &types.MessagePeerInfoReq{MessageData: &types.MessageComm{Id: "1"}},
+	}
+	var resp types.MessagePeerInfoResp
+	err = proto.SendRecvPeer(req, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", resp.GetMessage().GetName())
+	assert.Equal(t, "1", resp.GetMessageData().GetId())
+	//超过频率限制返回busy
+	resp.Reset()
+	err = proto.SendRecvPeer(req, &resp)
+	assert.Equal(t, manage.ErrPeerBusy, err)
+	assert.Nil(t, resp.GetMessage())
+
+	//对端直接重置流不是限流响应, 由调用方按节点故障处理
+	resetID := "/resetTest"
+	h2.SetStreamHandler(protocol.ID(resetID), func(s core.Stream) { _ = s.Reset() })
+	req.MsgID = resetID
+	err = proto.SendRecvPeer(req, &resp)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, manage.ErrPeerBusy, err)
+}
+
+func TestBusyReply(t *testing.T) {
+	//限流响应解码为任意响应类型都能识别, 正常响应不会被误判
+	for _, resp := range []types.Message{&types.MessagePeerInfoResp{}, &types.MessageGetBlocksResp{}, &types.ReqNil{}} {
+		assert.Nil(t, types.Decode(busyReplyData, resp))
+		assert.True(t, isBusyReply(resp))
+	}
+	assert.False(t, isBusyReply(&types.MessagePeerInfoResp{MessageData: &types.MessageComm{Id: "1"}}))
+	assert.False(t, isBusyReply(&types.ReqNil{}))
+}
+
+func TestStreamPermission(t *testing.T) {
+	h1, h2 := newHostPair(13808, 13809)
+	defer h1.Close()
+	defer h2.Close()
+	err := h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	assert.Nil(t, err)
+
+	//host没有使用许可网络的安全传输, 连接可以建立, 但是不处理白名单之外节点的请求
+	msgID := "/peerInfoTest"
+	perm := manage.NewPermissionManager(h2.ID(), &p2pty.P2PSubConfig{EnablePermission: true}, nil)
+	remote := &testProtocol{}
+	remote.P2PEnv = &P2PEnv{Host: h2, PermManager: perm}
+	handler := &BaseStreamHandler{Protocol: remote, child: &peerInfoTestHandler{BaseStreamHandler: &BaseStreamHandler{}}}
+	h2.SetStreamHandler(protocol.ID(msgID), handler.HandleStream)
+
+	proto := &testProtocol{}
+	proto.P2PEnv = &P2PEnv{Host: h1}
+	req := &StreamRequest{
+		PeerID: h2.ID(),
+		MsgID:  msgID,
+		Data:   &types.MessagePeerInfoReq{MessageData: &types.MessageComm{Id: "1"}},
+	}
+	var resp types.MessagePeerInfoResp
+	err = proto.SendRecvPeer(req, &resp)
+	assert.NotNil(t, err)
+	assert.Nil(t, resp.GetMessage())
+}
@@ -5,13 +5,16 @@ package types
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"io"
 	"time"
 
 	"github.com/libp2p/go-libp2p-core/helpers"
 
+	"github.com/33cn/chain33/system/p2p/dht/manage"
 	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
 	core "github.com/libp2p/go-libp2p-core"
 	protobufCodec "github.com/multiformats/go-multicodec/protobuf"
 )
@@ -32,6 +35,7 @@ func (base *BaseProtocol) SendPeer(req *StreamRequest) error {
 	if err != nil {
 		return err
 	}
+	stream = base.LimitManager.LimitStream(stream)
 	defer CloseStream(stream)
 	err = WriteStream(req.Data, stream)
 	if err != nil {
@@ -49,6 +53,7 @@ func (base *BaseProtocol) SendRecvPeer(req *StreamRequest, resp types.Message) e
 	if err != nil {
 		return err
 	}
+	stream = base.LimitManager.LimitStream(stream)
 	defer CloseStream(stream)
 	err = WriteStream(req.Data, stream)
 	if err != nil {
@@ -58,9 +63,44 @@ func (base *BaseProtocol) SendRecvPeer(req *StreamRequest, resp types.Message) e
 	if err != nil {
 		return err
 	}
+	if isBusyReply(resp) {
+		resp.Reset()
+		return manage.ErrPeerBusy
+	}
 	return nil
 }
 
+//busyReplyData 对端超过频率限制时代替正常响应返回的数据, 只包含一个保留字段号(busyReplyField)的varint字段,
+//任何响应类型都不使用该字段号, 解码为任意响应类型时都只出现在未识别字段中, 不依赖响应消息的字段布局
+var busyReplyData = append(proto.EncodeVarint(busyReplyField<<3|proto.WireVarint), 1)
+
+//p2p流协议响应消息保留的字段号
+const busyReplyField = 2047
+
+//busyReply 对端限流时的响应
+type busyReply struct{}
+
+func (busyReply) Reset()         {}
+func (busyReply) String() string { return "busy" }
+func (busyReply) ProtoMessage()  {}
+
+// Marshal 直接返回保留字段的编码
+func (busyReply) Marshal() ([]byte, error) {
+	return busyReplyData, nil
+}
+
+//WriteBusyReply 读出请求之后回复限流响应, 请求方SendRecvPeer返回ErrPeerBusy
+//先读出请求数据, 否则关闭stream时未读取的数据会导致stream被重置
+func WriteBusyReply(stream core.Stream) error {
+	_ = ReadStream(&types.ReqNil{}, stream)
+	return WriteStream(busyReply{}, stream)
+}
+
+//isBusyReply 响应是否为对端的限流响应, 大小不同时不需要重新编码比较
+func isBusyReply(resp types.Message) bool {
+	return proto.Size(resp) == len(busyReplyData) && bytes.Equal(types.Encode(resp), busyReplyData)
+}
+
 //NewStream new libp2p stream
 func NewStream(host core.Host, pid core.PeerID, msgID string) (core.Stream, error) {
 
@@ -10,4 +10,9 @@ const (
 
 	// DefaultP2PPort 默认端口
 	DefaultP2PPort = 13803
+
+	// BroadcastFlood 基于ttl的泛洪广播协议
+	BroadcastFlood = "broadcast"
+	// BroadcastGossipSub 基于gossipsub主题mesh的广播协议
+	BroadcastGossipSub = "gossipsub"
 )
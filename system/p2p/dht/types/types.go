@@ -25,4 +25,39 @@ type P2PSubConfig struct {
 	BootStraps []string `protobuf:"bytes,8,rep,name=bootStraps" json:"bootStraps,omitempty"`
 	//轻广播本地区块缓存大小, 单位M
 	LtBlockCacheSize int32 `protobuf:"varint,9,opt,name=ltBlockCacheSize" json:"ltBlockCacheSize,omitempty"`
+	//交易和区块的广播协议, broadcast(默认, 基于ttl向所有连接节点发送) 或 gossipsub(基于主题mesh转发)
+	BroadcastProtocol string `protobuf:"bytes,10,opt,name=broadcastProtocol" json:"broadcastProtocol,omitempty"`
+	//关闭upnp/nat-pmp端口映射
+	DisableNATPortMap bool `protobuf:"varint,11,opt,name=disableNATPortMap" json:"disableNATPortMap,omitempty"`
+	//开启autonat, 通过其他节点回拨检测本节点是否可以被外网直接访问, 同时为其他节点提供回拨服务
+	EnableAutoNAT bool `protobuf:"varint,12,opt,name=enableAutoNAT" json:"enableAutoNAT,omitempty"`
+	//作为中继节点, 为处于nat之后的节点转发连接
+	EnableRelayHop bool `protobuf:"varint,13,opt,name=enableRelayHop" json:"enableRelayHop,omitempty"`
+	//中继节点地址, 处于nat之后时通过中继节点对外提供连接地址, 格式同seeds
+	RelayNodes []string `protobuf:"bytes,14,rep,name=relayNodes" json:"relayNodes,omitempty"`
+	//连接反转, 通过中继连接到nat之后的节点时请求对方反向拨号建立直连, 要求本节点可以被直接访问
+	EnableConnReversal bool `protobuf:"varint,15,opt,name=enableConnReversal" json:"enableConnReversal,omitempty"`
+	//许可网络模式, 只接受白名单内的节点或者持有ca签发证书的节点连接
+	EnablePermission bool `protobuf:"varint,16,opt,name=enablePermission" json:"enablePermission,omitempty"`
+	//白名单, 节点id或者十六进制公钥
+	AllowPeers []string `protobuf:"bytes,17,rep,name=allowPeers" json:"allowPeers,omitempty"`
+	//manage合约中保存链上白名单的配置项, 为空时不使用链上白名单
+	PermissionManageKey string `protobuf:"bytes,18,opt,name=permissionManageKey" json:"permissionManageKey,omitempty"`
+	//ca证书文件, pem格式, 配置后持有该ca签发证书的节点允许连接
+	CACert string `protobuf:"bytes,19,opt,name=caCert" json:"caCert,omitempty"`
+	//本节点证书文件, pem格式, 证书的CommonName为本节点id
+	NodeCert string `protobuf:"bytes,20,opt,name=nodeCert" json:"nodeCert,omitempty"`
+	//开启紧凑区块广播, 未升级的节点不能识别紧凑区块, 需要网络内的节点都升级之后再开启
+	EnableCompactBlock bool `protobuf:"varint,21,opt,name=enableCompactBlock" json:"enableCompactBlock,omitempty"`
+	//全局上传和下载带宽上限, 单位KB/s, 0表示不限制
+	MaxUploadRate   int32 `protobuf:"varint,22,opt,name=maxUploadRate" json:"maxUploadRate,omitempty"`
+	MaxDownloadRate int32 `protobuf:"varint,23,opt,name=maxDownloadRate" json:"maxDownloadRate,omitempty"`
+	//单个节点的上传和下载带宽上限, 单位KB/s, 0表示不限制
+	PeerUploadRate   int32 `protobuf:"varint,24,opt,name=peerUploadRate" json:"peerUploadRate,omitempty"`
+	PeerDownloadRate int32 `protobuf:"varint,25,opt,name=peerDownloadRate" json:"peerDownloadRate,omitempty"`
+	//单个节点每秒允许的请求次数, 分别对应区块下载, 区块头, 节点信息以及广播中的交易和区块查询, 0表示不限制
+	DownloadReqRate int32 `protobuf:"varint,26,opt,name=downloadReqRate" json:"downloadReqRate,omitempty"`
+	HeadersReqRate  int32 `protobuf:"varint,27,opt,name=headersReqRate" json:"headersReqRate,omitempty"`
+	PeerInfoReqRate int32 `protobuf:"varint,28,opt,name=peerInfoReqRate" json:"peerInfoReqRate,omitempty"`
+	QueryReqRate    int32 `protobuf:"varint,29,opt,name=queryReqRate" json:"queryReqRate,omitempty"`
 }
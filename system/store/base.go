@@ -6,10 +6,12 @@ package store
 
 import (
 	"sync"
+	"time"
 
 	dbm "github.com/33cn/chain33/common/db"
 	clog "github.com/33cn/chain33/common/log"
 	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/types"
 	"github.com/33cn/chain33/util"
@@ -21,7 +23,7 @@ import (
 //批量写
 1. EventStoreSet(stateHash, (k1,v1),(k2,v2),(k3,v3)) -> 返回 stateHash
 
-//批量读
+//批量读, 历史状态已经被裁剪时返回 ErrStatePruned
 2. EventStoreGet(stateHash, k1,k2,k3)
 
 */
@@ -57,6 +59,11 @@ type SubStore interface {
 	CommitUpgrade(hash *types.ReqHash) ([]byte, error)
 }
 
+// StateChecker 可选接口, 子存储实现后在读取历史状态时检查该状态是否可读(例如已经被裁剪)
+type StateChecker interface {
+	CheckState(stateHash []byte) error
+}
+
 // BaseStore 基础的store结构体
 type BaseStore struct {
 	db      dbm.DB
@@ -64,13 +71,15 @@ type BaseStore struct {
 	done    chan struct{}
 	child   SubStore
 	wg      sync.WaitGroup
+	backup  *dbm.Backuper
 }
 
 // NewBaseStore new base store struct
 func NewBaseStore(cfg *types.Store) *BaseStore {
 	db := dbm.NewDB("store", cfg.Driver, cfg.DbPath, cfg.DbCache)
 	db.SetCacheSize(102400)
-	store := &BaseStore{db: db}
+	//备份需要数据库本身的快照接口, 使用没有缓存的db
+	store := &BaseStore{db: dbm.WrapCachedDB(db, "store", cfg.ReadCacheSize), backup: dbm.NewBackuper(db, "store", cfg.Driver)}
 	store.done = make(chan struct{}, 1)
 	slog.Info("Enter store " + cfg.Name)
 	return store
@@ -114,6 +123,13 @@ func (store *BaseStore) processMessage(msg *queue.Message) {
 			defer store.wg.Done()
 			datas := msg.GetData().(*types.StoreGet)
 			values := store.child.Get(datas)
+			//状态被裁剪之后读取不到数据, 只在有key没有读取到时检查, 执行区块时正常的读取不受影响
+			if hasNilValue(values) {
+				if err := store.checkState(datas.StateHash); err != nil {
+					msg.Reply(client.NewMessage("", types.EventStoreGetReply, err))
+					return
+				}
+			}
 			msg.Reply(client.NewMessage("", types.EventStoreGetReply, &types.StoreReplyValue{Values: values}))
 		}()
 	} else if msg.Ty == types.EventStoreMemSet { //只是在内存中set 一下，并不改变状态
@@ -121,6 +137,10 @@ func (store *BaseStore) processMessage(msg *queue.Message) {
 		go func() {
 			defer store.wg.Done()
 			datas := msg.GetData().(*types.StoreSetWithSync)
+			span := msg.StartSpan("store.MemSet")
+			defer span.End()
+			span.SetAttribute("height", datas.Storeset.GetHeight())
+			span.SetAttribute("kvs", len(datas.Storeset.GetKV()))
 			var hash []byte
 			var err error
 			if datas.Upgrade {
@@ -129,6 +149,7 @@ func (store *BaseStore) processMessage(msg *queue.Message) {
 				hash, err = store.child.MemSet(datas.Storeset, datas.Sync)
 			}
 			if err != nil {
+				span.SetError(err)
 				msg.Reply(client.NewMessage("", types.EventStoreSetReply, err))
 				return
 			}
@@ -139,13 +160,18 @@ func (store *BaseStore) processMessage(msg *queue.Message) {
 		go func() {
 			defer store.wg.Done()
 			req := msg.GetData().(*types.ReqHash)
+			span := msg.StartSpan("store.Commit")
+			defer span.End()
 			var hash []byte
 			var err error
+			beg := time.Now()
 			if req.Upgrade {
 				hash, err = store.child.CommitUpgrade(req)
 			} else {
 				hash, err = store.child.Commit(req)
 			}
+			metrics.StoreCommitTime.UpdateSince(beg)
+			span.SetError(err)
 			if hash == nil {
 				msg.Reply(client.NewMessage("", types.EventStoreCommit, types.ErrHashNotFound))
 				if err == types.ErrDataBaseDamage { //如果是数据库写失败，需要上报给用户
@@ -194,9 +220,25 @@ func (store *BaseStore) processMessage(msg *queue.Message) {
 		go func() {
 			defer store.wg.Done()
 			req := msg.GetData().(*types.StoreList)
+			if err := store.checkState(req.StateHash); err != nil {
+				msg.Reply(client.NewMessage("", types.EventStoreListReply, err))
+				return
+			}
 			query := NewStoreListQuery(store.child, req)
 			msg.Reply(client.NewMessage("", types.EventStoreListReply, query.Run()))
 		}()
+	} else if msg.Ty == types.EventSnapshotDB { //在线备份store数据库
+		store.wg.Add(1)
+		go func() {
+			defer store.wg.Done()
+			req := msg.GetData().(*types.ReqSnapshotDB)
+			info, err := store.backup.Handle(req)
+			if err != nil {
+				msg.Reply(client.NewMessage("", types.EventSnapshotDB, err))
+				return
+			}
+			msg.Reply(client.NewMessage("", types.EventSnapshotDB, info))
+		}()
 	} else {
 		store.wg.Add(1)
 		go func() {
@@ -206,6 +248,22 @@ func (store *BaseStore) processMessage(msg *queue.Message) {
 	}
 }
 
+func hasNilValue(values [][]byte) bool {
+	for _, value := range values {
+		if value == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *BaseStore) checkState(stateHash []byte) error {
+	if checker, ok := store.child.(StateChecker); ok {
+		return checker.CheckState(stateHash)
+	}
+	return nil
+}
+
 // SetChild 设置BaseStore中的子存储参数
 func (store *BaseStore) SetChild(sub SubStore) {
 	store.child = sub
@@ -0,0 +1,136 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flat
+
+import (
+	"sort"
+
+	"github.com/33cn/chain33/common"
+)
+
+/*
+状态承诺采用分桶hash树:
+
+1. key 按 sha256(key) 的前两个字节分到 65536 个桶中
+2. 叶子hash = sha256(sha256(key) + sha256(value))
+3. 桶hash = sha256(桶内按 sha256(key) 排序的全部叶子hash), 空桶为nil
+4. 在全部桶hash上构造固定深度的二叉hash树, 左右子节点都为空的节点为空, 空树的根为 EmptyRoot
+
+状态hash只和最终的kv集合有关, 和写入顺序无关; 每次更新只需要重算被修改的桶以及它们到根的路径
+*/
+
+const (
+	bucketBits = 16
+	bucketNum  = 1 << bucketBits
+)
+
+var zeroHash = make([]byte, 32)
+
+func bucketOf(keyHash []byte) int {
+	return int(keyHash[0])<<8 | int(keyHash[1])
+}
+
+func leafHash(keyHash, value []byte) []byte {
+	data := make([]byte, 0, 64)
+	data = append(data, keyHash...)
+	data = append(data, common.Sha256(value)...)
+	return common.Sha256(data)
+}
+
+//bucketDigest 计算桶hash, leaves 为 keyHash -> 叶子hash
+func bucketDigest(leaves map[string][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(leaves))
+	for k := range leaves {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	data := make([]byte, 0, 32*len(keys))
+	for _, k := range keys {
+		data = append(data, leaves[k]...)
+	}
+	return common.Sha256(data)
+}
+
+func hashPair(left, right []byte) []byte {
+	if left == nil && right == nil {
+		return nil
+	}
+	if left == nil {
+		left = zeroHash
+	}
+	if right == nil {
+		right = zeroHash
+	}
+	data := make([]byte, 0, 64)
+	data = append(data, left...)
+	data = append(data, right...)
+	return common.Sha256(data)
+}
+
+//bucketTree 内存中的桶hash树, nodes[1] 为根, nodes[bucketNum+i] 为第i个桶的hash
+type bucketTree struct {
+	nodes [][]byte
+}
+
+func newBucketTree() *bucketTree {
+	return &bucketTree{nodes: make([][]byte, 2*bucketNum)}
+}
+
+//build 由全部桶hash重建内部节点
+func (t *bucketTree) build() {
+	for i := bucketNum - 1; i > 0; i-- {
+		t.nodes[i] = hashPair(t.nodes[2*i], t.nodes[2*i+1])
+	}
+}
+
+func (t *bucketTree) set(bucket int, digest []byte) {
+	i := bucketNum + bucket
+	t.nodes[i] = digest
+	for i > 1 {
+		i >>= 1
+		t.nodes[i] = hashPair(t.nodes[2*i], t.nodes[2*i+1])
+	}
+}
+
+func (t *bucketTree) get(bucket int) []byte {
+	return t.nodes[bucketNum+bucket]
+}
+
+func (t *bucketTree) root() []byte {
+	return t.nodes[1]
+}
+
+//rootWith 在不修改树的情况下计算替换部分桶hash之后的根
+func (t *bucketTree) rootWith(digests map[int][]byte) []byte {
+	if len(digests) == 0 {
+		return t.root()
+	}
+	level := make(map[int][]byte, len(digests))
+	for bucket, digest := range digests {
+		level[bucketNum+bucket] = digest
+	}
+	for n := bucketNum; n > 1; n >>= 1 {
+		parents := make(map[int][]byte, len(level))
+		for i := range level {
+			p := i >> 1
+			if _, ok := parents[p]; ok {
+				continue
+			}
+			parents[p] = hashPair(t.node(level, 2*p), t.node(level, 2*p+1))
+		}
+		level = parents
+	}
+	return level[1]
+}
+
+func (t *bucketTree) node(level map[int][]byte, i int) []byte {
+	if v, ok := level[i]; ok {
+		return v
+	}
+	return t.nodes[i]
+}
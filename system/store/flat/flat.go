@@ -0,0 +1,480 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package flat 平铺kv状态存储, 最新状态的读取为一次数据库查询, 状态hash由分桶hash树计算
+package flat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/33cn/chain33/common"
+	clog "github.com/33cn/chain33/common/log"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/queue"
+	drivers "github.com/33cn/chain33/system/store"
+	"github.com/33cn/chain33/types"
+)
+
+var flog = log.New("module", "flat")
+
+// SetLogLevel set log level
+func SetLogLevel(level string) {
+	clog.SetLogLevel(level)
+}
+
+// DisableLog disable log
+func DisableLog() {
+	flog.SetHandler(log.DiscardHandler())
+}
+
+// Store flat store struct
+type Store struct {
+	*drivers.BaseStore
+	//MemSet 产生的未提交状态
+	pending *sync.Map
+	mtx     sync.RWMutex
+	tree    *bucketTree
+	//最新版本以及状态hash, -1 表示空状态
+	version int64
+	hash    []byte
+}
+
+//pendingState 一次MemSet的结果, 基于 prevHash 对应的版本
+type pendingState struct {
+	prevHash []byte
+	base     int64
+	height   int64
+	sync     bool
+	kvs      map[string][]byte
+	digests  map[int][]byte
+	hash     []byte
+}
+
+func init() {
+	drivers.Reg("flat", New)
+}
+
+// New new flat store module
+func New(cfg *types.Store, sub []byte, chain33cfg *types.Chain33Config) queue.Module {
+	bs := drivers.NewBaseStore(cfg)
+	flats := &Store{
+		BaseStore: bs,
+		pending:   &sync.Map{},
+		tree:      newBucketTree(),
+		version:   -1,
+		hash:      drivers.EmptyRoot[:],
+	}
+	err := flats.load()
+	if err != nil {
+		panic(err)
+	}
+	bs.SetChild(flats)
+	return flats
+}
+
+//load 加载最新版本以及桶hash树
+func (flats *Store) load() error {
+	db := flats.GetDB()
+	it := db.Iterator(versionPrefix, nil, true)
+	defer it.Close()
+	if it.Rewind() {
+		var info types.BlockInfo
+		err := types.Decode(it.Value(), &info)
+		if err != nil {
+			return err
+		}
+		flats.version = int64(binary.BigEndian.Uint64(it.Key()[len(versionPrefix):]))
+		flats.hash = info.Hash
+	}
+	dit := db.Iterator(digestPrefix, nil, false)
+	defer dit.Close()
+	for dit.Rewind(); dit.Valid(); dit.Next() {
+		if dit.Error() != nil {
+			return dit.Error()
+		}
+		flats.tree.nodes[bucketNum+digestBucket(dit.Key())] = dit.ValueCopy()
+	}
+	flats.tree.build()
+	flog.Info("store flat load", "version", flats.version, "hash", common.ToHex(flats.hash))
+	return nil
+}
+
+// Close close flat store
+func (flats *Store) Close() {
+	flats.BaseStore.Close()
+	flog.Info("store flat closed")
+}
+
+//resolve 获取状态hash对应的版本, 调用者需要持有读锁
+func (flats *Store) resolve(hash []byte) (int64, error) {
+	if bytes.Equal(hash, flats.hash) {
+		return flats.version, nil
+	}
+	version, err := getRootVersion(flats.GetDB(), hash)
+	if err == nil {
+		return version, nil
+	}
+	if bytes.Equal(hash, drivers.EmptyRoot[:]) {
+		return -1, nil
+	}
+	return 0, types.ErrHashNotFound
+}
+
+//getValue 调用者需要持有读锁
+func (flats *Store) getValue(key []byte, version int64) []byte {
+	var value []byte
+	if version < 0 {
+		return nil
+	}
+	if version == flats.version {
+		value, _ = flats.GetDB().Get(calcLatestKey(key))
+	} else {
+		value, _ = getHistory(flats.GetDB(), common.Sha256(key), version)
+	}
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
+
+// Set set k v to flat store db; sync is true represent write sync
+func (flats *Store) Set(datas *types.StoreSet, sync bool) ([]byte, error) {
+	if len(datas.KV) == 0 {
+		return datas.StateHash, nil
+	}
+	state, err := flats.execute(datas, sync)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(state.hash, datas.StateHash) {
+		return datas.StateHash, nil
+	}
+	flats.mtx.Lock()
+	defer flats.mtx.Unlock()
+	err = flats.commit(state)
+	if err != nil {
+		return nil, err
+	}
+	return state.hash, nil
+}
+
+// Get get values by keys
+func (flats *Store) Get(datas *types.StoreGet) [][]byte {
+	values := make([][]byte, len(datas.Keys))
+	stateHash := datas.StateHash
+	var state *pendingState
+	if data, ok := flats.pending.Load(string(stateHash)); ok && data != nil {
+		state = data.(*pendingState)
+		stateHash = state.prevHash
+	}
+	flats.mtx.RLock()
+	defer flats.mtx.RUnlock()
+	version, err := flats.resolve(stateHash)
+	if err != nil {
+		flog.Debug("store flat get", "err", err, "StateHash", common.ToHex(datas.StateHash))
+		return values
+	}
+	for i := 0; i < len(datas.Keys); i++ {
+		if state != nil {
+			if value, ok := state.kvs[string(datas.Keys[i])]; ok {
+				if len(value) > 0 {
+					values[i] = value
+				}
+				continue
+			}
+		}
+		values[i] = flats.getValue(datas.Keys[i], version)
+	}
+	return values
+}
+
+//execute 在 datas.StateHash 对应的状态上计算新的状态hash
+func (flats *Store) execute(datas *types.StoreSet, sync bool) (*pendingState, error) {
+	flats.mtx.RLock()
+	defer flats.mtx.RUnlock()
+	db := flats.GetDB()
+	base, err := flats.resolve(datas.StateHash)
+	if err != nil {
+		return nil, err
+	}
+	v, err := newView(db, base, flats.version)
+	if err != nil {
+		return nil, err
+	}
+	state := &pendingState{
+		prevHash: datas.StateHash,
+		base:     base,
+		height:   datas.Height,
+		sync:     sync,
+		kvs:      make(map[string][]byte),
+		digests:  make(map[int][]byte),
+	}
+	touched := make(map[int]map[string][]byte)
+	for _, kv := range datas.KV {
+		state.kvs[string(kv.Key)] = kv.Value
+	}
+	for key, value := range state.kvs {
+		keyHash := common.Sha256([]byte(key))
+		bucket := bucketOf(keyHash)
+		if touched[bucket] == nil {
+			touched[bucket] = make(map[string][]byte)
+		}
+		touched[bucket][string(keyHash)] = leafHash(keyHash, value)
+	}
+	overlay := make(map[int][]byte, len(v.digests)+len(touched))
+	for bucket, digest := range v.digests {
+		overlay[bucket] = digest
+	}
+	for bucket, changed := range touched {
+		leaves, err := v.bucketLeaves(db, bucket)
+		if err != nil {
+			return nil, err
+		}
+		for keyHash, leaf := range changed {
+			leaves[keyHash] = leaf
+		}
+		digest := bucketDigest(leaves)
+		state.digests[bucket] = digest
+		overlay[bucket] = digest
+	}
+	state.hash = flats.tree.rootWith(overlay)
+	if state.hash == nil {
+		state.hash = drivers.EmptyRoot[:]
+	}
+	return state, nil
+}
+
+//commit 保存状态, 基础版本必须是最新版本, 分叉时需要先通过Del显式回滚之后的版本, 调用者需要持有写锁
+func (flats *Store) commit(state *pendingState) error {
+	db := flats.GetDB()
+	base, err := flats.resolve(state.prevHash)
+	if err != nil {
+		return err
+	}
+	if base != state.base {
+		return types.ErrPrevVersion
+	}
+	if base != flats.version {
+		return ErrStateNotLatest
+	}
+	batch := db.NewBatch(state.sync)
+	version := base + 1
+	keys := make([]string, 0, len(state.kvs))
+	for key := range state.kvs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	changed := &types.LocalDBSet{}
+	for _, key := range keys {
+		value := state.kvs[key]
+		keyHash := common.Sha256([]byte(key))
+		batch.Set(calcHistoryKey(keyHash, version), wrapValue(value))
+		batch.Set(calcLatestKey([]byte(key)), value)
+		batch.Set(calcLeafKey(keyHash), leafHash(keyHash, value))
+		changed.KV = append(changed.KV, &types.KeyValue{Key: []byte(key)})
+	}
+	buckets := make([]int, 0, len(state.digests))
+	for bucket := range state.digests {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+	for _, bucket := range buckets {
+		key := calcDigestKey(bucket)
+		batch.Set(calcHistoryKey(common.Sha256(key), version), wrapValue(state.digests[bucket]))
+		batch.Set(key, state.digests[bucket])
+		changed.KV = append(changed.KV, &types.KeyValue{Key: key})
+	}
+	batch.Set(calcChangeKey(version), types.Encode(changed))
+	batch.Set(calcVersionKey(version), types.Encode(&types.BlockInfo{Height: state.height, Hash: state.hash}))
+	batch.Set(calcRootKey(state.hash), types.Encode(&types.Int64{Data: version}))
+	err = batch.Write()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		flats.tree.set(bucket, state.digests[bucket])
+	}
+	flats.version = version
+	flats.hash = state.hash
+	return nil
+}
+
+// MemSet set keys values to memcory, return root hash and error
+func (flats *Store) MemSet(datas *types.StoreSet, sync bool) ([]byte, error) {
+	beg := types.Now()
+	defer func() {
+		flog.Debug("MemSet", "cost", types.Since(beg))
+	}()
+	if len(datas.KV) == 0 {
+		flog.Info("store flat memset,use preStateHash as stateHash for kvset is null")
+		flats.pending.Store(string(datas.StateHash), nil)
+		return datas.StateHash, nil
+	}
+	state, err := flats.execute(datas, sync)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(state.hash, datas.StateHash) {
+		flats.pending.Store(string(datas.StateHash), nil)
+		return datas.StateHash, nil
+	}
+	flats.pending.Store(string(state.hash), state)
+	return state.hash, nil
+}
+
+// Commit convert memcory state to storage db
+func (flats *Store) Commit(req *types.ReqHash) ([]byte, error) {
+	beg := types.Now()
+	defer func() {
+		flog.Debug("Commit", "cost", types.Since(beg))
+	}()
+	data, ok := flats.pending.Load(string(req.Hash))
+	if !ok {
+		flog.Error("store flat commit", "err", types.ErrHashNotFound)
+		return nil, types.ErrHashNotFound
+	}
+	if data == nil {
+		flog.Info("store flat commit,do nothing for kvset is null")
+		flats.pending.Delete(string(req.Hash))
+		return req.Hash, nil
+	}
+	flats.mtx.Lock()
+	err := flats.commit(data.(*pendingState))
+	flats.mtx.Unlock()
+	if err == ErrStateNotLatest {
+		flog.Error("store flat commit", "err", err)
+		return nil, err
+	}
+	if err != nil {
+		flog.Error("store flat commit", "err", err)
+		return nil, types.ErrDataBaseDamage
+	}
+	flats.pending.Delete(string(req.Hash))
+	return req.Hash, nil
+}
+
+// MemSetUpgrade cacl state hash, but not store state, return root hash and error
+func (flats *Store) MemSetUpgrade(datas *types.StoreSet, sync bool) ([]byte, error) {
+	if len(datas.KV) == 0 {
+		return datas.StateHash, nil
+	}
+	state, err := flats.execute(datas, sync)
+	if err != nil {
+		return nil, err
+	}
+	return state.hash, nil
+}
+
+// CommitUpgrade convert memcory state to storage db
+func (flats *Store) CommitUpgrade(req *types.ReqHash) ([]byte, error) {
+	return req.Hash, nil
+}
+
+// Rollback 回退将缓存的状态删除掉
+func (flats *Store) Rollback(req *types.ReqHash) ([]byte, error) {
+	_, ok := flats.pending.Load(string(req.Hash))
+	if !ok {
+		flog.Error("store flat rollback", "err", types.ErrHashNotFound)
+		return nil, types.ErrHashNotFound
+	}
+	flats.pending.Delete(string(req.Hash))
+	return req.Hash, nil
+}
+
+// Del 删除区块产生的状态版本, 回滚到前一个版本
+func (flats *Store) Del(req *types.StoreDel) ([]byte, error) {
+	if req == nil {
+		return nil, types.ErrInvalidParam
+	}
+	flats.mtx.Lock()
+	defer flats.mtx.Unlock()
+	db := flats.GetDB()
+	version, err := flats.resolve(req.StateHash)
+	if err != nil {
+		return nil, err
+	}
+	if version < 0 {
+		return req.StateHash, nil
+	}
+	info, err := getVersionInfo(db, version)
+	if err != nil {
+		return nil, err
+	}
+	//空区块不产生新的版本
+	if info.Height != req.Height {
+		return req.StateHash, nil
+	}
+	batch := db.NewBatch(true)
+	reverted, err := revert(db, batch, flats.version, version-1)
+	if err != nil {
+		return nil, err
+	}
+	err = batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	for bucket, digest := range reverted {
+		flats.tree.set(bucket, digest)
+	}
+	flats.version = version - 1
+	flats.hash = drivers.EmptyRoot[:]
+	if flats.version >= 0 {
+		info, err = getVersionInfo(db, flats.version)
+		if err != nil {
+			return nil, err
+		}
+		flats.hash = info.Hash
+	}
+	return req.StateHash, nil
+}
+
+// IterateRangeByStateHash 迭代实现功能； statehash：当前状态hash, start：开始查找的key, end: 结束的key, ascending：升序，降序, fn 迭代回调函数
+func (flats *Store) IterateRangeByStateHash(statehash []byte, start []byte, end []byte, ascending bool, fn func(key, value []byte) bool) {
+	flats.mtx.RLock()
+	version, err := flats.resolve(statehash)
+	flats.mtx.RUnlock()
+	if err != nil || version < 0 {
+		return
+	}
+	//最新key的集合包含了所有历史版本的key, 值按版本读取, 迭代过程中不阻塞提交
+	db := flats.GetDB()
+	startKey := joinKey(latestPrefix, start)
+	endKey := prefixEnd(latestPrefix)
+	if end != nil {
+		endKey = joinKey(latestPrefix, end)
+	}
+	it := db.Iterator(startKey, endKey, !ascending)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		if it.Error() != nil {
+			flog.Error("store flat iterate", "err", it.Error())
+			return
+		}
+		key := it.Key()[len(latestPrefix):]
+		value, ok := getHistory(db, common.Sha256(key), version)
+		if !ok {
+			continue
+		}
+		if fn(cloneByte(key), value) {
+			return
+		}
+	}
+}
+
+func cloneByte(v []byte) []byte {
+	value := make([]byte, len(v))
+	copy(value, v)
+	return value
+}
+
+// ProcEvent not support message
+func (flats *Store) ProcEvent(msg *queue.Message) {
+	if msg == nil {
+		return
+	}
+	msg.ReplyErr("Store", types.ErrActionNotSupport)
+}
@@ -0,0 +1,337 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	drivers "github.com/33cn/chain33/system/store"
+	mavl "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/system/store/storetest"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStoreCfg(dir string) *types.Store {
+	return &types.Store{Name: "flat_test", Driver: "leveldb", DbPath: dir, DbCache: 100}
+}
+
+func newTestStore(t *testing.T) (*Store, string) {
+	dir, err := ioutil.TempDir("", "example")
+	require.Nil(t, err)
+	store := New(newStoreCfg(dir), nil, nil).(*Store)
+	require.NotNil(t, store)
+	return store, dir
+}
+
+func kvs(pairs ...string) []*types.KeyValue {
+	var kv []*types.KeyValue
+	for i := 0; i+1 < len(pairs); i += 2 {
+		kv = append(kv, &types.KeyValue{Key: []byte(pairs[i]), Value: []byte(pairs[i+1])})
+	}
+	return kv
+}
+
+func memCommit(t *testing.T, store *Store, prev []byte, height int64, kv []*types.KeyValue) []byte {
+	hash, err := store.MemSet(&types.StoreSet{StateHash: prev, KV: kv, Height: height}, true)
+	require.Nil(t, err)
+	_, err = store.Commit(&types.ReqHash{Hash: hash})
+	require.Nil(t, err)
+	return hash
+}
+
+func get(store *Store, hash []byte, keys ...string) []string {
+	req := &types.StoreGet{StateHash: hash}
+	for _, k := range keys {
+		req.Keys = append(req.Keys, []byte(k))
+	}
+	var values []string
+	for _, v := range store.Get(req) {
+		values = append(values, string(v))
+	}
+	return values
+}
+
+func TestKvdbNewClose(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	store.Close()
+}
+
+func TestKvdbSetGet(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	values0 := store.Get(&types.StoreGet{StateHash: drivers.EmptyRoot[:], Keys: [][]byte{[]byte("mk1"), []byte("mk2")}})
+	assert.Equal(t, [][]byte{nil, nil}, values0)
+
+	hash, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("k1", "v1", "k2", "v2")}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1", "v2"}, get(store, hash, "k1", "k2"))
+	assert.Equal(t, []string{""}, get(store, drivers.EmptyRoot[:], "k1"))
+
+	//空的kv集合不改变状态
+	hash1, err := store.Set(&types.StoreSet{StateHash: hash}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, hash1)
+}
+
+func TestKvdbMemSet(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	hash, err := store.MemSet(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("mk1", "v1", "mk2", "v2")}, true)
+	assert.Nil(t, err)
+	//未提交的状态可以读取
+	assert.Equal(t, []string{"v1", "v2"}, get(store, hash, "mk1", "mk2"))
+	assert.Equal(t, []string{""}, get(store, drivers.EmptyRoot[:], "mk1"))
+
+	actHash, _ := store.Commit(&types.ReqHash{Hash: hash})
+	assert.Equal(t, hash, actHash)
+	assert.Equal(t, []string{"v1", "v2"}, get(store, hash, "mk1", "mk2"))
+
+	notExistHash, _ := store.Commit(&types.ReqHash{Hash: drivers.EmptyRoot[:]})
+	assert.Nil(t, notExistHash)
+}
+
+func TestKvdbMemSetUpgrade(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	datas := &types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("mk1", "v1", "mk2", "v2")}
+	hash, err := store.MemSetUpgrade(datas, true)
+	assert.Nil(t, err)
+	hash1, err := store.CommitUpgrade(&types.ReqHash{Hash: hash})
+	assert.Nil(t, err)
+	assert.Equal(t, hash, hash1)
+	//升级时不保存状态
+	assert.Equal(t, []string{""}, get(store, hash, "mk1"))
+
+	hash2, err := store.MemSet(datas, true)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, hash2)
+}
+
+func TestKvdbRollback(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	hash, err := store.MemSet(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("mk1", "v1", "mk2", "v2")}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1", "v2"}, get(store, hash, "mk1", "mk2"))
+
+	actHash, _ := store.Rollback(&types.ReqHash{Hash: hash})
+	assert.Equal(t, hash, actHash)
+	assert.Equal(t, []string{""}, get(store, hash, "mk1"))
+
+	notExistHash, _ := store.Rollback(&types.ReqHash{Hash: drivers.EmptyRoot[:]})
+	assert.Nil(t, notExistHash)
+}
+
+func TestSubStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (drivers.SubStore, func()) {
+		store, dir := newTestStore(t)
+		return store, func() {
+			store.Close()
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestStateHashOrder(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	//状态hash只和最终的kv集合有关
+	hash1, err := store.MemSetUpgrade(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("a", "1", "b", "2", "c", "3")}, true)
+	assert.Nil(t, err)
+	hash2, err := store.MemSetUpgrade(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("c", "3", "a", "0", "b", "2", "a", "1")}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, hash1, hash2)
+
+	h := memCommit(t, store, drivers.EmptyRoot[:], 1, kvs("c", "3"))
+	h = memCommit(t, store, h, 2, kvs("b", "2", "a", "1"))
+	assert.Equal(t, hash1, h)
+}
+
+func TestForkAndDel(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	h1 := memCommit(t, store, drivers.EmptyRoot[:], 1, kvs("a", "1", "b", "1"))
+	h2 := memCommit(t, store, h1, 2, kvs("a", "2", "c", "2"))
+	h3 := memCommit(t, store, h2, 3, kvs("b", "3"))
+	assert.Equal(t, []string{"1", "1", ""}, get(store, h1, "a", "b", "c"))
+	assert.Equal(t, []string{"2", "1", "2"}, get(store, h2, "a", "b", "c"))
+	assert.Equal(t, []string{"2", "3", "2"}, get(store, h3, "a", "b", "c"))
+
+	//在高度1上分叉, 状态hash和直接在该状态上写入的结果一致
+	expect, err := store.MemSetUpgrade(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvs("a", "1", "b", "1", "d", "4")}, true)
+	assert.Nil(t, err)
+	f2, err := store.MemSet(&types.StoreSet{StateHash: h1, KV: kvs("d", "4"), Height: 2}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, expect, f2)
+	assert.Equal(t, []string{"1", "1", "", "4"}, get(store, f2, "a", "b", "c", "d"))
+	//基础版本不是最新版本, 不能直接提交
+	_, err = store.Commit(&types.ReqHash{Hash: f2})
+	assert.Equal(t, ErrStateNotLatest, err)
+	_, err = store.Set(&types.StoreSet{StateHash: h1, KV: kvs("d", "4"), Height: 2}, true)
+	assert.Equal(t, ErrStateNotLatest, err)
+	assert.Equal(t, h3, store.hash)
+	assert.Equal(t, []string{"2", "3", "2"}, get(store, h3, "a", "b", "c"))
+	//显式回滚原来的分支之后可以提交
+	_, err = store.Del(&types.StoreDel{StateHash: h2, Height: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, h1, store.hash)
+	_, err = store.Commit(&types.ReqHash{Hash: f2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "1", "", "4"}, get(store, f2, "a", "b", "c", "d"))
+	//原来的分支已经被回滚
+	assert.Equal(t, []string{"", "", "", ""}, get(store, h3, "a", "b", "c", "d"))
+	f3 := memCommit(t, store, f2, 3, kvs("c", "5"))
+	assert.Equal(t, []string{"1", "1", "5", "4"}, get(store, f3, "a", "b", "c", "d"))
+
+	//重新打开后状态不变
+	store.Close()
+	store = New(newStoreCfg(dir), nil, nil).(*Store)
+	defer store.Close()
+	assert.Equal(t, f3, store.hash)
+	assert.Equal(t, f3, store.tree.root())
+	assert.Equal(t, []string{"1", "1", "5", "4"}, get(store, f3, "a", "b", "c", "d"))
+
+	//删除最新的区块状态
+	_, err = store.Del(&types.StoreDel{StateHash: f3, Height: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, f2, store.hash)
+	assert.Equal(t, f2, store.tree.root())
+	assert.Equal(t, []string{"1", "1", "", "4"}, get(store, f2, "a", "b", "c", "d"))
+	assert.Equal(t, []string{""}, get(store, f3, "c"))
+	//空区块的状态hash和父区块相同, 不删除
+	_, err = store.Del(&types.StoreDel{StateHash: f2, Height: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, f2, store.hash)
+	_, err = store.Del(&types.StoreDel{StateHash: f2, Height: 2})
+	assert.Nil(t, err)
+	_, err = store.Del(&types.StoreDel{StateHash: h1, Height: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, drivers.EmptyRoot[:], store.hash)
+	assert.Nil(t, store.tree.root())
+	assert.Equal(t, []string{"", ""}, get(store, h1, "a", "b"))
+}
+
+var checkKVResult []*types.KeyValue
+
+func checkKV(k, v []byte) bool {
+	checkKVResult = append(checkKVResult, &types.KeyValue{Key: k, Value: v})
+	return false
+}
+
+func TestKvdbIterate(t *testing.T) {
+	store, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	h1 := memCommit(t, store, drivers.EmptyRoot[:], 1, kvs("mk1", "v1", "mk2", "v2"))
+	h2 := memCommit(t, store, h1, 2, kvs("mk1", "v3", "mk0", "v0", "mk3", "v4"))
+
+	checkKVResult = checkKVResult[:0]
+	store.IterateRangeByStateHash(h1, []byte("mk1"), []byte("mk3"), true, checkKV)
+	assert.Len(t, checkKVResult, 2)
+	assert.Equal(t, []byte("v1"), checkKVResult[0].Value)
+	assert.Equal(t, []byte("v2"), checkKVResult[1].Value)
+
+	checkKVResult = checkKVResult[:0]
+	store.IterateRangeByStateHash(h2, []byte("mk1"), nil, false, checkKV)
+	assert.Len(t, checkKVResult, 3)
+	assert.Equal(t, []byte("mk3"), checkKVResult[0].Key)
+	assert.Equal(t, []byte("v3"), checkKVResult[2].Value)
+
+	checkKVResult = checkKVResult[:0]
+	store.IterateRangeByStateHash(h2, nil, nil, true, checkKV)
+	assert.Len(t, checkKVResult, 4)
+}
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	mdb := dbm.NewDB("mavl", "leveldb", dir, 100)
+	defer mdb.Close()
+	treeCfg := &mavl.TreeConfig{}
+	var kv []*types.KeyValue
+	for i := 0; i < 1000; i++ {
+		kv = append(kv, &types.KeyValue{Key: []byte(fmt.Sprintf("key%d", i)), Value: []byte(fmt.Sprintf("value%d", i))})
+	}
+	mroot, err := mavl.SetKVPair(mdb, &types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv, Height: 10}, true, treeCfg)
+	require.Nil(t, err)
+
+	store := New(newStoreCfg(dir), nil, nil).(*Store)
+	tree := mavl.NewTree(mdb, true, treeCfg)
+	require.Nil(t, tree.Load(mroot))
+	hash, err := Import(store.GetDB(), 10, mroot, tree.Iterate)
+	require.Nil(t, err)
+	_, err = Import(store.GetDB(), 10, mroot, tree.Iterate)
+	assert.Equal(t, ErrStoreNotEmpty, err)
+	expect, err := store.MemSetUpgrade(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv}, true)
+	require.Nil(t, err)
+	assert.Equal(t, expect, hash)
+
+	store.Close()
+	store = New(newStoreCfg(dir), nil, nil).(*Store)
+	defer store.Close()
+	assert.Equal(t, hash, store.hash)
+	//mavl的root和flat的root都可以读取导入的状态
+	assert.Equal(t, []string{"value1", "value999"}, get(store, mroot, "key1", "key999"))
+	assert.Equal(t, []string{"value1", "value999"}, get(store, hash, "key1", "key999"))
+
+	//在导入的状态上继续执行区块
+	h11 := memCommit(t, store, mroot, 11, kvs("key1", "new"))
+	assert.Equal(t, []string{"new", "value2"}, get(store, h11, "key1", "key2"))
+	assert.Equal(t, []string{"value1"}, get(store, mroot, "key1"))
+	_, err = store.Del(&types.StoreDel{StateHash: h11, Height: 11})
+	assert.Nil(t, err)
+	assert.Equal(t, hash, store.hash)
+	//导入的版本不能回滚
+	_, err = store.Del(&types.StoreDel{StateHash: mroot, Height: 10})
+	assert.Equal(t, ErrStateNotRevertible, err)
+}
+
+func BenchmarkGet(b *testing.B) {
+	dir, err := ioutil.TempDir("", "example")
+	require.Nil(b, err)
+	defer os.RemoveAll(dir)
+	store := New(newStoreCfg(dir), nil, nil).(*Store)
+	defer store.Close()
+
+	var keys [][]byte
+	hash := drivers.EmptyRoot[:]
+	for i := 0; i < 100; i++ {
+		var kv []*types.KeyValue
+		for j := 0; j < 100; j++ {
+			key := common.Sha256([]byte(fmt.Sprintf("%d-%d", i, j)))
+			keys = append(keys, key)
+			kv = append(kv, &types.KeyValue{Key: key, Value: key})
+		}
+		hash, err = store.Set(&types.StoreSet{StateHash: hash, KV: kv, Height: int64(i)}, true)
+		require.Nil(b, err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		values := store.Get(&types.StoreGet{StateHash: hash, Keys: [][]byte{keys[i%len(keys)]}})
+		require.Equal(b, keys[i%len(keys)], values[0])
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flat
+
+import (
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+//登记store数据库中flat状态使用的key前缀, 历史值带有标记字节, 不是protobuf编码
+func init() {
+	dbm.RegisterKeyPrefix("store", string(latestPrefix), "flat最新值", nil)
+	dbm.RegisterKeyPrefix("store", string(historyPrefix), "flat历史值", nil)
+	dbm.RegisterKeyPrefix("store", string(leafPrefix), "flat叶子索引", nil)
+	dbm.RegisterKeyPrefix("store", string(digestPrefix), "flat桶hash", nil)
+	dbm.RegisterKeyPrefix("store", string(changePrefix), "版本修改的key列表", func() types.Message { return &types.LocalDBSet{} })
+	dbm.RegisterKeyPrefix("store", string(versionPrefix), "版本 -> 状态hash和高度", func() types.Message { return &types.BlockInfo{} })
+	dbm.RegisterKeyPrefix("store", string(rootPrefix), "状态hash -> 版本", func() types.Message { return &types.Int64{} })
+}
@@ -0,0 +1,99 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flat
+
+import (
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+const importBatchSize = 10000
+
+//Import 把其他存储中某个状态的全部kv导入空的flat数据库, 返回导入后的状态hash
+//iterate 依次回调状态中的每个kv, 回调返回true时停止
+//导入的状态保存为版本0, 没有修改记录, 不能再回滚到更早的状态;
+//alias 为原存储中该状态的hash(例如mavl的root), 同样映射到版本0, 使节点可以在原有的区块上继续执行
+func Import(db dbm.DB, height int64, alias []byte, iterate func(fn func(key, value []byte) bool) bool) ([]byte, error) {
+	it := db.Iterator(versionPrefix, nil, false)
+	exist := it.Rewind()
+	it.Close()
+	if exist {
+		return nil, ErrStoreNotEmpty
+	}
+	var err error
+	var count int
+	batch := db.NewBatch(false)
+	iterate(func(key, value []byte) bool {
+		keyHash := common.Sha256(key)
+		batch.Set(calcHistoryKey(keyHash, 0), wrapValue(value))
+		batch.Set(calcLatestKey(key), value)
+		batch.Set(calcLeafKey(keyHash), leafHash(keyHash, value))
+		count++
+		if count%importBatchSize == 0 {
+			err = batch.Write()
+			if err != nil {
+				return true
+			}
+			batch.Reset()
+			flog.Info("store flat import", "count", count)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	batch.Reset()
+
+	//叶子索引按 sha256(key) 有序, 同一个桶的叶子是连续的
+	tree := newBucketTree()
+	bucket := -1
+	leaves := make(map[string][]byte)
+	flush := func() {
+		if bucket < 0 {
+			return
+		}
+		digest := bucketDigest(leaves)
+		key := calcDigestKey(bucket)
+		batch.Set(calcHistoryKey(common.Sha256(key), 0), wrapValue(digest))
+		batch.Set(key, digest)
+		tree.nodes[bucketNum+bucket] = digest
+		leaves = make(map[string][]byte)
+	}
+	lit := db.Iterator(leafPrefix, nil, false)
+	defer lit.Close()
+	for lit.Rewind(); lit.Valid(); lit.Next() {
+		if lit.Error() != nil {
+			return nil, lit.Error()
+		}
+		keyHash := lit.Key()[len(leafPrefix):]
+		if b := bucketOf(keyHash); b != bucket {
+			flush()
+			bucket = b
+		}
+		leaves[string(keyHash)] = lit.ValueCopy()
+	}
+	flush()
+	tree.build()
+	hash := tree.root()
+	if hash == nil {
+		hash = make([]byte, 32)
+	}
+	batch.Set(calcVersionKey(0), types.Encode(&types.BlockInfo{Height: height, Hash: hash}))
+	batch.Set(calcRootKey(hash), types.Encode(&types.Int64{Data: 0}))
+	if alias != nil {
+		batch.Set(calcRootKey(alias), types.Encode(&types.Int64{Data: 0}))
+	}
+	err = batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	flog.Info("store flat import done", "count", count, "height", height, "hash", common.ToHex(hash))
+	return hash, nil
+}
@@ -0,0 +1,296 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+/*
+数据库中的key:
+
+.-flat-.l.key                -> value             最新值, 最新状态上的读取只需要一次查询
+.-flat-.h.sha256(key)version -> 0x01 + value      历史值, 用于分叉或历史状态的读取以及回滚
+.-flat-.f.sha256(key)        -> 叶子hash           最新状态的叶子索引, 按桶有序
+.-flat-.d.bucket             -> 桶hash             最新状态的桶hash, 历史值同样保存在 .-flat-.h. 中
+.-flat-.c.version            -> LocalDBSet         该版本修改过的key列表, 用于回滚
+.-flat-.v.version            -> BlockInfo          版本对应的状态hash和区块高度
+.-flat-.r.stateHash          -> Int64              状态hash对应的版本
+
+每个非空的kv集合提交后产生一个新的版本, 版本号连续递增
+*/
+
+var (
+	latestPrefix  = []byte(".-flat-.l.")
+	historyPrefix = []byte(".-flat-.h.")
+	leafPrefix    = []byte(".-flat-.f.")
+	digestPrefix  = []byte(".-flat-.d.")
+	changePrefix  = []byte(".-flat-.c.")
+	versionPrefix = []byte(".-flat-.v.")
+	rootPrefix    = []byte(".-flat-.r.")
+)
+
+var (
+	//ErrStateNotRevertible 版本没有修改记录(例如从mavl迁移得到的初始版本), 不能回滚到更早的状态
+	ErrStateNotRevertible = errors.New("ErrStateNotRevertible")
+	//ErrStoreNotEmpty 迁移的目标数据库中已经存在状态
+	ErrStoreNotEmpty = errors.New("ErrStoreNotEmpty")
+	//ErrStateNotLatest 基础版本之后还有更新的版本, 需要先通过Del回滚之后的版本才能提交
+	ErrStateNotLatest = errors.New("ErrStateNotLatest")
+)
+
+func encodeVersion(version int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(version))
+	return b[:]
+}
+
+func joinKey(prefix []byte, parts ...[]byte) []byte {
+	key := append([]byte{}, prefix...)
+	for _, p := range parts {
+		key = append(key, p...)
+	}
+	return key
+}
+
+func isDigestKey(key []byte) bool {
+	return bytes.HasPrefix(key, digestPrefix)
+}
+
+//calcLatestKey 桶hash本身就保存在 .-flat-.d. 下
+func calcLatestKey(key []byte) []byte {
+	if isDigestKey(key) {
+		return key
+	}
+	return joinKey(latestPrefix, key)
+}
+
+func calcHistoryKey(keyHash []byte, version int64) []byte {
+	return joinKey(historyPrefix, keyHash, encodeVersion(version))
+}
+
+func calcLeafKey(keyHash []byte) []byte {
+	return joinKey(leafPrefix, keyHash)
+}
+
+func calcBucketLeafPrefix(bucket int) []byte {
+	return joinKey(leafPrefix, []byte{byte(bucket >> 8), byte(bucket)})
+}
+
+func calcDigestKey(bucket int) []byte {
+	return joinKey(digestPrefix, []byte{byte(bucket >> 8), byte(bucket)})
+}
+
+func digestBucket(key []byte) int {
+	return int(key[len(digestPrefix)])<<8 | int(key[len(digestPrefix)+1])
+}
+
+func calcChangeKey(version int64) []byte {
+	return joinKey(changePrefix, encodeVersion(version))
+}
+
+func calcVersionKey(version int64) []byte {
+	return joinKey(versionPrefix, encodeVersion(version))
+}
+
+func calcRootKey(hash []byte) []byte {
+	return joinKey(rootPrefix, hash)
+}
+
+func wrapValue(value []byte) []byte {
+	return append([]byte{1}, value...)
+}
+
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	end[len(end)-1]++
+	return end
+}
+
+//getHistory 获取key在version(含)之前最后一次写入的值
+func getHistory(db dbm.DB, keyHash []byte, version int64) ([]byte, bool) {
+	if version < 0 {
+		return nil, false
+	}
+	it := db.Iterator(calcHistoryKey(keyHash, 0), calcHistoryKey(keyHash, version+1), true)
+	defer it.Close()
+	if !it.Rewind() {
+		return nil, false
+	}
+	value := it.ValueCopy()
+	if len(value) == 0 {
+		return nil, false
+	}
+	return value[1:], true
+}
+
+func getVersionInfo(db dbm.DB, version int64) (*types.BlockInfo, error) {
+	value, err := db.Get(calcVersionKey(version))
+	if err != nil {
+		return nil, err
+	}
+	var info types.BlockInfo
+	err = types.Decode(value, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func getRootVersion(db dbm.DB, hash []byte) (int64, error) {
+	value, err := db.Get(calcRootKey(hash))
+	if err != nil {
+		return 0, err
+	}
+	var data types.Int64
+	err = types.Decode(value, &data)
+	if err != nil {
+		return 0, err
+	}
+	return data.Data, nil
+}
+
+func getChangeKeys(db dbm.DB, version int64) ([][]byte, error) {
+	value, err := db.Get(calcChangeKey(version))
+	if err == dbm.ErrNotFoundInDb {
+		return nil, ErrStateNotRevertible
+	}
+	if err != nil {
+		return nil, err
+	}
+	var set types.LocalDBSet
+	err = types.Decode(value, &set)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][]byte, len(set.KV))
+	for i, kv := range set.KV {
+		keys[i] = kv.Key
+	}
+	return keys, nil
+}
+
+//view 最新状态之前某个版本的视图, 只记录该版本之后被修改过的key在该版本时的叶子和桶hash
+type view struct {
+	version int64
+	//bucket -> keyHash -> 叶子hash, nil 表示该版本时key不存在
+	leaves  map[int]map[string][]byte
+	digests map[int][]byte
+}
+
+func newView(db dbm.DB, version, latest int64) (*view, error) {
+	v := &view{version: version, leaves: make(map[int]map[string][]byte), digests: make(map[int][]byte)}
+	seen := make(map[string]bool)
+	for i := latest; i > version; i-- {
+		keys, err := getChangeKeys(db, i)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+			keyHash := common.Sha256(key)
+			value, ok := getHistory(db, keyHash, version)
+			if isDigestKey(key) {
+				v.digests[digestBucket(key)] = value
+				continue
+			}
+			bucket := bucketOf(keyHash)
+			if v.leaves[bucket] == nil {
+				v.leaves[bucket] = make(map[string][]byte)
+			}
+			if ok {
+				v.leaves[bucket][string(keyHash)] = leafHash(keyHash, value)
+			} else {
+				v.leaves[bucket][string(keyHash)] = nil
+			}
+		}
+	}
+	return v, nil
+}
+
+//bucketLeaves 获取视图中某个桶的全部叶子
+func (v *view) bucketLeaves(db dbm.DB, bucket int) (map[string][]byte, error) {
+	leaves := make(map[string][]byte)
+	it := db.Iterator(calcBucketLeafPrefix(bucket), nil, false)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		if it.Error() != nil {
+			return nil, it.Error()
+		}
+		leaves[string(it.Key()[len(leafPrefix):])] = it.ValueCopy()
+	}
+	for keyHash, leaf := range v.leaves[bucket] {
+		if leaf == nil {
+			delete(leaves, keyHash)
+		} else {
+			leaves[keyHash] = leaf
+		}
+	}
+	return leaves, nil
+}
+
+//revert 把状态从latest回滚到version, 修改写入batch, 返回回滚后被修改的桶hash
+func revert(db dbm.DB, batch dbm.Batch, latest, version int64) (map[int][]byte, error) {
+	digests := make(map[int][]byte)
+	seen := make(map[string]bool)
+	for i := latest; i > version; i-- {
+		keys, err := getChangeKeys(db, i)
+		if err != nil {
+			return nil, err
+		}
+		info, err := getVersionInfo(db, i)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			keyHash := common.Sha256(key)
+			batch.Delete(calcHistoryKey(keyHash, i))
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+			value, ok := getHistory(db, keyHash, version)
+			if isDigestKey(key) {
+				digests[digestBucket(key)] = value
+			}
+			if ok {
+				batch.Set(calcLatestKey(key), value)
+			} else {
+				batch.Delete(calcLatestKey(key))
+			}
+			if isDigestKey(key) {
+				continue
+			}
+			if ok {
+				batch.Set(calcLeafKey(keyHash), leafHash(keyHash, value))
+			} else {
+				batch.Delete(calcLeafKey(keyHash))
+			}
+		}
+		batch.Delete(calcChangeKey(i))
+		batch.Delete(calcVersionKey(i))
+		if v, err := getRootVersion(db, info.Hash); err == nil && v == i {
+			batch.Delete(calcRootKey(info.Hash))
+		}
+	}
+	if version >= 0 {
+		//回滚的版本和保留的版本状态hash相同时, 需要恢复保留版本的映射
+		info, err := getVersionInfo(db, version)
+		if err != nil {
+			return nil, err
+		}
+		batch.Set(calcRootKey(info.Hash), types.Encode(&types.Int64{Data: version}))
+	}
+	return digests, nil
+}
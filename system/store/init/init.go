@@ -7,5 +7,6 @@ package init
 
 import (
 	// Register some standard stuff
+	_ "github.com/33cn/chain33/system/store/flat"
 	_ "github.com/33cn/chain33/system/store/mavl"
 )
@@ -0,0 +1,26 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mavl
+
+import (
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+// 登记store数据库中mavl树使用的key前缀, 没有开启EnableMavlPrefix时节点的key为hash, 只能作为其他前缀都不匹配时的默认类型
+func init() {
+	node := func() types.Message { return &types.StoreNode{} }
+	height := func() types.Message { return &types.Int64{} }
+	pruneData := func() types.Message { return &types.PruneData{} }
+	dbm.RegisterKeyPrefix("store", "", "mavl节点", node)
+	dbm.RegisterKeyPrefix("store", hashNodePrefix, "mavl中间节点", node)
+	dbm.RegisterKeyPrefix("store", leafNodePrefix, "mavl叶子节点", node)
+	dbm.RegisterKeyPrefix("store", curMaxBlockHeight, "mavl树的最大高度", height)
+	dbm.RegisterKeyPrefix("store", rootHashHeightPrefix, "高度 -> 状态根", height)
+	dbm.RegisterKeyPrefix("store", rootHashIndexPrefix, "状态根 -> 高度", height)
+	dbm.RegisterKeyPrefix("store", leafKeyCountPrefix, "叶子节点索引", pruneData)
+	dbm.RegisterKeyPrefix("store", oldLeafKeyCountPrefix, "叶子节点二级索引", pruneData)
+	dbm.RegisterKeyPrefix("store", pruneProgressKey, "裁剪进度", func() types.Message { return &types.StorePruneProgress{} })
+}
@@ -262,6 +262,7 @@ func (node *Node) saveRootHash(t *Tree) (err error) {
 		return err
 	}
 	t.ndb.batch.Set(genRootHashHeight(t.blockHeight, node.hash), value)
+	t.ndb.batch.Set(genRootHashIndex(node.hash), value)
 	return nil
 }
 
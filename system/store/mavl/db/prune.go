@@ -7,58 +7,31 @@ package mavl
 import (
 	"bytes"
 	"fmt"
-	"sync"
-
 	"runtime"
-	"sync/atomic"
-	"time"
-
 	"strconv"
+	"sync"
 
 	"github.com/33cn/chain33/common"
 	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/trace"
 	"github.com/33cn/chain33/types"
 	"github.com/golang/protobuf/proto"
 )
 
 const (
-	leafKeyCountPrefix     = "..mk.."
-	oldLeafKeyCountPrefix  = "..mok.."
-	secLvlPruningHeightKey = "_..mslphk.._"
-	blockHeightStrLen      = 10
-	hashLenStr             = 3
-	pruningStateStart      = 1
-	pruningStateEnd        = 0
-	//二级裁剪高度，达到此高度未裁剪则放入该处
-	secondLevelPruningHeight = 500000
-	//三级裁剪高度，达到此高度还没有裁剪，则不进行裁剪
-	threeLevelPruningHeight = 1500000
-	onceScanCount           = 10000 // 单次扫描数目
-	onceCount               = 1000  // 容器长度
-	batchDataSize           = 1024 * 1024 * 1
-	DefaultPruneHeight      = 10000
+	leafKeyCountPrefix    = "..mk.."
+	oldLeafKeyCountPrefix = "..mok.."
+	pruneProgressKey      = "_..mpp.._"
+	blockHeightStrLen     = 10
+	hashLenStr            = 3
+	// DefaultPruneHeight 默认保留的状态高度数目
+	DefaultPruneHeight = 10000
+	// DefaultPruneBatchSize 默认每个区块扫描的叶子节点索引数目
+	DefaultPruneBatchSize = 5000
 )
 
-var (
-	// 裁剪状态
-	pruningState   int32
-	wg             sync.WaitGroup
-	quit           bool
-	secLvlPruningH int64
-)
-
-type hashData struct {
-	height int64
-	hash   []byte
-}
-
-// ClosePrune 关闭裁剪
-func ClosePrune() {
-	quit = true
-	wg.Wait()
-	//防止BaseStore没有关闭再次进入
-	setPruning(pruningStateStart)
-}
+// 叶子节点索引, 旧版本的裁剪会把长期没有裁剪的索引转移到 oldLeafKeyCountPrefix 下
+var pruneIndexPrefixes = []string{leafKeyCountPrefix, oldLeafKeyCountPrefix}
 
 func genLeafCountKey(key, hash []byte, height int64, hashLen int) (hashkey []byte) {
 	hashkey = []byte(fmt.Sprintf("%s%s%010d%s%03d", leafKeyCountPrefix, string(key), height, string(hash), hashLen))
@@ -120,276 +93,345 @@ func getKeyHeightFromOldLeafCountKey(hashkey []byte) (key []byte, height int, ha
 	return key, height, hash, nil
 }
 
-func genOldLeafCountKeyFromKey(hashk []byte) (oldhashk []byte) {
-	if len(hashk) < len(leafKeyCountPrefix) {
-		return hashk
+func getKeyHeightFromIndexKey(prefix string, hashkey []byte) (key []byte, height int, hash []byte, err error) {
+	if prefix == oldLeafKeyCountPrefix {
+		return getKeyHeightFromOldLeafCountKey(hashkey)
 	}
-	oldhashk = []byte(fmt.Sprintf("%s%s", oldLeafKeyCountPrefix, string(hashk[len(leafKeyCountPrefix):])))
-	return oldhashk
+	return getKeyHeightFromLeafCountKey(hashkey)
 }
 
-func isPruning() bool {
-	return atomic.LoadInt32(&pruningState) == 1
+// prefixEnd 前缀扫描的结束key
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	end[len(end)-1]++
+	return end
 }
 
-func setPruning(state int32) {
-	atomic.StoreInt32(&pruningState, state)
+// Pruner mavl状态裁剪
+// 保存区块时每个新的叶子节点都记录了一条索引, 包含同时生成的父节点. 同一个key在高度h生成的叶子,
+// 在下一个版本的高度next之后就不会再被引用, 这些节点只被[h, next)之间的状态根引用.
+// 裁剪保留最近 PruneHeight 个高度的全部状态, 以及每 PruneArchiveInterval 个高度的归档状态,
+// 每次提交区块之后最多扫描 PruneBatchSize 条索引, 裁剪进度和删除的节点写入同一个batch, 重启之后继续.
+// 回滚的深度不能超过 PruneHeight, 否则分叉点的状态可能已经被裁剪
+type Pruner struct {
+	db       dbm.DB
+	cfg      *TreeConfig
+	mtx      sync.Mutex
+	progress *types.StorePruneProgress
+	height   int64
+	paused   bool
 }
 
-func getSecLvlPruningHeight(db dbm.DB) int64 {
-	value, err := db.Get([]byte(secLvlPruningHeightKey))
-	if len(value) == 0 || err != nil {
-		return 0
+// NewPruner 新建裁剪, 从数据库中加载上一次的裁剪进度
+func NewPruner(db dbm.DB, treeCfg *TreeConfig) *Pruner {
+	p := &Pruner{db: db, cfg: treeCfg, progress: &types.StorePruneProgress{}}
+	value, err := db.Get([]byte(pruneProgressKey))
+	if err == nil && len(value) > 0 {
+		err = types.Decode(value, p.progress)
+		if err != nil {
+			treelog.Error("NewPruner", "decode progress err", err)
+			p.progress = &types.StorePruneProgress{}
+		}
 	}
-	h := &types.Int64{}
-	err = proto.Unmarshal(value, h)
-	if err != nil {
-		return 0
+	value, err = db.Get([]byte(curMaxBlockHeight))
+	if err == nil && len(value) > 0 {
+		h := &types.Int64{}
+		if types.Decode(value, h) == nil {
+			p.height = h.Data
+		}
 	}
-	return h.Data
+	return p
 }
 
-func setSecLvlPruningHeight(db dbm.DB, height int64) error {
-	h := &types.Int64{}
-	h.Data = height
-	value, err := proto.Marshal(h)
-	if err != nil {
-		return err
-	}
-	return db.Set([]byte(secLvlPruningHeightKey), value)
+func (p *Pruner) enabled() bool {
+	return p.cfg != nil && p.cfg.EnableMavlPrune && p.cfg.PruneHeight > 0
 }
 
-func pruning(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	defer wg.Done()
-	pruningTree(db, curHeight, treeCfg)
+func (p *Pruner) batchSize() int {
+	if p.cfg == nil || p.cfg.PruneBatchSize <= 0 {
+		return DefaultPruneBatchSize
+	}
+	return int(p.cfg.PruneBatchSize)
 }
 
-func pruningTree(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	setPruning(pruningStateStart)
-	// 一级遍历
-	pruningFirstLevel(db, curHeight, treeCfg)
-	// 二级遍历
-	pruningSecondLevel(db, curHeight, treeCfg)
-	setPruning(pruningStateEnd)
+// Prune 提交区块之后调用, 推进一步裁剪
+func (p *Pruner) Prune(curHeight int64) {
+	if !p.enabled() {
+		return
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.height = curHeight
+	if p.paused {
+		return
+	}
+	//裁剪在提交区块时同步执行, 子存储接口不传递span上下文, 单独作为一个trace
+	span := trace.StartSpan(trace.SpanContext{}, "mavl.pruning")
+	defer span.End()
+	span.SetAttribute("height", curHeight)
+	reclaimed := p.progress.ReclaimedNodes
+	done := p.step(curHeight, p.batchSize())
+	span.SetAttribute("done", done)
+	span.SetAttribute("reclaimedNodes", p.progress.ReclaimedNodes-reclaimed)
 }
 
-func pruningFirstLevel(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	treelog.Info("pruningTree pruningFirstLevel", "start curHeight:", curHeight)
-	start := time.Now()
-	pruningFirstLevelNode(db, curHeight, treeCfg)
-	end := time.Now()
-	treelog.Info("pruningTree pruningFirstLevel", "curHeight:", curHeight, "pruning leafNode cost time:", end.Sub(start))
+// SetPause 暂停或者恢复裁剪, 不会持久化, 重启之后恢复裁剪
+func (p *Pruner) SetPause(pause bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.paused = pause
 }
 
-func pruningFirstLevelNode(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	prefix := []byte(leafKeyCountPrefix)
-	it := db.Iterator(prefix, nil, true)
-	defer it.Close()
-
-	var mp map[string][]hashData
-	var kvs []*types.KeyValue
-	count := 0
-	batch := db.NewBatch(true)
-	for it.Rewind(); it.Valid(); it.Next() {
-		if quit {
-			//该处退出
-			return
-		}
-		if mp == nil {
-			mp = make(map[string][]hashData, onceCount)
-		}
-		//copy key
-		hashK := make([]byte, len(it.Key()))
-		copy(hashK, it.Key())
+// Status 裁剪状态
+func (p *Pruner) Status() *types.StorePruneStatus {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	status := &types.StorePruneStatus{
+		Enable:         p.enabled(),
+		Paused:         p.paused,
+		CurHeight:      p.height,
+		PrunedHeight:   p.progress.PrunedHeight,
+		PruningHeight:  p.progress.PruningHeight,
+		ReclaimedNodes: p.progress.ReclaimedNodes,
+		Scanned:        p.progress.Scanned,
+	}
+	if p.cfg != nil {
+		status.RetainHeight = int64(p.cfg.PruneHeight)
+		status.ArchiveInterval = p.cfg.PruneArchiveInterval
+	}
+	if status.Enable && p.height-status.RetainHeight > status.PrunedHeight {
+		status.Backlog = p.height - status.RetainHeight - status.PrunedHeight
+	}
+	return status
+}
 
-		key, height, hash, err := getKeyHeightFromLeafCountKey(hashK)
-		if err != nil {
-			continue
-		}
-		if curHeight < int64(height)+secondLevelPruningHeight {
-			if curHeight >= int64(height)+int64(treeCfg.PruneHeight) {
-				data := hashData{
-					height: int64(height),
-					hash:   hash,
-				}
-				mp[string(key)] = append(mp[string(key)], data)
-				count++
-			}
-		} else {
-			value := make([]byte, len(it.Value()))
-			copy(value, it.Value())
-			kvs = append(kvs, &types.KeyValue{Key: hashK, Value: value})
+// step 扫描最多limit条索引, 返回这一轮裁剪是否已经完成
+func (p *Pruner) step(curHeight int64, limit int) bool {
+	prog := p.progress
+	if prog.PruningHeight == 0 {
+		safeHeight := curHeight - int64(p.cfg.PruneHeight)
+		if safeHeight <= prog.PrunedHeight {
+			return true
 		}
-		if len(mp) >= onceCount-1 || count > onceScanCount {
-			deleteNode(db, mp, curHeight, batch, treeCfg)
-			mp = nil
-			count = 0
-		}
-		if len(kvs) >= onceCount {
-			addLeafCountKeyToSecondLevel(db, kvs, batch)
-			kvs = kvs[:0]
+		prog.PruningHeight = safeHeight
+		prog.Cursor = nil
+		prog.Scanned = 0
+		treelog.Debug("mavl prune start", "height", curHeight, "pruningHeight", safeHeight)
+	}
+	batch := p.db.NewBatch(true)
+	//查找下一个版本时每个索引前缀复用同一个迭代器, 不为每条索引新建迭代器, 没有索引的前缀不需要查找
+	seekers := make(map[string]dbm.Iterator, len(pruneIndexPrefixes))
+	for _, prefix := range pruneIndexPrefixes {
+		it := p.db.Iterator([]byte(prefix), prefixEnd([]byte(prefix)), false)
+		defer it.Close()
+		if it.Rewind() {
+			seekers[prefix] = it
 		}
 	}
-	if len(mp) > 0 {
-		deleteNode(db, mp, curHeight, batch, treeCfg)
-		mp = nil
-		_ = mp
+	done := false
+	for _, prefix := range pruneIndexPrefixes[p.cursorPhase():] {
+		n, finished := p.scan(batch, seekers, prefix, limit)
+		limit -= n
+		if !finished {
+			break
+		}
+		done = prefix == pruneIndexPrefixes[len(pruneIndexPrefixes)-1]
 	}
-	if len(kvs) > 0 {
-		addLeafCountKeyToSecondLevel(db, kvs, batch)
+	if done {
+		treelog.Debug("mavl prune finish", "height", curHeight, "prunedHeight", prog.PruningHeight,
+			"scanned", prog.Scanned, "reclaimedNodes", prog.ReclaimedNodes)
+		prog.PrunedHeight = prog.PruningHeight
+		prog.PruningHeight = 0
+		prog.Cursor = nil
+		prog.Scanned = 0
 	}
+	batch.Set([]byte(pruneProgressKey), types.Encode(prog))
+	dbm.MustWrite(batch)
+	return done
 }
 
-func addLeafCountKeyToSecondLevel(db dbm.DB, kvs []*types.KeyValue, batch dbm.Batch) {
-	batch.Reset()
-	for _, kv := range kvs {
-		batch.Delete(kv.Key)
-		batch.Set(genOldLeafCountKeyFromKey(kv.Key), kv.Value)
-		if batch.ValueSize() > batchDataSize {
-			dbm.MustWrite(batch)
-			batch.Reset()
+// cursorPhase 游标所在的索引前缀
+func (p *Pruner) cursorPhase() int {
+	for i, prefix := range pruneIndexPrefixes {
+		if bytes.HasPrefix(p.progress.Cursor, []byte(prefix)) {
+			return i
 		}
 	}
-	dbm.MustWrite(batch)
+	return 0
 }
 
-func deleteNode(db dbm.DB, mp map[string][]hashData, curHeight int64, batch dbm.Batch, treeCfg *TreeConfig) {
-	if len(mp) == 0 {
-		return
-	}
-	batch.Reset()
-	for key, vals := range mp {
-		if len(vals) > 1 && vals[1].height != vals[0].height { //防止相同高度时候出现的误删除
-			for _, val := range vals[1:] { //从第二个开始判断
-				if curHeight >= val.height+int64(treeCfg.PruneHeight) {
-					leafCountKey := genLeafCountKey([]byte(key), val.hash, val.height, len(val.hash))
-					value, err := db.Get(leafCountKey)
-					if err == nil {
-						var pData types.PruneData
-						err := proto.Unmarshal(value, &pData)
-						if err == nil {
-							for _, hash := range pData.Hashs {
-								batch.Delete(hash)
-							}
-						}
-					}
-					batch.Delete(leafCountKey) // 叶子计数节点
-					batch.Delete(val.hash)     // 叶子节点hash值
-					if batch.ValueSize() > batchDataSize {
-						dbm.MustWrite(batch)
-						batch.Reset()
-					}
-				}
-			}
-		}
-		delete(mp, key)
-	}
-	dbm.MustWrite(batch)
+// leafIndex 扫描到的一条叶子节点索引, key为nil表示无法解析的索引
+type leafIndex struct {
+	hashK  []byte
+	value  []byte
+	key    []byte
+	height int64
+	hash   []byte
 }
 
-func pruningSecondLevel(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	if secLvlPruningH == 0 {
-		secLvlPruningH = getSecLvlPruningHeight(db)
-	}
-	if curHeight/secondLevelPruningHeight > 1 &&
-		curHeight/secondLevelPruningHeight != secLvlPruningH/secondLevelPruningHeight {
-		treelog.Info("pruningTree pruningSecondLevel", "start curHeight:", curHeight)
-		start := time.Now()
-		pruningSecondLevelNode(db, curHeight, treeCfg)
-		end := time.Now()
-		treelog.Info("pruningTree pruningSecondLevel", "curHeight:", curHeight, "pruning leafNode cost time:", end.Sub(start))
-		err := setSecLvlPruningHeight(db, curHeight)
-		if err != nil {
-			return
-		}
-		secLvlPruningH = curHeight
+func newLeafIndex(prefix string, hashK, value []byte) *leafIndex {
+	leaf := &leafIndex{hashK: hashK, value: value}
+	key, height, hash, err := getKeyHeightFromIndexKey(prefix, hashK)
+	if err == nil {
+		leaf.key, leaf.height, leaf.hash = key, int64(height), hash
 	}
+	return leaf
 }
 
-func pruningSecondLevelNode(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	prefix := []byte(oldLeafKeyCountPrefix)
-	it := db.Iterator(prefix, nil, true)
-	defer it.Close()
+// sameVersion 同一个key在同一高度的索引, 是分叉产生的同一个版本
+func (leaf *leafIndex) sameVersion(other *leafIndex) bool {
+	return leaf.key != nil && other.key != nil && leaf.height == other.height && bytes.Equal(leaf.key, other.key)
+}
 
-	var mp map[string][]hashData
+// scan 从游标开始扫描一个前缀下的索引, 返回扫描的数目以及该前缀是否已经扫描完成
+// 同一个前缀下的索引按照key和高度排序, 读到下一条索引之后再处理上一个版本, 通常不需要再查找下一个版本
+func (p *Pruner) scan(batch dbm.Batch, seekers map[string]dbm.Iterator, prefix string, limit int) (int, bool) {
+	prog := p.progress
+	start := []byte(prefix)
+	if bytes.HasPrefix(prog.Cursor, start) {
+		start = prog.Cursor
+	}
+	it := p.db.Iterator(start, prefixEnd([]byte(prefix)), false)
+	defer it.Close()
 	count := 0
-	batch := db.NewBatch(true)
-	for it.Rewind(); it.Valid(); it.Next() {
-		if quit {
-			//该处退出
-			return
+	var pending []*leafIndex
+	process := func(next *leafIndex) {
+		for _, leaf := range pending {
+			prog.Cursor = leaf.hashK
+			prog.Scanned++
+			count++
+			prog.ReclaimedNodes += p.pruneLeaf(batch, seekers, prefix, leaf, next)
 		}
-		if mp == nil {
-			mp = make(map[string][]hashData, onceCount)
+		pending = pending[:0]
+	}
+	for it.Rewind(); it.Valid(); it.Next() {
+		if bytes.Equal(it.Key(), prog.Cursor) {
+			continue
 		}
-		//copy key
 		hashK := make([]byte, len(it.Key()))
 		copy(hashK, it.Key())
-		key, height, hash, err := getKeyHeightFromOldLeafCountKey(hashK)
-		if err == nil {
-			data := hashData{
-				height: int64(height),
-				hash:   hash,
+		leaf := newLeafIndex(prefix, hashK, it.ValueCopy())
+		if len(pending) > 0 && !pending[0].sameVersion(leaf) {
+			process(leaf)
+		}
+		if len(pending) == 0 && count >= limit {
+			return count, false
+		}
+		pending = append(pending, leaf)
+	}
+	process(nil)
+	return count, true
+}
+
+// pruneLeaf 叶子节点在下一个版本之前的状态都不需要保留时, 删除叶子节点, 父节点以及索引, 返回删除的节点数目
+// next 为同一个前缀下扫描到的下一条索引, 是同一个key的更高版本时不需要再在这个前缀下查找
+func (p *Pruner) pruneLeaf(batch dbm.Batch, seekers map[string]dbm.Iterator, prefix string, leaf, next *leafIndex) int64 {
+	if leaf.key == nil {
+		return 0
+	}
+	var nextHeight int64
+	found := false
+	skip := ""
+	if next != nil && next.key != nil && next.height > leaf.height && bytes.Equal(next.key, leaf.key) {
+		nextHeight, found, skip = next.height, true, prefix
+	}
+	h, ok := nextVersion(seekers, leaf.key, leaf.height, skip)
+	if ok && (!found || h < nextHeight) {
+		nextHeight, found = h, true
+	}
+	if !found || !p.canPrune(leaf.height, nextHeight) {
+		return 0
+	}
+	var pData types.PruneData
+	err := proto.Unmarshal(leaf.value, &pData)
+	if err == nil {
+		for _, h := range pData.Hashs {
+			batch.Delete(h)
+		}
+	}
+	batch.Delete(leaf.hashK)
+	batch.Delete(leaf.hash)
+	return int64(len(pData.Hashs)) + 1
+}
+
+// nextVersion 获取key在height之后的下一个版本的高度, 同一高度的其他版本是分叉产生的, 不作为下一个版本. skip 前缀下不查找
+func nextVersion(seekers map[string]dbm.Iterator, key []byte, height int64, skip string) (int64, bool) {
+	var next int64
+	found := false
+	for _, prefix := range pruneIndexPrefixes {
+		if prefix == skip {
+			continue
+		}
+		start := []byte(fmt.Sprintf("%s%s%010d", prefix, string(key), height+1))
+		//高度都是数字, 以key为前缀的其他key也可能落在这个区间内, 需要解析之后比较
+		end := []byte(fmt.Sprintf("%s%s:", prefix, string(key)))
+		it, ok := seekers[prefix]
+		if !ok {
+			continue
+		}
+		for it.Seek(start); it.Valid() && bytes.Compare(it.Key(), end) < 0; it.Next() {
+			k, h, _, err := getKeyHeightFromIndexKey(prefix, it.Key())
+			if err != nil || !bytes.Equal(k, key) || int64(h) <= height {
+				continue
 			}
-			mp[string(key)] = append(mp[string(key)], data)
-			count++
-			if len(mp) >= onceCount-1 || count > onceScanCount {
-				deleteOldNode(db, mp, curHeight, batch, treeCfg)
-				mp = nil
-				count = 0
+			if !found || int64(h) < next {
+				next = int64(h)
+				found = true
 			}
+			break
 		}
 	}
-	if len(mp) > 0 {
-		deleteOldNode(db, mp, curHeight, batch, treeCfg)
-		mp = nil
-		_ = mp
+	return next, found
+}
+
+// CheckState 检查状态根对应的状态是否可以完整读取, 已经被裁剪返回 types.ErrStatePruned
+// 没有记录高度的状态根(未开启裁剪时保存的状态)不做检查
+func (p *Pruner) CheckState(stateHash []byte) error {
+	if !p.enabled() || len(stateHash) == 0 {
+		return nil
 	}
+	height, err := GetRootHashHeight(p.db, stateHash)
+	if err != nil {
+		return nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	limit := p.progress.PrunedHeight
+	if p.progress.PruningHeight > limit {
+		limit = p.progress.PruningHeight
+	}
+	if height >= limit {
+		return nil
+	}
+	interval := p.cfg.PruneArchiveInterval
+	if interval > 0 && height%interval == 0 {
+		return nil
+	}
+	return types.ErrStatePruned
 }
 
-func deleteOldNode(db dbm.DB, mp map[string][]hashData, curHeight int64, batch dbm.Batch, treeCfg *TreeConfig) {
-	if len(mp) == 0 {
-		return
+// canPrune 高度区间[height, next)内的状态都不需要保留时可以裁剪
+func (p *Pruner) canPrune(height, next int64) bool {
+	if next > p.progress.PruningHeight {
+		return false
 	}
-	batch.Reset()
-	for key, vals := range mp {
-		if len(vals) > 1 {
-			if vals[1].height != vals[0].height { //防止相同高度时候出现的误删除
-				for _, val := range vals[1:] { //从第二个开始判断
-					if curHeight >= val.height+int64(treeCfg.PruneHeight) {
-						leafCountKey := genOldLeafCountKey([]byte(key), val.hash, val.height, len(val.hash))
-						value, err := db.Get(leafCountKey)
-						if err == nil {
-							var pData types.PruneData
-							err := proto.Unmarshal(value, &pData)
-							if err == nil {
-								for _, hash := range pData.Hashs {
-									batch.Delete(hash)
-								}
-							}
-						}
-						batch.Delete(leafCountKey)
-						batch.Delete(val.hash) // 叶子节点hash值
-					}
-				}
-			} else {
-				// 删除第三层存储索引key
-				for _, val := range vals {
-					if curHeight >= val.height+threeLevelPruningHeight {
-						batch.Delete(genOldLeafCountKey([]byte(key), val.hash, val.height, len(val.hash)))
-					}
-				}
-			}
-		} else if len(vals) == 1 && curHeight >= vals[0].height+threeLevelPruningHeight { // 删除第三层存储索引key
-			batch.Delete(genOldLeafCountKey([]byte(key), vals[0].hash, vals[0].height, len(vals[0].hash)))
-		}
-		delete(mp, key)
-		if batch.ValueSize() > batchDataSize {
-			dbm.MustWrite(batch)
-			batch.Reset()
+	interval := p.cfg.PruneArchiveInterval
+	if interval > 0 && (height+interval-1)/interval*interval < next {
+		return false
+	}
+	return true
+}
+
+// PruningTree 裁剪树, 以curHeight为当前高度完成一轮裁剪
+func PruningTree(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
+	p := NewPruner(db, treeCfg)
+	safeHeight := curHeight - int64(treeCfg.PruneHeight)
+	for {
+		//可能先完成重启之前未完成的一轮裁剪, 再开始新的一轮
+		if p.step(curHeight, p.batchSize()) && p.progress.PrunedHeight >= safeHeight {
+			return
 		}
 	}
-	dbm.MustWrite(batch)
 }
 
 // PruningTreePrintDB pruning tree print db
@@ -549,11 +591,6 @@ func (node *Node) printNodeInfo(db *nodeDB) {
 	}
 }
 
-// PruningTree 裁剪树
-func PruningTree(db dbm.DB, curHeight int64, treeCfg *TreeConfig) {
-	pruningTree(db, curHeight, treeCfg)
-}
-
 // PrintMemStats 打印内存使用情况
 func PrintMemStats(height int64) {
 	var m runtime.MemStats
@@ -13,12 +13,14 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/33cn/chain33/blockchain"
 	"github.com/33cn/chain33/common"
 	dbm "github.com/33cn/chain33/common/db"
 	clog "github.com/33cn/chain33/common/log"
 	log "github.com/33cn/chain33/common/log/log15"
 	mavl "github.com/33cn/chain33/system/store/mavl/db"
 	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
 )
 
 var (
@@ -37,10 +39,31 @@ var (
 	key    = flag.String("k", "", "查询叶子节点父节点所需要：key")
 	hash   = flag.String("h", "", "查询叶子节点父节点所需要：hash")
 	height = flag.Int64("hei", 0, "查询叶子节点父节点所需要：height")
+
+	//verify 只读方式校验停止节点的数据
+	verify      = flag.Bool("verify", false, "校验区块高度范围内的状态树以及叶子节点索引, 需在节点停止时执行")
+	configPath  = flag.String("f", "chain33.toml", "verify 使用的配置文件")
+	datadir     = flag.String("datadir", "", "verify 使用的数据目录, 默认使用配置文件中的目录")
+	startHeight = flag.Int64("start", 0, "verify 开始高度")
+	endHeight   = flag.Int64("end", -1, "verify 结束高度, 默认最新高度")
 )
 
+type mavlConfig struct {
+	EnableMavlPrefix     bool  `json:"enableMavlPrefix"`
+	EnableMVCC           bool  `json:"enableMVCC"`
+	EnableMavlPrune      bool  `json:"enableMavlPrune"`
+	PruneHeight          int32 `json:"pruneHeight"`
+	PruneArchiveInterval int64 `json:"pruneArchiveInterval"`
+}
+
 func main() {
 	flag.Parse()
+	if *verify {
+		if !verifyState() {
+			os.Exit(1)
+		}
+		return
+	}
 	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
 	if err != nil {
 		fmt.Println(err)
@@ -111,3 +134,91 @@ func main() {
 		}
 	}
 }
+
+// verifyState 校验每个区块的状态根在store中存在且整棵树完整, 已经被裁剪的状态跳过
+func verifyState() bool {
+	clog.SetLogLevel("info")
+	cfg := types.NewChain33Config(types.ReadFile(*configPath))
+	mcfg := cfg.GetModuleConfig()
+	if *datadir != "" {
+		util.ResetDatadir(mcfg, *datadir)
+	}
+	var sub mavlConfig
+	if data, ok := cfg.GetSubConfig().Store["mavl"]; ok {
+		types.MustDecode(data, &sub)
+	}
+	treeCfg := &mavl.TreeConfig{
+		EnableMavlPrefix:     sub.EnableMavlPrefix || sub.EnableMavlPrune,
+		EnableMVCC:           sub.EnableMVCC,
+		EnableMavlPrune:      sub.EnableMavlPrune,
+		PruneHeight:          sub.PruneHeight,
+		PruneArchiveInterval: sub.PruneArchiveInterval,
+	}
+	if treeCfg.EnableMavlPrune && treeCfg.PruneHeight == 0 {
+		treeCfg.PruneHeight = mavl.DefaultPruneHeight
+	}
+
+	chainDB, err := dbm.NewReadOnlyDB("blockchain", mcfg.BlockChain.Driver, mcfg.BlockChain.DbPath, mcfg.BlockChain.DbCache)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open blockchain db", err)
+		return false
+	}
+	defer chainDB.Close()
+	storeDB, err := dbm.NewReadOnlyDB("store", mcfg.Store.Driver, mcfg.Store.DbPath, mcfg.Store.DbCache)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open store db", err)
+		return false
+	}
+	defer storeDB.Close()
+
+	end := *endHeight
+	if end < 0 {
+		header, err := blockchain.LoadLastHeader(chainDB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "load last header", err)
+			return false
+		}
+		end = header.Height
+	}
+	pruner := mavl.NewPruner(storeDB, treeCfg)
+	verifier := mavl.NewVerifier(storeDB, treeCfg)
+	var pruned, broken, noHeader int64
+	for h := *startHeight; h <= end; h++ {
+		header, err := blockchain.LoadHeaderByHeight(chainDB, h)
+		if err != nil {
+			fmt.Println("header", "height:", h, err)
+			noHeader++
+			continue
+		}
+		if pruner.CheckState(header.StateHash) == types.ErrStatePruned {
+			pruned++
+			continue
+		}
+		ok, err := verifier.VerifyRoot(h, header.StateHash)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "verify height", h, err)
+			return false
+		}
+		if !ok {
+			fmt.Println("broken", "height:", h)
+			broken++
+		}
+		if h%1000 == 0 {
+			log.Info("verify state", "height", h, "nodes", verifier.Nodes, "problems", len(verifier.Problems))
+		}
+	}
+	var indexes int64
+	if treeCfg.EnableMavlPrune {
+		indexes, err = verifier.VerifyLeafIndex(*startHeight, end)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "verify leaf index", err)
+			return false
+		}
+	}
+	for _, p := range verifier.Problems {
+		fmt.Println(p.String())
+	}
+	fmt.Printf("verify height %d-%d: broken %d, pruned %d, no header %d, nodes %d, leafs %d, leaf indexes %d, problems %d\n",
+		*startHeight, end, broken, pruned, noHeader, verifier.Nodes, verifier.Leafs, indexes, len(verifier.Problems))
+	return broken == 0 && noHeader == 0 && len(verifier.Problems) == 0
+}
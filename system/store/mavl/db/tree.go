@@ -26,6 +26,7 @@ const (
 	leafNodePrefix             = "_mb_"
 	curMaxBlockHeight          = "_..mcmbh.._"
 	rootHashHeightPrefix       = "_mrhp_"
+	rootHashIndexPrefix        = "_mrhh_"
 	tkCloseCacheLen      int32 = 10 * 10000
 )
 
@@ -67,10 +68,15 @@ type TreeConfig struct {
 	EnableMavlPrefix bool
 	EnableMVCC       bool
 	EnableMavlPrune  bool
-	PruneHeight      int32
-	EnableMemTree    bool
-	EnableMemVal     bool
-	TkCloseCacheLen  int32
+	// 保留最近PruneHeight个高度的全部状态
+	PruneHeight int32
+	// 每PruneArchiveInterval个高度永久保留一个状态, 0表示不保留
+	PruneArchiveInterval int64
+	// 每个区块最多扫描的叶子节点索引数目
+	PruneBatchSize  int32
+	EnableMemTree   bool
+	EnableMemVal    bool
+	TkCloseCacheLen int32
 }
 
 type memNode struct {
@@ -224,14 +230,6 @@ func (t *Tree) Save() []byte {
 		if err != nil {
 			return nil
 		}
-		// 该线程应只允许一个
-		if t.config != nil && t.config.EnableMavlPrune && !isPruning() &&
-			t.config.PruneHeight != 0 &&
-			t.blockHeight%int64(t.config.PruneHeight) == 0 &&
-			t.blockHeight/int64(t.config.PruneHeight) > 1 {
-			wg.Add(1)
-			go pruning(t.ndb.db, t.blockHeight, t.config)
-		}
 	}
 	return t.root.hash
 }
@@ -253,6 +251,11 @@ func (t *Tree) SetBlockHeight(height int64) {
 	t.blockHeight = height
 }
 
+// BlockHeight 获取tree对应的block高度
+func (t *Tree) BlockHeight() int64 {
+	return t.blockHeight
+}
+
 // Get 通过key获取leaf节点信息
 func (t *Tree) Get(key []byte) (index int32, value []byte, exists bool) {
 	if t.root == nil {
@@ -855,6 +858,25 @@ func genRootHashHeight(blockHeight int64, hash []byte) (key []byte) {
 	return key
 }
 
+func genRootHashIndex(hash []byte) (key []byte) {
+	key = append([]byte(rootHashIndexPrefix), hash...)
+	return key
+}
+
+// GetRootHashHeight 获取状态根对应的区块高度, 只有开启裁剪之后保存的状态根才有记录
+func GetRootHashHeight(db dbm.DB, hash []byte) (int64, error) {
+	value, err := db.Get(genRootHashIndex(hash))
+	if err != nil {
+		return 0, err
+	}
+	h := &types.Int64{}
+	err = proto.Unmarshal(value, h)
+	if err != nil {
+		return 0, err
+	}
+	return h.Data, nil
+}
+
 func getRootHash(hashKey []byte) (hash []byte, err error) {
 	if len(hashKey) < len(rootHashHeightPrefix)+blockHeightStrLen+sha256Len {
 		return nil, types.ErrSize
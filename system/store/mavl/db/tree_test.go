@@ -20,6 +20,7 @@ import (
 	. "github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/db"
 	"github.com/33cn/chain33/common/log"
+	"github.com/33cn/chain33/common/trace"
 	"github.com/33cn/chain33/types"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
@@ -786,49 +787,224 @@ func TestIAVLPrint(t *testing.T) {
 
 func TestPruningTree(t *testing.T) {
 	const txN = 5    // 每个块交易量
-	const preB = 500 // 一轮区块数
-	const round = 5  // 更新叶子节点次数
-	const preDel = preB / 10
+	const preB = 100 // 一轮区块数
+	const round = 4  // 更新叶子节点次数
 	dir, err := ioutil.TempDir("", "datastore")
 	require.NoError(t, err)
-	t.Log(dir)
+	defer os.RemoveAll(dir)
 	db := db.NewDB("test", "leveldb", dir, 100)
-	prevHash := make([]byte, 32)
+	defer db.Close()
 
 	treeCfg := &TreeConfig{
-		EnableMavlPrefix: true,
-		EnableMavlPrune:  true,
-		PruneHeight:      preDel,
+		EnableMavlPrefix:     true,
+		EnableMavlPrune:      true,
+		PruneHeight:          50,
+		PruneArchiveInterval: 150,
+		PruneBatchSize:       20,
 	}
-
+	pruner := NewPruner(db, treeCfg)
+	prevHash := make([]byte, 32)
+	var roots [][]byte
 	for j := 0; j < round; j++ {
 		for i := 0; i < preB; i++ {
-			setPruning(pruningStateStart)
-			prevHash, err = saveUpdateBlock(db, int64(i), prevHash, txN, j, int64(j*preB+i), treeCfg)
-			assert.Nil(t, err)
 			m := int64(j*preB + i)
-			if m/int64(preDel) > 1 && m%int64(preDel) == 0 {
-				pruningTree(db, m, treeCfg)
+			prevHash, err = saveUpdateBlock(db, int64(i), prevHash, txN, j, m, treeCfg)
+			assert.Nil(t, err)
+			roots = append(roots, prevHash)
+			pruner.Prune(m)
+		}
+	}
+	cur := int64(round*preB - 1)
+	status := pruner.Status()
+	assert.True(t, status.Enable)
+	assert.Equal(t, cur, status.CurHeight)
+	assert.True(t, status.PrunedHeight > 0)
+	assert.True(t, status.ReclaimedNodes > 0)
+
+	//裁剪进度持久化, 重启之后继续
+	status2 := NewPruner(db, treeCfg).Status()
+	assert.Equal(t, status, status2)
+
+	PruningTree(db, cur, treeCfg)
+	status = NewPruner(db, treeCfg).Status()
+	assert.Equal(t, cur-int64(treeCfg.PruneHeight), status.PrunedHeight)
+	assert.Equal(t, int64(0), status.PruningHeight)
+	assert.Equal(t, int64(0), status.Backlog)
+
+	//保留窗口内的状态以及归档状态都是完整的
+	pruner = NewPruner(db, treeCfg)
+	for h := int64(0); h <= cur; h++ {
+		if h < status.PrunedHeight && h%treeCfg.PruneArchiveInterval != 0 {
+			assert.Equal(t, types.ErrStatePruned, pruner.CheckState(roots[h]), "height %d", h)
+			continue
+		}
+		assert.Nil(t, pruner.CheckState(roots[h]), "height %d", h)
+		te := NewTree(db, true, treeCfg)
+		require.Nil(t, te.Load(roots[h]))
+		for i := 0; i < preB; i++ {
+			for n := int64(i * txN); n < int64((i+1)*txN); n++ {
+				key := []byte(fmt.Sprintf("my_%018d", n))
+				_, v, exist := te.Get(key)
+				if int64(i) > h {
+					assert.False(t, exist)
+					continue
+				}
+				vIndex := (h - int64(i)) / preB
+				assert.Equal(t, []byte(fmt.Sprintf("my_%018d_%d", n, vIndex)), v, "height %d key %s", h, key)
 			}
 		}
-		fmt.Printf("round %d over \n", j)
 	}
-	te := NewTree(db, true, treeCfg)
-	err = te.Load(prevHash)
-	if err == nil {
-		fmt.Printf("te.Load \n")
-		for i := 0; i < preB*txN; i++ {
-			key := []byte(fmt.Sprintf("my_%018d", i))
-			vIndex := round - 1
-			value := []byte(fmt.Sprintf("my_%018d_%d", i, vIndex))
-			_, v, exist := te.Get(key)
-			assert.Equal(t, exist, true)
-			assert.Equal(t, value, v)
+	//没有记录高度的状态根以及没有开启裁剪时不做检查
+	assert.Nil(t, pruner.CheckState(make([]byte, 32)))
+	assert.Nil(t, NewPruner(db, &TreeConfig{EnableMavlPrefix: true}).CheckState(roots[1]))
+	height, err := GetRootHashHeight(db, roots[1])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), height)
+}
+
+func TestPruneInterleavedKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := db.NewDB("test", "leveldb", dir, 100)
+	defer db.Close()
+
+	//a 和 a0 的索引在同一个前缀下交错排列: a@5, a0@60, a@70, a@5 的下一个版本是70而不是扫描到的下一条索引
+	treeCfg := &TreeConfig{EnableMavlPrefix: true, EnableMavlPrune: true, PruneHeight: 10, PruneBatchSize: 3}
+	versions := map[int64]string{5: "a", 60: "a0", 70: "a"}
+	pruner := NewPruner(db, treeCfg)
+	prevHash := make([]byte, 32)
+	var roots [][]byte
+	for i := int64(0); i <= 75; i++ {
+		tree := NewTree(db, true, treeCfg)
+		require.Nil(t, tree.Load(prevHash))
+		tree.SetBlockHeight(i)
+		tree.Set([]byte("b"), []byte(fmt.Sprintf("b-%d", i)))
+		if key, ok := versions[i]; ok {
+			tree.Set([]byte(key), []byte(fmt.Sprintf("%s-%d", key, i)))
+		}
+		prevHash = tree.Save()
+		roots = append(roots, prevHash)
+		pruner.Prune(i)
+	}
+	PruningTree(db, 75, treeCfg)
+	status := NewPruner(db, treeCfg).Status()
+	assert.Equal(t, int64(65), status.PrunedHeight)
+	assert.True(t, status.ReclaimedNodes > 0)
+	for h := status.PrunedHeight; h <= 75; h++ {
+		tree := NewTree(db, true, treeCfg)
+		require.Nil(t, tree.Load(roots[h]))
+		_, v, exist := tree.Get([]byte("a"))
+		assert.True(t, exist, "height %d", h)
+		if h < 70 {
+			assert.Equal(t, "a-5", string(v))
+		} else {
+			assert.Equal(t, "a-70", string(v))
 		}
+		_, v, _ = tree.Get([]byte("a0"))
+		assert.Equal(t, "a0-60", string(v))
 	}
-	PruningTreePrintDB(db, []byte(leafKeyCountPrefix))
-	PruningTreePrintDB(db, []byte(hashNodePrefix))
-	PruningTreePrintDB(db, []byte(leafNodePrefix))
+}
+
+type spanExporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (e *spanExporter) ExportSpans(spans []*trace.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *spanExporter) Shutdown() error {
+	return nil
+}
+
+func TestPruneSpan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := db.NewDB("test", "leveldb", dir, 100)
+	defer db.Close()
+
+	treeCfg := &TreeConfig{EnableMavlPrefix: true, EnableMavlPrune: true, PruneHeight: 2}
+	pruner := NewPruner(db, treeCfg)
+	prevHash := make([]byte, 32)
+	for i := int64(0); i < 4; i++ {
+		prevHash, err = saveUpdateBlock(db, 0, prevHash, 5, int(i), i, treeCfg)
+		require.Nil(t, err)
+	}
+	exporter := &spanExporter{}
+	trace.SetExporter(exporter, 1)
+	pruner.Prune(3)
+	pruner.SetPause(true)
+	//暂停时不产生span
+	pruner.Prune(4)
+	trace.Close()
+
+	require.Equal(t, 1, len(exporter.spans))
+	span := exporter.spans[0]
+	assert.Equal(t, "mavl.pruning", span.Name)
+	assert.Equal(t, trace.SpanID{}, span.Parent)
+	assert.Equal(t, []trace.Attribute{
+		{Key: "height", Value: int64(3)},
+		{Key: "done", Value: true},
+		{Key: "reclaimedNodes", Value: pruner.Status().ReclaimedNodes},
+	}, span.Attributes)
+}
+
+//BenchmarkPrune 裁剪在提交区块时同步执行, 对比提交一个区块的耗时和裁剪一批索引的耗时.
+//fullbatch 每次都从头扫描 DefaultPruneBatchSize 条索引, 每条索引在两个索引前缀下各打开一个迭代器查找下一个版本,
+//所有高度都是归档高度, 不删除节点, 每次扫描的都是满批次
+func BenchmarkPrune(b *testing.B) {
+	const txN = 1000
+	newDB := func(b *testing.B) (db.DB, func()) {
+		dir, err := ioutil.TempDir("", "datastore")
+		require.NoError(b, err)
+		ldb := db.NewDB("test", "leveldb", dir, 100)
+		return ldb, func() {
+			ldb.Close()
+			os.RemoveAll(dir)
+		}
+	}
+	commit := func(b *testing.B, prune bool) {
+		ldb, closer := newDB(b)
+		defer closer()
+		treeCfg := &TreeConfig{EnableMavlPrefix: true, EnableMavlPrune: true, PruneHeight: 10}
+		pruner := NewPruner(ldb, treeCfg)
+		prevHash := make([]byte, 32)
+		var err error
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			prevHash, err = saveUpdateBlock(ldb, 0, prevHash, txN, i, int64(i), treeCfg)
+			require.Nil(b, err)
+			if prune {
+				pruner.Prune(int64(i))
+			}
+		}
+	}
+	b.Run("commit", func(b *testing.B) { commit(b, false) })
+	b.Run("commit+prune", func(b *testing.B) { commit(b, true) })
+	b.Run("fullbatch", func(b *testing.B) {
+		ldb, closer := newDB(b)
+		defer closer()
+		treeCfg := &TreeConfig{EnableMavlPrefix: true, EnableMavlPrune: true, PruneHeight: 1, PruneArchiveInterval: 1}
+		prevHash := make([]byte, 32)
+		var err error
+		blocks := 2 * DefaultPruneBatchSize / txN
+		for i := 0; i < blocks; i++ {
+			prevHash, err = saveUpdateBlock(ldb, 0, prevHash, txN, i, int64(i), treeCfg)
+			require.Nil(b, err)
+		}
+		pruner := NewPruner(ldb, treeCfg)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			pruner.progress = &types.StorePruneProgress{}
+			assert.False(b, pruner.step(int64(blocks), DefaultPruneBatchSize))
+		}
+	})
 }
 
 func genUpdateKV(height int64, txN int64, vIndex int) (kvs []*types.KeyValue) {
@@ -1344,78 +1520,18 @@ func TestGetObsoleteNode(t *testing.T) {
 	}
 }
 
-func TestPruningFirstLevelNode(t *testing.T) {
-	dir, err := ioutil.TempDir("", "datastore")
-	require.NoError(t, err)
-	t.Log(dir)
-	defer os.RemoveAll(dir)
-
-	db1 := db.NewDB("mavltree", "leveldb", dir, 100)
-	//add node data
-	nodes1 := []Node{
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44d"), height: 1},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44a"), height: 5000},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44b"), height: 10000},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44c"), height: 30000},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44e"), height: 40000},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44f"), height: 450000},
-	}
-
-	nodes2 := []Node{
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45d"), height: 1},
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45a"), height: 5000},
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45b"), height: 10000},
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45c"), height: 30000},
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45e"), height: 40000},
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45f"), height: 450000},
-	}
-
-	hashNodes1 := []types.PruneData{
-		{Hashs: [][]byte{[]byte("113"), []byte("114"), []byte("115"), []byte("116"), []byte("117"), []byte("118")}},
-		{Hashs: [][]byte{[]byte("123"), []byte("124"), []byte("125"), []byte("126"), []byte("127"), []byte("128")}},
-		{Hashs: [][]byte{[]byte("133"), []byte("134"), []byte("135"), []byte("136"), []byte("137"), []byte("138")}},
-		{Hashs: [][]byte{[]byte("143"), []byte("144"), []byte("145"), []byte("146"), []byte("147"), []byte("148")}},
-		{Hashs: [][]byte{[]byte("153"), []byte("154"), []byte("155"), []byte("156"), []byte("157"), []byte("158")}},
-		{Hashs: [][]byte{[]byte("163"), []byte("164"), []byte("165"), []byte("166"), []byte("167"), []byte("168")}},
-	}
-
-	hashNodes2 := []types.PruneData{
-		{Hashs: [][]byte{[]byte("213"), []byte("214"), []byte("215"), []byte("216"), []byte("217"), []byte("218")}},
-		{Hashs: [][]byte{[]byte("223"), []byte("224"), []byte("225"), []byte("226"), []byte("227"), []byte("228")}},
-		{Hashs: [][]byte{[]byte("233"), []byte("234"), []byte("235"), []byte("236"), []byte("237"), []byte("238")}},
-		{Hashs: [][]byte{[]byte("243"), []byte("244"), []byte("245"), []byte("246"), []byte("247"), []byte("248")}},
-		{Hashs: [][]byte{[]byte("253"), []byte("254"), []byte("255"), []byte("256"), []byte("257"), []byte("258")}},
-		{Hashs: [][]byte{[]byte("263"), []byte("264"), []byte("265"), []byte("266"), []byte("267"), []byte("268")}},
-	}
-
-	batch := db1.NewBatch(true)
-	for i, node := range nodes1 {
+func saveLeafCountNodes(t *testing.T, dbm db.DB, nodes []Node, old bool) {
+	batch := dbm.NewBatch(true)
+	for _, node := range nodes {
 		k := genLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash))
-		data := &types.PruneData{
-			Hashs: hashNodes1[i].Hashs,
-		}
-		v, err := proto.Marshal(data)
-		if err != nil {
-			panic(err)
+		if old {
+			k = genOldLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash))
 		}
-		// 保存索引节点
-		batch.Set(k, v)
-		// 保存叶子节点
-		batch.Set(node.hash, node.key)
-		// 保存hash节点
-		for _, hash := range data.Hashs {
-			batch.Set(hash, hash)
-		}
-	}
-	for i, node := range nodes2 {
-		k := genLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash))
 		data := &types.PruneData{
-			Hashs: hashNodes2[i].Hashs,
+			Hashs: [][]byte{append([]byte("p1-"), node.hash...), append([]byte("p2-"), node.hash...)},
 		}
 		v, err := proto.Marshal(data)
-		if err != nil {
-			panic(err)
-		}
+		require.Nil(t, err)
 		// 保存索引节点
 		batch.Set(k, v)
 		// 保存叶子节点
@@ -1425,99 +1541,107 @@ func TestPruningFirstLevelNode(t *testing.T) {
 			batch.Set(hash, hash)
 		}
 	}
-	batch.Write()
-	db1.Close()
-
-	db2 := db.NewDB("mavltree", "leveldb", dir, 100)
-
-	var existHashs [][]byte
-	var noExistHashs [][]byte
-
-	treeCfg := &TreeConfig{
-		PruneHeight: 5000,
-	}
-	//当前高度设置为10000,只能删除高度为1的节点
-	pruningFirstLevel(db2, 10000, treeCfg)
+	require.Nil(t, batch.Write())
+}
 
-	for i, node := range nodes1 {
-		if i >= 1 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
+func verifyPruned(t *testing.T, dbm db.DB, nodes []Node, pruned []bool) {
+	var existHashs, noExistHashs [][]byte
+	for i, node := range nodes {
+		hashs := [][]byte{node.hash, append([]byte("p1-"), node.hash...), append([]byte("p2-"), node.hash...)}
+		if pruned[i] {
+			noExistHashs = append(noExistHashs, hashs...)
 		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
+			existHashs = append(existHashs, hashs...)
 		}
 	}
-	for i, node := range nodes2 {
-		if i >= 1 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
-		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
-		}
-	}
-	verifyNodeExist(t, db2, existHashs, noExistHashs)
+	verifyNodeExist(t, dbm, existHashs, noExistHashs)
+}
 
-	//当前高度设置为20000, 删除高度为1000的
-	pruningFirstLevel(db2, 20000, treeCfg)
+func TestPruner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dbm := db.NewDB("mavltree", "leveldb", dir, 100)
+	defer dbm.Close()
 
-	existHashs = existHashs[:0][:0]
-	existHashs = noExistHashs[:0][:0]
-	for i, node := range nodes1 {
-		if i >= 2 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
-		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
-		}
+	hash := func(s string) []byte {
+		return []byte(fmt.Sprintf("%064s", s))
 	}
-	for i, node := range nodes2 {
-		if i >= 2 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
-		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
-		}
+	nodes1 := []Node{
+		{key: []byte("11111111"), hash: hash("11-1"), height: 1},
+		{key: []byte("11111111"), hash: hash("11-5000"), height: 5000},
+		{key: []byte("11111111"), hash: hash("11-10000"), height: 10000},
+		{key: []byte("11111111"), hash: hash("11-30000"), height: 30000},
+		{key: []byte("11111111"), hash: hash("11-40000"), height: 40000},
+		{key: []byte("11111111"), hash: hash("11-450000"), height: 450000},
+	}
+	//以 11111111 为前缀的其他key的索引会落在 11111111 的扫描区间内
+	nodes2 := []Node{
+		{key: []byte("111111110"), hash: hash("110-2"), height: 2},
 	}
-	verifyNodeExist(t, db2, existHashs, noExistHashs)
-
-	//目前还剩下 10000 30000 40000 450000
-	//当前高度设置为510001, 将高度为10000的加入二级节点,删除30000 40000节点
-	pruningFirstLevel(db2, 510001, treeCfg)
-	existHashs = existHashs[:0][:0]
-	existHashs = noExistHashs[:0][:0]
-	for i, node := range nodes1 {
-		if i >= 5 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
-		} else if i == 2 {
-
-		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
-		}
+	//旧版本裁剪转移到二级索引的节点, 下一个版本在一级索引中
+	nodes3 := []Node{
+		{key: []byte("22222222"), hash: hash("22-1"), height: 1},
 	}
-	for i, node := range nodes2 {
-		if i >= 5 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
-		} else if i == 2 {
-
-		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
-		}
+	//相同高度的两个版本是分叉产生的
+	nodes4 := []Node{
+		{key: []byte("22222222"), hash: hash("22-5000"), height: 5000},
+		{key: []byte("33333333"), hash: hash("33-100-a"), height: 100},
+		{key: []byte("33333333"), hash: hash("33-100-b"), height: 100},
 	}
-	verifyNodeExist(t, db2, existHashs, noExistHashs)
+	saveLeafCountNodes(t, dbm, nodes1, false)
+	saveLeafCountNodes(t, dbm, nodes2, false)
+	saveLeafCountNodes(t, dbm, nodes3, true)
+	saveLeafCountNodes(t, dbm, nodes4, false)
 
-	//检查转换成二级裁剪高度的节点
-	var secLevelNodes []*Node
-	secLevelNodes = append(secLevelNodes, &nodes1[2])
-	secLevelNodes = append(secLevelNodes, &nodes2[2])
-	VerifySecLevelCountNodeExist(t, db2, secLevelNodes)
+	treeCfg := &TreeConfig{
+		EnableMavlPrune: true,
+		PruneHeight:     5000,
+		PruneBatchSize:  1,
+	}
+	//保留 [1000, 6000] 的状态, 11111111 在高度1的版本到5000之前一直有效
+	PruningTree(dbm, 6000, treeCfg)
+	verifyPruned(t, dbm, nodes1, []bool{false, false, false, false, false, false})
+	verifyPruned(t, dbm, nodes2, []bool{false})
+	verifyPruned(t, dbm, nodes3, []bool{false})
+
+	//每个区块只扫描一条索引, 暂停的时候不扫描, 重启之后从游标处继续
+	pruner := NewPruner(dbm, treeCfg)
+	pruner.SetPause(true)
+	pruner.Prune(10000)
+	status := pruner.Status()
+	assert.True(t, status.Paused)
+	assert.Equal(t, int64(10000), status.CurHeight)
+	assert.Equal(t, int64(1000), status.PrunedHeight)
+	assert.Equal(t, int64(4000), status.Backlog)
+	pruner.SetPause(false)
+	pruner.Prune(10000)
+	pruner.Prune(10000)
+	status = NewPruner(dbm, treeCfg).Status()
+	assert.Equal(t, int64(5000), status.PruningHeight)
+	assert.Equal(t, int64(2), status.Scanned)
+	for i := 0; i < 20; i++ {
+		NewPruner(dbm, treeCfg).Prune(10000)
+	}
+	status = NewPruner(dbm, treeCfg).Status()
+	assert.Equal(t, int64(5000), status.PrunedHeight)
+	assert.Equal(t, int64(0), status.PruningHeight)
+	assert.Equal(t, int64(6), status.ReclaimedNodes)
+	verifyPruned(t, dbm, nodes1, []bool{true, false, false, false, false, false})
+	verifyPruned(t, dbm, nodes2, []bool{false})
+	verifyPruned(t, dbm, nodes3, []bool{true})
+	verifyPruned(t, dbm, nodes4, []bool{false, false, false})
+
+	PruningTree(dbm, 20000, treeCfg)
+	verifyPruned(t, dbm, nodes1, []bool{true, true, false, false, false, false})
+
+	//每20000个高度保留一个归档状态, [10000, 30000) 包含归档高度20000, [40000, 450000) 包含40000
+	treeCfg.PruneArchiveInterval = 20000
+	PruningTree(dbm, 1000000, treeCfg)
+	verifyPruned(t, dbm, nodes1, []bool{true, true, false, true, false, false})
+	verifyPruned(t, dbm, nodes4, []bool{false, false, false})
+	_, err = dbm.Get(genLeafCountKey(nodes1[3].key, nodes1[3].hash, int64(nodes1[3].height), len(nodes1[3].hash)))
+	assert.Equal(t, db.ErrNotFoundInDb, err)
 }
 
 func verifyNodeExist(t *testing.T, dbm db.DB, existHashs [][]byte, noExistHashs [][]byte) {
@@ -1536,120 +1660,6 @@ func verifyNodeExist(t *testing.T, dbm db.DB, existHashs [][]byte, noExistHashs
 	}
 }
 
-func VerifySecLevelCountNodeExist(t *testing.T, dbm db.DB, nodes []*Node) {
-	for _, node := range nodes {
-		_, err := dbm.Get(genOldLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash)))
-		if err != nil {
-			require.NoError(t, fmt.Errorf("this node should exist key: %s, hash: %s", string(node.key), string(node.hash)))
-		}
-	}
-}
-
-func TestPruningSecondLevelNode(t *testing.T) {
-	dir, err := ioutil.TempDir("", "datastore")
-	require.NoError(t, err)
-	t.Log(dir)
-	defer os.RemoveAll(dir)
-
-	db1 := db.NewDB("mavltree", "leveldb", dir, 100)
-	//add node data
-	nodes1 := []Node{
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44d"), height: 1},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44a"), height: 5000},
-		{key: []byte("11111111"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc44b"), height: 10000},
-	}
-
-	nodes2 := []Node{
-		{key: []byte("22222222"), hash: []byte("d95f1027b1ecf9013a1cf870a85d967ca828e8faca366a290ec43adcecfbc45d"), height: 1},
-	}
-
-	hashNodes1 := []types.PruneData{
-		{Hashs: [][]byte{[]byte("113"), []byte("114"), []byte("115"), []byte("116"), []byte("117"), []byte("118")}},
-		{Hashs: [][]byte{[]byte("123"), []byte("124"), []byte("125"), []byte("126"), []byte("127"), []byte("128")}},
-		{Hashs: [][]byte{[]byte("133"), []byte("134"), []byte("135"), []byte("136"), []byte("137"), []byte("138")}},
-	}
-
-	hashNodes2 := []types.PruneData{
-		{Hashs: [][]byte{[]byte("213"), []byte("214"), []byte("215"), []byte("216"), []byte("217"), []byte("218")}},
-	}
-
-	batch := db1.NewBatch(true)
-	for i, node := range nodes1 {
-		k := genOldLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash))
-		data := &types.PruneData{
-			Hashs: hashNodes1[i].Hashs,
-		}
-		v, err := proto.Marshal(data)
-		if err != nil {
-			panic(err)
-		}
-		// 保存索引节点
-		batch.Set(k, v)
-		// 保存叶子节点
-		batch.Set(node.hash, node.key)
-		// 保存hash节点
-		for _, hash := range data.Hashs {
-			batch.Set(hash, hash)
-		}
-	}
-	for i, node := range nodes2 {
-		k := genOldLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash))
-		data := &types.PruneData{
-			Hashs: hashNodes2[i].Hashs,
-		}
-		v, err := proto.Marshal(data)
-		if err != nil {
-			panic(err)
-		}
-		// 保存索引节点
-		batch.Set(k, v)
-		// 保存叶子节点
-		batch.Set(node.hash, node.key)
-		// 保存hash节点
-		for _, hash := range data.Hashs {
-			batch.Set(hash, hash)
-		}
-	}
-	batch.Write()
-	db1.Close()
-
-	db2 := db.NewDB("mavltree", "leveldb", dir, 100)
-
-	var existHashs [][]byte
-	var noExistHashs [][]byte
-
-	//当前高度设置为1500010,只能删除高度为1的节点
-	treeCfg := &TreeConfig{
-		PruneHeight: 5000,
-	}
-	pruningSecondLevel(db2, 1500010, treeCfg)
-
-	for i, node := range nodes1 {
-		if i >= 2 {
-			existHashs = append(existHashs, node.hash)
-			existHashs = append(existHashs, hashNodes1[i].Hashs...)
-		} else {
-			noExistHashs = append(noExistHashs, node.hash)
-			noExistHashs = append(noExistHashs, hashNodes1[i].Hashs...)
-		}
-	}
-	verifyNodeExist(t, db2, existHashs, noExistHashs)
-
-	//检查转换成二级裁剪高度的节点
-	var secLevelNodes []*Node
-	secLevelNodes = append(secLevelNodes, &nodes2[0])
-	VerifyThreeLevelCountNodeExist(t, db2, secLevelNodes)
-}
-
-func VerifyThreeLevelCountNodeExist(t *testing.T, dbm db.DB, nodes []*Node) {
-	for _, node := range nodes {
-		v, err := dbm.Get(genOldLeafCountKey(node.key, node.hash, int64(node.height), len(node.hash)))
-		if err == nil || len(v) > 0 {
-			require.NoError(t, fmt.Errorf("this node should not exist key:%s hash:%s", string(node.key), string(node.hash)))
-		}
-	}
-}
-
 func TestGetHashNode(t *testing.T) {
 	eHashs := [][]byte{
 		[]byte("h44"),
@@ -2049,3 +2059,115 @@ func TestSize1(t *testing.T) {
 	PrintMemStats(1)
 	fmt.Println(unsafe.Sizeof(a), unsafe.Sizeof(b), unsafe.Sizeof(c), unsafe.Sizeof(d), len(d.Key), cap(d.Key))
 }
+
+func TestVerifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := db.NewDB("test", "leveldb", dir, 100)
+	defer db.Close()
+
+	treeCfg := &TreeConfig{EnableMavlPrefix: true, EnableMavlPrune: true}
+	prevHash := make([]byte, 32)
+	var roots [][]byte
+	for i := 0; i < 20; i++ {
+		prevHash, err = saveUpdateBlock(db, int64(i%10), prevHash, 5, i/10, int64(i), treeCfg)
+		require.NoError(t, err)
+		roots = append(roots, prevHash)
+	}
+	v := NewVerifier(db, treeCfg)
+	for h, root := range roots {
+		ok, err := v.VerifyRoot(int64(h), root)
+		require.NoError(t, err)
+		assert.True(t, ok, "height %d", h)
+	}
+	assert.Equal(t, int64(100), v.Leafs)
+	count, err := v.VerifyLeafIndex(0, 19)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), count)
+	assert.Equal(t, 0, len(v.Problems))
+
+	//损坏最新状态树的节点
+	tree := NewTree(db, true, treeCfg)
+	require.NoError(t, tree.Load(roots[19]))
+	leftHash := tree.root.leftHash
+	left := tree.root.getLeftNode(tree)
+	db.Delete(left.rightHash)
+	value, err := db.Get(left.leftHash)
+	require.NoError(t, err)
+	value[len(value)-1]++
+	db.Set(left.leftHash, value)
+
+	//删除一个叶子节点索引, 新增一个指向不存在的叶子节点的索引
+	_, leafHash, exist := tree.GetHash([]byte(fmt.Sprintf("my_%018d", 49)))
+	require.True(t, exist)
+	db.Delete(genLeafCountKey([]byte(fmt.Sprintf("my_%018d", 49)), leafHash, 19, len(leafHash)))
+	missLeaf := append(genPrefixHashKey(&Node{}, 18), make([]byte, 32)...)
+	db.Set(genLeafCountKey([]byte("nokey"), missLeaf, 18, len(missLeaf)), nil)
+
+	v = NewVerifier(db, treeCfg)
+	ok, err := v.VerifyRoot(19, roots[19])
+	require.NoError(t, err)
+	assert.False(t, ok)
+	kinds := make(map[string]int)
+	for _, p := range v.Problems {
+		kinds[p.Kind]++
+		if p.Kind != VerifyNoIndex {
+			assert.Equal(t, leftHash, p.Parent, p.String())
+		}
+	}
+	assert.Equal(t, map[string]int{VerifyMissing: 1, VerifyBadHash: 1, VerifyNoIndex: 1}, kinds)
+	//旧的状态不受影响
+	ok, err = v.VerifyRoot(0, roots[0])
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	v = NewVerifier(db, treeCfg)
+	_, err = v.VerifyLeafIndex(0, 19)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(v.Problems))
+	assert.Equal(t, VerifyBadIndex, v.Problems[0].Kind)
+	assert.Equal(t, missLeaf, v.Problems[0].Key)
+}
+
+func TestVerifierSharedBrokenNode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := db.NewDB("test", "leveldb", dir, 100)
+	defer db.Close()
+
+	treeCfg := &TreeConfig{EnableMavlPrefix: true}
+	prevHash := make([]byte, 32)
+	var roots [][]byte
+	for i := 0; i < 12; i++ {
+		prevHash, err = saveUpdateBlock(db, int64(i%10), prevHash, 5, i/10, int64(i), treeCfg)
+		require.NoError(t, err)
+		roots = append(roots, prevHash)
+	}
+
+	//删除两棵状态树共享的叶子节点
+	old := NewTree(db, true, treeCfg)
+	require.NoError(t, old.Load(roots[10]))
+	tree := NewTree(db, true, treeCfg)
+	require.NoError(t, tree.Load(roots[11]))
+	key := []byte(fmt.Sprintf("my_%018d", 49))
+	_, leafHash, exist := tree.GetHash(key)
+	require.True(t, exist)
+	_, oldHash, exist := old.GetHash(key)
+	require.True(t, exist)
+	require.Equal(t, oldHash, leafHash)
+	db.Delete(leafHash)
+
+	v := NewVerifier(db, treeCfg)
+	ok, err := v.VerifyRoot(11, roots[11])
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.Equal(t, 1, len(v.Problems))
+	assert.Equal(t, VerifyMissing, v.Problems[0].Kind)
+	//共享损坏节点的状态树同样校验失败, 问题只报告一次
+	ok, err = v.VerifyRoot(10, roots[10])
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, len(v.Problems))
+}
@@ -0,0 +1,285 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mavl
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
+)
+
+// 校验发现的问题类型
+const (
+	VerifyMissing   = "missing"   // 节点不存在
+	VerifyCorrupt   = "corrupt"   // 节点数据无法解析或者高度,大小不一致
+	VerifyBadHash   = "badhash"   // 节点内容和hash不一致
+	VerifyNoIndex   = "noindex"   // 开启裁剪时叶子节点没有索引
+	VerifyBadIndex  = "badindex"  // 叶子节点索引指向的节点不存在或者不一致
+	maxVerifyCached = 1000 * 1000 // 缓存已经校验过的节点数目, 超过之后清空
+)
+
+// VerifyProblem 校验发现的问题
+type VerifyProblem struct {
+	Kind   string
+	Height int64  // 发现问题时校验的区块高度, 索引问题为索引记录的高度
+	Key    []byte // 问题节点在数据库中的key
+	Parent []byte // 引用该节点的父节点
+	Reason string
+}
+
+func (p *VerifyProblem) String() string {
+	s := fmt.Sprintf("%s height:%d prefix:%q hash:%s", p.Kind, p.Height, nodeKeyPrefix(p.Key), common.ToHex(nodeKeyHash(p.Key)))
+	if p.Parent != nil {
+		s += " parent:" + common.ToHex(p.Parent)
+	}
+	if p.Reason != "" {
+		s += " " + p.Reason
+	}
+	return s
+}
+
+type verifySummary struct {
+	height int32
+	size   int32
+}
+
+//verifyBroken 缓存中标记子树已经损坏, 之后引用该子树的状态树都校验失败
+var verifyBroken = &verifySummary{height: -1, size: -1}
+
+// Verifier 离线校验状态树, 检查每个节点的hash以及左右子节点的引用, 只读取数据库不做修改
+// 相邻高度的状态树共享大部分节点, 校验过的节点会缓存下来不再重复校验
+type Verifier struct {
+	db       dbm.DB
+	cfg      *TreeConfig
+	checked  map[string]*verifySummary
+	height   int64
+	broken   bool
+	Nodes    int64
+	Leafs    int64
+	Problems []*VerifyProblem
+}
+
+// NewVerifier 新建校验
+func NewVerifier(db dbm.DB, treeCfg *TreeConfig) *Verifier {
+	if treeCfg == nil {
+		treeCfg = &TreeConfig{}
+	}
+	return &Verifier{db: db, cfg: treeCfg, checked: make(map[string]*verifySummary)}
+}
+
+// VerifyRoot 校验height高度的状态树, 返回这棵树是否完整, 数据库读取失败时返回错误
+func (v *Verifier) VerifyRoot(height int64, root []byte) (bool, error) {
+	if len(root) == 0 || bytes.Equal(root, emptyRoot[:]) {
+		return true, nil
+	}
+	v.height = height
+	v.broken = false
+	n := len(v.Problems)
+	_, err := v.walk(root, nil)
+	if err != nil {
+		return false, err
+	}
+	return !v.broken && len(v.Problems) == n, nil
+}
+
+func (v *Verifier) report(kind string, key, parent []byte, reason string) {
+	v.Problems = append(v.Problems, &VerifyProblem{Kind: kind, Height: v.height, Key: key, Parent: parent, Reason: reason})
+}
+
+// walk 校验节点以及子树, 节点缺失或者损坏时返回nil
+// 损坏的子树只在第一次校验时报告问题, 之后其他状态树引用到时也要标记为损坏
+func (v *Verifier) walk(key, parent []byte) (*verifySummary, error) {
+	if s, ok := v.checked[string(key)]; ok {
+		if s == verifyBroken {
+			v.broken = true
+			return nil, nil
+		}
+		return s, nil
+	}
+	s, err := v.verifyNode(key, parent)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.checked) >= maxVerifyCached {
+		v.checked = make(map[string]*verifySummary)
+	}
+	if s == nil {
+		v.broken = true
+		v.checked[string(key)] = verifyBroken
+		return nil, nil
+	}
+	v.checked[string(key)] = s
+	return s, nil
+}
+
+func (v *Verifier) verifyNode(key, parent []byte) (*verifySummary, error) {
+	buf, err := v.db.Get(key)
+	if err == dbm.ErrNotFoundInDb || (err == nil && len(buf) == 0) {
+		v.report(VerifyMissing, key, parent, "")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sn types.StoreNode
+	err = proto.Unmarshal(buf, &sn)
+	if err != nil {
+		v.report(VerifyCorrupt, key, parent, err.Error())
+		return nil, nil
+	}
+	v.Nodes++
+	s := &verifySummary{height: sn.Height, size: sn.Size}
+	if sn.Height == 0 {
+		v.Leafs++
+		if sn.Size != 1 {
+			v.report(VerifyCorrupt, key, parent, fmt.Sprintf("leaf size %d", sn.Size))
+			return nil, nil
+		}
+		//开启mvcc时叶子节点不保存value, 无法计算hash
+		if !v.cfg.EnableMVCC || sn.Value != nil {
+			leaf := &types.LeafNode{Key: sn.Key, Value: sn.Value, Height: sn.Height, Size: sn.Size}
+			if !bytes.Equal(leaf.Hash(), nodeKeyHash(key)) {
+				v.report(VerifyBadHash, key, parent, fmt.Sprintf("leaf key %q", sn.Key))
+				return nil, nil
+			}
+		}
+		v.checkLeafIndex(key, sn.Key)
+		return s, nil
+	}
+	if sn.LeftHash == nil || sn.RightHash == nil {
+		v.report(VerifyCorrupt, key, parent, "child hash is nil")
+		return nil, nil
+	}
+	inner := &types.InnerNode{Height: sn.Height, Size: sn.Size, LeftHash: sn.LeftHash, RightHash: sn.RightHash}
+	if !bytes.Equal(inner.Hash(), nodeKeyHash(key)) {
+		v.report(VerifyBadHash, key, parent, "")
+		return nil, nil
+	}
+	left, err := v.walk(sn.LeftHash, key)
+	if err != nil {
+		return nil, err
+	}
+	right, err := v.walk(sn.RightHash, key)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	childHeight := left.height
+	if right.height > childHeight {
+		childHeight = right.height
+	}
+	if sn.Height != childHeight+1 || sn.Size != left.size+right.size {
+		v.report(VerifyCorrupt, key, parent, fmt.Sprintf("height %d size %d, children height %d size %d",
+			sn.Height, sn.Size, childHeight, left.size+right.size))
+		return nil, nil
+	}
+	return s, nil
+}
+
+// checkLeafIndex 开启裁剪时, 带高度前缀的叶子节点都需要有索引, 否则不会被裁剪
+func (v *Verifier) checkLeafIndex(hash, key []byte) {
+	if !v.cfg.EnableMavlPrune {
+		return
+	}
+	height, ok := leafNodeHeight(hash)
+	if !ok {
+		return
+	}
+	for _, k := range [][]byte{genLeafCountKey(key, hash, height, len(hash)), genOldLeafCountKey(key, hash, height, len(hash))} {
+		value, err := v.db.Get(k)
+		if err == nil && len(value) > 0 {
+			return
+		}
+	}
+	v.report(VerifyNoIndex, hash, nil, fmt.Sprintf("leaf key %q", key))
+}
+
+// VerifyLeafIndex 校验高度在[start, end]之间的叶子节点索引, 索引指向的叶子节点必须存在且key一致
+// 索引中记录的父节点可能已经随着其他叶子节点一起被裁剪, 不做检查
+func (v *Verifier) VerifyLeafIndex(start, end int64) (int64, error) {
+	var count int64
+	for _, prefix := range pruneIndexPrefixes {
+		it := v.db.Iterator([]byte(prefix), nil, false)
+		for it.Rewind(); it.Valid(); it.Next() {
+			key, h, hash, err := getKeyHeightFromIndexKey(prefix, it.Key())
+			height := int64(h)
+			if err != nil {
+				v.Problems = append(v.Problems, &VerifyProblem{Kind: VerifyBadIndex, Key: copyBytes(it.Key()), Reason: "bad index key"})
+				continue
+			}
+			if height < start || height > end {
+				continue
+			}
+			count++
+			var pData types.PruneData
+			if err := proto.Unmarshal(it.Value(), &pData); err != nil {
+				v.addIndexProblem(height, hash, "bad index value: "+err.Error())
+				continue
+			}
+			if leafHeight, ok := leafNodeHeight(hash); ok && leafHeight != height {
+				v.addIndexProblem(height, hash, fmt.Sprintf("leaf height %d", leafHeight))
+				continue
+			}
+			buf, err := v.db.Get(hash)
+			if err == dbm.ErrNotFoundInDb || (err == nil && len(buf) == 0) {
+				v.addIndexProblem(height, hash, fmt.Sprintf("leaf not exist, key %q", key))
+				continue
+			}
+			if err != nil {
+				it.Close()
+				return count, err
+			}
+			var sn types.StoreNode
+			if err := proto.Unmarshal(buf, &sn); err != nil || sn.Height != 0 || !bytes.Equal(sn.Key, key) {
+				v.addIndexProblem(height, hash, fmt.Sprintf("leaf mismatch, key %q", key))
+			}
+		}
+		err := it.Error()
+		it.Close()
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (v *Verifier) addIndexProblem(height int64, hash []byte, reason string) {
+	v.Problems = append(v.Problems, &VerifyProblem{Kind: VerifyBadIndex, Height: height, Key: copyBytes(hash), Reason: reason})
+}
+
+// nodeKeyPrefix 节点key中hash之前的前缀, 开启前缀时包含节点类型以及生成节点的区块高度
+func nodeKeyPrefix(key []byte) string {
+	if len(key) <= sha256Len {
+		return ""
+	}
+	return string(key[:len(key)-sha256Len])
+}
+
+func nodeKeyHash(key []byte) []byte {
+	if len(key) <= sha256Len {
+		return key
+	}
+	return key[len(key)-sha256Len:]
+}
+
+// leafNodeHeight 从带前缀的叶子节点key中解析出生成节点的区块高度
+func leafNodeHeight(key []byte) (int64, bool) {
+	prefix := nodeKeyPrefix(key)
+	if len(prefix) != len(leafNodePrefix)+blockHeightStrLen+2 || prefix[:len(leafNodePrefix)] != leafNodePrefix {
+		return 0, false
+	}
+	height, err := strconv.ParseInt(prefix[len(leafNodePrefix)+1:len(prefix)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return height, true
+}
@@ -34,6 +34,7 @@ type Store struct {
 	*drivers.BaseStore
 	trees   *sync.Map
 	treeCfg *mavl.TreeConfig
+	pruner  *mavl.Pruner
 }
 
 func init() {
@@ -46,8 +47,12 @@ type subConfig struct {
 	EnableMVCC       bool `json:"enableMVCC"`
 	// 是否使能mavl数据裁剪
 	EnableMavlPrune bool `json:"enableMavlPrune"`
-	// 裁剪高度间隔
+	// 保留最近多少个高度的全部状态
 	PruneHeight int32 `json:"pruneHeight"`
+	// 每隔多少个高度永久保留一个状态, 0表示不保留
+	PruneArchiveInterval int64 `json:"pruneArchiveInterval"`
+	// 每个区块最多扫描的叶子节点索引数目
+	PruneBatchSize int32 `json:"pruneBatchSize"`
 	// 是否使能内存树
 	EnableMemTree bool `json:"enableMemTree"`
 	// 是否使能内存树中叶子节点
@@ -68,15 +73,17 @@ func New(cfg *types.Store, sub []byte, chain33cfg *types.Chain33Config) queue.Mo
 		subcfg.EnableMavlPrefix = subcfg.EnableMavlPrune
 	}
 	treeCfg := &mavl.TreeConfig{
-		EnableMavlPrefix: subcfg.EnableMavlPrefix,
-		EnableMVCC:       subcfg.EnableMVCC,
-		EnableMavlPrune:  subcfg.EnableMavlPrune,
-		PruneHeight:      subcfg.PruneHeight,
-		EnableMemTree:    subcfg.EnableMemTree,
-		EnableMemVal:     subcfg.EnableMemVal,
-		TkCloseCacheLen:  subcfg.TkCloseCacheLen,
-	}
-	mavls := &Store{bs, &sync.Map{}, treeCfg}
+		EnableMavlPrefix:     subcfg.EnableMavlPrefix,
+		EnableMVCC:           subcfg.EnableMVCC,
+		EnableMavlPrune:      subcfg.EnableMavlPrune,
+		PruneHeight:          subcfg.PruneHeight,
+		PruneArchiveInterval: subcfg.PruneArchiveInterval,
+		PruneBatchSize:       subcfg.PruneBatchSize,
+		EnableMemTree:        subcfg.EnableMemTree,
+		EnableMemVal:         subcfg.EnableMemVal,
+		TkCloseCacheLen:      subcfg.TkCloseCacheLen,
+	}
+	mavls := &Store{bs, &sync.Map{}, treeCfg, mavl.NewPruner(bs.GetDB(), treeCfg)}
 	mavl.InitGlobalMem(treeCfg)
 	bs.SetChild(mavls)
 	return mavls
@@ -84,14 +91,18 @@ func New(cfg *types.Store, sub []byte, chain33cfg *types.Chain33Config) queue.Mo
 
 // Close close mavl store
 func (mavls *Store) Close() {
-	mavl.ClosePrune()
 	mavls.BaseStore.Close()
 	mlog.Info("store mavl closed")
 }
 
 // Set set k v to mavl store db; sync is true represent write sync
 func (mavls *Store) Set(datas *types.StoreSet, sync bool) ([]byte, error) {
-	return mavl.SetKVPair(mavls.GetDB(), datas, sync, mavls.treeCfg)
+	hash, err := mavl.SetKVPair(mavls.GetDB(), datas, sync, mavls.treeCfg)
+	if err != nil {
+		return nil, err
+	}
+	mavls.pruner.Prune(datas.Height)
+	return hash, nil
 }
 
 // Get get values by keys
@@ -167,6 +178,7 @@ func (mavls *Store) Commit(req *types.ReqHash) ([]byte, error) {
 		return nil, types.ErrDataBaseDamage
 	}
 	mavls.trees.Delete(string(req.Hash))
+	mavls.pruner.Prune(tree.(*mavl.Tree).BlockHeight())
 	return req.Hash, nil
 }
 
@@ -219,12 +231,34 @@ func (mavls *Store) IterateRangeByStateHash(statehash []byte, start []byte, end
 	mavl.IterateRangeByStateHash(mavls.GetDB(), statehash, start, end, ascending, mavls.treeCfg, fn)
 }
 
-// ProcEvent not support message
+// CheckState 检查历史状态是否已经被裁剪, 内存中还没有提交的树总是可以读取
+func (mavls *Store) CheckState(stateHash []byte) error {
+	if _, ok := mavls.trees.Load(string(stateHash)); ok {
+		return nil
+	}
+	return mavls.pruner.CheckState(stateHash)
+}
+
+// ProcEvent 处理状态裁剪的查询和暂停, 其他消息不支持
 func (mavls *Store) ProcEvent(msg *queue.Message) {
 	if msg == nil {
 		return
 	}
-	msg.ReplyErr("Store", types.ErrActionNotSupport)
+	client := mavls.GetQueueClient()
+	switch msg.Ty {
+	case types.EventStorePruneStatus:
+		msg.Reply(client.NewMessage("", types.EventStorePruneStatus, mavls.pruner.Status()))
+	case types.EventStorePrunePause:
+		req, ok := msg.GetData().(*types.ReqStorePrune)
+		if !ok {
+			msg.Reply(client.NewMessage("", types.EventStorePrunePause, types.ErrInvalidParam))
+			return
+		}
+		mavls.pruner.SetPause(req.Pause)
+		msg.Reply(client.NewMessage("", types.EventStorePrunePause, mavls.pruner.Status()))
+	default:
+		msg.ReplyErr("Store", types.ErrActionNotSupport)
+	}
 }
 
 // Del ...
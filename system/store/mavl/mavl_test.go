@@ -14,12 +14,13 @@ import (
 
 	"encoding/json"
 
-	"github.com/33cn/chain33/account"
 	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/queue"
 	drivers "github.com/33cn/chain33/system/store"
+	"github.com/33cn/chain33/system/store/storetest"
 	"github.com/33cn/chain33/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const MaxKeylenth int = 64
@@ -40,594 +41,92 @@ func TestKvdbNewClose(t *testing.T) {
 	store.Close()
 }
 
-func TestKvddbSetGet(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	keys0 := [][]byte{[]byte("mk1"), []byte("mk2")}
-	get0 := &types.StoreGet{StateHash: drivers.EmptyRoot[:], Keys: keys0}
-	values0 := store.Get(get0)
-	mlog.Info("info", "info", values0)
-	// Get exist key, result nil
-	assert.Len(t, values0, 2)
-	assert.Equal(t, []byte(nil), values0[0])
-	assert.Equal(t, []byte(nil), values0[1])
-
-	var kv []*types.KeyValue
-	kv = append(kv, &types.KeyValue{Key: []byte("k1"), Value: []byte("v1")})
-	kv = append(kv, &types.KeyValue{Key: []byte("k2"), Value: []byte("v2")})
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        kv,
-	}
-	hash, err := store.Set(datas, true)
-	assert.Nil(t, err)
-	keys := [][]byte{[]byte("k1"), []byte("k2")}
-	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
-
-	values := store.Get(get1)
-	assert.Len(t, values, 2)
-	assert.Equal(t, []byte("v1"), values[0])
-	assert.Equal(t, []byte("v2"), values[1])
-
-	keys = [][]byte{[]byte("k1")}
-	get2 := &types.StoreGet{StateHash: hash, Keys: keys}
-	values2 := store.Get(get2)
-	assert.Len(t, values2, 1)
-	assert.Equal(t, []byte("v1"), values2[0])
-
-	get3 := &types.StoreGet{StateHash: drivers.EmptyRoot[:], Keys: keys}
-	values3 := store.Get(get3)
-	assert.Len(t, values3, 1)
-	assert.Equal(t, []byte(nil), values3[0])
-}
-
-func TestKvdbMemSet(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	var kv []*types.KeyValue
-	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
-	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        kv,
-	}
-	hash, err := store.MemSet(datas, true)
-	assert.Nil(t, err)
-	keys := [][]byte{[]byte("mk1"), []byte("mk2")}
-	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
-
-	values := store.Get(get1)
-	assert.Len(t, values, 2)
-
-	actHash, _ := store.Commit(&types.ReqHash{Hash: hash})
-	assert.Equal(t, hash, actHash)
-
-	notExistHash, _ := store.Commit(&types.ReqHash{Hash: drivers.EmptyRoot[:]})
-	assert.Nil(t, notExistHash)
-}
-
-func TestKvdbMemSetUpgrade(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	var kv []*types.KeyValue
-	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
-	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        kv,
-	}
-	hash, err := store.MemSetUpgrade(datas, true)
-	assert.Nil(t, err)
-	keys := [][]byte{[]byte("mk1"), []byte("mk2")}
-	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
-
-	values := store.Get(get1)
-	assert.Len(t, values, 2)
-
-	hash1, err := store.CommitUpgrade(&types.ReqHash{Hash: hash})
-	assert.Nil(t, err)
-	assert.Equal(t, hash, hash1)
-}
-
-func TestKvdbRollback(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	var kv []*types.KeyValue
-	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
-	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        kv,
-	}
-	hash, err := store.MemSet(datas, true)
-	assert.Nil(t, err)
-	keys := [][]byte{[]byte("mk1"), []byte("mk2")}
-	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
-	values := store.Get(get1)
-	assert.Len(t, values, 2)
-
-	actHash, _ := store.Rollback(&types.ReqHash{Hash: hash})
-	assert.Equal(t, hash, actHash)
-
-	notExistHash, _ := store.Rollback(&types.ReqHash{Hash: drivers.EmptyRoot[:]})
-	assert.Nil(t, notExistHash)
-}
-
-func TestProcEvent(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	store.ProcEvent(nil)
-	store.ProcEvent(&queue.Message{})
-}
-
-func TestDel(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	store.Del(nil)
-}
-
-var checkKVResult []*types.KeyValue
-
-func checkKV(k, v []byte) bool {
-	checkKVResult = append(checkKVResult,
-		&types.KeyValue{Key: k, Value: v})
-	mlog.Debug("checkKV", "key", string(k), "value", string(v))
-	return false
-}
-func TestKvdbIterate(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	var kv []*types.KeyValue
-	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
-	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        kv,
-	}
-	hash, err := store.Set(datas, true)
-	assert.Nil(t, err)
-	store.IterateRangeByStateHash(hash, []byte("mk1"), []byte("mk3"), true, checkKV)
-	assert.Len(t, checkKVResult, 2)
-	assert.Equal(t, []byte("v1"), checkKVResult[0].Value)
-	assert.Equal(t, []byte("v2"), checkKVResult[1].Value)
-}
-
-type StatTool struct {
-	Amount       int64
-	AmountActive int64
-	AmountFrozen int64
-}
-
-func (t *StatTool) AddItem(value [][]byte) {
-	for i := 0; i < len(value); i++ {
-		var acc types.Account
-		err := types.Decode(value[i], &acc)
-		if err != nil {
-			return
+func TestSubStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (drivers.SubStore, func()) {
+		dir, err := ioutil.TempDir("", "example")
+		require.Nil(t, err)
+		store := New(newStoreCfg(dir), nil, nil).(*Store)
+		return store, func() {
+			store.Close()
+			os.RemoveAll(dir)
 		}
-		t.Amount += acc.Balance
-		t.Amount += acc.Frozen
-
-		t.AmountActive += acc.Balance
-		t.AmountFrozen += acc.Frozen
-	}
-}
-
-func (t *StatTool) Reset() {
-	t.Amount = 0
-	t.AmountActive = 0
-	t.AmountFrozen = 0
+	})
 }
 
-func genPrefixEdge(prefix []byte) (r []byte) {
-	for j := 0; j < len(prefix); j++ {
-		r = append(r, prefix[j])
-	}
-
-	i := len(prefix) - 1
-	for i >= 0 {
-		if r[i] < 0xff {
-			r[i]++
-			break
-		} else {
-			i--
-		}
-	}
-
-	return r
-}
-func TestIterateCallBack_Mode1(t *testing.T) {
+func TestPruneEvent(t *testing.T) {
 	dir, err := ioutil.TempDir("", "example")
 	assert.Nil(t, err)
-	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
 	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var store_cfg = newStoreCfg(dir)
-	store := New(store_cfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-	//mavldb.EnableMavlPrefix(true)
-	//defer mavldb.EnableMavlPrefix(false)
-
-	//var accountdb *account.DB
-	accountdb := account.NewCoinsAccount(cfg)
-	key := "mavl-coins-bty-exec-16htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp:1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
-	prefix := "mavl-coins-bty-exec-"
-	execAddr1 := "16htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"
-	addr := "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
-	var acc = &types.Account{
-		Currency: 0,
-		Balance:  1,
-		Frozen:   1,
-		Addr:     addr,
-	}
-
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        accountdb.GetExecKVSet(execAddr1, acc),
-		Height:    0}
-	hash0, err := store.Set(datas, true)
-	assert.Nil(t, err)
-
-	execAddr2 := "26htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"
-	datas = &types.StoreSet{
-		StateHash: hash0,
-		KV:        accountdb.GetExecKVSet(execAddr2, acc),
-		Height:    1}
-	hash1, err := store.Set(datas, true)
-	assert.Nil(t, err)
-
-	execAddr3 := "36htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"
-	datas = &types.StoreSet{
-		StateHash: hash1,
-		KV:        accountdb.GetExecKVSet(execAddr3, acc),
-		Height:    2}
-	hash2, err := store.Set(datas, true)
-
+	var storeCfg = newStoreCfg(dir)
+	sub := []byte(`{"enableMavlPrune":true,"pruneHeight":2,"pruneBatchSize":10}`)
+	store := New(storeCfg, sub, nil).(*Store)
+	defer store.Close()
+	q := queue.New("channel")
+	store.SetQueueClient(q.Client())
+	client := q.Client()
+
+	hash := drivers.EmptyRoot[:]
+	var roots [][]byte
+	for i := int64(0); i < 10; i++ {
+		kv := []*types.KeyValue{{Key: []byte("k1"), Value: []byte(fmt.Sprintf("v%d", i))}}
+		hash, err = store.Set(&types.StoreSet{StateHash: hash, KV: kv, Height: i}, true)
+		assert.Nil(t, err)
+		roots = append(roots, hash)
+	}
+
+	msg := client.NewMessage("store", types.EventStorePruneStatus, &types.ReqNil{})
+	assert.Nil(t, client.Send(msg, true))
+	resp, err := client.Wait(msg)
 	assert.Nil(t, err)
-
-	fmt.Println("func TestIterateCallBack------test case1-------")
-	req := &types.StoreList{StateHash: hash2, Start: []byte(prefix), Suffix: []byte(addr), End: genPrefixEdge([]byte(prefix)), Count: 5, Mode: 1}
-	query := drivers.NewStoreListQuery(store, req)
-	resp2 := query.Run()
-	tool := &StatTool{}
-	tool.AddItem(resp2.Values)
-	assert.Equal(t, int64(3), resp2.Num)
-	assert.Equal(t, (0), len(resp2.NextKey))
-	assert.Equal(t, (3), len(resp2.Keys))
-	assert.Equal(t, (3), len(resp2.Values))
-	assert.Equal(t, int64(6), tool.Amount)
-	assert.Equal(t, int64(3), tool.AmountActive)
-	assert.Equal(t, int64(3), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case2-------")
-	resp1 := &types.StoreListReply{}
-	resp1.Suffix = []byte(addr)
-	resp1.Start = []byte(prefix)
-	resp1.End = genPrefixEdge([]byte(prefix))
-	resp1.Count = 5
-	resp1.Mode = 1
-
-	query = &drivers.StorelistQuery{StoreListReply: resp1}
-	store.IterateRangeByStateHash(hash1, resp1.Start, resp1.End, true, query.IterateCallBack)
-
-	tool.AddItem(resp1.Values)
-	assert.Equal(t, int64(2), resp1.Num)
-	assert.Equal(t, (0), len(resp1.NextKey))
-	assert.Equal(t, (2), len(resp1.Keys))
-	assert.Equal(t, (2), len(resp1.Values))
-	assert.Equal(t, int64(4), tool.Amount)
-	assert.Equal(t, int64(2), tool.AmountActive)
-	assert.Equal(t, int64(2), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case3-------")
-	resp0 := &types.StoreListReply{}
-	resp0.Suffix = []byte(addr)
-	resp0.Start = []byte(prefix)
-	resp0.End = genPrefixEdge([]byte(prefix))
-	resp0.Count = 5
-	resp0.Mode = 1
-	query = &drivers.StorelistQuery{StoreListReply: resp0}
-	store.IterateRangeByStateHash(hash0, resp0.Start, resp0.End, true, query.IterateCallBack)
-
-	tool.AddItem(resp0.Values)
-	assert.Equal(t, int64(1), resp0.Num)
-	assert.Equal(t, 0, len(resp0.NextKey))
-	assert.Equal(t, 1, len(resp0.Keys))
-	assert.Equal(t, 1, len(resp0.Values))
-	assert.Equal(t, int64(2), tool.Amount)
-	assert.Equal(t, int64(1), tool.AmountActive)
-	assert.Equal(t, int64(1), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case4-------")
-	resp := &types.StoreListReply{}
-	resp.Suffix = []byte(addr)
-	resp.Start = []byte(prefix)
-	resp.End = genPrefixEdge([]byte(prefix))
-	resp.Count = 1
-	resp.Mode = 1
-	query = &drivers.StorelistQuery{StoreListReply: resp}
-	store.IterateRangeByStateHash(hash2, resp.Start, resp.End, true, query.IterateCallBack)
-
-	tool.AddItem(resp.Values)
-	assert.Equal(t, int64(1), resp.Num)
-	assert.Equal(t, len([]byte(key)), len(resp.NextKey))
-	assert.Equal(t, (1), len(resp.Keys))
-	assert.Equal(t, (1), len(resp.Values))
-	assert.Equal(t, int64(2), tool.Amount)
-	assert.Equal(t, int64(1), tool.AmountActive)
-	assert.Equal(t, int64(1), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case5-------")
-	resp = &types.StoreListReply{}
-	resp.Suffix = []byte(addr)
-	resp.Start = []byte(prefix)
-	resp.End = genPrefixEdge([]byte(prefix))
-	resp.Count = 2
-	resp.Mode = 1
-	query = &drivers.StorelistQuery{StoreListReply: resp}
-	store.IterateRangeByStateHash(hash2, resp.Start, resp.End, true, query.IterateCallBack)
-
-	tool.AddItem(resp.Values)
-	assert.Equal(t, int64(2), resp.Num)
-	assert.Equal(t, len([]byte(key)), len(resp.NextKey))
-	assert.Equal(t, (2), len(resp.Keys))
-	assert.Equal(t, (2), len(resp.Values))
-	assert.Equal(t, int64(4), tool.Amount)
-	assert.Equal(t, int64(2), tool.AmountActive)
-	assert.Equal(t, int64(2), tool.AmountFrozen)
-	tool.Reset()
-}
-func TestIterateCallBack_Mode2(t *testing.T) {
-	dir, err := ioutil.TempDir("", "example")
+	status := resp.GetData().(*types.StorePruneStatus)
+	assert.True(t, status.Enable)
+	assert.Equal(t, int64(9), status.CurHeight)
+	assert.Equal(t, int64(7), status.PrunedHeight)
+	assert.Equal(t, int64(0), status.Backlog)
+	assert.True(t, status.ReclaimedNodes > 0)
+
+	//已经裁剪的历史状态不能读取
+	msg = client.NewMessage("store", types.EventStoreGet, &types.StoreGet{StateHash: roots[3], Keys: [][]byte{[]byte("k1")}})
+	assert.Nil(t, client.Send(msg, true))
+	_, err = client.Wait(msg)
+	assert.Equal(t, types.ErrStatePruned, err)
+	msg = client.NewMessage("store", types.EventStoreList, &types.StoreList{StateHash: roots[3], Start: []byte("k"), End: []byte("l")})
+	assert.Nil(t, client.Send(msg, true))
+	_, err = client.Wait(msg)
+	assert.Equal(t, types.ErrStatePruned, err)
+	//没有裁剪的状态中不存在的key正常返回
+	msg = client.NewMessage("store", types.EventStoreGet, &types.StoreGet{StateHash: roots[8], Keys: [][]byte{[]byte("k2")}})
+	assert.Nil(t, client.Send(msg, true))
+	resp, err = client.Wait(msg)
 	assert.Nil(t, err)
-	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var store_cfg = newStoreCfg(dir)
-	store := New(store_cfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-	//mavldb.EnableMavlPrefix(true)
-	//defer mavldb.EnableMavlPrefix(false)
-
-	//var accountdb *account.DB
-	accountdb := account.NewCoinsAccount(cfg)
-	key := "mavl-coins-bty-exec-16htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp:1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
-	prefix := "mavl-coins-bty-exec-"
-	execAddr1 := "16htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"
-	addr := "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
-	var acc = &types.Account{
-		Currency: 0,
-		Balance:  1,
-		Frozen:   1,
-		Addr:     addr,
-	}
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        accountdb.GetExecKVSet(execAddr1, acc),
-		Height:    0}
-	hash0, err := store.Set(datas, true)
+	assert.Nil(t, resp.GetData().(*types.StoreReplyValue).Values[0])
+	msg = client.NewMessage("store", types.EventStoreGet, &types.StoreGet{StateHash: roots[8], Keys: [][]byte{[]byte("k1")}})
+	assert.Nil(t, client.Send(msg, true))
+	resp, err = client.Wait(msg)
 	assert.Nil(t, err)
+	assert.Equal(t, []byte("v8"), resp.GetData().(*types.StoreReplyValue).Values[0])
 
-	execAddr2 := "26htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"
-	datas = &types.StoreSet{
-		StateHash: hash0,
-		KV:        accountdb.GetExecKVSet(execAddr2, acc),
-		Height:    1}
-	hash1, err := store.Set(datas, true)
+	msg = client.NewMessage("store", types.EventStorePrunePause, &types.ReqStorePrune{Pause: true})
+	assert.Nil(t, client.Send(msg, true))
+	resp, err = client.Wait(msg)
 	assert.Nil(t, err)
+	assert.True(t, resp.GetData().(*types.StorePruneStatus).Paused)
 
-	execAddr3 := "36htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"
-	datas = &types.StoreSet{
-		StateHash: hash1,
-		KV:        accountdb.GetExecKVSet(execAddr3, acc),
-		Height:    2}
-	hash2, err := store.Set(datas, true)
-
+	//暂停之后不再裁剪
+	kv := []*types.KeyValue{{Key: []byte("k1"), Value: []byte("v10")}}
+	hash, err = store.Set(&types.StoreSet{StateHash: hash, KV: kv, Height: 10}, true)
 	assert.Nil(t, err)
-
-	fmt.Println("func TestIterateCallBack------test case1-------")
-	resp2 := &types.StoreListReply{}
-	resp2.Suffix = []byte(addr)
-	resp2.Start = []byte(prefix)
-	resp2.End = genPrefixEdge([]byte(prefix))
-	resp2.Count = 5
-	resp2.Mode = 2
-
-	query := &drivers.StorelistQuery{StoreListReply: resp2}
-	store.IterateRangeByStateHash(hash2, resp2.Start, nil, true, query.IterateCallBack)
-	tool := &StatTool{}
-	tool.AddItem(resp2.Values)
-	assert.Equal(t, int64(3), resp2.Num)
-	assert.Equal(t, (0), len(resp2.NextKey))
-	assert.Equal(t, (3), len(resp2.Keys))
-	assert.Equal(t, (3), len(resp2.Values))
-	assert.Equal(t, int64(6), tool.Amount)
-	assert.Equal(t, int64(3), tool.AmountActive)
-	assert.Equal(t, int64(3), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case2-------")
-	resp1 := &types.StoreListReply{}
-	resp1.Suffix = []byte(addr)
-	resp1.Start = []byte(prefix)
-	resp1.End = genPrefixEdge([]byte(prefix))
-	resp1.Count = 5
-	resp1.Mode = 2
-	query = &drivers.StorelistQuery{StoreListReply: resp1}
-	store.IterateRangeByStateHash(hash1, resp1.Start, resp1.End, true, query.IterateCallBack)
-
-	tool.AddItem(resp1.Values)
-	assert.Equal(t, int64(2), resp1.Num)
-	assert.Equal(t, (0), len(resp1.NextKey))
-	assert.Equal(t, (2), len(resp1.Keys))
-	assert.Equal(t, (2), len(resp1.Values))
-	assert.Equal(t, int64(4), tool.Amount)
-	assert.Equal(t, int64(2), tool.AmountActive)
-	assert.Equal(t, int64(2), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case3-------")
-	resp0 := &types.StoreListReply{}
-	resp0.Suffix = []byte(addr)
-	resp0.Start = []byte(prefix)
-	resp0.End = genPrefixEdge([]byte(prefix))
-	resp0.Count = 5
-	resp0.Mode = 2
-	query = &drivers.StorelistQuery{StoreListReply: resp0}
-	store.IterateRangeByStateHash(hash0, resp0.Start, nil, true, query.IterateCallBack)
-
-	tool.AddItem(resp0.Values)
-	assert.Equal(t, int64(1), resp0.Num)
-	assert.Equal(t, (0), len(resp0.NextKey))
-	assert.Equal(t, (1), len(resp0.Keys))
-	assert.Equal(t, (1), len(resp0.Values))
-	assert.Equal(t, int64(2), tool.Amount)
-	assert.Equal(t, int64(1), tool.AmountActive)
-	assert.Equal(t, int64(1), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case4-------")
-	resp := &types.StoreListReply{}
-	resp.Suffix = []byte(addr)
-	resp.Start = []byte(prefix)
-	resp.End = genPrefixEdge([]byte(prefix))
-	resp.Count = 1
-	resp.Mode = 2
-	query = &drivers.StorelistQuery{StoreListReply: resp}
-	store.IterateRangeByStateHash(hash2, resp.Start, nil, true, query.IterateCallBack)
-
-	tool.AddItem(resp.Values)
-	assert.Equal(t, int64(1), resp.Num)
-	assert.Equal(t, len([]byte(key)), len(resp.NextKey))
-	assert.Equal(t, (1), len(resp.Keys))
-	assert.Equal(t, (1), len(resp.Values))
-	assert.Equal(t, int64(2), tool.Amount)
-	assert.Equal(t, int64(1), tool.AmountActive)
-	assert.Equal(t, int64(1), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case5-------")
-	resp = &types.StoreListReply{}
-	resp.Suffix = []byte(addr)
-	resp.Start = []byte(prefix)
-	resp.End = genPrefixEdge([]byte(prefix))
-	resp.Count = 2
-	resp.Mode = 2
-	query = &drivers.StorelistQuery{StoreListReply: resp}
-	store.IterateRangeByStateHash(hash2, resp.Start, nil, true, query.IterateCallBack)
-
-	tool.AddItem(resp.Values)
-	assert.Equal(t, int64(2), resp.Num)
-	assert.Equal(t, len([]byte(key)), len(resp.NextKey))
-	assert.Equal(t, (2), len(resp.Keys))
-	assert.Equal(t, (2), len(resp.Values))
-	assert.Equal(t, int64(4), tool.Amount)
-	assert.Equal(t, int64(2), tool.AmountActive)
-	assert.Equal(t, int64(2), tool.AmountFrozen)
-	tool.Reset()
-
-	fmt.Println("func TestIterateCallBack------test case6-------")
-	resp = &types.StoreListReply{}
-	resp.End = []byte(addr)
-	resp.Start = []byte("mavl-coins-bty-exec-26htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp:")
-	resp.End = genPrefixEdge([]byte("mavl-coins-bty-exec-26htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp:"))
-	resp.Count = 1
-	resp.Mode = 2
-	query = &drivers.StorelistQuery{StoreListReply: resp}
-	store.IterateRangeByStateHash(hash2, resp.Start, resp.End, true, query.IterateCallBack)
-	tool.AddItem(resp.Values)
-	assert.Equal(t, int64(1), resp.Num)
-	assert.Equal(t, len([]byte(key)), len(resp.NextKey))
-	assert.Equal(t, (1), len(resp.Keys))
-	assert.Equal(t, (1), len(resp.Values))
-	assert.Equal(t, int64(2), tool.Amount)
-	assert.Equal(t, int64(1), tool.AmountActive)
-	assert.Equal(t, int64(1), tool.AmountFrozen)
-	tool.Reset()
+	status = store.pruner.Status()
+	assert.Equal(t, int64(7), status.PrunedHeight)
+	assert.Equal(t, int64(1), status.Backlog)
+	values := store.Get(&types.StoreGet{StateHash: hash, Keys: [][]byte{[]byte("k1")}})
+	assert.Equal(t, []byte("v10"), values[0])
 }
 
 func GetRandomString(length int) string {
 	return common.GetRandPrintString(20, length)
 }
 
-func TestKvdbIterateTimes(t *testing.T) {
-	checkKVResult = checkKVResult[:0]
-	dir, err := ioutil.TempDir("", "example")
-	assert.Nil(t, err)
-	defer os.RemoveAll(dir) // clean up
-	os.RemoveAll(dir)       //删除已存在目录
-	var storeCfg = newStoreCfg(dir)
-	store := New(storeCfg, nil, nil).(*Store)
-	assert.NotNil(t, store)
-
-	var kv []*types.KeyValue
-	var key string
-	var value string
-
-	for i := 0; i < 1000; i++ {
-		key = GetRandomString(MaxKeylenth)
-		value = fmt.Sprintf("v%d", i)
-		kv = append(kv, &types.KeyValue{Key: []byte(key), Value: []byte(value)})
-	}
-	datas := &types.StoreSet{
-		StateHash: drivers.EmptyRoot[:],
-		KV:        kv,
-	}
-	hash, err := store.Set(datas, true)
-	assert.Nil(t, err)
-	start := time.Now()
-	store.IterateRangeByStateHash(hash, nil, nil, true, checkKV)
-	end := time.Now()
-	fmt.Println("mavl cost time is", end.Sub(start))
-	assert.Len(t, checkKVResult, 1000)
-}
-
 func BenchmarkGet(b *testing.B) {
 	dir, err := ioutil.TempDir("", "example")
 	assert.Nil(b, err)
@@ -0,0 +1,363 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package storetest 存储子模块的公共测试用例, 每个存储驱动在自己的测试中调用 Run 即可覆盖 SubStore 接口的基本语义
+package storetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/account"
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/queue"
+	drivers "github.com/33cn/chain33/system/store"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxKeyLength 随机key的长度
+const maxKeyLength = 64
+
+// NewStoreFunc 创建一个空的存储子模块, 返回的函数用于关闭存储并清理数据目录
+type NewStoreFunc func(t *testing.T) (drivers.SubStore, func())
+
+// Run 对存储子模块运行全部公共测试用例
+func Run(t *testing.T, newStore NewStoreFunc) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, store drivers.SubStore)
+	}{
+		{"SetGet", testSetGet},
+		{"MemSet", testMemSet},
+		{"MemSetUpgrade", testMemSetUpgrade},
+		{"Rollback", testRollback},
+		{"ProcEvent", testProcEvent},
+		{"Del", testDel},
+		{"Iterate", testIterate},
+		{"IterateCallBack_Mode1", testIterateCallBackMode1},
+		{"IterateCallBack_Mode2", testIterateCallBackMode2},
+		{"IterateTimes", testIterateTimes},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			store, closer := newStore(t)
+			defer closer()
+			c.fn(t, store)
+		})
+	}
+}
+
+func testSetGet(t *testing.T, store drivers.SubStore) {
+	keys0 := [][]byte{[]byte("mk1"), []byte("mk2")}
+	get0 := &types.StoreGet{StateHash: drivers.EmptyRoot[:], Keys: keys0}
+	values0 := store.Get(get0)
+	// Get exist key, result nil
+	assert.Len(t, values0, 2)
+	assert.Equal(t, []byte(nil), values0[0])
+	assert.Equal(t, []byte(nil), values0[1])
+
+	var kv []*types.KeyValue
+	kv = append(kv, &types.KeyValue{Key: []byte("k1"), Value: []byte("v1")})
+	kv = append(kv, &types.KeyValue{Key: []byte("k2"), Value: []byte("v2")})
+	datas := &types.StoreSet{
+		StateHash: drivers.EmptyRoot[:],
+		KV:        kv,
+	}
+	hash, err := store.Set(datas, true)
+	assert.Nil(t, err)
+	keys := [][]byte{[]byte("k1"), []byte("k2")}
+	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
+
+	values := store.Get(get1)
+	assert.Len(t, values, 2)
+	assert.Equal(t, []byte("v1"), values[0])
+	assert.Equal(t, []byte("v2"), values[1])
+
+	keys = [][]byte{[]byte("k1")}
+	get2 := &types.StoreGet{StateHash: hash, Keys: keys}
+	values2 := store.Get(get2)
+	assert.Len(t, values2, 1)
+	assert.Equal(t, []byte("v1"), values2[0])
+
+	get3 := &types.StoreGet{StateHash: drivers.EmptyRoot[:], Keys: keys}
+	values3 := store.Get(get3)
+	assert.Len(t, values3, 1)
+	assert.Equal(t, []byte(nil), values3[0])
+}
+
+func testMemSet(t *testing.T, store drivers.SubStore) {
+	var kv []*types.KeyValue
+	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
+	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
+	datas := &types.StoreSet{
+		StateHash: drivers.EmptyRoot[:],
+		KV:        kv,
+	}
+	hash, err := store.MemSet(datas, true)
+	assert.Nil(t, err)
+	keys := [][]byte{[]byte("mk1"), []byte("mk2")}
+	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
+
+	values := store.Get(get1)
+	assert.Len(t, values, 2)
+
+	actHash, _ := store.Commit(&types.ReqHash{Hash: hash})
+	assert.Equal(t, hash, actHash)
+
+	notExistHash, _ := store.Commit(&types.ReqHash{Hash: drivers.EmptyRoot[:]})
+	assert.Nil(t, notExistHash)
+}
+
+func testMemSetUpgrade(t *testing.T, store drivers.SubStore) {
+	var kv []*types.KeyValue
+	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
+	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
+	datas := &types.StoreSet{
+		StateHash: drivers.EmptyRoot[:],
+		KV:        kv,
+	}
+	hash, err := store.MemSetUpgrade(datas, true)
+	assert.Nil(t, err)
+	keys := [][]byte{[]byte("mk1"), []byte("mk2")}
+	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
+
+	values := store.Get(get1)
+	assert.Len(t, values, 2)
+
+	hash1, err := store.CommitUpgrade(&types.ReqHash{Hash: hash})
+	assert.Nil(t, err)
+	assert.Equal(t, hash, hash1)
+}
+
+func testRollback(t *testing.T, store drivers.SubStore) {
+	var kv []*types.KeyValue
+	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
+	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
+	datas := &types.StoreSet{
+		StateHash: drivers.EmptyRoot[:],
+		KV:        kv,
+	}
+	hash, err := store.MemSet(datas, true)
+	assert.Nil(t, err)
+	keys := [][]byte{[]byte("mk1"), []byte("mk2")}
+	get1 := &types.StoreGet{StateHash: hash, Keys: keys}
+	values := store.Get(get1)
+	assert.Len(t, values, 2)
+
+	actHash, _ := store.Rollback(&types.ReqHash{Hash: hash})
+	assert.Equal(t, hash, actHash)
+
+	notExistHash, _ := store.Rollback(&types.ReqHash{Hash: drivers.EmptyRoot[:]})
+	assert.Nil(t, notExistHash)
+}
+
+func testProcEvent(t *testing.T, store drivers.SubStore) {
+	store.ProcEvent(nil)
+	store.ProcEvent(&queue.Message{})
+}
+
+func testDel(t *testing.T, store drivers.SubStore) {
+	hash, _ := store.Del(nil)
+	assert.Nil(t, hash)
+}
+
+func testIterate(t *testing.T, store drivers.SubStore) {
+	var kv []*types.KeyValue
+	kv = append(kv, &types.KeyValue{Key: []byte("mk1"), Value: []byte("v1")})
+	kv = append(kv, &types.KeyValue{Key: []byte("mk2"), Value: []byte("v2")})
+	datas := &types.StoreSet{
+		StateHash: drivers.EmptyRoot[:],
+		KV:        kv,
+	}
+	hash, err := store.Set(datas, true)
+	assert.Nil(t, err)
+	var result []*types.KeyValue
+	store.IterateRangeByStateHash(hash, []byte("mk1"), []byte("mk3"), true, func(k, v []byte) bool {
+		result = append(result, &types.KeyValue{Key: k, Value: v})
+		return false
+	})
+	assert.Len(t, result, 2)
+	assert.Equal(t, []byte("v1"), result[0].Value)
+	assert.Equal(t, []byte("v2"), result[1].Value)
+}
+
+func testIterateTimes(t *testing.T, store drivers.SubStore) {
+	var kv []*types.KeyValue
+	for i := 0; i < 1000; i++ {
+		key := common.GetRandPrintString(20, maxKeyLength)
+		value := fmt.Sprintf("v%d", i)
+		kv = append(kv, &types.KeyValue{Key: []byte(key), Value: []byte(value)})
+	}
+	datas := &types.StoreSet{
+		StateHash: drivers.EmptyRoot[:],
+		KV:        kv,
+	}
+	hash, err := store.Set(datas, true)
+	assert.Nil(t, err)
+	var count int
+	start := time.Now()
+	store.IterateRangeByStateHash(hash, nil, nil, true, func(k, v []byte) bool {
+		count++
+		return false
+	})
+	t.Log("iterate cost time is", time.Since(start))
+	assert.Equal(t, 1000, count)
+}
+
+//statTool 统计账户的余额
+type statTool struct {
+	Amount       int64
+	AmountActive int64
+	AmountFrozen int64
+}
+
+func (t *statTool) AddItem(value [][]byte) {
+	for i := 0; i < len(value); i++ {
+		var acc types.Account
+		err := types.Decode(value[i], &acc)
+		if err != nil {
+			return
+		}
+		t.Amount += acc.Balance
+		t.Amount += acc.Frozen
+
+		t.AmountActive += acc.Balance
+		t.AmountFrozen += acc.Frozen
+	}
+}
+
+func genPrefixEdge(prefix []byte) (r []byte) {
+	for j := 0; j < len(prefix); j++ {
+		r = append(r, prefix[j])
+	}
+
+	i := len(prefix) - 1
+	for i >= 0 {
+		if r[i] < 0xff {
+			r[i]++
+			break
+		} else {
+			i--
+		}
+	}
+
+	return r
+}
+
+const (
+	accountKey    = "mavl-coins-bty-exec-16htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp:1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
+	accountPrefix = "mavl-coins-bty-exec-"
+	accountAddr   = "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
+)
+
+//setExecAccounts 依次在三个高度上写入同一个地址在三个执行器下的账户, 返回每个高度的状态hash
+func setExecAccounts(t *testing.T, store drivers.SubStore) [][]byte {
+	cfg := types.NewChain33Config(types.GetDefaultCfgstring())
+	accountdb := account.NewCoinsAccount(cfg)
+	var acc = &types.Account{
+		Currency: 0,
+		Balance:  1,
+		Frozen:   1,
+		Addr:     accountAddr,
+	}
+	hash := drivers.EmptyRoot[:]
+	var hashes [][]byte
+	execAddrs := []string{"16htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp", "26htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp", "36htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp"}
+	for i, execAddr := range execAddrs {
+		datas := &types.StoreSet{
+			StateHash: hash,
+			KV:        accountdb.GetExecKVSet(execAddr, acc),
+			Height:    int64(i)}
+		var err error
+		hash, err = store.Set(datas, true)
+		require.Nil(t, err)
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+//iterateCase 按照指定的区间和模式迭代, 检查返回的账户数, 是否有下一页以及余额统计
+type iterateCase struct {
+	hash    []byte
+	start   []byte
+	end     []byte
+	count   int64
+	num     int64
+	hasNext bool
+}
+
+func (c *iterateCase) run(t *testing.T, store drivers.SubStore, mode int64) {
+	resp := &types.StoreListReply{}
+	resp.Suffix = []byte(accountAddr)
+	resp.Start = c.start
+	resp.End = genPrefixEdge(c.start)
+	resp.Count = c.count
+	resp.Mode = mode
+	query := &drivers.StorelistQuery{StoreListReply: resp}
+	store.IterateRangeByStateHash(c.hash, resp.Start, c.end, true, query.IterateCallBack)
+
+	tool := &statTool{}
+	tool.AddItem(resp.Values)
+	assert.Equal(t, c.num, resp.Num)
+	if c.hasNext {
+		assert.Equal(t, len([]byte(accountKey)), len(resp.NextKey))
+	} else {
+		assert.Equal(t, 0, len(resp.NextKey))
+	}
+	assert.Equal(t, int(c.num), len(resp.Keys))
+	assert.Equal(t, int(c.num), len(resp.Values))
+	assert.Equal(t, 2*c.num, tool.Amount)
+	assert.Equal(t, c.num, tool.AmountActive)
+	assert.Equal(t, c.num, tool.AmountFrozen)
+}
+
+func testIterateCallBackMode1(t *testing.T, store drivers.SubStore) {
+	hashes := setExecAccounts(t, store)
+	prefix := []byte(accountPrefix)
+	end := genPrefixEdge(prefix)
+
+	req := &types.StoreList{StateHash: hashes[2], Start: prefix, Suffix: []byte(accountAddr), End: end, Count: 5, Mode: 1}
+	resp := drivers.NewStoreListQuery(store, req).Run()
+	tool := &statTool{}
+	tool.AddItem(resp.Values)
+	assert.Equal(t, int64(3), resp.Num)
+	assert.Equal(t, 0, len(resp.NextKey))
+	assert.Equal(t, 3, len(resp.Keys))
+	assert.Equal(t, 3, len(resp.Values))
+	assert.Equal(t, int64(6), tool.Amount)
+	assert.Equal(t, int64(3), tool.AmountActive)
+	assert.Equal(t, int64(3), tool.AmountFrozen)
+
+	cases := []*iterateCase{
+		{hash: hashes[1], start: prefix, end: end, count: 5, num: 2},
+		{hash: hashes[0], start: prefix, end: end, count: 5, num: 1},
+		{hash: hashes[2], start: prefix, end: end, count: 1, num: 1, hasNext: true},
+		{hash: hashes[2], start: prefix, end: end, count: 2, num: 2, hasNext: true},
+	}
+	for _, c := range cases {
+		c.run(t, store, 1)
+	}
+}
+
+func testIterateCallBackMode2(t *testing.T, store drivers.SubStore) {
+	hashes := setExecAccounts(t, store)
+	prefix := []byte(accountPrefix)
+	execPrefix := []byte("mavl-coins-bty-exec-26htvcBNSEA7fZhAdLJphDwQRQJaHpyHTp:")
+
+	cases := []*iterateCase{
+		{hash: hashes[2], start: prefix, count: 5, num: 3},
+		{hash: hashes[1], start: prefix, end: genPrefixEdge(prefix), count: 5, num: 2},
+		{hash: hashes[0], start: prefix, count: 5, num: 1},
+		{hash: hashes[2], start: prefix, count: 1, num: 1, hasNext: true},
+		{hash: hashes[2], start: prefix, count: 2, num: 2, hasNext: true},
+		{hash: hashes[2], start: execPrefix, end: genPrefixEdge(execPrefix), count: 1, num: 1, hasNext: true},
+	}
+	for _, c := range cases {
+		c.run(t, store, 2)
+	}
+}
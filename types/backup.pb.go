@@ -0,0 +1,368 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: backup.proto
+
+package types
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// 在线备份全部模块的数据库
+type ReqBackupDB struct {
+	// 备份目录, 必须不存在或者为空
+	Dir                  string   `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReqBackupDB) Reset()         { *m = ReqBackupDB{} }
+func (m *ReqBackupDB) String() string { return proto.CompactTextString(m) }
+func (*ReqBackupDB) ProtoMessage()    {}
+func (*ReqBackupDB) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65240d19de191688, []int{0}
+}
+
+func (m *ReqBackupDB) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReqBackupDB.Unmarshal(m, b)
+}
+func (m *ReqBackupDB) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReqBackupDB.Marshal(b, m, deterministic)
+}
+func (m *ReqBackupDB) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReqBackupDB.Merge(m, src)
+}
+func (m *ReqBackupDB) XXX_Size() int {
+	return xxx_messageInfo_ReqBackupDB.Size(m)
+}
+func (m *ReqBackupDB) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReqBackupDB.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReqBackupDB proto.InternalMessageInfo
+
+func (m *ReqBackupDB) GetDir() string {
+	if m != nil {
+		return m.Dir
+	}
+	return ""
+}
+
+// blockchain发给各模块的数据库快照请求
+type ReqSnapshotDB struct {
+	// 1: 获取快照, 2: 把快照写入备份目录, 3: 放弃备份
+	Op int32 `protobuf:"varint,1,opt,name=op,proto3" json:"op,omitempty"`
+	// 本模块的备份目录
+	Dir                  string   `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReqSnapshotDB) Reset()         { *m = ReqSnapshotDB{} }
+func (m *ReqSnapshotDB) String() string { return proto.CompactTextString(m) }
+func (*ReqSnapshotDB) ProtoMessage()    {}
+func (*ReqSnapshotDB) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65240d19de191688, []int{1}
+}
+
+func (m *ReqSnapshotDB) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReqSnapshotDB.Unmarshal(m, b)
+}
+func (m *ReqSnapshotDB) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReqSnapshotDB.Marshal(b, m, deterministic)
+}
+func (m *ReqSnapshotDB) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReqSnapshotDB.Merge(m, src)
+}
+func (m *ReqSnapshotDB) XXX_Size() int {
+	return xxx_messageInfo_ReqSnapshotDB.Size(m)
+}
+func (m *ReqSnapshotDB) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReqSnapshotDB.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReqSnapshotDB proto.InternalMessageInfo
+
+func (m *ReqSnapshotDB) GetOp() int32 {
+	if m != nil {
+		return m.Op
+	}
+	return 0
+}
+
+func (m *ReqSnapshotDB) GetDir() string {
+	if m != nil {
+		return m.Dir
+	}
+	return ""
+}
+
+// 单个数据库的备份信息
+type BackupDBInfo struct {
+	Module string `protobuf:"bytes,1,opt,name=module,proto3" json:"module,omitempty"`
+	Name   string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Driver string `protobuf:"bytes,3,opt,name=driver,proto3" json:"driver,omitempty"`
+	// 数据库所在目录, 清单中为相对备份目录的路径
+	Path string `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	// key的数目
+	Count int64 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	// key和value的总字节数
+	Size int64 `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	// 按顺序计算全部key和value的sha256
+	Checksum []byte `protobuf:"bytes,7,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// 是否通过快照备份, false表示在暂停区块写入期间直接复制
+	Snapshot             bool     `protobuf:"varint,8,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BackupDBInfo) Reset()         { *m = BackupDBInfo{} }
+func (m *BackupDBInfo) String() string { return proto.CompactTextString(m) }
+func (*BackupDBInfo) ProtoMessage()    {}
+func (*BackupDBInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65240d19de191688, []int{2}
+}
+
+func (m *BackupDBInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BackupDBInfo.Unmarshal(m, b)
+}
+func (m *BackupDBInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BackupDBInfo.Marshal(b, m, deterministic)
+}
+func (m *BackupDBInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BackupDBInfo.Merge(m, src)
+}
+func (m *BackupDBInfo) XXX_Size() int {
+	return xxx_messageInfo_BackupDBInfo.Size(m)
+}
+func (m *BackupDBInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_BackupDBInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BackupDBInfo proto.InternalMessageInfo
+
+func (m *BackupDBInfo) GetModule() string {
+	if m != nil {
+		return m.Module
+	}
+	return ""
+}
+
+func (m *BackupDBInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *BackupDBInfo) GetDriver() string {
+	if m != nil {
+		return m.Driver
+	}
+	return ""
+}
+
+func (m *BackupDBInfo) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *BackupDBInfo) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *BackupDBInfo) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *BackupDBInfo) GetChecksum() []byte {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
+func (m *BackupDBInfo) GetSnapshot() bool {
+	if m != nil {
+		return m.Snapshot
+	}
+	return false
+}
+
+// 备份清单, 保存在备份目录的MANIFEST文件中
+type BackupManifest struct {
+	Version int32  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Title   string `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	// 备份时的区块高度, hash以及状态hash
+	Height     int64  `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	BlockHash  []byte `protobuf:"bytes,4,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	StateHash  []byte `protobuf:"bytes,5,opt,name=stateHash,proto3" json:"stateHash,omitempty"`
+	CreateTime int64  `protobuf:"varint,6,opt,name=createTime,proto3" json:"createTime,omitempty"`
+	// 暂停区块写入的时间, 毫秒
+	PauseTime int64           `protobuf:"varint,7,opt,name=pauseTime,proto3" json:"pauseTime,omitempty"`
+	Dbs       []*BackupDBInfo `protobuf:"bytes,8,rep,name=dbs,proto3" json:"dbs,omitempty"`
+	// 区块归档段文件的数目和总大小
+	ArchiveSegments      int32    `protobuf:"varint,9,opt,name=archiveSegments,proto3" json:"archiveSegments,omitempty"`
+	ArchiveSize          int64    `protobuf:"varint,10,opt,name=archiveSize,proto3" json:"archiveSize,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BackupManifest) Reset()         { *m = BackupManifest{} }
+func (m *BackupManifest) String() string { return proto.CompactTextString(m) }
+func (*BackupManifest) ProtoMessage()    {}
+func (*BackupManifest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65240d19de191688, []int{3}
+}
+
+func (m *BackupManifest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BackupManifest.Unmarshal(m, b)
+}
+func (m *BackupManifest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BackupManifest.Marshal(b, m, deterministic)
+}
+func (m *BackupManifest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BackupManifest.Merge(m, src)
+}
+func (m *BackupManifest) XXX_Size() int {
+	return xxx_messageInfo_BackupManifest.Size(m)
+}
+func (m *BackupManifest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BackupManifest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BackupManifest proto.InternalMessageInfo
+
+func (m *BackupManifest) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *BackupManifest) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *BackupManifest) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *BackupManifest) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *BackupManifest) GetStateHash() []byte {
+	if m != nil {
+		return m.StateHash
+	}
+	return nil
+}
+
+func (m *BackupManifest) GetCreateTime() int64 {
+	if m != nil {
+		return m.CreateTime
+	}
+	return 0
+}
+
+func (m *BackupManifest) GetPauseTime() int64 {
+	if m != nil {
+		return m.PauseTime
+	}
+	return 0
+}
+
+func (m *BackupManifest) GetDbs() []*BackupDBInfo {
+	if m != nil {
+		return m.Dbs
+	}
+	return nil
+}
+
+func (m *BackupManifest) GetArchiveSegments() int32 {
+	if m != nil {
+		return m.ArchiveSegments
+	}
+	return 0
+}
+
+func (m *BackupManifest) GetArchiveSize() int64 {
+	if m != nil {
+		return m.ArchiveSize
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*ReqBackupDB)(nil), "types.ReqBackupDB")
+	proto.RegisterType((*ReqSnapshotDB)(nil), "types.ReqSnapshotDB")
+	proto.RegisterType((*BackupDBInfo)(nil), "types.BackupDBInfo")
+	proto.RegisterType((*BackupManifest)(nil), "types.BackupManifest")
+}
+
+func init() {
+	proto.RegisterFile("backup.proto", fileDescriptor_65240d19de191688)
+}
+
+var fileDescriptor_65240d19de191688 = []byte{
+	// 399 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x52, 0xcd, 0x8e, 0xd3, 0x30,
+	0x10, 0x56, 0x9a, 0x4d, 0x7f, 0xa6, 0x65, 0x41, 0x06, 0x21, 0x0b, 0x01, 0x1b, 0x55, 0x42, 0xca,
+	0xa9, 0x15, 0xe4, 0x0d, 0xaa, 0x3d, 0xc0, 0x81, 0x8b, 0x97, 0x13, 0x37, 0xc7, 0x99, 0x6d, 0xac,
+	0x36, 0xb6, 0x1b, 0x3b, 0x95, 0xe0, 0xfd, 0x90, 0x78, 0x2c, 0x64, 0x3b, 0x69, 0x2b, 0x6e, 0xf3,
+	0xfd, 0xd9, 0xf9, 0x9c, 0x81, 0x55, 0xc5, 0xc5, 0xa1, 0x37, 0x1b, 0xd3, 0x69, 0xa7, 0x49, 0xe6,
+	0x7e, 0x19, 0xb4, 0xeb, 0x07, 0x58, 0x32, 0x3c, 0xed, 0x82, 0xf2, 0xb8, 0x23, 0xaf, 0x20, 0xad,
+	0x65, 0x47, 0x93, 0x3c, 0x29, 0x16, 0xcc, 0x8f, 0xeb, 0xcf, 0xf0, 0x82, 0xe1, 0xe9, 0x49, 0x71,
+	0x63, 0x1b, 0xed, 0x1e, 0x77, 0xe4, 0x1e, 0x26, 0xda, 0x04, 0x47, 0xc6, 0x26, 0xda, 0x8c, 0x91,
+	0xc9, 0x35, 0xf2, 0x37, 0x81, 0xd5, 0x78, 0xe2, 0x37, 0xf5, 0xac, 0xc9, 0x5b, 0x98, 0xb6, 0xba,
+	0xee, 0x8f, 0x38, 0x1c, 0x3c, 0x20, 0x42, 0xe0, 0x4e, 0xf1, 0x16, 0x87, 0x6c, 0x98, 0xbd, 0xb7,
+	0xee, 0xe4, 0x19, 0x3b, 0x9a, 0x46, 0x6f, 0x44, 0xde, 0x6b, 0xb8, 0x6b, 0xe8, 0x5d, 0xf4, 0xfa,
+	0x99, 0xbc, 0x81, 0x4c, 0xe8, 0x5e, 0x39, 0x9a, 0xe5, 0x49, 0x91, 0xb2, 0x08, 0xbc, 0xd3, 0xca,
+	0xdf, 0x48, 0xa7, 0x81, 0x0c, 0x33, 0x79, 0x07, 0x73, 0xd1, 0xa0, 0x38, 0xd8, 0xbe, 0xa5, 0xb3,
+	0x3c, 0x29, 0x56, 0xec, 0x82, 0xbd, 0x66, 0x87, 0x7a, 0x74, 0x9e, 0x27, 0xc5, 0x9c, 0x5d, 0xf0,
+	0xfa, 0xcf, 0x04, 0xee, 0x63, 0x95, 0xef, 0x5c, 0xc9, 0x67, 0xb4, 0x8e, 0x50, 0x98, 0x9d, 0xb1,
+	0xb3, 0x52, 0xab, 0xe1, 0x11, 0x46, 0xe8, 0x3f, 0xc7, 0x49, 0x77, 0x1c, 0xfb, 0x44, 0xe0, 0x0b,
+	0x35, 0x28, 0xf7, 0x8d, 0x0b, 0x85, 0x52, 0x36, 0x20, 0xf2, 0x1e, 0x16, 0xd5, 0x51, 0x8b, 0xc3,
+	0x57, 0x6e, 0x63, 0xab, 0x15, 0xbb, 0x12, 0x5e, 0xb5, 0x8e, 0x3b, 0x0c, 0x6a, 0x16, 0xd5, 0x0b,
+	0x41, 0x3e, 0x02, 0x88, 0x0e, 0xb9, 0xc3, 0x1f, 0xb2, 0x1d, 0x8b, 0xde, 0x30, 0x3e, 0x6d, 0x78,
+	0x6f, 0xa3, 0x3c, 0x0b, 0xf2, 0x95, 0x20, 0x9f, 0x20, 0xad, 0x2b, 0x4b, 0xe7, 0x79, 0x5a, 0x2c,
+	0xbf, 0xbc, 0xde, 0x84, 0x45, 0xd8, 0xdc, 0xfe, 0x30, 0xe6, 0x75, 0x52, 0xc0, 0x4b, 0xde, 0x89,
+	0x46, 0x9e, 0xf1, 0x09, 0xf7, 0x2d, 0x2a, 0x67, 0xe9, 0x22, 0x14, 0xfe, 0x9f, 0x26, 0x39, 0x2c,
+	0x47, 0xca, 0x3f, 0x3c, 0x84, 0x0b, 0x6f, 0xa9, 0xdd, 0xc3, 0xcf, 0x0f, 0x7b, 0xe9, 0x9a, 0xbe,
+	0xda, 0x08, 0xdd, 0x6e, 0xcb, 0x52, 0xa8, 0xad, 0x68, 0xb8, 0x54, 0x65, 0xb9, 0x0d, 0xd7, 0x57,
+	0xd3, 0xb0, 0x95, 0xe5, 0xbf, 0x01, 0x00, 0xd5, 0x1c, 0x6f, 0xba, 0xa5, 0x02, 0x00, 0x00,
+}
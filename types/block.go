@@ -91,6 +91,26 @@ func (block *Block) getHeaderHashNew() *Header {
 	return head
 }
 
+// CalcHash 根据区块头字段计算区块哈希, 结果与Block.Hash一致, 用于只下载区块头时校验区块头
+func (head *Header) CalcHash(cfg *Chain33Config) []byte {
+	h := &Header{}
+	h.Version = head.Version
+	h.ParentHash = head.ParentHash
+	h.TxHash = head.TxHash
+	h.BlockTime = head.BlockTime
+	h.Height = head.Height
+	if cfg.IsFork(head.Height, "ForkBlockHash") {
+		h.Difficulty = head.Difficulty
+		h.StateHash = head.StateHash
+		h.TxCount = head.TxCount
+	}
+	data, err := proto.Marshal(h)
+	if err != nil {
+		panic(err)
+	}
+	return common.Sha256(data)
+}
+
 // CheckSign 检测block的签名
 func (block *Block) CheckSign(cfg *Chain33Config) bool {
 	//检查区块的签名
@@ -31,6 +31,11 @@ func TestBlock(t *testing.T) {
 	b.Txs = append(b.Txs, &Transaction{})
 	b.Txs = append(b.Txs, &Transaction{})
 	assert.Equal(t, false, b.CheckSign(cfg))
+
+	header := b.GetHeader(cfg)
+	assert.Equal(t, header.Hash, header.CalcHash(cfg))
+	header.TxCount = 1
+	assert.NotEqual(t, header.Hash, header.CalcHash(cfg))
 }
 
 func TestFilterParaTxsByTitle(t *testing.T) {
@@ -0,0 +1,194 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: blocksync.proto
+
+package types
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// 区块同步进度, 先同步区块头再并行下载区块体
+type SyncProgress struct {
+	// 是否正在进行区块头优先同步
+	InProgress bool `protobuf:"varint,1,opt,name=inProgress,proto3" json:"inProgress,omitempty"`
+	// 同步目标高度, 即最优链节点的最高高度
+	TargetHeight int64 `protobuf:"varint,2,opt,name=targetHeight,proto3" json:"targetHeight,omitempty"`
+	// 已下载并校验的最高区块头
+	HeaderHeight int64 `protobuf:"varint,3,opt,name=headerHeight,proto3" json:"headerHeight,omitempty"`
+	// 已连续下载的最高区块体
+	BodyHeight int64 `protobuf:"varint,4,opt,name=bodyHeight,proto3" json:"bodyHeight,omitempty"`
+	// 已执行的最高区块
+	ExecHeight int64 `protobuf:"varint,5,opt,name=execHeight,proto3" json:"execHeight,omitempty"`
+	// 参与下载区块体的节点
+	Peers                []*PeerSyncRate `protobuf:"bytes,6,rep,name=peers,proto3" json:"peers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *SyncProgress) Reset()         { *m = SyncProgress{} }
+func (m *SyncProgress) String() string { return proto.CompactTextString(m) }
+func (*SyncProgress) ProtoMessage()    {}
+func (*SyncProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0e8a51f48e1631f8, []int{0}
+}
+
+func (m *SyncProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncProgress.Unmarshal(m, b)
+}
+func (m *SyncProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncProgress.Marshal(b, m, deterministic)
+}
+func (m *SyncProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncProgress.Merge(m, src)
+}
+func (m *SyncProgress) XXX_Size() int {
+	return xxx_messageInfo_SyncProgress.Size(m)
+}
+func (m *SyncProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncProgress proto.InternalMessageInfo
+
+func (m *SyncProgress) GetInProgress() bool {
+	if m != nil {
+		return m.InProgress
+	}
+	return false
+}
+
+func (m *SyncProgress) GetTargetHeight() int64 {
+	if m != nil {
+		return m.TargetHeight
+	}
+	return 0
+}
+
+func (m *SyncProgress) GetHeaderHeight() int64 {
+	if m != nil {
+		return m.HeaderHeight
+	}
+	return 0
+}
+
+func (m *SyncProgress) GetBodyHeight() int64 {
+	if m != nil {
+		return m.BodyHeight
+	}
+	return 0
+}
+
+func (m *SyncProgress) GetExecHeight() int64 {
+	if m != nil {
+		return m.ExecHeight
+	}
+	return 0
+}
+
+func (m *SyncProgress) GetPeers() []*PeerSyncRate {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+// 节点下载区块体的速度
+type PeerSyncRate struct {
+	Pid string `protobuf:"bytes,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	// 当前下载窗口大小, 根据节点吞吐量自适应调整
+	Window int64 `protobuf:"varint,2,opt,name=window,proto3" json:"window,omitempty"`
+	// 每秒下载的区块数
+	Rate                 float64  `protobuf:"fixed64,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PeerSyncRate) Reset()         { *m = PeerSyncRate{} }
+func (m *PeerSyncRate) String() string { return proto.CompactTextString(m) }
+func (*PeerSyncRate) ProtoMessage()    {}
+func (*PeerSyncRate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0e8a51f48e1631f8, []int{1}
+}
+
+func (m *PeerSyncRate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PeerSyncRate.Unmarshal(m, b)
+}
+func (m *PeerSyncRate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PeerSyncRate.Marshal(b, m, deterministic)
+}
+func (m *PeerSyncRate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PeerSyncRate.Merge(m, src)
+}
+func (m *PeerSyncRate) XXX_Size() int {
+	return xxx_messageInfo_PeerSyncRate.Size(m)
+}
+func (m *PeerSyncRate) XXX_DiscardUnknown() {
+	xxx_messageInfo_PeerSyncRate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PeerSyncRate proto.InternalMessageInfo
+
+func (m *PeerSyncRate) GetPid() string {
+	if m != nil {
+		return m.Pid
+	}
+	return ""
+}
+
+func (m *PeerSyncRate) GetWindow() int64 {
+	if m != nil {
+		return m.Window
+	}
+	return 0
+}
+
+func (m *PeerSyncRate) GetRate() float64 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*SyncProgress)(nil), "types.SyncProgress")
+	proto.RegisterType((*PeerSyncRate)(nil), "types.PeerSyncRate")
+}
+
+func init() {
+	proto.RegisterFile("blocksync.proto", fileDescriptor_0e8a51f48e1631f8)
+}
+
+var fileDescriptor_0e8a51f48e1631f8 = []byte{
+	// 247 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x90, 0xb1, 0x4a, 0xc5, 0x30,
+	0x14, 0x86, 0x89, 0xbd, 0xb7, 0xe8, 0xb1, 0xa0, 0x44, 0x90, 0x2e, 0x6a, 0xe9, 0x54, 0x97, 0x16,
+	0xcc, 0x1b, 0x38, 0x39, 0x38, 0x5c, 0xe2, 0xe6, 0x96, 0xa6, 0x87, 0x36, 0xa8, 0x49, 0x49, 0x22,
+	0xd7, 0x3e, 0xae, 0x6f, 0x22, 0x49, 0x23, 0xd6, 0xed, 0x9c, 0xef, 0xff, 0x20, 0xf9, 0x0f, 0x5c,
+	0xf4, 0xef, 0x46, 0xbe, 0xb9, 0x45, 0xcb, 0x76, 0xb6, 0xc6, 0x1b, 0xba, 0xf7, 0xcb, 0x8c, 0xae,
+	0xfe, 0x26, 0x50, 0xbc, 0x2c, 0x5a, 0x1e, 0xac, 0x19, 0x2d, 0x3a, 0x47, 0x6f, 0x01, 0x94, 0xfe,
+	0xdd, 0x4a, 0x52, 0x91, 0xe6, 0x94, 0x6f, 0x08, 0xad, 0xa1, 0xf0, 0xc2, 0x8e, 0xe8, 0x9f, 0x50,
+	0x8d, 0x93, 0x2f, 0x4f, 0x2a, 0xd2, 0x64, 0xfc, 0x1f, 0x0b, 0xce, 0x84, 0x62, 0x40, 0x9b, 0x9c,
+	0x6c, 0x75, 0xb6, 0x2c, 0xbc, 0xd3, 0x9b, 0x61, 0x49, 0xc6, 0x2e, 0x1a, 0x1b, 0x12, 0x72, 0xfc,
+	0x42, 0x99, 0xf2, 0xfd, 0x9a, 0xff, 0x11, 0x7a, 0x0f, 0xfb, 0x19, 0xd1, 0xba, 0x32, 0xaf, 0xb2,
+	0xe6, 0xfc, 0xe1, 0xaa, 0x8d, 0x7d, 0xda, 0x03, 0xa2, 0x0d, 0x7d, 0xb8, 0xf0, 0xc8, 0x57, 0xa3,
+	0x7e, 0x86, 0x62, 0x8b, 0xe9, 0x25, 0x64, 0xb3, 0x1a, 0x62, 0xb7, 0x33, 0x1e, 0x46, 0x7a, 0x0d,
+	0xf9, 0x51, 0xe9, 0xc1, 0x1c, 0x53, 0x9d, 0xb4, 0x51, 0x0a, 0x3b, 0x2b, 0x3c, 0xc6, 0x02, 0x84,
+	0xc7, 0xf9, 0xf1, 0xee, 0xf5, 0x66, 0x54, 0x7e, 0xfa, 0xec, 0x5b, 0x69, 0x3e, 0x3a, 0xc6, 0xa4,
+	0xee, 0xe4, 0x24, 0x94, 0x66, 0xac, 0x8b, 0x5f, 0xe8, 0xf3, 0x78, 0x60, 0xf6, 0x33, 0x00, 0xa9,
+	0xb4, 0x2e, 0x04, 0x73, 0x01, 0x00, 0x00,
+}
@@ -25,6 +25,8 @@ type Config struct {
 	CoinSymbol     string       `protobuf:"bytes,17,opt,name=coinSymbol" json:"coinSymbol,omitempty"`
 	EnableParaFork bool         `protobuf:"bytes,18,opt,name=enableParaFork" json:"enableParaFork,omitempty"`
 	Metrics        *Metrics     `protobuf:"bytes,19,opt,name=metrics" json:"metrics,omitempty"`
+	RemoteQueue    *RemoteQueue `protobuf:"bytes,20,opt,name=remoteQueue" json:"remoteQueue,omitempty"`
+	Trace          *Trace       `protobuf:"bytes,21,opt,name=trace" json:"trace,omitempty"`
 }
 
 // ForkList fork列表配置
@@ -54,6 +56,12 @@ type Log struct {
 	CallerFile bool `protobuf:"varint,9,opt,name=callerFile" json:"callerFile,omitempty"`
 	// 是否打印调用方法
 	CallerFunction bool `protobuf:"varint,10,opt,name=callerFunction" json:"callerFunction,omitempty"`
+	// 日志格式，支持json/logfmt/terminal，为空时控制台使用terminal格式，文件使用logfmt格式
+	Format string `protobuf:"bytes,11,opt,name=format" json:"format,omitempty"`
+	// 按模块设置日志级别，同时作用于控制台和文件日志，子模块(p2p.dht)继承上级模块(p2p)的设置
+	ModuleLevels map[string]string `protobuf:"bytes,12,rep,name=moduleLevels" json:"moduleLevels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// 按时间切分日志文件的间隔（单位：小时），0表示只按大小切分
+	RotateInterval uint32 `protobuf:"varint,13,opt,name=rotateInterval" json:"rotateInterval,omitempty"`
 }
 
 // Mempool 配置
@@ -121,6 +129,8 @@ type Store struct {
 	LocalDBVersion string `protobuf:"bytes,5,opt,name=localdbVersion" json:"localdbVersion,omitempty"`
 	// 数据库版本
 	StoreDBVersion string `protobuf:"bytes,5,opt,name=storedbVersion" json:"storedbVersion,omitempty"`
+	// 数据库读缓存大小(MB), 0表示不开启
+	ReadCacheSize int32 `protobuf:"varint,6,opt,name=readCacheSize" json:"readCacheSize,omitempty"`
 }
 
 // BlockChain 配置
@@ -158,6 +168,20 @@ type BlockChain struct {
 	OnChainTimeout int64 `protobuf:"varint,17,opt,name=onChainTimeout" json:"onChainTimeout,omitempty"`
 	// 使能精简localdb
 	EnableReduceLocaldb bool `protobuf:"varint,18,opt,name=enableReduceLocaldb" json:"enableReduceLocaldb,omitempty"`
+	// 使能先同步区块头再从多个节点并行下载区块体的同步模式
+	HeaderFirstSync bool `protobuf:"varint,19,opt,name=headerFirstSync" json:"headerFirstSync,omitempty"`
+	// 检查点, 区块高度对应的区块哈希, 拒绝检查点以下的分叉
+	Checkpoints map[string]string `protobuf:"bytes,20,rep,name=checkpoints" json:"checkpoints,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// 使能区块归档, 较早区块的body和receipt迁移到压缩的只追加段文件中, 与enableReduceLocaldb互斥
+	EnableArchive bool `protobuf:"varint,21,opt,name=enableArchive" json:"enableArchive,omitempty"`
+	// 保留在数据库中不归档的最新区块个数, 不小于最大回滚高度
+	ArchiveReserveHeight int64 `protobuf:"varint,22,opt,name=archiveReserveHeight" json:"archiveReserveHeight,omitempty"`
+	// 归档段文件目录, 默认为dbPath下的archive目录
+	ArchiveDir string `protobuf:"bytes,23,opt,name=archiveDir" json:"archiveDir,omitempty"`
+	// 单个归档段文件的最大字节数
+	ArchiveSegmentSize int64 `protobuf:"varint,24,opt,name=archiveSegmentSize" json:"archiveSegmentSize,omitempty"`
+	// 数据库读缓存大小(MB), 0表示不开启
+	ReadCacheSize int32 `protobuf:"varint,25,opt,name=readCacheSize" json:"readCacheSize,omitempty"`
 }
 
 // P2P 配置
@@ -214,6 +238,10 @@ type Exec struct {
 	Alias            []string `protobuf:"bytes,5,rep,name=alias" json:"alias,omitempty"`
 	// 是否保存token交易信息
 	SaveTokenTxList bool `protobuf:"varint,6,opt,name=saveTokenTxList" json:"saveTokenTxList,omitempty"`
+	// 是否开启localmvcc插件, 按照区块高度保存执行器localdb的多版本数据, 必须从0高度开始同步
+	EnableLocalMVCC bool `protobuf:"varint,8,opt,name=enableLocalMVCC" json:"enableLocalMVCC,omitempty"`
+	// localdb多版本数据保留最近的区块个数, 更早的版本会被回收, 0表示全部保留, 不小于最大回滚高度
+	LocalMVCCRetainHeight int64 `protobuf:"varint,9,opt,name=localMVCCRetainHeight" json:"localMVCCRetainHeight,omitempty"`
 }
 
 // Pprof 配置
@@ -221,11 +249,23 @@ type Pprof struct {
 	ListenAddr string `protobuf:"bytes,1,opt,name=listenAddr" json:"listenAddr,omitempty"`
 }
 
-// HealthCheck 配置
+// HealthCheck 配置, 在listenAddr上提供 /healthz 和 /readyz http接口
 type HealthCheck struct {
 	ListenAddr     string `protobuf:"bytes,1,opt,name=listenAddr" json:"listenAddr,omitempty"`
 	CheckInterval  uint32 `protobuf:"varint,2,opt,name=checkInterval" json:"checkInterval,omitempty"`
 	UnSyncMaxTimes uint32 `protobuf:"varint,3,opt,name=unSyncMaxTimes" json:"unSyncMaxTimes,omitempty"`
+	// 就绪需要的最少peer个数, 不包括自己, 默认1
+	MinPeers int32 `protobuf:"varint,4,opt,name=minPeers" json:"minPeers,omitempty"`
+	// 就绪允许落后最优peer的最大高度, 默认10
+	MaxHeightGap int64 `protobuf:"varint,5,opt,name=maxHeightGap" json:"maxHeightGap,omitempty"`
+	// 就绪允许的最大时钟偏差, 单位毫秒, 默认10000
+	MaxClockDrift int64 `protobuf:"varint,6,opt,name=maxClockDrift" json:"maxClockDrift,omitempty"`
+	// 就绪允许的mempool最大占用比例, 取值(0, 1], 默认0.95
+	MaxMempoolFullness float64 `protobuf:"fixed64,7,opt,name=maxMempoolFullness" json:"maxMempoolFullness,omitempty"`
+	// 模块有待处理的消息但超过该时间没有取走消息, 认为模块阻塞, 单位秒, 默认60
+	ModuleStallTimeout int64 `protobuf:"varint,8,opt,name=moduleStallTimeout" json:"moduleStallTimeout,omitempty"`
+	// 检查存活的模块, 为空则检查所有订阅了消息的模块
+	Modules []string `protobuf:"bytes,9,rep,name=modules" json:"modules,omitempty"`
 }
 
 // Metrics:相关测量配置信息
@@ -239,3 +279,43 @@ type Metrics struct {
 	Password      string `protobuf:"bytes,7,opt,name=password" json:"password,omitempty"`
 	Namespace     string `protobuf:"bytes,8,opt,name=namespace" json:"namespace,omitempty"`
 }
+
+// RemoteQueue 远程消息队列配置, 用于把rpc, wallet等模块放到独立进程中运行
+type RemoteQueue struct {
+	// 核心节点远程队列服务监听地址, 为空则不开启服务
+	ListenAddr string `protobuf:"bytes,1,opt,name=listenAddr" json:"listenAddr,omitempty"`
+	// 模块进程连接的核心节点地址
+	ServerAddr string `protobuf:"bytes,2,opt,name=serverAddr" json:"serverAddr,omitempty"`
+	// 核心节点和模块进程之间的共享密钥
+	Secret string `protobuf:"bytes,3,opt,name=secret" json:"secret,omitempty"`
+	// 放到独立进程中运行的模块, 支持mempool, wallet, rpc, 核心节点不再加载这些模块
+	Modules []string `protobuf:"bytes,4,rep,name=modules" json:"modules,omitempty"`
+	// 断线重连间隔，单位毫秒，默认1000
+	ReconnectInterval int64 `protobuf:"varint,5,opt,name=reconnectInterval" json:"reconnectInterval,omitempty"`
+	// 是否使用TLS连接, 核心节点和模块进程需要一致
+	EnableTLS bool `protobuf:"varint,6,opt,name=enableTLS" json:"enableTLS,omitempty"`
+	// 证书文件, 核心节点为服务端证书(必须), 模块进程为客户端证书(核心节点配置了caFile时必须)
+	CertFile string `protobuf:"bytes,7,opt,name=certFile" json:"certFile,omitempty"`
+	// 证书对应的私钥文件
+	KeyFile string `protobuf:"bytes,8,opt,name=keyFile" json:"keyFile,omitempty"`
+	// CA证书文件, 核心节点用于校验模块进程的客户端证书, 模块进程用于校验核心节点的证书, 为空时使用系统CA
+	CAFile string `protobuf:"bytes,9,opt,name=caFile" json:"caFile,omitempty"`
+	// 没有开启TLS时, 是否允许在本机回环地址上明文传输共享密钥, 默认不允许
+	AllowInsecureLocal bool `protobuf:"varint,10,opt,name=allowInsecureLocal" json:"allowInsecureLocal,omitempty"`
+}
+
+// Trace 区块处理路径的span追踪配置
+type Trace struct {
+	// 是否开启追踪, 关闭时几乎没有额外开销
+	Enable bool `protobuf:"varint,1,opt,name=enable" json:"enable,omitempty"`
+	// 新建trace的采样比例, 取值(0, 1], 默认1
+	SampleRate float64 `protobuf:"fixed64,2,opt,name=sampleRate" json:"sampleRate,omitempty"`
+	// 导出方式, otlp: 以OTLP/HTTP发送到collector, file: 以json lines写入文件
+	Exporter string `protobuf:"bytes,3,opt,name=exporter" json:"exporter,omitempty"`
+	// otlp collector地址, 默认 http://localhost:4318/v1/traces
+	Endpoint string `protobuf:"bytes,4,opt,name=endpoint" json:"endpoint,omitempty"`
+	// file 导出的文件路径, 默认 logs/trace.json
+	FilePath string `protobuf:"bytes,5,opt,name=filePath" json:"filePath,omitempty"`
+	// 上报的服务名称, 默认chain33
+	ServiceName string `protobuf:"bytes,6,opt,name=serviceName" json:"serviceName,omitempty"`
+}
@@ -126,6 +126,18 @@ const (
 	ExecOk   = 2
 )
 
+//p2p节点行为类型, 用于节点评分
+const (
+	PeerGoodMsg            = 0 //正常提供数据
+	PeerFaultInvalidBlock  = 1 //区块校验或执行失败
+	PeerFaultInvalidTx     = 2 //交易校验失败
+	PeerFaultTimeout       = 3 //请求超时
+	PeerFaultProtocol      = 4 //违反协议, 如消息无法解析
+	PeerFaultLatency       = 5 //时延过高
+	PeerFaultRateLimit     = 6 //请求频率超过限制
+	PeerFaultInvalidHeader = 7 //区块头校验失败或者与检查点冲突
+)
+
 // TODO 后续调试确认放的位置
 //func init() {
 //	S("TxHeight", false)
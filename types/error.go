@@ -105,6 +105,8 @@ var (
 	ErrDecode                 = errors.New("ErrDecode")
 	ErrNotRollBack            = errors.New("ErrNotRollBack")
 	ErrPeerInfoIsNil          = errors.New("ErrPeerInfoIsNil")
+	ErrCheckpointMismatch     = errors.New("ErrCheckpointMismatch")
+	ErrForkBelowCheckpoint    = errors.New("ErrForkBelowCheckpoint")
 	//ErrWalletIsLocked wallet
 	ErrWalletIsLocked       = errors.New("ErrWalletIsLocked")
 	ErrSaveSeedFirst        = errors.New("ErrSaveSeedFirst")
@@ -195,4 +197,12 @@ var (
 	ErrMaxCountPerTime   = errors.New("ErrMaxCountPerTime")
 	ErrInValidFileHeader = errors.New("ErrInValidFileHeader")
 	ErrFileExists        = errors.New("ErrFileExists")
+	//ErrStatePruned 历史状态已经被裁剪, 不能在该状态上查询
+	ErrStatePruned = errors.New("ErrStatePruned")
+	//ErrLocalDBPruned 历史高度的localdb多版本数据已经被回收
+	ErrLocalDBPruned = errors.New("ErrLocalDBPruned")
+	//ErrBackupInProgress 已经有正在进行的数据库备份
+	ErrBackupInProgress = errors.New("ErrBackupInProgress")
+	//ErrBackupCorrupt 备份数据和清单不一致
+	ErrBackupCorrupt = errors.New("ErrBackupCorrupt")
 )
@@ -132,6 +132,11 @@ const (
 
 	EventReExecBlock  = 142
 	EventTxListByHash = 143
+	//p2p节点评分及封禁
+	EventReportFaultPeer = 144
+	EventBanPeer         = 145
+	EventUnbanPeer       = 146
+	EventGetBannedPeers  = 147
 	//exec
 	EventBlockChainQuery = 212
 	EventConsensusQuery  = 213
@@ -157,6 +162,16 @@ const (
 	EventGetParaTxByTitleAndHeight = 310
 	//比较当前区块和新广播的区块最优区块
 	EventCmpBestBlock = 311
+	//获取区块同步进度
+	EventGetSyncProgress = 312
+	//获取mavl状态裁剪进度
+	EventStorePruneStatus = 313
+	//暂停或者恢复mavl状态裁剪
+	EventStorePrunePause = 314
+	//在线备份全部模块的数据库
+	EventBackupDB = 315
+	//blockchain通知各模块获取数据库快照以及写入备份
+	EventSnapshotDB = 316
 )
 
 var eventName = map[int]string{
@@ -283,6 +298,11 @@ var eventName = map[int]string{
 	EventGetProperFee:   "EventGetProperFee",
 	EventReplyProperFee: "EventReplyProperFee",
 	EventTxListByHash:   "EventTxListByHash",
+	// p2p
+	EventReportFaultPeer: "EventReportFaultPeer",
+	EventBanPeer:         "EventBanPeer",
+	EventUnbanPeer:       "EventUnbanPeer",
+	EventGetBannedPeers:  "EventGetBannedPeers",
 	// block chain
 	EventGetLastBlockMainSequence:   "EventGetLastBlockMainSequence",
 	EventReplyLastBlockMainSequence: "EventReplyLastBlockMainSequence",
@@ -296,5 +316,10 @@ var eventName = map[int]string{
 	EventReplyHeightByTitle:         "EventReplyHeightByTitle",
 	EventGetParaTxByTitleAndHeight:  "EventGetParaTxByTitleAndHeight",
 	EventCmpBestBlock:               "EventCmpBestBlock",
+	EventGetSyncProgress:            "EventGetSyncProgress",
+	EventStorePruneStatus:           "EventStorePruneStatus",
+	EventStorePrunePause:            "EventStorePrunePause",
+	EventBackupDB:                   "EventBackupDB",
+	EventSnapshotDB:                 "EventSnapshotDB",
 	EventUpgrade:                    "EventUpgrade",
 }
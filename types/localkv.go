@@ -14,6 +14,7 @@ var (
 	LocalPrefix            = []byte("LODB")
 	FlagTxQuickIndex       = []byte("FLAG:FlagTxQuickIndex")
 	FlagKeyMVCC            = []byte("FLAG:keyMVCCFlag")
+	FlagKeyLocalMVCC       = []byte("FLAG:keyLocalMVCCFlag")
 	TxHashPerfix           = []byte("TX:")
 	TxShortHashPerfix      = []byte("STX:")
 	TxAddrHash             = []byte("TxAddrHash:")
@@ -22,12 +23,13 @@ var (
 	ConsensusParaTxsPrefix = []byte("LODBP:Consensus:Para:")            //存贮para共识模块从主链拉取的平行链交易
 	FlagReduceLocaldb      = []byte("FLAG:ReduceLocaldb")               // 精简版localdb标记
 	ReduceLocaldbHeight    = append(FlagReduceLocaldb, []byte(":H")...) // 精简版localdb高度
+	LocalMVCCTrashHeight   = []byte("FLAG:LocalMVCCTrash:H")            // localdb多版本数据已经回收的高度
 )
 
 // GetLocalDBKeyList 获取localdb的key列表
 func GetLocalDBKeyList() [][]byte {
 	return [][]byte{
-		FlagTxQuickIndex, FlagKeyMVCC, TxHashPerfix, TxShortHashPerfix, FlagReduceLocaldb,
+		FlagTxQuickIndex, FlagKeyMVCC, TxHashPerfix, TxShortHashPerfix, FlagReduceLocaldb, FlagKeyLocalMVCC,
 	}
 }
 
@@ -0,0 +1,266 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: localmvcc.proto
+
+package types
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// 执行器localdb中一个key在某个区块高度的版本
+type KeyVersion struct {
+	Height int64  `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	Value  []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// 该高度删除了这个key
+	Deleted              bool     `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyVersion) Reset()         { *m = KeyVersion{} }
+func (m *KeyVersion) String() string { return proto.CompactTextString(m) }
+func (*KeyVersion) ProtoMessage()    {}
+func (*KeyVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1929d50648f7a48e, []int{0}
+}
+
+func (m *KeyVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyVersion.Unmarshal(m, b)
+}
+func (m *KeyVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyVersion.Marshal(b, m, deterministic)
+}
+func (m *KeyVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyVersion.Merge(m, src)
+}
+func (m *KeyVersion) XXX_Size() int {
+	return xxx_messageInfo_KeyVersion.Size(m)
+}
+func (m *KeyVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyVersion proto.InternalMessageInfo
+
+func (m *KeyVersion) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *KeyVersion) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *KeyVersion) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+// 查询localdb中key的历史版本, 按照高度从小到大返回
+// 翻页时把fromHeight设置成上一页最后一个版本的高度加1
+type ReqKeyHistory struct {
+	Key        []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	FromHeight int64  `protobuf:"varint,2,opt,name=fromHeight,proto3" json:"fromHeight,omitempty"`
+	// 小于0表示查询到最新高度
+	ToHeight int64 `protobuf:"varint,3,opt,name=toHeight,proto3" json:"toHeight,omitempty"`
+	// 一次最多返回的版本个数, 0表示使用默认的最大值
+	Count                int32    `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReqKeyHistory) Reset()         { *m = ReqKeyHistory{} }
+func (m *ReqKeyHistory) String() string { return proto.CompactTextString(m) }
+func (*ReqKeyHistory) ProtoMessage()    {}
+func (*ReqKeyHistory) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1929d50648f7a48e, []int{1}
+}
+
+func (m *ReqKeyHistory) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReqKeyHistory.Unmarshal(m, b)
+}
+func (m *ReqKeyHistory) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReqKeyHistory.Marshal(b, m, deterministic)
+}
+func (m *ReqKeyHistory) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReqKeyHistory.Merge(m, src)
+}
+func (m *ReqKeyHistory) XXX_Size() int {
+	return xxx_messageInfo_ReqKeyHistory.Size(m)
+}
+func (m *ReqKeyHistory) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReqKeyHistory.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReqKeyHistory proto.InternalMessageInfo
+
+func (m *ReqKeyHistory) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *ReqKeyHistory) GetFromHeight() int64 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
+func (m *ReqKeyHistory) GetToHeight() int64 {
+	if m != nil {
+		return m.ToHeight
+	}
+	return 0
+}
+
+func (m *ReqKeyHistory) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type ReplyKeyHistory struct {
+	Versions             []*KeyVersion `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ReplyKeyHistory) Reset()         { *m = ReplyKeyHistory{} }
+func (m *ReplyKeyHistory) String() string { return proto.CompactTextString(m) }
+func (*ReplyKeyHistory) ProtoMessage()    {}
+func (*ReplyKeyHistory) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1929d50648f7a48e, []int{2}
+}
+
+func (m *ReplyKeyHistory) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReplyKeyHistory.Unmarshal(m, b)
+}
+func (m *ReplyKeyHistory) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReplyKeyHistory.Marshal(b, m, deterministic)
+}
+func (m *ReplyKeyHistory) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReplyKeyHistory.Merge(m, src)
+}
+func (m *ReplyKeyHistory) XXX_Size() int {
+	return xxx_messageInfo_ReplyKeyHistory.Size(m)
+}
+func (m *ReplyKeyHistory) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReplyKeyHistory.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReplyKeyHistory proto.InternalMessageInfo
+
+func (m *ReplyKeyHistory) GetVersions() []*KeyVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+// 放在ChainExecutor的extra中, 开启localmvcc时在历史高度上查询localdb
+type LocalDBHeight struct {
+	// 总是为true, 区分0高度和没有指定高度
+	Enable               bool     `protobuf:"varint,1,opt,name=enable,proto3" json:"enable,omitempty"`
+	Height               int64    `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LocalDBHeight) Reset()         { *m = LocalDBHeight{} }
+func (m *LocalDBHeight) String() string { return proto.CompactTextString(m) }
+func (*LocalDBHeight) ProtoMessage()    {}
+func (*LocalDBHeight) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1929d50648f7a48e, []int{3}
+}
+
+func (m *LocalDBHeight) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LocalDBHeight.Unmarshal(m, b)
+}
+func (m *LocalDBHeight) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LocalDBHeight.Marshal(b, m, deterministic)
+}
+func (m *LocalDBHeight) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LocalDBHeight.Merge(m, src)
+}
+func (m *LocalDBHeight) XXX_Size() int {
+	return xxx_messageInfo_LocalDBHeight.Size(m)
+}
+func (m *LocalDBHeight) XXX_DiscardUnknown() {
+	xxx_messageInfo_LocalDBHeight.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LocalDBHeight proto.InternalMessageInfo
+
+func (m *LocalDBHeight) GetEnable() bool {
+	if m != nil {
+		return m.Enable
+	}
+	return false
+}
+
+func (m *LocalDBHeight) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*KeyVersion)(nil), "types.KeyVersion")
+	proto.RegisterType((*ReqKeyHistory)(nil), "types.ReqKeyHistory")
+	proto.RegisterType((*ReplyKeyHistory)(nil), "types.ReplyKeyHistory")
+	proto.RegisterType((*LocalDBHeight)(nil), "types.LocalDBHeight")
+}
+
+func init() {
+	proto.RegisterFile("localmvcc.proto", fileDescriptor_1929d50648f7a48e)
+}
+
+var fileDescriptor_1929d50648f7a48e = []byte{
+	// 278 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0x51, 0x4f, 0xb3, 0x30,
+	0x14, 0x86, 0xd3, 0xf1, 0x8d, 0x8f, 0x1c, 0xb7, 0x4c, 0x1b, 0xb3, 0x34, 0x26, 0x2a, 0xe1, 0x8a,
+	0x1b, 0x21, 0x91, 0x1f, 0xa0, 0x59, 0xbc, 0x58, 0x32, 0xaf, 0x1a, 0xe3, 0x85, 0x77, 0xd0, 0x1d,
+	0x07, 0xb1, 0x50, 0x84, 0x42, 0xd2, 0x7f, 0x6f, 0x28, 0x38, 0xb9, 0xeb, 0xd3, 0xd3, 0xf4, 0x39,
+	0xef, 0x0b, 0x1b, 0xa9, 0x44, 0x2a, 0xcb, 0x5e, 0x88, 0xa8, 0x6e, 0x94, 0x56, 0x74, 0xa9, 0x4d,
+	0x8d, 0x6d, 0xf0, 0x06, 0x70, 0x40, 0xf3, 0x8e, 0x4d, 0x5b, 0xa8, 0x8a, 0x6e, 0xc1, 0xcd, 0xb1,
+	0x38, 0xe5, 0x9a, 0x11, 0x9f, 0x84, 0x0e, 0x9f, 0x88, 0x5e, 0xc3, 0xb2, 0x4f, 0x65, 0x87, 0x6c,
+	0xe1, 0x93, 0x70, 0xc5, 0x47, 0xa0, 0x0c, 0xfe, 0x1f, 0x51, 0xa2, 0xc6, 0x23, 0x73, 0x7c, 0x12,
+	0x7a, 0xfc, 0x17, 0x83, 0x16, 0xd6, 0x1c, 0xbf, 0x0f, 0x68, 0xf6, 0x45, 0xab, 0x55, 0x63, 0xe8,
+	0x25, 0x38, 0x5f, 0x68, 0xec, 0xaf, 0x2b, 0x3e, 0x1c, 0xe9, 0x1d, 0xc0, 0x67, 0xa3, 0xca, 0xfd,
+	0xa8, 0x5b, 0x58, 0xdd, 0xec, 0x86, 0xde, 0x80, 0xa7, 0xd5, 0x34, 0x75, 0xec, 0xf4, 0xcc, 0xc3,
+	0x3a, 0x42, 0x75, 0x95, 0x66, 0xff, 0x7c, 0x12, 0x2e, 0xf9, 0x08, 0xc1, 0x33, 0x6c, 0x38, 0xd6,
+	0xd2, 0xcc, 0xb4, 0x0f, 0xe0, 0xf5, 0x63, 0xb4, 0x96, 0x11, 0xdf, 0x09, 0x2f, 0x1e, 0xaf, 0x22,
+	0x9b, 0x3b, 0xfa, 0x0b, 0xcd, 0xcf, 0x4f, 0x82, 0x27, 0x58, 0xbf, 0x0e, 0x35, 0xbd, 0xec, 0x26,
+	0xd1, 0x16, 0x5c, 0xac, 0xd2, 0x4c, 0xa2, 0xdd, 0xdc, 0xe3, 0x13, 0xcd, 0x7a, 0x5a, 0xcc, 0x7b,
+	0xda, 0xdd, 0x7f, 0xdc, 0x9e, 0x0a, 0x9d, 0x77, 0x59, 0x24, 0x54, 0x19, 0x27, 0x89, 0xa8, 0x62,
+	0x91, 0xa7, 0x45, 0x95, 0x24, 0xb1, 0xd5, 0x66, 0xae, 0x2d, 0x3f, 0xf9, 0x19, 0x00, 0x53, 0xd4,
+	0x0f, 0x24, 0x8f, 0x01, 0x00, 0x00,
+}
@@ -1048,6 +1048,130 @@ func (m *LightBlock) GetSTxHashes() []string {
 	return nil
 }
 
+//*
+// p2p 紧凑区块, 交易采用加盐的短id广播, 预测对端缺失的交易直接附带
+type CompactBlock struct {
+	Size   int64   `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Header *Header `protobuf:"bytes,2,opt,name=header,proto3" json:"header,omitempty"`
+	// 短id的盐, 与区块哈希一起生成siphash密钥, 避免针对性构造碰撞
+	Nonce uint64 `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// 未预填充交易的短id(6字节), 按区块内交易顺序排列
+	ShortIDs             []uint64       `protobuf:"varint,4,rep,packed,name=shortIDs,proto3" json:"shortIDs,omitempty"`
+	PrefilledTxs         []*PrefilledTx `protobuf:"bytes,5,rep,name=prefilledTxs,proto3" json:"prefilledTxs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *CompactBlock) Reset()         { *m = CompactBlock{} }
+func (m *CompactBlock) String() string { return proto.CompactTextString(m) }
+func (*CompactBlock) ProtoMessage()    {}
+func (*CompactBlock) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e7fdddb109e6467a, []int{19}
+}
+
+func (m *CompactBlock) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactBlock.Unmarshal(m, b)
+}
+func (m *CompactBlock) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactBlock.Marshal(b, m, deterministic)
+}
+func (m *CompactBlock) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactBlock.Merge(m, src)
+}
+func (m *CompactBlock) XXX_Size() int {
+	return xxx_messageInfo_CompactBlock.Size(m)
+}
+func (m *CompactBlock) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactBlock.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactBlock proto.InternalMessageInfo
+
+func (m *CompactBlock) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *CompactBlock) GetHeader() *Header {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *CompactBlock) GetNonce() uint64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *CompactBlock) GetShortIDs() []uint64 {
+	if m != nil {
+		return m.ShortIDs
+	}
+	return nil
+}
+
+func (m *CompactBlock) GetPrefilledTxs() []*PrefilledTx {
+	if m != nil {
+		return m.PrefilledTxs
+	}
+	return nil
+}
+
+// 紧凑区块中预填充的完整交易
+type PrefilledTx struct {
+	// 交易在区块内的索引
+	Index                int32        `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Tx                   *Transaction `protobuf:"bytes,2,opt,name=tx,proto3" json:"tx,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *PrefilledTx) Reset()         { *m = PrefilledTx{} }
+func (m *PrefilledTx) String() string { return proto.CompactTextString(m) }
+func (*PrefilledTx) ProtoMessage()    {}
+func (*PrefilledTx) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e7fdddb109e6467a, []int{20}
+}
+
+func (m *PrefilledTx) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrefilledTx.Unmarshal(m, b)
+}
+func (m *PrefilledTx) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrefilledTx.Marshal(b, m, deterministic)
+}
+func (m *PrefilledTx) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrefilledTx.Merge(m, src)
+}
+func (m *PrefilledTx) XXX_Size() int {
+	return xxx_messageInfo_PrefilledTx.Size(m)
+}
+func (m *PrefilledTx) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrefilledTx.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrefilledTx proto.InternalMessageInfo
+
+func (m *PrefilledTx) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *PrefilledTx) GetTx() *Transaction {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
 // 轻量级交易广播
 type LightTx struct {
 	TxHash               []byte    `protobuf:"bytes,1,opt,name=txHash,proto3" json:"txHash,omitempty"`
@@ -1061,7 +1185,7 @@ func (m *LightTx) Reset()         { *m = LightTx{} }
 func (m *LightTx) String() string { return proto.CompactTextString(m) }
 func (*LightTx) ProtoMessage()    {}
 func (*LightTx) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{19}
+	return fileDescriptor_e7fdddb109e6467a, []int{21}
 }
 
 func (m *LightTx) XXX_Unmarshal(b []byte) error {
@@ -1108,7 +1232,7 @@ func (m *P2PTxReq) Reset()         { *m = P2PTxReq{} }
 func (m *P2PTxReq) String() string { return proto.CompactTextString(m) }
 func (*P2PTxReq) ProtoMessage()    {}
 func (*P2PTxReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{20}
+	return fileDescriptor_e7fdddb109e6467a, []int{22}
 }
 
 func (m *P2PTxReq) XXX_Unmarshal(b []byte) error {
@@ -1149,7 +1273,7 @@ func (m *P2PBlockTxReq) Reset()         { *m = P2PBlockTxReq{} }
 func (m *P2PBlockTxReq) String() string { return proto.CompactTextString(m) }
 func (*P2PBlockTxReq) ProtoMessage()    {}
 func (*P2PBlockTxReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{21}
+	return fileDescriptor_e7fdddb109e6467a, []int{23}
 }
 
 func (m *P2PBlockTxReq) XXX_Unmarshal(b []byte) error {
@@ -1198,7 +1322,7 @@ func (m *P2PBlockTxReply) Reset()         { *m = P2PBlockTxReply{} }
 func (m *P2PBlockTxReply) String() string { return proto.CompactTextString(m) }
 func (*P2PBlockTxReply) ProtoMessage()    {}
 func (*P2PBlockTxReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{22}
+	return fileDescriptor_e7fdddb109e6467a, []int{24}
 }
 
 func (m *P2PBlockTxReply) XXX_Unmarshal(b []byte) error {
@@ -1257,7 +1381,7 @@ func (m *P2PQueryData) Reset()         { *m = P2PQueryData{} }
 func (m *P2PQueryData) String() string { return proto.CompactTextString(m) }
 func (*P2PQueryData) ProtoMessage()    {}
 func (*P2PQueryData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{23}
+	return fileDescriptor_e7fdddb109e6467a, []int{25}
 }
 
 func (m *P2PQueryData) XXX_Unmarshal(b []byte) error {
@@ -1338,7 +1462,7 @@ func (m *Versions) Reset()         { *m = Versions{} }
 func (m *Versions) String() string { return proto.CompactTextString(m) }
 func (*Versions) ProtoMessage()    {}
 func (*Versions) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{24}
+	return fileDescriptor_e7fdddb109e6467a, []int{26}
 }
 
 func (m *Versions) XXX_Unmarshal(b []byte) error {
@@ -1392,6 +1516,7 @@ type BroadCastData struct {
 	//	*BroadCastData_LtBlock
 	//	*BroadCastData_Query
 	//	*BroadCastData_BlockRep
+	//	*BroadCastData_CompactBlock
 	Value                isBroadCastData_Value `protobuf_oneof:"value"`
 	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
 	XXX_unrecognized     []byte                `json:"-"`
@@ -1402,7 +1527,7 @@ func (m *BroadCastData) Reset()         { *m = BroadCastData{} }
 func (m *BroadCastData) String() string { return proto.CompactTextString(m) }
 func (*BroadCastData) ProtoMessage()    {}
 func (*BroadCastData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{25}
+	return fileDescriptor_e7fdddb109e6467a, []int{27}
 }
 
 func (m *BroadCastData) XXX_Unmarshal(b []byte) error {
@@ -1459,6 +1584,10 @@ type BroadCastData_BlockRep struct {
 	BlockRep *P2PBlockTxReply `protobuf:"bytes,8,opt,name=blockRep,proto3,oneof"`
 }
 
+type BroadCastData_CompactBlock struct {
+	CompactBlock *CompactBlock `protobuf:"bytes,9,opt,name=compactBlock,proto3,oneof"`
+}
+
 func (*BroadCastData_Tx) isBroadCastData_Value() {}
 
 func (*BroadCastData_Block) isBroadCastData_Value() {}
@@ -1475,6 +1604,8 @@ func (*BroadCastData_Query) isBroadCastData_Value() {}
 
 func (*BroadCastData_BlockRep) isBroadCastData_Value() {}
 
+func (*BroadCastData_CompactBlock) isBroadCastData_Value() {}
+
 func (m *BroadCastData) GetValue() isBroadCastData_Value {
 	if m != nil {
 		return m.Value
@@ -1538,6 +1669,13 @@ func (m *BroadCastData) GetBlockRep() *P2PBlockTxReply {
 	return nil
 }
 
+func (m *BroadCastData) GetCompactBlock() *CompactBlock {
+	if x, ok := m.GetValue().(*BroadCastData_CompactBlock); ok {
+		return x.CompactBlock
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*BroadCastData) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
@@ -1549,6 +1687,7 @@ func (*BroadCastData) XXX_OneofWrappers() []interface{} {
 		(*BroadCastData_LtBlock)(nil),
 		(*BroadCastData_Query)(nil),
 		(*BroadCastData_BlockRep)(nil),
+		(*BroadCastData_CompactBlock)(nil),
 	}
 }
 
@@ -1567,7 +1706,7 @@ func (m *P2PGetHeaders) Reset()         { *m = P2PGetHeaders{} }
 func (m *P2PGetHeaders) String() string { return proto.CompactTextString(m) }
 func (*P2PGetHeaders) ProtoMessage()    {}
 func (*P2PGetHeaders) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{26}
+	return fileDescriptor_e7fdddb109e6467a, []int{28}
 }
 
 func (m *P2PGetHeaders) XXX_Unmarshal(b []byte) error {
@@ -1622,7 +1761,7 @@ func (m *P2PHeaders) Reset()         { *m = P2PHeaders{} }
 func (m *P2PHeaders) String() string { return proto.CompactTextString(m) }
 func (*P2PHeaders) ProtoMessage()    {}
 func (*P2PHeaders) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{27}
+	return fileDescriptor_e7fdddb109e6467a, []int{29}
 }
 
 func (m *P2PHeaders) XXX_Unmarshal(b []byte) error {
@@ -1667,7 +1806,7 @@ func (m *InvData) Reset()         { *m = InvData{} }
 func (m *InvData) String() string { return proto.CompactTextString(m) }
 func (*InvData) ProtoMessage()    {}
 func (*InvData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{28}
+	return fileDescriptor_e7fdddb109e6467a, []int{30}
 }
 
 func (m *InvData) XXX_Unmarshal(b []byte) error {
@@ -1753,7 +1892,7 @@ func (m *InvDatas) Reset()         { *m = InvDatas{} }
 func (m *InvDatas) String() string { return proto.CompactTextString(m) }
 func (*InvDatas) ProtoMessage()    {}
 func (*InvDatas) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{29}
+	return fileDescriptor_e7fdddb109e6467a, []int{31}
 }
 
 func (m *InvDatas) XXX_Unmarshal(b []byte) error {
@@ -1799,7 +1938,7 @@ func (m *Peer) Reset()         { *m = Peer{} }
 func (m *Peer) String() string { return proto.CompactTextString(m) }
 func (*Peer) ProtoMessage()    {}
 func (*Peer) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{30}
+	return fileDescriptor_e7fdddb109e6467a, []int{32}
 }
 
 func (m *Peer) XXX_Unmarshal(b []byte) error {
@@ -1875,7 +2014,7 @@ func (m *PeerList) Reset()         { *m = PeerList{} }
 func (m *PeerList) String() string { return proto.CompactTextString(m) }
 func (*PeerList) ProtoMessage()    {}
 func (*PeerList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{31}
+	return fileDescriptor_e7fdddb109e6467a, []int{33}
 }
 
 func (m *PeerList) XXX_Unmarshal(b []byte) error {
@@ -1916,7 +2055,7 @@ func (m *P2PGetPeerReq) Reset()         { *m = P2PGetPeerReq{} }
 func (m *P2PGetPeerReq) String() string { return proto.CompactTextString(m) }
 func (*P2PGetPeerReq) ProtoMessage()    {}
 func (*P2PGetPeerReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{32}
+	return fileDescriptor_e7fdddb109e6467a, []int{34}
 }
 
 func (m *P2PGetPeerReq) XXX_Unmarshal(b []byte) error {
@@ -1957,7 +2096,7 @@ func (m *P2PGetNetInfoReq) Reset()         { *m = P2PGetNetInfoReq{} }
 func (m *P2PGetNetInfoReq) String() string { return proto.CompactTextString(m) }
 func (*P2PGetNetInfoReq) ProtoMessage()    {}
 func (*P2PGetNetInfoReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{33}
+	return fileDescriptor_e7fdddb109e6467a, []int{35}
 }
 
 func (m *P2PGetNetInfoReq) XXX_Unmarshal(b []byte) error {
@@ -1993,6 +2132,7 @@ type NodeNetInfo struct {
 	Service              bool     `protobuf:"varint,3,opt,name=service,proto3" json:"service,omitempty"`
 	Outbounds            int32    `protobuf:"varint,4,opt,name=outbounds,proto3" json:"outbounds,omitempty"`
 	Inbounds             int32    `protobuf:"varint,5,opt,name=inbounds,proto3" json:"inbounds,omitempty"`
+	Reachability         string   `protobuf:"bytes,6,opt,name=reachability,proto3" json:"reachability,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -2002,7 +2142,7 @@ func (m *NodeNetInfo) Reset()         { *m = NodeNetInfo{} }
 func (m *NodeNetInfo) String() string { return proto.CompactTextString(m) }
 func (*NodeNetInfo) ProtoMessage()    {}
 func (*NodeNetInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{34}
+	return fileDescriptor_e7fdddb109e6467a, []int{36}
 }
 
 func (m *NodeNetInfo) XXX_Unmarshal(b []byte) error {
@@ -2058,6 +2198,13 @@ func (m *NodeNetInfo) GetInbounds() int32 {
 	return 0
 }
 
+func (m *NodeNetInfo) GetReachability() string {
+	if m != nil {
+		return m.Reachability
+	}
+	return ""
+}
+
 type PeersReply struct {
 	Peers                []*PeersInfo `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
@@ -2069,7 +2216,7 @@ func (m *PeersReply) Reset()         { *m = PeersReply{} }
 func (m *PeersReply) String() string { return proto.CompactTextString(m) }
 func (*PeersReply) ProtoMessage()    {}
 func (*PeersReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{35}
+	return fileDescriptor_e7fdddb109e6467a, []int{37}
 }
 
 func (m *PeersReply) XXX_Unmarshal(b []byte) error {
@@ -2112,7 +2259,7 @@ func (m *PeersInfo) Reset()         { *m = PeersInfo{} }
 func (m *PeersInfo) String() string { return proto.CompactTextString(m) }
 func (*PeersInfo) ProtoMessage()    {}
 func (*PeersInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e7fdddb109e6467a, []int{36}
+	return fileDescriptor_e7fdddb109e6467a, []int{38}
 }
 
 func (m *PeersInfo) XXX_Unmarshal(b []byte) error {
@@ -2188,6 +2335,8 @@ func init() {
 	proto.RegisterType((*P2PTx)(nil), "types.P2PTx")
 	proto.RegisterType((*P2PBlock)(nil), "types.P2PBlock")
 	proto.RegisterType((*LightBlock)(nil), "types.LightBlock")
+	proto.RegisterType((*CompactBlock)(nil), "types.CompactBlock")
+	proto.RegisterType((*PrefilledTx)(nil), "types.PrefilledTx")
 	proto.RegisterType((*LightTx)(nil), "types.LightTx")
 	proto.RegisterType((*P2PTxReq)(nil), "types.P2PTxReq")
 	proto.RegisterType((*P2PBlockTxReq)(nil), "types.P2PBlockTxReq")
@@ -2213,107 +2362,113 @@ func init() {
 }
 
 var fileDescriptor_e7fdddb109e6467a = []byte{
-	// 1593 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xcb, 0x6e, 0x1b, 0xbd,
-	0x15, 0x1e, 0xdd, 0x2c, 0xe9, 0xc8, 0x57, 0x26, 0x0d, 0x04, 0xc1, 0x4d, 0x52, 0xc2, 0x69, 0xdc,
-	0x26, 0x51, 0x92, 0x71, 0x9a, 0x02, 0x4d, 0x37, 0x76, 0xda, 0x46, 0x46, 0xdd, 0x60, 0x4a, 0xab,
-	0x5d, 0x74, 0x37, 0x96, 0x68, 0x69, 0x10, 0x89, 0x33, 0x9e, 0xa1, 0x04, 0x29, 0xfb, 0xee, 0xba,
-	0x6a, 0x1f, 0xa0, 0x8b, 0xbe, 0x5e, 0x1e, 0xa2, 0xe0, 0x21, 0x39, 0x17, 0x49, 0x16, 0x7e, 0xfc,
-	0xc1, 0xbf, 0x1b, 0x9e, 0x1b, 0xcf, 0xf5, 0xe3, 0x91, 0xa0, 0x19, 0xb9, 0x51, 0x37, 0x8a, 0x43,
-	0x19, 0x92, 0x9a, 0x5c, 0x46, 0x3c, 0xe9, 0x1c, 0xc9, 0xd8, 0x17, 0x89, 0x3f, 0x90, 0x41, 0x28,
-	0x34, 0xa7, 0xb3, 0x3b, 0x08, 0xa7, 0xd3, 0xf4, 0x74, 0x78, 0x33, 0x09, 0x07, 0x5f, 0x06, 0x63,
-	0x3f, 0x30, 0x14, 0xfa, 0x6b, 0xd8, 0xf7, 0x5c, 0xef, 0x13, 0x97, 0x1e, 0xe7, 0xf1, 0xa5, 0xb8,
-	0x0d, 0x49, 0x1b, 0xea, 0x73, 0x1e, 0x27, 0x41, 0x28, 0xda, 0xa5, 0xa7, 0xa5, 0xd3, 0x1a, 0xb3,
-	0x47, 0xfa, 0xef, 0x12, 0xb4, 0x3c, 0xd7, 0x4b, 0x25, 0x09, 0x54, 0xfd, 0xe1, 0x30, 0x46, 0xb1,
-	0x26, 0xc3, 0x6f, 0x45, 0x8b, 0xc2, 0x58, 0xb6, 0xcb, 0xa8, 0x8a, 0xdf, 0x8a, 0x26, 0xfc, 0x29,
-	0x6f, 0x57, 0xb4, 0x9c, 0xfa, 0x26, 0x4f, 0xa1, 0x35, 0xe5, 0xd3, 0x28, 0x0c, 0x27, 0xd7, 0xc1,
-	0x57, 0xde, 0xae, 0xa2, 0x78, 0x9e, 0x44, 0x9e, 0xc1, 0xce, 0x98, 0xfb, 0x43, 0x1e, 0xb7, 0x6b,
-	0x4f, 0x4b, 0xa7, 0x2d, 0x77, 0xaf, 0x8b, 0x41, 0x76, 0x7b, 0x48, 0x64, 0x86, 0x49, 0xbf, 0x95,
-	0x00, 0x3c, 0xd7, 0xfb, 0xbb, 0xf6, 0xf1, 0x7e, 0xef, 0x15, 0x27, 0xe1, 0xf1, 0x3c, 0x18, 0x70,
-	0x74, 0xae, 0xc2, 0xec, 0x91, 0x1c, 0x43, 0x53, 0x06, 0x53, 0x9e, 0x48, 0x7f, 0x1a, 0xa1, 0x93,
-	0x15, 0x96, 0x11, 0x48, 0x07, 0x1a, 0x2a, 0x32, 0xc6, 0x07, 0x73, 0x74, 0xb3, 0xc9, 0xd2, 0xb3,
-	0xe5, 0xfd, 0x29, 0x0e, 0xa7, 0xe8, 0xa5, 0xe1, 0xa9, 0x33, 0x79, 0x08, 0x35, 0x11, 0x8a, 0x01,
-	0x6f, 0xef, 0xa0, 0x45, 0x7d, 0x50, 0x77, 0xcd, 0x12, 0x1e, 0x9f, 0x8f, 0xb8, 0x90, 0xed, 0x3a,
-	0xaa, 0x64, 0x04, 0x95, 0x95, 0x44, 0xfa, 0xb1, 0xec, 0xf1, 0x60, 0x34, 0x96, 0xed, 0x06, 0x6a,
-	0xe6, 0x49, 0xf4, 0x6f, 0xd0, 0xd4, 0xd1, 0x9e, 0x0f, 0xbe, 0xfc, 0xa8, 0x60, 0x53, 0xb7, 0x2a,
-	0x39, 0xb7, 0xe8, 0x14, 0xea, 0xaa, 0xb2, 0x81, 0x18, 0x65, 0x02, 0xa5, 0xbc, 0xdf, 0xb6, 0xd6,
-	0xe5, 0x0d, 0xb5, 0xae, 0xe4, 0x6a, 0x7d, 0x02, 0xd5, 0x24, 0x18, 0x09, 0xcc, 0x54, 0xcb, 0x3d,
-	0x34, 0x35, 0xbb, 0x0e, 0x46, 0xc2, 0x97, 0xb3, 0x98, 0x33, 0xe4, 0xd2, 0x27, 0xfa, 0xba, 0xf0,
-	0xbe, 0xeb, 0x28, 0xc5, 0xa2, 0x7e, 0xe2, 0xf2, 0x5c, 0x5d, 0xb4, 0x59, 0xe6, 0x03, 0x1a, 0xb9,
-	0x5f, 0xc0, 0x56, 0x67, 0x12, 0x24, 0xaa, 0x1f, 0x2b, 0xb6, 0x3a, 0xea, 0x4c, 0xaf, 0xb1, 0x95,
-	0x95, 0xf2, 0x55, 0x90, 0xc8, 0x7b, 0x0c, 0x74, 0xa1, 0x11, 0x71, 0x1e, 0x07, 0xe2, 0x36, 0x44,
-	0x03, 0x2d, 0x97, 0x98, 0x80, 0x72, 0x63, 0xc0, 0x52, 0x19, 0xfa, 0x11, 0x0e, 0x3c, 0xd7, 0xfb,
-	0xe3, 0x42, 0xf2, 0x58, 0xf8, 0x93, 0x7b, 0x67, 0xe4, 0x18, 0x9a, 0x41, 0x12, 0xce, 0x64, 0x12,
-	0x0c, 0x75, 0x79, 0x1a, 0x2c, 0x23, 0xd0, 0x31, 0xec, 0xea, 0xd0, 0x2f, 0xd4, 0xac, 0x26, 0x5b,
-	0x8a, 0xbc, 0xd2, 0x2d, 0xe5, 0xb5, 0x6e, 0x51, 0x37, 0x71, 0x31, 0x34, 0x7c, 0xd3, 0xd9, 0x29,
-	0x81, 0xfe, 0x0a, 0xf6, 0xf4, 0x4d, 0x7f, 0xd1, 0x63, 0xb7, 0x65, 0xf4, 0xbb, 0xb0, 0xe3, 0xb9,
-	0xde, 0xa5, 0x98, 0xab, 0x02, 0x07, 0x62, 0x9e, 0xb4, 0x4b, 0x98, 0x0f, 0x5b, 0xe0, 0x4b, 0x31,
-	0xe7, 0x42, 0x86, 0xf1, 0x92, 0x21, 0x97, 0x7e, 0x82, 0x66, 0x4a, 0x22, 0xfb, 0x50, 0x96, 0x4b,
-	0x63, 0xb1, 0x2c, 0x97, 0x2a, 0x27, 0x63, 0x3f, 0x19, 0xa3, 0xc3, 0xbb, 0x0c, 0xbf, 0xc9, 0x23,
-	0x35, 0xed, 0x39, 0x37, 0xcd, 0x89, 0x5e, 0xd9, 0x46, 0xf8, 0x83, 0x2f, 0xfd, 0x2d, 0xb9, 0xb0,
-	0x6e, 0x95, 0xb7, 0xba, 0x75, 0x0c, 0x0d, 0xcf, 0xf5, 0x58, 0x38, 0x93, 0x9c, 0x1c, 0x42, 0xa5,
-	0xdf, 0xbf, 0x32, 0x76, 0xd4, 0x27, 0x65, 0x50, 0xf3, 0x5c, 0xaf, 0xbf, 0x20, 0x14, 0xca, 0x72,
-	0x81, 0x9c, 0xac, 0xe2, 0xfd, 0x0c, 0x5a, 0x59, 0x59, 0x2e, 0xc8, 0x33, 0xa8, 0xc5, 0xca, 0x0e,
-	0x46, 0xd1, 0x72, 0x0f, 0xb2, 0xc6, 0x40, 0xf3, 0x4c, 0x73, 0x69, 0x17, 0x6f, 0xc4, 0x52, 0x12,
-	0x0a, 0x35, 0xc4, 0x5f, 0x63, 0x79, 0xd7, 0xa8, 0x20, 0x93, 0x69, 0x16, 0xfd, 0x4f, 0x09, 0xe0,
-	0x4a, 0x45, 0xae, 0x55, 0x88, 0x1a, 0xa7, 0xaf, 0xb6, 0x2d, 0xf1, 0x3b, 0x07, 0x8c, 0xe5, 0x2d,
-	0xc0, 0x48, 0x5e, 0x42, 0x7d, 0x1a, 0x08, 0x1e, 0xf7, 0x17, 0x98, 0xd2, 0xcd, 0x91, 0x58, 0x11,
-	0xd5, 0x29, 0x49, 0x7f, 0xd1, 0xf3, 0x93, 0x31, 0x4f, 0xda, 0x55, 0x1c, 0x96, 0x8c, 0x40, 0x7b,
-	0x50, 0x47, 0xa7, 0xfa, 0x0b, 0x55, 0x28, 0x89, 0x64, 0xf4, 0x69, 0x97, 0x99, 0xd3, 0x0f, 0xcd,
-	0x07, 0xc5, 0x7c, 0xf4, 0x17, 0x8c, 0xdf, 0xdd, 0x67, 0x8a, 0xfe, 0x19, 0xfb, 0x12, 0x13, 0xa0,
-	0x05, 0x8f, 0xa1, 0x89, 0xd9, 0x49, 0x65, 0x9b, 0x2c, 0x23, 0x20, 0x7c, 0x2f, 0x2e, 0xc5, 0x30,
-	0x18, 0x70, 0x5d, 0xff, 0x1a, 0xcb, 0x08, 0x34, 0xc1, 0x99, 0x4c, 0x8d, 0x45, 0x93, 0xe5, 0xf7,
-	0x98, 0x23, 0x27, 0x50, 0x91, 0x8b, 0xa4, 0x5d, 0x29, 0xa0, 0x41, 0x3e, 0xa3, 0x8a, 0x4d, 0x17,
-	0x38, 0xc3, 0x7f, 0x9d, 0xf1, 0x78, 0x89, 0x7d, 0xfb, 0x1c, 0x6a, 0x52, 0x45, 0x62, 0x2a, 0x9f,
-	0x4b, 0x0e, 0x06, 0xd8, 0x73, 0x98, 0xe6, 0x93, 0xf7, 0x00, 0x37, 0x69, 0xdc, 0x26, 0x95, 0x0f,
-	0x33, 0xe9, 0x2c, 0x27, 0x3d, 0x87, 0xe5, 0x24, 0x2f, 0xea, 0x50, 0x9b, 0xfb, 0x93, 0x99, 0x42,
-	0x8f, 0x86, 0x79, 0x0a, 0x13, 0xf2, 0x18, 0x20, 0x72, 0xa3, 0xe2, 0xc0, 0xe4, 0x28, 0x88, 0x1f,
-	0xe1, 0xad, 0xb4, 0x02, 0x1a, 0xda, 0xf3, 0x24, 0x85, 0xa0, 0x0a, 0xdc, 0x72, 0xaf, 0x77, 0x7a,
-	0xa6, 0xdf, 0xca, 0xb0, 0x77, 0x11, 0x87, 0xfe, 0xf0, 0xa3, 0x9f, 0xe8, 0xe9, 0x7c, 0x9c, 0x1b,
-	0x9b, 0xdd, 0x7c, 0x88, 0x3d, 0x07, 0x47, 0xe6, 0xb9, 0xed, 0xff, 0xb5, 0x16, 0xc1, 0xb8, 0x54,
-	0x16, 0x90, 0xaf, 0x86, 0x39, 0x0a, 0xc4, 0xc8, 0xf4, 0xed, 0x7e, 0x0e, 0x73, 0x03, 0x31, 0xea,
-	0x39, 0x0c, 0xb9, 0xe4, 0x45, 0x06, 0x06, 0xd5, 0x82, 0x41, 0x9b, 0x80, 0x9e, 0x53, 0xc0, 0x87,
-	0x89, 0xec, 0x2f, 0xcc, 0x2e, 0x61, 0x4d, 0x9a, 0xa6, 0x56, 0x26, 0x15, 0x97, 0xbc, 0x82, 0xfa,
-	0x44, 0x4f, 0x1e, 0xbe, 0xda, 0x2d, 0xf7, 0x28, 0x2f, 0x68, 0xbd, 0xb4, 0x32, 0xe4, 0x05, 0xd4,
-	0xee, 0x54, 0x8d, 0xf1, 0x21, 0x6f, 0xb9, 0x0f, 0x32, 0x47, 0xd3, 0xd2, 0xab, 0xa0, 0x50, 0x86,
-	0xbc, 0x83, 0x06, 0x46, 0xc7, 0x78, 0x84, 0x0f, 0x7b, 0xcb, 0x7d, 0xb4, 0xa1, 0xb0, 0xd1, 0x64,
-	0xd9, 0x73, 0x58, 0x2a, 0x99, 0x15, 0x36, 0xb0, 0x60, 0xad, 0xc7, 0xfc, 0xa7, 0x7c, 0x17, 0x7e,
-	0x83, 0x98, 0x6b, 0xef, 0x79, 0x0e, 0x75, 0x8d, 0x28, 0x16, 0xf3, 0x57, 0xf0, 0xc6, 0x72, 0xa9,
-	0x80, 0xfa, 0xa5, 0x98, 0x63, 0x27, 0x9c, 0x6c, 0x07, 0x50, 0xd3, 0x0f, 0x27, 0xc5, 0x7e, 0x28,
-	0xe0, 0x61, 0xd6, 0x0c, 0xfa, 0xf5, 0xa8, 0xd8, 0xd7, 0x23, 0xcb, 0xc8, 0x1b, 0x68, 0x98, 0xfb,
-	0xd4, 0x58, 0xd6, 0x02, 0xc9, 0xa7, 0xd6, 0xc5, 0xfd, 0x0c, 0xff, 0x15, 0x9f, 0x69, 0x26, 0xfd,
-	0x6f, 0x09, 0xaa, 0xea, 0xd9, 0xfe, 0xae, 0xcd, 0x55, 0x41, 0x32, 0x9f, 0xdc, 0x62, 0xcf, 0x35,
-	0x18, 0x7e, 0xaf, 0x6e, 0xb3, 0xb5, 0x6d, 0xdb, 0xec, 0xce, 0xb6, 0x6d, 0xf6, 0x15, 0x34, 0x94,
-	0x83, 0xb8, 0x93, 0xfc, 0x02, 0x6a, 0x6a, 0xd8, 0x6c, 0x4c, 0x2d, 0xdb, 0x2d, 0x9c, 0xc7, 0x4c,
-	0x73, 0xb2, 0x17, 0x1c, 0x89, 0xfc, 0x4e, 0x35, 0x45, 0xe4, 0x46, 0xfd, 0x65, 0xc4, 0x4d, 0x6c,
-	0xf6, 0x48, 0x5f, 0xc2, 0xa1, 0x16, 0xfd, 0xcc, 0x25, 0xae, 0x2d, 0x5b, 0xa5, 0xff, 0x57, 0x82,
-	0xd6, 0xe7, 0x70, 0xc8, 0x8d, 0x30, 0xa1, 0xb0, 0xcb, 0xcd, 0x5a, 0x93, 0x4b, 0x5c, 0x81, 0xa6,
-	0x9a, 0x6a, 0x12, 0x0e, 0x8c, 0x80, 0x06, 0x93, 0x8c, 0x90, 0xdf, 0x48, 0x2b, 0x98, 0xb9, 0xfc,
-	0xfa, 0x1d, 0xce, 0xe4, 0x4d, 0x38, 0x13, 0xc3, 0xc4, 0xfc, 0x10, 0xc8, 0x08, 0x0a, 0x82, 0x02,
-	0x61, 0x98, 0x3a, 0xaf, 0xe9, 0x99, 0xbe, 0x03, 0x50, 0x81, 0x27, 0x1a, 0xd6, 0x7f, 0x59, 0xcc,
-	0xd7, 0x61, 0x2e, 0x5f, 0x09, 0x46, 0x6c, 0x92, 0xf6, 0xcf, 0x12, 0x34, 0x53, 0x62, 0x5a, 0xe2,
-	0x52, 0xae, 0xc4, 0xfb, 0x50, 0x0e, 0x22, 0x13, 0x42, 0x39, 0x88, 0x36, 0x2e, 0xba, 0x2b, 0xe0,
-	0x59, 0x5d, 0x07, 0xcf, 0x22, 0xfc, 0xd6, 0x56, 0xe1, 0xd7, 0xfd, 0x57, 0x1d, 0x5a, 0x91, 0x1b,
-	0x8d, 0x6c, 0x1e, 0x5e, 0x40, 0x2b, 0xc5, 0xd3, 0xfe, 0x82, 0x14, 0x10, 0xb4, 0x63, 0x4f, 0x18,
-	0x2a, 0x75, 0xc8, 0x5b, 0xd8, 0x4f, 0x85, 0x35, 0x18, 0xad, 0xc2, 0xe9, 0x9a, 0xca, 0x29, 0x54,
-	0x71, 0xc1, 0x5f, 0xc1, 0xd3, 0x4e, 0xfe, 0x1c, 0x8a, 0x11, 0x75, 0x48, 0x17, 0xea, 0x76, 0xf5,
-	0x3e, 0xca, 0x98, 0x86, 0x94, 0x97, 0x57, 0x67, 0xea, 0x90, 0xf7, 0xd0, 0x32, 0x4c, 0x6c, 0xdc,
-	0x0d, 0x3a, 0xa4, 0xa8, 0xa3, 0xc4, 0xa8, 0x43, 0xde, 0x40, 0xdd, 0xfe, 0x6e, 0xcb, 0xe9, 0x18,
-	0x52, 0xe7, 0xb0, 0x40, 0x3a, 0x1f, 0x7c, 0xa1, 0x0e, 0x71, 0xd3, 0xe7, 0xcd, 0xdd, 0xa4, 0xb2,
-	0x4e, 0xa2, 0x0e, 0x79, 0x05, 0xad, 0xeb, 0xf0, 0x56, 0xda, 0x9b, 0x56, 0xc3, 0x5f, 0xcf, 0x6c,
-	0x33, 0x5b, 0xbe, 0x1f, 0x14, 0x42, 0xd1, 0xc4, 0xce, 0x5e, 0x46, 0xbc, 0x14, 0x73, 0xea, 0x90,
-	0x33, 0x00, 0xbd, 0x45, 0x7b, 0x6a, 0x8b, 0x7e, 0x58, 0xd0, 0x31, 0xbb, 0xf5, 0xba, 0xd2, 0x5b,
-	0x4c, 0x32, 0xc2, 0x65, 0x31, 0x61, 0x8a, 0xd4, 0x39, 0x28, 0x22, 0x58, 0x42, 0x9d, 0x37, 0x25,
-	0xf2, 0x5b, 0xbc, 0xc7, 0x02, 0x73, 0xf1, 0x1e, 0x43, 0xcd, 0xa7, 0xc0, 0x90, 0xa8, 0x43, 0x7e,
-	0x87, 0x05, 0x4a, 0x7f, 0xb8, 0xff, 0xac, 0xa0, 0x69, 0xc9, 0x9d, 0x0d, 0x3f, 0x6e, 0xa8, 0x43,
-	0x3e, 0xc0, 0xe1, 0x35, 0x8f, 0xe7, 0x3c, 0xbe, 0x96, 0x31, 0xf7, 0xa7, 0x8c, 0xfb, 0xc3, 0xf4,
-	0xea, 0xc2, 0xfb, 0x9f, 0x86, 0xc8, 0xf8, 0xdd, 0xe7, 0x60, 0x42, 0x9d, 0xd3, 0x12, 0xf9, 0x7d,
-	0x51, 0xf9, 0x9a, 0x8b, 0xe1, 0x5a, 0x01, 0x36, 0x1a, 0xc3, 0x78, 0xcf, 0x60, 0xff, 0x63, 0x38,
-	0x99, 0xf0, 0x81, 0xbc, 0x14, 0x38, 0xb1, 0x6b, 0xba, 0x07, 0xb9, 0x21, 0x37, 0x4d, 0xf5, 0x1e,
-	0x0e, 0x8a, 0x4a, 0xee, 0x9a, 0xd6, 0x51, 0x1e, 0x1a, 0x4c, 0xdd, 0x2f, 0x9e, 0xfc, 0xe3, 0xe7,
-	0xa3, 0x40, 0x8e, 0x67, 0x37, 0xdd, 0x41, 0x38, 0x7d, 0x7d, 0x76, 0x36, 0x10, 0xaf, 0xf1, 0x8f,
-	0x92, 0xb3, 0xb3, 0xd7, 0x28, 0x7d, 0xb3, 0x83, 0xff, 0x98, 0x9c, 0xfd, 0x3f, 0x00, 0x00, 0xff,
-	0xff, 0x38, 0x19, 0x81, 0x59, 0x78, 0x11, 0x00, 0x00,
+	// 1684 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xdd, 0x92, 0xdb, 0x48,
+	0x15, 0x1e, 0xd9, 0xd6, 0xd8, 0x3e, 0x72, 0x66, 0x26, 0xbd, 0x61, 0x4b, 0xe5, 0x0a, 0xbb, 0x43,
+	0x57, 0x76, 0x93, 0x25, 0x59, 0x4f, 0x56, 0x93, 0xdd, 0x62, 0xe1, 0x2a, 0xc9, 0x42, 0x3c, 0x45,
+	0x48, 0x89, 0x1e, 0xc3, 0x05, 0x77, 0x1a, 0xb9, 0xc7, 0x56, 0xad, 0x2c, 0x29, 0x52, 0xdb, 0x65,
+	0xe7, 0x9e, 0x17, 0x80, 0x07, 0xe0, 0x92, 0x07, 0xe0, 0x21, 0x78, 0x17, 0x8a, 0x87, 0xa0, 0xfa,
+	0x74, 0xb7, 0x7e, 0x3c, 0xb6, 0x8b, 0x22, 0xc5, 0x9d, 0xfa, 0xfc, 0x74, 0x9f, 0xdf, 0xaf, 0xfb,
+	0x08, 0xfa, 0x99, 0x97, 0x8d, 0xb2, 0x3c, 0x15, 0x29, 0xb1, 0xc5, 0x26, 0xe3, 0xc5, 0xf0, 0xbe,
+	0xc8, 0x83, 0xa4, 0x08, 0x42, 0x11, 0xa5, 0x89, 0xe2, 0x0c, 0x07, 0x61, 0xba, 0x58, 0x94, 0xab,
+	0xb3, 0x9b, 0x38, 0x0d, 0x7f, 0x0c, 0xe7, 0x41, 0xa4, 0x29, 0xf4, 0xe7, 0x70, 0xe2, 0x7b, 0xfe,
+	0x1b, 0x2e, 0x7c, 0xce, 0xf3, 0xab, 0xe4, 0x36, 0x25, 0x2e, 0x74, 0x57, 0x3c, 0x2f, 0xa2, 0x34,
+	0x71, 0xad, 0x73, 0xeb, 0x89, 0xcd, 0xcc, 0x92, 0xfe, 0xc5, 0x02, 0xc7, 0xf7, 0xfc, 0x52, 0x92,
+	0x40, 0x27, 0x98, 0x4e, 0x73, 0x14, 0xeb, 0x33, 0xfc, 0x96, 0xb4, 0x2c, 0xcd, 0x85, 0xdb, 0x42,
+	0x55, 0xfc, 0x96, 0xb4, 0x24, 0x58, 0x70, 0xb7, 0xad, 0xe4, 0xe4, 0x37, 0x39, 0x07, 0x67, 0xc1,
+	0x17, 0x59, 0x9a, 0xc6, 0xd7, 0xd1, 0x07, 0xee, 0x76, 0x50, 0xbc, 0x4e, 0x22, 0x5f, 0xc0, 0xf1,
+	0x9c, 0x07, 0x53, 0x9e, 0xbb, 0xf6, 0xb9, 0xf5, 0xc4, 0xf1, 0xee, 0x8d, 0xd0, 0xc9, 0xd1, 0x18,
+	0x89, 0x4c, 0x33, 0xe9, 0xbf, 0x2d, 0x00, 0xdf, 0xf3, 0xff, 0xa8, 0x6c, 0xdc, 0x6f, 0xbd, 0xe4,
+	0x14, 0x3c, 0x5f, 0x45, 0x21, 0x47, 0xe3, 0xda, 0xcc, 0x2c, 0xc9, 0x43, 0xe8, 0x8b, 0x68, 0xc1,
+	0x0b, 0x11, 0x2c, 0x32, 0x34, 0xb2, 0xcd, 0x2a, 0x02, 0x19, 0x42, 0x4f, 0x7a, 0xc6, 0x78, 0xb8,
+	0x42, 0x33, 0xfb, 0xac, 0x5c, 0x1b, 0xde, 0x6f, 0xf2, 0x74, 0xe1, 0xda, 0x15, 0x4f, 0xae, 0xc9,
+	0x03, 0xb0, 0x93, 0x34, 0x09, 0xb9, 0x7b, 0x8c, 0x3b, 0xaa, 0x85, 0x3c, 0x6b, 0x59, 0xf0, 0xfc,
+	0xe5, 0x8c, 0x27, 0xc2, 0xed, 0xa2, 0x4a, 0x45, 0x90, 0x51, 0x29, 0x44, 0x90, 0x8b, 0x31, 0x8f,
+	0x66, 0x73, 0xe1, 0xf6, 0x50, 0xb3, 0x4e, 0xa2, 0x7f, 0x80, 0xbe, 0xf2, 0xf6, 0x65, 0xf8, 0xe3,
+	0xff, 0xe4, 0x6c, 0x69, 0x56, 0xbb, 0x66, 0x16, 0x5d, 0x40, 0x57, 0x66, 0x36, 0x4a, 0x66, 0x95,
+	0x80, 0x55, 0xb7, 0xdb, 0xe4, 0xba, 0xb5, 0x23, 0xd7, 0xed, 0x5a, 0xae, 0x1f, 0x41, 0xa7, 0x88,
+	0x66, 0x09, 0x46, 0xca, 0xf1, 0xce, 0x74, 0xce, 0xae, 0xa3, 0x59, 0x12, 0x88, 0x65, 0xce, 0x19,
+	0x72, 0xe9, 0xe7, 0xea, 0xb8, 0x74, 0xdf, 0x71, 0x94, 0x62, 0x52, 0xdf, 0x70, 0xf1, 0x52, 0x1e,
+	0xb4, 0x5b, 0xe6, 0x57, 0xb8, 0xc9, 0x7e, 0x01, 0x93, 0x9d, 0x38, 0x2a, 0x64, 0x3d, 0xb6, 0x4d,
+	0x76, 0xe4, 0x9a, 0x5e, 0x83, 0xa3, 0x95, 0xdf, 0x46, 0x85, 0xd8, 0xb3, 0xc1, 0x08, 0x7a, 0x19,
+	0xe7, 0x79, 0x94, 0xdc, 0xa6, 0xb8, 0x81, 0xe3, 0x11, 0xed, 0x50, 0xad, 0x0d, 0x58, 0x29, 0x43,
+	0x5f, 0xc3, 0xa9, 0xef, 0xf9, 0xbf, 0x5e, 0x0b, 0x9e, 0x27, 0x41, 0xbc, 0xb7, 0x47, 0x1e, 0x42,
+	0x3f, 0x2a, 0xd2, 0xa5, 0x28, 0xa2, 0xa9, 0x4a, 0x4f, 0x8f, 0x55, 0x04, 0x3a, 0x87, 0x81, 0x72,
+	0xfd, 0x95, 0xec, 0xd5, 0xe2, 0x40, 0x92, 0xb7, 0xaa, 0xa5, 0x75, 0xa7, 0x5a, 0xe4, 0x49, 0x3c,
+	0x99, 0x6a, 0xbe, 0xae, 0xec, 0x92, 0x40, 0xbf, 0x82, 0x7b, 0xea, 0xa4, 0xdf, 0xa9, 0xb6, 0x3b,
+	0xd0, 0xfa, 0x23, 0x38, 0xf6, 0x3d, 0xff, 0x2a, 0x59, 0xc9, 0x04, 0x47, 0xc9, 0xaa, 0x70, 0xad,
+	0xf3, 0x76, 0x2d, 0xc1, 0x57, 0xc9, 0x8a, 0x27, 0x22, 0xcd, 0x37, 0x0c, 0xb9, 0xf4, 0x0d, 0xf4,
+	0x4b, 0x12, 0x39, 0x81, 0x96, 0xd8, 0xe8, 0x1d, 0x5b, 0x62, 0x23, 0x63, 0x32, 0x0f, 0x8a, 0x39,
+	0x1a, 0x3c, 0x60, 0xf8, 0x4d, 0x3e, 0x95, 0xdd, 0x5e, 0x33, 0x53, 0xaf, 0xe8, 0x5b, 0x53, 0x08,
+	0x3f, 0x04, 0x22, 0x38, 0x10, 0x0b, 0x63, 0x56, 0xeb, 0xa0, 0x59, 0x0f, 0xa1, 0xe7, 0x7b, 0x3e,
+	0x4b, 0x97, 0x82, 0x93, 0x33, 0x68, 0x4f, 0x26, 0x6f, 0xf5, 0x3e, 0xf2, 0x93, 0x32, 0xb0, 0x7d,
+	0xcf, 0x9f, 0xac, 0x09, 0x85, 0x96, 0x58, 0x23, 0xa7, 0xca, 0xf8, 0xa4, 0x82, 0x56, 0xd6, 0x12,
+	0x6b, 0xf2, 0x05, 0xd8, 0xb9, 0xdc, 0x07, 0xbd, 0x70, 0xbc, 0xd3, 0xaa, 0x30, 0x70, 0x7b, 0xa6,
+	0xb8, 0x74, 0x84, 0x27, 0x62, 0x2a, 0x09, 0x05, 0x1b, 0xf1, 0x57, 0xef, 0x3c, 0xd0, 0x2a, 0xc8,
+	0x64, 0x8a, 0x45, 0xff, 0x6a, 0x01, 0xbc, 0x95, 0x9e, 0x2b, 0x15, 0x22, 0xdb, 0xe9, 0x83, 0x29,
+	0xcb, 0x4e, 0xd1, 0x04, 0xc6, 0xd6, 0x01, 0x60, 0x24, 0xcf, 0xa0, 0xbb, 0x88, 0x12, 0x9e, 0x4f,
+	0xd6, 0x6e, 0x7b, 0xaf, 0x27, 0x46, 0x44, 0x56, 0x4a, 0x31, 0x59, 0x8f, 0x83, 0x62, 0xce, 0x0b,
+	0xb7, 0x83, 0xcd, 0x52, 0x11, 0xe8, 0x3f, 0x2c, 0x18, 0xbc, 0x4e, 0x17, 0x59, 0x10, 0x7e, 0xbc,
+	0x5d, 0x0d, 0x00, 0xea, 0xd4, 0x7a, 0xb5, 0x98, 0xa7, 0xb9, 0xb8, 0xfa, 0x41, 0x1d, 0xdf, 0x61,
+	0xe5, 0x9a, 0x7c, 0x07, 0x83, 0x2c, 0xe7, 0xb7, 0x51, 0x1c, 0xf3, 0xe9, 0x64, 0x5d, 0xb8, 0x76,
+	0xb3, 0x15, 0x2b, 0x16, 0x6b, 0xc8, 0xd1, 0x37, 0xe0, 0xd4, 0x98, 0xf2, 0xe0, 0x28, 0x99, 0xf2,
+	0xb5, 0x4e, 0xb9, 0x5a, 0xe8, 0x5c, 0xb7, 0x0e, 0xe5, 0x9a, 0x8e, 0xa1, 0x8b, 0x39, 0x99, 0xac,
+	0x65, 0x9d, 0x0a, 0x8c, 0x0a, 0xee, 0x32, 0x60, 0x7a, 0xf5, 0xdf, 0x96, 0x03, 0xc5, 0x72, 0x98,
+	0xac, 0x19, 0x7f, 0xbf, 0x6f, 0x2b, 0xfa, 0x5b, 0x6c, 0x4b, 0x8c, 0xb3, 0x12, 0x7c, 0x08, 0x7d,
+	0x2c, 0x8e, 0x52, 0xb6, 0xcf, 0x2a, 0x82, 0xe4, 0x8a, 0xf5, 0x55, 0x32, 0x8d, 0x42, 0xae, 0xca,
+	0xdf, 0x66, 0x15, 0x81, 0x16, 0x70, 0x5a, 0xdf, 0x2c, 0x8b, 0x37, 0x1f, 0xb3, 0x1d, 0x79, 0x04,
+	0x6d, 0xb1, 0x2e, 0xdc, 0xf6, 0x79, 0x7b, 0x4f, 0xb8, 0x24, 0x9b, 0xae, 0x11, 0xc2, 0x7e, 0xbf,
+	0xe4, 0xf9, 0x06, 0xdb, 0xf6, 0x31, 0xd8, 0x42, 0x7a, 0xe2, 0x5a, 0xdb, 0xc1, 0x41, 0x07, 0xc7,
+	0x47, 0x4c, 0xf1, 0xc9, 0x77, 0x00, 0x37, 0xa5, 0xdf, 0x3a, 0x94, 0x0f, 0x2a, 0xe9, 0x2a, 0x26,
+	0xe3, 0x23, 0x56, 0x93, 0x7c, 0xd5, 0x05, 0x7b, 0x15, 0xc4, 0x4b, 0x09, 0x9e, 0x3d, 0xfd, 0x12,
+	0x28, 0xc8, 0x67, 0x00, 0x99, 0x97, 0x35, 0xf1, 0xa2, 0x46, 0x41, 0xf8, 0x4c, 0x6f, 0x85, 0x11,
+	0x50, 0x37, 0x5b, 0x9d, 0x24, 0x8b, 0x52, 0x62, 0x7b, 0xed, 0xf1, 0x52, 0xae, 0xe9, 0xdf, 0xdb,
+	0x70, 0xef, 0x55, 0x9e, 0x06, 0xd3, 0xd7, 0x41, 0xa1, 0xc0, 0xe9, 0xb3, 0x1a, 0x6a, 0x0c, 0xea,
+	0x2e, 0x8e, 0x8f, 0x10, 0x31, 0x1e, 0x9b, 0xf6, 0xbf, 0x53, 0x22, 0xe8, 0x97, 0x8c, 0x02, 0xf2,
+	0x25, 0x96, 0x65, 0x51, 0x32, 0xd3, 0x6d, 0x7b, 0x52, 0xc9, 0xc9, 0xfb, 0x79, 0x7c, 0xc4, 0x90,
+	0x4b, 0x9e, 0x56, 0x58, 0xd8, 0x69, 0x6c, 0x68, 0x02, 0x30, 0x3e, 0x6a, 0xc0, 0x63, 0x2c, 0x26,
+	0x6b, 0xd7, 0x6e, 0x6c, 0xa9, 0x8b, 0x5a, 0x6e, 0x29, 0xb9, 0xe4, 0x6b, 0xe8, 0xc6, 0xaa, 0xc1,
+	0xf1, 0xd1, 0xe2, 0x78, 0xf7, 0xeb, 0x82, 0xc6, 0x4a, 0x23, 0x43, 0x9e, 0x82, 0xfd, 0x5e, 0xe6,
+	0x18, 0xdf, 0x31, 0x8e, 0xf7, 0x49, 0x65, 0x68, 0x99, 0x7a, 0xe9, 0x14, 0xca, 0x90, 0x17, 0xd0,
+	0x43, 0xef, 0x18, 0xcf, 0xf0, 0x5d, 0xe3, 0x78, 0x9f, 0xee, 0x48, 0x6c, 0x16, 0x6f, 0xc6, 0x47,
+	0xac, 0x94, 0x24, 0xdf, 0xc3, 0x20, 0xac, 0xe1, 0x8e, 0xdb, 0x6f, 0x9c, 0x54, 0x87, 0xa4, 0xf1,
+	0x11, 0x6b, 0x88, 0x56, 0x35, 0x11, 0x99, 0x6b, 0x4e, 0x01, 0xd1, 0xff, 0xf3, 0x46, 0xfd, 0x16,
+	0x6f, 0x2b, 0x73, 0xce, 0x63, 0xe8, 0x2a, 0xcc, 0x33, 0xb7, 0xe5, 0x16, 0x22, 0x1a, 0x2e, 0x4d,
+	0xa0, 0x7b, 0x95, 0xac, 0xb0, 0x88, 0x1e, 0x1d, 0xbe, 0x7a, 0x74, 0x29, 0x3d, 0x6a, 0x96, 0x52,
+	0xe3, 0x26, 0xa9, 0xea, 0x48, 0xdd, 0xbb, 0x6d, 0x73, 0xef, 0x56, 0x11, 0x79, 0x0e, 0x3d, 0x7d,
+	0x9e, 0xec, 0x68, 0x3b, 0x12, 0x7c, 0x61, 0x4c, 0x3c, 0xa9, 0x6e, 0x4e, 0xc9, 0x67, 0x8a, 0x49,
+	0xff, 0x66, 0x41, 0x47, 0x3e, 0x78, 0x3e, 0xea, 0xcd, 0x2f, 0x2f, 0x0d, 0x1e, 0xdf, 0x62, 0xb9,
+	0xf6, 0x18, 0x7e, 0x6f, 0xcf, 0x01, 0xf6, 0xa1, 0x39, 0xe0, 0xf8, 0xd0, 0x1c, 0xf0, 0x35, 0xf4,
+	0xa4, 0x81, 0xf8, 0x9a, 0xfb, 0x19, 0xd8, 0xb2, 0x4f, 0x8d, 0x4f, 0x8e, 0x29, 0x34, 0xce, 0x73,
+	0xa6, 0x38, 0xd5, 0xdb, 0x07, 0x89, 0xfc, 0xbd, 0x2c, 0x8a, 0xcc, 0xcb, 0x26, 0x9b, 0x8c, 0x6b,
+	0xdf, 0xcc, 0x92, 0x3e, 0x83, 0x33, 0x25, 0xfa, 0x8e, 0x0b, 0x7c, 0xf0, 0x1d, 0x94, 0xfe, 0xa7,
+	0x05, 0xce, 0xbb, 0x74, 0xca, 0xb5, 0x30, 0xa1, 0x30, 0xe0, 0xfa, 0x41, 0x58, 0x0b, 0x5c, 0x83,
+	0x26, 0x8b, 0x2a, 0x4e, 0x43, 0x2d, 0xa0, 0x70, 0xa8, 0x22, 0xd4, 0xdf, 0xf2, 0x6d, 0x8c, 0x5c,
+	0x7d, 0x70, 0x49, 0x97, 0xe2, 0x26, 0x5d, 0x26, 0xd3, 0x42, 0x8f, 0x50, 0x15, 0x41, 0xa2, 0x57,
+	0x94, 0x68, 0xa6, 0x8a, 0x6b, 0xb9, 0x96, 0x56, 0xe5, 0x3c, 0x08, 0xe7, 0xc1, 0x4d, 0x14, 0x47,
+	0x62, 0x83, 0xa1, 0xed, 0xb3, 0x06, 0x8d, 0xbe, 0x00, 0x90, 0xc1, 0x29, 0xd4, 0xad, 0xf1, 0x65,
+	0x33, 0xa6, 0x67, 0xb5, 0x98, 0x16, 0x18, 0x15, 0x1d, 0xd8, 0x3f, 0x5b, 0xd0, 0x2f, 0x89, 0x65,
+	0x19, 0x58, 0xb5, 0x32, 0x38, 0x81, 0x56, 0x94, 0x69, 0x37, 0x5b, 0x51, 0xb6, 0x73, 0x8c, 0xd8,
+	0xc2, 0xe6, 0xce, 0x5d, 0x6c, 0x6e, 0xa2, 0xbb, 0xbd, 0x8d, 0xee, 0xde, 0xbf, 0x8e, 0xc1, 0xc9,
+	0xbc, 0x6c, 0x66, 0x62, 0xf5, 0x15, 0x38, 0x25, 0x5c, 0x4f, 0xd6, 0xa4, 0x01, 0xd0, 0x43, 0xb3,
+	0x52, 0xae, 0x5e, 0xc0, 0x49, 0x29, 0xaa, 0x90, 0x6e, 0x1b, 0xab, 0xb7, 0x14, 0xbe, 0x84, 0x0e,
+	0x8e, 0x4e, 0x5b, 0x50, 0x3d, 0xac, 0xaf, 0xe5, 0xac, 0xf3, 0x0c, 0xba, 0x66, 0xa4, 0xb9, 0x5f,
+	0xb1, 0x34, 0xa9, 0x2e, 0x8d, 0x22, 0x2f, 0xc0, 0xd1, 0x2c, 0x2c, 0xea, 0x1d, 0x1a, 0xa4, 0xa9,
+	0x81, 0x62, 0x23, 0xe8, 0x9a, 0x59, 0xb8, 0xa6, 0xa1, 0x49, 0xc3, 0xb3, 0x06, 0x49, 0xce, 0x90,
+	0xcf, 0xcb, 0x1b, 0xd3, 0xdb, 0xa5, 0x70, 0x97, 0x44, 0x9e, 0x82, 0x73, 0x9d, 0xde, 0x0a, 0xb3,
+	0xdc, 0x76, 0x7a, 0x3b, 0x96, 0xfd, 0x6a, 0x94, 0xf9, 0xa4, 0xe1, 0x82, 0x22, 0x0e, 0xef, 0x55,
+	0x44, 0x39, 0x5f, 0x7c, 0x03, 0xa0, 0x26, 0x12, 0x5f, 0x4e, 0x24, 0x0f, 0x1a, 0x1a, 0x7a, 0x4e,
+	0xd9, 0x56, 0xb9, 0xc0, 0xb0, 0x22, 0x7c, 0x36, 0x83, 0x24, 0x49, 0xc3, 0xd3, 0x26, 0xa2, 0x15,
+	0xcf, 0x2d, 0xf2, 0x2d, 0x9e, 0x61, 0x60, 0xba, 0x79, 0x86, 0xa6, 0xd6, 0x1d, 0x37, 0x82, 0xbf,
+	0xc0, 0x84, 0x94, 0xbf, 0x3f, 0x7e, 0xd2, 0xd0, 0x33, 0xe4, 0xe1, 0x8e, 0x11, 0x91, 0x7c, 0x0f,
+	0x67, 0xd7, 0x3c, 0x5f, 0xf1, 0xfc, 0x5a, 0xe4, 0x3c, 0x58, 0x30, 0x1e, 0x4c, 0xcb, 0x63, 0x1b,
+	0x8f, 0x88, 0xd2, 0x35, 0xc6, 0xdf, 0xbf, 0x8b, 0xe2, 0x27, 0x16, 0xf9, 0x65, 0x53, 0xf5, 0x9a,
+	0x27, 0xd3, 0x3b, 0x21, 0xdf, 0xb9, 0xd5, 0x73, 0x8b, 0x7c, 0x03, 0x27, 0xaf, 0xd3, 0x38, 0xe6,
+	0xa1, 0xb8, 0x4a, 0xb0, 0x27, 0xef, 0x68, 0x9e, 0xd6, 0xda, 0x18, 0xcb, 0xe7, 0x05, 0x9c, 0x36,
+	0x55, 0xbc, 0x3b, 0x3a, 0xf7, 0x6b, 0x3a, 0x0a, 0x1c, 0x5e, 0x7d, 0xfe, 0xa7, 0x9f, 0xce, 0x22,
+	0x31, 0x5f, 0xde, 0x8c, 0xc2, 0x74, 0x71, 0x71, 0x79, 0x19, 0x26, 0x17, 0xf8, 0x93, 0xe9, 0xf2,
+	0xf2, 0x02, 0x65, 0x6f, 0x8e, 0xf1, 0x6f, 0xd3, 0xe5, 0x7f, 0x06, 0x00, 0x37, 0xef, 0x7c, 0xc2,
+	0xb4, 0x12, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -0,0 +1,91 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: p2pnat.proto
+
+package types
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// 连接反转, 通过中继连接交换直连地址后由响应方拨号发起方建立直连
+type ConnReversal struct {
+	// 消息类型, 1: connect 交换地址, 2: sync 开始拨号
+	Ty int32 `protobuf:"varint,1,opt,name=ty,proto3" json:"ty,omitempty"`
+	// 发送方的直连地址, multiaddr字节格式
+	Addrs                [][]byte `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConnReversal) Reset()         { *m = ConnReversal{} }
+func (m *ConnReversal) String() string { return proto.CompactTextString(m) }
+func (*ConnReversal) ProtoMessage()    {}
+func (*ConnReversal) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b82ee126dcb419ea, []int{0}
+}
+
+func (m *ConnReversal) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConnReversal.Unmarshal(m, b)
+}
+func (m *ConnReversal) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConnReversal.Marshal(b, m, deterministic)
+}
+func (m *ConnReversal) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConnReversal.Merge(m, src)
+}
+func (m *ConnReversal) XXX_Size() int {
+	return xxx_messageInfo_ConnReversal.Size(m)
+}
+func (m *ConnReversal) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConnReversal.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ConnReversal proto.InternalMessageInfo
+
+func (m *ConnReversal) GetTy() int32 {
+	if m != nil {
+		return m.Ty
+	}
+	return 0
+}
+
+func (m *ConnReversal) GetAddrs() [][]byte {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*ConnReversal)(nil), "types.ConnReversal")
+}
+
+func init() {
+	proto.RegisterFile("p2pnat.proto", fileDescriptor_b82ee126dcb419ea)
+}
+
+var fileDescriptor_b82ee126dcb419ea = []byte{
+	// 127 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x29, 0x30, 0x2a, 0xc8,
+	0x4b, 0x2c, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2d, 0xa9, 0x2c, 0x48, 0x2d, 0x56,
+	0x32, 0xe1, 0xe2, 0x71, 0xce, 0xcf, 0xcb, 0x0b, 0x4a, 0x2d, 0x4b, 0x2d, 0x2a, 0x4e, 0xcc, 0x11,
+	0xe2, 0xe3, 0x62, 0x2a, 0xa9, 0x94, 0x60, 0x54, 0x60, 0xd4, 0x60, 0x0d, 0x62, 0x2a, 0xa9, 0x14,
+	0x12, 0xe1, 0x62, 0x4d, 0x4c, 0x49, 0x29, 0x2a, 0x96, 0x60, 0x52, 0x60, 0xd6, 0xe0, 0x09, 0x82,
+	0x70, 0x9c, 0xe4, 0xa3, 0x64, 0xd3, 0x33, 0x4b, 0x32, 0x4a, 0x93, 0xf4, 0x92, 0xf3, 0x73, 0xf5,
+	0x8d, 0x8d, 0x93, 0xf3, 0xf4, 0x93, 0x33, 0x12, 0x33, 0xf3, 0x8c, 0x8d, 0xf5, 0xc1, 0xc6, 0x26,
+	0xb1, 0x81, 0x2d, 0x31, 0x06, 0x0c, 0x00, 0xa7, 0x0a, 0xda, 0xc9, 0x74, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,320 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: peerscore.proto
+
+package types
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// 上报节点的异常行为, 用于p2p节点评分
+type PeerFaultReport struct {
+	Pid string `protobuf:"bytes,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	// 行为类型, 参考types.PeerFault*
+	Ty                   int32    `protobuf:"varint,2,opt,name=ty,proto3" json:"ty,omitempty"`
+	ErrInfo              string   `protobuf:"bytes,3,opt,name=errInfo,proto3" json:"errInfo,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PeerFaultReport) Reset()         { *m = PeerFaultReport{} }
+func (m *PeerFaultReport) String() string { return proto.CompactTextString(m) }
+func (*PeerFaultReport) ProtoMessage()    {}
+func (*PeerFaultReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75fe6b0225e7a1d4, []int{0}
+}
+
+func (m *PeerFaultReport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PeerFaultReport.Unmarshal(m, b)
+}
+func (m *PeerFaultReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PeerFaultReport.Marshal(b, m, deterministic)
+}
+func (m *PeerFaultReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PeerFaultReport.Merge(m, src)
+}
+func (m *PeerFaultReport) XXX_Size() int {
+	return xxx_messageInfo_PeerFaultReport.Size(m)
+}
+func (m *PeerFaultReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_PeerFaultReport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PeerFaultReport proto.InternalMessageInfo
+
+func (m *PeerFaultReport) GetPid() string {
+	if m != nil {
+		return m.Pid
+	}
+	return ""
+}
+
+func (m *PeerFaultReport) GetTy() int32 {
+	if m != nil {
+		return m.Ty
+	}
+	return 0
+}
+
+func (m *PeerFaultReport) GetErrInfo() string {
+	if m != nil {
+		return m.ErrInfo
+	}
+	return ""
+}
+
+// 封禁或解封节点
+type ReqBanPeer struct {
+	Pid string `protobuf:"bytes,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	// 封禁时长, 单位秒, 为0时使用默认时长
+	Duration             int64    `protobuf:"varint,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	Reason               string   `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	P2PType              string   `protobuf:"bytes,4,opt,name=p2pType,proto3" json:"p2pType,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReqBanPeer) Reset()         { *m = ReqBanPeer{} }
+func (m *ReqBanPeer) String() string { return proto.CompactTextString(m) }
+func (*ReqBanPeer) ProtoMessage()    {}
+func (*ReqBanPeer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75fe6b0225e7a1d4, []int{1}
+}
+
+func (m *ReqBanPeer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReqBanPeer.Unmarshal(m, b)
+}
+func (m *ReqBanPeer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReqBanPeer.Marshal(b, m, deterministic)
+}
+func (m *ReqBanPeer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReqBanPeer.Merge(m, src)
+}
+func (m *ReqBanPeer) XXX_Size() int {
+	return xxx_messageInfo_ReqBanPeer.Size(m)
+}
+func (m *ReqBanPeer) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReqBanPeer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReqBanPeer proto.InternalMessageInfo
+
+func (m *ReqBanPeer) GetPid() string {
+	if m != nil {
+		return m.Pid
+	}
+	return ""
+}
+
+func (m *ReqBanPeer) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *ReqBanPeer) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *ReqBanPeer) GetP2PType() string {
+	if m != nil {
+		return m.P2PType
+	}
+	return ""
+}
+
+type ReqBannedPeers struct {
+	P2PType              string   `protobuf:"bytes,1,opt,name=p2pType,proto3" json:"p2pType,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReqBannedPeers) Reset()         { *m = ReqBannedPeers{} }
+func (m *ReqBannedPeers) String() string { return proto.CompactTextString(m) }
+func (*ReqBannedPeers) ProtoMessage()    {}
+func (*ReqBannedPeers) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75fe6b0225e7a1d4, []int{2}
+}
+
+func (m *ReqBannedPeers) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReqBannedPeers.Unmarshal(m, b)
+}
+func (m *ReqBannedPeers) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReqBannedPeers.Marshal(b, m, deterministic)
+}
+func (m *ReqBannedPeers) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReqBannedPeers.Merge(m, src)
+}
+func (m *ReqBannedPeers) XXX_Size() int {
+	return xxx_messageInfo_ReqBannedPeers.Size(m)
+}
+func (m *ReqBannedPeers) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReqBannedPeers.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReqBannedPeers proto.InternalMessageInfo
+
+func (m *ReqBannedPeers) GetP2PType() string {
+	if m != nil {
+		return m.P2PType
+	}
+	return ""
+}
+
+type BannedPeer struct {
+	Pid string `protobuf:"bytes,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	// 解封时间, unix时间戳
+	Expire int64 `protobuf:"varint,2,opt,name=expire,proto3" json:"expire,omitempty"`
+	// 累计被封禁次数, 封禁时长随次数增长
+	Count                int32    `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Reason               string   `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BannedPeer) Reset()         { *m = BannedPeer{} }
+func (m *BannedPeer) String() string { return proto.CompactTextString(m) }
+func (*BannedPeer) ProtoMessage()    {}
+func (*BannedPeer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75fe6b0225e7a1d4, []int{3}
+}
+
+func (m *BannedPeer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BannedPeer.Unmarshal(m, b)
+}
+func (m *BannedPeer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BannedPeer.Marshal(b, m, deterministic)
+}
+func (m *BannedPeer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BannedPeer.Merge(m, src)
+}
+func (m *BannedPeer) XXX_Size() int {
+	return xxx_messageInfo_BannedPeer.Size(m)
+}
+func (m *BannedPeer) XXX_DiscardUnknown() {
+	xxx_messageInfo_BannedPeer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BannedPeer proto.InternalMessageInfo
+
+func (m *BannedPeer) GetPid() string {
+	if m != nil {
+		return m.Pid
+	}
+	return ""
+}
+
+func (m *BannedPeer) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *BannedPeer) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *BannedPeer) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type BannedPeers struct {
+	Items                []*BannedPeer `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *BannedPeers) Reset()         { *m = BannedPeers{} }
+func (m *BannedPeers) String() string { return proto.CompactTextString(m) }
+func (*BannedPeers) ProtoMessage()    {}
+func (*BannedPeers) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75fe6b0225e7a1d4, []int{4}
+}
+
+func (m *BannedPeers) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BannedPeers.Unmarshal(m, b)
+}
+func (m *BannedPeers) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BannedPeers.Marshal(b, m, deterministic)
+}
+func (m *BannedPeers) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BannedPeers.Merge(m, src)
+}
+func (m *BannedPeers) XXX_Size() int {
+	return xxx_messageInfo_BannedPeers.Size(m)
+}
+func (m *BannedPeers) XXX_DiscardUnknown() {
+	xxx_messageInfo_BannedPeers.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BannedPeers proto.InternalMessageInfo
+
+func (m *BannedPeers) GetItems() []*BannedPeer {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*PeerFaultReport)(nil), "types.PeerFaultReport")
+	proto.RegisterType((*ReqBanPeer)(nil), "types.ReqBanPeer")
+	proto.RegisterType((*ReqBannedPeers)(nil), "types.ReqBannedPeers")
+	proto.RegisterType((*BannedPeer)(nil), "types.BannedPeer")
+	proto.RegisterType((*BannedPeers)(nil), "types.BannedPeers")
+}
+
+func init() {
+	proto.RegisterFile("peerscore.proto", fileDescriptor_75fe6b0225e7a1d4)
+}
+
+var fileDescriptor_75fe6b0225e7a1d4 = []byte{
+	// 280 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x91, 0x41, 0x4b, 0xc3, 0x30,
+	0x18, 0x86, 0x69, 0xbb, 0x56, 0xfd, 0x06, 0x9b, 0x06, 0x19, 0x41, 0x10, 0x4b, 0x2f, 0x16, 0x0f,
+	0x2d, 0xac, 0xe0, 0x0f, 0xd8, 0x41, 0xf0, 0x20, 0x48, 0xf0, 0xe4, 0xad, 0x6b, 0x3f, 0x5d, 0x60,
+	0x4b, 0x62, 0xfa, 0x15, 0xec, 0xbf, 0x97, 0xa6, 0xd5, 0x55, 0xd8, 0xad, 0x0f, 0x7d, 0xf3, 0xbe,
+	0x0f, 0x09, 0x2c, 0x0d, 0xa2, 0x6d, 0x2a, 0x6d, 0x31, 0x33, 0x56, 0x93, 0x66, 0x21, 0x75, 0x06,
+	0x9b, 0xe4, 0x05, 0x96, 0xaf, 0x88, 0xf6, 0xa9, 0x6c, 0xf7, 0x24, 0xd0, 0x68, 0x4b, 0xec, 0x12,
+	0x02, 0x23, 0x6b, 0xee, 0xc5, 0x5e, 0x7a, 0x21, 0xfa, 0x4f, 0xb6, 0x00, 0x9f, 0x3a, 0xee, 0xc7,
+	0x5e, 0x1a, 0x0a, 0x9f, 0x3a, 0xc6, 0xe1, 0x0c, 0xad, 0x7d, 0x56, 0x1f, 0x9a, 0x07, 0x2e, 0xf5,
+	0x8b, 0xc9, 0x1e, 0x40, 0xe0, 0xd7, 0xa6, 0x54, 0x7d, 0xe9, 0x89, 0xa6, 0x1b, 0x38, 0xaf, 0x5b,
+	0x5b, 0x92, 0xd4, 0xca, 0xf5, 0x05, 0xe2, 0x8f, 0xd9, 0x0a, 0x22, 0x8b, 0x65, 0xa3, 0xd5, 0x58,
+	0x3a, 0x52, 0xbf, 0x66, 0xd6, 0xe6, 0xad, 0x33, 0xc8, 0x67, 0xc3, 0xda, 0x88, 0xc9, 0x03, 0x2c,
+	0x86, 0x35, 0x85, 0x75, 0x3f, 0xd8, 0x4c, 0xb3, 0xde, 0xff, 0x6c, 0x0d, 0x70, 0x0c, 0x9e, 0x30,
+	0x5b, 0x41, 0x84, 0xdf, 0x46, 0x5a, 0x1c, 0xbd, 0x46, 0x62, 0xd7, 0x10, 0x56, 0xba, 0x55, 0xe4,
+	0xa4, 0x42, 0x31, 0xc0, 0xc4, 0x75, 0x36, 0x75, 0x4d, 0x1e, 0x61, 0x3e, 0xd5, 0xb9, 0x87, 0x50,
+	0x12, 0x1e, 0x1a, 0xee, 0xc5, 0x41, 0x3a, 0x5f, 0x5f, 0x65, 0xee, 0xd2, 0xb3, 0x63, 0x44, 0x0c,
+	0xff, 0x37, 0x77, 0xef, 0xb7, 0x9f, 0x92, 0x76, 0xed, 0x36, 0xab, 0xf4, 0x21, 0x2f, 0x8a, 0x4a,
+	0xe5, 0xd5, 0xae, 0x94, 0xaa, 0x28, 0x72, 0x77, 0x64, 0x1b, 0xb9, 0x57, 0x2b, 0x7e, 0x06, 0x00,
+	0xd7, 0x2c, 0xb7, 0x3d, 0xc8, 0x01, 0x00, 0x00,
+}
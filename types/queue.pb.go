@@ -0,0 +1,285 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: queue.proto
+
+package types
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// QueueFrame 远程消息队列的传输帧, 用于跨进程承载 queue.Message
+type QueueFrame struct {
+	// 帧类型: 订阅, 发送, 投递, 回复, 关闭队列
+	Kind int32 `protobuf:"varint,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	// 消息编号, 回复帧通过该编号关联到请求
+	Id        int64  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	Topic     string `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`
+	Ty        int64  `protobuf:"varint,4,opt,name=ty,proto3" json:"ty,omitempty"`
+	WaitReply bool   `protobuf:"varint,5,opt,name=waitReply,proto3" json:"waitReply,omitempty"`
+	// 发送超时时间(纳秒), -1 表示一直等待
+	Timeout int64 `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// 消息数据的protobuf类型名称, 为空表示数据为nil
+	DataType string `protobuf:"bytes,7,opt,name=dataType,proto3" json:"dataType,omitempty"`
+	Data     []byte `protobuf:"bytes,8,opt,name=data,proto3" json:"data,omitempty"`
+	// 消息数据为error时的错误信息
+	Err string `protobuf:"bytes,9,opt,name=err,proto3" json:"err,omitempty"`
+	// W3C traceparent 格式的span上下文, 为空表示消息没有被追踪
+	TraceParent          string   `protobuf:"bytes,10,opt,name=traceParent,proto3" json:"traceParent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueueFrame) Reset()         { *m = QueueFrame{} }
+func (m *QueueFrame) String() string { return proto.CompactTextString(m) }
+func (*QueueFrame) ProtoMessage()    {}
+func (*QueueFrame) Descriptor() ([]byte, []int) {
+	return fileDescriptor_96e4d7d76a734cd8, []int{0}
+}
+
+func (m *QueueFrame) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueueFrame.Unmarshal(m, b)
+}
+func (m *QueueFrame) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueueFrame.Marshal(b, m, deterministic)
+}
+func (m *QueueFrame) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueueFrame.Merge(m, src)
+}
+func (m *QueueFrame) XXX_Size() int {
+	return xxx_messageInfo_QueueFrame.Size(m)
+}
+func (m *QueueFrame) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueueFrame.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueueFrame proto.InternalMessageInfo
+
+func (m *QueueFrame) GetKind() int32 {
+	if m != nil {
+		return m.Kind
+	}
+	return 0
+}
+
+func (m *QueueFrame) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *QueueFrame) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *QueueFrame) GetTy() int64 {
+	if m != nil {
+		return m.Ty
+	}
+	return 0
+}
+
+func (m *QueueFrame) GetWaitReply() bool {
+	if m != nil {
+		return m.WaitReply
+	}
+	return false
+}
+
+func (m *QueueFrame) GetTimeout() int64 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+func (m *QueueFrame) GetDataType() string {
+	if m != nil {
+		return m.DataType
+	}
+	return ""
+}
+
+func (m *QueueFrame) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *QueueFrame) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+func (m *QueueFrame) GetTraceParent() string {
+	if m != nil {
+		return m.TraceParent
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*QueueFrame)(nil), "types.QueueFrame")
+}
+
+func init() {
+	proto.RegisterFile("queue.proto", fileDescriptor_96e4d7d76a734cd8)
+}
+
+var fileDescriptor_96e4d7d76a734cd8 = []byte{
+	// 274 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x90, 0xc1, 0x4e, 0xf3, 0x30,
+	0x10, 0x84, 0x7f, 0xb7, 0x4d, 0x9a, 0x6c, 0x7f, 0x55, 0xb0, 0xe2, 0x60, 0x55, 0x20, 0xac, 0x9e,
+	0x7c, 0x4a, 0x10, 0xe1, 0x09, 0x38, 0xf4, 0x0c, 0xa6, 0x27, 0x6e, 0x6e, 0x62, 0x51, 0x0b, 0x12,
+	0x1b, 0x77, 0x23, 0x94, 0xd7, 0xe6, 0x09, 0x50, 0x5c, 0x41, 0x91, 0xb8, 0xcd, 0x8c, 0x67, 0xe4,
+	0xd5, 0x07, 0x8b, 0xf7, 0xde, 0xf4, 0xa6, 0xf0, 0xc1, 0x91, 0xc3, 0x84, 0x06, 0x6f, 0x0e, 0xeb,
+	0x4f, 0x06, 0xf0, 0x38, 0xc6, 0x9b, 0xa0, 0x5b, 0x83, 0x08, 0xb3, 0x57, 0xdb, 0x35, 0x9c, 0x09,
+	0x26, 0x13, 0x15, 0x35, 0x2e, 0x61, 0x62, 0x1b, 0x3e, 0x11, 0x4c, 0x4e, 0xd5, 0xc4, 0x36, 0x78,
+	0x01, 0x09, 0x39, 0x6f, 0x6b, 0x3e, 0x15, 0x4c, 0xe6, 0xea, 0x68, 0xc6, 0x16, 0x0d, 0x7c, 0x76,
+	0x6c, 0xd1, 0x80, 0x97, 0x90, 0x7f, 0x68, 0x4b, 0xca, 0xf8, 0xb7, 0x81, 0x27, 0x82, 0xc9, 0x4c,
+	0x9d, 0x02, 0xe4, 0x30, 0x27, 0xdb, 0x1a, 0xd7, 0x13, 0x4f, 0xe3, 0xe4, 0xdb, 0xe2, 0x0a, 0xb2,
+	0x46, 0x93, 0xde, 0x0e, 0xde, 0xf0, 0x79, 0xfc, 0xe0, 0xc7, 0x8f, 0xd7, 0x8d, 0x9a, 0x67, 0x82,
+	0xc9, 0xff, 0x2a, 0x6a, 0x3c, 0x83, 0xa9, 0x09, 0x81, 0xe7, 0xb1, 0x3a, 0x4a, 0x14, 0xb0, 0xa0,
+	0xa0, 0x6b, 0xf3, 0xa0, 0x83, 0xe9, 0x88, 0x43, 0x7c, 0xf9, 0x1d, 0xdd, 0x6e, 0x60, 0x19, 0x51,
+	0x6c, 0x83, 0xee, 0x0e, 0xde, 0x05, 0xc2, 0x3b, 0x48, 0x9f, 0x28, 0x18, 0xdd, 0xe2, 0x79, 0x11,
+	0xc1, 0x14, 0x27, 0x28, 0xab, 0xbf, 0xd1, 0xfa, 0x9f, 0x64, 0x37, 0xec, 0xfe, 0xfa, 0xf9, 0xea,
+	0xc5, 0xd2, 0xbe, 0xdf, 0x15, 0xb5, 0x6b, 0xcb, 0xaa, 0xaa, 0xbb, 0xb2, 0xde, 0x6b, 0xdb, 0x55,
+	0x55, 0x19, 0x17, 0xbb, 0x34, 0xb2, 0xae, 0xbe, 0x06, 0x00, 0x01, 0xb0, 0x4f, 0xf2, 0x7a, 0x01,
+	0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// QueueTransportClient is the client API for QueueTransport service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type QueueTransportClient interface {
+	Stream(ctx context.Context, opts ...grpc.CallOption) (QueueTransport_StreamClient, error)
+}
+
+type queueTransportClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQueueTransportClient(cc grpc.ClientConnInterface) QueueTransportClient {
+	return &queueTransportClient{cc}
+}
+
+func (c *queueTransportClient) Stream(ctx context.Context, opts ...grpc.CallOption) (QueueTransport_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_QueueTransport_serviceDesc.Streams[0], "/types.queueTransport/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &queueTransportStreamClient{stream}
+	return x, nil
+}
+
+type QueueTransport_StreamClient interface {
+	Send(*QueueFrame) error
+	Recv() (*QueueFrame, error)
+	grpc.ClientStream
+}
+
+type queueTransportStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *queueTransportStreamClient) Send(m *QueueFrame) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *queueTransportStreamClient) Recv() (*QueueFrame, error) {
+	m := new(QueueFrame)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// QueueTransportServer is the server API for QueueTransport service.
+type QueueTransportServer interface {
+	Stream(QueueTransport_StreamServer) error
+}
+
+// UnimplementedQueueTransportServer can be embedded to have forward compatible implementations.
+type UnimplementedQueueTransportServer struct {
+}
+
+func (*UnimplementedQueueTransportServer) Stream(srv QueueTransport_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+
+func RegisterQueueTransportServer(s *grpc.Server, srv QueueTransportServer) {
+	s.RegisterService(&_QueueTransport_serviceDesc, srv)
+}
+
+func _QueueTransport_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(QueueTransportServer).Stream(&queueTransportStreamServer{stream})
+}
+
+type QueueTransport_StreamServer interface {
+	Send(*QueueFrame) error
+	Recv() (*QueueFrame, error)
+	grpc.ServerStream
+}
+
+type queueTransportStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *queueTransportStreamServer) Send(m *QueueFrame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *queueTransportStreamServer) Recv() (*QueueFrame, error) {
+	m := new(QueueFrame)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _QueueTransport_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "types.queueTransport",
+	HandlerType: (*QueueTransportServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _QueueTransport_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "queue.proto",
+}
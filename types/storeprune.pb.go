@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: storeprune.proto
+
+package types
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// mavl状态裁剪进度, 和裁剪删除的节点写入同一个batch, 重启之后从游标处继续
+type StorePruneProgress struct {
+	// 当前这一轮裁剪的目标高度, 0表示没有正在进行的裁剪
+	PruningHeight int64 `protobuf:"varint,1,opt,name=pruningHeight,proto3" json:"pruningHeight,omitempty"`
+	// 上一次处理的叶子节点索引
+	Cursor []byte `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// 已经完成裁剪的高度
+	PrunedHeight int64 `protobuf:"varint,3,opt,name=prunedHeight,proto3" json:"prunedHeight,omitempty"`
+	// 累计删除的节点数目
+	ReclaimedNodes int64 `protobuf:"varint,4,opt,name=reclaimedNodes,proto3" json:"reclaimedNodes,omitempty"`
+	// 本轮已经扫描的叶子节点索引数目
+	Scanned              int64    `protobuf:"varint,5,opt,name=scanned,proto3" json:"scanned,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StorePruneProgress) Reset()         { *m = StorePruneProgress{} }
+func (m *StorePruneProgress) String() string { return proto.CompactTextString(m) }
+func (*StorePruneProgress) ProtoMessage()    {}
+func (*StorePruneProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5e05114164d3fcfe, []int{0}
+}
+
+func (m *StorePruneProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StorePruneProgress.Unmarshal(m, b)
+}
+func (m *StorePruneProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StorePruneProgress.Marshal(b, m, deterministic)
+}
+func (m *StorePruneProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StorePruneProgress.Merge(m, src)
+}
+func (m *StorePruneProgress) XXX_Size() int {
+	return xxx_messageInfo_StorePruneProgress.Size(m)
+}
+func (m *StorePruneProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_StorePruneProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StorePruneProgress proto.InternalMessageInfo
+
+func (m *StorePruneProgress) GetPruningHeight() int64 {
+	if m != nil {
+		return m.PruningHeight
+	}
+	return 0
+}
+
+func (m *StorePruneProgress) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+func (m *StorePruneProgress) GetPrunedHeight() int64 {
+	if m != nil {
+		return m.PrunedHeight
+	}
+	return 0
+}
+
+func (m *StorePruneProgress) GetReclaimedNodes() int64 {
+	if m != nil {
+		return m.ReclaimedNodes
+	}
+	return 0
+}
+
+func (m *StorePruneProgress) GetScanned() int64 {
+	if m != nil {
+		return m.Scanned
+	}
+	return 0
+}
+
+// mavl状态裁剪状态
+type StorePruneStatus struct {
+	Enable bool `protobuf:"varint,1,opt,name=enable,proto3" json:"enable,omitempty"`
+	Paused bool `protobuf:"varint,2,opt,name=paused,proto3" json:"paused,omitempty"`
+	// 保留最近retainHeight个高度的全部状态
+	RetainHeight int64 `protobuf:"varint,3,opt,name=retainHeight,proto3" json:"retainHeight,omitempty"`
+	// 每archiveInterval个高度永久保留一个状态, 0表示不保留
+	ArchiveInterval int64 `protobuf:"varint,4,opt,name=archiveInterval,proto3" json:"archiveInterval,omitempty"`
+	// 最近一次提交的区块高度
+	CurHeight int64 `protobuf:"varint,5,opt,name=curHeight,proto3" json:"curHeight,omitempty"`
+	// 该高度以下除归档高度之外的状态都已经裁剪
+	PrunedHeight int64 `protobuf:"varint,6,opt,name=prunedHeight,proto3" json:"prunedHeight,omitempty"`
+	// 当前这一轮裁剪的目标高度, 该高度以下的状态可能已经不完整
+	PruningHeight int64 `protobuf:"varint,7,opt,name=pruningHeight,proto3" json:"pruningHeight,omitempty"`
+	// 累计删除的节点数目
+	ReclaimedNodes int64 `protobuf:"varint,8,opt,name=reclaimedNodes,proto3" json:"reclaimedNodes,omitempty"`
+	// 超出保留窗口还没有裁剪的区块数目
+	Backlog int64 `protobuf:"varint,9,opt,name=backlog,proto3" json:"backlog,omitempty"`
+	// 本轮已经扫描的叶子节点索引数目
+	Scanned              int64    `protobuf:"varint,10,opt,name=scanned,proto3" json:"scanned,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StorePruneStatus) Reset()         { *m = StorePruneStatus{} }
+func (m *StorePruneStatus) String() string { return proto.CompactTextString(m) }
+func (*StorePruneStatus) ProtoMessage()    {}
+func (*StorePruneStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5e05114164d3fcfe, []int{1}
+}
+
+func (m *StorePruneStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StorePruneStatus.Unmarshal(m, b)
+}
+func (m *StorePruneStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StorePruneStatus.Marshal(b, m, deterministic)
+}
+func (m *StorePruneStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StorePruneStatus.Merge(m, src)
+}
+func (m *StorePruneStatus) XXX_Size() int {
+	return xxx_messageInfo_StorePruneStatus.Size(m)
+}
+func (m *StorePruneStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_StorePruneStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StorePruneStatus proto.InternalMessageInfo
+
+func (m *StorePruneStatus) GetEnable() bool {
+	if m != nil {
+		return m.Enable
+	}
+	return false
+}
+
+func (m *StorePruneStatus) GetPaused() bool {
+	if m != nil {
+		return m.Paused
+	}
+	return false
+}
+
+func (m *StorePruneStatus) GetRetainHeight() int64 {
+	if m != nil {
+		return m.RetainHeight
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetArchiveInterval() int64 {
+	if m != nil {
+		return m.ArchiveInterval
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetCurHeight() int64 {
+	if m != nil {
+		return m.CurHeight
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetPrunedHeight() int64 {
+	if m != nil {
+		return m.PrunedHeight
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetPruningHeight() int64 {
+	if m != nil {
+		return m.PruningHeight
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetReclaimedNodes() int64 {
+	if m != nil {
+		return m.ReclaimedNodes
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetBacklog() int64 {
+	if m != nil {
+		return m.Backlog
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetScanned() int64 {
+	if m != nil {
+		return m.Scanned
+	}
+	return 0
+}
+
+// 暂停或者恢复状态裁剪
+type ReqStorePrune struct {
+	Pause                bool     `protobuf:"varint,1,opt,name=pause,proto3" json:"pause,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReqStorePrune) Reset()         { *m = ReqStorePrune{} }
+func (m *ReqStorePrune) String() string { return proto.CompactTextString(m) }
+func (*ReqStorePrune) ProtoMessage()    {}
+func (*ReqStorePrune) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5e05114164d3fcfe, []int{2}
+}
+
+func (m *ReqStorePrune) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReqStorePrune.Unmarshal(m, b)
+}
+func (m *ReqStorePrune) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReqStorePrune.Marshal(b, m, deterministic)
+}
+func (m *ReqStorePrune) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReqStorePrune.Merge(m, src)
+}
+func (m *ReqStorePrune) XXX_Size() int {
+	return xxx_messageInfo_ReqStorePrune.Size(m)
+}
+func (m *ReqStorePrune) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReqStorePrune.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReqStorePrune proto.InternalMessageInfo
+
+func (m *ReqStorePrune) GetPause() bool {
+	if m != nil {
+		return m.Pause
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*StorePruneProgress)(nil), "types.StorePruneProgress")
+	proto.RegisterType((*StorePruneStatus)(nil), "types.StorePruneStatus")
+	proto.RegisterType((*ReqStorePrune)(nil), "types.ReqStorePrune")
+}
+
+func init() {
+	proto.RegisterFile("storeprune.proto", fileDescriptor_5e05114164d3fcfe)
+}
+
+var fileDescriptor_5e05114164d3fcfe = []byte{
+	// 321 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0xcf, 0x4e, 0xc2, 0x40,
+	0x10, 0xc6, 0x53, 0x90, 0x7f, 0x13, 0x50, 0xb2, 0x31, 0xa6, 0x07, 0x8d, 0x84, 0xa8, 0xe1, 0x04,
+	0x87, 0xbe, 0x81, 0x27, 0xbd, 0x18, 0x52, 0x6e, 0xde, 0xb6, 0xdb, 0x49, 0xbb, 0xb1, 0xec, 0xd6,
+	0xd9, 0x2d, 0x89, 0x6f, 0xe6, 0x83, 0xf8, 0x40, 0xa6, 0xcb, 0x1a, 0x28, 0xc5, 0xe3, 0xf7, 0xed,
+	0x4c, 0xfb, 0xcb, 0x2f, 0x03, 0x53, 0x63, 0x35, 0x61, 0x49, 0x95, 0xc2, 0x65, 0x49, 0xda, 0x6a,
+	0xd6, 0xb3, 0x5f, 0x25, 0x9a, 0xf9, 0x77, 0x00, 0x6c, 0x53, 0xbf, 0xad, 0xeb, 0xb7, 0x35, 0xe9,
+	0x8c, 0xd0, 0x18, 0xf6, 0x00, 0x93, 0x7a, 0x58, 0xaa, 0xec, 0x05, 0x65, 0x96, 0xdb, 0x30, 0x98,
+	0x05, 0x8b, 0x6e, 0xdc, 0x2c, 0xd9, 0x0d, 0xf4, 0x45, 0x45, 0x46, 0x53, 0xd8, 0x99, 0x05, 0x8b,
+	0x71, 0xec, 0x13, 0x9b, 0xc3, 0xd8, 0xfd, 0x2a, 0xf5, 0xcb, 0x5d, 0xb7, 0xdc, 0xe8, 0xd8, 0x13,
+	0x5c, 0x12, 0x8a, 0x82, 0xcb, 0x2d, 0xa6, 0x6f, 0x3a, 0x45, 0x13, 0x5e, 0xb8, 0xa9, 0x93, 0x96,
+	0x85, 0x30, 0x30, 0x82, 0x2b, 0x85, 0x69, 0xd8, 0x73, 0x03, 0x7f, 0x71, 0xfe, 0xd3, 0x81, 0xe9,
+	0x01, 0x7d, 0x63, 0xb9, 0xad, 0x4c, 0x8d, 0x84, 0x8a, 0x27, 0x05, 0x3a, 0xe2, 0x61, 0xec, 0x53,
+	0xdd, 0x97, 0xbc, 0x32, 0x98, 0x3a, 0xd4, 0x61, 0xec, 0x53, 0x8d, 0x4a, 0x68, 0xb9, 0x54, 0x4d,
+	0xd4, 0xe3, 0x8e, 0x2d, 0xe0, 0x8a, 0x93, 0xc8, 0xe5, 0x0e, 0x5f, 0x95, 0x45, 0xda, 0xf1, 0xc2,
+	0xb3, 0x9e, 0xd6, 0xec, 0x16, 0x46, 0xa2, 0x22, 0xff, 0xa9, 0x3d, 0xee, 0xa1, 0x68, 0x69, 0xe9,
+	0x9f, 0xd1, 0xd2, 0x12, 0x3f, 0x38, 0x27, 0xbe, 0x2d, 0x6f, 0xf8, 0x9f, 0xbc, 0x84, 0x8b, 0x8f,
+	0x42, 0x67, 0xe1, 0x68, 0x2f, 0xcf, 0xc7, 0x63, 0xad, 0xd0, 0xd4, 0xfa, 0x08, 0x93, 0x18, 0x3f,
+	0x0f, 0x62, 0xd9, 0x35, 0xf4, 0x9c, 0x2c, 0x6f, 0x74, 0x1f, 0x9e, 0xef, 0xdf, 0xef, 0x32, 0x69,
+	0xf3, 0x2a, 0x59, 0x0a, 0xbd, 0x5d, 0x45, 0x91, 0x50, 0x2b, 0x91, 0x73, 0xa9, 0xa2, 0x68, 0xe5,
+	0x2e, 0x2b, 0xe9, 0xbb, 0x3b, 0x8b, 0x7e, 0x07, 0x00, 0xa6, 0xa6, 0x23, 0x00, 0x7b, 0x02, 0x00,
+	0x00,
+}
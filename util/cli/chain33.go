@@ -18,8 +18,10 @@ import (
 	"net/http"
 	_ "net/http/pprof" //
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"syscall"
 
 	"github.com/33cn/chain33/p2p"
 
@@ -34,6 +36,7 @@ import (
 	"github.com/33cn/chain33/common/limits"
 	clog "github.com/33cn/chain33/common/log"
 	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/common/trace"
 	"github.com/33cn/chain33/common/version"
 	"github.com/33cn/chain33/consensus"
 	"github.com/33cn/chain33/executor"
@@ -55,10 +58,11 @@ var (
 	waitPid     = flag.Bool("waitpid", false, "p2p stuck until seed save info wallet & wallet unlock")
 	rollback    = flag.Int64("rollback", 0, "rollback block")
 	save        = flag.Bool("save", false, "rollback save temporary block")
-	importFile  = flag.String("import", "", "import block file name")
+	importFile  = flag.String("import", "", "import block file name, export dir or - for stdin")
 	exportTitle = flag.String("export", "", "export block title name")
 	fileDir     = flag.String("filedir", "", "import/export block file dir,defalut current path")
 	startHeight = flag.Int64("startheight", 0, "export block start height")
+	remote      = flag.Bool("remote", false, "run modules of remoteQueue as a separate process")
 )
 
 //RunChain33 : run Chain33
@@ -150,12 +154,21 @@ func RunChain33(name, defCfg string) {
 	version.SetStoreDBVersion(cfg.Store.StoreDBVersion)
 	version.SetAppVersion(cfg.Version)
 	log.Info(cfg.Title + "-app:" + version.GetAppVersion() + " chain33:" + version.GetVersion() + " localdb:" + version.GetLocalDBVersion() + " statedb:" + version.GetStoreDBVersion())
+	//set trace
+	if err := trace.Init(cfg.Trace); err != nil {
+		panic(err)
+	}
+	defer trace.Close()
+	if *remote {
+		runRemoteModules(chain33Cfg)
+		return
+	}
 	log.Info("loading queue")
 	q := queue.New("channel")
 	q.SetConfig(chain33Cfg)
 
 	log.Info("loading mempool module")
-	mem := mempool.New(chain33Cfg)
+	mem := loadModule(cfg, "mempool", func() module { return mempool.New(chain33Cfg) })
 	mem.SetQueueClient(q.Client())
 
 	log.Info("loading execs module")
@@ -179,11 +192,11 @@ func RunChain33(name, defCfg string) {
 	cs.SetQueueClient(q.Client())
 
 	//jsonrpc, grpc, channel 三种模式
-	rpcapi := rpc.New(chain33Cfg)
+	rpcapi := loadModule(cfg, "rpc", func() module { return rpc.New(chain33Cfg) })
 	rpcapi.SetQueueClient(q.Client())
 
 	log.Info("loading wallet module")
-	walletm := wallet.New(chain33Cfg)
+	walletm := loadModule(cfg, "wallet", func() module { return wallet.New(chain33Cfg) })
 	walletm.SetQueueClient(q.Client())
 
 	chain.Rollbackblock()
@@ -206,8 +219,23 @@ func RunChain33(name, defCfg string) {
 	health := util.NewHealthCheckServer(q.Client())
 	health.Start(cfg.Health)
 	metrics.StartMetrics(chain33Cfg)
+	var remoteServer *queue.RemoteServer
+	if cfg.RemoteQueue != nil && cfg.RemoteQueue.ListenAddr != "" {
+		var err error
+		remoteServer, err = queue.NewRemoteServer(q, cfg.RemoteQueue)
+		if err != nil {
+			panic(err)
+		}
+		if _, err = remoteServer.Listen(cfg.RemoteQueue.ListenAddr); err != nil {
+			panic(err)
+		}
+	}
 	defer func() {
 		//close all module,clean some resource
+		if remoteServer != nil {
+			log.Info("begin close remote queue server")
+			remoteServer.Close()
+		}
 		log.Info("begin close health module")
 		health.Close()
 		log.Info("begin close blockchain module")
@@ -233,6 +261,75 @@ func RunChain33(name, defCfg string) {
 	q.Start()
 }
 
+//module 可以放到独立进程中运行的模块
+type module interface {
+	SetQueueClient(client queue.Client)
+	Close()
+}
+
+//remoteModule 在独立进程中运行的模块, 核心节点中只占位
+type remoteModule struct{}
+
+func (m *remoteModule) SetQueueClient(client queue.Client) {}
+func (m *remoteModule) Close()                             {}
+
+func isRemoteModule(cfg *types.Config, name string) bool {
+	if cfg.RemoteQueue == nil || cfg.RemoteQueue.ListenAddr == "" {
+		return false
+	}
+	for _, m := range cfg.RemoteQueue.Modules {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+//loadModule 配置为远程运行的模块由模块进程加载
+func loadModule(cfg *types.Config, name string, create func() module) module {
+	if isRemoteModule(cfg, name) {
+		log.Info("module run in remote process", "module", name)
+		return &remoteModule{}
+	}
+	return create()
+}
+
+//runRemoteModules 以独立进程运行配置的模块, 通过远程队列连接到核心节点
+func runRemoteModules(chain33Cfg *types.Chain33Config) {
+	cfg := chain33Cfg.GetModuleConfig()
+	if cfg.RemoteQueue == nil || cfg.RemoteQueue.ServerAddr == "" {
+		panic("remoteQueue.serverAddr is not configured")
+	}
+	var modules []module
+	for _, name := range cfg.RemoteQueue.Modules {
+		var m module
+		switch name {
+		case "mempool":
+			m = mempool.New(chain33Cfg)
+		case "wallet":
+			m = wallet.New(chain33Cfg)
+		case "rpc":
+			m = rpc.New(chain33Cfg)
+		default:
+			panic("module not support remote mode: " + name)
+		}
+		client, err := queue.NewRemoteClient(chain33Cfg, cfg.RemoteQueue)
+		if err != nil {
+			panic(err)
+		}
+		log.Info("loading remote module", "module", name, "server", cfg.RemoteQueue.ServerAddr)
+		m.SetQueueClient(client)
+		modules = append(modules, m)
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	s := <-c
+	log.Info("Got signal:", s)
+	for _, m := range modules {
+		m.Close()
+	}
+}
+
 func createFile(filename string) (*os.File, error) {
 	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
@@ -91,6 +91,9 @@ func Run(RPCAddr, ParaName, name string) {
 		commands.TxCmd(),
 		commands.WalletCmd(),
 		commands.VersionCmd(),
+		commands.LogCmd(),
+		commands.StoreCmd(),
+		commands.DBCmd(),
 		commands.OneStepSendCmd(),
 		closeCmd,
 		commands.AssetCmd(),
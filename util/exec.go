@@ -10,6 +10,7 @@ import (
 	clientApi "github.com/33cn/chain33/client"
 	"github.com/33cn/chain33/common"
 	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/types"
 )
@@ -200,6 +201,9 @@ func ReportErrEventToFront(logger log.Logger, client queue.Client, frommodule st
 	}
 
 	logger.Debug("SendErrEventToFront", "frommodule", frommodule, "tomodule", tomodule, "err", err)
+	if err == types.ErrDataBaseDamage {
+		metrics.DBWriteErrors.Inc(1)
+	}
 
 	var reportErrEvent types.ReportErrEvent
 	reportErrEvent.Frommodule = frommodule
@@ -5,13 +5,16 @@
 package util
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
-	"time"
-
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/33cn/chain33/client"
 	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/metrics"
 	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/types"
 )
@@ -22,12 +25,84 @@ var (
 	checkInterval  uint32 = 5                // 5s
 )
 
+const (
+	defaultMinPeers           = 1
+	defaultMaxHeightGap       = 10
+	defaultMaxClockDrift      = 10000 //ms
+	defaultMaxMempoolFullness = 0.95
+	defaultModuleStallTimeout = 60 //s
+	defaultMempoolCapacity    = 10240
+	healthQueryTimeout        = 3 * time.Second
+	//最近一段时间内有数据库写入错误时存活检查失败, 之前的错误不再影响
+	dbWriteErrorWindow = 10 * time.Minute
+)
+
+//健康状态
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusFail     = "fail"
+	HealthStatusStarting = "starting"
+)
+
+// ModuleHealth 模块消息通道的存活状态
+type ModuleHealth struct {
+	Name       string    `json:"name"`
+	Alive      bool      `json:"alive"`
+	Pending    int       `json:"pending"`
+	LastActive time.Time `json:"lastActive"`
+}
+
+// HealthReport /healthz 和 /readyz 返回的节点健康状况
+type HealthReport struct {
+	Status              string          `json:"status"`
+	Ready               bool            `json:"ready"`
+	Time                time.Time       `json:"time"`
+	IsSync              bool            `json:"isSync"`
+	Height              int64           `json:"height"`
+	BestPeerHeight      int64           `json:"bestPeerHeight"`
+	HeightGap           int64           `json:"heightGap"`
+	Peers               int             `json:"peers"`
+	NtpClockSync        bool            `json:"ntpClockSync"`
+	ClockDriftMs        int64           `json:"clockDriftMs"`
+	MempoolSize         int64           `json:"mempoolSize"`
+	MempoolCapacity     int64           `json:"mempoolCapacity"`
+	MempoolFullness     float64         `json:"mempoolFullness"`
+	DBWriteErrors       int64           `json:"dbWriteErrors"`
+	RecentDBWriteErrors int64           `json:"recentDbWriteErrors"`
+	WalletFatalFailure  bool            `json:"walletFatalFailure"`
+	Modules             []*ModuleHealth `json:"modules"`
+	//存活检查失败的原因, 不为空时 /healthz 返回503
+	Failures []string `json:"failures,omitempty"`
+	//就绪检查失败的原因, 和Failures任一不为空时 /readyz 返回503
+	NotReady []string `json:"notReady,omitempty"`
+}
+
+//counterSample 计数器在某一时刻的值
+type counterSample struct {
+	time  time.Time
+	count int64
+}
+
+//topicStatuser 可以查询模块消息通道状态的queue client
+type topicStatuser interface {
+	TopicStatus() []*queue.TopicStatus
+}
+
 // HealthCheckServer  a node's health check server
 type HealthCheckServer struct {
-	api  client.QueueProtocolAPI
-	l    net.Listener
-	quit chan struct{}
-	wg   sync.WaitGroup
+	api         client.QueueProtocolAPI
+	client      queue.Client
+	cfg         types.HealthCheck
+	l           net.Listener
+	mu          sync.RWMutex
+	report      *HealthReport
+	ready       bool
+	unSyncTimes uint32
+	quit        chan struct{}
+	wg          sync.WaitGroup
+	//数据库写入错误计数的采样, 用于计算最近一段时间内的错误数
+	errMu        sync.Mutex
+	dbErrSamples []counterSample
 }
 
 // Close NewHealthCheckServer close
@@ -42,7 +117,7 @@ func NewHealthCheckServer(c queue.Client) *HealthCheckServer {
 	if c == nil {
 		return nil
 	}
-	h := &HealthCheckServer{}
+	h := &HealthCheckServer{client: c}
 	var err error
 	h.api, err = client.New(c, nil)
 	if err != nil {
@@ -55,70 +130,260 @@ func NewHealthCheckServer(c queue.Client) *HealthCheckServer {
 // Start HealthCheckServer start
 func (s *HealthCheckServer) Start(cfg *types.HealthCheck) {
 	if cfg != nil {
-		if cfg.ListenAddr != "" {
-			listenAddr = cfg.ListenAddr
-		}
-		if cfg.CheckInterval != 0 {
-			checkInterval = cfg.CheckInterval
-		}
-		if cfg.UnSyncMaxTimes != 0 {
-			unSyncMaxTimes = cfg.UnSyncMaxTimes
-		}
+		s.cfg = *cfg
+	}
+	if s.cfg.ListenAddr == "" {
+		s.cfg.ListenAddr = listenAddr
+	}
+	if s.cfg.CheckInterval == 0 {
+		s.cfg.CheckInterval = checkInterval
+	}
+	if s.cfg.UnSyncMaxTimes == 0 {
+		s.cfg.UnSyncMaxTimes = unSyncMaxTimes
+	}
+	if s.cfg.MinPeers == 0 {
+		s.cfg.MinPeers = defaultMinPeers
+	}
+	if s.cfg.MaxHeightGap == 0 {
+		s.cfg.MaxHeightGap = defaultMaxHeightGap
+	}
+	if s.cfg.MaxClockDrift == 0 {
+		s.cfg.MaxClockDrift = defaultMaxClockDrift
+	}
+	if s.cfg.MaxMempoolFullness <= 0 || s.cfg.MaxMempoolFullness > 1 {
+		s.cfg.MaxMempoolFullness = defaultMaxMempoolFullness
+	}
+	if s.cfg.ModuleStallTimeout == 0 {
+		s.cfg.ModuleStallTimeout = defaultModuleStallTimeout
+	}
+	log.Info("healthCheck start ", "addr", s.cfg.ListenAddr, "inter", s.cfg.CheckInterval, "times", s.cfg.UnSyncMaxTimes)
+	l, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		log.Error("healthCheck ", "listen err", err)
+	} else {
+		s.l = l
+		s.wg.Add(1)
+		go s.serve(l)
 	}
-	log.Info("healthCheck start ", "addr", listenAddr, "inter", checkInterval, "times", unSyncMaxTimes)
 	s.wg.Add(1)
 	go s.healthCheck()
+}
 
+func (s *HealthCheckServer) serve(l net.Listener) {
+	defer s.wg.Done()
+	err := http.Serve(l, s.Handler())
+	if err != nil {
+		log.Debug("healthCheck serve", "err", err)
+	}
 }
 
-func (s *HealthCheckServer) listen(on bool) error {
-	if on {
-		listener, err := net.Listen("tcp", listenAddr)
-		if err != nil {
-			return err
+// Handler 返回提供 /healthz 和 /readyz 的http handler, 可以用于kubernetes的存活和就绪探针
+func (s *HealthCheckServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		s.writeReport(w, false)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s.writeReport(w, true)
+	})
+	return mux
+}
+
+func (s *HealthCheckServer) writeReport(w http.ResponseWriter, readiness bool) {
+	s.mu.RLock()
+	report, ready := s.report, s.ready
+	s.mu.RUnlock()
+	code := http.StatusOK
+	if report == nil {
+		report = &HealthReport{Status: HealthStatusStarting, Time: time.Now()}
+		code = http.StatusServiceUnavailable
+	} else if len(report.Failures) > 0 || (readiness && !ready) {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(report)
+	if err != nil {
+		log.Error("healthCheck ", "write report err", err)
+	}
+}
+
+// Report 返回最近一次检查的结果, 还没有检查时返回nil
+func (s *HealthCheckServer) Report() *HealthReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.report
+}
+
+func (s *HealthCheckServer) getHealth() *HealthReport {
+	report := &HealthReport{Time: time.Now()}
+	reply, err := s.api.IsSync()
+	if err != nil {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("isSync: %v", err))
+	} else {
+		report.IsSync = reply.IsOk
+	}
+	header, err := s.api.GetLastHeader()
+	if err != nil {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("lastHeader: %v", err))
+	} else {
+		report.Height = header.Height
+	}
+	peerList, err := s.api.PeerInfo(&types.P2PGetPeerReq{})
+	if err != nil {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("peerInfo: %v", err))
+	} else {
+		for _, peer := range peerList.Peers {
+			if peer.Self {
+				continue
+			}
+			report.Peers++
+			if peer.Header != nil && peer.Header.Height > report.BestPeerHeight {
+				report.BestPeerHeight = peer.Header.Height
+			}
 		}
-		s.l = listener
-		log.Info("healthCheck listen open")
-		return nil
+		if report.BestPeerHeight > report.Height {
+			report.HeightGap = report.BestPeerHeight - report.Height
+		}
+	}
+	ntp, err := s.api.IsNtpClockSync()
+	if err == nil {
+		report.NtpClockSync = ntp.IsOk
 	}
+	report.ClockDriftMs = metrics.ClockDrift.Value()
+	s.getMempoolHealth(report)
+	report.DBWriteErrors = metrics.DBWriteErrors.Count()
+	report.RecentDBWriteErrors = s.recentDBWriteErrors(report.Time, report.DBWriteErrors)
+	fatal, err := s.api.ExecWalletFunc("wallet", "FatalFailure", &types.ReqNil{})
+	if err != nil {
+		log.Debug("healthCheck ", "wallet FatalFailure err", err)
+	} else if flag, ok := fatal.(*types.Int32); ok {
+		report.WalletFatalFailure = flag.Data == 1
+	}
+	s.getModuleHealth(report)
 
-	if s.l != nil {
-		err := s.l.Close()
-		if err != nil {
-			return err
+	if report.RecentDBWriteErrors > 0 {
+		report.Failures = append(report.Failures, fmt.Sprintf("db write errors: %d", report.RecentDBWriteErrors))
+	}
+	if report.WalletFatalFailure {
+		report.Failures = append(report.Failures, "wallet fatal failure")
+	}
+	for _, m := range report.Modules {
+		if !m.Alive {
+			report.Failures = append(report.Failures, fmt.Sprintf("module %s stalled, pending: %d", m.Name, m.Pending))
 		}
-		log.Info("healthCheck listen close")
-		s.l = nil
 	}
+	if !report.IsSync {
+		report.NotReady = append(report.NotReady, "not sync")
+	}
+	if report.Peers < int(s.cfg.MinPeers) {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("peers %d < %d", report.Peers, s.cfg.MinPeers))
+	}
+	if report.HeightGap > s.cfg.MaxHeightGap {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("height gap %d > %d", report.HeightGap, s.cfg.MaxHeightGap))
+	}
+	if report.ClockDriftMs > s.cfg.MaxClockDrift || report.ClockDriftMs < -s.cfg.MaxClockDrift {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("clock drift %dms", report.ClockDriftMs))
+	}
+	if report.MempoolFullness > s.cfg.MaxMempoolFullness {
+		report.NotReady = append(report.NotReady, fmt.Sprintf("mempool fullness %.2f", report.MempoolFullness))
+	}
+	report.Status = HealthStatusOK
+	if len(report.Failures) > 0 {
+		report.Status = HealthStatusFail
+	}
+	return report
+}
 
-	return nil
+//recentDBWriteErrors 根据每次检查时的采样计算dbWriteErrorWindow内新增的错误数
+//第一次检查之前的错误都算作最近的错误
+func (s *HealthCheckServer) recentDBWriteErrors(now time.Time, count int64) int64 {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if len(s.dbErrSamples) == 0 {
+		s.dbErrSamples = append(s.dbErrSamples, counterSample{time: now})
+	}
+	s.dbErrSamples = append(s.dbErrSamples, counterSample{time: now, count: count})
+	//保留窗口开始之前的最后一个采样作为基准
+	for len(s.dbErrSamples) > 1 && now.Sub(s.dbErrSamples[1].time) >= dbWriteErrorWindow {
+		s.dbErrSamples = s.dbErrSamples[1:]
+	}
+	return count - s.dbErrSamples[0].count
 }
 
-func (s *HealthCheckServer) getHealth(sync bool) (bool, error) {
-	reply, err := s.api.IsSync()
+func (s *HealthCheckServer) getMempoolHealth(report *HealthReport) {
+	msg := s.client.NewMessage("mempool", types.EventGetMempoolSize, nil)
+	err := s.client.SendTimeout(msg, true, healthQueryTimeout)
 	if err != nil {
-		return false, err
+		log.Debug("healthCheck ", "mempool size err", err)
+		return
 	}
-
-	peerList, err := s.api.PeerInfo(&types.P2PGetPeerReq{})
+	resp, err := s.client.WaitTimeout(msg, healthQueryTimeout)
 	if err != nil {
-		return false, err
+		log.Debug("healthCheck ", "mempool size err", err)
+		return
+	}
+	if size, ok := resp.GetData().(*types.MempoolSize); ok {
+		report.MempoolSize = size.Size
 	}
+	report.MempoolCapacity = defaultMempoolCapacity
+	if cfg := s.client.GetConfig().GetModuleConfig().Mempool; cfg != nil && cfg.PoolCacheSize > 0 {
+		report.MempoolCapacity = cfg.PoolCacheSize
+	}
+	report.MempoolFullness = float64(report.MempoolSize) / float64(report.MempoolCapacity)
+}
 
-	log.Debug("healthCheck tick", "peers", len(peerList.Peers), "isCaughtUp", reply.IsOk,
-		"health", len(peerList.Peers) > 1 && reply.IsOk, "listen", sync)
+//getModuleHealth 模块有待处理的消息, 但超过ModuleStallTimeout没有取走消息, 认为模块已经阻塞
+func (s *HealthCheckServer) getModuleHealth(report *HealthReport) {
+	statuser, ok := s.client.(topicStatuser)
+	if !ok {
+		return
+	}
+	status := make(map[string]*queue.TopicStatus)
+	var names []string
+	for _, st := range statuser.TopicStatus() {
+		status[st.Topic] = st
+		names = append(names, st.Topic)
+	}
+	if len(s.cfg.Modules) > 0 {
+		names = s.cfg.Modules
+	}
+	stall := time.Duration(s.cfg.ModuleStallTimeout) * time.Second
+	for _, name := range names {
+		m := &ModuleHealth{Name: name}
+		if st, ok := status[name]; ok {
+			m.Pending = st.Pending
+			m.LastActive = st.LastRecv
+			m.Alive = st.Pending == 0 || time.Since(st.LastRecv) < stall
+		}
+		report.Modules = append(report.Modules, m)
+	}
+}
 
-	return len(peerList.Peers) > 1 && reply.IsOk, nil
+//check 更新检查结果, 已经就绪的节点连续unSyncMaxTimes次不同步才认为不再就绪
+func (s *HealthCheckServer) check() {
+	report := s.getHealth()
+	ready := len(report.Failures) == 0 && len(report.NotReady) == 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ready {
+		s.unSyncTimes = 0
+	} else if s.ready && len(report.Failures) == 0 && s.unSyncTimes < s.cfg.UnSyncMaxTimes {
+		s.unSyncTimes++
+		ready = true
+	}
+	log.Debug("healthCheck tick", "peers", report.Peers, "isCaughtUp", report.IsSync, "status", report.Status, "ready", ready)
+	report.Ready = ready
+	s.report = report
+	s.ready = ready
 }
 
 func (s *HealthCheckServer) healthCheck() {
-	ticker := time.NewTicker(time.Second * time.Duration(checkInterval))
+	ticker := time.NewTicker(time.Second * time.Duration(s.cfg.CheckInterval))
 	defer ticker.Stop()
 	defer s.wg.Done()
 
-	var sync bool
-	var unSyncTimes uint32
-
+	s.check()
 	for {
 		select {
 		case <-s.quit:
@@ -133,35 +398,7 @@ func (s *HealthCheckServer) healthCheck() {
 			}
 			return
 		case <-ticker.C:
-			health, err := s.getHealth(sync)
-			if err != nil {
-				continue
-			}
-			//sync
-			if health {
-				if !sync {
-					err = s.listen(true)
-					if err != nil {
-						log.Error("healthCheck ", "listen open err", err.Error())
-						continue
-					}
-					sync = true
-				}
-				unSyncTimes = 0
-
-			} else {
-				if sync {
-					if unSyncTimes >= unSyncMaxTimes {
-						err = s.listen(false)
-						if err != nil {
-							log.Error("healthCheck ", "listen close err", err.Error())
-							continue
-						}
-						sync = false
-					}
-					unSyncTimes++
-				}
-			}
+			s.check()
 		}
 	}
 }
@@ -5,9 +5,14 @@
 package util
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"time"
 
@@ -17,44 +22,184 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestStart(t *testing.T) {
+func newHealthTestQueue(mempoolSize int64) queue.Queue {
 	q := queue.New("channel")
-	health := NewHealthCheckServer(q.Client())
+	q.SetConfig(types.NewChain33Config(types.GetDefaultCfgstring()))
+	go func() {
+		client := q.Client()
+		client.Sub("mempool")
+		for msg := range client.Recv() {
+			msg.Reply(client.NewMessage("", types.EventMempoolSize, &types.MempoolSize{Size: mempoolSize}))
+		}
+	}()
+	return q
+}
 
+func newHealthTestAPI(isSync bool, height int64, peers ...*types.Peer) *mocks.QueueProtocolAPI {
 	api := new(mocks.QueueProtocolAPI)
-	api.On("IsSync").Return(&types.Reply{IsOk: true}, nil).Times(2)
-	api.On("IsSync").Return(&types.Reply{IsOk: false}, nil)
-	peer1 := &types.Peer{Addr: "addr1"}
-	peer2 := &types.Peer{Addr: "addr2"}
-	peers := &types.PeerList{Peers: []*types.Peer{peer1, peer2}}
-	api.On("PeerInfo", mock.Anything).Return(peers, nil)
+	api.On("IsSync").Return(&types.Reply{IsOk: isSync}, nil)
+	api.On("GetLastHeader").Return(&types.Header{Height: height}, nil)
+	self := &types.Peer{Addr: "self", Self: true, Header: &types.Header{Height: height}}
+	api.On("PeerInfo", mock.Anything).Return(&types.PeerList{Peers: append([]*types.Peer{self}, peers...)}, nil)
+	api.On("IsNtpClockSync").Return(&types.Reply{IsOk: true}, nil)
+	api.On("ExecWalletFunc", "wallet", "FatalFailure", mock.Anything).Return(&types.Int32{}, nil)
 	api.On("Close").Return()
-	health.api = api
+	return api
+}
+
+func getHealthReport(t *testing.T, h http.Handler, path string) (int, *HealthReport) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	report := &HealthReport{}
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), report))
+	return rec.Code, report
+}
+
+func TestStart(t *testing.T) {
+	q := newHealthTestQueue(0)
+	defer q.Close()
+	health := NewHealthCheckServer(q.Client())
+	peer := &types.Peer{Addr: "addr1", Header: &types.Header{Height: 100}}
+	health.api = newHealthTestAPI(true, 100, peer)
 
 	cfg, _ := types.InitCfg("../cmd/chain33/chain33.test.toml")
 	health.Start(cfg.Health)
-	time.Sleep(time.Second * 6)
+	time.Sleep(time.Millisecond * 500)
+
+	resp, err := http.Get("http://" + cfg.Health.ListenAddr + "/readyz")
+	require.Nil(t, err)
+	var report HealthReport
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, report.Ready)
+	assert.Equal(t, HealthStatusOK, report.Status)
+	assert.Equal(t, 1, report.Peers)
 
 	health.Close()
 	time.Sleep(time.Second * 1)
-
 }
 
 func TestGetHealth(t *testing.T) {
-	api := new(mocks.QueueProtocolAPI)
-	reply := &types.Reply{IsOk: true}
-	api.On("IsSync").Return(reply, nil).Once()
-	peer2 := &types.Peer{Addr: "addr2"}
-	peerlist := &types.PeerList{Peers: []*types.Peer{peer2}}
-	api.On("PeerInfo", mock.Anything).Return(peerlist, nil).Once()
-
 	healthNil := NewHealthCheckServer(nil)
 	assert.Nil(t, healthNil)
-	q := queue.New("channel")
+
+	q := newHealthTestQueue(5)
+	defer q.Close()
+	health := NewHealthCheckServer(q.Client())
+	peer1 := &types.Peer{Addr: "addr1", Header: &types.Header{Height: 120}}
+	peer2 := &types.Peer{Addr: "addr2", Header: &types.Header{Height: 90}}
+	health.api = newHealthTestAPI(true, 100, peer1, peer2)
+	health.Start(&types.HealthCheck{ListenAddr: "127.0.0.1:0", CheckInterval: 60})
+	defer health.Close()
+
+	report := health.getHealth()
+	assert.True(t, report.IsSync)
+	assert.Equal(t, int64(100), report.Height)
+	assert.Equal(t, 2, report.Peers)
+	assert.Equal(t, int64(120), report.BestPeerHeight)
+	assert.Equal(t, int64(20), report.HeightGap)
+	assert.True(t, report.NtpClockSync)
+	assert.Equal(t, int64(5), report.MempoolSize)
+	assert.True(t, report.MempoolFullness > 0)
+	assert.False(t, report.WalletFatalFailure)
+	assert.Equal(t, HealthStatusOK, report.Status)
+	assert.Equal(t, 0, len(report.Failures))
+	assert.Equal(t, []string{"height gap 20 > 10"}, report.NotReady)
+	require.Equal(t, 1, len(report.Modules))
+	assert.Equal(t, "mempool", report.Modules[0].Name)
+	assert.True(t, report.Modules[0].Alive)
+
+	health.check()
+	code, _ := getHealthReport(t, health.Handler(), "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	code, report = getHealthReport(t, health.Handler(), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, report.Ready)
+}
+
+func TestHealthReadiness(t *testing.T) {
+	q := newHealthTestQueue(0)
+	defer q.Close()
 	health := NewHealthCheckServer(q.Client())
+	//还没有检查时返回starting
+	code, report := getHealthReport(t, health.Handler(), "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusStarting, report.Status)
+
+	peer := &types.Peer{Addr: "addr1", Header: &types.Header{Height: 100}}
+	health.api = newHealthTestAPI(true, 100, peer)
+	health.cfg = types.HealthCheck{MinPeers: 1, MaxHeightGap: 10, MaxClockDrift: 10000, MaxMempoolFullness: 0.95,
+		ModuleStallTimeout: 60, UnSyncMaxTimes: 1}
+	health.check()
+	code, _ = getHealthReport(t, health.Handler(), "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	//不同步的次数不超过unSyncMaxTimes时仍然就绪
+	health.api = newHealthTestAPI(false, 100, peer)
+	health.check()
+	code, report = getHealthReport(t, health.Handler(), "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"not sync"}, report.NotReady)
+	health.check()
+	code, _ = getHealthReport(t, health.Handler(), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	//钱包致命错误, 存活检查失败
+	api := new(mocks.QueueProtocolAPI)
+	api.On("IsSync").Return(nil, errors.New("timeout"))
+	api.On("GetLastHeader").Return(nil, errors.New("timeout"))
+	api.On("PeerInfo", mock.Anything).Return(nil, errors.New("timeout"))
+	api.On("IsNtpClockSync").Return(nil, errors.New("timeout"))
+	api.On("ExecWalletFunc", "wallet", "FatalFailure", mock.Anything).Return(&types.Int32{Data: 1}, nil)
 	health.api = api
-	ret, err := health.getHealth(true)
-	assert.Nil(t, err)
-	assert.Equal(t, false, ret)
+	health.check()
+	code, report = getHealthReport(t, health.Handler(), "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusFail, report.Status)
+	assert.True(t, report.WalletFatalFailure)
+	assert.Equal(t, []string{"wallet fatal failure"}, report.Failures)
+}
+
+func TestModuleStall(t *testing.T) {
+	q := queue.New("channel")
+	defer q.Close()
+	//订阅了但是不处理消息的模块
+	blocked := q.Client()
+	blocked.Sub("blockchain")
+	client := q.Client()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, client.Send(client.NewMessage("blockchain", types.EventGetLastHeader, nil), false))
+	}
+	health := &HealthCheckServer{client: client, cfg: types.HealthCheck{ModuleStallTimeout: 1}}
+	time.Sleep(1100 * time.Millisecond)
+	report := &HealthReport{}
+	health.getModuleHealth(report)
+	require.Equal(t, 1, len(report.Modules))
+	assert.Equal(t, "blockchain", report.Modules[0].Name)
+	assert.False(t, report.Modules[0].Alive)
+	assert.True(t, report.Modules[0].Pending > 0)
+
+	//配置的模块没有订阅
+	health.cfg.Modules = []string{"wallet"}
+	report = &HealthReport{}
+	health.getModuleHealth(report)
+	assert.False(t, report.Modules[0].Alive)
+}
 
+func TestRecentDBWriteErrors(t *testing.T) {
+	health := &HealthCheckServer{}
+	now := time.Now()
+	//启动之后的错误算作最近的错误
+	assert.Equal(t, int64(2), health.recentDBWriteErrors(now, 2))
+	now = now.Add(dbWriteErrorWindow / 2)
+	assert.Equal(t, int64(3), health.recentDBWriteErrors(now, 3))
+	//超过窗口之后只计算窗口内新增的错误
+	now = now.Add(dbWriteErrorWindow / 2)
+	assert.Equal(t, int64(1), health.recentDBWriteErrors(now, 3))
+	now = now.Add(dbWriteErrorWindow / 2)
+	assert.Equal(t, int64(0), health.recentDBWriteErrors(now, 3))
+	assert.Equal(t, int64(1), health.recentDBWriteErrors(now, 4))
+	assert.True(t, len(health.dbErrSamples) <= 4)
 }
@@ -0,0 +1,503 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/p2p"
+	"github.com/33cn/chain33/system/p2p/dht"
+	p2pty "github.com/33cn/chain33/system/p2p/dht/types"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util"
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	multiaddr "github.com/multiformats/go-multiaddr"
+)
+
+//simP2PType 模拟网络使用的p2p类型, 底层为基于内存网络host的dht p2p
+const simP2PType = "sim"
+
+var (
+	//每个节点的配置对应一个内存网络host
+	simHosts sync.Map
+
+	errSimPacketLoss = errors.New("ErrSimPacketLoss")
+	errNodeCrashed   = errors.New("ErrNodeCrashed")
+	errWaitTimeout   = errors.New("ErrWaitTimeout")
+)
+
+func init() {
+	p2p.RegisterP2PCreate(simP2PType, func(mgr *p2p.Manager, subCfg []byte) p2p.IP2P {
+		host, ok := simHosts.Load(mgr.ChainCfg)
+		if !ok {
+			panic("sim p2p, host not exist")
+		}
+		return dht.NewWithHost(mgr, simP2PType, subCfg, host.(core.Host))
+	})
+}
+
+//Network 网络模拟器, 在同一进程内启动多个完整节点(queue, blockchain, executor, store, mempool, consensus),
+//节点之间的p2p通过libp2p内存网络连接, 可以模拟延时, 丢包, 网络分区以及节点崩溃
+type Network struct {
+	mtx     sync.Mutex
+	mn      mocknet.Mocknet
+	cancel  context.CancelFunc
+	nodes   []*SimNode
+	latency time.Duration
+}
+
+//SimNode 模拟网络中的节点
+type SimNode struct {
+	*Chain33Mock
+	index   int
+	host    *simHost
+	crashed bool
+}
+
+//NewNetwork 使用相同的配置创建count个节点, cfgstring为空时使用默认配置, 默认只有第一个节点挖矿,
+//启动时由第一个节点打包两个区块并同步到所有节点, 避免节点在创世高度等待同步超时
+func NewNetwork(cfgstring string, count int) (*Network, error) {
+	if cfgstring == "" {
+		cfgstring = types.GetDefaultCfgstring()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Network{mn: mocknet.New(ctx), cancel: cancel}
+	for i := 0; i < count; i++ {
+		node, err := n.newNode(cfgstring, i)
+		if err != nil {
+			n.Close()
+			return nil, err
+		}
+		n.nodes = append(n.nodes, node)
+	}
+	//没有连接的节点处于单节点模式, 可以直接出块
+	if err := n.MineBlocks(0, 2); err != nil {
+		n.Close()
+		return nil, err
+	}
+	if err := n.Heal(); err != nil {
+		n.Close()
+		return nil, err
+	}
+	if _, err := n.WaitSync(time.Minute); err != nil {
+		n.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *Network) newNode(cfgstring string, index int) (*SimNode, error) {
+	cfg := types.NewChain33Config(cfgstring)
+	mcfg := cfg.GetModuleConfig()
+	mcfg.P2P.Enable = true
+	mcfg.P2P.Types = []string{simP2PType}
+	mcfg.Consensus.Minerstart = index == 0
+	//丢包时可能收不到最新区块的广播, 落后一个区块时超时后主动向其他节点同步
+	if mcfg.BlockChain.OnChainTimeout == 0 {
+		mcfg.BlockChain.OnChainTimeout = 5
+	}
+	sub := cfg.GetSubConfig()
+	subCfg := &p2pty.P2PSubConfig{}
+	types.MustDecode(sub.P2P[p2pty.DHTTypeName], subCfg)
+	//内存网络中不需要种子节点以及nat穿透
+	subCfg.Seeds, subCfg.BootStraps, subCfg.RelayNodes = nil, nil, nil
+	subCfg.DisableNATPortMap = true
+	subCfg.EnableAutoNAT, subCfg.EnableRelayHop, subCfg.EnableConnReversal = false, false, false
+	data, err := json.Marshal(subCfg)
+	if err != nil {
+		return nil, err
+	}
+	sub.P2P[simP2PType] = data
+
+	priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/10.0.%d.%d/tcp/13803", index/250, index%250+1))
+	if err != nil {
+		return nil, err
+	}
+	h, err := n.mn.AddPeer(priv, addr)
+	if err != nil {
+		return nil, err
+	}
+	host := &simHost{Host: h, random: rand.New(rand.NewSource(types.Now().UnixNano()))}
+	simHosts.Store(cfg, host)
+	defer simHosts.Delete(cfg)
+	mock := newWithConfig(cfg, nil)
+	if mock == nil {
+		return nil, fmt.Errorf("new node %d failed", index)
+	}
+	return &SimNode{Chain33Mock: mock, index: index, host: host}, nil
+}
+
+//Nodes 获取所有节点, 包括已经崩溃的节点
+func (n *Network) Nodes() []*SimNode {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return append([]*SimNode{}, n.nodes...)
+}
+
+//Node 获取指定索引的节点
+func (n *Network) Node(index int) *SimNode {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.nodes[index]
+}
+
+func (n *Network) running() []*SimNode {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	var nodes []*SimNode
+	for _, node := range n.nodes {
+		if !node.crashed {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+//link 连接两个节点, 已经连接的节点保持不变
+func (n *Network) link(a, b *SimNode) error {
+	if len(n.mn.LinksBetweenPeers(a.host.ID(), b.host.ID())) == 0 {
+		if _, err := n.mn.LinkPeers(a.host.ID(), b.host.ID()); err != nil {
+			return err
+		}
+	}
+	n.setLatency(a, b)
+	if len(a.host.Network().ConnsToPeer(b.host.ID())) > 0 {
+		return nil
+	}
+	_, err := n.mn.ConnectPeers(a.host.ID(), b.host.ID())
+	return err
+}
+
+//unlink 断开两个节点的连接, 并且不允许重新拨号
+func (n *Network) unlink(a, b *SimNode) error {
+	if len(n.mn.LinksBetweenPeers(a.host.ID(), b.host.ID())) == 0 {
+		return nil
+	}
+	if err := n.mn.UnlinkPeers(a.host.ID(), b.host.ID()); err != nil {
+		return err
+	}
+	return n.mn.DisconnectPeers(a.host.ID(), b.host.ID())
+}
+
+func (n *Network) setLatency(a, b *SimNode) {
+	n.mtx.Lock()
+	latency := n.latency
+	n.mtx.Unlock()
+	for _, l := range n.mn.LinksBetweenPeers(a.host.ID(), b.host.ID()) {
+		l.SetOptions(mocknet.LinkOptions{Latency: latency})
+	}
+}
+
+//SetLatency 设置所有节点之间的单向延时
+func (n *Network) SetLatency(latency time.Duration) {
+	n.mtx.Lock()
+	n.latency = latency
+	n.mtx.Unlock()
+	nodes := n.running()
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			n.setLatency(nodes[i], nodes[j])
+		}
+	}
+}
+
+//SetPacketLoss 设置节点的丢包率, 以stream为单位, 新建的stream按概率失败, 对端收到的stream按概率被重置
+func (n *Network) SetPacketLoss(index int, rate float64) {
+	n.Node(index).host.setLoss(rate)
+}
+
+//Partition 将节点划分为多个互不连通的分区, 未包含在任何分区中的节点与所有节点断开
+func (n *Network) Partition(groups ...[]int) error {
+	group := make(map[int]int)
+	for i, g := range groups {
+		for _, index := range g {
+			group[index] = i + 1
+		}
+	}
+	nodes := n.running()
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			a, b := nodes[i], nodes[j]
+			ga, gb := group[a.index], group[b.index]
+			var err error
+			if ga != 0 && ga == gb {
+				err = n.link(a, b)
+			} else {
+				err = n.unlink(a, b)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//Heal 恢复所有运行中节点之间的连接
+func (n *Network) Heal() error {
+	nodes := n.running()
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			if err := n.link(nodes[i], nodes[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//Crash 模拟节点崩溃, 断开所有连接后关闭节点, 崩溃的节点不再参与后续的同步检查
+func (n *Network) Crash(index int) error {
+	node := n.Node(index)
+	n.mtx.Lock()
+	if node.crashed {
+		n.mtx.Unlock()
+		return errNodeCrashed
+	}
+	node.crashed = true
+	n.mtx.Unlock()
+	for _, other := range n.running() {
+		if err := n.unlink(node, other); err != nil {
+			return err
+		}
+	}
+	node.Close()
+	return nil
+}
+
+//SetMining 开启或关闭节点的挖矿
+func (n *Network) SetMining(index int, mining bool) error {
+	node := n.Node(index)
+	if node.crashed {
+		return errNodeCrashed
+	}
+	ty := types.EventMinerStop
+	if mining {
+		ty = types.EventMinerStart
+	}
+	client := node.GetClient()
+	msg := client.NewMessage("consensus", int64(ty), nil)
+	if err := client.Send(msg, true); err != nil {
+		return err
+	}
+	//重复开启或关闭返回的错误可以忽略
+	_, err := client.WaitTimeout(msg, time.Second*10)
+	return err
+}
+
+//MineBlocks 向节点逐个发送交易, 每个交易等待节点高度增长后再发送下一个, 节点所在分区内需要有挖矿节点
+func (n *Network) MineBlocks(index int, count int) error {
+	node := n.Node(index)
+	if node.crashed {
+		return errNodeCrashed
+	}
+	cfg := node.GetClient().GetConfig()
+	for i := 0; i < count; i++ {
+		header, err := node.GetAPI().GetLastHeader()
+		if err != nil {
+			return err
+		}
+		tx := util.CreateCoinsTx(cfg, node.GetGenesisKey(), node.GetHotAddress(), types.Coin)
+		if _, err = node.GetAPI().SendTx(tx); err != nil {
+			return err
+		}
+		if err = waitFor(time.Minute, func() bool {
+			last, err := node.GetAPI().GetLastHeader()
+			return err == nil && last.Height > header.Height
+		}); err != nil {
+			return fmt.Errorf("mine block on node %d, height %d: %v", index, header.Height+1, err)
+		}
+	}
+	return nil
+}
+
+//WaitSync 等待所有运行中的节点处于同一条最优链上, 返回共同的最新区块头
+func (n *Network) WaitSync(timeout time.Duration) (*types.Header, error) {
+	var best *types.Header
+	err := waitFor(timeout, func() bool {
+		best = nil
+		for _, node := range n.running() {
+			header, err := node.GetAPI().GetLastHeader()
+			if err != nil {
+				return false
+			}
+			if best != nil && (best.Height != header.Height || !bytes.Equal(best.Hash, header.Hash)) {
+				return false
+			}
+			best = header
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("wait sync: %v, %s", err, n.summary())
+	}
+	return best, nil
+}
+
+//summary 各节点的最新高度和哈希, 用于输出同步失败时的状态
+func (n *Network) summary() string {
+	var buf bytes.Buffer
+	for _, node := range n.running() {
+		header, err := node.GetAPI().GetLastHeader()
+		if err != nil {
+			fmt.Fprintf(&buf, "node%d: %v; ", node.index, err)
+			continue
+		}
+		fmt.Fprintf(&buf, "node%d: %d %s; ", node.index, header.Height, common.ToHex(header.Hash))
+	}
+	return buf.String()
+}
+
+//Close 关闭所有节点
+func (n *Network) Close() {
+	for _, node := range n.running() {
+		n.mtx.Lock()
+		node.crashed = true
+		n.mtx.Unlock()
+		node.Close()
+	}
+	n.cancel()
+}
+
+func waitFor(timeout time.Duration, cond func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return errWaitTimeout
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return nil
+}
+
+//Step 模拟场景中的一个步骤
+type Step func(n *Network) error
+
+//Run 依次执行场景步骤, 任一步骤失败时返回错误
+func (n *Network) Run(steps ...Step) error {
+	for i, step := range steps {
+		if err := step(n); err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+//PartitionStep 网络分区
+func PartitionStep(groups ...[]int) Step {
+	return func(n *Network) error { return n.Partition(groups...) }
+}
+
+//HealStep 恢复网络
+func HealStep() Step {
+	return func(n *Network) error { return n.Heal() }
+}
+
+//CrashStep 节点崩溃
+func CrashStep(index int) Step {
+	return func(n *Network) error { return n.Crash(index) }
+}
+
+//MiningStep 开启或关闭节点挖矿
+func MiningStep(index int, mining bool) Step {
+	return func(n *Network) error { return n.SetMining(index, mining) }
+}
+
+//MineStep 在节点上打包count个区块
+func MineStep(index, count int) Step {
+	return func(n *Network) error { return n.MineBlocks(index, count) }
+}
+
+//LatencyStep 设置节点间延时
+func LatencyStep(latency time.Duration) Step {
+	return func(n *Network) error {
+		n.SetLatency(latency)
+		return nil
+	}
+}
+
+//PacketLossStep 设置节点丢包率
+func PacketLossStep(index int, rate float64) Step {
+	return func(n *Network) error {
+		n.SetPacketLoss(index, rate)
+		return nil
+	}
+}
+
+//SyncStep 等待所有运行中的节点同步到同一条最优链, check用于检查同步后的最新区块头
+func SyncStep(timeout time.Duration, check func(header *types.Header) error) Step {
+	return func(n *Network) error {
+		header, err := n.WaitSync(timeout)
+		if err != nil || check == nil {
+			return err
+		}
+		return check(header)
+	}
+}
+
+//simHost 按丢包率随机丢弃stream的host
+type simHost struct {
+	core.Host
+	mtx    sync.Mutex
+	loss   float64
+	random *rand.Rand
+}
+
+func (h *simHost) setLoss(rate float64) {
+	h.mtx.Lock()
+	h.loss = rate
+	h.mtx.Unlock()
+}
+
+func (h *simHost) drop() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.loss > 0 && h.random.Float64() < h.loss
+}
+
+//NewStream 按丢包率新建stream失败
+func (h *simHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error) {
+	if h.drop() {
+		return nil, errSimPacketLoss
+	}
+	return h.Host.NewStream(ctx, p, pids...)
+}
+
+//SetStreamHandler 按丢包率重置收到的stream
+func (h *simHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.wrapHandler(handler))
+}
+
+//SetStreamHandlerMatch 按丢包率重置收到的stream
+func (h *simHost) SetStreamHandlerMatch(pid protocol.ID, match func(string) bool, handler network.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, match, h.wrapHandler(handler))
+}
+
+func (h *simHost) wrapHandler(handler network.StreamHandler) network.StreamHandler {
+	return func(s network.Stream) {
+		if h.drop() {
+			_ = s.Reset()
+			return
+		}
+		handler(s)
+	}
+}
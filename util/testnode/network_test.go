@@ -0,0 +1,82 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testnode_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/33cn/chain33/system"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/util/testnode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkPartition(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip network simulation in short mode")
+	}
+	net, err := testnode.NewNetwork("", 4)
+	require.Nil(t, err)
+	defer net.Close()
+
+	//分区期间两边各自出块, 节点0所在分区的链更长
+	var longest, shorter *types.Header
+	err = net.Run(
+		testnode.PartitionStep([]int{0, 1}, []int{2, 3}),
+		testnode.MiningStep(2, true),
+		testnode.MineStep(0, 30),
+		testnode.MineStep(2, 10),
+		func(n *testnode.Network) (err error) {
+			if longest, err = n.Node(0).GetAPI().GetLastHeader(); err != nil {
+				return err
+			}
+			shorter, err = n.Node(2).GetAPI().GetLastHeader()
+			return err
+		},
+		testnode.MiningStep(2, false),
+		testnode.HealStep(),
+		//恢复后所有节点回滚到最长链, 回滚的交易可能被重新打包, 最优链高度可能继续增长
+		testnode.SyncStep(time.Minute*3, nil),
+	)
+	require.Nil(t, err)
+	require.True(t, longest.Height > shorter.Height)
+	for _, node := range net.Nodes() {
+		reply, err := node.GetAPI().GetBlockHash(&types.ReqInt{Height: longest.Height})
+		require.Nil(t, err)
+		require.Equal(t, longest.Hash, reply.Hash)
+		reply, err = node.GetAPI().GetBlockHash(&types.ReqInt{Height: shorter.Height})
+		require.Nil(t, err)
+		require.NotEqual(t, shorter.Hash, reply.Hash)
+	}
+}
+
+func TestNetworkFaults(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip network simulation in short mode")
+	}
+	net, err := testnode.NewNetwork("", 3)
+	require.Nil(t, err)
+	defer net.Close()
+
+	err = net.Run(
+		testnode.LatencyStep(time.Millisecond*20),
+		testnode.PacketLossStep(1, 0.1),
+		testnode.CrashStep(2),
+		testnode.MineStep(0, 5),
+		//恢复丢包后同步, 丢失的下载请求会使区块同步任务等待超时
+		testnode.PacketLossStep(1, 0),
+		testnode.SyncStep(time.Minute, func(header *types.Header) error {
+			if header.Height < 7 {
+				return errors.New("block not synced")
+			}
+			return nil
+		}),
+	)
+	require.Nil(t, err)
+	require.NotNil(t, net.Crash(2))
+	require.NotNil(t, net.MineBlocks(2, 1))
+}
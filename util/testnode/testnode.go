@@ -481,7 +481,10 @@ func (m *mockP2P) SetQueueClient(client queue.Client) {
 				msg.Reply(client.NewMessage(p2pKey, types.EventPeerList, &types.PeerList{}))
 			case types.EventGetNetInfo:
 				msg.Reply(client.NewMessage(p2pKey, types.EventPeerList, &types.NodeNetInfo{}))
-			case types.EventTxBroadcast, types.EventBlockBroadcast:
+			case types.EventTxBroadcast, types.EventBlockBroadcast, types.EventReportFaultPeer:
+			case types.EventSnapshotDB:
+				//mock p2p没有需要备份的数据库
+				msg.Reply(client.NewMessage(p2pKey, types.EventReply, &types.Reply{IsOk: false}))
 			default:
 				msg.ReplyErr("p2p->Do not support "+types.GetEventName(int(msg.Ty)), types.ErrNotSupport)
 			}
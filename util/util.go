@@ -565,13 +565,15 @@ func PrintKV(kvs []*types.KeyValue) {
 
 // MockModule struct
 type MockModule struct {
-	Key string
+	Key    string
+	client queue.Client
 }
 
 // SetQueueClient method
 func (m *MockModule) SetQueueClient(client queue.Client) {
+	m.client = client
+	client.Sub(m.Key)
 	go func() {
-		client.Sub(m.Key)
 		for msg := range client.Recv() {
 			msg.Reply(client.NewMessage(m.Key, types.EventReply, &types.Reply{IsOk: false,
 				Msg: []byte(fmt.Sprintf("mock %s module not handle message %v", m.Key, msg.Ty))}))
@@ -582,5 +584,9 @@ func (m *MockModule) SetQueueClient(client queue.Client) {
 // Wait for ready
 func (m *MockModule) Wait() {}
 
-// Close method
-func (m *MockModule) Close() {}
+// Close method, 关闭队列客户端, 结束消息处理协程
+func (m *MockModule) Close() {
+	if m.client != nil {
+		m.client.Close()
+	}
+}
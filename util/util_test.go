@@ -235,6 +235,7 @@ func TestMockModule(t *testing.T) {
 	assert.Equal(t, ok, true)
 	assert.Equal(t, reply.GetIsOk(), false)
 	assert.Equal(t, reply.GetMsg(), []byte("mock mempool module not handle message 1"))
+	mem.Close()
 }
 
 func TestJSONPrint(t *testing.T) {
@@ -4,7 +4,12 @@
 
 package common
 
-import "fmt"
+import (
+	"fmt"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
 
 const (
 	keyAccount            = "Account"
@@ -63,3 +68,12 @@ func CalcWalletSeed() []byte {
 func CalcAirDropIndex() []byte {
 	return []byte(keyAirDropIndex)
 }
+
+//登记钱包数据库中的key前缀, 用于dbinspect解析数据
+func init() {
+	account := func() types.Message { return &types.WalletAccountStore{} }
+	dbm.RegisterKeyPrefix("wallet", keyAccount+":", "按时间排序的账户", account)
+	dbm.RegisterKeyPrefix("wallet", keyAddr+":", "地址 -> 账户", account)
+	dbm.RegisterKeyPrefix("wallet", keyLabel+":", "标签 -> 账户", account)
+	dbm.RegisterKeyPrefix("wallet", keyTx+":", "钱包交易", func() types.Message { return &types.WalletTxDetail{} })
+}
@@ -66,6 +66,7 @@ type Wallet struct {
 	EncryptFlag        int64
 	wg                 *sync.WaitGroup
 	walletStore        *walletStore
+	backup             *dbm.Backuper
 	random             *rand.Rand
 	cfg                *types.Wallet
 	done               chan struct{}
@@ -106,6 +107,7 @@ func New(cfg *types.Chain33Config) *Wallet {
 
 	wallet := &Wallet{
 		walletStore:      walletStore,
+		backup:           dbm.NewBackuper(walletStoreDB, "wallet", mcfg.Driver),
 		isWalletLocked:   1,
 		fatalFailureFlag: 0,
 		wg:               &sync.WaitGroup{},
@@ -322,3 +322,13 @@ func (wallet *Wallet) On_ImportPrivkeysFile(req *types.ReqPrivkeysFile) (types.M
 	}
 	return reply, err
 }
+
+// On_SnapshotDB 在线备份钱包数据库
+func (wallet *Wallet) On_SnapshotDB(req *types.ReqSnapshotDB) (types.Message, error) {
+	reply, err := wallet.backup.Handle(req)
+	if err != nil {
+		walletlog.Error("On_SnapshotDB", "op", req.GetOp(), "err", err.Error())
+		return nil, err
+	}
+	return reply, nil
+}